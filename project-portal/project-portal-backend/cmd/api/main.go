@@ -1,12 +1,16 @@
 package main
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,19 +21,27 @@ import (
 	"carbon-scribe/project-portal/project-portal-backend/internal/config"
 	"carbon-scribe/project-portal/project-portal-backend/internal/documents"
 	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial"
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/geocoding"
 	"carbon-scribe/project-portal/project-portal-backend/internal/health"
 	"carbon-scribe/project-portal/project-portal-backend/internal/integration"
+	"carbon-scribe/project-portal/project-portal-backend/internal/middleware"
 	"carbon-scribe/project-portal/project-portal-backend/internal/project"
 	"carbon-scribe/project-portal/project-portal-backend/internal/reports"
 	"carbon-scribe/project-portal/project-portal-backend/internal/search"
+	"carbon-scribe/project-portal/project-portal-backend/internal/seed"
 	"carbon-scribe/project-portal/project-portal-backend/pkg/elastic"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/lifecycle"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/metrics"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/postgis"
 	"carbon-scribe/project-portal/project-portal-backend/pkg/storage"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/utils"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/validation"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/version"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func main() {
@@ -44,17 +56,36 @@ func main() {
 		log.Fatalf("❌ Failed to load configuration: %v", err)
 	}
 
+	// "api seed" fills a development database with sample data and exits.
+	seedOnly := len(os.Args) > 1 && os.Args[1] == "seed"
+	if seedOnly && cfg.Server.Mode == "production" {
+		log.Fatalf("❌ %v", seed.ErrProduction)
+	}
+
+	logger, logCloser, err := initLogger(cfg)
+	if err != nil {
+		log.Fatalf("❌ Invalid logging configuration: %v", err)
+	}
+	defer logCloser.Close()
+	slog.SetDefault(logger)
+	logger.Info("starting project portal API", "build", version.Get(), "config", cfg.Redacted())
+
 	// Initialize database connection
-	db, err := initDatabase(cfg)
+	dbClient, err := initDatabase(cfg)
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to database: %v", err)
 	}
+	db := dbClient.DB()
 	log.Println("✅ Database connection established")
 
-	// Run all migrations
-	if err := runAllMigrations(db); err != nil {
+	// Run all migrations; DATABASE_STATEMENT_TIMEOUT bounds queries, not schema changes
+	if err := postgis.WithoutStatementTimeout(db, runAllMigrations); err != nil {
 		log.Printf("⚠️ Migration warnings: %v", err)
 	}
+	if seedOnly {
+		runSeed(cfg, db)
+		return
+	}
 
 	// Initialize Elasticsearch client
 	esClient, err := elastic.NewClient(elastic.Config{
@@ -75,7 +106,76 @@ func main() {
 	searchService := search.NewService(searchRepo)
 	searchHandler := search.NewHandler(searchService)
 
-	authHandler := &auth.Handler{}
+	if cfg.Auth.JWTSecret == "" {
+		log.Println("⚠️  JWT_SECRET not set — using the development signing key")
+	}
+	if cfg.Auth.JWTLeeway < 0 {
+		log.Printf("⚠️  Invalid JWT_LEEWAY (%s) — checking token times exactly", cfg.Auth.JWTLeeway)
+	}
+	auth.ConfigureJWT(auth.JWTConfig{
+		Secret:     []byte(cfg.Auth.JWTSecret),
+		Issuer:     cfg.Auth.JWTIssuer,
+		Audience:   cfg.Auth.JWTAudience,
+		TTL:        cfg.Auth.AccessTokenTTL,
+		RefreshTTL: cfg.Auth.RefreshTokenTTL,
+		Leeway:     cfg.Auth.JWTLeeway,
+	})
+	if cfg.Auth.PasswordPepperEnabled && cfg.Auth.PasswordPepper == "" {
+		log.Println("⚠️  PASSWORD_PEPPER_ENABLED is set but PASSWORD_PEPPER is empty — passwords will not be peppered")
+	}
+	utils.ConfigurePepper([]byte(cfg.Auth.PasswordPepper), cfg.Auth.PasswordPepperEnabled)
+	if err := utils.ConfigurePasswordHashing(cfg.Auth.HashAlgorithm); err != nil {
+		log.Printf("⚠️  Invalid PASSWORD_HASH_ALGORITHM (%v) — using bcrypt", err)
+	}
+	roles, err := auth.ParseRoles(cfg.Auth.Roles)
+	if err == nil {
+		err = auth.ConfigurePermissions(roles)
+	}
+	if err != nil {
+		log.Printf("⚠️  Invalid AUTH_ROLES (%v) — using the built-in roles", err)
+	}
+	authRepo := auth.NewRepository(db)
+	authService, err := auth.NewAuthServiceWithRoles(authRepo, auth.RolePolicy{
+		DefaultRole: cfg.Auth.DefaultRole,
+		Assignable:  cfg.Auth.AssignableRoles,
+	})
+	if err != nil {
+		log.Fatalf("❌ Invalid role configuration: %v", err)
+	}
+	if err := authService.SetSessionLimit(auth.SessionLimit{Max: cfg.Auth.MaxSessions, Policy: cfg.Auth.SessionLimitPolicy}); err != nil {
+		log.Printf("⚠️  Invalid AUTH_SESSION_LIMIT_POLICY (%v) — rejecting logins over the limit", err)
+		authService.SetSessionLimit(auth.SessionLimit{Max: cfg.Auth.MaxSessions, Policy: auth.SessionLimitReject})
+	}
+	if err := authService.SetDeletionGrace(cfg.Auth.DeletionGracePeriod); err != nil {
+		log.Printf("⚠️  Invalid AUTH_DELETION_GRACE_PERIOD (%v) — keeping deleted accounts for %s", err, auth.DefaultDeletionGrace)
+	}
+	if err := authService.SetLoginFailureAlert(auth.LoginFailureAlert{Threshold: cfg.Auth.LoginFailureThreshold, Window: cfg.Auth.LoginFailureWindow}); err != nil {
+		log.Printf("⚠️  Invalid AUTH_LOGIN_FAILURE_WINDOW (%v) — not alerting on failed logins", err)
+	}
+	authService.SetEmailNormalization(auth.EmailNormalization{Lowercase: cfg.Auth.EmailLowercase, NFC: cfg.Auth.EmailNFC})
+	authService.SetRequireEmailVerification(cfg.Features.RequireEmailVerification)
+	authMetrics := auth.NewMetrics()
+	authService.SetMetrics(authMetrics)
+	auth.CheckTokenVersions(authService)
+	authHandler := auth.NewHandler(authService)
+	authHandler.SetTokenCookies(cfg.Auth.SessionCookies)
+
+	// Background workers run until shutdown; singleton jobs run on one replica at a time
+	workers := lifecycle.NewGroup(context.Background())
+	cleanupInterval := cfg.Auth.TokenCleanupInterval
+	if cleanupInterval <= 0 {
+		log.Printf("⚠️  Invalid TOKEN_CLEANUP_INTERVAL (%s) — using 1h", cleanupInterval)
+		cleanupInterval = time.Hour
+	}
+	tokenCleanup, err := dbClient.Singleton("auth-token-cleanup")
+	if err != nil {
+		log.Fatalf("❌ Failed to set up token cleanup: %v", err)
+	}
+	workers.Go("auth-token-cleanup", func(ctx context.Context) {
+		tokenCleanup.Run(ctx, func(ctx context.Context) {
+			auth.RunTokenCleanup(ctx, authService, cleanupInterval)
+		})
+	})
 
 	collabRepo := collaboration.NewRepository(db)
 	collabService := collaboration.NewService(collabRepo)
@@ -93,10 +193,6 @@ func main() {
 	reportsService := reports.NewService(reportsRepo, nil) // Exporter can be added later
 	reportsHandler := reports.NewHandler(reportsService)
 
-	projectRepo := project.NewRepository(db)
-	projectService := project.NewService(projectRepo)
-	projectHandler := project.NewHandler(projectService)
-
 	// Initialize document management service
 	var docsHandler *documents.Handler
 	s3Client, s3Err := storage.NewS3Client(storage.S3Config{
@@ -130,34 +226,244 @@ func main() {
 		docSvc := documents.NewServiceWithIPFS(docRepo, docStorageSvc, ipfsUploader)
 		docsHandler = documents.NewHandler(docSvc)
 	}
+
+	projectRepo := project.NewRepository(db)
+	var thumbnails project.ImageStore
+	switch cfg.Storage.ThumbnailBackend {
+	case "s3":
+		if s3Client != nil {
+			thumbnails = &project.S3ImageStore{Client: s3Client, BaseURL: cfg.Storage.ThumbnailBaseURL}
+		} else {
+			log.Println("⚠️  Thumbnails: THUMBNAIL_STORAGE=s3 but the S3 client is unavailable — thumbnail upload disabled")
+		}
+	default:
+		thumbnails = &project.LocalImageStore{Dir: cfg.Storage.ThumbnailDir, BaseURL: cfg.Storage.ThumbnailBaseURL}
+	}
+	projectService := project.NewServiceWithThumbnails(projectRepo, thumbnails, cfg.Storage.ThumbnailMaxSizeMB*1024*1024)
+	projectHandler := project.NewHandler(projectService)
+
+	// Live project changes, relayed from the database triggers of migration 021
+	projectChanges := project.NewChangeHub()
+	workers.Go("project-changes", func(ctx context.Context) {
+		if err := project.ListenChanges(ctx, cfg.DatabaseURL, projectChanges); err != nil {
+			log.Printf("⚠️  Project change feed unavailable (%v) — /ws/projects will stay silent", err)
+		}
+	})
+	// Hijacked WebSocket connections outlive server.Shutdown; closing the hub ends them
+	workers.Go("project-change-stream", func(ctx context.Context) {
+		<-ctx.Done()
+		projectChanges.Close()
+	})
+
 	complianceRepo := compliance.NewRepository(db)
 	complianceService := compliance.NewService(complianceRepo)
 	complianceHandler := compliance.NewHandler(complianceService)
+	project.RecordAuditTo(complianceService)
 
-	geospatialRepo := geospatial.NewRepository(db)
-	geospatialService := geospatial.NewService(geospatialRepo)
-	geospatialHandler := geospatial.NewHandler(geospatialService)
+	geospatialRepo := geospatial.NewReplicaAwareRepository(dbClient)
+	areaPolicy, err := geospatial.ParseAreaPolicy(cfg.Geospatial.AreaBounds)
+	if err != nil {
+		log.Printf("⚠️  Invalid GEOSPATIAL_AREA_BOUNDS (%v) — using default area bounds", err)
+		areaPolicy = geospatial.DefaultAreaPolicy
+	}
+	overlapScope, err := geospatial.ParseOverlapScope(cfg.Geospatial.OverlapScope)
+	if err != nil {
+		log.Printf("⚠️  Invalid GEOSPATIAL_OVERLAP_SCOPE (%v) — checking overlaps against all projects", err)
+		overlapScope = geospatial.OverlapAllProjects
+	}
+	overlapTolerance := cfg.Geospatial.OverlapSimplifyTolerance
+	if overlapTolerance < 0 {
+		log.Printf("⚠️  Invalid GEOSPATIAL_OVERLAP_SIMPLIFY_TOLERANCE (%g) — comparing overlap candidates exactly", overlapTolerance)
+		overlapTolerance = 0
+	}
+	maxVertices := cfg.Geospatial.MaxVertices
+	if maxVertices < 0 {
+		log.Printf("⚠️  Invalid MAX_POLYGON_VERTICES (%d) — not limiting geometry vertices", maxVertices)
+		maxVertices = 0
+	}
+	carbonRates, err := geospatial.ParseCarbonRates(cfg.Geospatial.CarbonRates)
+	if err != nil {
+		log.Printf("⚠️  Invalid GEOSPATIAL_CARBON_RATES (%v) — carbon estimates need a supplied rate", err)
+		carbonRates = geospatial.CarbonRates{}
+	}
+	retryPolicy := geospatial.RetryPolicy{
+		MaxAttempts: cfg.Geospatial.DBRetryAttempts,
+		BaseDelay:   cfg.Geospatial.DBRetryBaseDelay,
+		MaxDelay:    cfg.Geospatial.DBRetryMaxDelay,
+	}
+	if retryPolicy.MaxAttempts < 1 {
+		log.Printf("⚠️  Invalid GEOSPATIAL_DB_RETRY_ATTEMPTS (%d) — retrying transient database errors %d times", retryPolicy.MaxAttempts, geospatial.DefaultRetryPolicy.MaxAttempts)
+		retryPolicy.MaxAttempts = geospatial.DefaultRetryPolicy.MaxAttempts
+	}
+	geospatialService := geospatial.NewServiceWithOptions(geospatialRepo, geospatial.ServiceOptions{
+		AreaPolicy:               areaPolicy,
+		RejectOverlaps:           cfg.Features.StrictOverlaps,
+		OverlapScope:             overlapScope,
+		OverlapSimplifyTolerance: overlapTolerance,
+		MaxVertices:              maxVertices,
+		Retry:                    retryPolicy,
+		MaxConcurrentDB:          int64(cfg.Geospatial.DBMaxConcurrent),
+		DBAcquireTimeout:         cfg.Geospatial.DBAcquireTimeout,
+		CarbonRates:              carbonRates,
+	})
+	if cfg.Geospatial.UploadTTL <= 0 {
+		log.Printf("⚠️  Invalid GEOSPATIAL_UPLOAD_TTL (%s) — keeping unfinished uploads for %s", cfg.Geospatial.UploadTTL, geospatial.DefaultUploadTTL)
+		cfg.Geospatial.UploadTTL = geospatial.DefaultUploadTTL
+	}
+	if cfg.Geospatial.UploadMaxPerUser <= 0 {
+		log.Printf("⚠️  Invalid GEOSPATIAL_UPLOAD_MAX_PER_USER (%d) — allowing %d unfinished uploads per user", cfg.Geospatial.UploadMaxPerUser, geospatial.DefaultMaxUploadsPerOwner)
+		cfg.Geospatial.UploadMaxPerUser = geospatial.DefaultMaxUploadsPerOwner
+	}
+	geometryUploads, err := geospatial.NewUploadStore(cfg.Geospatial.UploadDir, cfg.Geospatial.UploadTTL, cfg.Geospatial.UploadMaxPerUser)
+	if err != nil {
+		log.Printf("⚠️  Cannot use GEOSPATIAL_UPLOAD_DIR %q (%v) — resumable geometry uploads disabled", cfg.Geospatial.UploadDir, err)
+		geometryUploads = nil
+	} else {
+		sweepInterval := cfg.Geospatial.UploadSweepInterval
+		if sweepInterval <= 0 {
+			log.Printf("⚠️  Invalid GEOSPATIAL_UPLOAD_SWEEP_INTERVAL (%s) — using 1h", sweepInterval)
+			sweepInterval = time.Hour
+		}
+		workers.Go("geometry-upload-sweep", func(ctx context.Context) {
+			geospatial.RunUploadSweep(ctx, geometryUploads, sweepInterval)
+		})
+	}
+	geospatialHandler := geospatial.NewHandlerWithUploads(geospatialService, cfg.Storage.MaxUploadSizeMB<<20, geometryUploads)
+
+	// Address lookup (only if a geocoding provider is configured)
+	var geocodeHandler *geocoding.Handler
+	geocoder, err := geocoding.New(geocoding.Config{
+		Provider:  cfg.Geospatial.GeocoderProvider,
+		BaseURL:   cfg.Geospatial.GeocoderURL,
+		UserAgent: cfg.Geospatial.GeocoderUserAgent,
+		Timeout:   cfg.Geospatial.GeocoderTimeout,
+	})
+	if err != nil {
+		log.Printf("⚠️  Invalid GEOCODER_PROVIDER (%v) — address lookup disabled", err)
+	} else if geocoder != nil {
+		geocodeHandler = geocoding.NewHandler(geocoder)
+	}
 
 	// Setup Gin
 	if !cfg.Debug {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	// Unknown JSON fields are ignored unless ENABLE_STRICT_JSON is set
+	validation.SetStrictDecoding(cfg.Features.StrictJSON)
 
 	router := gin.Default()
 
-	// Add CORS middleware
-	router.Use(corsMiddleware())
+	// Client IP for rate limiting and audit logs; forwarded headers count only from trusted proxies
+	configureTrustedProxies(router, cfg.Server.TrustedProxies)
+
+	// CORS by route: /auth/* is restricted to explicit origins, public map and
+	// status paths answer any origin without credentials
+	router.Use(corsMiddleware(cfg.CORS))
+
+	// HSTS (HTTPS only), nosniff, frame and referrer policy; after CORS so preflights are answered as before
+	if cfg.Security.Enabled {
+		router.Use(middleware.SecurityHeaders(middleware.SecurityHeadersConfig{
+			HSTSMaxAge:            cfg.Security.HSTSMaxAge,
+			HSTSIncludeSubdomains: cfg.Security.HSTSIncludeSubdomains,
+			HSTSPreload:           cfg.Security.HSTSPreload,
+			NoSniff:               cfg.Security.NoSniff,
+			FrameOptions:          cfg.Security.FrameOptions,
+			ReferrerPolicy:        cfg.Security.ReferrerPolicy,
+		}))
+	}
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"service":   "carbon-scribe-project-portal",
-			"timestamp": time.Now().Format(time.RFC3339),
-			"version":   "1.0.0",
-			"modules":   []string{"auth", "collaboration", "documents", "integration", "reports", "search", "geospatial"},
-		})
-	})
+	// Tag every request with a correlation id (reused from a gateway's header
+	// when present) and a request-scoped logger
+	router.Use(middleware.RequestLogger(slog.Default(), middleware.RequestIDConfig{Headers: cfg.Logging.RequestIDHeaders}))
+
+	// Body sizes by route for capacity planning; responses are counted as sent
+	if cfg.Metrics.Enabled {
+		if cfg.Metrics.PayloadWarnBytes < 0 {
+			log.Printf("⚠️  Invalid PAYLOAD_WARN_BYTES (%d) — not warning about large payloads", cfg.Metrics.PayloadWarnBytes)
+			cfg.Metrics.PayloadWarnBytes = 0
+		}
+		payloadMetrics := middleware.NewPayloadMetrics()
+		registry := metrics.NewRegistry()
+		registry.Register(payloadMetrics.Request, payloadMetrics.Response)
+		registry.Register(authMetrics.Collectors()...)
+		router.Use(middleware.PayloadSizes(middleware.PayloadSizeConfig{
+			Metrics:   payloadMetrics,
+			WarnBytes: cfg.Metrics.PayloadWarnBytes,
+		}))
+		// The auth counters reveal attack activity; scrapers sign in like any client
+		router.GET("/metrics", auth.AuthMiddleware(), auth.RequirePermission(auth.PermSystemDiagnostics), gin.WrapH(registry.Handler()))
+	}
+
+	// Machine clients sign in with X-API-Key; routes opt in with AuthMiddlewareOrAPIKey.
+	// Resolved ahead of the rate limiter so each key gets its own bucket
+	router.Use(auth.ResolveAPIKey(authService))
+
+	// Per-client token bucket; 429 with Retry-After once it is spent
+	if cfg.RateLimit.RequestsPerSecond > 0 && cfg.RateLimit.Burst < 1 {
+		log.Printf("⚠️  Invalid RATE_LIMIT_BURST (%d) — allowing bursts of 1 request", cfg.RateLimit.Burst)
+		cfg.RateLimit.Burst = 1
+	}
+	router.Use(middleware.RateLimit(middleware.RateLimitConfig{
+		Rate:   cfg.RateLimit.RequestsPerSecond,
+		Burst:  cfg.RateLimit.Burst,
+		Exempt: cfg.RateLimit.Exempt,
+		OnLimited: func(c *gin.Context) {
+			if isLoginAttempt(c.Request) {
+				authMetrics.Lockouts.Inc(auth.LockoutRateLimit)
+			}
+		},
+	}))
+
+	// Process-wide cap on in-flight requests; the rest wait briefly, then get 503
+	if cfg.Concurrency.MaxInFlight > 0 && cfg.Concurrency.QueueTimeout < 0 {
+		log.Printf("⚠️  Invalid CONCURRENCY_QUEUE_TIMEOUT (%s) — rejecting requests over the cap at once", cfg.Concurrency.QueueTimeout)
+		cfg.Concurrency.QueueTimeout = 0
+	}
+	router.Use(middleware.ConcurrencyLimit(middleware.ConcurrencyLimitConfig{
+		MaxInFlight:  cfg.Concurrency.MaxInFlight,
+		QueueTimeout: cfg.Concurrency.QueueTimeout,
+		Exempt:       cfg.Concurrency.Exempt,
+	}))
+
+	// Deadline for handlers; long PostGIS queries are cancelled with a 503
+	routeTimeouts, err := middleware.ParseRouteTimeouts(cfg.Server.RouteTimeouts)
+	if err != nil {
+		log.Printf("⚠️  Invalid SERVER_ROUTE_TIMEOUTS (%v) — using SERVER_REQUEST_TIMEOUT for every route", err)
+		routeTimeouts = map[string]time.Duration{}
+	}
+	// The change feed is a long-lived WebSocket, not a request to bound
+	if _, ok := routeTimeouts["/ws/projects"]; !ok {
+		routeTimeouts["/ws/projects"] = 0
+	}
+	router.Use(middleware.RouteTimeouts(cfg.Server.RequestTimeout, routeTimeouts))
+
+	// Gzip large responses such as GeoJSON; zip exports and the WebSocket pass through
+	if cfg.Compression.Enabled {
+		if cfg.Compression.Level < gzip.HuffmanOnly || cfg.Compression.Level > gzip.BestCompression {
+			log.Printf("⚠️  Invalid COMPRESSION_LEVEL (%d) — using the default gzip level", cfg.Compression.Level)
+			cfg.Compression.Level = 0
+		}
+		router.Use(middleware.Compress(middleware.CompressConfig{
+			MinSize: cfg.Compression.MinSize,
+			Level:   cfg.Compression.Level,
+			Exempt:  cfg.Compression.Exempt,
+		}))
+	}
+
+	// JSON errors for unknown paths and unsupported methods
+	registerFallbackHandlers(router)
+
+	// Locally stored project thumbnails
+	if cfg.Storage.ThumbnailBackend != "s3" && strings.HasPrefix(cfg.Storage.ThumbnailBaseURL, "/") {
+		router.Static(cfg.Storage.ThumbnailBaseURL, cfg.Storage.ThumbnailDir)
+	}
+
+	// Health check endpoint; database pings are cached so frequent probes stay cheap
+	dbHealth := postgis.NewHealthCache(dbClient.Health, cfg.Database.HealthCacheTTL)
+	router.GET("/health", healthCheck(dbHealth.Check))
+
+	// Build metadata injected with -ldflags
+	router.GET("/version", versionHandler)
 
 	// Root API route
 	router.GET("/", func(c *gin.Context) {
@@ -165,15 +471,17 @@ func main() {
 			"name":    "CarbonScribe Project Portal API",
 			"version": "1.0.0",
 			"endpoints": gin.H{
-				"health":        "/health",
-				"auth":          "/api/auth/*",
-				"collaboration": "/api/collaboration/*",
-				"documents":     "/api/v1/documents/*",
-				"compliance":    "/api/v1/compliance/*",
-				"integration":   "/api/integration/*",
-				"reports":       "/api/v1/reports/*",
-				"search":        "/api/v1/search/*",
-				"geospatial":    "/api/v1/geospatial/*",
+				"health":          "/health",
+				"version":         "/version",
+				"auth":            "/api/auth/*",
+				"collaboration":   "/api/collaboration/*",
+				"documents":       "/api/v1/documents/*",
+				"compliance":      "/api/v1/compliance/*",
+				"integration":     "/api/integration/*",
+				"reports":         "/api/v1/reports/*",
+				"search":          "/api/v1/search/*",
+				"geospatial":      "/api/v1/geospatial/*",
+				"project_changes": "/ws/projects",
 			},
 		})
 	})
@@ -187,6 +495,11 @@ func main() {
 	// Integration routes
 	integration.RegisterRoutes(router, integrationHandler)
 
+	// Live project change feed
+	projectStream := project.NewChangeStream(projectChanges)
+	projectStream.SetAllowedOrigins(cfg.CORS.AllowedOrigins)
+	projectStream.RegisterRoutes(router)
+
 	// API v1 routes (for reports and future APIs)
 	v1 := router.Group("/api/v1")
 	{
@@ -210,6 +523,9 @@ func main() {
 		complianceHandler.RegisterRoutes(v1)
 		// Register geospatial routes under v1
 		geospatialHandler.RegisterRoutes(v1)
+		if geocodeHandler != nil {
+			geocodeHandler.RegisterRoutes(v1)
+		}
 
 		// Ping endpoint for testing
 		v1.GET("/ping", func(c *gin.Context) {
@@ -218,13 +534,7 @@ func main() {
 	}
 
 	// Create HTTP server with proper timeouts
-	server := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.Port),
-		Handler:      router,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
+	server := newHTTPServer(cfg, router)
 
 	// Channel to listen for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -245,6 +555,7 @@ func main() {
 		fmt.Println("   - Search: /api/v1/search/*")
 		fmt.Println("   - Compliance: /api/v1/compliance/*")
 		fmt.Println("   - Geospatial: /api/v1/geospatial/*")
+		fmt.Println("   - Project changes: /ws/projects")
 
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("❌ Server failed to start: %v", err)
@@ -255,8 +566,13 @@ func main() {
 	<-quit
 	fmt.Println("\n🛑 Shutdown signal received...")
 
-	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Draining requests and stopping workers share one deadline
+	shutdownTimeout := cfg.Server.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		log.Printf("⚠️  Invalid SERVER_SHUTDOWN_TIMEOUT (%s) — using 30s", shutdownTimeout)
+		shutdownTimeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
 	// Attempt graceful shutdown
@@ -264,46 +580,125 @@ func main() {
 		log.Fatalf("❌ Server forced to shutdown: %v", err)
 	}
 
+	// Stop background workers and release their locks
+	if err := workers.Shutdown(ctx); err != nil {
+		log.Fatalf("❌ Background workers did not stop: %v", err)
+	}
+
 	fmt.Println("✅ Server exited gracefully")
 }
 
-// initDatabase initializes the GORM database connection
-func initDatabase(config *config.Config) (*gorm.DB, error) {
-	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	}
+// isLoginAttempt reports whether r is a sign-in, the only throttled auth
+// request counted as a lockout.
+func isLoginAttempt(r *http.Request) bool {
+	return r.Method == http.MethodPost && r.URL.Path == "/auth/login"
+}
 
-	if config.Debug {
-		gormConfig.Logger = logger.Default.LogMode(logger.Info)
-	}
+// versionHandler reports the version, commit and build date of the binary.
+func versionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
 
-	db, err := gorm.Open(postgres.Open(config.DatabaseURL), gormConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+// registerFallbackHandlers answers unknown paths with 404 and known paths hit
+// with the wrong method with 405, both in the API's JSON error format. Global
+// middleware such as CORS still runs for these responses.
+func registerFallbackHandlers(router *gin.Engine) {
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found", "code": "NOT_FOUND"})
+	})
+	router.NoMethod(func(c *gin.Context) {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method " + c.Request.Method + " is not allowed on this route", "code": "METHOD_NOT_ALLOWED"})
+	})
+}
+
+// initLogger builds the application logger from cfg.Logging. Without a level,
+// debug mode logs at debug and everything else at info.
+func initLogger(cfg *config.Config) (*slog.Logger, io.Closer, error) {
+	level := cfg.Logging.Level
+	if level == "" && cfg.Debug {
+		level = "debug"
 	}
+	return logging.New(logging.Options{
+		Format: cfg.Logging.Format,
+		Level:  level,
+		Output: cfg.Logging.OutputPath,
+	})
+}
 
-	// Get underlying SQL DB and configure connection pool
-	sqlDB, err := db.DB()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get underlying DB: %w", err)
+// newHTTPServer builds the API server with the configured timeouts.
+func newHTTPServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
+}
 
-	sqlDB.SetMaxOpenConns(25)
-	sqlDB.SetMaxIdleConns(5)
-	sqlDB.SetConnMaxLifetime(5 * time.Minute)
+// initDatabase initializes the GORM database connection
+func initDatabase(config *config.Config) (*postgis.Client, error) {
+	return postgis.Open(postgis.Config{
+		DSN:                config.DatabaseURL,
+		ReplicaDSN:         config.Database.ReplicaURL,
+		MaxOpenConns:       config.Database.MaxOpenConns,
+		MaxIdleConns:       config.Database.MaxIdleConns,
+		ConnMaxLifetime:    config.Database.ConnMaxLifetime,
+		ConnMaxIdleTime:    config.Database.ConnMaxIdleTime,
+		SlowQueryThreshold: config.Database.SlowQueryThreshold,
+		CreatePostGIS:      config.Database.CreatePostGIS,
+		StatementTimeout:   config.Database.StatementTimeout,
+		Debug:              config.Debug,
+	})
+}
 
-	// Test connection
-	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("database ping failed: %w", err)
+// healthCheck answers 200 while the database is reachable and 503 when the
+// last check failed. checkDB is expected to be cached.
+func healthCheck(checkDB func(context.Context) (time.Time, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checkedAt, err := checkDB(c.Request.Context())
+		status, state, database := http.StatusOK, "healthy", "up"
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn("health check: database unreachable", "error", err)
+			status, state, database = http.StatusServiceUnavailable, "unhealthy", "down"
+		}
+		c.JSON(status, gin.H{
+			"status":              state,
+			"service":             "carbon-scribe-project-portal",
+			"timestamp":           time.Now().Format(time.RFC3339),
+			"database":            database,
+			"database_checked_at": checkedAt.UTC().Format(time.RFC3339Nano),
+			"version":             version.Version,
+			"build":               version.Get(),
+			"modules":             []string{"auth", "collaboration", "documents", "integration", "reports", "search", "geospatial"},
+		})
 	}
+}
 
-	return db, nil
+// runSeed fills the database with sample data for "api seed" and reports
+// the credentials of the seeded users.
+func runSeed(cfg *config.Config, db *gorm.DB) {
+	result, err := seed.Run(context.Background(), db, cfg.Server.Mode)
+	if err != nil {
+		log.Fatalf("❌ Seeding failed: %v", err)
+	}
+	log.Printf("✅ Seeded %d users, %d projects and %d boundaries; seeded users sign in with %q",
+		result.UsersCreated, result.ProjectsCreated, result.GeometriesCreated, seed.Password)
 }
 
 // runAllMigrations runs migrations for all modules
 func runAllMigrations(db *gorm.DB) error {
 	// Auto-migrate all models from all modules
 	err := db.AutoMigrate(
+		// Auth models
+		&auth.Organization{},
+		&auth.User{},
+		&auth.APIKey{},
+		&auth.RefreshToken{},
+		&auth.Session{},
+
 		// Project models
 		&project.Project{},
 
@@ -382,6 +777,11 @@ func runAllMigrations(db *gorm.DB) error {
 		return err
 	}
 
+	// Emails are unique regardless of case, however they are stored (migration 029)
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))").Error; err != nil {
+		return fmt.Errorf("case-insensitive email index: %w", err)
+	}
+
 	return nil
 }
 
@@ -392,7 +792,7 @@ func runGeospatialDDL(db *gorm.DB) error {
 		`CREATE TABLE IF NOT EXISTS project_geometries (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			project_id UUID NOT NULL UNIQUE REFERENCES projects(id) ON DELETE CASCADE,
-			geometry GEOGRAPHY(GEOMETRY, 4326) NOT NULL,
+			geometry GEOGRAPHY(MULTIPOLYGON, 4326) NOT NULL,
 			centroid GEOGRAPHY(POINT, 4326) NOT NULL,
 			bounding_box GEOGRAPHY(POLYGON, 4326),
 			area_hectares DECIMAL(12, 4) NOT NULL,
@@ -408,8 +808,47 @@ func runGeospatialDDL(db *gorm.DB) error {
 			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		)`,
+		// Tables created before boundaries were restricted to polygons
+		`DO $$
+		BEGIN
+			IF EXISTS (
+				SELECT 1 FROM geography_columns
+				WHERE f_table_name = 'project_geometries' AND f_geography_column = 'geometry' AND type <> 'MultiPolygon'
+			) THEN
+				ALTER TABLE project_geometries
+					ALTER COLUMN geometry TYPE GEOGRAPHY(MULTIPOLYGON, 4326)
+					USING ST_Multi(geometry::geometry)::geography;
+			END IF;
+		END $$`,
+		"ALTER TABLE project_geometries ADD COLUMN IF NOT EXISTS winding_corrected BOOLEAN NOT NULL DEFAULT FALSE",
+		// area_hectares is cached by a trigger whenever the geometry is written (migration 028)
+		`CREATE OR REPLACE FUNCTION cache_project_geometry_area() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'INSERT' OR ST_AsBinary(NEW.geometry) IS DISTINCT FROM ST_AsBinary(OLD.geometry) THEN
+				NEW.area_hectares := ST_Area(NEW.geometry) * 0.0001;
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS project_geometries_cache_area ON project_geometries",
+		`CREATE TRIGGER project_geometries_cache_area
+			BEFORE INSERT OR UPDATE OF geometry ON project_geometries
+			FOR EACH ROW EXECUTE FUNCTION cache_project_geometry_area()`,
 		"CREATE INDEX IF NOT EXISTS idx_project_geometries_geometry ON project_geometries USING GIST (geometry)",
+		"CREATE INDEX IF NOT EXISTS idx_project_geometries_geometry_geom ON project_geometries USING GIST ((geometry::geometry))",
 		"CREATE INDEX IF NOT EXISTS idx_project_geometries_centroid ON project_geometries USING GIST (centroid)",
+		`CREATE TABLE IF NOT EXISTS project_geometry_versions (
+			project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+			version INTEGER NOT NULL,
+			geometry GEOGRAPHY(MULTIPOLYGON, 4326) NOT NULL,
+			area_hectares DECIMAL(12, 4) NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (project_id, version)
+		)`,
+		`INSERT INTO project_geometry_versions (project_id, version, geometry, area_hectares, created_at)
+		SELECT project_id, COALESCE(version, 1), ST_Multi(geometry::geometry)::geography, area_hectares, COALESCE(updated_at, CURRENT_TIMESTAMP)
+		FROM project_geometries
+		ON CONFLICT (project_id, version) DO NOTHING`,
 		`CREATE TABLE IF NOT EXISTS administrative_boundaries (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			name VARCHAR(255) NOT NULL,
@@ -436,6 +875,31 @@ func runGeospatialDDL(db *gorm.DB) error {
 			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
 		)`,
 		"CREATE INDEX IF NOT EXISTS idx_geofences_geometry ON geofences USING GIST (geometry)",
+		`CREATE TABLE IF NOT EXISTS project_sub_areas (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			kind VARCHAR(50) NOT NULL DEFAULT '',
+			geometry GEOGRAPHY(MULTIPOLYGON, 4326) NOT NULL,
+			area_hectares DECIMAL(12, 4) NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_project_sub_areas_project ON project_sub_areas (project_id)",
+		"CREATE INDEX IF NOT EXISTS idx_project_sub_areas_geometry ON project_sub_areas USING GIST (geometry)",
+		`CREATE TABLE IF NOT EXISTS reference_layers (
+			name VARCHAR(63) PRIMARY KEY,
+			description TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS reference_layer_features (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			layer_name VARCHAR(63) NOT NULL REFERENCES reference_layers(name) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			geometry GEOGRAPHY(MULTIPOLYGON, 4326) NOT NULL
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_reference_layer_features_layer ON reference_layer_features (layer_name)",
+		"CREATE INDEX IF NOT EXISTS idx_reference_layer_features_geometry ON reference_layer_features USING GIST (geometry)",
 		`CREATE TABLE IF NOT EXISTS map_tile_cache (
 			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 			tile_key VARCHAR(500) UNIQUE NOT NULL,
@@ -473,35 +937,52 @@ func runGeospatialDDL(db *gorm.DB) error {
 	return nil
 }
 
-// corsMiddleware adds CORS headers
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		allowedOrigins := os.Getenv("CORS_ALLOWED_ORIGINS")
-		if allowedOrigins == "" {
-			allowedOrigins = "*"
-		}
+// corsMiddleware answers cross-origin callers allowed by the policy for the
+// request path: cfg, or the entry of cfg.Routes with the longest prefix of the
+// path. It runs on every request, preflights included, since those match no
+// route group. Requests without an Origin, or from the API's own origin, get
+// no CORS headers. An origin listed explicitly is echoed back, with
+// credentials unless the policy omits them; any other origin matched by "*"
+// gets a literal "*" and never credentials, so no arbitrary site can make
+// credentialed calls. Preflights are answered with 204 and cached for the
+// policy's MaxAge; a preflight asking for a method or header outside the
+// policy's lists gets no CORS headers, so the browser blocks the actual
+// request.
+func corsMiddleware(cfg config.CORSConfig) gin.HandlerFunc {
+	fallback := newCORSPolicy(cfg)
+	routes := make(map[string]*corsPolicy, len(cfg.Routes))
+	for prefix, route := range cfg.Routes {
+		routes[strings.TrimSuffix(prefix, "/")] = newCORSPolicy(route)
+	}
 
-		origin := c.Request.Header.Get("Origin")
-		allowOrigin := "*"
-		if allowedOrigins != "*" {
-			for _, o := range strings.Split(allowedOrigins, ",") {
-				if o == origin {
-					allowOrigin = origin
-					break
+	return func(c *gin.Context) {
+		policy := corsPolicyFor(c.Request.URL.Path, routes, fallback)
+		preflight := c.Request.Method == http.MethodOptions
+		origin := c.GetHeader("Origin")
+		listed := originListed(policy.AllowedOrigins, origin)
+		if origin != "" && !isSameOrigin(c.Request, origin) && (listed || policy.anyOrigin) {
+			h := c.Writer.Header()
+			h.Add("Vary", "Origin")
+			if !preflight || preflightAllowed(c.Request, policy.CORSConfig) {
+				if listed {
+					h.Set("Access-Control-Allow-Origin", origin)
+					if !policy.OmitCredentials {
+						h.Set("Access-Control-Allow-Credentials", "true")
+					}
+				} else {
+					h.Set("Access-Control-Allow-Origin", "*")
 				}
 			}
-			// If not matching, fallback to the first origin so the header is always valid
-			if allowOrigin == "*" {
-				allowOrigin = strings.Split(allowedOrigins, ",")[0]
+			if preflight && h.Get("Access-Control-Allow-Origin") != "" {
+				h.Set("Access-Control-Allow-Methods", policy.methods)
+				h.Set("Access-Control-Allow-Headers", policy.headers)
+				if policy.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", policy.maxAge)
+				}
 			}
 		}
 
-		c.Writer.Header().Set("Access-Control-Allow-Origin", allowOrigin)
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-User-ID")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
-
-		if c.Request.Method == "OPTIONS" {
+		if preflight {
 			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
@@ -509,3 +990,87 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// corsPolicy is a CORS policy with its response header values rendered once.
+// anyOrigin records a "*" among the allowed origins.
+type corsPolicy struct {
+	config.CORSConfig
+	anyOrigin bool
+	methods   string
+	headers   string
+	maxAge    string
+}
+
+func newCORSPolicy(cfg config.CORSConfig) *corsPolicy {
+	return &corsPolicy{
+		CORSConfig: cfg,
+		anyOrigin:  originListed(cfg.AllowedOrigins, "*"),
+		methods:    strings.Join(cfg.AllowedMethods, ", "),
+		headers:    strings.Join(cfg.AllowedHeaders, ", "),
+		maxAge:     strconv.Itoa(int(cfg.MaxAge / time.Second)),
+	}
+}
+
+// corsPolicyFor returns the policy of the longest prefix in routes that is
+// path or a parent of it, and fallback when none is.
+func corsPolicyFor(path string, routes map[string]*corsPolicy, fallback *corsPolicy) *corsPolicy {
+	best, bestLen := fallback, -1
+	for prefix, policy := range routes {
+		if (path == prefix || strings.HasPrefix(path, prefix+"/")) && len(prefix) > bestLen {
+			best, bestLen = policy, len(prefix)
+		}
+	}
+	return best
+}
+
+// configureTrustedProxies makes ClientIP honour X-Forwarded-For only when the
+// connecting peer is one of proxies (IPs or CIDRs). With none, ClientIP is the
+// peer address, so a client cannot pick its own rate limit bucket or the IP
+// recorded in audit logs.
+func configureTrustedProxies(router *gin.Engine, proxies []string) {
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		log.Printf("⚠️  Invalid TRUSTED_PROXIES (%v) — ignoring forwarded client addresses", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+}
+
+// isSameOrigin reports whether origin names the host the request was sent to.
+func isSameOrigin(r *http.Request, origin string) bool {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return strings.EqualFold(origin, scheme+"://"+r.Host)
+}
+
+func originListed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// preflightAllowed checks the method and headers a preflight asks for against
+// the configured lists. Header names compare case-insensitively.
+func preflightAllowed(r *http.Request, cfg config.CORSConfig) bool {
+	if method := r.Header.Get("Access-Control-Request-Method"); method != "" && !containsFold(cfg.AllowedMethods, method) {
+		return false
+	}
+	for _, header := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header != "" && !containsFold(cfg.AllowedHeaders, header) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
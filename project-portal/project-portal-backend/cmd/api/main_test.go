@@ -0,0 +1,483 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/config"
+	"carbon-scribe/project-portal/project-portal-backend/internal/middleware"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/postgis"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNewHTTPServer_UsesConfiguredTimeouts(t *testing.T) {
+	cfg := &config.Config{
+		Port: "9090",
+		Server: config.ServerConfig{
+			ReadTimeout:       11 * time.Second,
+			ReadHeaderTimeout: 3 * time.Second,
+			WriteTimeout:      12 * time.Second,
+			IdleTimeout:       45 * time.Second,
+		},
+	}
+
+	server := newHTTPServer(cfg, http.NotFoundHandler())
+
+	if server.Addr != ":9090" {
+		t.Errorf("Addr = %q, want :9090", server.Addr)
+	}
+	if server.ReadTimeout != 11*time.Second || server.WriteTimeout != 12*time.Second {
+		t.Errorf("read/write timeouts = %v/%v", server.ReadTimeout, server.WriteTimeout)
+	}
+	if server.ReadHeaderTimeout != 3*time.Second {
+		t.Errorf("ReadHeaderTimeout = %v, want 3s", server.ReadHeaderTimeout)
+	}
+	if server.IdleTimeout != 45*time.Second {
+		t.Errorf("IdleTimeout = %v, want 45s", server.IdleTimeout)
+	}
+}
+
+func TestLoad_DefaultServerTimeouts(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.ReadHeaderTimeout <= 0 || cfg.Server.IdleTimeout <= 0 {
+		t.Fatalf("expected non-zero default timeouts, got %+v", cfg.Server)
+	}
+}
+
+func TestFallbackHandlers_ReturnJSONWithCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware(config.CORSConfig{AllowedOrigins: []string{"*"}}))
+	registerFallbackHandlers(router)
+	router.GET("/api/v1/things", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/api/v1/nope", http.StatusNotFound, "NOT_FOUND"},
+		{http.MethodDelete, "/api/v1/things", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Origin", "https://portal.example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s %s: status = %d, want %d", tc.method, tc.path, w.Code, tc.status)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("%s %s: Content-Type = %q, want JSON", tc.method, tc.path, ct)
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") == "" {
+			t.Errorf("%s %s: missing CORS headers", tc.method, tc.path)
+		}
+		var body struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: body is not JSON: %s", tc.method, tc.path, w.Body.String())
+		}
+		if body.Code != tc.code || body.Error == "" {
+			t.Errorf("%s %s: body = %+v, want code %s with a message", tc.method, tc.path, body, tc.code)
+		}
+	}
+}
+
+func TestInitLogger_LevelFromConfig(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name    string
+		cfg     config.Config
+		enabled slog.Level
+		below   slog.Level
+	}{
+		{"defaults", config.Config{}, slog.LevelInfo, slog.LevelDebug},
+		{"debug mode", config.Config{Debug: true}, slog.LevelDebug, slog.LevelDebug - 4},
+		{"explicit level wins over debug", config.Config{Debug: true, Logging: config.LoggingConfig{Level: "warn"}}, slog.LevelWarn, slog.LevelInfo},
+		{"json at info", config.Config{Logging: config.LoggingConfig{Format: "json", Level: "info", OutputPath: "stdout"}}, slog.LevelInfo, slog.LevelDebug},
+	} {
+		logger, closer, err := initLogger(&tc.cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		closer.Close()
+		if !logger.Enabled(ctx, tc.enabled) || logger.Enabled(ctx, tc.below) {
+			t.Errorf("%s: expected %v to be the threshold", tc.name, tc.enabled)
+		}
+	}
+}
+
+func TestVersionEndpoint_ReturnsInjectedValues(t *testing.T) {
+	prev := version.Get()
+	version.Version, version.Commit, version.BuildDate = "1.4.0", "abc1234", "2026-10-16T08:00:00Z"
+	t.Cleanup(func() { version.Version, version.Commit, version.BuildDate = prev.Version, prev.Commit, prev.BuildDate })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/version", versionHandler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var got version.Info
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := version.Info{Version: "1.4.0", Commit: "abc1234", BuildDate: "2026-10-16T08:00:00Z"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestVersionDefaults(t *testing.T) {
+	if version.Version != "dev" || version.Commit != "unknown" || version.BuildDate != "unknown" {
+		t.Errorf("unexpected defaults without -ldflags: %+v", version.Get())
+	}
+}
+
+func corsRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware(config.CORSConfig{
+		AllowedOrigins: []string{"https://portal.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         10 * time.Minute,
+	}))
+	router.GET("/api/v1/things", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestCORS_PreflightIsCached(t *testing.T) {
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/things", nil)
+	req.Header.Set("Origin", "https://portal.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+	w := httptest.NewRecorder()
+	corsRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", w.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://portal.example.com",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Max-Age":       "600",
+		"Vary":                         "Origin",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCORS_RestrictsHeadersMethodsAndOrigins(t *testing.T) {
+	router := corsRouter()
+	for _, tc := range []struct {
+		name, origin, method, headers string
+	}{
+		{"unlisted header", "https://portal.example.com", "GET", "X-Debug"},
+		{"unlisted method", "https://portal.example.com", "DELETE", ""},
+		{"unlisted origin", "https://evil.example.com", "GET", ""},
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/things", nil)
+		req.Header.Set("Origin", tc.origin)
+		req.Header.Set("Access-Control-Request-Method", tc.method)
+		if tc.headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", tc.headers)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s: status = %d, want 204", tc.name, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", tc.name, got)
+		}
+	}
+}
+
+func TestCORS_SameOriginAndNoOriginGetNoHeaders(t *testing.T) {
+	router := corsRouter()
+	for _, origin := range []string{"", "http://example.com"} {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/api/v1/things", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("origin %q: status = %d", origin, w.Code)
+		}
+		if len(w.Header().Values("Access-Control-Allow-Origin")) != 0 || w.Header().Get("Vary") != "" {
+			t.Errorf("origin %q: unexpected CORS headers %v", origin, w.Header())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/things", nil)
+	req.Header.Set("Origin", "https://portal.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://portal.example.com" {
+		t.Errorf("cross-origin GET: Access-Control-Allow-Origin = %q", got)
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "" {
+		t.Error("Access-Control-Max-Age belongs on preflight responses only")
+	}
+}
+
+func TestCORS_RouteGroupsHaveTheirOwnPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := config.CORSConfig{
+		AllowedOrigins: []string{"https://portal.example.com", "https://partner.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+	}
+	auth, public := base, base
+	auth.AllowedOrigins = []string{"https://portal.example.com"}
+	public.AllowedOrigins = []string{"*"}
+	public.AllowedMethods = []string{"GET", "OPTIONS"}
+	public.OmitCredentials = true
+	cfg := base
+	cfg.Routes = map[string]config.CORSConfig{"/auth": auth, "/api/v1/geospatial/maps/": public}
+
+	router := gin.New()
+	router.Use(corsMiddleware(cfg))
+	for _, path := range []string{"/auth/login", "/api/v1/geospatial/maps/static", "/api/v1/projects", "/authors"} {
+		router.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	for _, tc := range []struct {
+		path, origin, method string
+		allowed, credentials bool
+	}{
+		{"/auth/login", "https://portal.example.com", http.MethodPost, true, true},
+		{"/auth/login", "https://partner.example.com", http.MethodPost, false, false},
+		{"/auth/login", "https://maps.example.org", http.MethodPost, false, false},
+		{"/api/v1/geospatial/maps/static", "https://maps.example.org", http.MethodGet, true, false},
+		{"/api/v1/geospatial/maps/static", "https://maps.example.org", http.MethodPost, false, false},
+		{"/api/v1/projects", "https://partner.example.com", http.MethodPost, true, true},
+		{"/api/v1/projects", "https://maps.example.org", http.MethodGet, false, false},
+		{"/authors", "https://partner.example.com", http.MethodGet, true, true}, // not under /auth
+	} {
+		req := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+		req.Header.Set("Origin", tc.origin)
+		req.Header.Set("Access-Control-Request-Method", tc.method)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); (got == tc.origin || got == "*") != tc.allowed {
+			t.Errorf("%s %s from %s: Access-Control-Allow-Origin = %q, want allowed=%v", tc.method, tc.path, tc.origin, got, tc.allowed)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tc.credentials {
+			t.Errorf("%s %s from %s: credentials = %v, want %v", tc.method, tc.path, tc.origin, got, tc.credentials)
+		}
+	}
+}
+
+func TestCORS_WildcardNeverAllowsCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware(config.CORSConfig{
+		AllowedOrigins: []string{"*", "https://portal.example.com"},
+		AllowedMethods: []string{"GET"},
+	}))
+	router.GET("/api/v1/things", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for origin, want := range map[string]struct {
+		allowOrigin string
+		credentials bool
+	}{
+		"https://portal.example.com": {"https://portal.example.com", true},
+		"https://evil.example.com":   {"*", false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/things", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want.allowOrigin {
+			t.Errorf("origin %s: Access-Control-Allow-Origin = %q, want %q", origin, got, want.allowOrigin)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != want.credentials {
+			t.Errorf("origin %s: credentials = %v, want %v", origin, got, want.credentials)
+		}
+	}
+}
+
+func TestLoad_CORSRoutePolicies(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://portal.example.com,https://partner.example.com")
+	t.Setenv("CORS_AUTH_ALLOWED_ORIGINS", "https://portal.example.com")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	auth, ok := cfg.CORS.Routes["/auth"]
+	if !ok || len(auth.AllowedOrigins) != 1 || auth.AllowedOrigins[0] != "https://portal.example.com" || auth.OmitCredentials {
+		t.Errorf("unexpected /auth policy %+v", auth)
+	}
+	maps, ok := cfg.CORS.Routes["/api/v1/geospatial/maps"]
+	if !ok || len(maps.AllowedOrigins) != 1 || maps.AllowedOrigins[0] != "*" || !maps.OmitCredentials {
+		t.Errorf("unexpected public maps policy %+v", maps)
+	}
+	if _, ok := cfg.CORS.Routes["/api/v1/geospatial/tiles"]; ok {
+		t.Error("project tiles need sign-in and must not be a public CORS path")
+	}
+	if len(cfg.CORS.AllowedOrigins) != 2 {
+		t.Errorf("the default policy should keep CORS_ALLOWED_ORIGINS, got %v", cfg.CORS.AllowedOrigins)
+	}
+
+	t.Setenv("CORS_AUTH_ALLOWED_ORIGINS", "*,https://portal.example.com")
+	if cfg, err = config.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if auth := cfg.CORS.Routes["/auth"]; len(auth.AllowedOrigins) != 1 || auth.AllowedOrigins[0] != "https://portal.example.com" {
+		t.Errorf("/auth should drop * and keep the listed origins, got %v", auth.AllowedOrigins)
+	}
+}
+
+func TestTrustedProxies_ResolveClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	configureTrustedProxies(router, []string{"10.0.0.0/8"})
+	router.Use(middleware.RateLimit(middleware.RateLimitConfig{Rate: 0.001, Burst: 1}))
+	router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+	get := func(peer, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		req.RemoteAddr = peer + ":40000"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tc := range []struct {
+		name, peer, forwardedFor, want string
+	}{
+		{"trusted proxy", "10.1.2.3", "203.0.113.7", "203.0.113.7"},
+		{"trusted proxy chain", "10.1.2.3", "198.51.100.9, 10.0.0.5", "198.51.100.9"},
+		{"untrusted peer", "192.0.2.44", "203.0.113.8", "192.0.2.44"},
+	} {
+		w := get(tc.peer, tc.forwardedFor)
+		if w.Code != http.StatusOK || w.Body.String() != tc.want {
+			t.Errorf("%s: got %d %q, want 200 %q", tc.name, w.Code, w.Body.String(), tc.want)
+		}
+	}
+
+	// Rate limiting keys on the resolved IP: each client behind the proxy
+	// has its own bucket.
+	if w := get("10.1.2.3", "203.0.113.7"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("second request from 203.0.113.7: status = %d, want 429", w.Code)
+	}
+	if w := get("10.1.2.3", "203.0.113.99"); w.Code != http.StatusOK {
+		t.Errorf("first request from 203.0.113.99: status = %d, want 200", w.Code)
+	}
+
+	untrusting := gin.New()
+	configureTrustedProxies(untrusting, []string{"not-a-cidr"})
+	untrusting.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = "10.1.2.3:40000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w := httptest.NewRecorder()
+	untrusting.ServeHTTP(w, req)
+	if w.Body.String() != "10.1.2.3" {
+		t.Errorf("invalid proxy list: ClientIP = %q, want the peer address", w.Body.String())
+	}
+}
+
+func TestHealthEndpoint_CachesDatabasePing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pings := 0
+	var pingErr error
+	cache := postgis.NewHealthCache(func(context.Context) error { pings++; return pingErr }, time.Hour)
+	router := gin.New()
+	router.GET("/health", healthCheck(cache.Check))
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return w
+	}
+	for i := 0; i < 5; i++ {
+		if w := get(); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"database":"up"`) {
+			t.Fatalf("expected a healthy response, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	if pings != 1 {
+		t.Errorf("expected rapid checks to share one database ping, got %d", pings)
+	}
+
+	pingErr = errors.New("connection refused")
+	uncached := postgis.NewHealthCache(func(context.Context) error { return pingErr }, 0)
+	router = gin.New()
+	router.GET("/health", healthCheck(uncached.Check))
+	if w := get(); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"database":"down"`) {
+		t.Errorf("expected 503 while the database is down, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSecurityHeaders_LeavePreflightsAlone(t *testing.T) {
+	router := corsRouter()
+	router.Use(middleware.SecurityHeaders(middleware.SecurityHeadersConfig{HSTSMaxAge: time.Hour, NoSniff: true, FrameOptions: "DENY"}))
+	router.GET("/api/v1/other", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/other", nil)
+	req.Header.Set("Origin", "https://portal.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://portal.example.com" {
+		t.Fatalf("preflight: expected 204 with CORS headers, got %d %v", w.Code, w.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/other", nil)
+	req.Header.Set("Origin", "https://portal.example.com")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Header().Get("X-Frame-Options") != "DENY" || w.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Errorf("expected CORS and security headers on the request itself, got %v", w.Header())
+	}
+}
+
+func TestIsLoginAttempt(t *testing.T) {
+	cases := []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodPost, "/auth/login", true},
+		{http.MethodGet, "/auth/login", false},
+		{http.MethodPost, "/auth/register", false},
+		{http.MethodGet, "/auth/sessions", false},
+		{http.MethodPost, "/api/v1/projects", false},
+	}
+	for _, tc := range cases {
+		if got := isLoginAttempt(httptest.NewRequest(tc.method, tc.path, nil)); got != tc.want {
+			t.Errorf("isLoginAttempt(%s %s) = %v, want %v", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+)
+
+// TokenPurge counts the rows deleted by one cleanup pass.
+type TokenPurge struct {
+	RefreshTokens int64
+	Sessions      int64
+	// Users counts deleted accounts erased after their grace period.
+	Users int64
+}
+
+// PurgeExpiredTokens deletes refresh tokens and sessions that can no longer
+// be used, expired or revoked ones, then erases accounts deleted longer ago
+// than the deletion grace period. The two steps fail independently, so an
+// account that cannot be erased never holds up token cleanup.
+func (s *AuthService) PurgeExpiredTokens(ctx context.Context) (TokenPurge, error) {
+	now := s.now()
+	purged, err := s.repo.PurgeTokens(ctx, now)
+	users, userErr := s.purgeDeletedUsers(ctx, now.Add(-s.deletionGrace))
+	purged.Users = users
+	return purged, errors.Join(err, userErr)
+}
+
+// purgeDeletedUsers erases the users deleted before deletedBefore one at a
+// time. A user that cannot be erased is logged and retried on the next pass
+// while the rest go ahead.
+func (s *AuthService) purgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ids, err := s.repo.ListPurgeableUsers(ctx, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	var purged int64
+	for _, id := range ids {
+		if err := s.repo.PurgeUser(ctx, id); err != nil {
+			if ctx.Err() != nil {
+				return purged, ctx.Err()
+			}
+			logging.FromContext(ctx).Warn("deleted account could not be erased", "user_id", id, "error", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunTokenCleanup purges expired tokens now and then every interval until ctx
+// is done. Run it on one replica only, for example under a postgis.Singleton.
+func RunTokenCleanup(ctx context.Context, s *AuthService, interval time.Duration) {
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := s.PurgeExpiredTokens(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("token cleanup failed", "error", err)
+		} else if err == nil {
+			logger.Info("token cleanup", "refresh_tokens_purged", purged.RefreshTokens, "sessions_purged", purged.Sessions, "users_purged", purged.Users)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPurgeExpiredTokens_RemovesDeadRowsOnly(t *testing.T) {
+	service, repo, login := loginForRefresh(t)
+	ctx := context.Background()
+	if _, err := service.Refresh(ctx, login.RefreshToken, ClientInfo{}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	now := time.Now()
+	past, earlier := now.Add(-time.Hour), now.Add(-2*time.Hour)
+	repo.CreateSession(ctx, &Session{ID: "expired-family", UserID: "u", ExpiresAt: past})
+	repo.CreateRefreshToken(ctx, &RefreshToken{TokenHash: "expired-token-hash", FamilyID: "expired-family", ExpiresAt: past})
+	repo.CreateSession(ctx, &Session{ID: "revoked-family", UserID: "u", ExpiresAt: now.Add(time.Hour), RevokedAt: &earlier})
+	repo.CreateRefreshToken(ctx, &RefreshToken{TokenHash: "revoked-token-hash", FamilyID: "revoked-family", ExpiresAt: now.Add(time.Hour), RevokedAt: &earlier})
+
+	purged, err := service.PurgeExpiredTokens(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpiredTokens: %v", err)
+	}
+	if purged.RefreshTokens != 2 || purged.Sessions != 2 {
+		t.Errorf("expected 2 refresh tokens and 2 sessions purged, got %+v", purged)
+	}
+	if len(repo.refreshTokens) != 2 || len(repo.sessions) != 1 {
+		t.Errorf("expected the live family's 2 tokens and session to remain, have %d tokens and %d sessions",
+			len(repo.refreshTokens), len(repo.sessions))
+	}
+
+	// The rotated-out token is kept until it expires, so replaying it is
+	// still caught as reuse.
+	if _, err := service.Refresh(ctx, login.RefreshToken, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("expected ErrRefreshTokenReused after cleanup, got %v", err)
+	}
+}
+
+func TestRunTokenCleanup_StopsOnCancel(t *testing.T) {
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	past := time.Now().Add(-time.Hour)
+	repo.CreateRefreshToken(context.Background(), &RefreshToken{TokenHash: "expired-token-hash", FamilyID: "f", ExpiresAt: past})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunTokenCleanup(ctx, service, time.Hour)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		repo.mu.Lock()
+		remaining := len(repo.refreshTokens)
+		repo.mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the first pass to run immediately")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected RunTokenCleanup to return once its context is cancelled")
+	}
+}
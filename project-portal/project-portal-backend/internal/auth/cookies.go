@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cookie names used when tokens are handed to browsers as cookies.
+const (
+	AccessTokenCookie  = "cs_access_token"
+	RefreshTokenCookie = "cs_refresh_token"
+)
+
+// refreshCookiePath limits the refresh cookie to the group RegisterRoutes
+// mounts, so it is not sent with every API call.
+const refreshCookiePath = "/auth"
+
+// SetTokenCookies makes Login and Refresh set the token cookies on every
+// response, not only when the client asks with ?cookie=true.
+func (h *Handler) SetTokenCookies(always bool) {
+	h.alwaysCookies = always
+}
+
+// wantsCookies reports whether the token pair should also go out as cookies.
+func (h *Handler) wantsCookies(c *gin.Context) bool {
+	if h.alwaysCookies {
+		return true
+	}
+	want, _ := strconv.ParseBool(c.Query("cookie"))
+	return want
+}
+
+// setTokenCookies sets the access and refresh tokens as HttpOnly, Secure,
+// SameSite=Strict cookies, so browser code never handles them. The refresh
+// token is only sent back to the auth routes.
+func setTokenCookies(c *gin.Context, resp *LoginResponse) {
+	http.SetCookie(c.Writer, tokenCookie(AccessTokenCookie, resp.Token, "/", int(resp.ExpiresIn)))
+	http.SetCookie(c.Writer, tokenCookie(RefreshTokenCookie, resp.RefreshToken, refreshCookiePath, int(jwtConfig.RefreshTTL.Seconds())))
+}
+
+// clearTokenCookies expires both token cookies, as on logout.
+func clearTokenCookies(c *gin.Context) {
+	http.SetCookie(c.Writer, tokenCookie(AccessTokenCookie, "", "/", -1))
+	http.SetCookie(c.Writer, tokenCookie(RefreshTokenCookie, "", refreshCookiePath, -1))
+}
+
+func tokenCookie(name, value, path string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultDeletionGrace is how long a deleted account is kept before the
+// cleanup job erases it.
+const DefaultDeletionGrace = 30 * 24 * time.Hour
+
+// ErrOwnsProjects refuses to delete an account that still owns projects; they
+// must be transferred or deleted first.
+var ErrOwnsProjects = errors.New("transfer or delete your projects before deleting your account")
+
+// DeleteAccountRequest re-confirms the password before an account is deleted.
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required,max=128"`
+}
+
+// AccountDeletion reports when a deleted account will be erased for good.
+type AccountDeletion struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// SetDeletionGrace sets how long deleted accounts are kept before
+// PurgeExpiredTokens erases them. 0 erases them on the next cleanup pass.
+func (s *AuthService) SetDeletionGrace(grace time.Duration) error {
+	if grace < 0 {
+		return fmt.Errorf("deletion grace period must not be negative, got %s", grace)
+	}
+	s.deletionGrace = grace
+	return nil
+}
+
+// DeleteAccount deletes userID's own account once password matches. The
+// account is soft-deleted at once: it can no longer sign in, and its refresh
+// tokens, sessions and API keys are revoked while its token version moves on,
+// so access tokens already issued are rejected too. The row itself, and with
+// it the email address, is kept for the grace period and then erased by the
+// cleanup job. An account that owns live projects is refused with
+// ErrOwnsProjects.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID string, req DeleteAccountRequest) (*AccountDeletion, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPassword(req.Password, user.PasswordHash); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	owned, err := s.repo.CountOwnedProjects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if owned > 0 {
+		return nil, fmt.Errorf("%w (%d owned)", ErrOwnsProjects, owned)
+	}
+
+	now := s.now()
+	if err := s.repo.SoftDeleteUser(ctx, userID, now); err != nil {
+		return nil, err
+	}
+	s.revoked(RevokedAccountDeletion)
+	return &AccountDeletion{DeletedAt: now, PurgeAt: now.Add(s.deletionGrace)}, nil
+}
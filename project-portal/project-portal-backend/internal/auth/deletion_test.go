@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (m *memoryRepo) CountOwnedProjects(_ context.Context, userID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ownedProjects[userID], nil
+}
+
+func (m *memoryRepo) SoftDeleteUser(ctx context.Context, id string, at time.Time) error {
+	m.mu.Lock()
+	u, ok := m.users[id]
+	if !ok || u.DeletedAt.Valid {
+		m.mu.Unlock()
+		return gorm.ErrRecordNotFound
+	}
+	u.IsActive = false
+	u.TokenVersion++
+	u.DeletedAt.Time, u.DeletedAt.Valid = at, true
+	m.mu.Unlock()
+	return m.RevokeUserTokens(ctx, id, at)
+}
+
+func (m *memoryRepo) ListPurgeableUsers(_ context.Context, deletedBefore time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, u := range m.users {
+		if u.DeletedAt.Valid && u.DeletedAt.Time.Before(deletedBefore) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryRepo) PurgeUser(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.purgeErrs[id]; err != nil {
+		return err
+	}
+	if m.ownedProjects[id] > 0 {
+		return ErrOwnsProjects
+	}
+	delete(m.users, id)
+	return nil
+}
+
+func TestDeleteMe_RequiresPasswordAndSignsOut(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	service := NewAuthService(newMemoryRepo())
+	CheckTokenVersions(service)
+	t.Cleanup(func() { CheckTokenVersions(nil) })
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(service))
+
+	ctx := context.Background()
+	if _, err := service.Register(ctx, RegisterRequest{Email: "leaving@example.com", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	session, err := service.Login(ctx, LoginRequest{Email: "leaving@example.com", Password: "correct horse battery"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	deleteMe := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/auth/me", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := deleteMe(session.Token, `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("no password: expected 400, got %d", w.Code)
+	}
+	if w := deleteMe(session.Token, `{"password":"wrong password"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: expected 401, got %d", w.Code)
+	}
+	if _, err := service.Login(ctx, LoginRequest{Email: "leaving@example.com", Password: "correct horse battery"}, ClientInfo{}); err != nil {
+		t.Fatalf("a refused deletion must leave the account usable: %v", err)
+	}
+
+	w := deleteMe(session.Token, `{"password":"correct horse battery"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var deletion AccountDeletion
+	_ = json.Unmarshal(w.Body.Bytes(), &deletion)
+	if got := deletion.PurgeAt.Sub(deletion.DeletedAt); got != DefaultDeletionGrace {
+		t.Errorf("expected erasure after the default grace period, got %s", got)
+	}
+
+	if _, err := service.Login(ctx, LoginRequest{Email: "leaving@example.com", Password: "correct horse battery"}, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("login after deletion: expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := service.Refresh(ctx, session.RefreshToken, ClientInfo{}); err == nil {
+		t.Error("refresh after deletion: expected the refresh token to be revoked")
+	}
+	if w := deleteMe(session.Token, `{"password":"correct horse battery"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("access token after deletion: expected 401, got %d", w.Code)
+	}
+}
+
+func TestDeleteAccount_OwnsProjectsIsConflict(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(service))
+
+	ctx := context.Background()
+	user, err := service.Register(ctx, RegisterRequest{Email: "owner@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	repo.ownedProjects = map[string]int64{user.ID: 2}
+	token, _ := GenerateJWT(user)
+
+	req := httptest.NewRequest(http.MethodDelete, "/auth/me", strings.NewReader(`{"password":"correct horse battery"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "transfer") {
+		t.Fatalf("expected 409 asking for a transfer, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := service.Login(ctx, LoginRequest{Email: "owner@example.com", Password: "correct horse battery"}, ClientInfo{}); err != nil {
+		t.Errorf("a blocked deletion must leave the account usable: %v", err)
+	}
+
+	delete(repo.ownedProjects, user.ID)
+	if _, err := service.DeleteAccount(ctx, user.ID, DeleteAccountRequest{Password: "correct horse battery"}); err != nil {
+		t.Errorf("after transferring the projects: %v", err)
+	}
+}
+
+func TestPurgeExpiredTokens_ErasesDeletedAccountsAfterGrace(t *testing.T) {
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	if err := service.SetDeletionGrace(-time.Hour); err == nil {
+		t.Error("expected a negative grace period to be refused")
+	}
+	if err := service.SetDeletionGrace(48 * time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	user, err := service.Register(ctx, RegisterRequest{Email: "gone@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	deletion, err := service.DeleteAccount(ctx, user.ID, DeleteAccountRequest{Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if !deletion.PurgeAt.Equal(now.Add(48 * time.Hour)) {
+		t.Errorf("expected erasure 48h on, got %s", deletion.PurgeAt)
+	}
+
+	// Within the grace period the row stays, and with it the email address.
+	now = now.Add(24 * time.Hour)
+	if purged, err := service.PurgeExpiredTokens(ctx); err != nil || purged.Users != 0 {
+		t.Fatalf("within the grace period: expected nothing erased, got %+v (%v)", purged, err)
+	}
+	if _, ok := repo.users[user.ID]; !ok {
+		t.Fatal("expected the deleted account to be kept during the grace period")
+	}
+	if _, err := service.Register(ctx, RegisterRequest{Email: "gone@example.com", Password: "another horse battery"}); err == nil {
+		t.Error("expected the email address to stay taken during the grace period")
+	}
+
+	now = now.Add(25 * time.Hour)
+	if purged, err := service.PurgeExpiredTokens(ctx); err != nil || purged.Users != 1 {
+		t.Fatalf("after the grace period: expected one account erased, got %+v (%v)", purged, err)
+	}
+	if _, ok := repo.users[user.ID]; ok {
+		t.Error("expected the account to be erased")
+	}
+	if _, err := service.Register(ctx, RegisterRequest{Email: "gone@example.com", Password: "another horse battery"}); err != nil {
+		t.Errorf("expected the email address to be free again: %v", err)
+	}
+}
+
+func TestPurgeExpiredTokens_OneStuckAccountBlocksNothingElse(t *testing.T) {
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	service.SetDeletionGrace(0)
+	ctx := context.Background()
+
+	var ids []string
+	for _, email := range []string{"stuck@example.com", "gone@example.com"} {
+		user, err := service.Register(ctx, RegisterRequest{Email: email, Password: "correct horse battery"})
+		if err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		if _, err := service.DeleteAccount(ctx, user.ID, DeleteAccountRequest{Password: "correct horse battery"}); err != nil {
+			t.Fatalf("DeleteAccount: %v", err)
+		}
+		ids = append(ids, user.ID)
+	}
+	repo.purgeErrs = map[string]error{ids[0]: errors.New("violates foreign key constraint")}
+	repo.CreateRefreshToken(ctx, &RefreshToken{TokenHash: "expired-token-hash", FamilyID: "f", ExpiresAt: time.Now().Add(-time.Hour)})
+
+	service.now = func() time.Time { return time.Now().Add(time.Minute) }
+	purged, err := service.PurgeExpiredTokens(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpiredTokens: %v", err)
+	}
+	if purged.Users != 1 || purged.RefreshTokens != 1 {
+		t.Errorf("expected the other account and the expired token purged, got %+v", purged)
+	}
+	if _, ok := repo.users[ids[0]]; !ok {
+		t.Error("expected the stuck account to be kept for the next pass")
+	}
+	if _, ok := repo.users[ids[1]]; ok {
+		t.Error("expected the other account to be erased")
+	}
+}
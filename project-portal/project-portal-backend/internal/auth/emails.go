@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// EmailNormalization controls how email addresses are normalized before they
+// are stored or looked up. Surrounding whitespace is always trimmed.
+// Lowercase stores addresses lowercased; lookups match case-insensitively
+// either way. NFC composes Unicode characters, so an address typed with a
+// combining accent matches the same address typed precomposed.
+type EmailNormalization struct {
+	Lowercase bool
+	NFC       bool
+}
+
+// DefaultEmailNormalization trims and lowercases.
+var DefaultEmailNormalization = EmailNormalization{Lowercase: true}
+
+// Normalize returns email in the form it is stored and looked up in.
+func (n EmailNormalization) Normalize(email string) string {
+	email = strings.TrimSpace(email)
+	if n.NFC {
+		email = norm.NFC.String(email)
+	}
+	if n.Lowercase {
+		email = strings.ToLower(email)
+	}
+	return email
+}
+
+// SetEmailNormalization sets how emails are normalized on registration,
+// account creation, import and login.
+func (s *AuthService) SetEmailNormalization(n EmailNormalization) {
+	s.emails = n
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEmailNormalization_Normalize(t *testing.T) {
+	cases := []struct {
+		n    EmailNormalization
+		in   string
+		want string
+	}{
+		{DefaultEmailNormalization, "  User@Example.com \t", "user@example.com"},
+		{EmailNormalization{}, " User@Example.com ", "User@Example.com"},
+		{EmailNormalization{NFC: true}, "Rene\u0301@example.com", "Ren\u00e9@example.com"},
+		{EmailNormalization{Lowercase: true, NFC: true}, " RENE\u0301@Example.com", "ren\u00e9@example.com"},
+		{DefaultEmailNormalization, "rene\u0301@example.com", "rene\u0301@example.com"},
+	}
+	for _, tc := range cases {
+		if got := tc.n.Normalize(tc.in); got != tc.want {
+			t.Errorf("%+v.Normalize(%q) = %q, want %q", tc.n, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestEmailNormalization_RegisterMixedCaseLoginAnyCase(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(NewAuthService(newMemoryRepo())))
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := post("/auth/register", `{"email":"  Mixed.Case@Example.COM ","password":"correct horse battery"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var user User
+	if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil || user.Email != "mixed.case@example.com" {
+		t.Fatalf("expected the email stored normalized, got %q (%v)", user.Email, err)
+	}
+
+	for _, body := range []string{
+		`{"email":"mixed.case@example.com","password":"correct horse battery"}`,
+		`{"email":" MIXED.CASE@EXAMPLE.COM  ","password":"correct horse battery"}`,
+		`{"identifier":"\tMixed.Case@example.com\n","password":"correct horse battery"}`,
+	} {
+		if w := post("/auth/login", body); w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	if w := post("/auth/register", `{"email":"MIXED.case@example.com ","password":"correct horse battery"}`); w.Code != http.StatusConflict {
+		t.Errorf("same address in another case: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestEmailNormalization_Configurable(t *testing.T) {
+	ctx := context.Background()
+	service := NewAuthService(newMemoryRepo())
+	service.SetEmailNormalization(EmailNormalization{NFC: true})
+
+	user, err := service.Register(ctx, RegisterRequest{Email: " Rene\u0301@Example.com ", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Email != "Ren\u00e9@Example.com" {
+		t.Errorf("expected the address trimmed and composed but not lowercased, got %q", user.Email)
+	}
+
+	// Lookups still ignore case, and the decomposed spelling finds the account.
+	for _, email := range []string{"rene\u0301@example.com", "REN\u00c9@EXAMPLE.COM"} {
+		if _, err := service.Login(ctx, LoginRequest{Email: email, Password: "correct horse battery"}, ClientInfo{}); err != nil {
+			t.Errorf("login as %q: %v", email, err)
+		}
+	}
+	if _, err := service.Register(ctx, RegisterRequest{Email: "ren\u00e9@example.com", Password: "correct horse battery"}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken for the same address in another case, got %v", err)
+	}
+}
@@ -1,24 +1,353 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/apierror"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/validation"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-type Handler struct{}
+// maxImportBodyBytes caps bulk user import bodies.
+const maxImportBodyBytes = 2 << 20
+
+type Handler struct {
+	service Authenticator
+	// alwaysCookies sets token cookies without ?cookie=true; see
+	// SetTokenCookies.
+	alwaysCookies bool
+}
+
+func NewHandler(service Authenticator) *Handler {
+	return &Handler{service: service}
+}
 
-// Ping endpoint
+// Ping reports whether the auth service can reach its store. It answers 503
+// with db false when it cannot.
 func (h *Handler) Ping(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "auth service alive!"})
+	if err := h.service.CheckStore(c.Request.Context()); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("auth ping: store unreachable", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"service": "auth", "status": "degraded", "db": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"service": "auth", "status": "ok", "db": true})
 }
 
-// Dummy register endpoint
 func (h *Handler) Register(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "register endpoint works"})
+	var req RegisterRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.service.Register(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, ErrInvalidUsername), errors.Is(err, ErrOrganizationChoice), errors.Is(err, ErrInvalidJoinCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// CreateUser lets an administrator create an account with a whitelisted role.
+func (h *Handler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.service.CreateUser(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, ErrRoleNotAllowed), errors.Is(err, ErrInvalidUsername):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// ImportUsers creates users in bulk from a JSON array of {email, role,
+// full_name, username} or, with Content-Type text/csv, a CSV with those
+// columns. Imported users join the administrator's organization. It answers
+// 201 when every row was created and 207 with the per-row results otherwise.
+func (h *Handler) ImportUsers(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+
+	var rows []ImportUserRow
+	if c.ContentType() == "text/csv" {
+		var err error
+		if rows, err = ParseUserCSV(c.Request.Body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if err := c.ShouldBindJSON(&rows); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must be a JSON array of users or a CSV file", "errors": validation.Errors(err)})
+		return
+	}
+	if len(rows) == 0 || len(rows) > MaxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("an import must hold 1 to %d users", MaxImportRows)})
+		return
+	}
+
+	result, err := h.service.ImportUsers(c.Request.Context(), c.GetString("org_id"), rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logging.FromContext(c.Request.Context()).Info("users imported",
+		"actor_id", c.GetString("user_id"), "created", result.Created, "skipped", result.Skipped, "failed", result.Failed)
+
+	status := http.StatusCreated
+	if result.Created < len(rows) {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, result)
+}
+
+// ListUsers lets an administrator page through accounts, optionally filtered
+// by ?role= and ?verified=.
+func (h *Handler) ListUsers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	filter := UserFilter{Limit: limit, Offset: offset, Role: c.Query("role")}
+	if v := c.Query("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "verified must be true or false"})
+			return
+		}
+		filter.Verified = &verified
+	}
+
+	users, total, err := h.service.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"users": users, "count": len(users), "total": total})
+}
+
+// GetUser returns one user to an administrator.
+func (h *Handler) GetUser(c *gin.Context) {
+	user, err := h.service.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, err, "user")
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser lets an administrator change a user's role or deactivate them.
+func (h *Handler) UpdateUser(c *gin.Context) {
+	var req UpdateUserRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.service.UpdateUser(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req)
+	switch {
+	case errors.Is(err, ErrRoleNotAllowed), errors.Is(err, ErrSelfDeactivation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user)
 }
 
-// Dummy login endpoint
 func (h *Handler) Login(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "login endpoint works"})
+	var req LoginRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+	if req.identifier() == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "identifier or email is required"})
+		return
+	}
+
+	resp, err := h.service.Login(c.Request.Context(), req, clientInfo(c))
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrInactiveUser), errors.Is(err, ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrSessionLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if h.wantsCookies(c) {
+		setTokenCookies(c, resp)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Refresh takes the refresh token from the body or, for browser clients
+// signed in with cookies, from the refresh token cookie.
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if c.Request.ContentLength != 0 && !validation.BindJSON(c, &req) {
+		return
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(RefreshTokenCookie)
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	resp, err := h.service.Refresh(c.Request.Context(), req.RefreshToken, clientInfo(c))
+	switch {
+	case errors.Is(err, ErrInvalidRefreshToken), errors.Is(err, ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrInactiveUser):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if h.wantsCookies(c) {
+		setTokenCookies(c, resp)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.service.CreateAPIKey(c.Request.Context(), c.GetString("user_id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys, "count": len(keys)})
+}
+
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	err := h.service.RevokeAPIKey(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+}
+
+// GetOrganization returns the signed-in user's organization, including the
+// join code new members register with.
+func (h *Handler) GetOrganization(c *gin.Context) {
+	org, err := h.service.Organization(c.Request.Context(), c.GetString("org_id"))
+	switch {
+	case errors.Is(err, ErrNoOrganization), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// ListSessions lists the signed-in user's active sessions.
+func (h *Handler) ListSessions(c *gin.Context) {
+	sessions, err := h.service.ListSessions(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "count": len(sessions)})
+}
+
+// RevokeSession signs out one of the user's sessions. Revoking the session
+// the request was made from is a logout, which also clears token cookies.
+func (h *Handler) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	err := h.service.RevokeSession(c.Request.Context(), c.GetString("user_id"), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if id == c.GetString("session_id") {
+		clearTokenCookies(c)
+		c.JSON(http.StatusOK, gin.H{"message": "logged out", "current": true})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked", "current": false})
+}
+
+// DeleteMe deletes the signed-in user's account after re-confirming their
+// password, and signs them out.
+func (h *Handler) DeleteMe(c *gin.Context) {
+	var req DeleteAccountRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	deletion, err := h.service.DeleteAccount(c.Request.Context(), c.GetString("user_id"), req)
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "password is incorrect"})
+		return
+	case errors.Is(err, ErrOwnsProjects):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	clearTokenCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted", "deleted_at": deletion.DeletedAt, "purge_at": deletion.PurgeAt})
+}
+
+func clientInfo(c *gin.Context) ClientInfo {
+	return ClientInfo{UserAgent: c.Request.UserAgent(), IPAddress: c.ClientIP()}
 }
@@ -0,0 +1,553 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegister_FieldLevelValidationErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(NewAuthService(newMemoryRepo())))
+
+	body := `{"email":"not-an-email","password":"short","full_name":"` + strings.Repeat("x", 201) + `"}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body)))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Errors []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	got := map[string]string{}
+	for _, e := range resp.Errors {
+		got[e.Field] = e.Message
+	}
+	for _, field := range []string{"email", "password", "full_name"} {
+		if got[field] == "" {
+			t.Errorf("expected an error for %s, got %v", field, got)
+		}
+	}
+}
+
+func TestCreateUser_OffWhitelistRoleIsBadRequest(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(NewAuthService(newMemoryRepo())))
+
+	post := func(token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/users", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	admin, _ := GenerateJWT(&User{ID: "admin-1", Email: "admin@example.com", Role: "admin"})
+	member, _ := GenerateJWT(&User{ID: "user-1", Email: "user@example.com", Role: "user"})
+
+	if code := post(admin, `{"email":"root2@example.com","password":"correct horse battery","role":"superuser"}`); code != http.StatusBadRequest {
+		t.Errorf("unknown role: expected 400, got %d", code)
+	}
+	if code := post(admin, `{"email":"root@example.com","password":"correct horse battery","role":"admin"}`); code != http.StatusBadRequest {
+		t.Errorf("admin role: expected 400, got %d", code)
+	}
+	if code := post(admin, `{"email":"checker@example.com","password":"correct horse battery","role":"verifier"}`); code != http.StatusCreated {
+		t.Errorf("verifier role: expected 201, got %d", code)
+	}
+	if code := post(member, `{"email":"other@example.com","password":"correct horse battery"}`); code != http.StatusForbidden {
+		t.Errorf("non-admin caller: expected 403, got %d", code)
+	}
+}
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	service := NewAuthService(newMemoryRepo())
+	RegisterRoutes(router, NewHandler(service))
+	if _, err := service.Register(context.Background(), RegisterRequest{Email: "devices@example.com", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	send := func(method, path, token, userAgent, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if userAgent != "" {
+			req.Header.Set("User-Agent", userAgent)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(userAgent string) LoginResponse {
+		w := send(http.MethodPost, "/auth/login", "", userAgent, `{"email":"devices@example.com","password":"correct horse battery"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("login: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp LoginResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+	laptop := login("laptop-browser")
+	phone := login("phone-app")
+
+	w := send(http.MethodGet, "/auth/sessions", laptop.Token, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var list struct {
+		Sessions []Session `json:"sessions"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", list.Sessions)
+	}
+	var phoneID string
+	for _, s := range list.Sessions {
+		if s.UserAgent == "phone-app" {
+			phoneID = s.ID
+			if s.Current {
+				t.Error("expected the phone session not to be flagged current")
+			}
+		} else if !s.Current {
+			t.Errorf("expected the laptop session to be flagged current, got %+v", s)
+		}
+	}
+	if phoneID == "" {
+		t.Fatalf("expected a session with the phone's user agent, got %+v", list.Sessions)
+	}
+
+	if w := send(http.MethodDelete, "/auth/sessions/"+phoneID, laptop.Token, "", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"current":false`) {
+		t.Fatalf("revoke: expected 200 for another session, got %d: %s", w.Code, w.Body.String())
+	}
+	refresh := func(token string) int {
+		return send(http.MethodPost, "/auth/refresh", "", "", `{"refresh_token":"`+token+`"}`).Code
+	}
+	if code := refresh(phone.RefreshToken); code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked session's refresh token to be rejected, got %d", code)
+	}
+	if code := refresh(laptop.RefreshToken); code != http.StatusOK {
+		t.Fatalf("expected the other session's refresh token to still work, got %d", code)
+	}
+	if w := send(http.MethodDelete, "/auth/sessions/"+phoneID, laptop.Token, "", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 revoking an already revoked session, got %d", w.Code)
+	}
+}
+
+func TestSessions_RevokingCurrentSessionLogsOut(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	service := NewAuthService(newMemoryRepo())
+	RegisterRoutes(router, NewHandler(service))
+	if _, err := service.Register(context.Background(), RegisterRequest{Email: "self@example.com", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	login, err := service.Login(context.Background(), LoginRequest{Email: "self@example.com", Password: "correct horse battery"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	claims, err := ValidateJWT(login.Token)
+	if err != nil || claims.SessionID == "" {
+		t.Fatalf("expected the access token to carry a session id, got %+v (%v)", claims, err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/auth/sessions/"+claims.SessionID, nil)
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "logged out") {
+		t.Fatalf("expected a logout response, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := service.Refresh(context.Background(), login.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the refresh token to be invalid after logout, got %v", err)
+	}
+}
+
+func TestAdminUsers_DeactivateBlocksLogin(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	service := NewAuthService(newMemoryRepo())
+	RegisterRoutes(router, NewHandler(service))
+
+	target, err := service.Register(context.Background(), RegisterRequest{Email: "leaver@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := service.Register(context.Background(), RegisterRequest{Email: "stayer@example.com", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	session, err := service.Login(context.Background(), LoginRequest{Email: "leaver@example.com", Password: "correct horse battery"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	admin, _ := GenerateJWT(&User{ID: "admin-1", Email: "admin@example.com", Role: "admin"})
+	member, _ := GenerateJWT(&User{ID: target.ID, Email: target.Email, Role: "user"})
+
+	if w := send(http.MethodGet, "/auth/users", member, ""); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin list: expected 403, got %d", w.Code)
+	}
+	if w := send(http.MethodPatch, "/auth/users/"+target.ID, member, `{"active":false}`); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin update: expected 403, got %d", w.Code)
+	}
+
+	w := send(http.MethodGet, "/auth/users?role=user&verified=false&limit=1", admin, "")
+	var page struct {
+		Users []User `json:"users"`
+		Total int64  `json:"total"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if w.Code != http.StatusOK || len(page.Users) != 1 || page.Total != 2 {
+		t.Fatalf("list: expected one of two users, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = send(http.MethodPatch, "/auth/users/"+target.ID, admin, `{"active":false}`)
+	var updated User
+	_ = json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.IsActive {
+		t.Fatalf("deactivate: expected 200 with is_active false, got %d: %s", w.Code, w.Body.String())
+	}
+	if stored, _ := service.GetUser(context.Background(), target.ID); stored == nil || stored.TokenVersion != 1 {
+		t.Errorf("deactivate: expected the token version to be bumped, got %+v", stored)
+	}
+
+	if _, err := service.Login(context.Background(), LoginRequest{Email: "leaver@example.com", Password: "correct horse battery"}, ClientInfo{}); !errors.Is(err, ErrInactiveUser) {
+		t.Errorf("expected login to fail with ErrInactiveUser, got %v", err)
+	}
+	if _, err := service.Refresh(context.Background(), session.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected the existing refresh token to be revoked, got %v", err)
+	}
+
+	if w := send(http.MethodPatch, "/auth/users/"+target.ID, admin, `{"role":"admin"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("promote to admin: expected 400, got %d", w.Code)
+	}
+	if w := send(http.MethodPatch, "/auth/users/admin-1", admin, `{"active":false}`); w.Code != http.StatusBadRequest {
+		t.Errorf("self-deactivation: expected 400, got %d", w.Code)
+	}
+	if w := send(http.MethodPatch, "/auth/users/missing", admin, `{"role":"verifier"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown user: expected 404, got %d", w.Code)
+	}
+}
+
+func TestAdminUsers_GetUnknownUserIsNotFound(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	service := NewAuthService(newMemoryRepo())
+	RegisterRoutes(router, NewHandler(service))
+
+	target, err := service.Register(context.Background(), RegisterRequest{Email: "known@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	admin, _ := GenerateJWT(&User{ID: "admin-1", Email: "admin@example.com", Role: "admin"})
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth/users/"+id, nil)
+		req.Header.Set("Authorization", "Bearer "+admin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(target.ID)
+	var got User
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || got.Email != "known@example.com" {
+		t.Fatalf("known user: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, id := range []string{"7b0c1e52-3f7d-4c55-9a43-2f3e8d0b6a11", "not-a-uuid"} {
+		w := get(id)
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusNotFound || body.Error != "user not found" {
+			t.Errorf("%s: expected 404 user not found, got %d: %s", id, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAdminUsers_RoleChangeSupersedesAccessTokens(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	CheckTokenVersions(service)
+	t.Cleanup(func() { CheckTokenVersions(nil) })
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(service))
+
+	_ = repo.CreateUser(context.Background(), &User{ID: "admin-1", Email: "admin@example.com", Role: "admin", IsActive: true})
+	target, err := service.Register(context.Background(), RegisterRequest{Email: "promoted@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	session, err := service.Login(context.Background(), LoginRequest{Email: "promoted@example.com", Password: "correct horse battery"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	admin, _ := GenerateJWT(&User{ID: "admin-1", Email: "admin@example.com", Role: "admin"})
+
+	if w := send(http.MethodGet, "/auth/sessions", session.Token, ""); w.Code != http.StatusOK {
+		t.Fatalf("before the change: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	// Re-sending the current role changes nothing and keeps tokens valid.
+	if w := send(http.MethodPatch, "/auth/users/"+target.ID, admin, `{"role":"user"}`); w.Code != http.StatusOK {
+		t.Fatalf("same role: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodGet, "/auth/sessions", session.Token, ""); w.Code != http.StatusOK {
+		t.Fatalf("after a no-op update: expected 200, got %d", w.Code)
+	}
+
+	if w := send(http.MethodPatch, "/auth/users/"+target.ID, admin, `{"role":"verifier"}`); w.Code != http.StatusOK {
+		t.Fatalf("role change: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w := send(http.MethodGet, "/auth/sessions", session.Token, "")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), "invalid_token") {
+		t.Fatalf("old token: expected 401 invalid_token, got %d (%q)", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+
+	w = send(http.MethodPost, "/auth/refresh", "", `{"refresh_token":"`+session.RefreshToken+`"}`)
+	var refreshed LoginResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &refreshed)
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	claims, err := ValidateJWT(refreshed.Token)
+	if err != nil || claims.Role != "verifier" {
+		t.Fatalf("expected the refreshed token to carry the new role, got %+v, %v", claims, err)
+	}
+	if w := send(http.MethodGet, "/auth/sessions", refreshed.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("refreshed token: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLogin_TokenCookiesAuthenticateWithoutHeader(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	service := NewAuthService(newMemoryRepo())
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(service))
+	if _, err := service.Register(context.Background(), RegisterRequest{Email: "browser@example.com", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	send := func(method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	cookiesOf := func(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+		out := map[string]*http.Cookie{}
+		for _, cookie := range w.Result().Cookies() {
+			out[cookie.Name] = cookie
+		}
+		return out
+	}
+	login := `{"email":"browser@example.com","password":"correct horse battery"}`
+
+	if w := send(http.MethodPost, "/auth/login", login); len(w.Result().Cookies()) != 0 {
+		t.Errorf("expected no cookies without ?cookie=true, got %v", w.Header().Values("Set-Cookie"))
+	}
+
+	w := send(http.MethodPost, "/auth/login?cookie=true", login)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	cookies := cookiesOf(w)
+	access, refresh := cookies[AccessTokenCookie], cookies[RefreshTokenCookie]
+	if access == nil || refresh == nil {
+		t.Fatalf("expected both token cookies, got %v", w.Header().Values("Set-Cookie"))
+	}
+	for _, cookie := range []*http.Cookie{access, refresh} {
+		if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode || cookie.MaxAge <= 0 {
+			t.Errorf("%s: expected HttpOnly, Secure, SameSite=Strict with a max age, got %+v", cookie.Name, cookie)
+		}
+	}
+	if access.Path != "/" || refresh.Path != "/auth" || access.MaxAge < 59 || access.MaxAge > 60 {
+		t.Errorf("unexpected cookie scope: access %s %ds, refresh %s", access.Path, access.MaxAge, refresh.Path)
+	}
+
+	if w := send(http.MethodGet, "/auth/sessions", "", access); w.Code != http.StatusOK {
+		t.Fatalf("cookie-only request: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodGet, "/auth/sessions", "", &http.Cookie{Name: AccessTokenCookie, Value: "forged"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("bad cookie: expected 401, got %d", w.Code)
+	}
+
+	// A cookie client refreshes with an empty body.
+	w = send(http.MethodPost, "/auth/refresh?cookie=true", "", refresh)
+	if w.Code != http.StatusOK || cookiesOf(w)[AccessTokenCookie] == nil {
+		t.Fatalf("cookie refresh: expected 200 with new cookies, got %d: %s", w.Code, w.Body.String())
+	}
+	access = cookiesOf(w)[AccessTokenCookie]
+	if w := send(http.MethodPost, "/auth/refresh", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("refresh without a token: expected 400, got %d", w.Code)
+	}
+
+	claims, _ := ValidateJWT(access.Value)
+	w = send(http.MethodDelete, "/auth/sessions/"+claims.SessionID, "", access)
+	if cleared := cookiesOf(w)[AccessTokenCookie]; w.Code != http.StatusOK || cleared == nil || cleared.MaxAge >= 0 {
+		t.Errorf("logout: expected the access cookie to be cleared, got %d %v", w.Code, w.Header().Values("Set-Cookie"))
+	}
+}
+
+func TestPing_ReportsStoreHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMemoryRepo()
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(NewAuthService(repo)))
+
+	ping := func(method string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/auth/ping", nil))
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s /auth/ping: expected JSON, got %q", method, w.Body.String())
+		}
+		return w.Code, body
+	}
+
+	code, body := ping(http.MethodGet)
+	if code != http.StatusOK || body["service"] != "auth" || body["status"] != "ok" || body["db"] != true {
+		t.Errorf("expected 200 {service:auth status:ok db:true}, got %d %v", code, body)
+	}
+
+	repo.pingErr = errors.New("connection refused")
+	code, body = ping(http.MethodGet)
+	if code != http.StatusServiceUnavailable || body["db"] != false {
+		t.Errorf("expected 503 with db false when the store is down, got %d %v", code, body)
+	}
+}
+
+// mockAuthenticator answers Register, Login and Refresh with canned results
+// and records what the handler passed. Other methods are not implemented.
+type mockAuthenticator struct {
+	Authenticator
+	user   *User
+	tokens *LoginResponse
+	err    error
+
+	lastLogin  LoginRequest
+	lastClient ClientInfo
+	lastToken  string
+}
+
+func (m *mockAuthenticator) Register(_ context.Context, req RegisterRequest) (*User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.user, nil
+}
+
+func (m *mockAuthenticator) Login(_ context.Context, req LoginRequest, client ClientInfo) (*LoginResponse, error) {
+	m.lastLogin, m.lastClient = req, client
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.tokens, nil
+}
+
+func (m *mockAuthenticator) Refresh(_ context.Context, refreshToken string, client ClientInfo) (*LoginResponse, error) {
+	m.lastToken, m.lastClient = refreshToken, client
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.tokens, nil
+}
+
+func TestHandler_WithMockAuthenticator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mock := &mockAuthenticator{
+		user:   &User{ID: "user-1", Email: "mock@example.com", Role: "user"},
+		tokens: &LoginResponse{Token: "access", RefreshToken: "refresh", ExpiresIn: 900},
+	}
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(mock))
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("User-Agent", "mock-client")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	const (
+		register = `{"email":"mock@example.com","password":"correct horse battery"}`
+		login    = `{"identifier":"mock","password":"correct horse battery"}`
+		refresh  = `{"refresh_token":"old-refresh"}`
+	)
+
+	w := post("/auth/login", login)
+	var tokens LoginResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil || w.Code != http.StatusOK || tokens.Token != "access" {
+		t.Fatalf("login: expected 200 with the issued tokens, got %d: %s", w.Code, w.Body.String())
+	}
+	if mock.lastLogin.Identifier != "mock" || mock.lastClient.UserAgent != "mock-client" {
+		t.Errorf("expected the identifier and client to reach the service, got %+v %+v", mock.lastLogin, mock.lastClient)
+	}
+	if w := post("/auth/refresh", refresh); w.Code != http.StatusOK || mock.lastToken != "old-refresh" {
+		t.Errorf("refresh: expected 200 for the presented token, got %d (token %q)", w.Code, mock.lastToken)
+	}
+	if w := post("/auth/register", register); w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"user-1"`) {
+		t.Errorf("register: expected 201 with the user, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, tc := range []struct {
+		path, body string
+		err        error
+		want       int
+	}{
+		{"/auth/login", login, ErrInvalidCredentials, http.StatusUnauthorized},
+		{"/auth/login", login, ErrInactiveUser, http.StatusForbidden},
+		{"/auth/login", login, ErrSessionLimit, http.StatusConflict},
+		{"/auth/login", login, errors.New("store down"), http.StatusInternalServerError},
+		{"/auth/refresh", refresh, ErrRefreshTokenReused, http.StatusUnauthorized},
+		{"/auth/refresh", refresh, ErrInactiveUser, http.StatusForbidden},
+		{"/auth/register", register, ErrEmailTaken, http.StatusConflict},
+		{"/auth/register", register, ErrInvalidJoinCode, http.StatusBadRequest},
+	} {
+		mock.err = tc.err
+		w := post(tc.path, tc.body)
+		if w.Code != tc.want || !strings.Contains(w.Body.String(), tc.err.Error()) {
+			t.Errorf("%s with %v: expected %d carrying the error, got %d: %s", tc.path, tc.err, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
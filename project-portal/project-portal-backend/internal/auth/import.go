@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+)
+
+// MaxImportRows caps how many users one bulk import may create.
+const MaxImportRows = 1000
+
+// Outcomes of one row of a bulk user import.
+const (
+	ImportCreated = "created"
+	ImportSkipped = "skipped" // the email or username is already taken
+	ImportFailed  = "failed"
+)
+
+// ImportUserRow is one user of a bulk import. Role defaults like CreateUser.
+type ImportUserRow struct {
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	FullName string `json:"full_name,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// ImportUserResult reports one row. Rows are numbered from 1 in the order
+// given, not counting a CSV header. TemporaryPassword is set for created
+// users only; it is shown once, to be handed to the user, and should be
+// changed at first sign-in.
+type ImportUserResult struct {
+	Row               int    `json:"row"`
+	Email             string `json:"email"`
+	Status            string `json:"status"`
+	UserID            string `json:"user_id,omitempty"`
+	Role              string `json:"role,omitempty"`
+	TemporaryPassword string `json:"temporary_password,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// ImportUsersResult is the outcome of a bulk import, row by row.
+type ImportUsersResult struct {
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Results []ImportUserResult `json:"results"`
+}
+
+// ImportUsers creates an account for each row with a random temporary
+// password, in orgID when it is not empty. Rows whose email or username is
+// taken, by an existing account or an earlier row, are skipped; invalid rows
+// fail. Neither stops the import. An error is returned only when the store
+// fails.
+func (s *AuthService) ImportUsers(ctx context.Context, orgID string, rows []ImportUserRow) (*ImportUsersResult, error) {
+	if len(rows) > MaxImportRows {
+		return nil, fmt.Errorf("an import may hold at most %d users, got %d", MaxImportRows, len(rows))
+	}
+	var org *Organization
+	if orgID != "" {
+		org = &Organization{ID: orgID}
+	}
+
+	out := &ImportUsersResult{Results: make([]ImportUserResult, 0, len(rows))}
+	for i, row := range rows {
+		res := ImportUserResult{Row: i + 1, Email: s.emails.Normalize(row.Email)}
+		user, password, err := s.importUser(ctx, row, org)
+		switch {
+		case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrUsernameTaken):
+			res.Status, res.Error = ImportSkipped, err.Error()
+			out.Skipped++
+		case errors.Is(err, errInvalidImportRow), errors.Is(err, ErrRoleNotAllowed), errors.Is(err, ErrInvalidUsername):
+			res.Status, res.Error = ImportFailed, err.Error()
+			out.Failed++
+		case err != nil:
+			return nil, err
+		default:
+			res.Status, res.UserID, res.Role, res.TemporaryPassword = ImportCreated, user.ID, user.Role, password
+			out.Created++
+		}
+		out.Results = append(out.Results, res)
+	}
+	return out, nil
+}
+
+var errInvalidImportRow = errors.New("invalid row")
+
+func (s *AuthService) importUser(ctx context.Context, row ImportUserRow, org *Organization) (*User, string, error) {
+	email := strings.TrimSpace(row.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, "", fmt.Errorf("%w: %q is not an email address", errInvalidImportRow, row.Email)
+	}
+	username := strings.TrimSpace(row.Username)
+	if username != "" && (len(username) < 3 || len(username) > 32) {
+		return nil, "", fmt.Errorf("%w: username must be 3-32 characters", errInvalidImportRow)
+	}
+	if len(row.FullName) > 200 {
+		return nil, "", fmt.Errorf("%w: full_name is longer than 200 characters", errInvalidImportRow)
+	}
+	role := strings.ToLower(strings.TrimSpace(row.Role))
+	if role == "" {
+		role = s.roles.DefaultRole
+	} else if !s.roles.allows(role) {
+		return nil, "", fmt.Errorf("%w: %q", ErrRoleNotAllowed, row.Role)
+	}
+
+	password, err := newTemporaryPassword()
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := s.createUser(ctx, email, username, password, strings.TrimSpace(row.FullName), role, org)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, password, nil
+}
+
+// newTemporaryPassword returns 18 random bytes as 24 URL-safe characters.
+func newTemporaryPassword() (string, error) {
+	raw := make([]byte, 18)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// ParseUserCSV reads import rows from CSV with a header row. The email column
+// is required; role, full_name and username are optional and columns may come
+// in any order. A byte order mark before the header is ignored.
+func ParseUserCSV(r io.Reader) ([]ImportUserRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := col["email"]; !ok {
+		return nil, errors.New("csv header must include an email column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []ImportUserRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("an import may hold at most %d users", MaxImportRows)
+		}
+		rows = append(rows, ImportUserRow{
+			Email:    field(record, "email"),
+			Role:     field(record, "role"),
+			FullName: field(record, "full_name"),
+			Username: field(record, "username"),
+		})
+	}
+}
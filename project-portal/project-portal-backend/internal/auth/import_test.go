@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestImportUsers_ReportsDuplicatesWithoutAborting(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(service))
+
+	if _, err := service.Register(context.Background(), RegisterRequest{Email: "existing@example.com", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	orgID := "org-acme"
+	admin, _ := GenerateJWT(&User{ID: "admin-1", Email: "admin@example.com", Role: "admin", OrgID: &orgID})
+	member, _ := GenerateJWT(&User{ID: "member-1", Email: "member@example.com", Role: "user"})
+
+	send := func(token, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/users/import", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	batch := `[
+		{"email":"ana@example.com","role":"verifier"},
+		{"email":"Existing@example.com","role":"user"},
+		{"email":"ben@example.com"},
+		{"email":"ana@example.com","role":"partner"},
+		{"email":"not-an-email","role":"user"},
+		{"email":"root@example.com","role":"admin"}
+	]`
+	if w := send(member, "application/json", batch); w.Code != http.StatusForbidden {
+		t.Fatalf("non-admin import: expected 403, got %d", w.Code)
+	}
+
+	w := send(admin, "application/json", batch)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	var result ImportUsersResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Created != 2 || result.Skipped != 2 || result.Failed != 2 || len(result.Results) != 6 {
+		t.Fatalf("expected 2 created, 2 skipped, 2 failed, got %+v", result)
+	}
+	want := []string{ImportCreated, ImportSkipped, ImportCreated, ImportSkipped, ImportFailed, ImportFailed}
+	for i, res := range result.Results {
+		if res.Row != i+1 || res.Status != want[i] {
+			t.Errorf("row %d: expected %s, got %+v", i+1, want[i], res)
+		}
+		if (res.Status == ImportCreated) != (res.TemporaryPassword != "") {
+			t.Errorf("row %d: a temporary password must come with created rows only, got %+v", i+1, res)
+		}
+	}
+
+	ana := result.Results[0]
+	created, err := repo.GetUserByEmail(context.Background(), "ana@example.com")
+	if err != nil || created.Role != "verifier" || created.orgID() != orgID {
+		t.Fatalf("expected ana to be a verifier in the admin's organization, got %+v (%v)", created, err)
+	}
+	if result.Results[2].Role != "user" {
+		t.Errorf("expected a row without a role to get the default role, got %q", result.Results[2].Role)
+	}
+	if _, err := service.Login(context.Background(), LoginRequest{Email: "ana@example.com", Password: ana.TemporaryPassword}, ClientInfo{}); err != nil {
+		t.Errorf("expected the temporary password to sign in, got %v", err)
+	}
+}
+
+func TestImportUsers_CSV(t *testing.T) {
+	rows, err := ParseUserCSV(strings.NewReader("\ufeffRole,Email,full_name\nverifier, cara@example.com,Cara Diaz\n,dan@example.com\n"))
+	if err != nil {
+		t.Fatalf("ParseUserCSV: %v", err)
+	}
+	if len(rows) != 2 || rows[0] != (ImportUserRow{Email: "cara@example.com", Role: "verifier", FullName: "Cara Diaz"}) || rows[1].Email != "dan@example.com" {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	if _, err := ParseUserCSV(strings.NewReader("name,role\nCara,user\n")); err == nil {
+		t.Error("expected a CSV without an email column to be refused")
+	}
+
+	result, err := NewAuthService(newMemoryRepo()).ImportUsers(context.Background(), "", rows)
+	if err != nil || result.Created != 2 {
+		t.Fatalf("expected both rows created, got %+v (%v)", result, err)
+	}
+}
@@ -1,42 +1,113 @@
 package auth
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("supersecretkey") // Replace with env var in production
+// JWTConfig controls how access tokens are signed and which registered
+// claims a token must carry to pass verification.
+type JWTConfig struct {
+	Secret     []byte
+	Issuer     string
+	Audience   string
+	TTL        time.Duration
+	RefreshTTL time.Duration
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// Development defaults; ConfigureJWT overrides them from config at startup.
+var jwtConfig = JWTConfig{
+	Secret:     []byte("supersecretkey"),
+	Issuer:     "carbon-scribe-project-portal",
+	Audience:   "carbon-scribe-api",
+	TTL:        15 * time.Minute,
+	RefreshTTL: 30 * 24 * time.Hour,
+	Leeway:     30 * time.Second,
+}
+
+// ConfigureJWT replaces the token settings. Empty fields keep their current
+// value, except Leeway, which is always applied: zero tolerates no skew.
+func ConfigureJWT(cfg JWTConfig) {
+	jwtConfig.Leeway = max(cfg.Leeway, 0)
+	if len(cfg.Secret) > 0 {
+		jwtConfig.Secret = cfg.Secret
+	}
+	if cfg.Issuer != "" {
+		jwtConfig.Issuer = cfg.Issuer
+	}
+	if cfg.Audience != "" {
+		jwtConfig.Audience = cfg.Audience
+	}
+	if cfg.TTL > 0 {
+		jwtConfig.TTL = cfg.TTL
+	}
+	if cfg.RefreshTTL > 0 {
+		jwtConfig.RefreshTTL = cfg.RefreshTTL
+	}
+}
 
 // Claims struct
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// SessionID is the refresh-token family the token was issued for; empty
+	// for tokens minted outside a login.
+	SessionID string `json:"sid,omitempty"`
+	// OrgID is the user's organization; empty for users without one.
+	OrgID string `json:"org_id,omitempty"`
+	// TokenVersion is the user's token version when the token was issued.
+	TokenVersion int `json:"ver,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateJWT generates a JWT token for a user
 func GenerateJWT(user *User) (string, error) {
+	token, _, err := generateSessionJWT(user, "", time.Now())
+	return token, err
+}
+
+// generateSessionJWT generates an access token bound to sessionID, issued at
+// now, and reports when it expires.
+func generateSessionJWT(user *User, sessionID string, now time.Time) (string, time.Time, error) {
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		SessionID:    sessionID,
+		OrgID:        user.orgID(),
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    jwtConfig.Issuer,
+			Audience:  jwt.ClaimStrings{jwtConfig.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(jwtConfig.TTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtConfig.Secret)
+	return token, claims.ExpiresAt.Time, err
 }
 
-// ValidateJWT parses and validates a JWT token string
+// ValidateJWT parses and validates a JWT token string. The token must be
+// signed with HS256 and carry the configured issuer and audience; exp, nbf
+// and iat are all enforced, each allowing the configured leeway.
 func ValidateJWT(tokenStr string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+		return jwtConfig.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(jwtConfig.Issuer),
+		jwt.WithAudience(jwtConfig.Audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtConfig.Leeway),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -45,5 +116,5 @@ func ValidateJWT(tokenStr string) (*Claims, error) {
 		return claims, nil
 	}
 
-	return nil, err
+	return nil, errors.New("invalid token")
 }
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func useTestJWTConfig(t *testing.T) {
+	t.Helper()
+	saved := jwtConfig
+	t.Cleanup(func() { jwtConfig = saved })
+	ConfigureJWT(JWTConfig{Secret: []byte("test-secret"), Issuer: "portal-test", Audience: "api-test", TTL: time.Minute})
+}
+
+func signClaims(t *testing.T, claims *Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtConfig.Secret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func registered(iss, aud string, nbf time.Time) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    iss,
+		Audience:  jwt.ClaimStrings{aud},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(nbf),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+func TestGenerateJWT_RoundTripsWithRegisteredClaims(t *testing.T) {
+	useTestJWTConfig(t)
+
+	token, err := GenerateJWT(&User{ID: "u1", Email: "a@example.com", Role: "admin"})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	claims, err := ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("ValidateJWT: %v", err)
+	}
+	if claims.Issuer != "portal-test" || len(claims.Audience) != 1 || claims.Audience[0] != "api-test" {
+		t.Errorf("unexpected iss/aud: %q %v", claims.Issuer, claims.Audience)
+	}
+	if claims.IssuedAt == nil || claims.NotBefore == nil {
+		t.Error("expected iat and nbf to be set")
+	}
+}
+
+func TestValidateJWT_RejectsWrongIssuer(t *testing.T) {
+	useTestJWTConfig(t)
+
+	token := signClaims(t, &Claims{UserID: "u1", RegisteredClaims: registered("someone-else", "api-test", time.Now())})
+	if _, err := ValidateJWT(token); err == nil {
+		t.Fatal("expected token with foreign issuer to be rejected")
+	}
+}
+
+func TestValidateJWT_RejectsWrongAudience(t *testing.T) {
+	useTestJWTConfig(t)
+
+	token := signClaims(t, &Claims{UserID: "u1", RegisteredClaims: registered("portal-test", "other-api", time.Now())})
+	if _, err := ValidateJWT(token); err == nil {
+		t.Fatal("expected token with foreign audience to be rejected")
+	}
+}
+
+func TestValidateJWT_RejectsTokenNotYetValid(t *testing.T) {
+	useTestJWTConfig(t)
+
+	token := signClaims(t, &Claims{UserID: "u1", RegisteredClaims: registered("portal-test", "api-test", time.Now().Add(10*time.Minute))})
+	if _, err := ValidateJWT(token); err == nil {
+		t.Fatal("expected token with future nbf to be rejected")
+	}
+}
+
+func TestValidateJWT_ToleratesClockSkewWithinLeeway(t *testing.T) {
+	useTestJWTConfig(t)
+	ConfigureJWT(JWTConfig{Leeway: 30 * time.Second})
+
+	expiredAgo := func(d time.Duration) string {
+		claims := registered("portal-test", "api-test", time.Now().Add(-time.Hour))
+		claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-d))
+		return signClaims(t, &Claims{UserID: "u1", RegisteredClaims: claims})
+	}
+	if _, err := ValidateJWT(expiredAgo(10 * time.Second)); err != nil {
+		t.Errorf("token expired 10s ago within a 30s leeway: %v", err)
+	}
+	if _, err := ValidateJWT(expiredAgo(time.Minute)); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Errorf("token expired 1m ago: expected ErrTokenExpired, got %v", err)
+	}
+
+	// A client clock running ahead stamps nbf and iat in the future.
+	ahead := registered("portal-test", "api-test", time.Now().Add(10*time.Second))
+	ahead.IssuedAt = jwt.NewNumericDate(time.Now().Add(10 * time.Second))
+	if _, err := ValidateJWT(signClaims(t, &Claims{UserID: "u1", RegisteredClaims: ahead})); err != nil {
+		t.Errorf("nbf and iat 10s ahead within a 30s leeway: %v", err)
+	}
+
+	ConfigureJWT(JWTConfig{})
+	if _, err := ValidateJWT(expiredAgo(10 * time.Second)); err == nil {
+		t.Error("expected a zero leeway to reject a token expired 10s ago")
+	}
+}
+
+func TestAuthMiddleware_WWWAuthenticateDistinguishesExpiry(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	expired := registered("portal-test", "api-test", time.Now().Add(-2*time.Hour))
+	expired.IssuedAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Hour))
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	forged := registered("portal-test", "api-test", time.Now())
+
+	for name, tc := range map[string]struct {
+		token       string
+		description string
+	}{
+		"expired":  {signClaims(t, &Claims{UserID: "u1", RegisteredClaims: expired}), "the access token expired"},
+		"tampered": {signClaims(t, &Claims{UserID: "u1", RegisteredClaims: forged}) + "x", "the access token is invalid"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", name, w.Code)
+		}
+		want := `Bearer error="invalid_token", error_description="` + tc.description + `"`
+		if got := w.Header().Get("WWW-Authenticate"); got != want {
+			t.Errorf("%s: expected WWW-Authenticate %q, got %q", name, want, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if got := w.Header().Get("WWW-Authenticate"); strings.Contains(got, "invalid_token") {
+		t.Errorf("expected no invalid_token error without credentials, got %q", got)
+	}
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/metrics"
+)
+
+// Login outcomes, the outcome label of auth_logins_total.
+const (
+	LoginSuccess            = "success"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginInactive           = "inactive"
+	LoginUnverified         = "unverified"
+	LoginSessionLimit       = "session_limit"
+	LoginError              = "error"
+)
+
+// Lockout reasons, the reason label of auth_lockouts_total: a login refused
+// by the session limit, or a login throttled by the rate limiter.
+const (
+	LockoutSessionLimit = "session_limit"
+	LockoutRateLimit    = "rate_limit"
+)
+
+// Revocation reasons, the reason label of auth_tokens_revoked_total.
+const (
+	RevokedLogout          = "logout"
+	RevokedSessionEviction = "session_eviction"
+	RevokedRefreshReuse    = "refresh_reuse"
+	RevokedDeactivation    = "deactivation"
+	RevokedAccountDeletion = "account_deletion"
+	RevokedAPIKey          = "api_key"
+)
+
+// Metrics are the sign-in counters served on /metrics. Labels name outcomes
+// and reasons only, never an email address or user id.
+type Metrics struct {
+	Logins        *metrics.CounterVec
+	Lockouts      *metrics.CounterVec
+	TokensRevoked *metrics.CounterVec
+	FailureAlerts *metrics.CounterVec
+}
+
+// NewMetrics returns zeroed auth counters.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Logins:        metrics.NewCounterVec("auth_logins_total", "Login attempts by outcome.", "outcome"),
+		Lockouts:      metrics.NewCounterVec("auth_lockouts_total", "Sign-ins refused by the session limit or rate limiter.", "reason"),
+		TokensRevoked: metrics.NewCounterVec("auth_tokens_revoked_total", "Revocations of sessions, refresh tokens and API keys by reason.", "reason"),
+		FailureAlerts: metrics.NewCounterVec("auth_login_failure_alerts_total", "Login failure thresholds crossed, by ip or account.", "scope"),
+	}
+}
+
+// Collectors lists the counters for a metrics.Registry.
+func (m *Metrics) Collectors() []metrics.Collector {
+	return []metrics.Collector{m.Logins, m.Lockouts, m.TokensRevoked, m.FailureAlerts}
+}
+
+// SetMetrics makes the service count logins and revocations in m.
+func (s *AuthService) SetMetrics(m *Metrics) {
+	s.metrics = m
+}
+
+// revoked counts one revocation, when metrics are set.
+func (s *AuthService) revoked(reason string) {
+	if s.metrics != nil {
+		s.metrics.TokensRevoked.Inc(reason)
+	}
+}
+
+// LoginFailureAlert logs a warning when one client IP or one account fails
+// to sign in Threshold times within Window. A Threshold below 1 disables it.
+type LoginFailureAlert struct {
+	Threshold int
+	Window    time.Duration
+}
+
+// SetLoginFailureAlert starts watching failed logins for alert.
+func (s *AuthService) SetLoginFailureAlert(alert LoginFailureAlert) error {
+	if alert.Threshold > 0 && alert.Window <= 0 {
+		return fmt.Errorf("login failure window must be positive, got %s", alert.Window)
+	}
+	if alert.Threshold < 1 {
+		s.failures = nil
+		return nil
+	}
+	s.failures = &failureTracker{alert: alert, failures: map[string][]time.Time{}}
+	return nil
+}
+
+// failureTracker keeps the recent failed logins per ip or account key.
+type failureTracker struct {
+	alert LoginFailureAlert
+
+	mu        sync.Mutex
+	failures  map[string][]time.Time
+	lastSweep time.Time
+}
+
+// record adds a failure of key at now and returns how many fell within the
+// window. crossed is true only for the failure that reaches the threshold, so
+// a sustained attack warns once per window rather than on every attempt.
+func (t *failureTracker) record(key string, now time.Time) (count int, crossed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sweep(now)
+	recent := t.failures[key][:0]
+	for _, at := range t.failures[key] {
+		if now.Sub(at) < t.alert.Window {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	t.failures[key] = recent
+	return len(recent), len(recent) == t.alert.Threshold
+}
+
+// reset forgets key's failures, after it signs in.
+func (t *failureTracker) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+// sweep drops keys with no failure inside the window, at most once a window.
+func (t *failureTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.alert.Window {
+		return
+	}
+	t.lastSweep = now
+	for key, times := range t.failures {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) >= t.alert.Window {
+			delete(t.failures, key)
+		}
+	}
+}
+
+// recordLogin counts the outcome of a login and watches failures for the
+// alert threshold. user is the account the identifier matched, if any;
+// unknown accounts are tracked, and logged, by a hash of the identifier.
+func (s *AuthService) recordLogin(ctx context.Context, identifier string, user *User, client ClientInfo, err error) {
+	outcome := loginOutcome(err)
+	if s.metrics != nil {
+		s.metrics.Logins.Inc(outcome)
+		if outcome == LoginSessionLimit {
+			s.metrics.Lockouts.Inc(LockoutSessionLimit)
+		}
+	}
+	if s.failures == nil {
+		return
+	}
+
+	accountAttr, accountID := "account_hash", accountHash(identifier)
+	if user != nil {
+		accountAttr, accountID = "user_id", user.ID
+	}
+	if outcome == LoginSuccess {
+		s.failures.reset("account:" + accountID)
+		return
+	}
+	if outcome != LoginInvalidCredentials && outcome != LoginInactive {
+		return
+	}
+
+	now := s.now()
+	alerts := []struct{ scope, attr, value string }{
+		{"ip", "ip", client.IPAddress},
+		{"account", accountAttr, accountID},
+	}
+	for _, a := range alerts {
+		if a.value == "" {
+			continue
+		}
+		count, crossed := s.failures.record(a.scope+":"+a.value, now)
+		if !crossed {
+			continue
+		}
+		if s.metrics != nil {
+			s.metrics.FailureAlerts.Inc(a.scope)
+		}
+		logging.FromContext(ctx).Warn("login failure threshold crossed",
+			"scope", a.scope, a.attr, a.value, "failures", count,
+			"threshold", s.failures.alert.Threshold, "window", s.failures.alert.Window.String())
+	}
+}
+
+// loginOutcome maps a Login error to its outcome label.
+func loginOutcome(err error) string {
+	switch {
+	case err == nil:
+		return LoginSuccess
+	case errors.Is(err, ErrInvalidCredentials):
+		return LoginInvalidCredentials
+	case errors.Is(err, ErrInactiveUser):
+		return LoginInactive
+	case errors.Is(err, ErrEmailNotVerified):
+		return LoginUnverified
+	case errors.Is(err, ErrSessionLimit):
+		return LoginSessionLimit
+	default:
+		return LoginError
+	}
+}
+
+// accountHash identifies a login identifier in logs without revealing it.
+func accountHash(identifier string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(identifier))))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+)
+
+func TestMetrics_CountLoginOutcomes(t *testing.T) {
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	m := NewMetrics()
+	service.SetMetrics(m)
+	ctx := context.Background()
+
+	user, err := service.Register(ctx, RegisterRequest{Email: "counted@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	login := func(email, password string) error {
+		_, err := service.Login(ctx, LoginRequest{Email: email, Password: password}, ClientInfo{IPAddress: "203.0.113.7"})
+		return err
+	}
+
+	if err := login("counted@example.com", "correct horse battery"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	login("counted@example.com", "wrong password")
+	login("nobody@example.com", "correct horse battery")
+	service.SetSessionLimit(SessionLimit{Max: 1, Policy: SessionLimitReject})
+	if err := login("counted@example.com", "correct horse battery"); !errors.Is(err, ErrSessionLimit) {
+		t.Fatalf("expected ErrSessionLimit, got %v", err)
+	}
+	repo.users[user.ID].IsActive = false
+	login("counted@example.com", "correct horse battery")
+
+	for outcome, want := range map[string]float64{
+		LoginSuccess:            1,
+		LoginInvalidCredentials: 2,
+		LoginSessionLimit:       1,
+		LoginInactive:           1,
+		LoginError:              0,
+	} {
+		if got := m.Logins.Value(outcome); got != want {
+			t.Errorf("auth_logins_total{outcome=%q}: expected %v, got %v", outcome, want, got)
+		}
+	}
+	if got := m.Lockouts.Value(LockoutSessionLimit); got != 1 {
+		t.Errorf("expected one session-limit lockout, got %v", got)
+	}
+
+	var exposition bytes.Buffer
+	m.Logins.WriteText(&exposition)
+	if strings.Contains(exposition.String(), "@") {
+		t.Errorf("metrics must not carry email addresses:\n%s", exposition.String())
+	}
+}
+
+func TestMetrics_CountRevocations(t *testing.T) {
+	service, repo, login := loginForRefresh(t)
+	m := NewMetrics()
+	service.SetMetrics(m)
+	ctx := context.Background()
+
+	// Replaying a rotated-out refresh token revokes its family.
+	if _, err := service.Refresh(ctx, login.RefreshToken, ClientInfo{}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := service.Refresh(ctx, login.RefreshToken, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+
+	var userID string
+	for id := range repo.users {
+		userID = id
+	}
+	again, err := service.Login(ctx, LoginRequest{Email: "rotate@example.com", Password: "correct horse battery"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	claims, _ := ValidateJWT(again.Token)
+	if err := service.RevokeSession(ctx, userID, claims.SessionID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := service.RevokeSession(ctx, userID, "no-such-session"); err == nil {
+		t.Fatal("expected revoking an unknown session to fail")
+	}
+
+	inactive := false
+	if _, err := service.UpdateUser(ctx, "admin-1", userID, UpdateUserRequest{Active: &inactive}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+
+	for reason, want := range map[string]float64{
+		RevokedRefreshReuse: 1,
+		RevokedLogout:       1,
+		RevokedDeactivation: 1,
+		RevokedAPIKey:       0,
+	} {
+		if got := m.TokensRevoked.Value(reason); got != want {
+			t.Errorf("auth_tokens_revoked_total{reason=%q}: expected %v, got %v", reason, want, got)
+		}
+	}
+}
+
+func TestLoginFailureAlert_WarnsOncePerWindow(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	m := NewMetrics()
+	service.SetMetrics(m)
+	if err := service.SetLoginFailureAlert(LoginFailureAlert{Threshold: 3, Window: 0}); err == nil {
+		t.Error("expected a zero window to be refused")
+	}
+	if err := service.SetLoginFailureAlert(LoginFailureAlert{Threshold: 3, Window: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	var logs bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+	if _, err := service.Register(ctx, RegisterRequest{Email: "target@example.com", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	fail := func(email, ip string) {
+		service.Login(ctx, LoginRequest{Email: email, Password: "guess"}, ClientInfo{IPAddress: ip})
+		now = now.Add(time.Second)
+	}
+
+	// One IP guessing at three different accounts.
+	fail("a@example.com", "198.51.100.9")
+	fail("b@example.com", "198.51.100.9")
+	if m.FailureAlerts.Value("ip") != 0 {
+		t.Fatal("expected no alert below the threshold")
+	}
+	fail("c@example.com", "198.51.100.9")
+	fail("d@example.com", "198.51.100.9")
+	if got := m.FailureAlerts.Value("ip"); got != 1 {
+		t.Errorf("expected one ip alert per window, got %v", got)
+	}
+	if !strings.Contains(logs.String(), "login failure threshold crossed") || !strings.Contains(logs.String(), "ip=198.51.100.9") {
+		t.Errorf("expected a warning naming the ip, got:\n%s", logs.String())
+	}
+	if strings.Contains(logs.String(), "@example.com") {
+		t.Errorf("the warning must not carry email addresses:\n%s", logs.String())
+	}
+
+	// One account attacked from three addresses.
+	fail("target@example.com", "192.0.2.1")
+	fail("target@example.com", "192.0.2.2")
+	fail("target@example.com", "192.0.2.3")
+	if got := m.FailureAlerts.Value("account"); got != 1 {
+		t.Errorf("expected an account alert, got %v", got)
+	}
+
+	// Once the window has passed, the count starts over.
+	now = now.Add(2 * time.Minute)
+	fail("e@example.com", "198.51.100.9")
+	fail("f@example.com", "198.51.100.9")
+	fail("g@example.com", "198.51.100.9")
+	if got := m.FailureAlerts.Value("ip"); got != 2 {
+		t.Errorf("expected a second ip alert in a new window, got %v", got)
+	}
+}
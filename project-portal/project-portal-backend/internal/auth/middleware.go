@@ -1,44 +1,177 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware validates JWT tokens in the Authorization header
+// TokenVersionSource reports the token version a user's access tokens must
+// carry. AuthService implements it.
+type TokenVersionSource interface {
+	TokenVersion(ctx context.Context, userID string) (int, error)
+}
+
+// tokenVersions is consulted by AuthMiddleware; nil skips the check.
+var tokenVersions TokenVersionSource
+
+// CheckTokenVersions makes AuthMiddleware look up the user behind every
+// access token and reject tokens issued before their token version last
+// changed, such as ones carrying a role the user no longer has. nil turns
+// the check off.
+func CheckTokenVersions(source TokenVersionSource) {
+	tokenVersions = source
+}
+
+// AuthMiddleware validates JWT tokens in the Authorization header or, when
+// there is none, in the access token cookie set for browser clients.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		cookie, _ := c.Cookie(AccessTokenCookie)
+		if authHeader == "" && cookie == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
 			c.Abort()
 			return
 		}
 
-		// Expect "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
-			c.Abort()
-			return
+		tokenStr := cookie
+		if authHeader != "" {
+			// Expect "Bearer <token>"
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+				c.Abort()
+				return
+			}
+			tokenStr = parts[1]
 		}
 
-		tokenStr := parts[1]
-
 		claims, err := ValidateJWT(tokenStr)
 		if err != nil {
+			// RFC 6750: an expired token is still invalid_token, but the
+			// description lets clients refresh instead of logging out.
+			description := "the access token is invalid"
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				description = "the access token expired"
+			}
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token", error_description="`+description+`"`)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: " + err.Error()})
 			c.Abort()
 			return
 		}
 
+		if tokenVersions != nil {
+			current, err := tokenVersions.TokenVersion(c.Request.Context(), claims.UserID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+				c.Abort()
+				return
+			}
+			if err != nil || claims.TokenVersion != current {
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token", error_description="the access token was superseded; refresh it"`)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: token has been superseded"})
+				c.Abort()
+				return
+			}
+		}
+
 		// Add claims to context
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		c.Set("org_id", claims.OrgID)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", claims.UserID))
 
 		c.Next()
 	}
 }
+
+// ResolveAPIKey authenticates machine clients that send an X-API-Key header
+// and resolves the key to its owning user. It sets the same context keys as
+// AuthMiddleware plus api_key_id and scopes; routes accept the result through
+// AuthMiddlewareOrAPIKey. Requests without the header pass through untouched.
+// A bad key is refused with 401, and 503 when the key cannot be looked up.
+func ResolveAPIKey(service Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		user, apiKey, err := service.AuthenticateAPIKey(c.Request.Context(), key)
+		if errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, ErrAPIKeyRevoked) ||
+			errors.Is(err, ErrAPIKeyExpired) || errors.Is(err, ErrInactiveUser) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("api key lookup failed", "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify API key"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("email", user.Email)
+		c.Set("role", user.Role)
+		c.Set("org_id", user.orgID())
+		c.Set("api_key_id", apiKey.ID)
+		c.Set("scopes", []string(apiKey.Scopes))
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", user.ID, "api_key_id", apiKey.ID))
+
+		c.Next()
+	}
+}
+
+// AuthMiddlewareOrAPIKey is AuthMiddleware for routes machine clients may
+// use too: a request ResolveAPIKey has authenticated passes, any other needs
+// an access token. Pair it with RequireScope.
+func AuthMiddlewareOrAPIKey() gin.HandlerFunc {
+	tokens := AuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetString("api_key_id") != "" {
+			c.Next()
+			return
+		}
+		tokens(c)
+	}
+}
+
+// RequireScope limits API key requests to keys granted scope: read for GET
+// and HEAD, write for anything else, which also covers reads. Requests signed
+// in with an access token pass, their role alone deciding.
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("api_key_id") == "" {
+			c.Next()
+			return
+		}
+		scopes := c.GetStringSlice("scopes")
+		need := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			need = read
+			if slices.Contains(scopes, read) {
+				c.Next()
+				return
+			}
+		}
+		if !slices.Contains(scopes, write) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "api key is missing scope: " + need})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
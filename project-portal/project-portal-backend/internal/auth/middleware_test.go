@@ -0,0 +1,547 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// memoryRepo is an in-memory Repository for exercising the service and
+// middleware without a database.
+type memoryRepo struct {
+	mu    sync.Mutex
+	users map[string]*User
+	keys  map[string]*APIKey
+
+	refreshTokens map[string]*RefreshToken
+	sessions      map[string]*Session
+	orgs          map[string]*Organization
+
+	ownedProjects map[string]int64 // live projects per owner
+	purgeErrs     map[string]error // returned by PurgeUser per user
+
+	afterLookup func() // optional hook run after GetUserByEmail
+	pingErr     error  // returned by Ping
+}
+
+// uniqueViolation mimics the SQLState-bearing errors of pgx and lib/pq.
+type uniqueViolation struct{}
+
+func (uniqueViolation) Error() string    { return "duplicate key value violates unique constraint" }
+func (uniqueViolation) SQLState() string { return "23505" }
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{users: map[string]*User{}, keys: map[string]*APIKey{}, refreshTokens: map[string]*RefreshToken{}, sessions: map[string]*Session{}, orgs: map[string]*Organization{}}
+}
+
+func (m *memoryRepo) Ping(context.Context) error { return m.pingErr }
+
+func (m *memoryRepo) CreateUser(_ context.Context, user *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if strings.EqualFold(u.Email, user.Email) {
+			return uniqueViolation{}
+		}
+		if u.Username != nil && user.Username != nil && *u.Username == *user.Username {
+			return uniqueViolation{}
+		}
+	}
+	if user.ID == "" {
+		user.ID = "user-" + user.Email
+	}
+	if org := user.Organization; org != nil {
+		org.ID = "org-" + org.Name
+		m.orgs[org.ID] = org
+		user.OrgID = &org.ID
+	}
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *memoryRepo) GetUserByEmail(_ context.Context, email string) (*User, error) {
+	m.mu.Lock()
+	var found *User
+	for _, u := range m.users {
+		if strings.EqualFold(u.Email, email) && !u.DeletedAt.Valid {
+			found = u
+		}
+	}
+	m.mu.Unlock()
+	if m.afterLookup != nil {
+		m.afterLookup()
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return found, nil
+}
+
+func (m *memoryRepo) GetUserByUsername(_ context.Context, username string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Username != nil && *u.Username == username && !u.DeletedAt.Valid {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) GetUserByID(_ context.Context, id string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok && !u.DeletedAt.Valid {
+		return u, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) ListUsers(_ context.Context, filter UserFilter) ([]User, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []User
+	for _, u := range m.users {
+		if filter.Role != "" && u.Role != filter.Role {
+			continue
+		}
+		if filter.Verified != nil && u.EmailVerified != *filter.Verified {
+			continue
+		}
+		matched = append(matched, *u)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Email < matched[j].Email })
+	total := int64(len(matched))
+	if filter.Offset >= len(matched) {
+		return nil, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+func (m *memoryRepo) UpdateUser(_ context.Context, id string, updates map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if role, ok := updates["role"].(string); ok {
+		u.Role = role
+	}
+	if active, ok := updates["is_active"].(bool); ok {
+		u.IsActive = active
+	}
+	if _, ok := updates["token_version"].(clause.Expr); ok {
+		u.TokenVersion++ // token_version + 1
+	}
+	if hash, ok := updates["password_hash"].(string); ok {
+		u.PasswordHash = hash
+	}
+	return nil
+}
+
+func (m *memoryRepo) RevokeUserTokens(_ context.Context, userID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.refreshTokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &at
+		}
+	}
+	for _, k := range m.keys {
+		if k.UserID == userID && k.RevokedAt == nil {
+			k.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) PurgeTokens(_ context.Context, now time.Time) (TokenPurge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var purged TokenPurge
+	families := map[string]bool{}
+	for id, t := range m.refreshTokens {
+		if t.ExpiresAt.Before(now) || t.RevokedAt != nil {
+			delete(m.refreshTokens, id)
+			purged.RefreshTokens++
+		} else {
+			families[t.FamilyID] = true
+		}
+	}
+	for id, s := range m.sessions {
+		if (s.ExpiresAt.Before(now) || s.RevokedAt != nil) && !families[id] {
+			delete(m.sessions, id)
+			purged.Sessions++
+		}
+	}
+	return purged, nil
+}
+
+func (m *memoryRepo) GetOrganization(_ context.Context, id string) (*Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if org, ok := m.orgs[id]; ok {
+		return org, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) GetOrganizationByJoinCode(_ context.Context, code string) (*Organization, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, org := range m.orgs {
+		if org.JoinCode == code {
+			return org, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) CreateAPIKey(_ context.Context, key *APIKey) error {
+	key.ID = key.Prefix
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *memoryRepo) GetAPIKeyByHash(_ context.Context, hash string) (*APIKey, error) {
+	for _, k := range m.keys {
+		if k.KeyHash == hash {
+			return k, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) ListAPIKeys(_ context.Context, userID string) ([]APIKey, error) {
+	var out []APIKey
+	for _, k := range m.keys {
+		if k.UserID == userID {
+			out = append(out, *k)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryRepo) RevokeAPIKey(_ context.Context, userID, keyID string, at time.Time) error {
+	k, ok := m.keys[keyID]
+	if !ok || k.UserID != userID || k.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	k.RevokedAt = &at
+	return nil
+}
+
+func (m *memoryRepo) TouchAPIKey(_ context.Context, keyID string, at time.Time) error {
+	if k, ok := m.keys[keyID]; ok {
+		k.LastUsedAt = &at
+	}
+	return nil
+}
+
+func (m *memoryRepo) CreateRefreshToken(_ context.Context, token *RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token.ID = token.TokenHash[:12]
+	m.refreshTokens[token.ID] = token
+	return nil
+}
+
+func (m *memoryRepo) GetRefreshTokenByHash(_ context.Context, hash string) (*RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.refreshTokens {
+		if t.TokenHash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memoryRepo) MarkRefreshTokenUsed(_ context.Context, id string, at time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.refreshTokens[id]
+	if !ok || t.UsedAt != nil || t.RevokedAt != nil {
+		return false, nil
+	}
+	t.UsedAt = &at
+	return true, nil
+}
+
+func (m *memoryRepo) RevokeRefreshTokenFamily(_ context.Context, familyID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.refreshTokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &at
+		}
+	}
+	if s, ok := m.sessions[familyID]; ok && s.RevokedAt == nil {
+		s.RevokedAt = &at
+	}
+	return nil
+}
+
+func (m *memoryRepo) CreateSession(_ context.Context, session *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *memoryRepo) TouchSession(_ context.Context, id string, client ClientInfo, at, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok {
+		s.UserAgent, s.IPAddress = client.UserAgent, client.IPAddress
+		s.LastSeenAt, s.ExpiresAt = at, expiresAt
+	}
+	return nil
+}
+
+func (m *memoryRepo) ListSessions(_ context.Context, userID string, now time.Time) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Session
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil && s.ExpiresAt.After(now) {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryRepo) RevokeSession(ctx context.Context, userID, sessionID string, at time.Time) error {
+	m.mu.Lock()
+	s, ok := m.sessions[sessionID]
+	live := ok && s.UserID == userID && s.RevokedAt == nil
+	m.mu.Unlock()
+	if !live {
+		return gorm.ErrRecordNotFound
+	}
+	return m.RevokeRefreshTokenFamily(ctx, sessionID, at)
+}
+
+func newAPIKeyRouter(t *testing.T) (*gin.Engine, *AuthService, *User) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	repo := newMemoryRepo()
+	user := &User{ID: "partner-1", Email: "partner@example.com", Role: "partner", IsActive: true}
+	_ = repo.CreateUser(context.Background(), user)
+	service := NewAuthService(repo)
+
+	router := gin.New()
+	router.Use(ResolveAPIKey(service))
+	router.GET("/data", AuthMiddlewareOrAPIKey(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "role": c.GetString("role")})
+	})
+	return router, service, user
+}
+
+func requestWithKey(router *gin.Engine, key string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/data", nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestResolveAPIKey_ValidKey(t *testing.T) {
+	router, service, user := newAPIKeyRouter(t)
+	created, err := service.CreateAPIKey(context.Background(), user.ID, CreateAPIKeyRequest{Name: "etl", Scopes: []string{"projects:read"}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if created.APIKey.KeyHash == created.Key {
+		t.Fatal("key must be stored hashed")
+	}
+
+	w := requestWithKey(router, created.Key)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); body != `{"role":"partner","user_id":"partner-1"}` {
+		t.Errorf("unexpected identity in context: %s", body)
+	}
+}
+
+func TestResolveAPIKey_RevokedKey(t *testing.T) {
+	router, service, user := newAPIKeyRouter(t)
+	created, _ := service.CreateAPIKey(context.Background(), user.ID, CreateAPIKeyRequest{Name: "etl"})
+	if err := service.RevokeAPIKey(context.Background(), user.ID, created.APIKey.ID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+
+	if w := requestWithKey(router, created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for revoked key, got %d", w.Code)
+	}
+}
+
+func TestResolveAPIKey_ExpiredKey(t *testing.T) {
+	router, service, user := newAPIKeyRouter(t)
+	expiry := time.Now().Add(time.Hour)
+	created, err := service.CreateAPIKey(context.Background(), user.ID, CreateAPIKeyRequest{Name: "etl", ExpiresAt: &expiry})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	service.now = func() time.Time { return expiry.Add(time.Second) }
+
+	if w := requestWithKey(router, created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for expired key, got %d", w.Code)
+	}
+}
+
+func TestResolveAPIKey_MissingOrUnknownKey(t *testing.T) {
+	router, _, _ := newAPIKeyRouter(t)
+
+	if w := requestWithKey(router, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a key, got %d", w.Code)
+	}
+	if w := requestWithKey(router, apiKeyPrefix+"deadbeef"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown key, got %d", w.Code)
+	}
+}
+
+// failingKeys cannot reach the key store.
+type failingKeys struct {
+	Authenticator
+}
+
+func (failingKeys) AuthenticateAPIKey(context.Context, string) (*User, *APIKey, error) {
+	return nil, nil, errors.New("connection refused")
+}
+
+func TestResolveAPIKey_LookupFailureIsUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ResolveAPIKey(failingKeys{}))
+	router.GET("/data", AuthMiddlewareOrAPIKey(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if w := requestWithKey(router, apiKeyPrefix+"deadbeef"); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when the key cannot be looked up, got %d", w.Code)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	useTestJWTConfig(t)
+	gin.SetMode(gin.TestMode)
+	repo := newMemoryRepo()
+	user := &User{ID: "partner-1", Email: "partner@example.com", Role: "partner", IsActive: true}
+	_ = repo.CreateUser(context.Background(), user)
+	service := NewAuthService(repo)
+
+	router := gin.New()
+	router.Use(ResolveAPIKey(service))
+	projects := router.Group("/projects", AuthMiddlewareOrAPIKey(), RequireScope(ScopeProjectsRead, ScopeProjectsWrite))
+	projects.GET("", func(c *gin.Context) { c.Status(http.StatusOK) })
+	projects.POST("", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	send := func(method, key, token string) int {
+		req := httptest.NewRequest(method, "/projects", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	newKey := func(scopes ...string) string {
+		created, err := service.CreateAPIKey(context.Background(), user.ID, CreateAPIKeyRequest{Name: "etl", Scopes: scopes})
+		if err != nil {
+			t.Fatalf("CreateAPIKey: %v", err)
+		}
+		return created.Key
+	}
+
+	reader, writer, other := newKey(ScopeProjectsRead), newKey(ScopeProjectsWrite), newKey(ScopeGeospatialRead)
+	for _, tc := range []struct {
+		name, method, key string
+		want              int
+	}{
+		{"read key reads", http.MethodGet, reader, http.StatusOK},
+		{"read key writes", http.MethodPost, reader, http.StatusForbidden},
+		{"write key reads", http.MethodGet, writer, http.StatusOK},
+		{"write key writes", http.MethodPost, writer, http.StatusCreated},
+		{"other scope", http.MethodGet, other, http.StatusForbidden},
+	} {
+		if got := send(tc.method, tc.key, ""); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	token, _ := GenerateJWT(user)
+	if got := send(http.MethodPost, "", token); got != http.StatusCreated {
+		t.Errorf("access token: expected scopes not to apply, got %d", got)
+	}
+	if _, err := service.CreateAPIKey(context.Background(), user.ID, CreateAPIKeyRequest{Name: "etl", Scopes: []string{"admin"}}); !errors.Is(err, ErrUnknownScope) {
+		t.Errorf("expected ErrUnknownScope for an unknown scope, got %v", err)
+	}
+}
+
+func TestResolveAPIKey_GivesTheRateLimiterTheKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newMemoryRepo()
+	user := &User{ID: "partner-1", Email: "partner@example.com", Role: "partner", IsActive: true}
+	_ = repo.CreateUser(context.Background(), user)
+	service := NewAuthService(repo)
+	created, err := service.CreateAPIKey(context.Background(), user.ID, CreateAPIKeyRequest{Name: "etl", Scopes: []string{ScopeProjectsRead}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	// The order cmd/api mounts them in.
+	router := gin.New()
+	router.Use(ResolveAPIKey(service), middleware.RateLimit(middleware.RateLimitConfig{Rate: 0.001, Burst: 1}))
+	router.GET("/data", func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(ip, key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/data", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("203.0.113.7", ""); code != http.StatusOK {
+		t.Fatalf("expected the first anonymous request to pass, got %d", code)
+	}
+	if code := get("203.0.113.7", created.Key); code != http.StatusOK {
+		t.Fatalf("expected the API key to have its own bucket, got %d", code)
+	}
+	if code := get("192.0.2.50", created.Key); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the API key's bucket to follow it across IPs, got %d", code)
+	}
+	if code := get("192.0.2.50", apiKeyPrefix+"deadbeef"); code != http.StatusUnauthorized {
+		t.Fatalf("expected an unknown key to be refused, got %d", code)
+	}
+}
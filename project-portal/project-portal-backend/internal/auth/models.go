@@ -1,17 +1,192 @@
 package auth
 
 import (
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID            string    `json:"id"`
-	Email         string    `json:"email"`
-	PasswordHash  string    `json:"-"`
+	ID            string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Email         string    `json:"email" gorm:"uniqueIndex;not null"`
+	Username      *string   `json:"username,omitempty" gorm:"uniqueIndex"`
+	PasswordHash  string    `json:"-" gorm:"not null"`
 	FullName      string    `json:"full_name"`
-	Role          string    `json:"role"`
-	EmailVerified bool      `json:"email_verified"`
-	IsActive      bool      `json:"is_active"`
+	Role          string    `json:"role" gorm:"not null;default:'user'"`
+	EmailVerified bool      `json:"email_verified" gorm:"not null;default:false"`
+	IsActive      bool      `json:"is_active" gorm:"not null;default:true"`
+	TokenVersion  int       `json:"-" gorm:"not null;default:0"`
+	OrgID         *string   `json:"org_id,omitempty" gorm:"type:uuid;index"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
+	// DeletedAt marks an account its owner deleted; it is erased once the
+	// deletion grace period has passed.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Organization, when set on a new user, is created together with it.
+	Organization *Organization `json:"-" gorm:"foreignKey:OrgID"`
+}
+
+func (u *User) orgID() string {
+	if u.OrgID == nil {
+		return ""
+	}
+	return *u.OrgID
+}
+
+// Organization is a tenant. Its members share its projects and see no one
+// else's. New users join one with its JoinCode, which members can look up.
+type Organization struct {
+	ID        string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name      string    `json:"name" gorm:"not null"`
+	JoinCode  string    `json:"join_code" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIKey is a long-lived machine credential owned by a user. Only the SHA-256
+// hash of the key is stored; the plaintext is returned once at creation.
+type APIKey struct {
+	ID         string         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID     string         `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name       string         `json:"name" gorm:"not null"`
+	Prefix     string         `json:"prefix" gorm:"not null"`
+	KeyHash    string         `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     pq.StringArray `json:"scopes" gorm:"type:text[]"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// RefreshToken is one link in a rotation chain. Every login starts a new
+// family; each refresh marks the presented token used and issues its successor
+// in the same family. Presenting a used token revokes the whole family.
+type RefreshToken struct {
+	ID        string     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	FamilyID  string     `json:"family_id" gorm:"type:uuid;not null;index"`
+	ParentID  *string    `json:"parent_id,omitempty" gorm:"type:uuid"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Session is a signed-in device: one refresh-token family, from the login that
+// started it until it expires or is revoked. Its ID is the family ID and is
+// carried in access tokens as the sid claim.
+type Session struct {
+	ID         string     `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID     string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Current marks the session the listing request was made from.
+	Current bool `json:"current" gorm:"-"`
+}
+
+// ClientInfo describes the client a login or refresh came from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// RegisterRequest signs a user up. Organization names a new organization to
+// create with the user as its first member; JoinCode joins an existing one.
+// At most one of the two may be given.
+type RegisterRequest struct {
+	Email        string `json:"email" binding:"required,trimmed_email"`
+	Username     string `json:"username" binding:"omitempty,min=3,max=32"`
+	Password     string `json:"password" binding:"required,min=8,max=128"`
+	FullName     string `json:"full_name" binding:"max=200"`
+	Organization string `json:"organization" binding:"max=200"`
+	JoinCode     string `json:"join_code" binding:"max=64"`
+}
+
+// CreateUserRequest is used by administrators to create accounts with a role.
+type CreateUserRequest struct {
+	Email    string `json:"email" binding:"required,trimmed_email"`
+	Username string `json:"username" binding:"omitempty,min=3,max=32"`
+	Password string `json:"password" binding:"required,min=8,max=128"`
+	FullName string `json:"full_name" binding:"max=200"`
+	Role     string `json:"role"`
+}
+
+// UserFilter narrows the administrator's user listing. Role and Verified are
+// optional.
+type UserFilter struct {
+	Limit    int
+	Offset   int
+	Role     string
+	Verified *bool
+}
+
+// UpdateUserRequest changes an account's role or active flag. Omitted fields
+// are left alone.
+type UpdateUserRequest struct {
+	Role   *string `json:"role"`
+	Active *bool   `json:"active"`
+}
+
+// LoginRequest identifies the account by Identifier, which is matched against
+// email when it contains "@" and against username otherwise. Email is still
+// accepted for older clients.
+type LoginRequest struct {
+	Identifier string `json:"identifier" binding:"max=254"`
+	Email      string `json:"email" binding:"omitempty,trimmed_email"`
+	Password   string `json:"password" binding:"required,max=128"`
+}
+
+func (r LoginRequest) identifier() string {
+	if id := strings.TrimSpace(r.Identifier); id != "" {
+		return id
+	}
+	return strings.TrimSpace(r.Email)
+}
+
+// LoginResponse carries a new token pair. ExpiresIn (seconds) and ExpiresAt
+// both describe the access token, so clients can refresh before it lapses.
+type LoginResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    int64     `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         *User     `json:"user"`
+}
+
+// RefreshRequest carries the refresh token. Clients holding it in the
+// refresh token cookie may leave it out.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// API key scopes. A key reaches only the route groups whose scope it was
+// granted; see RequireScope.
+const (
+	ScopeProjectsRead    = "projects:read"
+	ScopeProjectsWrite   = "projects:write"
+	ScopeGeospatialRead  = "geospatial:read"
+	ScopeGeospatialWrite = "geospatial:write"
+)
+
+// APIKeyScopes lists the scopes a key may be created with.
+var APIKeyScopes = []string{ScopeProjectsRead, ScopeProjectsWrite, ScopeGeospatialRead, ScopeGeospatialWrite}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes,omitempty" binding:"max=20"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKeyResponse carries the plaintext key. It is never retrievable again.
+type CreateAPIKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"api_key"`
 }
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions checked by handlers. Handlers ask for a permission, never a role.
+const (
+	PermProjectsRead        = "projects:read"
+	PermProjectsWrite       = "projects:write"
+	PermProjectsVerify      = "projects:verify"
+	PermProjectsViewDeleted = "projects:view_deleted"
+	PermProjectsManageAll   = "projects:manage_all"
+	PermUsersManage         = "users:manage"
+	PermSystemDiagnostics   = "system:diagnostics"
+)
+
+// RoleDefinition grants permissions directly and through inherited roles.
+type RoleDefinition struct {
+	Inherits    []string
+	Permissions []string
+}
+
+// DefaultRoles is the role hierarchy: admin ⊃ verifier ⊃ user.
+var DefaultRoles = map[string]RoleDefinition{
+	"user": {
+		Permissions: []string{PermProjectsRead, PermProjectsWrite},
+	},
+	"partner": {
+		Permissions: []string{PermProjectsRead},
+	},
+	"verifier": {
+		Inherits:    []string{"user"},
+		Permissions: []string{PermProjectsVerify},
+	},
+	"admin": {
+		Inherits:    []string{"verifier"},
+		Permissions: []string{PermProjectsViewDeleted, PermProjectsManageAll, PermUsersManage, PermSystemDiagnostics},
+	},
+}
+
+// PermissionModel maps each role to its fully resolved permission set.
+type PermissionModel struct {
+	byRole map[string]map[string]struct{}
+}
+
+var permissionModel = mustPermissionModel(DefaultRoles)
+
+// NewPermissionModel resolves inheritance. Unknown parents and cycles are errors.
+func NewPermissionModel(roles map[string]RoleDefinition) (*PermissionModel, error) {
+	m := &PermissionModel{byRole: make(map[string]map[string]struct{}, len(roles))}
+	for role := range roles {
+		perms := map[string]struct{}{}
+		if err := collectPermissions(roles, role, perms, map[string]bool{}); err != nil {
+			return nil, err
+		}
+		m.byRole[role] = perms
+	}
+	return m, nil
+}
+
+func mustPermissionModel(roles map[string]RoleDefinition) *PermissionModel {
+	m, err := NewPermissionModel(roles)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func collectPermissions(roles map[string]RoleDefinition, role string, into map[string]struct{}, visiting map[string]bool) error {
+	def, ok := roles[role]
+	if !ok {
+		return fmt.Errorf("role %q is not defined", role)
+	}
+	if visiting[role] {
+		return fmt.Errorf("role %q inherits from itself", role)
+	}
+	visiting[role] = true
+	defer delete(visiting, role)
+
+	for _, p := range def.Permissions {
+		into[p] = struct{}{}
+	}
+	for _, parent := range def.Inherits {
+		if err := collectPermissions(roles, parent, into, visiting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Defined reports whether role is part of the model.
+func (m *PermissionModel) Defined(role string) bool {
+	_, ok := m.byRole[role]
+	return ok
+}
+
+// Has reports whether role grants perm. Unknown roles grant nothing.
+func (m *PermissionModel) Has(role, perm string) bool {
+	_, ok := m.byRole[role][perm]
+	return ok
+}
+
+// Permissions lists the resolved permissions of a role, sorted.
+func (m *PermissionModel) Permissions(role string) []string {
+	out := make([]string, 0, len(m.byRole[role]))
+	for p := range m.byRole[role] {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ParseRoles reads role definitions of the form role=item,item separated by
+// semicolons, e.g. "auditor=projects:read;lead=@verifier,users:manage", on top
+// of DefaultRoles. An item starting with @ inherits that role; any other item
+// is a permission. An entry for an existing role replaces its definition.
+func ParseRoles(spec string) (map[string]RoleDefinition, error) {
+	roles := make(map[string]RoleDefinition, len(DefaultRoles))
+	for name, def := range DefaultRoles {
+		roles[name] = def
+	}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, items, ok := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			return nil, fmt.Errorf("role entry %q must be role=permission,@parent", entry)
+		}
+		var def RoleDefinition
+		for _, item := range strings.Split(items, ",") {
+			item = strings.TrimSpace(item)
+			switch {
+			case item == "":
+			case strings.HasPrefix(item, "@"):
+				def.Inherits = append(def.Inherits, strings.ToLower(item[1:]))
+			default:
+				def.Permissions = append(def.Permissions, item)
+			}
+		}
+		roles[name] = def
+	}
+	return roles, nil
+}
+
+// ConfigurePermissions replaces the role hierarchy used by HasPermission and
+// RequirePermission.
+func ConfigurePermissions(roles map[string]RoleDefinition) error {
+	m, err := NewPermissionModel(roles)
+	if err != nil {
+		return err
+	}
+	permissionModel = m
+	return nil
+}
+
+// HasPermission reports whether role grants perm under the configured model.
+func HasPermission(role, perm string) bool {
+	return permissionModel.Has(role, perm)
+}
+
+// RequirePermission rejects requests whose role lacks perm. It must run after
+// AuthMiddleware or ResolveAPIKey, which put the role in the context.
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			c.Abort()
+			return
+		}
+		if !HasPermission(role, perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "missing permission: " + perm})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func permissionRouter(role string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/verify", func(c *gin.Context) {
+		if role != "" {
+			c.Set("role", role)
+		}
+		c.Next()
+	}, RequirePermission(PermProjectsVerify), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func TestRequirePermission_RoleHierarchy(t *testing.T) {
+	cases := map[string]int{
+		"admin":    http.StatusNoContent,
+		"verifier": http.StatusNoContent,
+		"user":     http.StatusForbidden,
+		"unknown":  http.StatusForbidden,
+		"":         http.StatusUnauthorized,
+	}
+	for role, want := range cases {
+		w := httptest.NewRecorder()
+		permissionRouter(role).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verify", nil))
+		if w.Code != want {
+			t.Errorf("role %q: got %d, want %d", role, w.Code, want)
+		}
+	}
+}
+
+func TestNewPermissionModel_ResolvesInheritance(t *testing.T) {
+	m, err := NewPermissionModel(DefaultRoles)
+	if err != nil {
+		t.Fatalf("NewPermissionModel: %v", err)
+	}
+	for _, perm := range []string{PermProjectsRead, PermProjectsVerify, PermUsersManage} {
+		if !m.Has("admin", perm) {
+			t.Errorf("admin should have %s", perm)
+		}
+	}
+	if m.Has("verifier", PermUsersManage) {
+		t.Error("verifier must not inherit admin permissions")
+	}
+}
+
+func TestNewPermissionModel_RejectsCyclesAndUnknownParents(t *testing.T) {
+	cyclic := map[string]RoleDefinition{
+		"a": {Inherits: []string{"b"}},
+		"b": {Inherits: []string{"a"}},
+	}
+	if _, err := NewPermissionModel(cyclic); err == nil {
+		t.Error("expected an error for an inheritance cycle")
+	}
+	if _, err := NewPermissionModel(map[string]RoleDefinition{"a": {Inherits: []string{"ghost"}}}); err == nil {
+		t.Error("expected an error for an undefined parent role")
+	}
+}
+
+func TestParseRoles_ExtendsDefaults(t *testing.T) {
+	roles, err := ParseRoles("auditor=projects:read; lead=@verifier,users:manage ;user=projects:read")
+	if err != nil {
+		t.Fatalf("ParseRoles: %v", err)
+	}
+	m, err := NewPermissionModel(roles)
+	if err != nil {
+		t.Fatalf("NewPermissionModel: %v", err)
+	}
+	if !m.Has("auditor", PermProjectsRead) || m.Has("auditor", PermProjectsWrite) {
+		t.Errorf("auditor: got %v", m.Permissions("auditor"))
+	}
+	if !m.Has("lead", PermProjectsVerify) || !m.Has("lead", PermUsersManage) {
+		t.Errorf("lead should inherit verifier and add users:manage, got %v", m.Permissions("lead"))
+	}
+	if m.Has("user", PermProjectsWrite) || !m.Has("admin", PermUsersManage) {
+		t.Error("an entry should replace its role and leave the other defaults alone")
+	}
+	if _, err := ParseRoles("auditor"); err == nil {
+		t.Error("expected an error for an entry without =")
+	}
+}
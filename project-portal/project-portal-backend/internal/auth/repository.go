@@ -1,48 +1,371 @@
 package auth
 
-import "database/sql"
-
-type Repository struct {
-	DB *sql.DB
-}
-
-func NewRepository(db *sql.DB) *Repository {
-	return &Repository{DB: db}
-}
-
-func (r *Repository) CreateUser(user *User) error {
-	query := `
-		INSERT INTO users (email, password_hash, full_name, role)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
-	`
-	return r.DB.QueryRow(
-		query,
-		user.Email,
-		user.PasswordHash,
-		user.FullName,
-		user.Role,
-	).Scan(&user.ID, &user.CreatedAt)
-}
-
-func (r *Repository) GetUserByEmail(email string) (*User, error) {
-	user := &User{}
-	query := `
-		SELECT id, email, password_hash, full_name, role, email_verified, is_active, created_at
-		FROM users WHERE email = $1
-	`
-	err := r.DB.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.FullName,
-		&user.Role,
-		&user.EmailVerified,
-		&user.IsActive,
-		&user.CreatedAt,
-	)
-	if err != nil {
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateUser(ctx context.Context, user *User) error
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	ListUsers(ctx context.Context, filter UserFilter) ([]User, int64, error)
+	UpdateUser(ctx context.Context, id string, updates map[string]interface{}) error
+	CountOwnedProjects(ctx context.Context, userID string) (int64, error)
+	SoftDeleteUser(ctx context.Context, id string, at time.Time) error
+	ListPurgeableUsers(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	PurgeUser(ctx context.Context, id string) error
+
+	GetOrganization(ctx context.Context, id string) (*Organization, error)
+	GetOrganizationByJoinCode(ctx context.Context, code string) (*Organization, error)
+
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID string, at time.Time) error
+	TouchAPIKey(ctx context.Context, keyID string, at time.Time) error
+
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id string, at time.Time) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string, at time.Time) error
+	RevokeUserTokens(ctx context.Context, userID string, at time.Time) error
+	PurgeTokens(ctx context.Context, now time.Time) (TokenPurge, error)
+
+	CreateSession(ctx context.Context, session *Session) error
+	TouchSession(ctx context.Context, id string, client ClientInfo, at, expiresAt time.Time) error
+	ListSessions(ctx context.Context, userID string, now time.Time) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string, at time.Time) error
+
+	Ping(ctx context.Context) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// CreateUser inserts user and, in the same transaction, a new Organization
+// set on it.
+func (r *repository) CreateUser(ctx context.Context, user *User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+// GetUserByEmail matches case-insensitively so accounts stored before emails
+// were lowercased on registration can still sign in.
+func (r *repository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	var user User
+	if err := r.db.WithContext(ctx).Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *repository) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	var user User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByID returns the user id. An id that is not a UUID names no user;
+// it is reported as gorm.ErrRecordNotFound instead of a cast error.
+func (r *repository) GetUserByID(ctx context.Context, id string) (*User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var user User
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
 		return nil, err
 	}
-	return user, nil
+	return &user, nil
+}
+
+// ListUsers returns one page of users, newest first, and the number of users
+// matching the filter.
+func (r *repository) ListUsers(ctx context.Context, filter UserFilter) ([]User, int64, error) {
+	q := r.db.WithContext(ctx).Model(&User{})
+	if filter.Role != "" {
+		q = q.Where("role = ?", filter.Role)
+	}
+	if filter.Verified != nil {
+		q = q.Where("email_verified = ?", *filter.Verified)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []User
+	err := q.Order("created_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error
+	return users, total, err
+}
+
+// UpdateUser applies updates to one user. It returns gorm.ErrRecordNotFound
+// when there is no such user.
+func (r *repository) UpdateUser(ctx context.Context, id string, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetOrganization(ctx context.Context, id string) (*Organization, error) {
+	var org Organization
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&org).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *repository) GetOrganizationByJoinCode(ctx context.Context, code string) (*Organization, error) {
+	var org Organization
+	if err := r.db.WithContext(ctx).Where("join_code = ?", code).First(&org).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *repository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *repository) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	var key APIKey
+	if err := r.db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *repository) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	var keys []APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *repository) RevokeAPIKey(ctx context.Context, userID, keyID string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *repository) TouchAPIKey(ctx context.Context, keyID string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", keyID).Update("last_used_at", at).Error
+}
+
+func (r *repository) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *repository) GetRefreshTokenByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed flags the token as rotated out. It reports false when
+// the token was already used, so two concurrent refreshes cannot both win.
+func (r *repository) MarkRefreshTokenUsed(ctx context.Context, id string, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RevokeRefreshTokenFamily revokes every token in the family and ends the
+// session it belongs to.
+func (r *repository) RevokeRefreshTokenFamily(ctx context.Context, familyID string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return revokeFamily(tx, familyID, at)
+	})
+}
+
+// RevokeUserTokens revokes every refresh token, session and API key of a user.
+func (r *repository) RevokeUserTokens(ctx context.Context, userID string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return revokeUserTokens(tx, userID, at)
+	})
+}
+
+func revokeUserTokens(tx *gorm.DB, userID string, at time.Time) error {
+	for _, model := range []interface{}{&RefreshToken{}, &Session{}, &APIKey{}} {
+		if err := tx.Model(model).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountOwnedProjects counts the live projects userID owns. Deleted projects
+// do not count; PurgeUser releases them when the account is erased.
+func (r *repository) CountOwnedProjects(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Raw("SELECT COUNT(*) FROM projects WHERE owner_id = ? AND deleted_at IS NULL", userID).
+		Scan(&count).Error
+	return count, err
+}
+
+// SoftDeleteUser deactivates and soft-deletes user id, moves its token
+// version on and revokes its tokens, in one transaction.
+func (r *repository) SoftDeleteUser(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"is_active":     false,
+			"token_version": gorm.Expr("token_version + 1"),
+			"deleted_at":    at,
+			"updated_at":    at,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return revokeUserTokens(tx, id, at)
+	})
+}
+
+// ListPurgeableUsers returns the ids of the users soft-deleted before
+// deletedBefore.
+func (r *repository) ListPurgeableUsers(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Unscoped().Model(&User{}).
+		Where("deleted_at < ?", deletedBefore).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PurgeUser erases the soft-deleted user id together with its API keys,
+// refresh tokens and sessions, in one transaction. References that outlive the
+// account are cleared: alerts it acknowledged, and projects it owned that were
+// deleted since, which stay restorable by administrators. A user owning a live
+// project again, say after a restore, is refused with ErrOwnsProjects.
+func (r *repository) PurgeUser(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var live int64
+		if err := tx.Raw("SELECT COUNT(*) FROM projects WHERE owner_id = ? AND deleted_at IS NULL", id).Scan(&live).Error; err != nil {
+			return err
+		}
+		if live > 0 {
+			return fmt.Errorf("%w (%d owned)", ErrOwnsProjects, live)
+		}
+		for _, stmt := range []string{
+			"UPDATE projects SET owner_id = NULL WHERE owner_id = ?",
+			"UPDATE system_alerts SET acknowledged_by = NULL WHERE acknowledged_by = ?",
+		} {
+			if err := tx.Exec(stmt, id).Error; err != nil {
+				return err
+			}
+		}
+		for _, model := range []interface{}{&APIKey{}, &RefreshToken{}, &Session{}} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(&User{}).Error
+	})
+}
+
+// PurgeTokens deletes refresh tokens that are expired or revoked, then the
+// expired or revoked sessions left without tokens. Used tokens are kept until
+// they expire: presenting one is how reuse is detected.
+func (r *repository) PurgeTokens(ctx context.Context, now time.Time) (TokenPurge, error) {
+	var purged TokenPurge
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("expires_at < ? OR revoked_at IS NOT NULL", now).Delete(&RefreshToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged.RefreshTokens = result.RowsAffected
+
+		result = tx.Where("(expires_at < ? OR revoked_at IS NOT NULL) AND NOT EXISTS (?)", now,
+			tx.Model(&RefreshToken{}).Select("1").Where("refresh_tokens.family_id = sessions.id")).
+			Delete(&Session{})
+		purged.Sessions = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+func revokeFamily(tx *gorm.DB, familyID string, at time.Time) error {
+	if err := tx.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error; err != nil {
+		return err
+	}
+	return tx.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *repository) CreateSession(ctx context.Context, session *Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// TouchSession records a refresh: the session was seen at at from client and
+// now lives until expiresAt.
+func (r *repository) TouchSession(ctx context.Context, id string, client ClientInfo, at, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"user_agent":   client.UserAgent,
+		"ip_address":   client.IPAddress,
+		"last_seen_at": at,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// ListSessions returns the user's live sessions, most recently used first.
+func (r *repository) ListSessions(ctx context.Context, userID string, now time.Time) ([]Session, error) {
+	var sessions []Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession ends one of the user's live sessions and revokes its refresh
+// tokens. It returns gorm.ErrRecordNotFound when the user has no such session.
+func (r *repository) RevokeSession(ctx context.Context, userID, sessionID string, at time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var session Session
+		if err := tx.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+			First(&session).Error; err != nil {
+			return err
+		}
+		return revokeFamily(tx, session.ID, at)
+	})
+}
+
+// Ping checks that the database behind the repository answers.
+func (r *repository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
 }
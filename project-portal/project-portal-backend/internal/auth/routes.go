@@ -8,6 +8,32 @@ func RegisterRoutes(r *gin.Engine, handler *Handler) {
 		authGroup.GET("/ping", handler.Ping)
 		authGroup.POST("/register", handler.Register)
 		authGroup.POST("/login", handler.Login)
+		authGroup.POST("/refresh", handler.Refresh)
+
+		// User administration
+		users := authGroup.Group("/users", AuthMiddleware(), RequirePermission(PermUsersManage))
+		users.POST("", handler.CreateUser)
+		users.POST("/import", handler.ImportUsers)
+		users.GET("", handler.ListUsers)
+		users.GET("/:id", handler.GetUser)
+		users.PATCH("/:id", handler.UpdateUser)
+
+		// API key management for the signed-in user
+		apiKeys := authGroup.Group("/api-keys", AuthMiddleware())
+		apiKeys.POST("", handler.CreateAPIKey)
+		apiKeys.GET("", handler.ListAPIKeys)
+		apiKeys.DELETE("/:id", handler.RevokeAPIKey)
+
+		// Self-service account deletion
+		authGroup.DELETE("/me", AuthMiddleware(), handler.DeleteMe)
+
+		// Organization of the current user
+		authGroup.GET("/organization", AuthMiddleware(), handler.GetOrganization)
+
+		// Signed-in devices of the current user
+		sessions := authGroup.Group("/sessions", AuthMiddleware())
+		sessions.GET("", handler.ListSessions)
+		sessions.DELETE("/:id", handler.RevokeSession)
 
 		// Submission endpoints
 		authGroup.POST("/submit", SubmitQuest)
@@ -1,32 +1,661 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+const apiKeyPrefix = "cs_"
+
+var (
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidUsername    = errors.New("username may only contain letters, digits, '.', '_' and '-'")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInactiveUser       = errors.New("user account is disabled")
+	ErrEmailNotVerified   = errors.New("email address is not verified")
+	ErrInvalidAPIKey      = errors.New("invalid api key")
+	ErrAPIKeyRevoked      = errors.New("api key has been revoked")
+	ErrAPIKeyExpired      = errors.New("api key has expired")
+	ErrUnknownScope       = errors.New("unknown api key scope")
+	ErrRoleNotAllowed     = errors.New("role is not assignable")
+	ErrSelfDeactivation   = errors.New("administrators cannot deactivate their own account")
+	ErrOrganizationChoice = errors.New("give either organization or join_code, not both")
+	ErrInvalidJoinCode    = errors.New("invalid organization join code")
+	ErrNoOrganization     = errors.New("user does not belong to an organization")
+
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected; all sessions in this family were revoked")
+)
+
+// RolePolicy decides which role self-registered users get and which roles an
+// administrator may assign when creating accounts.
+type RolePolicy struct {
+	DefaultRole string
+	Assignable  []string
+}
+
+// DefaultRolePolicy keeps admin out of the assignable set, so admin accounts
+// can only be created out of band.
+var DefaultRolePolicy = RolePolicy{
+	DefaultRole: "user",
+	Assignable:  []string{"user", "partner", "verifier"},
+}
+
+func (p RolePolicy) allows(role string) bool {
+	for _, r := range p.Assignable {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator is what the HTTP handler needs from the auth service:
+// accounts, sign-in and token issuance, sessions and API keys. AuthService
+// implements it; handler tests can substitute a mock.
+type Authenticator interface {
+	Register(ctx context.Context, req RegisterRequest) (*User, error)
+	Login(ctx context.Context, req LoginRequest, client ClientInfo) (*LoginResponse, error)
+	Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*LoginResponse, error)
+	ListSessions(ctx context.Context, userID, currentID string) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+
+	CreateUser(ctx context.Context, req CreateUserRequest) (*User, error)
+	ImportUsers(ctx context.Context, orgID string, rows []ImportUserRow) (*ImportUsersResult, error)
+	ListUsers(ctx context.Context, filter UserFilter) ([]User, int64, error)
+	GetUser(ctx context.Context, userID string) (*User, error)
+	UpdateUser(ctx context.Context, actorID, userID string, req UpdateUserRequest) (*User, error)
+	DeleteAccount(ctx context.Context, userID string, req DeleteAccountRequest) (*AccountDeletion, error)
+	Organization(ctx context.Context, orgID string) (*Organization, error)
+
+	CreateAPIKey(ctx context.Context, userID string, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID string) error
+	AuthenticateAPIKey(ctx context.Context, plaintext string) (*User, *APIKey, error)
+
+	CheckStore(ctx context.Context) error
+}
+
 type AuthService struct {
-	users map[string]string // map[email]password
+	repo  Repository
+	roles RolePolicy
+	now   func() time.Time
+	// sessionLimit caps live sessions per user; see SetSessionLimit.
+	sessionLimit SessionLimit
+	// checkPassword verifies a password against a stored hash.
+	checkPassword func(password, hashed string) error
+	// deletionGrace is how long deleted accounts are kept; see
+	// SetDeletionGrace.
+	deletionGrace time.Duration
+	// metrics counts logins and revocations when set; see SetMetrics.
+	metrics *Metrics
+	// failures watches failed logins; see SetLoginFailureAlert.
+	failures *failureTracker
+	// emails normalizes addresses; see SetEmailNormalization.
+	emails EmailNormalization
+	// requireVerifiedEmail refuses logins to unverified accounts; see
+	// SetRequireEmailVerification.
+	requireVerifiedEmail bool
+	// dummyHash is compared against for logins that match no account; see
+	// dummyPasswordHash.
+	dummyHash struct {
+		sync.Mutex
+		hash string
+	}
+}
+
+// NewAuthService returns a service using DefaultRolePolicy. It panics if the
+// service cannot be built, which happens only when the system's random source
+// fails; NewAuthServiceWithRoles returns that error instead.
+func NewAuthService(repo Repository) *AuthService {
+	s, err := NewAuthServiceWithRoles(repo, DefaultRolePolicy)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewAuthServiceWithRoles returns a service using policy. Every role it names
+// must exist in the permission model.
+func NewAuthServiceWithRoles(repo Repository, policy RolePolicy) (*AuthService, error) {
+	for _, role := range append([]string{policy.DefaultRole}, policy.Assignable...) {
+		if !permissionModel.Defined(role) {
+			return nil, fmt.Errorf("role %q is not defined in the permission model", role)
+		}
+	}
+	dummy, err := newDummyPasswordHash()
+	if err != nil {
+		return nil, fmt.Errorf("dummy password hash: %w", err)
+	}
+	s := &AuthService{repo: repo, roles: policy, now: time.Now, checkPassword: utils.CheckPassword, deletionGrace: DefaultDeletionGrace, emails: DefaultEmailNormalization}
+	s.dummyHash.hash = dummy
+	return s, nil
+}
+
+// Register creates a self-service account with the default role.
+func (s *AuthService) Register(ctx context.Context, req RegisterRequest) (*User, error) {
+	name, code := strings.TrimSpace(req.Organization), strings.TrimSpace(req.JoinCode)
+	var org *Organization
+	switch {
+	case name != "" && code != "":
+		return nil, ErrOrganizationChoice
+	case code != "":
+		existing, err := s.repo.GetOrganizationByJoinCode(ctx, code)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidJoinCode
+		} else if err != nil {
+			return nil, err
+		}
+		org = existing
+	case name != "":
+		joinCode, err := newJoinCode()
+		if err != nil {
+			return nil, err
+		}
+		org = &Organization{Name: name, JoinCode: joinCode}
+	}
+	return s.createUser(ctx, req.Email, req.Username, req.Password, req.FullName, s.roles.DefaultRole, org)
+}
+
+// Organization returns the organization orgID, the caller's from its token.
+func (s *AuthService) Organization(ctx context.Context, orgID string) (*Organization, error) {
+	if orgID == "" {
+		return nil, ErrNoOrganization
+	}
+	return s.repo.GetOrganization(ctx, orgID)
+}
+
+// TokenVersion returns the user's current token version; see
+// CheckTokenVersions.
+func (s *AuthService) TokenVersion(ctx context.Context, userID string) (int, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+// CheckStore reports whether the user and token store can be reached.
+func (s *AuthService) CheckStore(ctx context.Context) error {
+	return s.repo.Ping(ctx)
+}
+
+func newJoinCode() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// CreateUser is the administrator path. The requested role must be on the
+// assignable whitelist; an empty role falls back to the default.
+func (s *AuthService) CreateUser(ctx context.Context, req CreateUserRequest) (*User, error) {
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	if role == "" {
+		role = s.roles.DefaultRole
+	} else if !s.roles.allows(role) {
+		return nil, fmt.Errorf("%w: %q", ErrRoleNotAllowed, req.Role)
+	}
+	return s.createUser(ctx, req.Email, req.Username, req.Password, req.FullName, role, nil)
+}
+
+// ListUsers returns a page of users and the total matching filter. Limit
+// defaults to 20 and is capped at 100.
+func (s *AuthService) ListUsers(ctx context.Context, filter UserFilter) ([]User, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Role = strings.ToLower(strings.TrimSpace(filter.Role))
+	return s.repo.ListUsers(ctx, filter)
+}
+
+// GetUser returns the user userID, or gorm.ErrRecordNotFound.
+func (s *AuthService) GetUser(ctx context.Context, userID string) (*User, error) {
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+// UpdateUser is the administrator path for changing a user's role or
+// disabling the account. Roles must be on the assignable whitelist.
+// Changing the role bumps the user's token version, so access tokens still
+// carrying the old role are rejected and the client must refresh.
+// Deactivating a user revokes their refresh tokens, sessions and API keys and
+// bumps the token version, so access tokens already issued stop working at
+// once. actorID is the administrator, who may not deactivate themselves.
+func (s *AuthService) UpdateUser(ctx context.Context, actorID, userID string, req UpdateUserRequest) (*User, error) {
+	updates := map[string]interface{}{}
+	if req.Role != nil {
+		role := strings.ToLower(strings.TrimSpace(*req.Role))
+		if !s.roles.allows(role) {
+			return nil, fmt.Errorf("%w: %q", ErrRoleNotAllowed, *req.Role)
+		}
+		user, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role != user.Role {
+			updates["role"] = role
+			updates["token_version"] = gorm.Expr("token_version + 1")
+		}
+	}
+	if req.Active != nil {
+		if !*req.Active && userID == actorID {
+			return nil, ErrSelfDeactivation
+		}
+		updates["is_active"] = *req.Active
+		if !*req.Active {
+			updates["token_version"] = gorm.Expr("token_version + 1")
+		}
+	}
+
+	if len(updates) > 0 {
+		updates["updated_at"] = s.now()
+		if err := s.repo.UpdateUser(ctx, userID, updates); err != nil {
+			return nil, err
+		}
+	}
+	if req.Active != nil && !*req.Active {
+		if err := s.repo.RevokeUserTokens(ctx, userID, s.now()); err != nil {
+			return nil, err
+		}
+		s.revoked(RevokedDeactivation)
+	}
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+// createUser stores a new account. The up-front lookups give a fast answer for
+// the common case; the unique indexes on email and username settle concurrent
+// registrations, so a unique violation from the insert is reported as
+// ErrEmailTaken or ErrUsernameTaken too. Emails are stored normalized; see
+// SetEmailNormalization. The account joins org, if any; a new org, without an
+// ID, is created along with the user.
+func (s *AuthService) createUser(ctx context.Context, email, username, password, fullName, role string, org *Organization) (*User, error) {
+	email = s.emails.Normalize(email)
+	username = strings.TrimSpace(username)
+	if username != "" && !validUsername(username) {
+		return nil, ErrInvalidUsername
+	}
+
+	if _, err := s.repo.GetUserByEmail(ctx, email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if username != "" {
+		if _, err := s.repo.GetUserByUsername(ctx, username); err == nil {
+			return nil, ErrUsernameTaken
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &User{
+		Email:        email,
+		PasswordHash: hash,
+		FullName:     fullName,
+		Role:         role,
+		IsActive:     true,
+	}
+	if username != "" {
+		user.Username = &username
+	}
+	if org != nil && org.ID != "" {
+		user.OrgID = &org.ID
+	} else if org != nil {
+		user.Organization = org
+	}
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		if isUniqueViolation(err) {
+			if username != "" {
+				if _, lookupErr := s.repo.GetUserByUsername(ctx, username); lookupErr == nil {
+					return nil, ErrUsernameTaken
+				}
+			}
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetRequireEmailVerification makes Login refuse accounts whose email address
+// has not been verified with ErrEmailNotVerified.
+func (s *AuthService) SetRequireEmailVerification(required bool) {
+	s.requireVerifiedEmail = required
 }
 
-func NewAuthService() *AuthService {
-	return &AuthService{users: make(map[string]string)}
+// Login accepts either an email address or a username as the identifier.
+// Identifiers containing "@" are looked up as emails, since usernames may not
+// contain one. Every login starts a new session for client, subject to the
+// session limit. An unknown account and a wrong password both fail with
+// ErrInvalidCredentials after one password hash comparison, so neither the
+// error nor the timing reveals which it was.
+func (s *AuthService) Login(ctx context.Context, req LoginRequest, client ClientInfo) (*LoginResponse, error) {
+	identifier := req.identifier()
+	resp, user, err := s.login(ctx, identifier, req.Password, client)
+	s.recordLogin(ctx, identifier, user, client, err)
+	return resp, err
 }
 
-func (s *AuthService) Register(email, password string) error {
-	if _, exists := s.users[email]; exists {
-		return errors.New("user already exists")
+// login signs in; user is the account identifier matched, even when the
+// login then fails.
+func (s *AuthService) login(ctx context.Context, identifier, password string, client ClientInfo) (*LoginResponse, *User, error) {
+	var user *User
+	err := ErrInvalidCredentials
+	if strings.Contains(identifier, "@") {
+		user, err = s.repo.GetUserByEmail(ctx, s.emails.Normalize(identifier))
+	} else if identifier != "" {
+		user, err = s.repo.GetUserByUsername(ctx, identifier)
 	}
-	s.users[email] = password
+	if err != nil {
+		// Hash anyway, so an unknown account takes as long to refuse as a
+		// wrong password and the two cannot be told apart.
+		s.checkPassword(password, s.dummyPasswordHash())
+		return nil, nil, ErrInvalidCredentials
+	}
+	if err := s.checkPassword(password, user.PasswordHash); err != nil {
+		return nil, user, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, user, ErrInactiveUser
+	}
+	if s.requireVerifiedEmail && !user.EmailVerified {
+		return nil, user, ErrEmailNotVerified
+	}
+	if err := s.makeRoomForSession(ctx, user.ID); err != nil {
+		return nil, user, err
+	}
+	s.upgradePasswordHash(ctx, user, password)
+	now := s.now()
+	session := &Session{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		ExpiresAt:  now.Add(jwtConfig.RefreshTTL),
+		LastSeenAt: now,
+		CreatedAt:  now,
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, user, err
+	}
+	resp, err := s.issueTokens(ctx, user, session.ID, nil)
+	return resp, user, err
+}
+
+// dummyPasswordHash returns a hash of a random password made with the
+// configured algorithm, for logins that match no account to compare against.
+// It is remade when the algorithm changes so its cost tracks real hashes; if
+// that fails the previous hash is kept.
+func (s *AuthService) dummyPasswordHash() string {
+	s.dummyHash.Lock()
+	defer s.dummyHash.Unlock()
+	if utils.NeedsRehash(s.dummyHash.hash) {
+		if hash, err := newDummyPasswordHash(); err == nil {
+			s.dummyHash.hash = hash
+		}
+	}
+	return s.dummyHash.hash
+}
+
+func newDummyPasswordHash() (string, error) {
+	raw := make([]byte, 18)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return utils.HashPassword(hex.EncodeToString(raw))
+}
+
+// upgradePasswordHash rehashes a just-verified password whose stored hash
+// uses an algorithm other than the configured one. A failure only delays the
+// upgrade to the next login.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, user *User, password string) {
+	if !utils.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	hash, err := utils.HashPassword(password)
+	if err == nil {
+		err = s.repo.UpdateUser(ctx, user.ID, map[string]interface{}{"password_hash": hash})
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("password hash upgrade failed", "user_id", user.ID, "error", err)
+		return
+	}
+	user.PasswordHash = hash
+}
+
+// Refresh rotates a refresh token: the presented token is marked used and a
+// successor in the same family is issued with a new access token. Presenting a
+// token that was already rotated out is treated as theft and revokes the
+// whole family. The token's session is marked as seen from client.
+func (s *AuthService) Refresh(ctx context.Context, plaintext string, client ClientInfo) (*LoginResponse, error) {
+	current, err := s.repo.GetRefreshTokenByHash(ctx, hashToken(plaintext))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := s.now()
+	if current.RevokedAt != nil || !now.Before(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if current.UsedAt != nil {
+		return nil, s.revokeFamily(ctx, current.FamilyID, now)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, current.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if !user.IsActive {
+		return nil, ErrInactiveUser
+	}
+
+	won, err := s.repo.MarkRefreshTokenUsed(ctx, current.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !won {
+		return nil, s.revokeFamily(ctx, current.FamilyID, now)
+	}
+	if err := s.repo.TouchSession(ctx, current.FamilyID, client, now, now.Add(jwtConfig.RefreshTTL)); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, user, current.FamilyID, &current.ID)
+}
+
+// ListSessions returns the user's live sessions, flagging currentID as the
+// caller's own.
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentID string) ([]Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = currentID != "" && sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's sessions out: its refresh token stops
+// working immediately, and access tokens already issued to it run out at
+// their expiry.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if err := s.repo.RevokeSession(ctx, userID, sessionID, s.now()); err != nil {
+		return err
+	}
+	s.revoked(RevokedLogout)
 	return nil
 }
 
-func (s *AuthService) Login(email, password string) error {
-	pass, exists := s.users[email]
-	if !exists {
-		return errors.New("user not found")
+func (s *AuthService) revokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	if err := s.repo.RevokeRefreshTokenFamily(ctx, familyID, at); err != nil {
+		return err
+	}
+	s.revoked(RevokedRefreshReuse)
+	return ErrRefreshTokenReused
+}
+
+// issueTokens signs an access token for the session familyID and stores a new
+// refresh token in that family.
+func (s *AuthService) issueTokens(ctx context.Context, user *User, familyID string, parentID *string) (*LoginResponse, error) {
+	now := s.now()
+	access, expiresAt, err := generateSessionJWT(user, familyID, now)
+	if err != nil {
+		return nil, err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	refresh := hex.EncodeToString(raw)
+	record := &RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ParentID:  parentID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(jwtConfig.RefreshTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateRefreshToken(ctx, record); err != nil {
+		return nil, err
+	}
+	return &LoginResponse{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(expiresAt.Sub(now).Seconds()),
+		ExpiresAt:    expiresAt.UTC(),
+		User:         user,
+	}, nil
+}
+
+// CreateAPIKey issues a new key for userID. The plaintext key is only part of
+// the response; the database keeps its SHA-256 hash.
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID string, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownScope, scope)
+		}
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	plaintext := apiKeyPrefix + hex.EncodeToString(raw)
+
+	key := &APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    plaintext[:len(apiKeyPrefix)+8],
+		KeyHash:   hashToken(plaintext),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: s.now(),
 	}
-	if pass != password {
-		return errors.New("invalid password")
+	if err := s.repo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
 	}
+	return &CreateAPIKeyResponse{Key: plaintext, APIKey: key}, nil
+}
+
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+func (s *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID string) error {
+	if err := s.repo.RevokeAPIKey(ctx, userID, keyID, s.now()); err != nil {
+		return err
+	}
+	s.revoked(RevokedAPIKey)
 	return nil
 }
+
+// AuthenticateAPIKey resolves a plaintext key to its owner. Revoked, expired
+// and unknown keys are rejected, as are keys of disabled users; a failed
+// lookup is returned as it is, so callers can tell an outage from a bad key.
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, plaintext string) (*User, *APIKey, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	key, err := s.repo.GetAPIKeyByHash(ctx, hashToken(plaintext))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	} else if err != nil {
+		return nil, nil, err
+	}
+	now := s.now()
+	if key.RevokedAt != nil {
+		return nil, nil, ErrAPIKeyRevoked
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, nil, ErrAPIKeyExpired
+	}
+	user, err := s.repo.GetUserByID(ctx, key.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrInactiveUser
+	}
+	_ = s.repo.TouchAPIKey(ctx, key.ID, now)
+	return user, key, nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation (23505).
+// Both pgx and lib/pq errors expose the code through SQLState.
+func isUniqueViolation(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
+// validUsername reports whether every rune of name is an ASCII letter, digit,
+// '.', '_' or '-'. Length is checked by the request binding.
+func validUsername(name string) bool {
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// hashToken returns the SHA-256 digest stored in place of API keys and
+// refresh tokens.
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
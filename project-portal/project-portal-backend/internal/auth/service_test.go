@@ -0,0 +1,410 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/utils"
+)
+
+func TestRegister_ConcurrentDuplicateEmail(t *testing.T) {
+	repo := newMemoryRepo()
+	// Hold both registrations after the existence check so they race on insert.
+	var lookups sync.WaitGroup
+	lookups.Add(2)
+	repo.afterLookup = func() {
+		lookups.Done()
+		lookups.Wait()
+	}
+	service := NewAuthService(repo)
+	req := RegisterRequest{Email: "race@example.com", Password: "correct horse battery"}
+
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = service.Register(context.Background(), req)
+		}(i)
+	}
+	wg.Wait()
+
+	var succeeded, taken int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrEmailTaken):
+			taken++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 || taken != 1 {
+		t.Fatalf("expected one success and one ErrEmailTaken, got %d and %d", succeeded, taken)
+	}
+}
+
+func TestRegister_ExistingEmail(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	req := RegisterRequest{Email: "dup@example.com", Password: "correct horse battery"}
+
+	if _, err := service.Register(context.Background(), req); err != nil {
+		t.Fatalf("first Register: %v", err)
+	}
+	if _, err := service.Register(context.Background(), req); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+}
+
+func loginForRefresh(t *testing.T) (*AuthService, *memoryRepo, *LoginResponse) {
+	t.Helper()
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	req := RegisterRequest{Email: "rotate@example.com", Password: "correct horse battery"}
+	if _, err := service.Register(context.Background(), req); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	resp, err := service.Login(context.Background(), LoginRequest{Email: req.Email, Password: req.Password}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if resp.RefreshToken == "" {
+		t.Fatal("expected login to issue a refresh token")
+	}
+	return service, repo, resp
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	service, repo, login := loginForRefresh(t)
+
+	first, err := service.Refresh(context.Background(), login.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatalf("first Refresh: %v", err)
+	}
+	if first.RefreshToken == login.RefreshToken || first.Token == "" {
+		t.Fatal("expected a new refresh token and access token")
+	}
+	second, err := service.Refresh(context.Background(), first.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatalf("second Refresh: %v", err)
+	}
+
+	original, _ := repo.GetRefreshTokenByHash(context.Background(), hashToken(login.RefreshToken))
+	latest, _ := repo.GetRefreshTokenByHash(context.Background(), hashToken(second.RefreshToken))
+	if original.UsedAt == nil {
+		t.Fatal("expected the rotated-out token to be marked used")
+	}
+	if latest.FamilyID != original.FamilyID || latest.UsedAt != nil || latest.RevokedAt != nil {
+		t.Fatalf("expected the latest token to be live in the same family, got %+v", latest)
+	}
+}
+
+func TestRefresh_ReportsAccessTokenExpiry(t *testing.T) {
+	useTestJWTConfig(t)
+	service, _, login := loginForRefresh(t)
+	now := time.Now().Truncate(time.Second)
+	service.now = func() time.Time { return now }
+
+	resp, err := service.Refresh(context.Background(), login.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if resp.ExpiresIn != 60 || !resp.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected expiry one TTL from now, got expires_in=%d expires_at=%s", resp.ExpiresIn, resp.ExpiresAt)
+	}
+	claims, err := ValidateJWT(resp.Token)
+	if err != nil {
+		t.Fatalf("ValidateJWT: %v", err)
+	}
+	if !claims.ExpiresAt.Time.Equal(resp.ExpiresAt) {
+		t.Errorf("expires_at %s does not match the token's exp %s", resp.ExpiresAt, claims.ExpiresAt.Time)
+	}
+
+	raw, _ := json.Marshal(resp)
+	var body struct {
+		ExpiresAt string `json:"expires_at"`
+	}
+	_ = json.Unmarshal(raw, &body)
+	if want := now.Add(time.Minute).UTC().Format(time.RFC3339); body.ExpiresAt != want {
+		t.Errorf("expected expires_at %q, got %q", want, body.ExpiresAt)
+	}
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	service, _, login := loginForRefresh(t)
+
+	rotated, err := service.Refresh(context.Background(), login.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := service.Refresh(context.Background(), login.RefreshToken, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused replaying a used token, got %v", err)
+	}
+	if _, err := service.Refresh(context.Background(), rotated.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the current token to be revoked with its family, got %v", err)
+	}
+
+	// A fresh login starts a new family that is unaffected.
+	again, err := service.Login(context.Background(), LoginRequest{Email: "rotate@example.com", Password: "correct horse battery"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := service.Refresh(context.Background(), again.RefreshToken, ClientInfo{}); err != nil {
+		t.Fatalf("Refresh after new login: %v", err)
+	}
+}
+
+func TestRegister_AssignsConfiguredDefaultRole(t *testing.T) {
+	service, err := NewAuthServiceWithRoles(newMemoryRepo(), RolePolicy{DefaultRole: "partner", Assignable: []string{"partner"}})
+	if err != nil {
+		t.Fatalf("NewAuthServiceWithRoles: %v", err)
+	}
+	user, err := service.Register(context.Background(), RegisterRequest{Email: "new@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Role != "partner" {
+		t.Fatalf("expected the default role partner, got %q", user.Role)
+	}
+}
+
+func TestLogin_UpgradesPasswordHashToConfiguredAlgorithm(t *testing.T) {
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	t.Cleanup(func() { _ = utils.ConfigurePasswordHashing(utils.HashAlgorithmBcrypt) })
+
+	_ = utils.ConfigurePasswordHashing(utils.HashAlgorithmBcrypt)
+	user, err := service.Register(context.Background(), RegisterRequest{Email: "legacy@example.com", Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	legacy := user.PasswordHash
+
+	if err := utils.ConfigurePasswordHashing(utils.HashAlgorithmArgon2id); err != nil {
+		t.Fatalf("ConfigurePasswordHashing: %v", err)
+	}
+	login := LoginRequest{Email: "legacy@example.com", Password: "correct horse battery"}
+	if _, err := service.Login(context.Background(), login, ClientInfo{}); err != nil {
+		t.Fatalf("Login with a bcrypt hash: %v", err)
+	}
+	stored, _ := repo.GetUserByEmail(context.Background(), "legacy@example.com")
+	if stored.PasswordHash == legacy || !strings.HasPrefix(stored.PasswordHash, "$argon2id$") {
+		t.Fatalf("expected the hash to be upgraded to argon2id, got %q", stored.PasswordHash)
+	}
+
+	if _, err := service.Login(context.Background(), login, ClientInfo{}); err != nil {
+		t.Fatalf("Login with the upgraded hash: %v", err)
+	}
+	if _, err := service.Login(context.Background(), LoginRequest{Email: login.Email, Password: "wrong"}, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+}
+
+func TestCreateUser_RoleWhitelist(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	req := CreateUserRequest{Email: "verifier@example.com", Password: "correct horse battery", Role: "Verifier"}
+
+	user, err := service.CreateUser(context.Background(), req)
+	if err != nil || user.Role != "verifier" {
+		t.Fatalf("expected a verifier account, got %+v, %v", user, err)
+	}
+
+	req.Email, req.Role = "escalate@example.com", "admin"
+	if _, err := service.CreateUser(context.Background(), req); !errors.Is(err, ErrRoleNotAllowed) {
+		t.Fatalf("expected ErrRoleNotAllowed for admin, got %v", err)
+	}
+}
+
+func TestNewAuthServiceWithRoles_RejectsUndefinedRoles(t *testing.T) {
+	if _, err := NewAuthServiceWithRoles(newMemoryRepo(), RolePolicy{DefaultRole: "member"}); err == nil {
+		t.Fatal("expected an error for a default role missing from the permission model")
+	}
+}
+
+func TestLogin_ByEmailOrUsername(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	reg := RegisterRequest{Email: "Field.Officer@Example.com", Username: "field_officer", Password: "correct horse battery"}
+	user, err := service.Register(context.Background(), reg)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if user.Email != "field.officer@example.com" {
+		t.Fatalf("expected the email to be stored lowercased, got %q", user.Email)
+	}
+
+	for _, identifier := range []string{"FIELD.OFFICER@example.com", "field_officer"} {
+		resp, err := service.Login(context.Background(), LoginRequest{Identifier: identifier, Password: reg.Password}, ClientInfo{})
+		if err != nil {
+			t.Fatalf("Login as %q: %v", identifier, err)
+		}
+		if resp.User.ID != user.ID {
+			t.Fatalf("Login as %q returned user %q", identifier, resp.User.ID)
+		}
+	}
+	if _, err := service.Login(context.Background(), LoginRequest{Email: "field.officer@example.com", Password: reg.Password}, ClientInfo{}); err != nil {
+		t.Fatalf("Login with the legacy email field: %v", err)
+	}
+	if _, err := service.Login(context.Background(), LoginRequest{Identifier: "field_officer", Password: "wrong password"}, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for a bad password, got %v", err)
+	}
+}
+
+func TestLogin_RequiresVerifiedEmailWhenConfigured(t *testing.T) {
+	repo := newMemoryRepo()
+	service := NewAuthService(repo)
+	reg := RegisterRequest{Email: "officer@example.com", Password: "correct horse battery"}
+	user, err := service.Register(context.Background(), reg)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	login := LoginRequest{Identifier: reg.Email, Password: reg.Password}
+	if _, err := service.Login(context.Background(), login, ClientInfo{}); err != nil {
+		t.Fatalf("Login without the requirement: %v", err)
+	}
+
+	service.SetRequireEmailVerification(true)
+	if _, err := service.Login(context.Background(), login, ClientInfo{}); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified for an unverified account, got %v", err)
+	}
+	if _, err := service.Login(context.Background(), LoginRequest{Identifier: reg.Email, Password: "wrong password"}, ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("a wrong password must still fail as invalid credentials, got %v", err)
+	}
+
+	repo.mu.Lock()
+	repo.users[user.ID].EmailVerified = true
+	repo.mu.Unlock()
+	if _, err := service.Login(context.Background(), login, ClientInfo{}); err != nil {
+		t.Fatalf("Login once verified: %v", err)
+	}
+}
+
+func TestRegister_UsernameCollision(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	first := RegisterRequest{Email: "one@example.com", Username: "surveyor", Password: "correct horse battery"}
+	if _, err := service.Register(context.Background(), first); err != nil {
+		t.Fatalf("first Register: %v", err)
+	}
+
+	second := RegisterRequest{Email: "two@example.com", Username: "surveyor", Password: "correct horse battery"}
+	if _, err := service.Register(context.Background(), second); !errors.Is(err, ErrUsernameTaken) {
+		t.Fatalf("expected ErrUsernameTaken, got %v", err)
+	}
+	second.Username = "not@allowed"
+	if _, err := service.Register(context.Background(), second); !errors.Is(err, ErrInvalidUsername) {
+		t.Fatalf("expected ErrInvalidUsername, got %v", err)
+	}
+	// Usernames are optional, so several accounts may register without one.
+	for _, email := range []string{"three@example.com", "four@example.com"} {
+		if _, err := service.Register(context.Background(), RegisterRequest{Email: email, Password: "correct horse battery"}); err != nil {
+			t.Fatalf("Register %s without a username: %v", email, err)
+		}
+	}
+}
+
+func TestRegister_CreatesOrJoinsOrganization(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	ctx := context.Background()
+	founder, err := service.Register(ctx, RegisterRequest{Email: "founder@example.com", Password: "correct horse battery", Organization: "Green Belt Co-op"})
+	if err != nil {
+		t.Fatalf("Register with a new organization: %v", err)
+	}
+	if founder.OrgID == nil {
+		t.Fatal("expected the founder to belong to the new organization")
+	}
+	org, err := service.Organization(ctx, *founder.OrgID)
+	if err != nil {
+		t.Fatalf("Organization: %v", err)
+	}
+	if org.Name != "Green Belt Co-op" || org.JoinCode == "" {
+		t.Fatalf("unexpected organization %+v", org)
+	}
+
+	member, err := service.Register(ctx, RegisterRequest{Email: "member@example.com", Password: "correct horse battery", JoinCode: org.JoinCode})
+	if err != nil {
+		t.Fatalf("Register with a join code: %v", err)
+	}
+	if member.OrgID == nil || *member.OrgID != org.ID {
+		t.Fatalf("expected the member to join %s, got %v", org.ID, member.OrgID)
+	}
+	resp, err := service.Login(ctx, LoginRequest{Email: "member@example.com", Password: "correct horse battery"}, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	claims, err := ValidateJWT(resp.Token)
+	if err != nil {
+		t.Fatalf("ValidateJWT: %v", err)
+	}
+	if claims.OrgID != org.ID {
+		t.Errorf("expected the access token to carry org_id %s, got %q", org.ID, claims.OrgID)
+	}
+
+	if _, err := service.Register(ctx, RegisterRequest{Email: "guess@example.com", Password: "correct horse battery", JoinCode: "guessed"}); !errors.Is(err, ErrInvalidJoinCode) {
+		t.Errorf("expected ErrInvalidJoinCode, got %v", err)
+	}
+	both := RegisterRequest{Email: "both@example.com", Password: "correct horse battery", Organization: "Other", JoinCode: org.JoinCode}
+	if _, err := service.Register(ctx, both); !errors.Is(err, ErrOrganizationChoice) {
+		t.Errorf("expected ErrOrganizationChoice, got %v", err)
+	}
+	if _, err := service.Organization(ctx, ""); !errors.Is(err, ErrNoOrganization) {
+		t.Errorf("expected ErrNoOrganization for a user without one, got %v", err)
+	}
+}
+
+func TestLogin_UnknownAccountIndistinguishableFromWrongPassword(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	if _, err := service.Register(context.Background(), RegisterRequest{Email: "known@example.com", Username: "known", Password: "correct horse battery"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var compared []string
+	service.checkPassword = func(password, hashed string) error {
+		compared = append(compared, hashed)
+		return utils.CheckPassword(password, hashed)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		req        LoginRequest
+		wantHashed string
+	}{
+		{"wrong password", LoginRequest{Identifier: "known@example.com", Password: "wrong password"}, ""},
+		{"unknown email", LoginRequest{Identifier: "nobody@example.com", Password: "wrong password"}, service.dummyPasswordHash()},
+		{"unknown username", LoginRequest{Identifier: "nobody", Password: "wrong password"}, service.dummyPasswordHash()},
+	} {
+		compared = nil
+		_, err := service.Login(context.Background(), tc.req, ClientInfo{})
+		if err != ErrInvalidCredentials {
+			t.Errorf("%s: expected exactly ErrInvalidCredentials, got %v", tc.name, err)
+		}
+		if len(compared) != 1 {
+			t.Fatalf("%s: expected one password comparison, got %d", tc.name, len(compared))
+		}
+		if tc.wantHashed != "" && compared[0] != tc.wantHashed {
+			t.Errorf("%s: expected the comparison against the dummy hash, got %q", tc.name, compared[0])
+		}
+		if tc.wantHashed == "" && compared[0] == service.dummyPasswordHash() {
+			t.Errorf("%s: expected the comparison against the account's hash", tc.name)
+		}
+	}
+}
+
+func TestDummyPasswordHash_FollowsConfiguredAlgorithm(t *testing.T) {
+	service := NewAuthService(newMemoryRepo())
+	t.Cleanup(func() { _ = utils.ConfigurePasswordHashing(utils.HashAlgorithmBcrypt) })
+	if !strings.HasPrefix(service.dummyPasswordHash(), "$2") {
+		t.Fatalf("expected a bcrypt dummy hash, got %q", service.dummyPasswordHash())
+	}
+	if err := utils.ConfigurePasswordHashing(utils.HashAlgorithmArgon2id); err != nil {
+		t.Fatalf("ConfigurePasswordHashing: %v", err)
+	}
+	if !strings.HasPrefix(service.dummyPasswordHash(), "$argon2id$") {
+		t.Errorf("expected the dummy hash to switch to argon2id, got %q", service.dummyPasswordHash())
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// What a login does when the user already has the maximum number of sessions.
+const (
+	SessionLimitReject      = "reject"       // refuse the login
+	SessionLimitEvictOldest = "evict_oldest" // sign the oldest session out
+)
+
+// ErrSessionLimit refuses a login that would exceed the session limit under
+// SessionLimitReject.
+var ErrSessionLimit = errors.New("maximum number of active sessions reached; sign out of another session first")
+
+// SessionLimit caps a user's live sessions. A Max of 0 or less is unlimited.
+type SessionLimit struct {
+	Max    int
+	Policy string
+}
+
+// SetSessionLimit caps the sessions each user may hold at once. Sessions
+// already over the cap are left alone until the user's next login.
+func (s *AuthService) SetSessionLimit(limit SessionLimit) error {
+	if limit.Policy != SessionLimitReject && limit.Policy != SessionLimitEvictOldest {
+		return fmt.Errorf("unknown session limit policy %q (want %s or %s)", limit.Policy, SessionLimitReject, SessionLimitEvictOldest)
+	}
+	s.sessionLimit = limit
+	return nil
+}
+
+// makeRoomForSession enforces the session limit before userID's login starts
+// a new session: it fails with ErrSessionLimit, or revokes the sessions that
+// were started earliest until the new one fits. Two logins racing may each
+// see room for themselves, so the cap can be exceeded by concurrent logins.
+func (s *AuthService) makeRoomForSession(ctx context.Context, userID string) error {
+	if s.sessionLimit.Max <= 0 {
+		return nil
+	}
+	sessions, err := s.repo.ListSessions(ctx, userID, s.now())
+	if err != nil {
+		return err
+	}
+	excess := len(sessions) - s.sessionLimit.Max + 1
+	if excess <= 0 {
+		return nil
+	}
+	if s.sessionLimit.Policy != SessionLimitEvictOldest {
+		return ErrSessionLimit
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	for _, session := range sessions[:excess] {
+		if err := s.repo.RevokeSession(ctx, userID, session.ID, s.now()); err != nil {
+			return err
+		}
+		s.revoked(RevokedSessionEviction)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sessionLimitService returns a service with one registered user whose clock
+// advances a minute on every reading, so each login's session is newer.
+func sessionLimitService(t *testing.T, limit SessionLimit) (*AuthService, func(device string) (*LoginResponse, error)) {
+	t.Helper()
+	useTestJWTConfig(t)
+	service := NewAuthService(newMemoryRepo())
+	clock := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	if err := service.SetSessionLimit(limit); err != nil {
+		t.Fatalf("SetSessionLimit: %v", err)
+	}
+	req := RegisterRequest{Email: "shared@example.com", Password: "correct horse battery"}
+	if _, err := service.Register(context.Background(), req); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	login := func(device string) (*LoginResponse, error) {
+		return service.Login(context.Background(), LoginRequest{Email: req.Email, Password: req.Password}, ClientInfo{UserAgent: device})
+	}
+	return service, login
+}
+
+func TestLogin_SessionLimitRejects(t *testing.T) {
+	service, login := sessionLimitService(t, SessionLimit{Max: 2, Policy: SessionLimitReject})
+	first, err := login("laptop")
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if _, err := login("phone"); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if _, err := login("tablet"); !errors.Is(err, ErrSessionLimit) {
+		t.Fatalf("expected ErrSessionLimit for a third session, got %v", err)
+	}
+
+	sessions, _ := service.ListSessions(context.Background(), first.User.ID, "")
+	if len(sessions) != 2 {
+		t.Fatalf("expected the existing 2 sessions to be kept, got %+v", sessions)
+	}
+	if _, err := service.Refresh(context.Background(), first.RefreshToken, ClientInfo{}); err != nil {
+		t.Errorf("expected the oldest session to keep working, got %v", err)
+	}
+
+	// Signing out of one session makes room again.
+	if err := service.RevokeSession(context.Background(), first.User.ID, sessions[0].ID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if _, err := login("tablet"); err != nil {
+		t.Errorf("expected a login to succeed after signing out, got %v", err)
+	}
+}
+
+func TestLogin_SessionLimitEvictsOldest(t *testing.T) {
+	service, login := sessionLimitService(t, SessionLimit{Max: 2, Policy: SessionLimitEvictOldest})
+	laptop, err := login("laptop")
+	if err != nil {
+		t.Fatalf("laptop login: %v", err)
+	}
+	phone, err := login("phone")
+	if err != nil {
+		t.Fatalf("phone login: %v", err)
+	}
+	// Using the laptop session again does not make it any younger.
+	if laptop, err = service.Refresh(context.Background(), laptop.RefreshToken, ClientInfo{UserAgent: "laptop"}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := login("tablet"); err != nil {
+		t.Fatalf("expected the third login to evict a session, got %v", err)
+	}
+
+	sessions, _ := service.ListSessions(context.Background(), laptop.User.ID, "")
+	devices := map[string]bool{}
+	for _, s := range sessions {
+		devices[s.UserAgent] = true
+	}
+	if len(sessions) != 2 || devices["laptop"] || !devices["phone"] || !devices["tablet"] {
+		t.Fatalf("expected the laptop session to be evicted, got %+v", sessions)
+	}
+	if _, err := service.Refresh(context.Background(), laptop.RefreshToken, ClientInfo{}); err == nil {
+		t.Error("expected the evicted session's refresh token to stop working")
+	}
+	if _, err := service.Refresh(context.Background(), phone.RefreshToken, ClientInfo{}); err != nil {
+		t.Errorf("expected the phone session to keep working, got %v", err)
+	}
+}
+
+func TestSetSessionLimit_RejectsUnknownPolicy(t *testing.T) {
+	if err := NewAuthService(newMemoryRepo()).SetSessionLimit(SessionLimit{Max: 3, Policy: "newest"}); err == nil {
+		t.Error("expected an unknown policy to be refused")
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"net/http"
 	"strconv"
 
+	"carbon-scribe/project-portal/project-portal-backend/pkg/apierror"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Handler exposes compliance endpoints via Gin.
@@ -120,9 +123,13 @@ func (h *Handler) CreateDeleteRequest(c *gin.Context) {
 
 func (h *Handler) GetRequestStatus(c *gin.Context) {
 	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request id"})
+		return
+	}
 	result, err := h.service.GetRequestStatus(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+		apierror.Respond(c, err, "request")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -286,9 +293,13 @@ func (h *Handler) CreateRetentionPolicy(c *gin.Context) {
 
 func (h *Handler) GetRetentionPolicy(c *gin.Context) {
 	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid policy id"})
+		return
+	}
 	policy, err := h.service.GetRetentionPolicy(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "policy not found"})
+		apierror.Respond(c, err, "policy")
 		return
 	}
 	c.JSON(http.StatusOK, policy)
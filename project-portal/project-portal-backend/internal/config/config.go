@@ -2,9 +2,11 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds application configuration
@@ -12,10 +14,176 @@ type Config struct {
 	Port          string
 	DatabaseURL   string
 	Debug         bool
+	Server        ServerConfig
+	Database      DatabaseConfig
+	Auth          AuthConfig
 	Elasticsearch ElasticsearchConfig
 	AWS           AWSConfig
 	Storage       StorageConfig
 	Geospatial    GeospatialConfig
+	Logging       LoggingConfig
+	CORS          CORSConfig
+	RateLimit     RateLimitConfig
+	Concurrency   ConcurrencyConfig
+	Metrics       MetricsConfig
+	Compression   CompressionConfig
+	Security      SecurityHeadersConfig
+	Features      FeaturesConfig
+}
+
+// CompressionConfig controls gzip response compression; see
+// middleware.CompressConfig. Responses under MinSize bytes are sent as is.
+type CompressionConfig struct {
+	Enabled bool
+	MinSize int
+	Level   int
+	Exempt  []string
+}
+
+// SecurityHeadersConfig controls the security headers sent with every
+// response; see middleware.SecurityHeadersConfig. HSTSMaxAge of 0 disables
+// Strict-Transport-Security, and empty values leave their header out.
+type SecurityHeadersConfig struct {
+	Enabled               bool
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	NoSniff               bool
+	FrameOptions          string
+	ReferrerPolicy        string
+}
+
+// RateLimitConfig is the per-client token bucket applied to every route but
+// the Exempt path prefixes. RequestsPerSecond below or equal to 0 disables it.
+type RateLimitConfig struct {
+	RequestsPerSecond float64
+	Burst             int
+	Exempt            []string
+}
+
+// ConcurrencyConfig caps the requests served at once across all clients;
+// see middleware.ConcurrencyLimitConfig. MaxInFlight 0 disables the cap.
+type ConcurrencyConfig struct {
+	MaxInFlight  int
+	QueueTimeout time.Duration
+	Exempt       []string
+}
+
+// MetricsConfig controls the Prometheus metrics served to system:diagnostics
+// holders on /metrics; see middleware.PayloadSizeConfig. PayloadWarnBytes 0
+// disables the warning.
+type MetricsConfig struct {
+	Enabled          bool
+	PayloadWarnBytes int64
+}
+
+// CORSConfig controls which cross-origin callers the API answers. MaxAge is
+// how long browsers may cache a preflight result. Routes overrides the policy
+// for paths under a prefix; the longest matching prefix wins.
+type CORSConfig struct {
+	AllowedOrigins []string // "*" allows any origin, without credentials
+	AllowedMethods []string
+	AllowedHeaders []string
+	MaxAge         time.Duration
+	// OmitCredentials leaves out Access-Control-Allow-Credentials, so browsers
+	// send no cookies or HTTP auth cross-origin.
+	OmitCredentials bool
+	Routes          map[string]CORSConfig
+}
+
+// LoggingConfig selects the application log handler; see logging.Options.
+// RequestIDHeaders are the incoming headers a correlation id is reused from,
+// in order; see middleware.RequestIDConfig.
+type LoggingConfig struct {
+	Level            string
+	Format           string
+	OutputPath       string
+	RequestIDHeaders []string
+}
+
+// ServerConfig holds the HTTP server timeouts. ReadHeaderTimeout and
+// IdleTimeout bound how long slow or idle clients can hold a connection open.
+// RequestTimeout is the handler deadline, overridden per route by
+// RouteTimeouts (see middleware.ParseRouteTimeouts).
+type ServerConfig struct {
+	// Mode is development or production. Unset counts as production, so
+	// development-only tooling such as the seed command stays off by default.
+	Mode              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	RequestTimeout    time.Duration
+	RouteTimeouts     string
+	// ShutdownTimeout bounds draining HTTP requests and stopping background
+	// workers after SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration
+	// TrustedProxies are the IPs and CIDRs whose X-Forwarded-For is believed
+	// when resolving the client address. Empty trusts no proxy.
+	TrustedProxies []string
+}
+
+// DatabaseConfig holds connection pool tuning and query logging settings.
+type DatabaseConfig struct {
+	ReplicaURL         string
+	MaxOpenConns       int
+	MaxIdleConns       int
+	ConnMaxLifetime    time.Duration
+	ConnMaxIdleTime    time.Duration
+	SlowQueryThreshold time.Duration
+	// HealthCacheTTL is how long /health reuses a database ping result.
+	HealthCacheTTL time.Duration
+	CreatePostGIS  bool
+	// StatementTimeout is set as statement_timeout on every connection, so
+	// Postgres kills runaway queries itself; 0 disables it.
+	StatementTimeout time.Duration
+}
+
+// AuthConfig holds access token signing and verification settings.
+type AuthConfig struct {
+	JWTSecret       string
+	JWTIssuer       string
+	JWTAudience     string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// JWTLeeway is the clock skew tolerated on exp, nbf and iat.
+	JWTLeeway time.Duration
+	// TokenCleanupInterval is how often expired and revoked refresh tokens
+	// and sessions are deleted.
+	TokenCleanupInterval time.Duration
+	DefaultRole          string   // role given to self-registered users
+	AssignableRoles      []string // roles administrators may assign
+	// Roles adds to or overrides the built-in role hierarchy; see
+	// auth.ParseRoles.
+	Roles string
+	// MaxSessions caps each user's live sessions; 0 is unlimited.
+	// SessionLimitPolicy is what a login over the cap does: reject, or
+	// evict_oldest to sign the oldest session out.
+	MaxSessions        int
+	SessionLimitPolicy string
+	// SessionCookies hands every login and refresh the tokens as HttpOnly
+	// cookies too; without it clients opt in with ?cookie=true.
+	SessionCookies bool
+	// DeletionGracePeriod is how long self-deleted accounts are kept before
+	// the token cleanup job erases them.
+	DeletionGracePeriod time.Duration
+	// LoginFailureThreshold failed logins from one IP or for one account
+	// within LoginFailureWindow log a warning; 0 disables it.
+	LoginFailureThreshold int
+	LoginFailureWindow    time.Duration
+	// EmailLowercase and EmailNFC normalize email addresses on registration
+	// and login; see auth.EmailNormalization. Whitespace is always trimmed.
+	EmailLowercase bool
+	EmailNFC       bool
+
+	// PasswordPepper is HMAC'd into passwords before bcrypt when
+	// PasswordPepperEnabled is set. Keep the secret configured after disabling
+	// so existing peppered hashes still verify.
+	PasswordPepper        string
+	PasswordPepperEnabled bool
+	// HashAlgorithm is used for new password hashes: bcrypt or argon2id. Hashes
+	// of the other algorithm still verify and are rehashed on login.
+	HashAlgorithm string
 }
 
 // ElasticsearchConfig holds configuration for Elasticsearch
@@ -41,6 +209,14 @@ type StorageConfig struct {
 	MaxUploadSizeMB int64
 	IPFSEnabled     bool
 	IPFSNodeURL     string
+
+	// Project thumbnails: ThumbnailBackend is "local" (files under
+	// ThumbnailDir served at ThumbnailBaseURL) or "s3" (the documents bucket,
+	// with ThumbnailBaseURL as an optional public prefix).
+	ThumbnailBackend   string
+	ThumbnailDir       string
+	ThumbnailBaseURL   string
+	ThumbnailMaxSizeMB int64
 }
 
 type GeospatialConfig struct {
@@ -48,6 +224,33 @@ type GeospatialConfig struct {
 	MapboxAccessToken string
 	GoogleMapsAPIKey  string
 	TileCacheTTL      string
+	AreaBounds        string // per project type, see geospatial.ParseAreaPolicy
+	OverlapScope      string // "all" or "owner", see geospatial.ParseOverlapScope
+	CarbonRates       string // tCO2e per hectare by land cover, see geospatial.ParseCarbonRates
+	// ST_Simplify tolerance in degrees for the overlap pre-check; 0 disables it.
+	OverlapSimplifyTolerance float64
+	// Most vertices (ST_NPoints) an uploaded geometry may have; 0 = unlimited.
+	MaxVertices int
+	// Retries of serialization failures and deadlocks; 1 attempt disables them.
+	DBRetryAttempts  int
+	DBRetryBaseDelay time.Duration
+	DBRetryMaxDelay  time.Duration
+	// In-flight database operations allowed at once (0 = unlimited) and how
+	// long a request waits for a slot before answering 503.
+	DBMaxConcurrent  int
+	DBAcquireTimeout time.Duration
+	// Where resumable geometry uploads are kept, for how long an unfinished
+	// one survives, how often expired ones are swept, and how many unfinished
+	// ones each user may hold.
+	UploadDir           string
+	UploadTTL           time.Duration
+	UploadSweepInterval time.Duration
+	UploadMaxPerUser    int
+	// Address lookup; see geocoding.Config. An empty provider disables it.
+	GeocoderProvider  string
+	GeocoderURL       string
+	GeocoderUserAgent string
+	GeocoderTimeout   time.Duration
 }
 
 // Load loads configuration from environment variables
@@ -73,11 +276,60 @@ func Load() (*Config, error) {
 	if maxUpload <= 0 {
 		maxUpload = 100
 	}
+	maxThumbnail, _ := strconv.ParseInt(os.Getenv("THUMBNAIL_MAX_SIZE_MB"), 10, 64)
+	if maxThumbnail <= 0 {
+		maxThumbnail = 5
+	}
 
 	return &Config{
 		Port:        port,
 		DatabaseURL: databaseURL,
 		Debug:       debug,
+		Server: ServerConfig{
+			Mode:              strings.ToLower(getEnvOrDefault("SERVER_MODE", "production")),
+			ReadTimeout:       getEnvDurationOrDefault("SERVER_READ_TIMEOUT", 30*time.Second),
+			ReadHeaderTimeout: getEnvDurationOrDefault("SERVER_READ_HEADER_TIMEOUT", 10*time.Second),
+			WriteTimeout:      getEnvDurationOrDefault("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       getEnvDurationOrDefault("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			RequestTimeout:    getEnvDurationOrDefault("SERVER_REQUEST_TIMEOUT", 25*time.Second),
+			RouteTimeouts:     os.Getenv("SERVER_ROUTE_TIMEOUTS"),
+			ShutdownTimeout:   getEnvDurationOrDefault("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+			TrustedProxies:    splitList(os.Getenv("TRUSTED_PROXIES")),
+		},
+		Database: DatabaseConfig{
+			ReplicaURL:         os.Getenv("DATABASE_REPLICA_URL"),
+			MaxOpenConns:       getEnvIntOrDefault("DATABASE_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:       getEnvIntOrDefault("DATABASE_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime:    getEnvDurationOrDefault("DATABASE_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnMaxIdleTime:    getEnvDurationOrDefault("DATABASE_CONN_MAX_IDLE_TIME", time.Minute),
+			SlowQueryThreshold: getEnvDurationOrDefault("DATABASE_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+			HealthCacheTTL:     getEnvDurationOrDefault("DATABASE_HEALTH_CACHE_TTL", 2*time.Second),
+			CreatePostGIS:      os.Getenv("DATABASE_CREATE_POSTGIS") != "false",
+			StatementTimeout:   getEnvDurationOrDefault("DATABASE_STATEMENT_TIMEOUT", 0),
+		},
+		Auth: AuthConfig{
+			JWTSecret:             os.Getenv("JWT_SECRET"),
+			JWTIssuer:             getEnvOrDefault("JWT_ISSUER", "carbon-scribe-project-portal"),
+			JWTAudience:           getEnvOrDefault("JWT_AUDIENCE", "carbon-scribe-api"),
+			AccessTokenTTL:        getEnvDurationOrDefault("JWT_ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL:       getEnvDurationOrDefault("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			JWTLeeway:             getEnvDurationOrDefault("JWT_LEEWAY", 30*time.Second),
+			TokenCleanupInterval:  getEnvDurationOrDefault("TOKEN_CLEANUP_INTERVAL", time.Hour),
+			DefaultRole:           getEnvOrDefault("AUTH_DEFAULT_ROLE", "user"),
+			AssignableRoles:       splitList(getEnvOrDefault("AUTH_ASSIGNABLE_ROLES", "user,partner,verifier")),
+			Roles:                 os.Getenv("AUTH_ROLES"),
+			MaxSessions:           getEnvIntOrDefault("AUTH_MAX_SESSIONS", 0),
+			SessionLimitPolicy:    getEnvOrDefault("AUTH_SESSION_LIMIT_POLICY", "reject"),
+			SessionCookies:        getEnvBoolOrDefault("AUTH_SESSION_COOKIES", false),
+			DeletionGracePeriod:   getEnvDurationOrDefault("AUTH_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+			LoginFailureThreshold: getEnvIntOrDefault("AUTH_LOGIN_FAILURE_THRESHOLD", 10),
+			LoginFailureWindow:    getEnvDurationOrDefault("AUTH_LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			EmailLowercase:        getEnvBoolOrDefault("AUTH_EMAIL_LOWERCASE", true),
+			EmailNFC:              getEnvBoolOrDefault("AUTH_EMAIL_NFC", false),
+			PasswordPepper:        os.Getenv("PASSWORD_PEPPER"),
+			PasswordPepperEnabled: os.Getenv("PASSWORD_PEPPER_ENABLED") == "true",
+			HashAlgorithm:         getEnvOrDefault("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+		},
 		Elasticsearch: ElasticsearchConfig{
 			Addresses: strings.Split(esAddresses, ","),
 			Username:  os.Getenv("ELASTICSEARCH_USERNAME"),
@@ -92,23 +344,155 @@ func Load() (*Config, error) {
 			Endpoint:        os.Getenv("AWS_ENDPOINT_URL"), // for LocalStack
 		},
 		Storage: StorageConfig{
-			S3BucketName:    getEnvOrDefault("S3_BUCKET_NAME", "carbon-scribe-documents"),
-			MaxUploadSizeMB: maxUpload,
-			IPFSEnabled:     os.Getenv("IPFS_ENABLED") == "true",
-			IPFSNodeURL:     getEnvOrDefault("IPFS_NODE_URL", "http://localhost:5001"),
+			S3BucketName:       getEnvOrDefault("S3_BUCKET_NAME", "carbon-scribe-documents"),
+			MaxUploadSizeMB:    maxUpload,
+			IPFSEnabled:        os.Getenv("IPFS_ENABLED") == "true",
+			IPFSNodeURL:        getEnvOrDefault("IPFS_NODE_URL", "http://localhost:5001"),
+			ThumbnailBackend:   getEnvOrDefault("THUMBNAIL_STORAGE", "local"),
+			ThumbnailDir:       getEnvOrDefault("THUMBNAIL_DIR", "./uploads/thumbnails"),
+			ThumbnailBaseURL:   getEnvOrDefault("THUMBNAIL_BASE_URL", "/media/thumbnails"),
+			ThumbnailMaxSizeMB: maxThumbnail,
+		},
+		CORS: loadCORS(),
+		RateLimit: RateLimitConfig{
+			RequestsPerSecond: getEnvFloatOrDefault("RATE_LIMIT_RPS", 10),
+			Burst:             getEnvIntOrDefault("RATE_LIMIT_BURST", 40),
+			Exempt:            splitList(getEnvOrDefault("RATE_LIMIT_EXEMPT", "/health,/api/v1/health")),
+		},
+		Concurrency: ConcurrencyConfig{
+			MaxInFlight:  getEnvIntOrDefault("MAX_CONCURRENT_REQUESTS", 0),
+			QueueTimeout: getEnvDurationOrDefault("CONCURRENCY_QUEUE_TIMEOUT", 2*time.Second),
+			Exempt:       splitList(getEnvOrDefault("CONCURRENCY_EXEMPT", "/health,/api/v1/health,/ws/projects")),
+		},
+		Metrics: MetricsConfig{
+			Enabled:          getEnvBoolOrDefault("METRICS_ENABLED", true),
+			PayloadWarnBytes: int64(getEnvIntOrDefault("PAYLOAD_WARN_BYTES", 10<<20)),
+		},
+		Compression: CompressionConfig{
+			Enabled: os.Getenv("COMPRESSION_ENABLED") != "false",
+			MinSize: getEnvIntOrDefault("COMPRESSION_MIN_SIZE", 1024),
+			Level:   getEnvIntOrDefault("COMPRESSION_LEVEL", 0),
+			Exempt:  splitList(os.Getenv("COMPRESSION_EXEMPT")),
+		},
+		Security: SecurityHeadersConfig{
+			Enabled:               getEnvBoolOrDefault("SECURITY_HEADERS_ENABLED", true),
+			HSTSMaxAge:            getEnvDurationOrDefault("SECURITY_HSTS_MAX_AGE", 180*24*time.Hour),
+			HSTSIncludeSubdomains: getEnvBoolOrDefault("SECURITY_HSTS_INCLUDE_SUBDOMAINS", false),
+			HSTSPreload:           getEnvBoolOrDefault("SECURITY_HSTS_PRELOAD", false),
+			NoSniff:               getEnvBoolOrDefault("SECURITY_NOSNIFF", true),
+			FrameOptions:          getEnvOrDefault("SECURITY_FRAME_OPTIONS", "DENY"),
+			ReferrerPolicy:        getEnvOrDefault("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin"),
+		},
+		Features: loadFeatures(),
+		Logging: LoggingConfig{
+			Level:            os.Getenv("LOGGING_LEVEL"),
+			Format:           os.Getenv("LOGGING_FORMAT"),
+			OutputPath:       os.Getenv("LOGGING_OUTPUT_PATH"),
+			RequestIDHeaders: splitList(getEnvOrDefault("LOGGING_REQUEST_ID_HEADERS", "X-Request-ID,X-Correlation-ID,traceparent")),
 		},
 		Geospatial: GeospatialConfig{
-			DefaultProvider:   getEnvOrDefault("MAPS_DEFAULT_PROVIDER", "mapbox"),
-			MapboxAccessToken: os.Getenv("MAPS_MAPBOX_ACCESS_TOKEN"),
-			GoogleMapsAPIKey:  os.Getenv("MAPS_GOOGLE_MAPS_API_KEY"),
-			TileCacheTTL:      getEnvOrDefault("MAPS_TILE_CACHE_TTL", "24h"),
+			DefaultProvider:          getEnvOrDefault("MAPS_DEFAULT_PROVIDER", "mapbox"),
+			MapboxAccessToken:        os.Getenv("MAPS_MAPBOX_ACCESS_TOKEN"),
+			GoogleMapsAPIKey:         os.Getenv("MAPS_GOOGLE_MAPS_API_KEY"),
+			TileCacheTTL:             getEnvOrDefault("MAPS_TILE_CACHE_TTL", "24h"),
+			AreaBounds:               os.Getenv("GEOSPATIAL_AREA_BOUNDS"),
+			OverlapScope:             os.Getenv("GEOSPATIAL_OVERLAP_SCOPE"),
+			CarbonRates:              os.Getenv("GEOSPATIAL_CARBON_RATES"),
+			OverlapSimplifyTolerance: getEnvFloatOrDefault("GEOSPATIAL_OVERLAP_SIMPLIFY_TOLERANCE", 0.0001),
+			MaxVertices:              getEnvIntOrDefault("MAX_POLYGON_VERTICES", 10000),
+			DBRetryAttempts:          getEnvIntOrDefault("GEOSPATIAL_DB_RETRY_ATTEMPTS", 3),
+			DBRetryBaseDelay:         getEnvDurationOrDefault("GEOSPATIAL_DB_RETRY_BASE_DELAY", 50*time.Millisecond),
+			DBRetryMaxDelay:          getEnvDurationOrDefault("GEOSPATIAL_DB_RETRY_MAX_DELAY", time.Second),
+			DBMaxConcurrent:          getEnvIntOrDefault("GEOSPATIAL_DB_MAX_CONCURRENT", 0),
+			DBAcquireTimeout:         getEnvDurationOrDefault("GEOSPATIAL_DB_ACQUIRE_TIMEOUT", 2*time.Second),
+			UploadDir:                getEnvOrDefault("GEOSPATIAL_UPLOAD_DIR", "./uploads/geometry"),
+			UploadTTL:                getEnvDurationOrDefault("GEOSPATIAL_UPLOAD_TTL", 24*time.Hour),
+			UploadSweepInterval:      getEnvDurationOrDefault("GEOSPATIAL_UPLOAD_SWEEP_INTERVAL", time.Hour),
+			UploadMaxPerUser:         getEnvIntOrDefault("GEOSPATIAL_UPLOAD_MAX_PER_USER", 5),
+			GeocoderProvider:         os.Getenv("GEOCODER_PROVIDER"),
+			GeocoderURL:              os.Getenv("GEOCODER_URL"),
+			GeocoderUserAgent:        getEnvOrDefault("GEOCODER_USER_AGENT", "carbon-scribe-project-portal"),
+			GeocoderTimeout:          getEnvDurationOrDefault("GEOCODER_TIMEOUT", 5*time.Second),
 		},
 	}, nil
 }
 
+// loadCORS reads the default CORS policy and its overrides: /auth answers
+// only the explicit origins of CORS_AUTH_ALLOWED_ORIGINS (by default those of
+// the default policy), ignoring "*", and the read-only CORS_PUBLIC_PATHS
+// answer any origin without credentials.
+func loadCORS() CORSConfig {
+	cors := CORSConfig{
+		AllowedOrigins: splitList(getEnvOrDefault("CORS_ALLOWED_ORIGINS", "*")),
+		AllowedMethods: splitList(getEnvOrDefault("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")),
+		AllowedHeaders: splitList(getEnvOrDefault("CORS_ALLOWED_HEADERS", "Content-Type,Content-Length,Accept-Encoding,X-CSRF-Token,Authorization,Accept,Origin,Cache-Control,X-Requested-With,X-User-ID")),
+		MaxAge:         time.Duration(getEnvIntOrDefault("CORS_MAX_AGE", 600)) * time.Second,
+	}
+
+	authCORS := cors
+	authCORS.AllowedOrigins = nil
+	for _, origin := range splitList(getEnvOrDefault("CORS_AUTH_ALLOWED_ORIGINS", os.Getenv("CORS_ALLOWED_ORIGINS"))) {
+		if origin == "*" {
+			log.Printf("⚠️  Invalid CORS_AUTH_ALLOWED_ORIGINS (*) — /auth answers only listed origins")
+			continue
+		}
+		authCORS.AllowedOrigins = append(authCORS.AllowedOrigins, origin)
+	}
+
+	public := cors
+	public.AllowedOrigins = splitList(getEnvOrDefault("CORS_PUBLIC_ALLOWED_ORIGINS", "*"))
+	public.AllowedMethods = []string{"GET", "HEAD", "OPTIONS"}
+	public.OmitCredentials = true
+
+	cors.Routes = map[string]CORSConfig{"/auth": authCORS}
+	for _, prefix := range splitList(getEnvOrDefault("CORS_PUBLIC_PATHS", "/api/v1/geospatial/maps,/health,/version")) {
+		cors.Routes[prefix] = public
+	}
+	return cors
+}
+
 func getEnvOrDefault(key, defaultVal string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return defaultVal
 }
+
+// splitList splits a comma-separated value, trimming blanks.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func getEnvBoolOrDefault(key string, defaultVal bool) bool {
+	if b, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return b
+	}
+	return defaultVal
+}
+
+func getEnvIntOrDefault(key string, defaultVal int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return defaultVal
+}
+
+func getEnvFloatOrDefault(key string, defaultVal float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return f
+	}
+	return defaultVal
+}
+
+func getEnvDurationOrDefault(key string, defaultVal time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return defaultVal
+}
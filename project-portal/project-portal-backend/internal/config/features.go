@@ -0,0 +1,61 @@
+package config
+
+// Feature flag names accepted by FeaturesConfig.Enabled.
+const (
+	FeatureGeofenceMonitoring       = "geofence_monitoring"
+	FeatureMapTileCaching           = "map_tile_caching"
+	FeatureAutomaticValidation      = "automatic_validation"
+	FeatureRequireEmailVerification = "require_email_verification"
+	FeatureStrictOverlaps           = "strict_overlaps"
+	FeatureStrictJSON               = "strict_json"
+)
+
+// FeaturesConfig toggles behaviour per environment. Each flag is read from
+// ENABLE_<NAME> as a boolean (true/false, 1/0); unset or unparsable values
+// keep the default noted beside it.
+type FeaturesConfig struct {
+	GeofenceMonitoring  bool // default true
+	MapTileCaching      bool // default true
+	AutomaticValidation bool // default true
+	// Refuse logins to accounts whose email is not verified with 403;
+	// default false.
+	RequireEmailVerification bool
+	// Refuse project boundaries that overlap another project with 409;
+	// default true. GEOSPATIAL_REJECT_OVERLAPS is still honoured when
+	// ENABLE_STRICT_OVERLAPS is unset.
+	StrictOverlaps bool
+	// Refuse JSON request bodies with fields the endpoint does not know,
+	// naming the field in the 400; default false, which ignores them.
+	StrictJSON bool
+}
+
+// Enabled reports whether the flag called name is on. Unknown names are off.
+func (f FeaturesConfig) Enabled(name string) bool {
+	switch name {
+	case FeatureGeofenceMonitoring:
+		return f.GeofenceMonitoring
+	case FeatureMapTileCaching:
+		return f.MapTileCaching
+	case FeatureAutomaticValidation:
+		return f.AutomaticValidation
+	case FeatureRequireEmailVerification:
+		return f.RequireEmailVerification
+	case FeatureStrictOverlaps:
+		return f.StrictOverlaps
+	case FeatureStrictJSON:
+		return f.StrictJSON
+	}
+	return false
+}
+
+func loadFeatures() FeaturesConfig {
+	return FeaturesConfig{
+		GeofenceMonitoring:       getEnvBoolOrDefault("ENABLE_GEOFENCE_MONITORING", true),
+		MapTileCaching:           getEnvBoolOrDefault("ENABLE_MAP_TILE_CACHING", true),
+		AutomaticValidation:      getEnvBoolOrDefault("ENABLE_AUTOMATIC_VALIDATION", true),
+		RequireEmailVerification: getEnvBoolOrDefault("ENABLE_REQUIRE_EMAIL_VERIFICATION", false),
+		StrictOverlaps: getEnvBoolOrDefault("ENABLE_STRICT_OVERLAPS",
+			getEnvBoolOrDefault("GEOSPATIAL_REJECT_OVERLAPS", true)),
+		StrictJSON: getEnvBoolOrDefault("ENABLE_STRICT_JSON", false),
+	}
+}
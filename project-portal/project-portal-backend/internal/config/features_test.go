@@ -0,0 +1,48 @@
+package config
+
+import "testing"
+
+func TestLoadFeatures_DefaultsWhenUnset(t *testing.T) {
+	for _, key := range []string{
+		"ENABLE_GEOFENCE_MONITORING", "ENABLE_MAP_TILE_CACHING", "ENABLE_AUTOMATIC_VALIDATION",
+		"ENABLE_REQUIRE_EMAIL_VERIFICATION", "ENABLE_STRICT_OVERLAPS", "GEOSPATIAL_REJECT_OVERLAPS", "ENABLE_STRICT_JSON",
+	} {
+		t.Setenv(key, "")
+	}
+
+	want := FeaturesConfig{GeofenceMonitoring: true, MapTileCaching: true, AutomaticValidation: true, StrictOverlaps: true}
+	if got := loadFeatures(); got != want {
+		t.Errorf("loadFeatures() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadFeatures_ParsesEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://localhost/carbonscribe")
+	t.Setenv("ENABLE_REQUIRE_EMAIL_VERIFICATION", "true")
+	t.Setenv("ENABLE_STRICT_JSON", "true")
+	t.Setenv("ENABLE_STRICT_OVERLAPS", "false")
+	t.Setenv("ENABLE_MAP_TILE_CACHING", "not-a-bool")
+	t.Setenv("GEOSPATIAL_REJECT_OVERLAPS", "true")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for name, want := range map[string]bool{
+		FeatureRequireEmailVerification: true,
+		FeatureStrictJSON:               true,
+		FeatureStrictOverlaps:           false,
+		FeatureMapTileCaching:           true,
+		"no_such_flag":                  false,
+	} {
+		if got := cfg.Features.Enabled(name); got != want {
+			t.Errorf("Enabled(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	t.Setenv("ENABLE_STRICT_OVERLAPS", "")
+	t.Setenv("GEOSPATIAL_REJECT_OVERLAPS", "false")
+	if loadFeatures().StrictOverlaps {
+		t.Error("expected GEOSPATIAL_REJECT_OVERLAPS=false to apply while ENABLE_STRICT_OVERLAPS is unset")
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// redactedValue replaces secrets in Redacted output. Unset secrets stay empty
+// so a missing value is still visible.
+const redactedValue = "REDACTED"
+
+// dsnPassword matches the password in a keyword/value DSN such as
+// "host=db user=app password=secret".
+var dsnPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redacted returns a copy of c that is safe to log: passwords, signing
+// secrets, API keys and access tokens are masked, and URLs keep everything
+// but the password in their user info or query.
+func (c Config) Redacted() Config {
+	r := c
+	r.DatabaseURL = redactDSN(c.DatabaseURL)
+	r.Database.ReplicaURL = redactDSN(c.Database.ReplicaURL)
+	r.Auth.JWTSecret = redact(c.Auth.JWTSecret)
+	r.Auth.PasswordPepper = redact(c.Auth.PasswordPepper)
+	r.Storage.IPFSNodeURL = redactDSN(c.Storage.IPFSNodeURL)
+	r.Storage.ThumbnailBaseURL = redactDSN(c.Storage.ThumbnailBaseURL)
+	if c.Elasticsearch.Addresses != nil {
+		r.Elasticsearch.Addresses = make([]string, len(c.Elasticsearch.Addresses))
+		for i, addr := range c.Elasticsearch.Addresses {
+			r.Elasticsearch.Addresses[i] = redactDSN(addr)
+		}
+	}
+	r.Elasticsearch.Password = redact(c.Elasticsearch.Password)
+	r.Elasticsearch.APIKey = redact(c.Elasticsearch.APIKey)
+	r.AWS.AccessKeyID = redact(c.AWS.AccessKeyID)
+	r.AWS.SecretAccessKey = redact(c.AWS.SecretAccessKey)
+	r.Geospatial.MapboxAccessToken = redact(c.Geospatial.MapboxAccessToken)
+	r.Geospatial.GoogleMapsAPIKey = redact(c.Geospatial.GoogleMapsAPIKey)
+	r.Geospatial.GeocoderURL = redactDSN(c.Geospatial.GeocoderURL)
+	return r
+}
+
+func redact(v string) string {
+	if v == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactDSN masks the password of a URL or a keyword/value DSN. Values that
+// parse as neither are masked whole.
+func redactDSN(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", redactedValue)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	if dsnPassword.MatchString(dsn) {
+		return dsnPassword.ReplaceAllString(dsn, "${1}"+redactedValue)
+	}
+	return redactedValue
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactedMasksEverySecret(t *testing.T) {
+	secrets := []string{"db-pass-1", "replica-pass-2", "jwt-secret-3", "pepper-4", "es-pass-5", "es-key-6", "AKIA7", "aws-secret-8", "pk.mapbox9", "AIza10", "es-url-pass-11"}
+	cfg := Config{
+		Port:        "8080",
+		DatabaseURL: "postgres://app:" + secrets[0] + "@db:5432/carbonscribe?sslmode=disable",
+		Database:    DatabaseConfig{ReplicaURL: "host=replica user=app password=" + secrets[1] + " dbname=carbonscribe"},
+		Auth:        AuthConfig{JWTSecret: secrets[2], JWTIssuer: "carbon-scribe", PasswordPepper: secrets[3]},
+		Elasticsearch: ElasticsearchConfig{
+			Addresses: []string{"http://elastic:" + secrets[10] + "@es:9200"}, Username: "elastic", Password: secrets[4], APIKey: secrets[5],
+		},
+		AWS:        AWSConfig{Region: "eu-west-1", AccessKeyID: secrets[6], SecretAccessKey: secrets[7]},
+		Geospatial: GeospatialConfig{MapboxAccessToken: secrets[8], GoogleMapsAPIKey: secrets[9]},
+	}
+
+	redacted := cfg.Redacted()
+	asJSON, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []string{fmt.Sprintf("%+v", redacted), string(asJSON)} {
+		for _, secret := range secrets {
+			if strings.Contains(out, secret) {
+				t.Errorf("redacted config leaks %q:\n%s", secret, out)
+			}
+		}
+	}
+
+	if redacted.DatabaseURL != "postgres://app:REDACTED@db:5432/carbonscribe?sslmode=disable" {
+		t.Errorf("DatabaseURL = %q", redacted.DatabaseURL)
+	}
+	if redacted.Database.ReplicaURL != "host=replica user=app password=REDACTED dbname=carbonscribe" {
+		t.Errorf("ReplicaURL = %q", redacted.Database.ReplicaURL)
+	}
+	if cfg.Elasticsearch.Addresses[0] == redacted.Elasticsearch.Addresses[0] {
+		t.Error("Redacted must copy the address list, not mask it in place")
+	}
+	if redacted.Auth.JWTIssuer != "carbon-scribe" || redacted.AWS.Region != "eu-west-1" || redacted.Elasticsearch.Username != "elastic" {
+		t.Error("non-secret settings must be left alone")
+	}
+	if cfg.Auth.JWTSecret != secrets[2] {
+		t.Error("Redacted must not modify the receiver")
+	}
+	if (Config{}).Redacted().Auth.JWTSecret != "" {
+		t.Error("unset secrets should stay empty")
+	}
+}
+
+// TestRedactedCoversSensitiveFields fails when a new field that looks secret
+// is added to Config without being masked by Redacted.
+func TestRedactedCoversSensitiveFields(t *testing.T) {
+	var cfg Config
+	fill(reflect.ValueOf(&cfg).Elem(), "sentinel")
+	leaked := leakedFields(reflect.ValueOf(cfg.Redacted()), "Config")
+	if len(leaked) > 0 {
+		t.Errorf("Redacted leaves these sensitive fields unmasked: %v", leaked)
+	}
+}
+
+var sensitiveName = []string{"password", "secret", "key", "token", "pepper", "url"}
+
+func fill(v reflect.Value, s string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fill(v.Field(i), s)
+		}
+	case reflect.String:
+		v.SetString(s)
+	}
+}
+
+func leakedFields(v reflect.Value, path string) []string {
+	var out []string
+	for i := 0; i < v.NumField(); i++ {
+		f, name := v.Field(i), v.Type().Field(i).Name
+		switch f.Kind() {
+		case reflect.Struct:
+			out = append(out, leakedFields(f, path+"."+name)...)
+		case reflect.String:
+			lower := strings.ToLower(name)
+			for _, s := range sensitiveName {
+				if strings.Contains(lower, s) && strings.Contains(f.String(), "sentinel") {
+					out = append(out, path+"."+name)
+					break
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,9 @@
+-- Migration: 015_project_geometry_multipolygon
+-- Description: Store project boundaries as MULTIPOLYGON so polygons with holes
+-- and multi-part projects share one column type. Existing single polygons are
+-- promoted with ST_Multi; non-polygonal rows must be removed before running.
+-- Date: 2026-10-16
+
+ALTER TABLE project_geometries
+    ALTER COLUMN geometry TYPE GEOGRAPHY(MULTIPOLYGON, 4326)
+    USING ST_Multi(geometry::geometry)::geography;
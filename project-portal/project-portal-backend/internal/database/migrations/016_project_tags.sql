@@ -0,0 +1,8 @@
+-- Migration: 016_project_tags
+-- Description: Free-form tags on projects for grouping and filtering. The GIN
+-- index serves both the any (&&) and all (@>) tag filters.
+-- Date: 2026-10-16
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_projects_tags ON projects USING GIN (tags);
@@ -0,0 +1,11 @@
+-- Migration: 017_project_geometries_geometry_index
+-- Description: The bbox, polygon and overlap queries filter on
+-- geometry::geometry, which cannot use the GiST index on the geography column.
+-- Index the cast expression so those predicates get an index scan.
+-- Date: 2026-10-16
+
+CREATE INDEX IF NOT EXISTS idx_project_geometries_geometry
+    ON project_geometries USING GIST (geometry);
+
+CREATE INDEX IF NOT EXISTS idx_project_geometries_geometry_geom
+    ON project_geometries USING GIST ((geometry::geometry));
@@ -0,0 +1,5 @@
+-- Migration: 018_project_thumbnails
+-- Description: URL of the uploaded project thumbnail shown on the map.
+-- Date: 2026-10-16
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS thumbnail_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,19 @@
+-- Migration: 019_project_geometry_versions
+-- Description: Every stored project boundary, one row per version, so edits
+-- can be reviewed and diffed. Current boundaries are copied in as their
+-- present version.
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS project_geometry_versions (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    geometry GEOGRAPHY(MULTIPOLYGON, 4326) NOT NULL,
+    area_hectares DECIMAL(12, 4) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, version)
+);
+
+INSERT INTO project_geometry_versions (project_id, version, geometry, area_hectares, created_at)
+SELECT project_id, COALESCE(version, 1), ST_Multi(geometry::geometry)::geography, area_hectares, COALESCE(updated_at, CURRENT_TIMESTAMP)
+FROM project_geometries
+ON CONFLICT (project_id, version) DO NOTHING;
@@ -0,0 +1,8 @@
+-- Migration: 020_project_owner
+-- Description: Owning user of each project. Non-admin callers only see and
+-- edit their own projects; rows created before ownership have no owner and are
+-- only reachable by administrators.
+-- Date: 2026-10-16
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS owner_id UUID;
+CREATE INDEX IF NOT EXISTS idx_projects_owner_id ON projects (owner_id);
@@ -0,0 +1,59 @@
+-- Migration: 021_project_change_notify
+-- Description: NOTIFY project_changes on every write to a project or its
+-- boundary, feeding the /ws/projects live feed. The payload is JSON with type
+-- (created, updated or deleted), project_id, owner_id and the boundary's bbox.
+-- A soft delete is reported as deleted.
+-- Date: 2026-10-16
+
+CREATE OR REPLACE FUNCTION notify_project_change() RETURNS trigger AS $$
+DECLARE
+    pid UUID;
+    owner UUID;
+    kind TEXT;
+    box BOX2D;
+BEGIN
+    IF TG_TABLE_NAME = 'project_geometries' THEN
+        pid := CASE WHEN TG_OP = 'DELETE' THEN OLD.project_id ELSE NEW.project_id END;
+        kind := 'updated';
+        SELECT owner_id INTO owner FROM projects WHERE id = pid AND deleted_at IS NULL;
+        IF NOT FOUND THEN
+            -- Cascade from a deleted project, which reports itself.
+            RETURN NULL;
+        END IF;
+    ELSIF TG_OP = 'INSERT' THEN
+        pid := NEW.id;
+        owner := NEW.owner_id;
+        kind := 'created';
+    ELSIF TG_OP = 'DELETE' THEN
+        pid := OLD.id;
+        owner := OLD.owner_id;
+        kind := 'deleted';
+    ELSE
+        pid := NEW.id;
+        owner := NEW.owner_id;
+        kind := CASE WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN 'deleted' ELSE 'updated' END;
+    END IF;
+
+    SELECT Box2D(geometry::geometry) INTO box FROM project_geometries WHERE project_id = pid;
+
+    PERFORM pg_notify('project_changes', json_build_object(
+        'type', kind,
+        'project_id', pid,
+        'owner_id', owner,
+        'bbox', CASE WHEN box IS NULL THEN NULL ELSE json_build_object(
+            'min_lon', ST_XMin(box), 'min_lat', ST_YMin(box),
+            'max_lon', ST_XMax(box), 'max_lat', ST_YMax(box)) END
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS projects_notify_change ON projects;
+CREATE TRIGGER projects_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON projects
+    FOR EACH ROW EXECUTE FUNCTION notify_project_change();
+
+DROP TRIGGER IF EXISTS project_geometries_notify_change ON project_geometries;
+CREATE TRIGGER project_geometries_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON project_geometries
+    FOR EACH ROW EXECUTE FUNCTION notify_project_change();
@@ -0,0 +1,12 @@
+-- Migration: 022_project_geometry_winding
+-- Description: Boundaries are stored with RFC 7946 ring orientation, exterior
+-- rings counter-clockwise and holes clockwise. winding_corrected records that
+-- an upload had to be reoriented. Existing boundaries are reoriented in place.
+-- Date: 2026-10-16
+
+ALTER TABLE project_geometries ADD COLUMN IF NOT EXISTS winding_corrected BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE project_geometries
+SET geometry = ST_ForcePolygonCCW(geometry::geometry)::geography,
+    winding_corrected = TRUE
+WHERE NOT ST_IsPolygonCCW(geometry::geometry);
@@ -0,0 +1,68 @@
+-- Migration: 023_organizations
+-- Description: Organizations as tenants. Users belong to at most one and
+-- projects record the organization they were created in; members of an
+-- organization only see its projects. The project change notification gains
+-- org_id so the live feed can be scoped the same way.
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    join_code TEXT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_join_code ON organizations (join_code);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations (id);
+CREATE INDEX IF NOT EXISTS idx_users_org_id ON users (org_id);
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS org_id UUID;
+CREATE INDEX IF NOT EXISTS idx_projects_org_id ON projects (org_id);
+
+CREATE OR REPLACE FUNCTION notify_project_change() RETURNS trigger AS $$
+DECLARE
+    pid UUID;
+    owner UUID;
+    org UUID;
+    kind TEXT;
+    box BOX2D;
+BEGIN
+    IF TG_TABLE_NAME = 'project_geometries' THEN
+        pid := CASE WHEN TG_OP = 'DELETE' THEN OLD.project_id ELSE NEW.project_id END;
+        kind := 'updated';
+        SELECT owner_id, org_id INTO owner, org FROM projects WHERE id = pid AND deleted_at IS NULL;
+        IF NOT FOUND THEN
+            -- Cascade from a deleted project, which reports itself.
+            RETURN NULL;
+        END IF;
+    ELSIF TG_OP = 'INSERT' THEN
+        pid := NEW.id;
+        owner := NEW.owner_id;
+        org := NEW.org_id;
+        kind := 'created';
+    ELSIF TG_OP = 'DELETE' THEN
+        pid := OLD.id;
+        owner := OLD.owner_id;
+        org := OLD.org_id;
+        kind := 'deleted';
+    ELSE
+        pid := NEW.id;
+        owner := NEW.owner_id;
+        org := NEW.org_id;
+        kind := CASE WHEN NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL THEN 'deleted' ELSE 'updated' END;
+    END IF;
+
+    SELECT Box2D(geometry::geometry) INTO box FROM project_geometries WHERE project_id = pid;
+
+    PERFORM pg_notify('project_changes', json_build_object(
+        'type', kind,
+        'project_id', pid,
+        'owner_id', owner,
+        'org_id', org,
+        'bbox', CASE WHEN box IS NULL THEN NULL ELSE json_build_object(
+            'min_lon', ST_XMin(box), 'min_lat', ST_YMin(box),
+            'max_lon', ST_XMax(box), 'max_lat', ST_YMax(box)) END
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
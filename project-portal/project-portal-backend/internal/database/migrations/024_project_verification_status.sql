@@ -0,0 +1,16 @@
+-- Migration: 024_project_verification_status
+-- Description: Verification lifecycle of a project, separate from its
+-- operational status: draft -> submitted -> under_review -> verified or
+-- rejected, and rejected -> draft. Transitions are enforced by the service.
+-- Date: 2026-10-16
+
+ALTER TABLE projects
+    ADD COLUMN IF NOT EXISTS verification_status TEXT NOT NULL DEFAULT 'draft';
+
+ALTER TABLE projects
+    DROP CONSTRAINT IF EXISTS projects_verification_status_check;
+ALTER TABLE projects
+    ADD CONSTRAINT projects_verification_status_check
+    CHECK (verification_status IN ('draft', 'submitted', 'under_review', 'verified', 'rejected'));
+
+CREATE INDEX IF NOT EXISTS idx_projects_verification_status ON projects (verification_status);
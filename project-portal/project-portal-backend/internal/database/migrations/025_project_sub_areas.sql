@@ -0,0 +1,18 @@
+-- Migration: 025_project_sub_areas
+-- Description: Sub-areas (stands, parcels) delineated within a project. Each
+-- must lie within the project boundary when it is created; listings only
+-- return those still within the current boundary.
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS project_sub_areas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(50) NOT NULL DEFAULT '',
+    geometry GEOGRAPHY(MULTIPOLYGON, 4326) NOT NULL,
+    area_hectares DECIMAL(12, 4) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_sub_areas_project ON project_sub_areas (project_id);
+CREATE INDEX IF NOT EXISTS idx_project_sub_areas_geometry ON project_sub_areas USING GIST (geometry);
@@ -0,0 +1,21 @@
+-- Migration: 026_reference_layers
+-- Description: Named reference layers (protected areas and the like) whose
+-- polygons project boundaries are measured against.
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS reference_layers (
+    name VARCHAR(63) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reference_layer_features (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    layer_name VARCHAR(63) NOT NULL REFERENCES reference_layers(name) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    geometry GEOGRAPHY(MULTIPOLYGON, 4326) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reference_layer_features_layer ON reference_layer_features (layer_name);
+CREATE INDEX IF NOT EXISTS idx_reference_layer_features_geometry ON reference_layer_features USING GIST (geometry);
@@ -0,0 +1,7 @@
+-- Migration: 027_user_token_version
+-- Description: Users carry a token version, bumped when their role changes.
+-- Access tokens record the version they were issued at and are rejected once
+-- it is stale, so a role change takes effect at the next refresh.
+-- Date: 2026-10-16
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
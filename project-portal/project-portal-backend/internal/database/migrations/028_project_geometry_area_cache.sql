@@ -0,0 +1,25 @@
+-- Migration: 028_project_geometry_area_cache
+-- Description: project_geometries.area_hectares is the cached spheroidal area
+-- of the boundary, served from the row instead of running ST_Area per request.
+-- A trigger fills it on insert and recomputes it only when an update changes
+-- the geometry, whichever code path wrote the row. Existing rows are
+-- backfilled; POST /api/v1/geospatial/admin/areas/recompute repeats that.
+-- Date: 2026-10-16
+
+CREATE OR REPLACE FUNCTION cache_project_geometry_area() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR ST_AsBinary(NEW.geometry) IS DISTINCT FROM ST_AsBinary(OLD.geometry) THEN
+        NEW.area_hectares := ST_Area(NEW.geometry) * 0.0001;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS project_geometries_cache_area ON project_geometries;
+CREATE TRIGGER project_geometries_cache_area
+    BEFORE INSERT OR UPDATE OF geometry ON project_geometries
+    FOR EACH ROW EXECUTE FUNCTION cache_project_geometry_area();
+
+UPDATE project_geometries
+SET area_hectares = ST_Area(geometry) * 0.0001
+WHERE area_hectares IS DISTINCT FROM ROUND((ST_Area(geometry) * 0.0001)::numeric, 4);
@@ -0,0 +1,9 @@
+-- Migration: 029_users_email_normalized_unique
+-- Description: Email addresses are unique regardless of case, so two accounts
+-- cannot differ only in the case of their address even when emails are stored
+-- as typed (AUTH_EMAIL_LOWERCASE=false). The index also serves the
+-- case-insensitive login lookup. Existing accounts whose addresses differ only
+-- in case must be merged before this migration can run.
+-- Date: 2026-10-16
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"strings"
 
+	"carbon-scribe/project-portal/project-portal-backend/pkg/apierror"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -189,7 +191,7 @@ func (h *Handler) Download(c *gin.Context) {
 
 	url, _, err := h.svc.GenerateDownloadURL(ctx, id, userID, ipAddr, ua)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierror.Respond(c, err, "document")
 		return
 	}
 
@@ -207,7 +209,7 @@ func (h *Handler) GetMetadata(c *gin.Context) {
 	userID := extractUserID(c)
 	doc, err := h.svc.GetMetadata(ctx, id, userID, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierror.Respond(c, err, "document")
 		return
 	}
 	c.JSON(http.StatusOK, doc)
@@ -296,7 +298,7 @@ func (h *Handler) GetVersion(c *gin.Context) {
 
 	version, err := h.svc.GetVersion(c.Request.Context(), id, versionNum)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierror.Respond(c, err, "document version")
 		return
 	}
 	c.JSON(http.StatusOK, version)
@@ -0,0 +1,104 @@
+package geospatial
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AreaBounds are plausible project sizes in hectares. Areas below Min or above
+// Max are rejected; areas outside SoftMin..SoftMax are accepted with a warning.
+// A zero bound is not enforced.
+type AreaBounds struct {
+	Min     float64
+	SoftMin float64
+	SoftMax float64
+	Max     float64
+}
+
+// AreaPolicy holds the default bounds and per project type overrides.
+type AreaPolicy struct {
+	Default AreaBounds
+	ByType  map[string]AreaBounds
+}
+
+// DefaultAreaPolicy is used when GEOSPATIAL_AREA_BOUNDS is not set.
+var DefaultAreaPolicy = AreaPolicy{
+	Default: AreaBounds{Min: 0.1, SoftMin: 1, SoftMax: 500000, Max: 2000000},
+}
+
+// AreaOutOfRangeError reports a polygon whose computed area is outside the
+// hard bounds for its project type.
+type AreaOutOfRangeError struct {
+	AreaHectares float64
+	MinHectares  float64
+	MaxHectares  float64
+}
+
+func (e *AreaOutOfRangeError) Error() string {
+	return fmt.Sprintf("geometry area %.4f ha is outside the allowed range %.4f-%.4f ha", e.AreaHectares, e.MinHectares, e.MaxHectares)
+}
+
+// For returns the bounds for a project type, falling back to the default.
+func (p AreaPolicy) For(projectType string) AreaBounds {
+	if b, ok := p.ByType[strings.ToLower(projectType)]; ok {
+		return b
+	}
+	return p.Default
+}
+
+func (p AreaPolicy) enabled() bool {
+	return p.Default != (AreaBounds{}) || len(p.ByType) > 0
+}
+
+// Check returns an error for areas outside the hard bounds and a warning for
+// areas in the suspicious band.
+func (b AreaBounds) Check(areaHectares float64) (string, error) {
+	if (b.Min > 0 && areaHectares < b.Min) || (b.Max > 0 && areaHectares > b.Max) {
+		return "", &AreaOutOfRangeError{AreaHectares: areaHectares, MinHectares: b.Min, MaxHectares: b.Max}
+	}
+	if b.SoftMin > 0 && areaHectares < b.SoftMin {
+		return fmt.Sprintf("geometry area %.4f ha is unusually small (expected at least %.4f ha)", areaHectares, b.SoftMin), nil
+	}
+	if b.SoftMax > 0 && areaHectares > b.SoftMax {
+		return fmt.Sprintf("geometry area %.4f ha is unusually large (expected at most %.4f ha)", areaHectares, b.SoftMax), nil
+	}
+	return "", nil
+}
+
+// ParseAreaPolicy parses entries of the form type=min:soft_min:soft_max:max
+// separated by semicolons, e.g. "default=0.1:1:500000:2000000;reforestation=1:5:100000:500000".
+// Types without an entry use the default bounds.
+func ParseAreaPolicy(spec string) (AreaPolicy, error) {
+	policy := AreaPolicy{Default: DefaultAreaPolicy.Default, ByType: map[string]AreaBounds{}}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, values, ok := strings.Cut(entry, "=")
+		if !ok {
+			return AreaPolicy{}, fmt.Errorf("area bounds entry %q must be type=min:soft_min:soft_max:max", entry)
+		}
+		parts := strings.Split(values, ":")
+		if len(parts) != 4 {
+			return AreaPolicy{}, fmt.Errorf("area bounds for %q need four values", name)
+		}
+		var nums [4]float64
+		for i, part := range parts {
+			n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || n < 0 {
+				return AreaPolicy{}, fmt.Errorf("invalid area bound %q for %q", part, name)
+			}
+			nums[i] = n
+		}
+		bounds := AreaBounds{Min: nums[0], SoftMin: nums[1], SoftMax: nums[2], Max: nums[3]}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "default" {
+			policy.Default = bounds
+		} else {
+			policy.ByType[name] = bounds
+		}
+	}
+	return policy, nil
+}
@@ -0,0 +1,28 @@
+package geospatial
+
+import (
+	"context"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+)
+
+// AreaRecompute reports a backfill of the cached boundary areas: how many
+// boundaries were measured and how many stored areas were wrong.
+type AreaRecompute struct {
+	Checked   int `json:"checked"`
+	Corrected int `json:"corrected"`
+}
+
+// RecomputeAreas measures every stored boundary again and corrects cached
+// areas that no longer match, such as rows written before the area cache
+// trigger existed. Correct rows are left untouched.
+func (s *service) RecomputeAreas(ctx context.Context) (*AreaRecompute, error) {
+	out, err := dbCall(ctx, s, weightBulk, func() (*AreaRecompute, error) {
+		return s.repo.RecomputeAreas(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("project areas recomputed", "checked", out.Checked, "corrected", out.Corrected)
+	return out, nil
+}
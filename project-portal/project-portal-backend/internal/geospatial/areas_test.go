@@ -0,0 +1,67 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recomputeRepo has three stored boundaries, one with a stale cached area.
+type recomputeRepo struct {
+	*fakeRepo
+	calls int
+}
+
+func (r *recomputeRepo) RecomputeAreas(context.Context) (*AreaRecompute, error) {
+	r.calls++
+	return &AreaRecompute{Checked: 3, Corrected: 1}, nil
+}
+
+func TestRecomputeAreas_AdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &recomputeRepo{fakeRepo: newFakeRepo()}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	post := func(role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/admin/areas/recompute", nil)
+		if role != "" {
+			token, err := auth.GenerateJWT(&auth.User{ID: "u-" + role, Email: role + "@example.com", Role: role})
+			if err != nil {
+				t.Fatalf("GenerateJWT: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", w.Code)
+	}
+	if w := post("user"); w.Code != http.StatusForbidden {
+		t.Errorf("user: expected 403, got %d", w.Code)
+	}
+	if repo.calls != 0 {
+		t.Fatalf("expected refused callers not to start a recompute, got %d calls", repo.calls)
+	}
+
+	w := post("admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result AreaRecompute
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if result.Checked != 3 || result.Corrected != 1 || repo.calls != 1 {
+		t.Errorf("expected 3 checked and 1 corrected in one call, got %+v after %d calls", result, repo.calls)
+	}
+}
@@ -0,0 +1,92 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// BoundingCircle is the smallest circle around a project boundary. Reock is
+// the boundary's area over the circle's: 1 for a disc, near 0 for a long
+// thin project.
+type BoundingCircle struct {
+	ProjectID    uuid.UUID       `json:"project_id"`
+	Center       [2]float64      `json:"center"`
+	RadiusMeters float64         `json:"radius_meters"`
+	AreaHectares float64         `json:"area_hectares"`
+	Reock        float64         `json:"reock"`
+	Geometry     json.RawMessage `json:"geometry"`
+}
+
+// BoundingRectangle is the oriented minimum bounding rectangle of a project
+// boundary. LengthMeters is its longer side. Fill is the boundary's area
+// over the rectangle's: 1 for a rectangular project.
+type BoundingRectangle struct {
+	ProjectID    uuid.UUID       `json:"project_id"`
+	AreaHectares float64         `json:"area_hectares"`
+	LengthMeters float64         `json:"length_meters"`
+	WidthMeters  float64         `json:"width_meters"`
+	Fill         float64         `json:"fill"`
+	Geometry     json.RawMessage `json:"geometry"`
+}
+
+// BoundingShapes is what the repository measures for both endpoints.
+// BoundaryHectares is the area of the project boundary itself.
+type BoundingShapes struct {
+	Center            [2]float64
+	RadiusMeters      float64
+	Circle            json.RawMessage
+	Rectangle         json.RawMessage
+	RectangleHectares float64
+	SideMeters        [2]float64
+	BoundaryHectares  float64
+}
+
+// BoundingCircle returns the minimum bounding circle of projectID's boundary.
+// A project without a boundary yields sql.ErrNoRows.
+func (s *service) BoundingCircle(ctx context.Context, projectID uuid.UUID) (*BoundingCircle, error) {
+	shapes, err := dbCall(ctx, s, weightQuery, func() (*BoundingShapes, error) {
+		return s.repo.BoundingShapes(ctx, projectID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	circleHectares := math.Pi * shapes.RadiusMeters * shapes.RadiusMeters * 0.0001
+	return &BoundingCircle{
+		ProjectID:    projectID,
+		Center:       shapes.Center,
+		RadiusMeters: shapes.RadiusMeters,
+		AreaHectares: circleHectares,
+		Reock:        ratio(shapes.BoundaryHectares, circleHectares),
+		Geometry:     shapes.Circle,
+	}, nil
+}
+
+// BoundingRectangle returns the oriented minimum bounding rectangle of
+// projectID's boundary. A project without a boundary yields sql.ErrNoRows.
+func (s *service) BoundingRectangle(ctx context.Context, projectID uuid.UUID) (*BoundingRectangle, error) {
+	shapes, err := dbCall(ctx, s, weightQuery, func() (*BoundingShapes, error) {
+		return s.repo.BoundingShapes(ctx, projectID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BoundingRectangle{
+		ProjectID:    projectID,
+		AreaHectares: shapes.RectangleHectares,
+		LengthMeters: math.Max(shapes.SideMeters[0], shapes.SideMeters[1]),
+		WidthMeters:  math.Min(shapes.SideMeters[0], shapes.SideMeters[1]),
+		Fill:         ratio(shapes.BoundaryHectares, shapes.RectangleHectares),
+		Geometry:     shapes.Rectangle,
+	}, nil
+}
+
+// ratio is part/whole, or 0 for a degenerate whole.
+func ratio(part, whole float64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return part / whole
+}
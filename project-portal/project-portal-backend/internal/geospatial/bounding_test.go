@@ -0,0 +1,76 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// boundingRepo measures one 100 ha boundary: a 1000 m circle around it and a
+// 1600 m by 800 m rectangle.
+type boundingRepo struct {
+	*fakeRepo
+	projectID uuid.UUID
+}
+
+func (r *boundingRepo) BoundingShapes(_ context.Context, projectID uuid.UUID) (*BoundingShapes, error) {
+	if projectID != r.projectID {
+		return nil, sql.ErrNoRows
+	}
+	return &BoundingShapes{
+		Center:            [2]float64{36.8, -1.3},
+		RadiusMeters:      1000,
+		Circle:            json.RawMessage(`{"type":"Polygon","coordinates":[]}`),
+		Rectangle:         json.RawMessage(`{"type":"Polygon","coordinates":[]}`),
+		RectangleHectares: 128,
+		SideMeters:        [2]float64{800, 1600},
+		BoundaryHectares:  100,
+	}, nil
+}
+
+func TestBoundingShapes_CompactnessAndMissingBoundary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &boundingRepo{fakeRepo: newFakeRepo(), projectID: uuid.New()}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	get := func(projectID, shape string, out any) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+projectID+"/"+shape, nil))
+		if out != nil {
+			_ = json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+
+	var circle BoundingCircle
+	if code := get(repo.projectID.String(), "bounding-circle", &circle); code != http.StatusOK {
+		t.Fatalf("circle: expected 200, got %d", code)
+	}
+	// π·1000² m² is 314.16 ha.
+	if math.Abs(circle.AreaHectares-314.159) > 0.01 || math.Abs(circle.Reock-100/314.159) > 1e-4 || circle.Center != [2]float64{36.8, -1.3} {
+		t.Errorf("unexpected circle %+v", circle)
+	}
+
+	var rectangle BoundingRectangle
+	if code := get(repo.projectID.String(), "bounding-rectangle", &rectangle); code != http.StatusOK {
+		t.Fatalf("rectangle: expected 200, got %d", code)
+	}
+	if rectangle.LengthMeters != 1600 || rectangle.WidthMeters != 800 || rectangle.Fill != 100.0/128 || len(rectangle.Geometry) == 0 {
+		t.Errorf("unexpected rectangle %+v", rectangle)
+	}
+
+	if code := get(uuid.NewString(), "bounding-circle", nil); code != http.StatusNotFound {
+		t.Errorf("missing boundary: expected 404, got %d", code)
+	}
+	if code := get("not-a-uuid", "bounding-rectangle", nil); code != http.StatusBadRequest {
+		t.Errorf("bad id: expected 400, got %d", code)
+	}
+}
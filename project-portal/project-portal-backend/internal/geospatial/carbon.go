@@ -0,0 +1,131 @@
+package geospatial
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CarbonRates are sequestration rates in tonnes of CO2e per hectare, keyed
+// by lower-case land-cover tag. A project's tags and type are its land-cover
+// tags.
+type CarbonRates map[string]float64
+
+// Rate sources reported by a CarbonEstimate.
+const (
+	RateSupplied  = "supplied"
+	RateLandCover = "land_cover"
+)
+
+// CarbonEstimateQuery asks for a project's estimated sequestration. Rate, in
+// tCO2e per hectare, takes precedence over the configured rates; otherwise
+// LandCover picks one, falling back to the project's tags and then its type.
+type CarbonEstimateQuery struct {
+	Rate      *float64
+	LandCover string
+}
+
+// CarbonEstimate is a project's boundary area times a per-hectare rate.
+type CarbonEstimate struct {
+	ProjectID      uuid.UUID `json:"project_id"`
+	AreaHectares   float64   `json:"area_hectares"`
+	LandCover      string    `json:"land_cover,omitempty"`
+	RatePerHectare float64   `json:"rate_tco2e_per_hectare"`
+	RateSource     string    `json:"rate_source"`
+	EstimatedTCO2e float64   `json:"estimated_tco2e"`
+}
+
+// CarbonInputs are what a carbon estimate needs from the database.
+type CarbonInputs struct {
+	AreaHectares float64
+	ProjectType  string
+	Tags         []string
+}
+
+// MissingCarbonFactorError reports a project for which no rate was supplied
+// and none of its land-cover tags has a configured rate.
+type MissingCarbonFactorError struct {
+	LandCovers []string
+}
+
+func (e *MissingCarbonFactorError) Error() string {
+	if len(e.LandCovers) == 0 {
+		return "no carbon sequestration rate: supply one or tag the project with a land cover"
+	}
+	return fmt.Sprintf("no carbon sequestration rate for land cover %s", strings.Join(e.LandCovers, ", "))
+}
+
+// ParseCarbonRates parses entries of the form tag=rate separated by
+// semicolons, e.g. "reforestation=12.5;mangrove=25". Rates are tCO2e per
+// hectare.
+func ParseCarbonRates(spec string) (CarbonRates, error) {
+	rates := CarbonRates{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("carbon rate entry %q must be tag=rate", entry)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid carbon rate %q for %q", value, name)
+		}
+		rates[strings.ToLower(strings.TrimSpace(name))] = rate
+	}
+	return rates, nil
+}
+
+// lookup returns the first land cover with a configured rate.
+func (r CarbonRates) lookup(landCovers []string) (string, float64, bool) {
+	for _, lc := range landCovers {
+		if rate, ok := r[strings.ToLower(lc)]; ok {
+			return lc, rate, true
+		}
+	}
+	return "", 0, false
+}
+
+// EstimateCarbon multiplies the area of projectID's boundary by the rate
+// q selects. A project without a boundary yields sql.ErrNoRows, one without
+// a rate a *MissingCarbonFactorError.
+func (s *service) EstimateCarbon(ctx context.Context, projectID uuid.UUID, q CarbonEstimateQuery) (*CarbonEstimate, error) {
+	in, err := dbCall(ctx, s, weightQuery, func() (*CarbonInputs, error) {
+		return s.repo.CarbonInputs(ctx, projectID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &CarbonEstimate{ProjectID: projectID, AreaHectares: in.AreaHectares, LandCover: q.LandCover}
+	if q.Rate != nil {
+		out.RatePerHectare, out.RateSource = *q.Rate, RateSupplied
+	} else {
+		landCovers := []string{q.LandCover}
+		if q.LandCover == "" {
+			landCovers = append(append([]string{}, in.Tags...), in.ProjectType)
+		}
+		lc, rate, ok := s.carbonRates.lookup(landCovers)
+		if !ok {
+			return nil, &MissingCarbonFactorError{LandCovers: nonEmpty(landCovers)}
+		}
+		out.LandCover, out.RatePerHectare, out.RateSource = lc, rate, RateLandCover
+	}
+	out.EstimatedTCO2e = out.AreaHectares * out.RatePerHectare
+	return out, nil
+}
+
+func nonEmpty(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// carbonRepo measures one 40 ha mangrove restoration boundary.
+type carbonRepo struct {
+	*fakeRepo
+	projectID uuid.UUID
+}
+
+func (r *carbonRepo) CarbonInputs(_ context.Context, projectID uuid.UUID) (*CarbonInputs, error) {
+	if projectID != r.projectID {
+		return nil, sql.ErrNoRows
+	}
+	return &CarbonInputs{AreaHectares: 40, ProjectType: "Restoration", Tags: []string{"coastal", "Mangrove"}}, nil
+}
+
+func TestParseCarbonRates(t *testing.T) {
+	rates, err := ParseCarbonRates(" Reforestation=12.5; mangrove=25 ;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 2 || rates["reforestation"] != 12.5 || rates["mangrove"] != 25 {
+		t.Errorf("unexpected rates %v", rates)
+	}
+	for _, spec := range []string{"mangrove", "mangrove=lots", "mangrove=-1"} {
+		if _, err := ParseCarbonRates(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestCarbonEstimate_AreaTimesRate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &carbonRepo{fakeRepo: newFakeRepo(), projectID: uuid.New()}
+	svc := NewServiceWithOptions(repo, ServiceOptions{CarbonRates: CarbonRates{"mangrove": 25, "restoration": 8, "grassland": 3}})
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(svc).RegisterRoutes(router.Group("/api/v1"))
+	get := func(projectID, query string, out any) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+projectID+"/carbon-estimate"+query, nil))
+		if out != nil {
+			_ = json.Unmarshal(w.Body.Bytes(), out)
+		}
+		return w.Code
+	}
+
+	cases := []struct {
+		query     string
+		landCover string
+		rate      float64
+		source    string
+	}{
+		{"", "Mangrove", 25, RateLandCover}, // the first tag with a rate wins over the type
+		{"?land_cover=grassland", "grassland", 3, RateLandCover},
+		{"?rate=4.5", "", 4.5, RateSupplied},
+		{"?rate=4.5&land_cover=peat", "peat", 4.5, RateSupplied},
+	}
+	for _, tc := range cases {
+		var est CarbonEstimate
+		if code := get(repo.projectID.String(), tc.query, &est); code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d", tc.query, code)
+		}
+		if est.LandCover != tc.landCover || est.RatePerHectare != tc.rate || est.RateSource != tc.source {
+			t.Errorf("%q: unexpected rate %+v", tc.query, est)
+		}
+		if est.AreaHectares != 40 || est.EstimatedTCO2e != 40*tc.rate {
+			t.Errorf("%q: expected 40 ha × %g = %g tCO2e, got %+v", tc.query, tc.rate, 40*tc.rate, est)
+		}
+	}
+
+	var missing struct {
+		LandCovers []string `json:"land_covers"`
+	}
+	if code := get(repo.projectID.String(), "?land_cover=peat", &missing); code != http.StatusUnprocessableEntity {
+		t.Errorf("unrated land cover: expected 422, got %d", code)
+	}
+	if len(missing.LandCovers) != 1 || missing.LandCovers[0] != "peat" {
+		t.Errorf("expected the 422 to name the land cover tried, got %v", missing.LandCovers)
+	}
+	if code := get(uuid.NewString(), "?rate=1", nil); code != http.StatusNotFound {
+		t.Errorf("missing boundary: expected 404, got %d", code)
+	}
+	if code := get(repo.projectID.String(), "?rate=-2", nil); code != http.StatusBadRequest {
+		t.Errorf("negative rate: expected 400, got %d", code)
+	}
+}
+
+func TestCarbonEstimate_NoConfiguredRate(t *testing.T) {
+	repo := &carbonRepo{fakeRepo: newFakeRepo(), projectID: uuid.New()}
+	_, err := NewService(repo).EstimateCarbon(context.Background(), repo.projectID, CarbonEstimateQuery{})
+	missing, ok := err.(*MissingCarbonFactorError)
+	if !ok {
+		t.Fatalf("expected a MissingCarbonFactorError, got %v", err)
+	}
+	if got := missing.LandCovers; len(got) != 3 || got[0] != "coastal" || got[2] != "Restoration" {
+		t.Errorf("expected the tags then the type to be tried, got %v", got)
+	}
+}
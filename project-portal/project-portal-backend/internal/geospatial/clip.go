@@ -0,0 +1,100 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/geometry"
+)
+
+// Coverage of a project boundary by an area of interest.
+const (
+	CoverageInside  = "inside"
+	CoveragePartial = "partial"
+)
+
+// ClipRequest names the area of interest to clip project boundaries to. It
+// must be a Polygon or MultiPolygon, bare or wrapped in a Feature.
+type ClipRequest struct {
+	GeoJSON json.RawMessage `json:"geojson" binding:"required"`
+}
+
+// ClippedProject is the part of one project's boundary inside the area of
+// interest. Nothing is stored; the geometry is computed per request.
+type ClippedProject struct {
+	ProjectID           uuid.UUID       `json:"project_id"`
+	Name                string          `json:"name"`
+	Coverage            string          `json:"coverage"`
+	Geometry            json.RawMessage `json:"geometry"`
+	ClippedAreaHectares float64         `json:"clipped_area_hectares"`
+	ProjectAreaHectares float64         `json:"project_area_hectares"`
+	ClippedPercent      float64         `json:"clipped_percent"`
+}
+
+// InvalidAOIError is returned when the area of interest cannot be clipped
+// to: it is not a valid Polygon or MultiPolygon, or PostGIS rejected it, as
+// with the TopologyException raised for self-intersecting rings. Err is the
+// database error in the latter case.
+type InvalidAOIError struct {
+	Reason string
+	Err    error
+}
+
+func (e *InvalidAOIError) Error() string {
+	return "invalid area of interest: " + e.Reason
+}
+
+func (e *InvalidAOIError) Unwrap() error { return e.Err }
+
+// ClipToAOI returns each boundary of a live project in the caller's scope
+// that overlaps the area of interest, clipped to it. Projects wholly inside
+// come back whole with coverage "inside"; those straddling the edge are cut
+// with coverage "partial"; projects outside, or only touching the edge, are
+// left out. An unusable area fails with *InvalidAOIError.
+func (s *service) ClipToAOI(ctx context.Context, req ClipRequest) ([]ClippedProject, error) {
+	aoi := geometry.ExtractGeometry(req.GeoJSON)
+	if err := geometry.ValidateGeoJSON(aoi); err != nil {
+		return nil, &InvalidAOIError{Reason: err.Error()}
+	}
+	var kind struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(aoi, &kind)
+	if kind.Type != "Polygon" && kind.Type != "MultiPolygon" {
+		return nil, &InvalidAOIError{Reason: fmt.Sprintf("must be a Polygon or MultiPolygon, got %q", kind.Type)}
+	}
+
+	out, err := dbCall(ctx, s, weightQuery, func() ([]ClippedProject, error) {
+		return s.repo.ClipToAOI(ctx, aoi)
+	})
+	if rejectsGeometry(err) {
+		return nil, &InvalidAOIError{Reason: "PostGIS cannot clip to it; check for self-intersecting or overlapping rings", Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if out[i].ProjectAreaHectares > 0 {
+			out[i].ClippedPercent = out[i].ClippedAreaHectares / out[i].ProjectAreaHectares * 100
+		}
+	}
+	return out, nil
+}
+
+// rejectsGeometry reports whether err is PostGIS refusing an input geometry
+// rather than a database failure: malformed input is a data exception (SQL
+// state class 22), and GEOS failures such as TopologyException surface as
+// internal_error (XX000).
+func rejectsGeometry(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	state := pgErr.SQLState()
+	return strings.HasPrefix(state, "22") || (state == "XX000" && strings.Contains(err.Error(), "TopologyException"))
+}
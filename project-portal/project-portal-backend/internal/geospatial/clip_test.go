@@ -0,0 +1,115 @@
+package geospatial
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// clipRepo clips one project wholly inside the area and one across its edge,
+// or fails with err when set.
+type clipRepo struct {
+	*fakeRepo
+	aoi   json.RawMessage
+	scope project.Scope
+	err   error
+}
+
+func (r *clipRepo) ClipToAOI(ctx context.Context, aoi json.RawMessage) ([]ClippedProject, error) {
+	r.aoi = aoi
+	r.scope, _ = project.ScopeFromContext(ctx)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []ClippedProject{
+		{ProjectID: uuid.New(), Name: "Inside", Coverage: CoverageInside, ClippedAreaHectares: 40, ProjectAreaHectares: 40},
+		{ProjectID: uuid.New(), Name: "Straddling", Coverage: CoveragePartial, ClippedAreaHectares: 15, ProjectAreaHectares: 60},
+	}, nil
+}
+
+func TestClipToAOI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &clipRepo{fakeRepo: newFakeRepo()}
+	router := gin.New()
+	signIn(t, router, "user")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/analysis/clip", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	polygon := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`
+	w := post(`{"geojson":{"type":"Feature","properties":{},"geometry":` + polygon + `}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Projects []ClippedProject `json:"projects"`
+		Count    int              `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Count != 2 || len(resp.Projects) != 2 {
+		t.Fatalf("expected two projects, got %s", w.Body.String())
+	}
+	if got := resp.Projects[0]; got.Coverage != CoverageInside || got.ClippedPercent != 100 {
+		t.Errorf("inside: expected 100%% clipped, got %+v", got)
+	}
+	if got := resp.Projects[1]; got.Coverage != CoveragePartial || got.ClippedPercent != 25 {
+		t.Errorf("partial: expected 25%% clipped, got %+v", got)
+	}
+	if string(repo.aoi) != polygon {
+		t.Errorf("expected the Feature's geometry to reach the repository, got %s", repo.aoi)
+	}
+	if repo.scope.All || repo.scope.OwnerID == uuid.Nil {
+		t.Errorf("expected the clip to carry the caller's owner scope, got %+v", repo.scope)
+	}
+
+	for _, body := range []string{
+		`{}`,
+		`{"geojson":{"type":"Point","coordinates":[0,0]}}`,
+		`{"geojson":{"type":"LineString","coordinates":[[0,0],[1,1]]}}`,
+	} {
+		if w := post(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+
+	// A bowtie passes the GeoJSON checks but GEOS cannot intersect it.
+	repo.err = &pq.Error{Code: "XX000", Message: "GEOSIntersects: TopologyException: side location conflict at 0.5 0.5"}
+	w = post(`{"geojson":` + polygon + `}`)
+	if w.Code != http.StatusBadRequest || bytes.Contains(w.Body.Bytes(), []byte("GEOS")) {
+		t.Errorf("topology error: expected a 400 without the PostGIS text, got %d: %s", w.Code, w.Body.String())
+	}
+	repo.err = errors.New("connection refused")
+	if w := post(`{"geojson":` + polygon + `}`); w.Code != http.StatusInternalServerError {
+		t.Errorf("database failure: expected 500, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestClipToAOI_RequiresSignIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(NewService(&clipRepo{fakeRepo: newFakeRepo()})).RegisterRoutes(router.Group("/api/v1"))
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/analysis/clip", bytes.NewBufferString(`{"geojson":{}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", w.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package geospatial
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geometryCacheControl lets clients keep geometry but revalidate it on every
+// use; the ETag makes revalidation a cheap 304.
+const geometryCacheControl = "private, no-cache"
+
+// geometryETag is a strong validator over the geometry, its last update and
+// any variant of the representation (such as the export format).
+func geometryETag(geometry []byte, updatedAt time.Time, variant string) string {
+	h := sha256.New()
+	h.Write(geometry)
+	h.Write([]byte{0})
+	h.Write([]byte(updatedAt.UTC().Format(time.RFC3339Nano)))
+	h.Write([]byte{0})
+	h.Write([]byte(variant))
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches implements the weak comparison If-None-Match calls for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondCacheable writes body as JSON with ETag and Cache-Control, or a bare
+// 304 when the client already holds this version.
+func respondCacheable(c *gin.Context, etag string, body interface{}) {
+	if notModified(c, etag) {
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// respondCacheableData is respondCacheable for a body already serialized as
+// contentType.
+func respondCacheableData(c *gin.Context, etag, contentType string, body []byte) {
+	if notModified(c, etag) {
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// notModified sets the caching headers and answers 304 when the client's copy
+// is current.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", geometryCacheControl)
+	if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
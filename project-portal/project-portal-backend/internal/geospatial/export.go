@@ -0,0 +1,200 @@
+package geospatial
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/shapefile"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidExport wraps every rejection of an ExportQuery.
+var ErrInvalidExport = errors.New("invalid export query")
+
+// exportBase names the files inside an export archive.
+const exportBase = "projects"
+
+// exportFields are the Shapefile attribute columns, limited to 10-byte names.
+var exportFields = []shapefile.Field{
+	{Name: "project_id", Type: 'C', Length: 36},
+	{Name: "name", Type: 'C', Length: 254},
+	{Name: "type", Type: 'C', Length: 50},
+	{Name: "area_ha", Type: 'N', Length: 19, Decimals: 4},
+	{Name: "tags", Type: 'C', Length: 254},
+}
+
+// normalizeExportQuery validates q and fills in defaults.
+func normalizeExportQuery(q ExportQuery) (ExportQuery, error) {
+	switch strings.ToLower(q.Format) {
+	case "", ExportGeoJSON:
+		q.Format = ExportGeoJSON
+	case ExportShapefile, "shp":
+		q.Format = ExportShapefile
+	default:
+		return q, fmt.Errorf("format must be %q or %q", ExportGeoJSON, ExportShapefile)
+	}
+
+	bounds := 0
+	for _, v := range []*float64{q.MinLat, q.MinLon, q.MaxLat, q.MaxLon} {
+		if v != nil {
+			bounds++
+		}
+	}
+	switch bounds {
+	case 0:
+	case 4:
+		if *q.MinLon >= *q.MaxLon || *q.MinLat >= *q.MaxLat {
+			return q, fmt.Errorf("bbox minimums must be below its maximums")
+		}
+	default:
+		return q, fmt.Errorf("bbox needs all of min_lat, min_lon, max_lat and max_lon")
+	}
+
+	match, err := project.ParseTagMatch(q.TagMatch)
+	if err != nil {
+		return q, err
+	}
+	q.TagMatch = match
+
+	if q.OwnerID != "" {
+		if _, err := uuid.Parse(q.OwnerID); err != nil {
+			return q, fmt.Errorf("owner_id must be a UUID")
+		}
+	}
+	return q, nil
+}
+
+// ExportProjects writes a zip of every project matching q to w: a single
+// GeoJSON FeatureCollection, or a Shapefile with its .prj and .cpg. Rows are
+// streamed from the database; the Shapefile is spooled to temporary files
+// because its headers need the final totals. Nothing is written to w when q is
+// invalid.
+func (s *service) ExportProjects(ctx context.Context, q ExportQuery, w io.Writer) error {
+	q, err := normalizeExportQuery(q)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	// The export holds its slot while the archive streams to the client.
+	release, err := s.limiter.acquire(ctx, weightBulk)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	zw := zip.NewWriter(w)
+	if q.Format == ExportShapefile {
+		err = s.exportShapefile(ctx, q, zw)
+	} else {
+		err = s.exportGeoJSON(ctx, q, zw)
+	}
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (s *service) exportGeoJSON(ctx context.Context, q ExportQuery, zw *zip.Writer) error {
+	out, err := zw.Create(exportBase + ".geojson")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(out, `{"type":"FeatureCollection","features":[`); err != nil {
+		return err
+	}
+	first := true
+	err = s.repo.ExportGeometries(ctx, q, func(f ExportFeature) error {
+		tags := f.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		feature, err := json.Marshal(map[string]interface{}{
+			"type":     "Feature",
+			"id":       f.ProjectID,
+			"geometry": f.Geometry,
+			"properties": map[string]interface{}{
+				"project_id":    f.ProjectID,
+				"name":          f.Name,
+				"type":          f.Type,
+				"tags":          tags,
+				"area_hectares": f.AreaHectares,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(out, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = out.Write(feature)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, "]}")
+	return err
+}
+
+func (s *service) exportShapefile(ctx context.Context, q ExportQuery, zw *zip.Writer) error {
+	sw, err := shapefile.NewWriter("", exportFields)
+	if err != nil {
+		return err
+	}
+	defer sw.Close()
+
+	err = s.repo.ExportGeometries(ctx, q, func(f ExportFeature) error {
+		polygons, err := shapefilePolygons(f.Geometry)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", f.ProjectID, err)
+		}
+		return sw.WritePolygons(polygons, []string{
+			f.ProjectID.String(),
+			f.Name,
+			f.Type,
+			strconv.FormatFloat(f.AreaHectares, 'f', -1, 64),
+			strings.Join(f.Tags, ","),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return sw.WriteZip(zw, exportBase)
+}
+
+// shapefilePolygons reads a GeoJSON Polygon or MultiPolygon.
+func shapefilePolygons(raw json.RawMessage) ([]shapefile.Polygon, error) {
+	var g struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, err
+	}
+	switch g.Type {
+	case "Polygon":
+		var polygon shapefile.Polygon
+		if err := json.Unmarshal(g.Coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		return []shapefile.Polygon{polygon}, nil
+	case "MultiPolygon":
+		var polygons []shapefile.Polygon
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return nil, err
+		}
+		return polygons, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %q", g.Type)
+}
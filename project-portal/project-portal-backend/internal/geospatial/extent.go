@@ -0,0 +1,92 @@
+package geospatial
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+
+	"github.com/google/uuid"
+)
+
+// webMercatorMaxLat is the latitude where Web Mercator tiles end.
+const webMercatorMaxLat = 85.0511287798
+
+// maxExtentZoom caps the suggested zoom, so a single small project still opens
+// with some surroundings in view.
+const maxExtentZoom = 16
+
+// defaultMapExtent is returned when no project matches: the whole world.
+var defaultMapExtent = MapExtent{
+	Bounds:  [4]float64{-180, -webMercatorMaxLat, 180, webMercatorMaxLat},
+	Center:  [2]float64{0, 0},
+	Zoom:    1,
+	Default: true,
+}
+
+// ProjectExtent returns the map view covering every project matching q: their
+// combined bounding box, its center and the largest zoom at which it fits in
+// one 256px tile. An empty set gets defaultMapExtent.
+func (s *service) ProjectExtent(ctx context.Context, q ExtentQuery) (*MapExtent, error) {
+	match, err := project.ParseTagMatch(q.TagMatch)
+	if err != nil {
+		return nil, err
+	}
+	q.TagMatch = match
+	if q.OwnerID != "" {
+		if _, err := uuid.Parse(q.OwnerID); err != nil {
+			return nil, fmt.Errorf("owner_id must be a UUID")
+		}
+	}
+
+	type extent struct {
+		bounds [4]float64
+		count  int
+	}
+	found, err := dbCall(ctx, s, weightQuery, func() (extent, error) {
+		bounds, count, err := s.repo.ProjectExtent(ctx, q)
+		return extent{bounds, count}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found.count == 0 {
+		out := defaultMapExtent
+		return &out, nil
+	}
+
+	b := found.bounds
+	return &MapExtent{
+		Bounds: b,
+		Center: [2]float64{(b[0] + b[2]) / 2, (b[1] + b[3]) / 2},
+		Zoom:   fitZoom(b),
+		Count:  found.count,
+	}, nil
+}
+
+// fitZoom is the largest Web Mercator zoom, up to maxExtentZoom, at which
+// bounds fits inside a single tile both across and up.
+func fitZoom(bounds [4]float64) int {
+	lonSpan := (bounds[2] - bounds[0]) / 360
+	latSpan := (mercatorY(bounds[3]) - mercatorY(bounds[1])) / (2 * math.Pi)
+	span := math.Max(lonSpan, latSpan)
+	if span <= 0 {
+		return maxExtentZoom
+	}
+	zoom := int(math.Floor(math.Log2(1 / span)))
+	if zoom < 0 {
+		return 0
+	}
+	if zoom > maxExtentZoom {
+		return maxExtentZoom
+	}
+	return zoom
+}
+
+// mercatorY projects a latitude in degrees to Web Mercator radians, clamped
+// to the tiled range.
+func mercatorY(lat float64) float64 {
+	lat = math.Max(-webMercatorMaxLat, math.Min(webMercatorMaxLat, lat))
+	return math.Log(math.Tan(math.Pi/4 + lat*math.Pi/360))
+}
@@ -0,0 +1,157 @@
+package geospatial
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/geometry"
+)
+
+// featureStream reads the features of a GeoJSON FeatureCollection one at a
+// time, so an import holds a single feature in memory rather than the whole
+// document.
+type featureStream struct {
+	dec *json.Decoder
+	// srid is the collection's crs, applied to features without their own.
+	srid int
+	// headerRead is set when type and crs were checked before streaming, so
+	// their position in the document does not matter.
+	headerRead bool
+	done       bool
+}
+
+// readFeatureStream starts reading a FeatureCollection from r. Because
+// features are processed as they arrive, the collection's type, and its crs
+// if any, must come before its features.
+func readFeatureStream(r io.Reader) (*featureStream, error) {
+	return openFeatureStream(r, geometry.StorageSRID, false)
+}
+
+func openFeatureStream(r io.Reader, srid int, headerRead bool) (*featureStream, error) {
+	f := &featureStream{dec: json.NewDecoder(r), srid: srid, headerRead: headerRead}
+	if err := f.expect(json.Delim('{')); err != nil {
+		return nil, err
+	}
+	isCollection := headerRead
+	for f.dec.More() {
+		key, err := f.key()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case key == "features":
+			if !isCollection {
+				return nil, fmt.Errorf("geojson must be a FeatureCollection whose type precedes its features")
+			}
+			if err := f.expect(json.Delim('[')); err != nil {
+				return nil, err
+			}
+			return f, nil
+		case key == "type" && !headerRead:
+			var typ string
+			if err := f.dec.Decode(&typ); err != nil {
+				return nil, fmt.Errorf("invalid json: %w", err)
+			}
+			if typ != "FeatureCollection" {
+				return nil, fmt.Errorf("geojson must be a FeatureCollection")
+			}
+			isCollection = true
+		case key == "crs" && !headerRead:
+			var crs json.RawMessage
+			if err := f.dec.Decode(&crs); err != nil {
+				return nil, fmt.Errorf("invalid json: %w", err)
+			}
+			if f.srid, err = geometry.DetectSRID(json.RawMessage(`{"crs":` + string(crs) + `}`)); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !isCollection {
+		return nil, fmt.Errorf("geojson must be a FeatureCollection")
+	}
+	return nil, fmt.Errorf("feature collection is empty")
+}
+
+// next returns the next feature, or io.EOF once the collection has been read
+// to its end.
+func (f *featureStream) next() (json.RawMessage, error) {
+	if f.done {
+		return nil, io.EOF
+	}
+	if f.dec.More() {
+		var raw json.RawMessage
+		if err := f.dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid json: %w", err)
+		}
+		return raw, nil
+	}
+	if err := f.expect(json.Delim(']')); err != nil {
+		return nil, err
+	}
+	for f.dec.More() {
+		key, err := f.key()
+		if err != nil {
+			return nil, err
+		}
+		if key == "crs" && !f.headerRead {
+			return nil, fmt.Errorf("the collection's crs must precede its features")
+		}
+		if err := f.skip(); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.expect(json.Delim('}')); err != nil {
+		return nil, err
+	}
+	f.done = true
+	return nil, io.EOF
+}
+
+func (f *featureStream) key() (string, error) {
+	tok, err := f.dec.Token()
+	if err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid json: expected an object key")
+	}
+	return key, nil
+}
+
+func (f *featureStream) expect(delim json.Delim) error {
+	tok, err := f.dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	if tok != delim {
+		return fmt.Errorf("invalid json: expected %q", delim)
+	}
+	return nil
+}
+
+// skip discards the next value without keeping it.
+func (f *featureStream) skip() error {
+	depth := 0
+	for {
+		tok, err := f.dec.Token()
+		if err != nil {
+			return fmt.Errorf("invalid json: %w", err)
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package geospatial
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReadFeatureStream_MemberOrder(t *testing.T) {
+	feature := polygonFeature(uuid.New())
+	for _, tc := range []struct {
+		name, doc, wantErr string
+		srid               int
+	}{
+		{name: "plain", doc: `{"type":"FeatureCollection","features":[` + feature + `]}`, srid: 4326},
+		{name: "crs first", doc: `{"type":"FeatureCollection","crs":{"type":"name","properties":{"name":"EPSG:3857"}},"features":[` + feature + `]}`, srid: 3857},
+		{name: "trailing members", doc: `{"type":"FeatureCollection","features":[` + feature + `],"bbox":[1,2,3,4],"name":{"nested":[1]}}`, srid: 4326},
+		{name: "features first", doc: `{"features":[` + feature + `],"type":"FeatureCollection"}`, wantErr: "type precedes its features"},
+		{name: "crs last", doc: `{"type":"FeatureCollection","features":[` + feature + `],"crs":{"type":"name","properties":{"name":"EPSG:3857"}}}`, wantErr: "crs must precede"},
+		{name: "not a collection", doc: `{"type":"Feature","geometry":null}`, wantErr: "must be a FeatureCollection"},
+		{name: "truncated", doc: `{"type":"FeatureCollection","features":[` + feature, wantErr: "invalid json"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n, srid, err := drainFeatureStream(strings.NewReader(tc.doc))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || n != 1 || srid != tc.srid {
+				t.Fatalf("expected 1 feature in SRID %d, got %d in %d (err %v)", tc.srid, n, srid, err)
+			}
+		})
+	}
+}
+
+func drainFeatureStream(r io.Reader) (int, int, error) {
+	features, err := readFeatureStream(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	n := 0
+	for {
+		_, err := features.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, features.srid, err
+		}
+		n++
+	}
+	return n, features.srid, nil
+}
+
+// discardRepo stores nothing, so an import's memory is only what the import
+// itself keeps. Every sampleEvery writes it records the live heap.
+type discardRepo struct {
+	Repository
+	sampleEvery int
+
+	mu       sync.Mutex
+	writes   int
+	peakHeap uint64
+}
+
+func (r *discardRepo) UpsertProjectGeometry(_ context.Context, projectID uuid.UUID, _ UploadGeometryRequest) (*ProjectGeometry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes++
+	if r.writes%r.sampleEvery == 0 {
+		runtime.GC()
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		r.peakHeap = max(r.peakHeap, m.HeapAlloc)
+	}
+	return &ProjectGeometry{ID: uuid.New(), ProjectID: projectID, Version: 1}, nil
+}
+
+func (r *discardRepo) InTransaction(_ context.Context, fn func(tx Repository) error) error {
+	return fn(r)
+}
+
+// circleFeature is a valid polygon of vertices points, about 27 bytes each.
+func circleFeature(projectID uuid.UUID, vertices int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"type":"Feature","properties":{"project_id":%q},"geometry":{"type":"Polygon","coordinates":[[`, projectID)
+	for i := 0; i <= vertices; i++ {
+		angle := 2 * math.Pi * float64(i%vertices) / float64(vertices)
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "[%.6f,%.6f]", 36.8+0.01*math.Cos(angle), -1.3+0.01*math.Sin(angle))
+	}
+	b.WriteString("]]}}")
+	return b.String()
+}
+
+func TestImportFeatureStream_LargeCollectionInBoundedMemory(t *testing.T) {
+	const features, vertices = 3000, 250
+	featureSize := len(circleFeature(uuid.New(), vertices))
+	documentSize := features * featureSize
+
+	// The document is generated as it is read and never exists in full.
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		w.WriteString(`{"type":"FeatureCollection","features":[`)
+		for i := 0; i < features; i++ {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(circleFeature(uuid.New(), vertices))
+		}
+		w.WriteString("]}")
+		pw.CloseWithError(w.Flush())
+	}()
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	repo := &discardRepo{sampleEvery: 250}
+	result, err := NewService(repo).ImportFeatureStream(context.Background(), pr, BatchImportRequest{}, BatchImportModeStrict)
+	if err != nil {
+		t.Fatalf("ImportFeatureStream: %v", err)
+	}
+	if result.Total != features || result.Imported != features {
+		t.Fatalf("expected all %d features imported, got total %d imported %d", features, result.Total, result.Imported)
+	}
+
+	growth := int64(repo.peakHeap) - int64(before.HeapAlloc)
+	t.Logf("document %d bytes, peak live heap growth %d bytes", documentSize, growth)
+	if growth > int64(documentSize)/10 {
+		t.Errorf("live heap grew by %d bytes while importing a %d byte document; features should not be retained", growth, documentSize)
+	}
+}
@@ -0,0 +1,70 @@
+// Package geocoding looks up addresses with an external geocoding service so
+// projects can be located by address instead of a drawn boundary.
+package geocoding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultLimit is how many candidates are returned when none is requested.
+const DefaultLimit = 5
+
+var (
+	// ErrTimeout is returned when the upstream service does not answer in
+	// time.
+	ErrTimeout = errors.New("geocoding service timed out")
+	// ErrUnavailable is returned when the upstream service fails or answers
+	// with something that cannot be read.
+	ErrUnavailable = errors.New("geocoding service unavailable")
+)
+
+// RateLimitedError is returned when the upstream service refuses a lookup
+// because too many were made. RetryAfter is zero when it did not say.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return "geocoding service rate limit reached"
+}
+
+// Candidate is one place an address may refer to. Confidence runs from 0 to
+// 1; candidates are returned most confident first. BoundingBox, when known,
+// is min lon, min lat, max lon, max lat.
+type Candidate struct {
+	DisplayName string      `json:"display_name"`
+	Lat         float64     `json:"lat"`
+	Lon         float64     `json:"lon"`
+	Confidence  float64     `json:"confidence"`
+	Type        string      `json:"type,omitempty"`
+	BoundingBox *[4]float64 `json:"bbox,omitempty"`
+}
+
+// Geocoder turns a free-form address into candidate coordinates. An address
+// nothing matches yields no candidates and no error.
+type Geocoder interface {
+	Geocode(ctx context.Context, address string, limit int) ([]Candidate, error)
+}
+
+// Config selects and configures a Geocoder.
+type Config struct {
+	Provider  string // "nominatim"; empty disables geocoding
+	BaseURL   string
+	UserAgent string
+	Timeout   time.Duration
+}
+
+// New returns the Geocoder cfg names, or nil when cfg.Provider is empty.
+func New(cfg Config) (Geocoder, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "nominatim":
+		return NewNominatim(cfg.BaseURL, cfg.UserAgent, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown geocoding provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,122 @@
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNominatim_ParsesCandidates(t *testing.T) {
+	var gotQuery, gotAgent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery, gotAgent = r.URL.Query().Get("q"), r.Header.Get("User-Agent")
+		w.Write([]byte(`[
+			{"display_name":"Nakuru, Kenya","lat":"-0.2833","lon":"36.0667","importance":0.61,"type":"city","boundingbox":["-0.4","-0.2","35.9","36.2"]},
+			{"display_name":"Nairobi, Kenya","lat":"-1.2864","lon":"36.8172","importance":0.82,"type":"city"},
+			{"display_name":"Broken","lat":"n/a","lon":"36.0"}
+		]`))
+	}))
+	defer upstream.Close()
+
+	got, err := NewNominatim(upstream.URL, "carbon-scribe-test", time.Second).Geocode(context.Background(), "Nakuru", 3)
+	if err != nil {
+		t.Fatalf("Geocode: %v", err)
+	}
+	if gotQuery != "Nakuru" || gotAgent != "carbon-scribe-test" {
+		t.Errorf("expected q=Nakuru with the configured User-Agent, got %q %q", gotQuery, gotAgent)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 usable candidates, got %+v", got)
+	}
+	if got[0].DisplayName != "Nairobi, Kenya" || got[0].Confidence != 0.82 {
+		t.Errorf("expected the most confident candidate first, got %+v", got[0])
+	}
+	nakuru := got[1]
+	if nakuru.Lat != -0.2833 || nakuru.Lon != 36.0667 || nakuru.BoundingBox == nil || *nakuru.BoundingBox != [4]float64{35.9, -0.4, 36.2, -0.2} {
+		t.Errorf("unexpected candidate %+v (bbox %v)", nakuru, nakuru.BoundingBox)
+	}
+}
+
+func TestNominatim_UpstreamFailures(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	if _, err := NewNominatim(slow.URL, "", 50*time.Millisecond).Geocode(context.Background(), "Nakuru", 1); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected ErrTimeout from a slow upstream, got %v", err)
+	}
+
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+	var rateLimited *RateLimitedError
+	if _, err := NewNominatim(limited.URL, "", time.Second).Geocode(context.Background(), "Nakuru", 1); !errors.As(err, &rateLimited) || rateLimited.RetryAfter != 30*time.Second {
+		t.Errorf("expected a RateLimitedError retrying after 30s, got %v", err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if _, err := NewNominatim(failing.URL, "", time.Second).Geocode(context.Background(), "Nakuru", 1); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+// stubGeocoder answers every lookup with candidates or err.
+type stubGeocoder struct {
+	candidates []Candidate
+	err        error
+	limit      int
+}
+
+func (s *stubGeocoder) Geocode(_ context.Context, _ string, limit int) ([]Candidate, error) {
+	s.limit = limit
+	return s.candidates, s.err
+}
+
+func TestGeocodeHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stub := &stubGeocoder{candidates: []Candidate{{DisplayName: "Nakuru, Kenya", Lat: -0.28, Lon: 36.07, Confidence: 0.6}}}
+	router := gin.New()
+	NewHandler(stub).RegisterRoutes(router.Group("/api/v1"))
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/geocode?"+query, nil))
+		return w
+	}
+
+	w := get("q=Nakuru&limit=2")
+	var body struct {
+		Candidates []Candidate `json:"candidates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || len(body.Candidates) != 1 || stub.limit != 2 {
+		t.Errorf("expected 200 with one candidate for limit 2, got %d (limit %d): %s", w.Code, stub.limit, w.Body.String())
+	}
+	for _, query := range []string{"", "q=+", "q=Nakuru&limit=0", "q=Nakuru&limit=100"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	stub.err = ErrTimeout
+	if w := get("q=Nakuru"); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("upstream timeout: expected 504, got %d", w.Code)
+	}
+	stub.err = &RateLimitedError{RetryAfter: 1500 * time.Millisecond}
+	if w := get("q=Nakuru"); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("upstream rate limit: expected 503 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
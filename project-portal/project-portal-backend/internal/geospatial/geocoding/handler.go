@@ -0,0 +1,67 @@
+package geocoding
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLimit caps ?limit= so one request cannot ask the upstream for a page of
+// results.
+const maxLimit = 20
+
+type Handler struct {
+	geocoder Geocoder
+}
+
+func NewHandler(geocoder Geocoder) *Handler {
+	return &Handler{geocoder: geocoder}
+}
+
+func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
+	rg.GET("/geospatial/geocode", h.Geocode)
+}
+
+// Geocode looks up ?q= and returns candidate coordinates, most confident
+// first. ?limit= (1-20) caps how many. An upstream that times out answers
+// 504; one that is rate limiting or down answers 503, with Retry-After when
+// known.
+func (h *Handler) Geocode(c *gin.Context) {
+	address := strings.TrimSpace(c.Query("q"))
+	if address == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	limit := DefaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 20"})
+			return
+		}
+		limit = n
+	}
+
+	candidates, err := h.geocoder.Geocode(c.Request.Context(), address, limit)
+	var rateLimited *RateLimitedError
+	switch {
+	case errors.As(err, &rateLimited):
+		if rateLimited.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+	case err != nil:
+		logging.FromContext(c.Request.Context()).Warn("geocoding failed", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": ErrUnavailable.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"query": address, "candidates": candidates, "count": len(candidates)})
+	}
+}
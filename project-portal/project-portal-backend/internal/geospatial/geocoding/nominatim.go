@@ -0,0 +1,150 @@
+package geocoding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultNominatimURL is the public OpenStreetMap instance. Its usage
+	// policy allows about one lookup per second and requires a User-Agent
+	// identifying the application.
+	DefaultNominatimURL = "https://nominatim.openstreetmap.org"
+	defaultUserAgent    = "carbon-scribe-project-portal"
+	defaultTimeout      = 5 * time.Second
+)
+
+// Nominatim geocodes with a Nominatim search API.
+type Nominatim struct {
+	baseURL    string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// NewNominatim returns a client for the Nominatim instance at baseURL.
+// Empty arguments use DefaultNominatimURL, a generic User-Agent and a 5s
+// timeout.
+func NewNominatim(baseURL, userAgent string, timeout time.Duration) *Nominatim {
+	if baseURL == "" {
+		baseURL = DefaultNominatimURL
+	}
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Nominatim{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		userAgent:  userAgent,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// nominatimPlace is one result of /search?format=jsonv2. Coordinates come as
+// strings; boundingbox is min lat, max lat, min lon, max lon.
+type nominatimPlace struct {
+	DisplayName string   `json:"display_name"`
+	Lat         string   `json:"lat"`
+	Lon         string   `json:"lon"`
+	Importance  float64  `json:"importance"`
+	Type        string   `json:"type"`
+	BoundingBox []string `json:"boundingbox"`
+}
+
+// Geocode searches for address. Nominatim's importance, a 0-1 ranking of
+// how prominent a place is, serves as the confidence.
+func (n *Nominatim) Geocode(ctx context.Context, address string, limit int) ([]Candidate, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	q := url.Values{}
+	q.Set("q", address)
+	q.Set("format", "jsonv2")
+	q.Set("limit", strconv.Itoa(limit))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+"/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", n.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, ErrTimeout
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &RateLimitedError{RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: upstream answered %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	var places []nominatimPlace
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		if isTimeout(err) {
+			return nil, ErrTimeout
+		}
+		return nil, fmt.Errorf("%w: unreadable response: %v", ErrUnavailable, err)
+	}
+	out := make([]Candidate, 0, len(places))
+	for _, p := range places {
+		c, ok := p.candidate()
+		if ok {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Confidence > out[j].Confidence })
+	return out, nil
+}
+
+// candidate converts p, reporting false when its coordinates are unusable.
+func (p nominatimPlace) candidate() (Candidate, bool) {
+	lat, errLat := strconv.ParseFloat(p.Lat, 64)
+	lon, errLon := strconv.ParseFloat(p.Lon, 64)
+	if errLat != nil || errLon != nil {
+		return Candidate{}, false
+	}
+	c := Candidate{DisplayName: p.DisplayName, Lat: lat, Lon: lon, Confidence: min(max(p.Importance, 0), 1), Type: p.Type}
+	if len(p.BoundingBox) == 4 {
+		var b [4]float64
+		for i, s := range p.BoundingBox {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return c, true
+			}
+			b[i] = v
+		}
+		c.BoundingBox = &[4]float64{b[2], b[0], b[3], b[1]}
+	}
+	return c, true
+}
+
+func isTimeout(err error) bool {
+	var netErr interface{ Timeout() bool }
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(v string) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StorageSRID is the SRID boundaries are stored and validated in. RFC 7946
+// GeoJSON is always WGS 84 longitude/latitude.
+const StorageSRID = 4326
+
+// sridAliases maps non-EPSG codes some tools still write to their EPSG SRID.
+var sridAliases = map[int]int{
+	900913: 3857, // the unofficial "Google" Web Mercator code
+	102100: 3857, // Esri's Web Mercator code
+}
+
+// supportedSRID reports whether PostGIS is expected to reproject srid: WGS 84,
+// NAD83, Web Mercator and the WGS 84 UTM zones.
+func supportedSRID(srid int) bool {
+	switch {
+	case srid == 4326, srid == 4269, srid == 3857:
+		return true
+	case srid >= 32601 && srid <= 32660, srid >= 32701 && srid <= 32760:
+		return true
+	}
+	return false
+}
+
+// ParseCRSName maps a named CRS from the legacy GeoJSON crs member to an SRID.
+// It accepts "EPSG:3857", "urn:ogc:def:crs:EPSG::3857" (with or without a
+// version) and the OGC CRS84 names, which are WGS 84.
+func ParseCRSName(name string) (int, error) {
+	n := strings.TrimSpace(name)
+	upper := strings.ToUpper(n)
+	if upper == "CRS84" || strings.HasPrefix(upper, "URN:OGC:DEF:CRS:OGC:") && strings.HasSuffix(upper, ":CRS84") {
+		return StorageSRID, nil
+	}
+
+	var code string
+	switch {
+	case strings.HasPrefix(upper, "EPSG:"):
+		code = n[len("EPSG:"):]
+	case strings.HasPrefix(upper, "URN:OGC:DEF:CRS:EPSG:"):
+		// urn:ogc:def:crs:EPSG:[version]:code
+		code = n[strings.LastIndex(n, ":")+1:]
+	default:
+		return 0, fmt.Errorf("unsupported crs %q: expected an EPSG code or CRS84", name)
+	}
+	srid, err := strconv.Atoi(code)
+	if err != nil || srid <= 0 {
+		return 0, fmt.Errorf("unsupported crs %q: invalid EPSG code", name)
+	}
+	if alias, ok := sridAliases[srid]; ok {
+		srid = alias
+	}
+	if !supportedSRID(srid) {
+		return 0, fmt.Errorf("unsupported crs %q: EPSG:%d cannot be reprojected", name, srid)
+	}
+	return srid, nil
+}
+
+type crsMember struct {
+	CRS *struct {
+		Type       string `json:"type"`
+		Properties struct {
+			Name string `json:"name"`
+		} `json:"properties"`
+	} `json:"crs"`
+	Type     string          `json:"type"`
+	Geometry json.RawMessage `json:"geometry"`
+}
+
+// DetectSRID returns the SRID declared by the crs member of a GeoJSON object,
+// looking inside a Feature's geometry when the Feature has none. Objects
+// without a crs member are WGS 84 (StorageSRID), as RFC 7946 requires. Only
+// named CRSes are understood; linked ones are rejected.
+func DetectSRID(raw json.RawMessage) (int, error) {
+	return detectSRID(raw, StorageSRID)
+}
+
+// DetectSRIDWithDefault is DetectSRID where objects without a crs member take
+// fallback, e.g. the SRID declared on their FeatureCollection.
+func DetectSRIDWithDefault(raw json.RawMessage, fallback int) (int, error) {
+	return detectSRID(raw, fallback)
+}
+
+func detectSRID(raw json.RawMessage, fallback int) (int, error) {
+	var obj crsMember
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return 0, fmt.Errorf("invalid json: %w", err)
+	}
+	if obj.CRS == nil {
+		if obj.Type == "Feature" && len(obj.Geometry) > 0 {
+			return detectSRID(obj.Geometry, fallback)
+		}
+		return fallback, nil
+	}
+	if obj.CRS.Type != "name" {
+		return 0, fmt.Errorf("unsupported crs type %q: only named crs members are supported", obj.CRS.Type)
+	}
+	return ParseCRSName(obj.CRS.Properties.Name)
+}
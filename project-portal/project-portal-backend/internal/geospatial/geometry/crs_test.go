@@ -0,0 +1,52 @@
+package geometry
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseCRSName(t *testing.T) {
+	for name, want := range map[string]int{
+		"EPSG:3857":                     3857,
+		"epsg:4326":                     4326,
+		"urn:ogc:def:crs:EPSG::3857":    3857,
+		"urn:ogc:def:crs:EPSG:6.6:4326": 4326,
+		"urn:ogc:def:crs:OGC:1.3:CRS84": 4326,
+		"urn:ogc:def:crs:OGC::CRS84":    4326,
+		"EPSG:900913":                   3857,
+		"EPSG:32737":                    32737,
+	} {
+		got, err := ParseCRSName(name)
+		if err != nil || got != want {
+			t.Errorf("ParseCRSName(%q) = %d, %v; want %d", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"", "EPSG:abc", "EPSG:27700", "urn:ogc:def:crs:EPSG::", "WGS84"} {
+		if _, err := ParseCRSName(name); err == nil {
+			t.Errorf("ParseCRSName(%q): expected an error", name)
+		}
+	}
+}
+
+func TestDetectSRID(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want int
+	}{
+		{`{"type":"Polygon","coordinates":[]}`, 4326},
+		{`{"type":"Polygon","crs":{"type":"name","properties":{"name":"EPSG:3857"}},"coordinates":[]}`, 3857},
+		{`{"type":"Feature","crs":{"type":"name","properties":{"name":"EPSG:3857"}},"geometry":{"type":"Polygon"}}`, 3857},
+		{`{"type":"Feature","geometry":{"type":"Polygon","crs":{"type":"name","properties":{"name":"EPSG:32737"}}}}`, 32737},
+	}
+	for _, tc := range cases {
+		if got, err := DetectSRID(json.RawMessage(tc.raw)); err != nil || got != tc.want {
+			t.Errorf("DetectSRID(%s) = %d, %v; want %d", tc.raw, got, err, tc.want)
+		}
+	}
+	if got, _ := DetectSRIDWithDefault(json.RawMessage(`{"type":"Feature","geometry":{"type":"Polygon"}}`), 3857); got != 3857 {
+		t.Errorf("expected the collection SRID to apply, got %d", got)
+	}
+	if _, err := DetectSRID(json.RawMessage(`{"type":"Polygon","crs":{"type":"link","properties":{"href":"http://example.com/crs"}}}`)); err == nil {
+		t.Error("expected linked crs members to be rejected")
+	}
+}
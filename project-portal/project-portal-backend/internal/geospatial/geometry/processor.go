@@ -24,3 +24,23 @@ func ExtractGeometry(raw json.RawMessage) json.RawMessage {
 	}
 	return raw
 }
+
+// ToMultiPolygon wraps a Polygon geometry as a single-member MultiPolygon so
+// every project boundary is stored with the same type. Other geometries are
+// returned unchanged.
+func ToMultiPolygon(raw json.RawMessage) (json.RawMessage, error) {
+	var obj struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	if obj.Type != "Polygon" {
+		return raw, nil
+	}
+	return json.Marshal(map[string]any{
+		"type":        "MultiPolygon",
+		"coordinates": []json.RawMessage{obj.Coordinates},
+	})
+}
@@ -124,3 +124,19 @@ func validateCoordinatePair(p []float64) error {
 	}
 	return nil
 }
+
+// ValidateBoundary validates a project boundary. Boundaries must be polygonal;
+// interior rings (holes) are allowed and excluded from area calculations.
+func ValidateBoundary(raw json.RawMessage) error {
+	if err := ValidateGeoJSON(raw); err != nil {
+		return err
+	}
+	var obj basicGeoJSON
+	if err := json.Unmarshal(ExtractGeometry(raw), &obj); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	if obj.Type != "Polygon" && obj.Type != "MultiPolygon" {
+		return fmt.Errorf("project boundary must be a Polygon or MultiPolygon, got %s", obj.Type)
+	}
+	return nil
+}
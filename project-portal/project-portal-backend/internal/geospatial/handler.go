@@ -1,35 +1,123 @@
 package geospatial
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/apierror"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/kml"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/validation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// DefaultMaxImportBytes caps geometry import bodies when no limit is
+// configured.
+const DefaultMaxImportBytes = 100 << 20
+
 type Handler struct {
-	service Service
+	service        Service
+	maxImportBytes int64
+	uploads        *UploadStore
 }
 
 func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+	return &Handler{service: service, maxImportBytes: DefaultMaxImportBytes}
+}
+
+// NewHandlerWithImportLimit returns a handler that refuses geometry import
+// bodies over maxBytes with 413. maxBytes <= 0 uses DefaultMaxImportBytes.
+func NewHandlerWithImportLimit(service Service, maxBytes int64) *Handler {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxImportBytes
+	}
+	return &Handler{service: service, maxImportBytes: maxBytes}
+}
+
+// NewHandlerWithUploads is NewHandlerWithImportLimit that also serves
+// resumable geometry uploads kept in uploads. A nil store leaves them off.
+func NewHandlerWithUploads(service Service, maxBytes int64, uploads *UploadStore) *Handler {
+	h := NewHandlerWithImportLimit(service, maxBytes)
+	h.uploads = uploads
+	return h
 }
 
+// RegisterRoutes mounts the geospatial API under rg. Routes serving project
+// data require a signed-in caller, or an API key with a geospatial scope, and
+// see only the projects of the caller's project.Scope: list and analysis
+// queries are filtered by it, and /projects/:id routes answer 404 for
+// projects outside it. Base maps, reference layers and administrative
+// boundaries stay public.
 func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
 	g := rg.Group("/geospatial")
 	{
-		g.POST("/projects/:id/geometry", h.UploadProjectGeometry)
-		g.GET("/projects/:id/geometry", h.GetProjectGeometry)
-		g.GET("/projects/:id/boundary", h.GetProjectBoundary)
-		g.GET("/projects/nearby", h.GetNearbyProjects)
-		g.GET("/projects/within", h.GetProjectsWithin)
-		g.POST("/analysis/intersect", h.AnalyzeIntersection)
 		g.GET("/maps/static", h.GetStaticMap)
 		g.GET("/maps/tile/:z/:x/:y", h.GetMapTile)
-		g.POST("/geofences", h.CreateGeofence)
-		g.GET("/geofences/project/:id", h.CheckProjectGeofences)
+		g.GET("/reference-layers", h.ListReferenceLayers)
 		g.GET("/boundaries/:level", h.GetBoundaries)
+		g.PUT("/reference-layers/:layer", auth.AuthMiddleware(), auth.RequirePermission(auth.PermProjectsManageAll), h.PutReferenceLayer)
+		g.GET("/admin/index-check", auth.AuthMiddleware(), auth.RequirePermission(auth.PermSystemDiagnostics), h.CheckSpatialIndex)
+		g.POST("/admin/areas/recompute", auth.AuthMiddleware(), auth.RequirePermission(auth.PermProjectsManageAll), h.RecomputeAreas)
+	}
+
+	s := g.Group("", auth.AuthMiddlewareOrAPIKey(), auth.RequireScope(auth.ScopeGeospatialRead, auth.ScopeGeospatialWrite), project.OwnerScope())
+	{
+		s.POST("/projects/geometry/import", h.ImportProjectGeometries)
+		s.POST("/geometry/validate", h.ValidateGeometries)
+		s.GET("/projects/nearby", h.GetNearbyProjects)
+		s.GET("/projects/within", h.GetProjectsWithin)
+		s.GET("/projects/at", h.GetProjectsAtPoint)
+		s.GET("/projects/clusters", h.GetProjectClusters)
+		s.GET("/projects/export", h.ExportProjects)
+		s.GET("/projects/extent", h.GetProjectExtent)
+		s.GET("/tiles/projects/:z/:x/:y", h.GetProjectTile)
+		s.POST("/analysis/intersect", h.AnalyzeIntersection)
+		s.POST("/analysis/overlaps", h.PreviewOverlaps)
+		s.POST("/analysis/clip", h.ClipToAOI)
+		s.POST("/projects/merge", auth.RequirePermission(auth.PermProjectsManageAll), h.MergeProjects)
+		s.POST("/geofences", h.CreateGeofence)
+		s.GET("/geofences/project/:id", h.requireProject, h.CheckProjectGeofences)
+	}
+
+	p := s.Group("/projects/:id", h.requireProject)
+	{
+		p.POST("/geometry", h.UploadProjectGeometry)
+		p.GET("/geometry", h.GetProjectGeometry)
+		p.GET("/geometry.kml", h.GetProjectGeometryKML)
+		p.GET("/boundary", h.GetProjectBoundary)
+		p.GET("/perimeter", h.GetProjectPerimeter)
+		p.GET("/boundary-distance", h.GetBoundaryDistance)
+		p.GET("/bounding-circle", h.GetBoundingCircle)
+		p.GET("/bounding-rectangle", h.GetBoundingRectangle)
+		p.GET("/carbon-estimate", h.GetCarbonEstimate)
+		p.GET("/geometry/versions", h.ListGeometryVersions)
+		p.GET("/geometry/parts", h.GetGeometryParts)
+		p.GET("/geometry/diff", h.DiffGeometryVersions)
+		p.GET("/geometry/simplify", h.PreviewSimplification)
+		p.POST("/geometry/simplify", h.ApplySimplification)
+		p.POST("/sub-areas", h.CreateSubArea)
+		p.GET("/sub-areas", h.ListSubAreas)
+		p.GET("/reference-layers/:layer/intersection", h.IntersectReferenceLayer)
+	}
+
+	if h.uploads != nil {
+		u := s.Group("/uploads")
+		u.POST("", h.CreateUpload)
+		u.GET("/:id", h.GetUpload)
+		u.PATCH("/:id", h.AppendUpload)
+		u.POST("/:id/finalize", h.FinalizeUpload)
+		u.DELETE("/:id", h.DeleteUpload)
 	}
 }
 
@@ -41,18 +129,309 @@ func (h *Handler) UploadProjectGeometry(c *gin.Context) {
 	}
 
 	var req UploadGeometryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !validation.BindJSON(c, &req) {
 		return
 	}
 
 	geometry, err := h.service.UploadProjectGeometry(c.Request.Context(), projectID, req)
+	if respondGeometryWrite(c, err) {
+		return
+	}
+	c.JSON(http.StatusCreated, geometry)
+}
+
+// respondGeometryWrite answers a failed boundary write, reporting whether it
+// did. Policy refusals carry the details a client needs to fix the boundary.
+func respondGeometryWrite(c *gin.Context, err error) bool {
+	if respondTransient(c, err) {
+		return true
+	}
+	if errors.Is(err, ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return true
+	}
+	var overlapErr *OverlapError
+	if errors.As(err, &overlapErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "overlaps": overlapErr.Overlaps})
+		return true
+	}
+	var areaErr *AreaOutOfRangeError
+	if errors.As(err, &areaErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":         err.Error(),
+			"area_hectares": areaErr.AreaHectares,
+			"min_hectares":  areaErr.MinHectares,
+			"max_hectares":  areaErr.MaxHectares,
+		})
+		return true
+	}
+	var vertexErr *VertexLimitError
+	if errors.As(err, &vertexErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":        err.Error(),
+			"vertices":     vertexErr.Vertices,
+			"max_vertices": vertexErr.MaxVertices,
+			"hint":         "simplify the boundary to at most max_vertices vertices and upload again",
+		})
+		return true
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
+// ImportProjectGeometries accepts a FeatureCollection and stores one geometry
+// per feature. ?mode=strict (default) is all-or-nothing; ?mode=best_effort
+// stores every valid feature and reports the rest. ?snapTolerance= snaps each
+// feature to a grid of that size (degrees) before validation.
+//
+// A KML document (Content-Type application/vnd.google-earth.kml+xml) is
+// imported the same way, one feature per polygon Placemark; ?source_file=
+// then names the uploaded file. A bare FeatureCollection sent as
+// application/geo+json is streamed, one feature at a time, so large uploads
+// are not held in memory; ?source_file= applies there too.
+//
+// Bodies over the import limit are refused with 413.
+func (h *Handler) ImportProjectGeometries(c *gin.Context) {
+	if c.Request.ContentLength > h.maxImportBytes {
+		respondTooLarge(c, h.maxImportBytes)
 		return
 	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxImportBytes)
 
-	c.JSON(http.StatusCreated, geometry)
+	var req BatchImportRequest
+	isKML := c.ContentType() == kml.ContentType
+	isStream := c.ContentType() == mediaTypeGeoJSON
+	if isKML || isStream {
+		req.SourceFile = c.Query("source_file")
+	} else if !validation.BindJSON(c, &req) {
+		return
+	}
+	if !bindSnapTolerance(c, &req) {
+		return
+	}
+
+	var result *BatchImportResult
+	var err error
+	switch {
+	case isKML:
+		result, err = h.service.ImportKML(c.Request.Context(), c.Request.Body, req, c.Query("mode"))
+	case isStream:
+		result, err = h.service.ImportFeatureStream(c.Request.Context(), c.Request.Body, req, c.Query("mode"))
+	default:
+		result, err = h.service.ImportFeatureCollection(c.Request.Context(), req, c.Query("mode"))
+	}
+	respondImport(c, result, err)
+}
+
+// bindSnapTolerance sets req.SnapTolerance from ?snapTolerance=, answering
+// 400 and returning false when it is not a positive number.
+func bindSnapTolerance(c *gin.Context, req *BatchImportRequest) bool {
+	raw := c.Query("snapTolerance")
+	if raw == "" {
+		return true
+	}
+	tolerance, err := strconv.ParseFloat(raw, 64)
+	if err != nil || tolerance <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "snapTolerance must be a positive number"})
+		return false
+	}
+	req.SnapTolerance = &tolerance
+	return true
+}
+
+// respondImport answers a batch import: 201 when every feature was stored,
+// 207 when some failed, 422 when a strict import was rejected.
+func respondImport(c *gin.Context, result *BatchImportResult, err error) {
+	if respondTransient(c, err) {
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondTooLarge(c, tooLarge.Limit)
+		return
+	}
+	if errors.Is(err, ErrBatchRejected) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusCreated
+	if result.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, result)
+}
+
+func respondTooLarge(c *gin.Context, limit int64) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds the %d byte import limit", limit)})
+}
+
+// CreateUpload starts a resumable geometry upload for a file too large to send
+// in one request. The body names the file's content type (GeoJSON or KML)
+// and, optionally, its size; chunks are then sent with PATCH and the upload
+// imported with POST .../finalize. Only the user who started an upload can
+// see or continue it, and each user may hold a few unfinished ones at a time;
+// past that the request is refused with 429.
+func (h *Handler) CreateUpload(c *gin.Context) {
+	var req CreateUploadRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+	switch req.ContentType {
+	case mediaTypeGeoJSON, "application/json", kml.ContentType:
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "content_type must be GeoJSON or KML"})
+		return
+	}
+	if req.Size > h.maxImportBytes {
+		respondTooLarge(c, h.maxImportBytes)
+		return
+	}
+
+	upload, err := h.uploads.Create(c.GetString("user_id"), req.ContentType, req.Filename, req.Size)
+	if errors.Is(err, ErrTooManyUploads) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to start geometry upload", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start upload"})
+		return
+	}
+	c.Header("Location", c.Request.URL.Path+"/"+upload.ID)
+	c.Header("Upload-Offset", "0")
+	c.JSON(http.StatusCreated, upload)
+}
+
+// GetUpload reports how much of an upload has arrived, so an interrupted
+// client knows where to resume.
+func (h *Handler) GetUpload(c *gin.Context) {
+	upload, err := h.uploads.Get(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		respondUploadError(c, upload, err)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.JSON(http.StatusOK, upload)
+}
+
+// AppendUpload appends the body to an upload. The Upload-Offset header must
+// name the upload's current end; a mismatch is refused with 409 and the
+// offset to resume from. Bytes received before the connection breaks are
+// kept.
+func (h *Handler) AppendUpload(c *gin.Context) {
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset header must be a non-negative integer"})
+		return
+	}
+
+	upload, err := h.uploads.Append(c.GetString("user_id"), c.Param("id"), offset, c.Request.Body, h.maxImportBytes)
+	if err != nil {
+		respondUploadError(c, upload, err)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+// FinalizeUpload imports a completed upload like ImportProjectGeometries,
+// taking ?mode= and ?snapTolerance= the same way, and removes it. An upload
+// whose import failed on a busy or unavailable database is kept so it can be
+// finalized again.
+func (h *Handler) FinalizeUpload(c *gin.Context) {
+	var req BatchImportRequest
+	if !bindSnapTolerance(c, &req) {
+		return
+	}
+	owner, id := c.GetString("user_id"), c.Param("id")
+	r, upload, err := h.uploads.Open(owner, id)
+	if err != nil {
+		respondUploadError(c, upload, err)
+		return
+	}
+	if !upload.Complete() {
+		r.Close()
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("upload has %d of %d bytes", upload.Offset, upload.Size), "offset": upload.Offset})
+		return
+	}
+
+	req.SourceFile = upload.Filename
+	var result *BatchImportResult
+	if upload.ContentType == kml.ContentType {
+		result, err = h.service.ImportKML(c.Request.Context(), r, req, c.Query("mode"))
+	} else {
+		result, err = h.service.ImportFeatureStream(c.Request.Context(), r, req, c.Query("mode"))
+	}
+	r.Close()
+	var transient *TransientError
+	if !errors.As(err, &transient) && !errors.Is(err, ErrDBBusy) {
+		if rmErr := h.uploads.Remove(owner, id); rmErr != nil {
+			logging.FromContext(c.Request.Context()).Warn("failed to remove finalized upload", "upload_id", id, "error", rmErr)
+		}
+	}
+	respondImport(c, result, err)
+}
+
+// DeleteUpload abandons an upload.
+func (h *Handler) DeleteUpload(c *gin.Context) {
+	if err := h.uploads.Remove(c.GetString("user_id"), c.Param("id")); err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to remove upload", "upload_id", c.Param("id"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove upload"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondUploadError maps UploadStore errors to responses. When the store
+// reports where the upload now ends, the offset is included so the client can
+// resume from it.
+func respondUploadError(c *gin.Context, upload *Upload, err error) {
+	if upload != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	}
+	var offsetErr *UploadOffsetError
+	var tooLarge *UploadTooLargeError
+	switch {
+	case errors.Is(err, ErrUploadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUploadBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &offsetErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "offset": offsetErr.Offset})
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case upload != nil:
+		// The chunk broke off; what arrived is stored.
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chunk interrupted: " + err.Error(), "offset": upload.Offset})
+	default:
+		logging.FromContext(c.Request.Context()).Error("geometry upload failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+	}
+}
+
+// ValidateGeometries reports per-feature validity, area and overlaps so a
+// client can preflight an upload. Nothing is stored.
+func (h *Handler) ValidateGeometries(c *gin.Context) {
+	var req ValidateGeometryRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	report, err := h.service.ValidateGeometries(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
 }
 
 func (h *Handler) GetProjectGeometry(c *gin.Context) {
@@ -62,12 +441,26 @@ func (h *Handler) GetProjectGeometry(c *gin.Context) {
 		return
 	}
 
+	c.Writer.Header().Add("Vary", "Accept")
+	repr, ok := negotiateGeometry(c.GetHeader("Accept"))
+	if !ok {
+		respondNotAcceptable(c)
+		return
+	}
+	if repr.format != "" {
+		h.respondBareGeometry(c, projectID, repr)
+		return
+	}
+
 	geometry, err := h.service.GetProjectGeometry(c.Request.Context(), projectID)
+	if respondTransient(c, err) {
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+		apierror.Respond(c, err, "project geometry")
 		return
 	}
-	c.JSON(http.StatusOK, geometry)
+	respondCacheable(c, geometryETag(geometry.GeometryGeoJSON, geometry.UpdatedAt, "detail"), geometry)
 }
 
 func (h *Handler) GetProjectBoundary(c *gin.Context) {
@@ -77,13 +470,333 @@ func (h *Handler) GetProjectBoundary(c *gin.Context) {
 		return
 	}
 
+	c.Writer.Header().Add("Vary", "Accept")
+	repr, ok := negotiateGeometry(c.GetHeader("Accept"))
+	if !ok {
+		respondNotAcceptable(c)
+		return
+	}
+	if repr.format != "" {
+		h.respondBareGeometry(c, projectID, repr)
+		return
+	}
+
 	format := c.DefaultQuery("format", BoundaryFormatGeoJSON)
 	boundary, err := h.service.GetProjectBoundary(c.Request.Context(), projectID, format)
+	if respondTransient(c, err) {
+		return
+	}
+	if errors.Is(err, ErrUnsupportedBoundaryFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, err, "project geometry")
+		return
+	}
+	respondCacheable(c, geometryETag(boundary.body(), boundary.UpdatedAt, "boundary:"+boundary.Format), boundary)
+}
+
+// GetProjectGeometryKML downloads a project's boundary as a KML document for
+// Google Earth. The file carries the project id, so it can be edited and
+// posted back to the import endpoint.
+func (h *Handler) GetProjectGeometryKML(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	boundary, err := h.service.GetProjectBoundary(c.Request.Context(), projectID, BoundaryFormatKML)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, err, "project geometry")
+		return
+	}
+	body, err := projectKML(projectID, boundary.KML)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode kml"})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="project-`+projectID.String()+`.kml"`)
+	respondCacheableData(c, geometryETag(body, boundary.UpdatedAt, "kml"), kml.ContentType, body)
+}
+
+// respondBareGeometry sends a project's boundary alone, serialized as the
+// negotiated GeoJSON, WKT or WKB, instead of inside a JSON envelope.
+func (h *Handler) respondBareGeometry(c *gin.Context, projectID uuid.UUID, repr geometryRepresentation) {
+	boundary, err := h.service.GetProjectBoundary(c.Request.Context(), projectID, repr.format)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, err, "project geometry")
+		return
+	}
+	body := boundary.body()
+	respondCacheableData(c, geometryETag(body, boundary.UpdatedAt, "bare:"+boundary.Format), repr.contentType, body)
+}
+
+func respondNotAcceptable(c *gin.Context) {
+	c.JSON(http.StatusNotAcceptable, gin.H{
+		"error":     "unsupported Accept header",
+		"supported": []string{"application/json", mediaTypeGeoJSON, mediaTypeWKT, mediaTypeWKB},
+	})
+}
+
+// GetProjectPerimeter returns the boundary length in meters. Pass
+// exterior_only=true to leave interior rings out of the total.
+func (h *Handler) GetProjectPerimeter(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	perimeter, err := h.service.GetProjectPerimeter(c.Request.Context(), projectID, c.Query("exterior_only") == "true")
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, err, "project geometry")
+		return
+	}
+	c.JSON(http.StatusOK, perimeter)
+}
+
+// GetBoundaryDistance answers how far ?lon=&lat= is from the nearest edge of
+// the project boundary, and whether it is inside, for setback checks.
+func (h *Handler) GetBoundaryDistance(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	var q PointQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	distance, err := h.service.DistanceToBoundary(c.Request.Context(), projectID, q)
+	if respondTransient(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, distance)
+	}
+}
+
+// GetBoundingCircle returns the project's minimum bounding circle: centre,
+// radius in meters and the circle as GeoJSON.
+func (h *Handler) GetBoundingCircle(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	circle, err := h.service.BoundingCircle(c.Request.Context(), projectID)
+	respondBoundingShape(c, circle, err)
+}
+
+// GetBoundingRectangle returns the project's oriented minimum bounding
+// rectangle as GeoJSON with its area and side lengths.
+func (h *Handler) GetBoundingRectangle(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	rectangle, err := h.service.BoundingRectangle(c.Request.Context(), projectID)
+	respondBoundingShape(c, rectangle, err)
+}
+
+// GetGeometryParts returns each polygon of the project's boundary as its own
+// GeoJSON feature with its area.
+func (h *Handler) GetGeometryParts(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	parts, err := h.service.ExplodeGeometry(c.Request.Context(), projectID)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, err, "project geometry")
+		return
+	}
+	c.JSON(http.StatusOK, parts)
+}
+
+func respondBoundingShape(c *gin.Context, shape any, err error) {
+	if respondTransient(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+	case err != nil:
+		logging.FromContext(c.Request.Context()).Error("bounding shape failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to measure bounding shape"})
+	default:
+		c.JSON(http.StatusOK, shape)
+	}
+}
+
+// GetCarbonEstimate returns the project's boundary area times a sequestration
+// rate in tCO2e per hectare: ?rate= when given, otherwise the configured rate
+// of ?land_cover= or of the project's tags and type. No rate is a 422.
+func (h *Handler) GetCarbonEstimate(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	q := CarbonEstimateQuery{LandCover: strings.TrimSpace(c.Query("land_cover"))}
+	if raw := c.Query("rate"); raw != "" {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be a non-negative number of tCO2e per hectare"})
+			return
+		}
+		q.Rate = &rate
+	}
+
+	estimate, err := h.service.EstimateCarbon(c.Request.Context(), projectID, q)
+	if respondTransient(c, err) {
+		return
+	}
+	var missing *MissingCarbonFactorError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+	case errors.As(err, &missing):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":       err.Error(),
+			"land_covers": missing.LandCovers,
+			"hint":        "pass rate= in tCO2e per hectare or land_cover= with a configured rate",
+		})
+	case err != nil:
+		logging.FromContext(c.Request.Context()).Error("carbon estimate failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to estimate carbon"})
+	default:
+		c.JSON(http.StatusOK, estimate)
+	}
+}
+
+func (h *Handler) ListGeometryVersions(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	versions, err := h.service.ListGeometryVersions(c.Request.Context(), projectID)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions, "count": len(versions)})
+}
+
+// DiffGeometryVersions returns the areas added and removed between
+// ?from= and ?to= boundary versions.
+func (h *Handler) DiffGeometryVersions(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be version numbers"})
+		return
+	}
+
+	diff, err := h.service.DiffGeometryVersions(c.Request.Context(), projectID, from, to)
+	if respondTransient(c, err) {
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "geometry version not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, boundary)
+	c.JSON(http.StatusOK, diff)
+}
+
+// PreviewSimplification shows what simplifying the stored boundary at
+// ?tolerance= (degrees) would do, with vertex counts and the area change.
+// Nothing is stored.
+func (h *Handler) PreviewSimplification(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	tolerance, err := strconv.ParseFloat(c.Query("tolerance"), 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tolerance must be a number of degrees"})
+		return
+	}
+
+	preview, err := h.service.PreviewSimplification(c.Request.Context(), projectID, tolerance)
+	if respondTransient(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, preview)
+	}
+}
+
+// ApplySimplification stores the simplification a preview showed as a new
+// boundary version. Sending the previewed version guards against storing a
+// simplification of a boundary that has since changed (409).
+func (h *Handler) ApplySimplification(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+	var req SimplifyRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	geometry, err := h.service.ApplySimplification(c.Request.Context(), projectID, req)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+		return
+	case errors.Is(err, ErrGeometryChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if respondGeometryWrite(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, geometry)
 }
 
 func (h *Handler) GetNearbyProjects(c *gin.Context) {
@@ -94,6 +807,9 @@ func (h *Handler) GetNearbyProjects(c *gin.Context) {
 	}
 
 	data, err := h.service.FindNearby(c.Request.Context(), q)
+	if respondTransient(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -109,6 +825,9 @@ func (h *Handler) GetProjectsWithin(c *gin.Context) {
 	}
 
 	data, err := h.service.FindWithin(c.Request.Context(), q)
+	if respondTransient(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -116,14 +835,126 @@ func (h *Handler) GetProjectsWithin(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"projects": data, "count": len(data)})
 }
 
+// GetProjectsAtPoint lists the projects whose boundary contains ?lon=&lat=.
+func (h *Handler) GetProjectsAtPoint(c *gin.Context) {
+	var q PointQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.service.ProjectsAtPoint(c.Request.Context(), q)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"projects": data, "count": len(data)})
+}
+
+// GetProjectExtent suggests the initial map view for the projects matching the
+// tag and owner filters.
+func (h *Handler) GetProjectExtent(c *gin.Context) {
+	var q ExtentQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.service.ProjectExtent(c.Request.Context(), q)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *Handler) GetProjectClusters(c *gin.Context) {
+	var q ClusterQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.service.ClusterProjects(c.Request.Context(), q)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// ExportProjects streams a zip of matching project boundaries. Headers are
+// only sent with the first archive byte, so an invalid query still gets a JSON
+// 400; a failure after that can only be logged and the archive is cut short.
+func (h *Handler) ExportProjects(c *gin.Context) {
+	var q ExportQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := &attachmentWriter{c: c, filename: exportBase + ".zip"}
+	err := h.service.ExportProjects(c.Request.Context(), q, out)
+	if err == nil {
+		return
+	}
+	if out.started {
+		logging.FromContext(c.Request.Context()).Error("project export failed mid-stream", "error", err)
+		return
+	}
+	if respondTransient(c, err) {
+		return
+	}
+	if errors.Is(err, ErrInvalidExport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// attachmentWriter sends the download headers on its first write.
+type attachmentWriter struct {
+	c        *gin.Context
+	filename string
+	started  bool
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "application/zip")
+		w.c.Header("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
 func (h *Handler) AnalyzeIntersection(c *gin.Context) {
 	var req IntersectRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !validation.BindJSON(c, &req) {
 		return
 	}
+	if c.Query("include_deleted") == "true" {
+		if !auth.HasPermission(c.GetString("role"), auth.PermProjectsViewDeleted) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "include_deleted requires the " + auth.PermProjectsViewDeleted + " permission"})
+			return
+		}
+		req.IncludeDeleted = true
+	}
 
 	results, err := h.service.Intersect(c.Request.Context(), req)
+	if respondTransient(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -132,6 +963,82 @@ func (h *Handler) AnalyzeIntersection(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"results": results, "count": len(results)})
 }
 
+// ClipToAOI returns each project boundary overlapping the posted area of
+// interest, clipped to it, with the clipped and full areas. Nothing is saved.
+func (h *Handler) ClipToAOI(c *gin.Context) {
+	var req ClipRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	projects, err := h.service.ClipToAOI(c.Request.Context(), req)
+	if respondTransient(c, err) {
+		return
+	}
+	var aoiErr *InvalidAOIError
+	if errors.As(err, &aoiErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": aoiErr.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("clip to area of interest failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clip project boundaries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"projects": projects, "count": len(projects)})
+}
+
+// PreviewOverlaps is the preflight for a boundary upload: it reports the
+// overlap with each existing project as hectares and as a percentage of both
+// boundaries, and stores nothing.
+func (h *Handler) PreviewOverlaps(c *gin.Context) {
+	var req OverlapPreviewRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	overlaps, err := h.service.PreviewOverlaps(c.Request.Context(), req)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"overlaps": overlaps, "count": len(overlaps)})
+}
+
+// MergeProjects unions the boundaries of several projects, such as adjacent
+// parcels, and optionally stores the union as a new project (201).
+func (h *Handler) MergeProjects(c *gin.Context) {
+	var req MergeProjectsRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.service.MergeProjects(c.Request.Context(), req)
+	if respondTransient(c, err) {
+		return
+	}
+	var missing *MissingBoundariesError
+	switch {
+	case errors.As(err, &missing):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "project_ids": missing.ProjectIDs})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+	case errors.Is(err, ErrNotContiguous):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case result.ProjectID != nil:
+		c.JSON(http.StatusCreated, result)
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
+
 func (h *Handler) GetStaticMap(c *gin.Context) {
 	width, _ := strconv.Atoi(c.DefaultQuery("width", "800"))
 	height, _ := strconv.Atoi(c.DefaultQuery("height", "600"))
@@ -188,10 +1095,37 @@ func (h *Handler) GetMapTile(c *gin.Context) {
 	c.Data(http.StatusOK, contentType, data)
 }
 
+// GetProjectTile serves the project boundaries in one XYZ tile as a Mapbox
+// Vector Tile, at /tiles/projects/:z/:x/:y.mvt.
+func (h *Handler) GetProjectTile(c *gin.Context) {
+	tile, err := ParseTileCoord(c.Param("z"), c.Param("x"), c.Param("y"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := h.service.ProjectTile(c.Request.Context(), tile)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("project tile failed", "z", tile.Z, "x", tile.X, "y", tile.Y, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render tile"})
+		return
+	}
+	etag := geometryETag(data, time.Time{}, "mvt")
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", tileCacheControl)
+	if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, MVTContentType, data)
+}
+
 func (h *Handler) CreateGeofence(c *gin.Context) {
 	var req CreateGeofenceRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !validation.BindJSON(c, &req) {
 		return
 	}
 
@@ -211,6 +1145,9 @@ func (h *Handler) CheckProjectGeofences(c *gin.Context) {
 	}
 
 	results, err := h.service.CheckProjectGeofences(c.Request.Context(), projectID)
+	if respondTransient(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -218,6 +1155,116 @@ func (h *Handler) CheckProjectGeofences(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"results": results, "count": len(results)})
 }
 
+// CreateSubArea adds a stand or parcel to a project. It must lie within the
+// project's boundary; one that does not gets 422 with the area outside.
+func (h *Handler) CreateSubArea(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	var req CreateSubAreaRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	subArea, err := h.service.CreateSubArea(c.Request.Context(), projectID, req)
+	if respondTransient(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+	case errors.Is(err, ErrSubAreaOutsideParent):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, subArea)
+	}
+}
+
+// ListSubAreas returns the sub-areas within a project's boundary and the sum
+// of their areas.
+func (h *Handler) ListSubAreas(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	list, err := h.service.ListSubAreas(c.Request.Context(), projectID)
+	if respondTransient(c, err) {
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// PutReferenceLayer creates or replaces a reference layer, such as protected
+// areas, from a FeatureCollection of polygons.
+func (h *Handler) PutReferenceLayer(c *gin.Context) {
+	var req PutReferenceLayerRequest
+	if !validation.BindJSON(c, &req) {
+		return
+	}
+
+	layer, err := h.service.PutReferenceLayer(c.Request.Context(), c.Param("layer"), req)
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, layer)
+}
+
+func (h *Handler) ListReferenceLayers(c *gin.Context) {
+	layers, err := h.service.ListReferenceLayers(c.Request.Context())
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"layers": layers, "count": len(layers)})
+}
+
+// IntersectReferenceLayer reports the area and share of a project's boundary
+// that falls within a reference layer.
+func (h *Handler) IntersectReferenceLayer(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project id"})
+		return
+	}
+
+	result, err := h.service.IntersectReferenceLayer(c.Request.Context(), projectID, c.Param("layer"))
+	if respondTransient(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ErrUnknownReferenceLayer):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s %q", err, c.Param("layer"))})
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project geometry not found"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
+
 func (h *Handler) GetBoundaries(c *gin.Context) {
 	level, err := strconv.Atoi(c.Param("level"))
 	if err != nil {
@@ -227,9 +1274,61 @@ func (h *Handler) GetBoundaries(c *gin.Context) {
 
 	countryCode := c.Query("country_code")
 	items, err := h.service.GetAdministrativeBoundaries(c.Request.Context(), level, countryCode)
+	if respondTransient(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"boundaries": items, "count": len(items)})
 }
+
+// CheckSpatialIndex reports whether the spatial indexes exist and are used.
+// Warnings do not change the status; the endpoint answers 200 either way.
+func (h *Handler) CheckSpatialIndex(c *gin.Context) {
+	report, err := h.service.CheckSpatialIndex(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RecomputeAreas backfills the cached area of every project boundary and
+// reports how many were corrected.
+func (h *Handler) RecomputeAreas(c *gin.Context) {
+	result, err := h.service.RecomputeAreas(c.Request.Context())
+	if respondTransient(c, err) {
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("area recompute failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to recompute areas"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// respondTransient answers 503 with a Retry-After hint when err is a database
+// failure that outlasted the service's retries, or the service had no free
+// database slot.
+func respondTransient(c *gin.Context, err error) bool {
+	var retryAfter time.Duration
+	var transient *TransientError
+	switch {
+	case errors.As(err, &transient):
+		retryAfter = transient.RetryAfter
+	case errors.Is(err, ErrDBBusy):
+		retryAfter = time.Second
+	default:
+		return false
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database is busy, retry shortly"})
+	return true
+}
@@ -0,0 +1,993 @@
+package geospatial
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+	"carbon-scribe/project-portal/project-portal-backend/internal/middleware"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/kml"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// signIn makes requests through router that carry no Authorization header
+// act as a user with role. Call it before registering routes.
+func signIn(t *testing.T, router *gin.Engine, role string) {
+	t.Helper()
+	token, err := auth.GenerateJWT(&auth.User{ID: uuid.NewString(), Email: role + "@example.com", Role: role})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+	})
+}
+
+func TestUploadProjectGeometry_LogsCarryRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	router := gin.New()
+	signIn(t, router, "admin")
+	router.Use(middleware.RequestLogger(slog.New(slog.NewJSONHandler(&logs, nil)), middleware.RequestIDConfig{}))
+	NewHandler(NewService(newFakeRepo())).RegisterRoutes(router.Group("/api/v1"))
+
+	body := `{"geojson":{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/projects/"+uuid.NewString()+"/geometry", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.RequestIDHeader, "req-308")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(middleware.RequestIDHeader); got != "req-308" {
+		t.Errorf("expected request id to be echoed, got %q", got)
+	}
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if strings.Contains(line, `"msg":"project geometry stored"`) {
+			found = true
+			if !strings.Contains(line, `"request_id":"req-308"`) {
+				t.Errorf("service log line is missing the request id: %s", line)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected a service-level log line, got:\n%s", logs.String())
+	}
+}
+
+func TestCreateGeofence_FieldLevelValidationErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(newFakeRepo())).RegisterRoutes(router.Group("/api/v1"))
+
+	body := `{"description":"no name, no shape","geofence_type":"","priority":-2}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/geofences", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Errors []validation.FieldError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	got := map[string]string{}
+	for _, e := range resp.Errors {
+		got[e.Field] = e.Message
+	}
+	want := map[string]string{
+		"name":          "is required",
+		"geojson":       "is required",
+		"geofence_type": "is required",
+		"priority":      "must be at least 0",
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("%s: got %q, want %q", field, got[field], msg)
+		}
+	}
+}
+
+// cachedGeometryRepo serves one stored geometry for the conditional GET tests.
+type cachedGeometryRepo struct {
+	*fakeRepo
+	geometry *ProjectGeometry
+}
+
+func (r *cachedGeometryRepo) GetProjectGeometry(_ context.Context, projectID uuid.UUID) (*ProjectGeometry, error) {
+	g := *r.geometry
+	g.ProjectID = projectID
+	return &g, nil
+}
+
+func (r *cachedGeometryRepo) GetProjectBoundary(_ context.Context, projectID uuid.UUID, format string) (*BoundaryResponse, error) {
+	return &BoundaryResponse{ProjectID: projectID, Format: format, Geometry: r.geometry.GeometryGeoJSON, UpdatedAt: r.geometry.UpdatedAt}, nil
+}
+
+func TestGeometryEndpoints_ConditionalGET(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &cachedGeometryRepo{fakeRepo: newFakeRepo(), geometry: &ProjectGeometry{
+		GeometryGeoJSON: json.RawMessage(`{"type":"MultiPolygon","coordinates":[]}`),
+		UpdatedAt:       time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+	}}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	id := uuid.NewString()
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{
+		"/api/v1/geospatial/projects/" + id + "/geometry",
+		"/api/v1/geospatial/projects/" + id + "/boundary?format=geojson",
+	} {
+		first := get(path, "")
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: expected 200 with an ETag, got %d %q", path, first.Code, etag)
+		}
+		if first.Header().Get("Cache-Control") == "" {
+			t.Errorf("%s: expected a Cache-Control header", path)
+		}
+
+		again := get(path, etag)
+		if again.Code != http.StatusNotModified || again.Body.Len() != 0 {
+			t.Errorf("%s: expected an empty 304 on re-fetch, got %d with %d bytes", path, again.Code, again.Body.Len())
+		}
+		if stale := get(path, `"stale"`); stale.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for a non-matching ETag, got %d", path, stale.Code)
+		}
+	}
+
+	first := get("/api/v1/geospatial/projects/"+id+"/geometry", "")
+	repo.geometry.UpdatedAt = repo.geometry.UpdatedAt.Add(time.Minute)
+	changed := get("/api/v1/geospatial/projects/"+id+"/geometry", first.Header().Get("ETag"))
+	if changed.Code != http.StatusOK || changed.Header().Get("ETag") == first.Header().Get("ETag") {
+		t.Errorf("expected a fresh 200 and new ETag after an update, got %d", changed.Code)
+	}
+}
+
+// indexRepo reports a missing expression index and a seq-scan plan.
+type indexRepo struct {
+	*fakeRepo
+}
+
+func (r *indexRepo) SpatialIndexExists(_ context.Context, index string) (bool, error) {
+	return index == geographyIndex, nil
+}
+
+func (r *indexRepo) ExplainWithinBBox(context.Context) (json.RawMessage, error) {
+	return json.RawMessage(`[{"Plan":{"Node Type":"Limit","Plans":[{"Node Type":"Seq Scan","Relation Name":"project_geometries"}]}}]`), nil
+}
+
+func TestCheckSpatialIndex_AdminOnlyAndWarnsOnSeqScan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(NewService(&indexRepo{fakeRepo: newFakeRepo()})).RegisterRoutes(router.Group("/api/v1"))
+
+	get := func(role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/admin/index-check", nil)
+		if role != "" {
+			token, err := auth.GenerateJWT(&auth.User{ID: "u-" + role, Email: role + "@example.com", Role: role})
+			if err != nil {
+				t.Fatalf("GenerateJWT: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get(""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", w.Code)
+	}
+	if w := get("user"); w.Code != http.StatusForbidden {
+		t.Errorf("user: expected 403, got %d", w.Code)
+	}
+
+	w := get("admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var report IndexCheckReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !report.SequentialScan || len(report.Warnings) != 2 {
+		t.Errorf("expected a seq scan and two warnings, got %+v", report)
+	}
+	if !report.Indexes[geographyIndex] || report.Indexes[geometryIndex] {
+		t.Errorf("unexpected index presence: %v", report.Indexes)
+	}
+}
+
+// diffRepo knows versions 1 and 2 of every project.
+type diffRepo struct {
+	*fakeRepo
+}
+
+func (r *diffRepo) DiffGeometryVersions(_ context.Context, projectID uuid.UUID, from, to int) (*GeometryDiff, error) {
+	if from > 2 || to > 2 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &GeometryDiff{ProjectID: projectID, FromVersion: from, ToVersion: to, AddedHectares: 3, RemovedHectares: 1, NetChangeHectares: 2}, nil
+}
+
+func TestDiffGeometryVersions_Params(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(&diffRepo{fakeRepo: newFakeRepo()})).RegisterRoutes(router.Group("/api/v1"))
+	base := "/api/v1/geospatial/projects/" + uuid.NewString() + "/geometry/diff"
+
+	for query, want := range map[string]int{
+		"?from=1&to=2": http.StatusOK,
+		"?from=1":      http.StatusBadRequest,
+		"?from=2&to=2": http.StatusBadRequest,
+		"?from=0&to=1": http.StatusBadRequest,
+		"?from=1&to=7": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+query, nil))
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", query, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+// exportRepo serves a fixed set of features to ExportGeometries.
+type exportRepo struct {
+	*fakeRepo
+	features []ExportFeature
+	got      ExportQuery
+}
+
+func (r *exportRepo) ExportGeometries(_ context.Context, q ExportQuery, fn func(ExportFeature) error) error {
+	r.got = q
+	for _, f := range r.features {
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	return files
+}
+
+func TestExportProjects(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	first, second := uuid.New(), uuid.New()
+	repo := &exportRepo{fakeRepo: newFakeRepo(), features: []ExportFeature{
+		{
+			ProjectID: first, Name: "Kakamega", Type: "Reforestation", Tags: []string{"kenya", "forest"}, AreaHectares: 12.5,
+			Geometry: json.RawMessage(`{"type":"Polygon","coordinates":[[[34.8,0.2],[34.9,0.2],[34.9,0.3],[34.8,0.3],[34.8,0.2]]]}`),
+		},
+		{
+			ProjectID: second, Name: "Tana Delta", Type: "Blue Carbon", AreaHectares: 3,
+			Geometry: json.RawMessage(`{"type":"MultiPolygon","coordinates":[[[[40.1,-2.5],[40.2,-2.5],[40.2,-2.4],[40.1,-2.5]]],[[[40.3,-2.5],[40.4,-2.5],[40.4,-2.4],[40.3,-2.5]]]]}`),
+		},
+	}}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	owner := uuid.NewString()
+	w := get("/api/v1/geospatial/projects/export?min_lat=-3&min_lon=34&max_lat=1&max_lon=41&tags=kenya&tag_match=all&owner_id=" + owner)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if repo.got.OwnerID != owner || repo.got.TagMatch != "all" || repo.got.MinLon == nil || *repo.got.MinLon != 34 {
+		t.Errorf("filters did not reach the repository: %+v", repo.got)
+	}
+	files := unzip(t, w.Body.Bytes())
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+			Properties struct {
+				ProjectID    uuid.UUID `json:"project_id"`
+				Name         string    `json:"name"`
+				Tags         []string  `json:"tags"`
+				AreaHectares float64   `json:"area_hectares"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(files["projects.geojson"], &fc); err != nil {
+		t.Fatalf("parse projects.geojson: %v\n%s", err, files["projects.geojson"])
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("unexpected collection %+v", fc)
+	}
+	if p := fc.Features[0].Properties; p.ProjectID != first || p.Name != "Kakamega" || len(p.Tags) != 2 || p.AreaHectares != 12.5 {
+		t.Errorf("unexpected properties %+v", p)
+	}
+	if fc.Features[1].Geometry.Type != "MultiPolygon" {
+		t.Errorf("geometry was not passed through: %+v", fc.Features[1].Geometry)
+	}
+
+	w = get("/api/v1/geospatial/projects/export?format=shapefile")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	files = unzip(t, w.Body.Bytes())
+	for _, name := range []string{"projects.shp", "projects.shx", "projects.dbf", "projects.prj", "projects.cpg"} {
+		if len(files[name]) == 0 {
+			t.Fatalf("archive is missing %s", name)
+		}
+	}
+	shp := files["projects.shp"]
+	if int(binary.BigEndian.Uint32(shp[24:]))*2 != len(shp) {
+		t.Error(".shp header length does not match the file")
+	}
+	// Second record: two parts, one per polygon of the MultiPolygon.
+	secondOffset := 100 + 8 + int(binary.BigEndian.Uint32(shp[104:]))*2
+	if parts := binary.LittleEndian.Uint32(shp[secondOffset+8+36:]); parts != 2 {
+		t.Errorf("second record has %d parts, want 2", parts)
+	}
+	dbf := files["projects.dbf"]
+	if n := binary.LittleEndian.Uint32(dbf[4:]); n != 2 {
+		t.Fatalf("dbf has %d records", n)
+	}
+	headerLen := int(binary.LittleEndian.Uint16(dbf[8:]))
+	if id := string(dbf[headerLen+1 : headerLen+37]); id != first.String() {
+		t.Errorf("first dbf row project_id = %q", id)
+	}
+
+	for _, url := range []string{
+		"/api/v1/geospatial/projects/export?format=kml",
+		"/api/v1/geospatial/projects/export?min_lat=1",
+		"/api/v1/geospatial/projects/export?owner_id=nope",
+	} {
+		if w := get(url); w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") == "application/zip" {
+			t.Errorf("%s: expected a JSON 400, got %d %q", url, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+// busyRepo fails every read with a serialization failure.
+type busyRepo struct {
+	*fakeRepo
+}
+
+func (r *busyRepo) GetProjectGeometry(context.Context, uuid.UUID) (*ProjectGeometry, error) {
+	return nil, &pq.Error{Code: "40001"}
+}
+
+func TestGetProjectGeometry_TransientErrorIs503WithRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	signIn(t, router, "admin")
+	svc := NewServiceWithOptions(&busyRepo{newFakeRepo()}, ServiceOptions{OverlapScope: OverlapAllProjects, Retry: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}})
+	NewHandler(svc).RegisterRoutes(router.Group("/api/v1"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+uuid.NewString()+"/geometry", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+}
+
+// pointRepo records the point it was asked about.
+type pointRepo struct {
+	*fakeRepo
+	lon, lat float64
+	calls    int
+}
+
+func (r *pointRepo) ProjectsAtPoint(_ context.Context, lon, lat float64) ([]ProjectAtPoint, error) {
+	r.lon, r.lat, r.calls = lon, lat, r.calls+1
+	return []ProjectAtPoint{{ProjectID: uuid.New(), Name: "Equator forest", AreaHectares: 12}}, nil
+}
+
+func TestGetProjectsAtPoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &pointRepo{fakeRepo: newFakeRepo()}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/at?"+query, nil))
+		return w
+	}
+
+	// Zero is a valid coordinate, not a missing one.
+	w := get("lon=0&lat=0")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the null island point, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Projects []ProjectAtPoint `json:"projects"`
+		Count    int              `json:"count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Count != 1 {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+
+	if w := get("lon=36.8&lat=-1.3"); w.Code != http.StatusOK || repo.lon != 36.8 || repo.lat != -1.3 {
+		t.Fatalf("expected the point to reach the repository, got %d with %v,%v", w.Code, repo.lon, repo.lat)
+	}
+	calls := repo.calls
+	for _, q := range []string{"lon=36.8", "lat=-1.3", "lon=181&lat=0", "lon=0&lat=-91", "lon=abc&lat=0"} {
+		if w := get(q); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+	if repo.calls != calls {
+		t.Error("invalid points must not reach the repository")
+	}
+}
+
+// blockingRepo holds every point lookup until release is closed.
+type blockingRepo struct {
+	*fakeRepo
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRepo) ProjectsAtPoint(context.Context, float64, float64) ([]ProjectAtPoint, error) {
+	r.entered <- struct{}{}
+	<-r.release
+	return []ProjectAtPoint{}, nil
+}
+
+func TestDBConcurrencyLimit_ExcessRequestsGet503(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &blockingRepo{fakeRepo: newFakeRepo(), entered: make(chan struct{}, 8), release: make(chan struct{})}
+	svc := NewServiceWithOptions(repo, ServiceOptions{MaxConcurrentDB: 2, DBAcquireTimeout: 20 * time.Millisecond})
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(svc).RegisterRoutes(router.Group("/api/v1"))
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/at?lon=36.8&lat=-1.3", nil))
+		return w
+	}
+
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- get().Code }()
+	}
+	<-repo.entered
+	<-repo.entered
+
+	w := get()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while the limit is saturated, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+
+	close(repo.release)
+	for i := 0; i < 2; i++ {
+		if code := <-done; code != http.StatusOK {
+			t.Errorf("in-flight request finished with %d", code)
+		}
+	}
+	if w := get(); w.Code != http.StatusOK {
+		t.Fatalf("expected capacity to recover, got %d", w.Code)
+	}
+}
+
+type extentRepo struct {
+	*fakeRepo
+	bounds [4]float64
+	count  int
+	last   ExtentQuery
+}
+
+func (r *extentRepo) ProjectExtent(_ context.Context, q ExtentQuery) ([4]float64, int, error) {
+	r.last = q
+	return r.bounds, r.count, nil
+}
+
+func TestGetProjectExtent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &extentRepo{fakeRepo: newFakeRepo()}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	get := func(query string) (*httptest.ResponseRecorder, MapExtent) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/extent?"+query, nil))
+		var extent MapExtent
+		_ = json.Unmarshal(w.Body.Bytes(), &extent)
+		return w, extent
+	}
+
+	// No projects: the whole world.
+	w, extent := get("")
+	if w.Code != http.StatusOK || !extent.Default || extent.Count != 0 {
+		t.Fatalf("expected the default extent for an empty set, got %d: %s", w.Code, w.Body.String())
+	}
+	if extent.Bounds[0] != -180 || extent.Bounds[2] != 180 || extent.Center != [2]float64{0, 0} {
+		t.Errorf("expected a world extent centered on 0,0, got %+v", extent)
+	}
+
+	// Two projects in Kenya spanning about a degree.
+	repo.bounds, repo.count = [4]float64{36.5, -1.5, 37.5, -0.5}, 2
+	w, extent = get("tags=mangrove&owner_id=" + uuid.NewString())
+	if w.Code != http.StatusOK || extent.Default || extent.Count != 2 {
+		t.Fatalf("expected a computed extent, got %d: %s", w.Code, w.Body.String())
+	}
+	if extent.Bounds != repo.bounds || extent.Center != [2]float64{37, -1} {
+		t.Errorf("expected the repository bounds and their midpoint, got %+v", extent)
+	}
+	if extent.Zoom != 8 {
+		t.Errorf("expected a one-degree extent to fit at zoom 8, got %d", extent.Zoom)
+	}
+	if repo.last.Tags != "mangrove" || repo.last.TagMatch != "any" {
+		t.Errorf("expected the tag filter to reach the repository, got %+v", repo.last)
+	}
+
+	if w, _ := get("owner_id=not-a-uuid"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed owner_id, got %d", w.Code)
+	}
+}
+
+func TestFitZoom(t *testing.T) {
+	cases := []struct {
+		bounds [4]float64
+		want   int
+	}{
+		{[4]float64{-180, -85, 180, 85}, 0},
+		{[4]float64{36.5, -1.5, 37.5, -0.5}, 8},
+		{[4]float64{37, -1, 37, -1}, maxExtentZoom},
+	}
+	for _, tc := range cases {
+		if got := fitZoom(tc.bounds); got != tc.want {
+			t.Errorf("fitZoom(%v) = %d, want %d", tc.bounds, got, tc.want)
+		}
+	}
+}
+
+// formatRepo serves a unit square in whichever format is asked for.
+type formatRepo struct {
+	*fakeRepo
+}
+
+func (r *formatRepo) GetProjectBoundary(_ context.Context, projectID uuid.UUID, format string) (*BoundaryResponse, error) {
+	resp := &BoundaryResponse{ProjectID: projectID, Format: format, UpdatedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	ring := [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	switch format {
+	case BoundaryFormatWKT:
+		resp.WKT = "POLYGON((0 0,1 0,1 1,0 1,0 0))"
+	case BoundaryFormatWKB:
+		var buf bytes.Buffer
+		buf.WriteByte(1) // little endian
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(3))
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(1))
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(len(ring)))
+		for _, pt := range ring {
+			_ = binary.Write(&buf, binary.LittleEndian, pt)
+		}
+		resp.WKB = buf.Bytes()
+	case BoundaryFormatKML:
+		resp.KML = "<Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 1,0 1,1 0,1 0,0</coordinates></LinearRing></outerBoundaryIs></Polygon>"
+	default:
+		resp.Geometry = json.RawMessage(`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`)
+	}
+	return resp, nil
+}
+
+func (r *formatRepo) GetProjectGeometry(_ context.Context, projectID uuid.UUID) (*ProjectGeometry, error) {
+	return &ProjectGeometry{ProjectID: projectID, GeometryGeoJSON: json.RawMessage(`{"type":"Polygon","coordinates":[]}`)}, nil
+}
+
+func TestGeometryEndpoints_AcceptNegotiation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(&formatRepo{fakeRepo: newFakeRepo()})).RegisterRoutes(router.Group("/api/v1"))
+	id := uuid.NewString()
+
+	for _, path := range []string{
+		"/api/v1/geospatial/projects/" + id + "/geometry",
+		"/api/v1/geospatial/projects/" + id + "/boundary",
+	} {
+		get := func(accept string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		w := get(mediaTypeGeoJSON)
+		var geometry struct {
+			Type string `json:"type"`
+		}
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != mediaTypeGeoJSON {
+			t.Fatalf("%s geo+json: got %d %q", path, w.Code, w.Header().Get("Content-Type"))
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &geometry); err != nil || geometry.Type != "Polygon" {
+			t.Errorf("%s geo+json: expected a bare Polygon, got %s", path, w.Body.String())
+		}
+
+		w = get("text/plain")
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+			t.Fatalf("%s wkt: got %d %q", path, w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.HasPrefix(w.Body.String(), "POLYGON((") {
+			t.Errorf("%s wkt: expected WKT, got %q", path, w.Body.String())
+		}
+
+		w = get("application/octet-stream")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != mediaTypeWKB {
+			t.Fatalf("%s wkb: got %d %q", path, w.Code, w.Header().Get("Content-Type"))
+		}
+		body := w.Body.Bytes()
+		if len(body) < 9 || body[0] != 1 || binary.LittleEndian.Uint32(body[1:5]) != 3 || binary.LittleEndian.Uint32(body[5:9]) != 1 {
+			t.Errorf("%s wkb: expected a little-endian one-ring polygon, got % x", path, body)
+		}
+
+		// No Accept header, or a browser-style one, keeps the JSON envelope.
+		for _, accept := range []string{"", "text/html,application/xhtml+xml,*/*;q=0.8"} {
+			w = get(accept)
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+				t.Errorf("%s %q: expected the JSON envelope, got %d %q", path, accept, w.Code, w.Header().Get("Content-Type"))
+			}
+		}
+
+		if w = get("image/png"); w.Code != http.StatusNotAcceptable {
+			t.Errorf("%s: expected 406 for image/png, got %d", path, w.Code)
+		}
+		if !slices.Contains(w.Header().Values("Vary"), "Accept") {
+			t.Errorf("%s: expected Vary to include Accept", path)
+		}
+	}
+}
+
+// missingBoundaryRepo has no boundary for any project.
+type missingBoundaryRepo struct {
+	*fakeRepo
+}
+
+func (r *missingBoundaryRepo) GetProjectBoundary(context.Context, uuid.UUID, string) (*BoundaryResponse, error) {
+	return nil, sql.ErrNoRows
+}
+
+func TestGetProjectBoundary_UnknownProjectIsNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(&missingBoundaryRepo{fakeRepo: newFakeRepo()})).RegisterRoutes(router.Group("/api/v1"))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+uuid.NewString()+path, nil))
+		return w
+	}
+
+	if w := get("/boundary"); w.Code != http.StatusNotFound {
+		t.Errorf("boundary: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("/geometry.kml"); w.Code != http.StatusNotFound {
+		t.Errorf("kml: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("/boundary?format=svg"); w.Code != http.StatusBadRequest {
+		t.Errorf("unsupported format: expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestNegotiateGeometry(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"", "", true},
+		{"application/json", "", true},
+		{"application/geo+json", BoundaryFormatGeoJSON, true},
+		{"text/plain;q=0.5, application/octet-stream", BoundaryFormatWKB, true},
+		{"application/octet-stream;q=0.2, text/plain;q=0.9", BoundaryFormatWKT, true},
+		{"text/*", BoundaryFormatWKT, true},
+		{"application/geo+json;q=0, image/png", "", false},
+		{"image/png", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := negotiateGeometry(tc.accept)
+		if ok != tc.ok || got.format != tc.want {
+			t.Errorf("negotiateGeometry(%q) = %q, %v; want %q, %v", tc.accept, got.format, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestProjectGeometryKML_RoundTripsThroughImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &formatRepo{fakeRepo: newFakeRepo()}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	id := uuid.New()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+id.String()+"/geometry.kml", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != kml.ContentType {
+		t.Errorf("expected %s, got %q", kml.ContentType, ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, id.String()+".kml") {
+		t.Errorf("expected an attachment named after the project, got %q", cd)
+	}
+	if w.Header().Get("ETag") == "" {
+		t.Error("expected an ETag on the KML download")
+	}
+
+	// Google Earth saves edits with altitudes; the import must drop them.
+	doc := strings.ReplaceAll(w.Body.String(), "0,0 1,0 1,1 0,1 0,0", "0,0,12 1,0,12 1,1,12 0,1,12 0,0,12")
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/projects/geometry/import?mode=best_effort&source_file=edited.kml", strings.NewReader(doc))
+	req.Header.Set("Content-Type", kml.ContentType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := repo.stored[id]; !ok {
+		t.Fatalf("expected the geometry of %s to be stored", id)
+	}
+	if repo.last.SourceType != "kml" || repo.last.SourceFile != "edited.kml" {
+		t.Errorf("unexpected source %q/%q", repo.last.SourceType, repo.last.SourceFile)
+	}
+	// Uploads are stored as MultiPolygons.
+	var stored struct {
+		Type        string           `json:"type"`
+		Coordinates [][][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(repo.last.GeoJSON, &stored); err != nil {
+		t.Fatalf("stored geometry is not GeoJSON: %v", err)
+	}
+	want := [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	if stored.Type != "MultiPolygon" || len(stored.Coordinates) != 1 || fmt.Sprint(stored.Coordinates[0][0]) != fmt.Sprint(want) {
+		t.Errorf("expected the exported square back, got %s", repo.last.GeoJSON)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/projects/geometry/import", strings.NewReader("<gpx/>"))
+	req.Header.Set("Content-Type", kml.ContentType)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-KML document, got %d", w.Code)
+	}
+}
+
+func TestImportProjectGeometries_StreamsGeoJSONUnderBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newFakeRepo()
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandlerWithImportLimit(NewService(repo), 2048).RegisterRoutes(router.Group("/api/v1"))
+	id := uuid.New()
+
+	post := func(query, body string, contentLength bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/projects/geometry/import?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/geo+json")
+		if !contentLength {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("mode=best_effort&source_file=plots.geojson", `{"type":"FeatureCollection","features":[`+polygonFeature(id)+`]}`, true)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := repo.stored[id]; !ok || repo.last.SourceFile != "plots.geojson" {
+		t.Fatalf("expected the streamed feature to be stored from plots.geojson, got %+v", repo.last)
+	}
+
+	large := `{"type":"FeatureCollection","features":[` + strings.Repeat(polygonFeature(uuid.New())+",", 20) + polygonFeature(uuid.New()) + `]}`
+	if w := post("", large, true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("declared oversize body: expected 413, got %d", w.Code)
+	}
+	before := len(repo.stored)
+	if w := post("", large, false); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunked oversize body: expected 413, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(repo.stored) != before {
+		t.Errorf("a strict import cut off by the limit must store nothing")
+	}
+}
+
+// vertexRepo counts vertices the way ST_NPoints does, including each ring's
+// closing point. The service hands it MultiPolygons.
+type vertexRepo struct {
+	*fakeRepo
+}
+
+func (r *vertexRepo) CountVertices(_ context.Context, geometry json.RawMessage) (int, error) {
+	var multi struct {
+		Coordinates [][][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(geometry, &multi); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, polygon := range multi.Coordinates {
+		for _, ring := range polygon {
+			n += len(ring)
+		}
+	}
+	return n, nil
+}
+
+func TestGeometryUploads_VertexLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &vertexRepo{fakeRepo: newFakeRepo()}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewServiceWithOptions(repo, ServiceOptions{MaxVertices: 5})).RegisterRoutes(router.Group("/api/v1"))
+
+	square := `{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}`
+	hexagon := `{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.815,-1.305],[36.81,-1.31],[36.8,-1.31],[36.795,-1.305],[36.8,-1.3]]]}`
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	under := uuid.New()
+	if w := post("/api/v1/geospatial/projects/"+under.String()+"/geometry", `{"geojson":`+square+`}`); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for 5 vertices, got %d: %s", w.Code, w.Body.String())
+	}
+
+	over := uuid.New()
+	w := post("/api/v1/geospatial/projects/"+over.String()+"/geometry", `{"geojson":`+hexagon+`}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for 7 vertices, got %d: %s", w.Code, w.Body.String())
+	}
+	var rejected struct {
+		Vertices    int    `json:"vertices"`
+		MaxVertices int    `json:"max_vertices"`
+		Hint        string `json:"hint"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &rejected)
+	if rejected.Vertices != 7 || rejected.MaxVertices != 5 || !strings.Contains(rejected.Hint, "simplify") {
+		t.Errorf("unexpected rejection body: %s", w.Body.String())
+	}
+	if _, stored := repo.stored[over]; stored {
+		t.Error("an over-limit geometry must not be stored")
+	}
+
+	feature := func(id uuid.UUID, geometry string) string {
+		return fmt.Sprintf(`{"type":"Feature","properties":{"project_id":%q},"geometry":%s}`, id, geometry)
+	}
+	batchUnder, batchOver := uuid.New(), uuid.New()
+	w = post("/api/v1/geospatial/projects/geometry/import?mode=best_effort",
+		`{"geojson":{"type":"FeatureCollection","features":[`+feature(batchUnder, square)+`,`+feature(batchOver, hexagon)+`]}}`)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207 for a mixed batch, got %d: %s", w.Code, w.Body.String())
+	}
+	var result BatchImportResult
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Results[0].Status != BatchFeatureImported || result.Results[1].Status != BatchFeatureInvalid ||
+		!strings.Contains(result.Results[1].Error, "7 vertices") {
+		t.Errorf("expected only the over-limit feature to be refused, got %+v", result.Results)
+	}
+}
+
+// distanceRepo knows one project's boundary and answers a fixed distance.
+type distanceRepo struct {
+	*fakeRepo
+	projectID uuid.UUID
+	lon, lat  float64
+}
+
+func (r *distanceRepo) BoundaryDistance(_ context.Context, projectID uuid.UUID, lon, lat float64) (float64, bool, error) {
+	if projectID != r.projectID {
+		return 0, false, sql.ErrNoRows
+	}
+	r.lon, r.lat = lon, lat
+	return 12.5, true, nil
+}
+
+func TestGetBoundaryDistance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &distanceRepo{fakeRepo: newFakeRepo(), projectID: uuid.New()}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	get := func(projectID, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+projectID+"/boundary-distance?"+query, nil))
+		return w
+	}
+
+	w := get(repo.projectID.String(), "lon=36.8&lat=-1.3")
+	var got BoundaryDistance
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.DistanceMeters != 12.5 || !got.Inside || got.ProjectID != repo.projectID || repo.lon != 36.8 || repo.lat != -1.3 {
+		t.Errorf("unexpected distance %+v (repository saw %v,%v)", got, repo.lon, repo.lat)
+	}
+
+	if w := get(uuid.NewString(), "lon=36.8&lat=-1.3"); w.Code != http.StatusNotFound {
+		t.Errorf("project without a boundary: expected 404, got %d", w.Code)
+	}
+	for _, q := range []string{"lon=36.8", "lon=0&lat=91"} {
+		if w := get(repo.projectID.String(), q); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+	if w := get("not-a-uuid", "lon=0&lat=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid project id: expected 400, got %d", w.Code)
+	}
+}
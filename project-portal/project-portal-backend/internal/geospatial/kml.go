@@ -0,0 +1,67 @@
+package geospatial
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/kml"
+
+	"github.com/google/uuid"
+)
+
+// ImportKML imports the polygon Placemarks of a KML document like
+// ImportFeatureCollection. Each Placemark names its project in an ExtendedData
+// project_id value, or failing that in its name. KML is WGS 84 and altitudes
+// are dropped, so the boundaries are stored as read.
+func (s *service) ImportKML(ctx context.Context, r io.Reader, req BatchImportRequest, mode string) (*BatchImportResult, error) {
+	placemarks, err := kml.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(placemarks) == 0 {
+		return nil, fmt.Errorf("kml document has no placemarks")
+	}
+
+	type feature struct {
+		Type       string            `json:"type"`
+		Properties map[string]string `json:"properties"`
+		Geometry   json.RawMessage   `json:"geometry"`
+	}
+	features := make([]feature, len(placemarks))
+	for i, p := range placemarks {
+		projectID := p.Data["project_id"]
+		if projectID == "" {
+			projectID = p.Name
+		}
+		geometry := p.Geometry
+		if geometry == nil {
+			geometry = json.RawMessage("null")
+		}
+		features[i] = feature{Type: "Feature", Properties: map[string]string{"project_id": projectID}, Geometry: geometry}
+	}
+	collection, err := json.Marshal(map[string]interface{}{"type": "FeatureCollection", "features": features})
+	if err != nil {
+		return nil, err
+	}
+
+	req.GeoJSON = collection
+	if req.SourceType == "" {
+		req.SourceType = "kml"
+	}
+	return s.ImportFeatureCollection(ctx, req, mode)
+}
+
+// projectKML wraps a boundary serialized by ST_AsKML in a KML document with
+// the project id as ExtendedData, so the file imports back unchanged.
+func projectKML(projectID uuid.UUID, geometry string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := kml.Encode(&buf, []kml.Feature{{
+		Name:     "Project " + projectID.String(),
+		Data:     map[string]string{"project_id": projectID.String()},
+		Geometry: geometry,
+	}})
+	return buf.Bytes(), err
+}
@@ -0,0 +1,143 @@
+package geospatial
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDBBusy is returned when a database operation could not get a slot under
+// the service's concurrency limit in time.
+var ErrDBBusy = errors.New("too many concurrent database operations")
+
+// Operation weights against the concurrency limit. Bulk imports and exports
+// hold their slot for a whole batch, so they count for more.
+const (
+	weightQuery int64 = 1
+	weightBulk  int64 = 4
+)
+
+// dbLimiter is a FIFO weighted semaphore bounding in-flight database work
+// independently of the connection pool, so a spike queues here for a bounded
+// time instead of piling onto PostGIS.
+type dbLimiter struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	size    int64
+	used    int64
+	waiters []*dbWaiter
+}
+
+type dbWaiter struct {
+	weight int64
+	ready  chan struct{}
+}
+
+// newDBLimiter allows size units in flight; waiting callers give up after
+// timeout. A size below 1 means no limit and returns nil.
+func newDBLimiter(size int64, timeout time.Duration) *dbLimiter {
+	if size < 1 {
+		return nil
+	}
+	return &dbLimiter{size: size, timeout: timeout}
+}
+
+// acquire waits for weight units and returns the function that gives them
+// back. It fails with ErrDBBusy after the limiter's timeout, or with ctx's
+// error if ctx ends first. A nil limiter admits everything.
+func (l *dbLimiter) acquire(ctx context.Context, weight int64) (func(), error) {
+	if l == nil || holdsLimiterSlot(ctx) {
+		return func() {}, nil
+	}
+	if weight > l.size {
+		weight = l.size
+	}
+	release := func() { l.release(weight) }
+
+	l.mu.Lock()
+	if l.used+weight <= l.size && len(l.waiters) == 0 {
+		l.used += weight
+		l.mu.Unlock()
+		return release, nil
+	}
+	w := &dbWaiter{weight: weight, ready: make(chan struct{})}
+	l.waiters = append(l.waiters, w)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+		return release, nil
+	case <-timer.C:
+		err = ErrDBBusy
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-w.ready:
+		// Granted while timing out; keep the slot rather than leak it.
+		return release, nil
+	default:
+	}
+	for i, other := range l.waiters {
+		if other == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			break
+		}
+	}
+	// A heavy waiter at the head may have been blocking lighter ones.
+	l.grant()
+	return nil, err
+}
+
+func (l *dbLimiter) release(weight int64) {
+	l.mu.Lock()
+	l.used -= weight
+	l.grant()
+	l.mu.Unlock()
+}
+
+// grant admits queued waiters in order while they fit. l.mu must be held.
+func (l *dbLimiter) grant() {
+	for len(l.waiters) > 0 {
+		w := l.waiters[0]
+		if l.used+w.weight > l.size {
+			return
+		}
+		l.used += w.weight
+		l.waiters = l.waiters[1:]
+		close(w.ready)
+	}
+}
+
+// dbCall runs fn under the service's concurrency limit and retry policy.
+func dbCall[T any](ctx context.Context, s *service, weight int64, fn func() (T, error)) (T, error) {
+	release, err := s.limiter.acquire(ctx, weight)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+	return retryValue(ctx, s.retry, fn)
+}
+
+type limiterSlotKey struct{}
+
+// withLimiterSlot marks ctx as belonging to an operation that already holds a
+// limiter slot, such as a bulk import, so the queries it makes along the way
+// do not queue behind it.
+func withLimiterSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, limiterSlotKey{}, true)
+}
+
+func holdsLimiterSlot(ctx context.Context) bool {
+	held, _ := ctx.Value(limiterSlotKey{}).(bool)
+	return held
+}
@@ -0,0 +1,65 @@
+package geospatial
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDBLimiterWeightsAndTimeout(t *testing.T) {
+	l := newDBLimiter(4, 20*time.Millisecond)
+	ctx := context.Background()
+
+	bulk, err := l.acquire(ctx, weightBulk)
+	if err != nil {
+		t.Fatalf("acquire bulk: %v", err)
+	}
+	if _, err := l.acquire(ctx, weightQuery); !errors.Is(err, ErrDBBusy) {
+		t.Fatalf("expected ErrDBBusy while a bulk operation holds every slot, got %v", err)
+	}
+	bulk()
+
+	var releases []func()
+	for i := 0; i < 4; i++ {
+		release, err := l.acquire(ctx, weightQuery)
+		if err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+		releases = append(releases, release)
+	}
+
+	// A waiter is granted as soon as capacity frees up.
+	l.timeout = time.Second
+	granted := make(chan error, 1)
+	go func() {
+		release, err := l.acquire(ctx, weightQuery)
+		if err == nil {
+			release()
+		}
+		granted <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	releases[0]()
+	if err := <-granted; err != nil {
+		t.Fatalf("expected the waiter to get the freed slot, got %v", err)
+	}
+	for _, release := range releases[1:] {
+		release()
+	}
+	if l.used != 0 || len(l.waiters) != 0 {
+		t.Fatalf("expected the limiter to drain, got used=%d waiters=%d", l.used, len(l.waiters))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	hold, _ := l.acquire(ctx, 4)
+	if _, err := l.acquire(cancelled, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+	hold()
+
+	if release, err := (*dbLimiter)(nil).acquire(ctx, weightBulk); err != nil || release == nil {
+		t.Error("a nil limiter must admit everything")
+	}
+}
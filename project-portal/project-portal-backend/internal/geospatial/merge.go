@@ -0,0 +1,162 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/google/uuid"
+)
+
+// MaxMergeProjects caps how many project boundaries one merge may union.
+const MaxMergeProjects = 50
+
+// ErrNotContiguous refuses a contiguous merge of boundaries that do not all
+// touch or overlap.
+var ErrNotContiguous = errors.New("project boundaries do not touch or overlap")
+
+// MissingBoundariesError names the merge sources that are not live projects
+// with a stored boundary.
+type MissingBoundariesError struct {
+	ProjectIDs []uuid.UUID
+}
+
+func (e *MissingBoundariesError) Error() string {
+	ids := make([]string, len(e.ProjectIDs))
+	for i, id := range e.ProjectIDs {
+		ids[i] = id.String()
+	}
+	return "no boundary stored for project " + strings.Join(ids, ", ")
+}
+
+// MergeProjectsRequest unions the boundaries of ProjectIDs. With Contiguous
+// set the boundaries must form one connected area. With Persist set the
+// union is stored as the boundary of a new project.
+type MergeProjectsRequest struct {
+	ProjectIDs []uuid.UUID   `json:"project_ids" binding:"required"`
+	Contiguous bool          `json:"contiguous"`
+	Persist    *MergedTarget `json:"persist,omitempty"`
+}
+
+// MergedTarget describes the project a merge is stored as. Its type,
+// location, owner and organization are those of the first source project.
+type MergedTarget struct {
+	Name string `json:"name" binding:"required,max=200"`
+}
+
+// ProjectUnion is the union of several project boundaries as measured by the
+// repository. Components counts the groups of boundaries that touch or
+// overlap; Found lists the sources that had a boundary.
+type ProjectUnion struct {
+	Geometry           json.RawMessage
+	AreaHectares       float64
+	SourceAreaHectares float64
+	Components         int
+	IsValid            bool
+	ValidationErrors   []string
+	Found              []uuid.UUID
+}
+
+// MergeResult is the merged boundary of several projects. AreaHectares is
+// the sum of the sources' areas less OverlapAreaHectares, the area they
+// share. ProjectID is the new project when the merge was stored.
+type MergeResult struct {
+	ProjectIDs          []uuid.UUID     `json:"project_ids"`
+	Geometry            json.RawMessage `json:"geometry"`
+	AreaHectares        float64         `json:"area_hectares"`
+	SourceAreaHectares  float64         `json:"source_area_hectares"`
+	OverlapAreaHectares float64         `json:"overlap_area_hectares"`
+	Contiguous          bool            `json:"contiguous"`
+	IsValid             bool            `json:"is_valid"`
+	ValidationErrors    []string        `json:"validation_errors,omitempty"`
+	ProjectID           *uuid.UUID      `json:"project_id,omitempty"`
+}
+
+// MergeProjects unions the boundaries of req.ProjectIDs with ST_Union and,
+// when asked, stores the result as a new project. The new boundary is not
+// checked for overlaps: it covers its sources by design, and retiring them is
+// left to the caller.
+func (s *service) MergeProjects(ctx context.Context, req MergeProjectsRequest) (*MergeResult, error) {
+	ids := make([]uuid.UUID, 0, len(req.ProjectIDs))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range req.ProjectIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 || len(ids) > MaxMergeProjects {
+		return nil, fmt.Errorf("a merge needs between 2 and %d distinct projects, got %d", MaxMergeProjects, len(ids))
+	}
+
+	union, err := dbCall(ctx, s, weightBulk, func() (*ProjectUnion, error) {
+		return s.repo.UnionProjectGeometries(ctx, ids)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &MissingBoundariesError{ProjectIDs: ids}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if missing := missingSources(ids, union.Found); len(missing) > 0 {
+		return nil, &MissingBoundariesError{ProjectIDs: missing}
+	}
+	if req.Contiguous && union.Components > 1 {
+		return nil, fmt.Errorf("%w: they form %d separate areas", ErrNotContiguous, union.Components)
+	}
+
+	result := &MergeResult{
+		ProjectIDs:          ids,
+		Geometry:            union.Geometry,
+		AreaHectares:        union.AreaHectares,
+		SourceAreaHectares:  union.SourceAreaHectares,
+		OverlapAreaHectares: max(union.SourceAreaHectares-union.AreaHectares, 0),
+		Contiguous:          union.Components == 1,
+		IsValid:             union.IsValid,
+		ValidationErrors:    union.ValidationErrors,
+	}
+	if req.Persist == nil {
+		return result, nil
+	}
+	if !union.IsValid {
+		return nil, fmt.Errorf("merged boundary is invalid: %s", strings.Join(union.ValidationErrors, "; "))
+	}
+
+	projectID, err := dbCall(ctx, s, weightQuery, func() (uuid.UUID, error) {
+		projectID := uuid.New()
+		err := s.repo.InTransaction(ctx, func(tx Repository) error {
+			if err := tx.CreateMergedProject(ctx, projectID, req.Persist.Name, ids, union.AreaHectares); err != nil {
+				return err
+			}
+			_, err := tx.UpsertProjectGeometry(ctx, projectID, UploadGeometryRequest{GeoJSON: union.Geometry, SourceType: "merge"})
+			return err
+		})
+		return projectID, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.ProjectID = &projectID
+	logging.FromContext(ctx).Info("merged project created", "project_id", projectID, "sources", len(ids), "area_hectares", union.AreaHectares)
+	return result, nil
+}
+
+// missingSources returns the ids not in found, in request order.
+func missingSources(ids, found []uuid.UUID) []uuid.UUID {
+	have := make(map[uuid.UUID]bool, len(found))
+	for _, id := range found {
+		have[id] = true
+	}
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if !have[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
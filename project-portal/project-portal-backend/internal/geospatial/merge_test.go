@@ -0,0 +1,94 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// mergeRepo unions the boundaries it knows, given as areas in hectares, as
+// though the first two share overlap hectares.
+type mergeRepo struct {
+	*fakeRepo
+	areas      map[uuid.UUID]float64
+	overlap    float64
+	components int
+	created    map[uuid.UUID]string
+}
+
+func (r *mergeRepo) UnionProjectGeometries(_ context.Context, ids []uuid.UUID) (*ProjectUnion, error) {
+	union := &ProjectUnion{Geometry: json.RawMessage(`{"type":"MultiPolygon","coordinates":[]}`), Components: r.components, IsValid: true}
+	for _, id := range ids {
+		if area, ok := r.areas[id]; ok {
+			union.Found = append(union.Found, id)
+			union.SourceAreaHectares += area
+		}
+	}
+	union.AreaHectares = union.SourceAreaHectares - r.overlap
+	return union, nil
+}
+
+func (r *mergeRepo) CreateMergedProject(_ context.Context, projectID uuid.UUID, name string, _ []uuid.UUID, _ float64) error {
+	r.created[projectID] = name
+	return nil
+}
+
+func (r *mergeRepo) InTransaction(_ context.Context, fn func(tx Repository) error) error {
+	return fn(r)
+}
+
+func TestMergeProjects(t *testing.T) {
+	west, east, far := uuid.New(), uuid.New(), uuid.New()
+	repo := &mergeRepo{
+		fakeRepo:   newFakeRepo(),
+		areas:      map[uuid.UUID]float64{west: 10, east: 6, far: 4},
+		overlap:    1.5,
+		components: 1,
+		created:    map[uuid.UUID]string{},
+	}
+	svc := NewService(repo)
+	ctx := context.Background()
+
+	result, err := svc.MergeProjects(ctx, MergeProjectsRequest{ProjectIDs: []uuid.UUID{west, east, west}, Contiguous: true})
+	if err != nil {
+		t.Fatalf("MergeProjects: %v", err)
+	}
+	if len(result.ProjectIDs) != 2 || result.AreaHectares != 14.5 || result.OverlapAreaHectares != 1.5 || !result.Contiguous || result.ProjectID != nil {
+		t.Errorf("expected a 14.5 ha preview of 2 projects sharing 1.5 ha, got %+v", result)
+	}
+	if math.Abs(result.SourceAreaHectares-result.OverlapAreaHectares-result.AreaHectares) > 1e-9 {
+		t.Errorf("union area must be the sum less the overlap, got %+v", result)
+	}
+	if len(repo.created) != 0 {
+		t.Error("a preview must not create a project")
+	}
+
+	stored, err := svc.MergeProjects(ctx, MergeProjectsRequest{ProjectIDs: []uuid.UUID{west, east}, Persist: &MergedTarget{Name: "Joined parcels"}})
+	if err != nil || stored.ProjectID == nil {
+		t.Fatalf("expected the merge to be stored, got %+v (%v)", stored, err)
+	}
+	if repo.created[*stored.ProjectID] != "Joined parcels" || repo.last.SourceType != "merge" {
+		t.Errorf("expected a new project with the merged boundary, got %v (source %q)", repo.created, repo.last.SourceType)
+	}
+
+	repo.components = 2
+	if _, err := svc.MergeProjects(ctx, MergeProjectsRequest{ProjectIDs: []uuid.UUID{west, far}, Contiguous: true}); !errors.Is(err, ErrNotContiguous) {
+		t.Errorf("expected ErrNotContiguous for separate areas, got %v", err)
+	}
+	if result, err := svc.MergeProjects(ctx, MergeProjectsRequest{ProjectIDs: []uuid.UUID{west, far}}); err != nil || result.Contiguous {
+		t.Errorf("expected separate areas to merge when contiguity is not required, got %+v (%v)", result, err)
+	}
+
+	unknown := uuid.New()
+	var missing *MissingBoundariesError
+	if _, err := svc.MergeProjects(ctx, MergeProjectsRequest{ProjectIDs: []uuid.UUID{west, unknown}}); !errors.As(err, &missing) || len(missing.ProjectIDs) != 1 || missing.ProjectIDs[0] != unknown {
+		t.Errorf("expected the project without a boundary to be named, got %v", err)
+	}
+	if _, err := svc.MergeProjects(ctx, MergeProjectsRequest{ProjectIDs: []uuid.UUID{west, west}}); err == nil {
+		t.Error("expected a merge of one distinct project to be refused")
+	}
+}
@@ -22,19 +22,23 @@ type ProjectGeometry struct {
 	SourceType             string          `json:"source_type"`
 	SourceFile             string          `json:"source_file,omitempty"`
 	AccuracyScore          *float64        `json:"accuracy_score,omitempty"`
+	// WindingCorrected is set when the uploaded rings were reoriented to the
+	// stored convention: exterior counter-clockwise, holes clockwise.
+	WindingCorrected       bool            `json:"winding_corrected"`
 	Version                int             `json:"version"`
 	PreviousVersionID      *uuid.UUID      `json:"previous_version_id,omitempty" gorm:"type:uuid"`
 	CreatedAt              time.Time       `json:"created_at"`
 	UpdatedAt              time.Time       `json:"updated_at"`
+	Warnings               []string        `json:"warnings,omitempty" gorm:"-"`
 }
 
 // UploadGeometryRequest uploads project geometry as RFC7946 GeoJSON.
 type UploadGeometryRequest struct {
 	GeoJSON               json.RawMessage `json:"geojson" binding:"required"`
-	SimplificationTolerance *float64      `json:"simplification_tolerance,omitempty"`
-	SourceType            string          `json:"source_type,omitempty"`
-	SourceFile            string          `json:"source_file,omitempty"`
-	AccuracyScore         *float64        `json:"accuracy_score,omitempty"`
+	SimplificationTolerance *float64      `json:"simplification_tolerance,omitempty" binding:"omitempty,gte=0"`
+	SourceType            string          `json:"source_type,omitempty" binding:"max=50"`
+	SourceFile            string          `json:"source_file,omitempty" binding:"max=255"`
+	AccuracyScore         *float64        `json:"accuracy_score,omitempty" binding:"omitempty,gte=0"`
 }
 
 type BoundaryResponse struct {
@@ -43,8 +47,59 @@ type BoundaryResponse struct {
 	Geometry       json.RawMessage `json:"geometry,omitempty"`
 	WKT            string          `json:"wkt,omitempty"`
 	KML            string          `json:"kml,omitempty"`
+	WKB            []byte          `json:"wkb,omitempty"`
 	AreaHectares   float64         `json:"area_hectares"`
 	PerimeterMeters float64        `json:"perimeter_meters"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// PerimeterResponse is the boundary length of a project in meters.
+type PerimeterResponse struct {
+	ProjectID       uuid.UUID `json:"project_id"`
+	PerimeterMeters float64   `json:"perimeter_meters"`
+	ExteriorOnly    bool      `json:"exterior_only"`
+}
+
+// BoundaryDistance is how far a point is from the nearest edge of a project
+// boundary, in meters on the spheroid. Inside includes points on the
+// boundary, where DistanceMeters is 0.
+type BoundaryDistance struct {
+	ProjectID      uuid.UUID `json:"project_id"`
+	Lon            float64   `json:"lon"`
+	Lat            float64   `json:"lat"`
+	DistanceMeters float64   `json:"distance_meters"`
+	Inside         bool      `json:"inside"`
+}
+
+// GeometryVersion is one stored revision of a project boundary.
+type GeometryVersion struct {
+	Version      int       `json:"version"`
+	AreaHectares float64   `json:"area_hectares"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GeometryDiff is what changed between two boundary versions. Added and
+// Removed are GeoJSON geometries and may be empty collections.
+type GeometryDiff struct {
+	ProjectID         uuid.UUID       `json:"project_id"`
+	FromVersion       int             `json:"from_version"`
+	ToVersion         int             `json:"to_version"`
+	Added             json.RawMessage `json:"added"`
+	Removed           json.RawMessage `json:"removed"`
+	AddedHectares     float64         `json:"added_hectares"`
+	RemovedHectares   float64         `json:"removed_hectares"`
+	NetChangeHectares float64         `json:"net_change_hectares"`
+}
+
+// IndexCheckReport is the result of the spatial index diagnostic. Warnings is
+// non-empty when an index is missing or the planner still scans the table.
+type IndexCheckReport struct {
+	Indexes        map[string]bool `json:"indexes"`
+	PlanNodes      []string        `json:"plan_nodes"`
+	IndexesUsed    []string        `json:"indexes_used"`
+	SequentialScan bool            `json:"sequential_scan"`
+	Warnings       []string        `json:"warnings"`
+	Plan           json.RawMessage `json:"plan"`
 }
 
 type NearbyProject struct {
@@ -68,16 +123,123 @@ type WithinQuery struct {
 	MaxLon    *float64 `form:"max_lon"`
 	GeoJSON   string   `form:"geojson"`
 	Limit     int      `form:"limit"`
+	Tags      string   `form:"tags"`      // comma-separated
+	TagMatch  string   `form:"tag_match"` // any (default) or all
+}
+
+// Export formats accepted by ExportQuery.Format.
+const (
+	ExportGeoJSON   = "geojson"
+	ExportShapefile = "shapefile"
+)
+
+// ExportQuery selects the projects of a bulk geometry export. Every filter is
+// optional; the bbox applies only when all four bounds are given.
+type ExportQuery struct {
+	MinLat   *float64 `form:"min_lat"`
+	MinLon   *float64 `form:"min_lon"`
+	MaxLat   *float64 `form:"max_lat"`
+	MaxLon   *float64 `form:"max_lon"`
+	Tags     string   `form:"tags"`      // comma-separated
+	TagMatch string   `form:"tag_match"` // any (default) or all
+	OwnerID  string   `form:"owner_id"`
+	Format   string   `form:"format"` // geojson (default) or shapefile
+}
+
+// ExportFeature is one project row of an export.
+type ExportFeature struct {
+	ProjectID    uuid.UUID
+	Name         string
+	Type         string
+	Tags         []string
+	AreaHectares float64
+	Geometry     json.RawMessage
+}
+
+// ExtentQuery selects the projects a default map extent must cover. Every
+// filter is optional.
+type ExtentQuery struct {
+	Tags     string `form:"tags"`      // comma-separated
+	TagMatch string `form:"tag_match"` // any (default) or all
+	OwnerID  string `form:"owner_id"`
+}
+
+// MapExtent is the initial view of a map showing a set of projects. Bounds
+// follows the GeoJSON bbox order and Center is [lon, lat]. Default is set
+// when no project matched and the extent is the whole world.
+type MapExtent struct {
+	Bounds  [4]float64 `json:"bounds"`
+	Center  [2]float64 `json:"center"`
+	Zoom    int        `json:"zoom"`
+	Count   int        `json:"count"`
+	Default bool       `json:"default"`
+}
+
+// PointQuery is a GPS position for a point-in-project lookup.
+type PointQuery struct {
+	Lon *float64 `form:"lon"`
+	Lat *float64 `form:"lat"`
+}
+
+// ProjectAtPoint is a project whose boundary contains a queried point.
+// OnBoundary is set when the point lies exactly on one of its rings.
+type ProjectAtPoint struct {
+	ProjectID    uuid.UUID `json:"project_id"`
+	Name         string    `json:"name"`
+	AreaHectares float64   `json:"area_hectares"`
+	OnBoundary   bool      `json:"on_boundary"`
+}
+
+type ClusterQuery struct {
+	MinLat         *float64 `form:"min_lat"`
+	MinLon         *float64 `form:"min_lon"`
+	MaxLat         *float64 `form:"max_lat"`
+	MaxLon         *float64 `form:"max_lon"`
+	Zoom           int      `form:"zoom"`
+	MinClusterSize int      `form:"min_cluster_size"`
+}
+
+// ProjectPoint is a project centroid used for map clustering.
+type ProjectPoint struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Name      string    `json:"name,omitempty"`
+	Lat       float64   `json:"lat"`
+	Lon       float64   `json:"lon"`
+}
+
+// MapCluster groups nearby project centroids. Bounds follows the GeoJSON bbox
+// order: [min_lon, min_lat, max_lon, max_lat].
+type MapCluster struct {
+	Lat    float64    `json:"lat"`
+	Lon    float64    `json:"lon"`
+	Count  int        `json:"count"`
+	Bounds [4]float64 `json:"bounds"`
+}
+
+type ClusterResponse struct {
+	Zoom     int            `json:"zoom"`
+	Clusters []MapCluster   `json:"clusters"`
+	Projects []ProjectPoint `json:"projects"`
 }
 
 type IntersectRequest struct {
-	GeoJSON json.RawMessage `json:"geojson" binding:"required"`
+	GeoJSON        json.RawMessage `json:"geojson" binding:"required"`
+	IncludeDeleted bool            `json:"-"` // admin-only ?include_deleted=true
+}
+
+// OverlapStats counts the work of an overlap check: projects whose bounding
+// box met the new boundary's, and how many of those survived the simplified
+// pre-check and were compared exactly.
+type OverlapStats struct {
+	BBoxCandidates   int
+	ExactComparisons int
 }
 
 type IntersectResult struct {
-	ProjectID        uuid.UUID `json:"project_id"`
-	IntersectionArea float64   `json:"intersection_area_hectares"`
-	Intersects       bool      `json:"intersects"`
+	ProjectID        uuid.UUID  `json:"project_id"`
+	IntersectionArea float64    `json:"intersection_area_hectares"`
+	Intersects       bool       `json:"intersects"`
+	OwnerID          *uuid.UUID `json:"-"`
 }
 
 type StaticMapRequest struct {
@@ -105,12 +267,12 @@ type Geofence struct {
 }
 
 type CreateGeofenceRequest struct {
-	Name         string          `json:"name" binding:"required"`
+	Name         string          `json:"name" binding:"required,max=255"`
 	Description  string          `json:"description,omitempty"`
 	GeoJSON      json.RawMessage `json:"geojson" binding:"required"`
-	GeofenceType string          `json:"geofence_type" binding:"required"`
+	GeofenceType string          `json:"geofence_type" binding:"required,max=50"`
 	AlertRules   json.RawMessage `json:"alert_rules,omitempty"`
-	Priority     int             `json:"priority,omitempty"`
+	Priority     int             `json:"priority,omitempty" binding:"gte=0"`
 	Metadata     json.RawMessage `json:"metadata,omitempty"`
 }
 
@@ -135,4 +297,109 @@ const (
 	BoundaryFormatGeoJSON = "geojson"
 	BoundaryFormatWKT     = "wkt"
 	BoundaryFormatKML     = "kml"
+	BoundaryFormatWKB     = "wkb"
 )
+
+const (
+	BatchImportModeStrict     = "strict"
+	BatchImportModeBestEffort = "best_effort"
+)
+
+const (
+	BatchFeatureImported   = "imported"
+	BatchFeatureInvalid    = "invalid"
+	BatchFeatureFailed     = "failed"
+	BatchFeatureRolledBack = "rolled_back"
+	BatchFeatureSkipped    = "skipped"
+)
+
+// BatchImportRequest imports one geometry per feature of a FeatureCollection.
+// Each feature must carry the target project in properties.project_id.
+type BatchImportRequest struct {
+	GeoJSON                 json.RawMessage `json:"geojson" binding:"required"`
+	SimplificationTolerance *float64        `json:"simplification_tolerance,omitempty" binding:"omitempty,gte=0"`
+	SourceType              string          `json:"source_type,omitempty" binding:"max=50"`
+	SourceFile              string          `json:"source_file,omitempty" binding:"max=255"`
+	// SnapTolerance comes from the ?snapTolerance= query parameter. When set,
+	// every feature is snapped to a grid of that size (in degrees) first.
+	SnapTolerance *float64 `json:"-"`
+}
+
+// SnapResult is a geometry after ST_SnapToGrid, with the vertex counts before
+// and after and the validity of the snapped shape.
+type SnapResult struct {
+	GeoJSON        json.RawMessage
+	VerticesBefore int
+	VerticesAfter  int
+	IsValid        bool
+	Reason         string
+}
+
+// ValidateGeometryRequest is a dry-run check of a FeatureCollection, a single
+// Feature or a bare geometry. Nothing is stored.
+type ValidateGeometryRequest struct {
+	GeoJSON json.RawMessage `json:"geojson" binding:"required"`
+}
+
+// GeometryCheck is what PostGIS reports for a geometry. Locations are
+// [lon, lat] and nil when PostGIS gives none.
+type GeometryCheck struct {
+	IsValid        bool
+	Reason         string
+	Location       *[2]float64
+	AreaHectares   float64
+	Vertices       int
+	NeedsRewinding bool
+	SelfTouching   bool
+	Slivers        int
+	SliverLocation *[2]float64
+}
+
+// FeatureValidation is the dry-run outcome for one feature. Reason is the
+// parse error or the ST_IsValidReason text when Valid is false. Issues
+// classifies every finding by severity; Warnings repeats the warning
+// messages for older clients.
+type FeatureValidation struct {
+	Index        int               `json:"index"`
+	ProjectID    *uuid.UUID        `json:"project_id,omitempty"`
+	Valid        bool              `json:"valid"`
+	Reason       string            `json:"reason,omitempty"`
+	AreaHectares float64           `json:"area_hectares"`
+	Overlaps     []IntersectResult `json:"overlaps,omitempty"`
+	Warnings     []string          `json:"warnings,omitempty"`
+	Issues       []ValidationIssue `json:"issues"`
+}
+
+// ValidationReport sums up a dry run. Severities counts the issues of
+// every feature by severity.
+type ValidationReport struct {
+	Total      int                 `json:"total"`
+	Valid      int                 `json:"valid"`
+	Invalid    int                 `json:"invalid"`
+	Severities map[Severity]int    `json:"severities"`
+	Features   []FeatureValidation `json:"features"`
+}
+
+type BatchFeatureResult struct {
+	Index        int        `json:"index"`
+	ProjectID    *uuid.UUID `json:"project_id,omitempty"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	GeometryID   *uuid.UUID `json:"geometry_id,omitempty"`
+	AreaHectares float64    `json:"area_hectares,omitempty"`
+	Version      int        `json:"version,omitempty"`
+	Warnings     []string   `json:"warnings,omitempty"`
+	// Vertex counts are only reported when the import was snapped to a grid.
+	VerticesBefore *int `json:"vertices_before,omitempty"`
+	VerticesAfter  *int `json:"vertices_after,omitempty"`
+	// WindingCorrected reports that the feature's ring orientation was fixed.
+	WindingCorrected bool `json:"winding_corrected,omitempty"`
+}
+
+type BatchImportResult struct {
+	Mode     string               `json:"mode"`
+	Total    int                  `json:"total"`
+	Imported int                  `json:"imported"`
+	Failed   int                  `json:"failed"`
+	Results  []BatchFeatureResult `json:"results"`
+}
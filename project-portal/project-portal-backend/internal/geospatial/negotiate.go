@@ -0,0 +1,92 @@
+package geospatial
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Media types the geometry endpoints can serve besides their JSON envelope.
+const (
+	mediaTypeGeoJSON = "application/geo+json"
+	mediaTypeWKT     = "text/plain"
+	mediaTypeWKB     = "application/octet-stream"
+)
+
+// geometryRepresentation is a negotiated response body. An empty format means
+// the endpoint's usual JSON envelope; otherwise the bare geometry is sent in
+// format with contentType.
+type geometryRepresentation struct {
+	format      string
+	contentType string
+}
+
+// geometryRepresentations maps each acceptable media range to what is served
+// for it, in the order ties are broken.
+var geometryRepresentations = []struct {
+	mediaRange string
+	repr       geometryRepresentation
+}{
+	{"application/json", geometryRepresentation{}},
+	{mediaTypeGeoJSON, geometryRepresentation{BoundaryFormatGeoJSON, mediaTypeGeoJSON}},
+	{mediaTypeWKT, geometryRepresentation{BoundaryFormatWKT, mediaTypeWKT + "; charset=utf-8"}},
+	{mediaTypeWKB, geometryRepresentation{BoundaryFormatWKB, mediaTypeWKB}},
+	{"application/*", geometryRepresentation{}},
+	{"text/*", geometryRepresentation{BoundaryFormatWKT, mediaTypeWKT + "; charset=utf-8"}},
+	{"*/*", geometryRepresentation{}},
+}
+
+// negotiateGeometry picks the representation for an Accept header, honouring
+// q-values. A missing header gets the JSON envelope; ok is false when nothing
+// acceptable can be produced.
+func negotiateGeometry(accept string) (geometryRepresentation, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return geometryRepresentation{}, true
+	}
+
+	type candidate struct {
+		mediaRange string
+		q          float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{mediaType, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		for _, r := range geometryRepresentations {
+			if r.mediaRange == c.mediaRange {
+				return r.repr, true
+			}
+		}
+	}
+	return geometryRepresentation{}, false
+}
+
+// body is the boundary serialized in its Format.
+func (b *BoundaryResponse) body() []byte {
+	switch b.Format {
+	case BoundaryFormatWKT:
+		return []byte(b.WKT)
+	case BoundaryFormatKML:
+		return []byte(b.KML)
+	case BoundaryFormatWKB:
+		return b.WKB
+	default:
+		return b.Geometry
+	}
+}
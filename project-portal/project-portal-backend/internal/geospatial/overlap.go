@@ -0,0 +1,256 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/geometry"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/google/uuid"
+)
+
+// ErrGeometryOverlap is returned when a boundary overlaps another project's.
+var ErrGeometryOverlap = errors.New("geometry overlaps an existing project")
+
+// OverlapScope selects which projects the overlap check compares against.
+type OverlapScope string
+
+const (
+	// OverlapAllProjects rejects overlaps with any live project (the default).
+	OverlapAllProjects OverlapScope = "all"
+	// OverlapSameOwner only rejects overlaps with projects of the same owner,
+	// letting separate organisations map adjacent or shared land.
+	OverlapSameOwner OverlapScope = "owner"
+)
+
+// ParseOverlapScope reads GEOSPATIAL_OVERLAP_SCOPE; empty means all projects.
+func ParseOverlapScope(raw string) (OverlapScope, error) {
+	switch OverlapScope(raw) {
+	case "", OverlapAllProjects:
+		return OverlapAllProjects, nil
+	case OverlapSameOwner:
+		return OverlapSameOwner, nil
+	}
+	return "", fmt.Errorf("overlap scope must be %q or %q", OverlapAllProjects, OverlapSameOwner)
+}
+
+// OverlapError lists the projects a rejected boundary overlaps.
+type OverlapError struct {
+	Overlaps []IntersectResult
+}
+
+func (e *OverlapError) Error() string {
+	return fmt.Sprintf("%s (%d overlapping project(s))", ErrGeometryOverlap, len(e.Overlaps))
+}
+
+func (e *OverlapError) Unwrap() error { return ErrGeometryOverlap }
+
+// Overlap writers serialise through transaction-scoped advisory locks on the
+// 1° grid cells covered by the new boundary's bounding box. Two overlapping
+// boundaries always share a cell, so one waits for the other to commit and
+// then sees its row. Every writer also holds overlapGlobalLock shared; a
+// boundary spanning more than overlapMaxCells cells takes it exclusively
+// instead of locking each cell.
+const (
+	overlapLockNamespace = int64(0x4f56) << 48 // "OV"
+	overlapGlobalLock    = overlapLockNamespace | 0xFFFFFFFFFFFF
+	overlapMaxCells      = 64
+)
+
+// overlapLockKeys returns the sorted cell keys for a bounding box, or
+// exclusive=true when the box is too large to lock cell by cell. Sorting keeps
+// the acquisition order identical across writers so they cannot deadlock.
+func overlapLockKeys(minX, minY, maxX, maxY float64) (keys []int64, exclusive bool) {
+	x0, x1 := int64(math.Floor(minX)), int64(math.Floor(maxX))
+	y0, y1 := int64(math.Floor(minY)), int64(math.Floor(maxY))
+	if (x1-x0+1)*(y1-y0+1) > overlapMaxCells {
+		return nil, true
+	}
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			keys = append(keys, overlapLockNamespace|(x+180)<<16|(y+90))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys, false
+}
+
+// storeGeometry writes a boundary through repo. With overlap rejection on,
+// repo must be bound to a transaction: the region locks are held until it
+// ends, so the overlap check and the insert cannot interleave with another
+// writer's.
+func (s *service) storeGeometry(ctx context.Context, repo Repository, projectID uuid.UUID, req UploadGeometryRequest) (*ProjectGeometry, error) {
+	if s.rejectOverlaps {
+		if err := s.checkOverlaps(ctx, repo, projectID, req.GeoJSON); err != nil {
+			return nil, err
+		}
+	}
+	return repo.UpsertProjectGeometry(ctx, projectID, req)
+}
+
+func (s *service) checkOverlaps(ctx context.Context, repo Repository, projectID uuid.UUID, geom json.RawMessage) error {
+	if err := repo.LockOverlapRegions(ctx, geom); err != nil {
+		return err
+	}
+	results, stats, err := repo.OverlapCandidates(ctx, geom, s.overlapTolerance)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Debug("overlap check", "project_id", projectID,
+		"bbox_candidates", stats.BBoxCandidates, "exact_comparisons", stats.ExactComparisons)
+	var owner *uuid.UUID
+	if s.overlapScope == OverlapSameOwner {
+		if owner, err = repo.GetProjectOwner(ctx, projectID); err != nil {
+			return err
+		}
+	}
+	var overlaps []IntersectResult
+	for _, r := range results {
+		// Boundaries that only touch share no area and are allowed.
+		if r.ProjectID == projectID || !r.Intersects || r.IntersectionArea <= 0 {
+			continue
+		}
+		if s.overlapScope == OverlapSameOwner && !sameOwner(owner, r.OwnerID) {
+			continue
+		}
+		overlaps = append(overlaps, r)
+	}
+	if len(overlaps) > 0 {
+		return &OverlapError{Overlaps: overlaps}
+	}
+	return nil
+}
+
+// sameOwner reports whether two projects share an owner. Projects without an
+// owner predate ownership and are treated as belonging to one shared owner.
+func sameOwner(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// writeGeometry stores a single boundary, inside its own transaction when the
+// overlap check needs one. The write is retried on transient errors and counts
+// against the service's database concurrency limit.
+func (s *service) writeGeometry(ctx context.Context, projectID uuid.UUID, req UploadGeometryRequest) (*ProjectGeometry, error) {
+	if !s.rejectOverlaps {
+		return dbCall(ctx, s, weightQuery, func() (*ProjectGeometry, error) {
+			return s.repo.UpsertProjectGeometry(ctx, projectID, req)
+		})
+	}
+	// A serialization failure or deadlock rolls the whole transaction back, so
+	// retrying re-runs the overlap check against the winner's boundary.
+	return dbCall(ctx, s, weightQuery, func() (*ProjectGeometry, error) {
+		var stored *ProjectGeometry
+		err := s.repo.InTransaction(ctx, func(tx Repository) error {
+			var err error
+			stored, err = s.storeGeometry(ctx, tx, projectID, req)
+			return err
+		})
+		return stored, err
+	})
+}
+
+// How a proposed boundary relates to an existing one it overlaps.
+const (
+	// OverlapPartial: each boundary has area outside the other.
+	OverlapPartial = "partial"
+	// OverlapWithin: the proposed boundary lies entirely inside the existing one.
+	OverlapWithin = "within"
+	// OverlapContains: the proposed boundary entirely covers the existing one.
+	OverlapContains = "contains"
+	// OverlapEqual: the two boundaries cover the same area.
+	OverlapEqual = "equal"
+)
+
+// OverlapMeasure is the raw comparison of a proposed boundary with one
+// existing project, as measured by MeasureOverlaps. Areas are in hectares.
+type OverlapMeasure struct {
+	ProjectID            uuid.UUID
+	NewAreaHectares      float64
+	ExistingAreaHectares float64
+	OverlapAreaHectares  float64
+	NewCoversExisting    bool
+	ExistingCoversNew    bool
+}
+
+// ProjectOverlap is how much a proposed boundary overlaps one existing
+// project, as a share of each boundary's area.
+type ProjectOverlap struct {
+	ProjectID            uuid.UUID `json:"project_id"`
+	Relation             string    `json:"relation"`
+	OverlapAreaHectares  float64   `json:"overlap_area_hectares"`
+	PercentOfNew         float64   `json:"percent_of_new"`
+	PercentOfExisting    float64   `json:"percent_of_existing"`
+	ExistingAreaHectares float64   `json:"existing_area_hectares"`
+}
+
+// OverlapPreviewRequest asks how much GeoJSON would overlap existing
+// projects. ProjectID leaves out that project's current boundary, for
+// previewing a replacement.
+type OverlapPreviewRequest struct {
+	GeoJSON   json.RawMessage `json:"geojson" binding:"required"`
+	ProjectID *uuid.UUID      `json:"project_id,omitempty"`
+}
+
+// overlap derives percentages and the relation from m. Containment is taken
+// from the ST_Covers tests rather than the areas, so a boundary inside another
+// reports exactly 100% even when the intersection area carries rounding error.
+func (m OverlapMeasure) overlap() ProjectOverlap {
+	o := ProjectOverlap{
+		ProjectID:            m.ProjectID,
+		Relation:             OverlapPartial,
+		OverlapAreaHectares:  m.OverlapAreaHectares,
+		PercentOfNew:         percentOf(m.OverlapAreaHectares, m.NewAreaHectares),
+		PercentOfExisting:    percentOf(m.OverlapAreaHectares, m.ExistingAreaHectares),
+		ExistingAreaHectares: m.ExistingAreaHectares,
+	}
+	switch {
+	case m.NewCoversExisting && m.ExistingCoversNew:
+		o.Relation, o.PercentOfNew, o.PercentOfExisting = OverlapEqual, 100, 100
+	case m.ExistingCoversNew:
+		o.Relation, o.PercentOfNew = OverlapWithin, 100
+	case m.NewCoversExisting:
+		o.Relation, o.PercentOfExisting = OverlapContains, 100
+	}
+	return o
+}
+
+// percentOf returns part as a percentage of whole, to two decimals and capped
+// at 100.
+func percentOf(part, whole float64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return math.Min(100, math.Round(part/whole*10000)/100)
+}
+
+// PreviewOverlaps reports how much a proposed boundary overlaps each live
+// project the caller may see, largest overlap first, without storing it.
+// Unlike the overlap check on upload it ignores the overlap scope.
+func (s *service) PreviewOverlaps(ctx context.Context, req OverlapPreviewRequest) ([]ProjectOverlap, error) {
+	geom := geometry.ExtractGeometry(req.GeoJSON)
+	if err := geometry.ValidateGeoJSON(geom); err != nil {
+		return nil, err
+	}
+	measures, err := dbCall(ctx, s, weightQuery, func() ([]OverlapMeasure, error) {
+		return s.repo.MeasureOverlaps(ctx, geom)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProjectOverlap, 0, len(measures))
+	for _, m := range measures {
+		if req.ProjectID != nil && m.ProjectID == *req.ProjectID {
+			continue
+		}
+		out = append(out, m.overlap())
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// GeometryPart is one polygon of a project boundary.
+type GeometryPart struct {
+	Part         int
+	Geometry     json.RawMessage
+	AreaHectares float64
+}
+
+// PartFeature is a GeometryPart as a GeoJSON Feature.
+type PartFeature struct {
+	Type       string          `json:"type"`
+	Geometry   json.RawMessage `json:"geometry"`
+	Properties PartProperties  `json:"properties"`
+}
+
+// PartProperties identify a part and carry its own area.
+type PartProperties struct {
+	ProjectID    uuid.UUID `json:"project_id"`
+	Part         int       `json:"part"`
+	AreaHectares float64   `json:"area_hectares"`
+}
+
+// GeometryParts is a project boundary exploded into a GeoJSON
+// FeatureCollection with one Polygon feature per part.
+type GeometryParts struct {
+	Type     string        `json:"type"`
+	Features []PartFeature `json:"features"`
+}
+
+// ExplodeGeometry splits projectID's boundary into its polygons, each with
+// its own area, leaving the stored boundary as it is. A single polygon comes
+// back as one feature. A project without a boundary yields sql.ErrNoRows.
+func (s *service) ExplodeGeometry(ctx context.Context, projectID uuid.UUID) (*GeometryParts, error) {
+	parts, err := dbCall(ctx, s, weightQuery, func() ([]GeometryPart, error) {
+		return s.repo.GeometryParts(ctx, projectID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := &GeometryParts{Type: "FeatureCollection", Features: make([]PartFeature, 0, len(parts))}
+	for _, p := range parts {
+		out.Features = append(out.Features, PartFeature{
+			Type:     "Feature",
+			Geometry: p.Geometry,
+			Properties: PartProperties{
+				ProjectID:    projectID,
+				Part:         p.Part,
+				AreaHectares: p.AreaHectares,
+			},
+		})
+	}
+	return out, nil
+}
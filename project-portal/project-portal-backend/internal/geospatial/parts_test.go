@@ -0,0 +1,70 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// partsRepo holds a three-part boundary for one project.
+type partsRepo struct {
+	*fakeRepo
+	projectID uuid.UUID
+}
+
+func (r *partsRepo) GeometryParts(_ context.Context, projectID uuid.UUID) ([]GeometryPart, error) {
+	if projectID != r.projectID {
+		return nil, sql.ErrNoRows
+	}
+	return []GeometryPart{
+		{Part: 1, Geometry: json.RawMessage(`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`), AreaHectares: 10},
+		{Part: 2, Geometry: json.RawMessage(`{"type":"Polygon","coordinates":[[[2,0],[3,0],[3,1],[2,0]]]}`), AreaHectares: 20},
+		{Part: 3, Geometry: json.RawMessage(`{"type":"Polygon","coordinates":[[[4,0],[5,0],[5,1],[4,0]]]}`), AreaHectares: 30},
+	}, nil
+}
+
+func TestGetGeometryParts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &partsRepo{fakeRepo: newFakeRepo(), projectID: uuid.New()}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	get := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+id+"/geometry/parts", nil))
+		return w
+	}
+
+	w := get(repo.projectID.String())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var parts GeometryParts
+	if err := json.Unmarshal(w.Body.Bytes(), &parts); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if parts.Type != "FeatureCollection" || len(parts.Features) != 3 {
+		t.Fatalf("expected a FeatureCollection of 3 features, got %s", w.Body.String())
+	}
+	for i, f := range parts.Features {
+		if f.Type != "Feature" || f.Properties.Part != i+1 || f.Properties.ProjectID != repo.projectID {
+			t.Errorf("feature %d: unexpected %+v", i, f)
+		}
+		if f.Properties.AreaHectares != float64(10*(i+1)) {
+			t.Errorf("feature %d: expected its own area, got %.1f ha", i, f.Properties.AreaHectares)
+		}
+	}
+
+	if w := get(uuid.NewString()); w.Code != http.StatusNotFound {
+		t.Errorf("no boundary: expected 404, got %d", w.Code)
+	}
+	if w := get("not-a-uuid"); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed id: expected 400, got %d", w.Code)
+	}
+}
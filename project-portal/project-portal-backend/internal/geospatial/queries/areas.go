@@ -0,0 +1,23 @@
+package queries
+
+// RecomputeAreasSQL refreshes the cached area_hectares of every stored
+// boundary from its geometry, rounded as the column stores it, so only rows
+// that drifted are written. It returns how many boundaries were checked and
+// how many were corrected. The update leaves geometry alone, so the area
+// cache trigger does not fire.
+// Arguments: none.
+func RecomputeAreasSQL() string {
+	return `
+WITH fresh AS (
+  SELECT id, ROUND((ST_Area(geometry) * 0.0001)::numeric, 4) AS area_hectares
+  FROM project_geometries
+), corrected AS (
+  UPDATE project_geometries pg
+  SET area_hectares = fresh.area_hectares
+  FROM fresh
+  WHERE pg.id = fresh.id AND pg.area_hectares IS DISTINCT FROM fresh.area_hectares
+  RETURNING pg.id
+)
+SELECT (SELECT COUNT(*) FROM fresh), (SELECT COUNT(*) FROM corrected)
+`
+}
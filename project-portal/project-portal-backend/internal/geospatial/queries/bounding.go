@@ -0,0 +1,37 @@
+package queries
+
+// BoundingShapesSQL returns the minimum bounding circle and the oriented
+// minimum bounding rectangle of a stored project boundary. The circle is
+// centred on ST_MinimumBoundingRadius's planar centre; its radius is the
+// spheroidal distance from there to the farthest vertex, and its GeoJSON is
+// a geodesic buffer of that radius, so it encloses the boundary in meters.
+// The rectangle is ST_OrientedEnvelope, with the lengths of two adjacent
+// sides. The boundary's own area closes the row.
+// Arguments: project id.
+func BoundingShapesSQL() string {
+	return `
+WITH boundary AS (
+  SELECT geometry::geometry AS geom,
+         (ST_MinimumBoundingRadius(geometry::geometry)).center AS center,
+         ST_ExteriorRing(ST_OrientedEnvelope(geometry::geometry)) AS ring
+  FROM project_geometries
+  WHERE project_id = ?
+),
+circle AS (
+  SELECT boundary.*,
+         (SELECT MAX(ST_Distance(boundary.center::geography, d.geom::geography))
+          FROM ST_DumpPoints(boundary.geom) AS d) AS radius
+  FROM boundary
+)
+SELECT ST_X(center),
+       ST_Y(center),
+       radius,
+       ST_AsGeoJSON(ST_Buffer(center::geography, radius)::geometry),
+       ST_AsGeoJSON(ST_MakePolygon(ring)),
+       ST_Area(ST_MakePolygon(ring)::geography) * 0.0001,
+       ST_Length(ST_MakeLine(ST_PointN(ring, 1), ST_PointN(ring, 2))::geography),
+       ST_Length(ST_MakeLine(ST_PointN(ring, 2), ST_PointN(ring, 3))::geography),
+       ST_Area(geom::geography) * 0.0001
+FROM circle
+`
+}
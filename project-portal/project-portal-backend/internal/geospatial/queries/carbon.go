@@ -0,0 +1,14 @@
+package queries
+
+// CarbonInputsSQL returns the cached spheroidal area in hectares of a live
+// project's stored boundary, with the project's type and tags, the inputs
+// of a carbon estimate.
+// Arguments: project id.
+func CarbonInputsSQL() string {
+	return `
+SELECT g.area_hectares, p.type, p.tags
+FROM project_geometries g
+JOIN projects p ON p.id = g.project_id
+WHERE g.project_id = ? AND p.deleted_at IS NULL
+`
+}
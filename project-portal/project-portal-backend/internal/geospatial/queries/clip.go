@@ -0,0 +1,36 @@
+package queries
+
+import "fmt"
+
+// ClipToAOISQL clips every live project boundary that meets an area of
+// interest to that area. Each row is the project's id and name, the clipped
+// geometry as GeoJSON, the clipped area and the cached full area in hectares,
+// and whether the boundary lies wholly inside the area. Only the polygonal
+// part of the intersection is kept, so a project that merely touches the
+// area's edge yields no row. Rows come largest clipped area first.
+// filter is appended to the WHERE clause of the project scan and is empty or
+// a ProjectScopeFilter predicate.
+//
+// Arguments: AOI GeoJSON, then filter's.
+func ClipToAOISQL(filter string) string {
+	return fmt.Sprintf(`
+WITH aoi AS (
+    SELECT ST_SetSRID(ST_GeomFromGeoJSON(?), 4326) AS geom
+), clipped AS (
+    SELECT p.id, p.name, pg.area_hectares,
+           ST_CoveredBy(pg.geometry::geometry, aoi.geom) AS inside,
+           ST_CollectionExtract(ST_Intersection(pg.geometry::geometry, aoi.geom), 3) AS part
+    FROM project_geometries pg
+    JOIN projects p ON p.id = pg.project_id
+    CROSS JOIN aoi
+    WHERE p.deleted_at IS NULL AND ST_Intersects(pg.geometry::geometry, aoi.geom)%s
+)
+SELECT id, name, ST_AsGeoJSON(part),
+       ST_Area(part::geography) * 0.0001 AS clipped_hectares,
+       area_hectares,
+       inside
+FROM clipped
+WHERE NOT ST_IsEmpty(part)
+ORDER BY clipped_hectares DESC, id
+`, filter)
+}
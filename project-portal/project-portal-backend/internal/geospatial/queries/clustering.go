@@ -1,9 +1,43 @@
 package queries
 
+import (
+	"fmt"
+	"math"
+)
+
 // ClusterKey returns a lightweight cluster key for map grouping.
 func ClusterKey(lat, lon float64, precision float64) (float64, float64) {
 	if precision <= 0 {
 		precision = 0.1
 	}
-	return float64(int(lat/precision)) * precision, float64(int(lon/precision)) * precision
+	return math.Floor(lat/precision) * precision, math.Floor(lon/precision) * precision
+}
+
+// ClusterPrecision returns the grid cell size in degrees for a web map zoom
+// level: a quarter of a tile, so cells shrink by half with every zoom step.
+func ClusterPrecision(zoom int) float64 {
+	if zoom < 0 {
+		zoom = 0
+	}
+	return 360 / math.Pow(2, float64(zoom+2))
+}
+
+// CentroidsInBBoxSQL selects the centroids of the projects inside an
+// envelope. filter is appended to the WHERE clause and is empty or a
+// ProjectScopeFilter predicate.
+func CentroidsInBBoxSQL(limit int, filter string) string {
+	if limit <= 0 {
+		limit = 5000
+	}
+	return fmt.Sprintf(`
+SELECT p.id AS project_id,
+       p.name,
+       ST_Y(pg.centroid::geometry) AS lat,
+       ST_X(pg.centroid::geometry) AS lon
+FROM project_geometries pg
+JOIN projects p ON p.id = pg.project_id
+WHERE pg.centroid::geometry && ST_MakeEnvelope(?, ?, ?, ?, 4326)
+  AND p.deleted_at IS NULL%s
+LIMIT %d
+`, filter, limit)
 }
@@ -0,0 +1,16 @@
+package queries
+
+// BoundaryDistanceSQL measures, on the spheroid, how far a point is from the
+// nearest edge of a stored project boundary, and whether the boundary covers
+// it. ST_Boundary of a MultiPolygon holds every ring of every part, holes
+// included, so a point in a hole is outside and measured to the hole's edge.
+// Arguments: lon, lat, project id.
+func BoundaryDistanceSQL() string {
+	return `
+WITH pt AS (SELECT ST_SetSRID(ST_MakePoint(?, ?), 4326) AS geom)
+SELECT ST_Distance(ST_Boundary(pg.geometry::geometry)::geography, pt.geom::geography),
+       ST_Covers(pg.geometry::geometry, pt.geom)
+FROM project_geometries pg, pt
+WHERE pg.project_id = ?
+`
+}
@@ -0,0 +1,28 @@
+package queries
+
+import "fmt"
+
+// ExportSQL selects the boundary and attributes of every live project for a
+// bulk export, ordered so repeated exports are identical. filter is appended
+// to the WHERE clause and is built from the Export*Filter predicates.
+func ExportSQL(filter string) string {
+	return fmt.Sprintf(`
+SELECT p.id AS project_id,
+       p.name,
+       p.type,
+       p.tags,
+       pg.area_hectares,
+       ST_AsGeoJSON(pg.geometry::geometry) AS geometry_geojson
+FROM project_geometries pg
+JOIN projects p ON p.id = pg.project_id
+WHERE p.deleted_at IS NULL%s
+ORDER BY p.name, p.id
+`, filter)
+}
+
+// ExportBBoxFilter limits ExportSQL to boundaries intersecting an envelope. It
+// takes min lon, min lat, max lon and max lat.
+const ExportBBoxFilter = "\n  AND ST_Intersects(pg.geometry::geometry, ST_MakeEnvelope(?, ?, ?, ?, 4326))"
+
+// ExportOwnerFilter limits ExportSQL to one owner's projects.
+const ExportOwnerFilter = "\n  AND p.owner_id = ?"
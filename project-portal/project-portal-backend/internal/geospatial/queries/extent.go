@@ -0,0 +1,20 @@
+package queries
+
+import "fmt"
+
+// ExtentSQL returns the project count and the combined bounding box of every
+// live project boundary as min lon, min lat, max lon, max lat. The box columns
+// are 0 when nothing matches. filter is appended to the WHERE clause and is
+// built from TagFilterSQL, ExportOwnerFilter and ProjectScopeFilter.
+func ExtentSQL(filter string) string {
+	return fmt.Sprintf(`
+SELECT COUNT(*) AS project_count,
+       COALESCE(ST_XMin(ST_Extent(pg.geometry::geometry)), 0) AS min_lon,
+       COALESCE(ST_YMin(ST_Extent(pg.geometry::geometry)), 0) AS min_lat,
+       COALESCE(ST_XMax(ST_Extent(pg.geometry::geometry)), 0) AS max_lon,
+       COALESCE(ST_YMax(ST_Extent(pg.geometry::geometry)), 0) AS max_lat
+FROM project_geometries pg
+JOIN projects p ON p.id = pg.project_id
+WHERE p.deleted_at IS NULL%s
+`, filter)
+}
@@ -1,6 +1,6 @@
 package queries
 
-const IntersectionSQL = `
+const intersectionSQL = `
 SELECT pg.project_id,
        ST_Intersects(pg.geometry::geometry, ST_SetSRID(ST_GeomFromGeoJSON(?), 4326)) AS intersects,
        CASE
@@ -9,6 +9,19 @@ SELECT pg.project_id,
            ST_Intersection(pg.geometry::geometry, ST_SetSRID(ST_GeomFromGeoJSON(?), 4326))::geography
          ) * 0.0001
          ELSE 0
-       END AS intersection_area_hectares
+       END AS intersection_area_hectares,
+       p.owner_id
 FROM project_geometries pg
+JOIN projects p ON p.id = pg.project_id
 `
+
+// IntersectionSQL returns the overlap query. Soft-deleted projects are left out
+// unless includeDeleted is set. filter is appended to the WHERE clause and is
+// empty or a ProjectScopeFilter predicate.
+func IntersectionSQL(includeDeleted bool, filter string) string {
+	where := "WHERE true"
+	if !includeDeleted {
+		where = "WHERE p.deleted_at IS NULL"
+	}
+	return intersectionSQL + where + filter + "\n"
+}
@@ -0,0 +1,47 @@
+package queries
+
+// ProjectUnionSQL unions the boundaries of live projects and measures the
+// result alongside the parts. components is how many groups of boundaries
+// touch or overlap each other; 1 means the union is one connected area.
+// Arguments: project ids as a uuid array.
+func ProjectUnionSQL() string {
+	return `
+WITH src AS (
+  SELECT pg.project_id, pg.geometry::geometry AS geom, pg.area_hectares
+  FROM project_geometries pg
+  JOIN projects p ON p.id = pg.project_id AND p.deleted_at IS NULL
+  WHERE pg.project_id = ANY(?::uuid[])
+),
+merged AS (
+  SELECT ST_Multi(ST_CollectionExtract(ST_Union(geom), 3)) AS geom,
+         SUM(area_hectares) AS source_area_hectares,
+         array_length(ST_ClusterIntersecting(geom), 1) AS components,
+         array_agg(project_id::text) AS found
+  FROM src
+)
+SELECT ST_AsGeoJSON(geom),
+       ST_Area(geom::geography) * 0.0001,
+       source_area_hectares,
+       components,
+       ST_IsValid(geom),
+       CASE WHEN ST_IsValid(geom) THEN ARRAY[]::text[] ELSE ARRAY[ST_IsValidReason(geom)] END,
+       found
+FROM merged
+WHERE geom IS NOT NULL
+`
+}
+
+// MergedProjectSQL creates the project a merge is stored as. Type, location,
+// owner and organization come from the first source; tags are those of every
+// source. Arguments: new id, name, area in hectares, source ids as a uuid
+// array, first source id.
+func MergedProjectSQL() string {
+	return `
+INSERT INTO projects (id, name, type, location, area, status, verification_status, tags, owner_id, org_id, created_at, updated_at)
+SELECT ?, ?, p.type, p.location, ?, 'pending', 'draft',
+       ARRAY(SELECT DISTINCT t FROM projects q, unnest(q.tags) t WHERE q.id = ANY(?::uuid[]) ORDER BY t),
+       p.owner_id, p.org_id, NOW(), NOW()
+FROM projects p
+WHERE p.id = ? AND p.deleted_at IS NULL
+`
+}
@@ -0,0 +1,74 @@
+package queries
+
+import "fmt"
+
+// OverlapCandidatesSQL finds the live projects a new boundary may overlap in
+// three stages. The && bounding-box test uses the geometry index; candidates
+// whose boundaries, both simplified with ST_Simplify, are further apart than
+// twice the tolerance are rejected, since Douglas-Peucker keeps a simplified
+// ring within the tolerance of the original; only the remaining candidates get
+// the exact ST_Intersects and intersection area. A tolerance of 0 or less
+// skips the simplified stage.
+//
+// It takes the tolerance, the GeoJSON, then the tolerance three more times
+// and twice the tolerance. Each bbox candidate is returned with survived set
+// when it reached the exact comparison. It is never limited by the caller's
+// project scope: a boundary must not overlap any project, seen or not.
+const OverlapCandidatesSQL = `
+WITH input AS (
+  SELECT g, ST_Simplify(g, ?, true) AS simple
+  FROM (SELECT ST_SetSRID(ST_GeomFromGeoJSON(?), 4326) AS g) src
+),
+candidates AS (
+  SELECT pg.project_id,
+         pg.geometry::geometry AS geom,
+         p.owner_id,
+         CASE WHEN ? <= 0 THEN true
+              ELSE ST_DWithin(ST_Simplify(pg.geometry::geometry, ?, true), input.simple, ?)
+         END AS survived
+  FROM project_geometries pg
+  JOIN projects p ON p.id = pg.project_id
+  CROSS JOIN input
+  WHERE p.deleted_at IS NULL
+    AND pg.geometry::geometry && input.g
+)
+SELECT c.project_id,
+       c.survived,
+       x.intersects,
+       CASE WHEN x.intersects
+            THEN ST_Area(ST_Intersection(c.geom, input.g)::geography) * 0.0001
+            ELSE 0
+       END AS intersection_area_hectares,
+       c.owner_id
+FROM candidates c
+CROSS JOIN input
+CROSS JOIN LATERAL (
+  SELECT CASE WHEN c.survived THEN ST_Intersects(c.geom, input.g) ELSE false END AS intersects
+) x
+`
+
+// OverlapMeasuresSQL measures each live project whose boundary shares area
+// with a proposed one: both areas and the intersection area in hectares, and
+// whether either boundary covers the other. It takes the GeoJSON once. filter
+// is appended to the WHERE clause and is empty or a ProjectScopeFilter
+// predicate.
+func OverlapMeasuresSQL(filter string) string {
+	return fmt.Sprintf(`
+WITH input AS (
+  SELECT ST_SetSRID(ST_GeomFromGeoJSON(?), 4326) AS g
+)
+SELECT pg.project_id,
+       ST_Area(input.g::geography) * 0.0001 AS new_area_hectares,
+       pg.area_hectares AS existing_area_hectares,
+       ST_Area(ST_Intersection(pg.geometry::geometry, input.g)::geography) * 0.0001 AS overlap_area_hectares,
+       ST_Covers(input.g, pg.geometry::geometry) AS new_covers_existing,
+       ST_Covers(pg.geometry::geometry, input.g) AS existing_covers_new
+FROM project_geometries pg
+JOIN projects p ON p.id = pg.project_id
+CROSS JOIN input
+WHERE p.deleted_at IS NULL
+  AND pg.geometry::geometry && input.g
+  AND ST_Relate(pg.geometry::geometry, input.g, '2********')%s
+ORDER BY overlap_area_hectares DESC
+`, filter)
+}
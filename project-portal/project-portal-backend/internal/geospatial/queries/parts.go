@@ -0,0 +1,18 @@
+package queries
+
+// GeometryPartsSQL returns each polygon of a stored project boundary, in the
+// order of the MultiPolygon, as its 1-based part number, GeoJSON and area in
+// hectares. A plain Polygon dumps with an empty path and is part 1. A
+// project without a boundary yields no rows.
+// Arguments: project id.
+func GeometryPartsSQL() string {
+	return `
+SELECT COALESCE((d.path)[1], 1) AS part,
+       ST_AsGeoJSON(d.geom),
+       ST_Area(d.geom::geography) * 0.0001
+FROM project_geometries pg
+CROSS JOIN LATERAL ST_Dump(pg.geometry::geometry) AS d
+WHERE pg.project_id = ?
+ORDER BY part
+`
+}
@@ -0,0 +1,22 @@
+package queries
+
+// ProjectPerimeterSQL measures a stored project boundary in meters on the
+// spheroid. MultiPolygon parts are summed. With exteriorOnly the interior
+// rings (holes) are left out, which is what fencing estimates need.
+func ProjectPerimeterSQL(exteriorOnly bool) string {
+	if !exteriorOnly {
+		return `
+SELECT ST_Perimeter(pg.geometry)
+FROM project_geometries pg
+WHERE pg.project_id = ?
+`
+	}
+	return `
+SELECT (
+  SELECT COALESCE(SUM(ST_Length(ST_ExteriorRing(d.geom)::geography)), 0)
+  FROM ST_Dump(pg.geometry::geometry) AS d
+)
+FROM project_geometries pg
+WHERE pg.project_id = ?
+`
+}
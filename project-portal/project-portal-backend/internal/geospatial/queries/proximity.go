@@ -2,7 +2,10 @@ package queries
 
 import "fmt"
 
-func NearbyProjectsSQL(limit int) string {
+// NearbyProjectsSQL selects the projects whose centroid lies within a radius
+// of a point, nearest first. filter is appended to the WHERE clause and is
+// empty or a ProjectScopeFilter predicate.
+func NearbyProjectsSQL(limit int, filter string) string {
 	if limit <= 0 {
 		limit = 20
 	}
@@ -14,12 +17,24 @@ SELECT p.id AS project_id,
 FROM project_geometries pg
 JOIN projects p ON p.id = pg.project_id
 WHERE ST_DWithin(pg.centroid::geometry::geography, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geometry::geography, ?)
+  AND p.deleted_at IS NULL%s
 ORDER BY distance_meters ASC
 LIMIT %d
-`, limit)
+`, filter, limit)
 }
 
-func WithinBBoxSQL(limit int) string {
+// TagFilterSQL returns a predicate on p.tags taking one text[] argument.
+// matchAll requires every tag (@>); otherwise any overlap (&&) matches.
+func TagFilterSQL(matchAll bool) string {
+	if matchAll {
+		return "\n  AND p.tags @> ?::text[]"
+	}
+	return "\n  AND p.tags && ?::text[]"
+}
+
+// WithinBBoxSQL selects projects intersecting an envelope. filter is appended
+// to the WHERE clause and is built from TagFilterSQL and ProjectScopeFilter.
+func WithinBBoxSQL(limit int, filter string) string {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -33,11 +48,13 @@ WHERE ST_Intersects(
   pg.geometry::geometry,
   ST_MakeEnvelope(?, ?, ?, ?, 4326)
 )
+  AND p.deleted_at IS NULL%s
 LIMIT %d
-`, limit)
+`, filter, limit)
 }
 
-func WithinPolygonSQL(limit int) string {
+// WithinPolygonSQL is WithinBBoxSQL for a GeoJSON search area.
+func WithinPolygonSQL(limit int, filter string) string {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -51,6 +68,30 @@ WHERE ST_Intersects(
   pg.geometry::geometry,
   ST_SetSRID(ST_GeomFromGeoJSON(?), 4326)
 )
+  AND p.deleted_at IS NULL%s
 LIMIT %d
-`, limit)
+`, filter, limit)
+}
+
+// ProjectsAtPointSQL selects the live projects whose boundary covers a point,
+// taking lon and lat. Points on a ring match and are flagged on_boundary;
+// points inside a hole are outside the polygon and do not match. The
+// geometry cast uses the idx_project_geometries_geometry_geom index. filter is
+// appended to the WHERE clause and is empty or a ProjectScopeFilter predicate.
+func ProjectsAtPointSQL(filter string) string {
+	return fmt.Sprintf(`
+WITH pt AS (
+  SELECT ST_SetSRID(ST_MakePoint(?, ?), 4326) AS geom
+)
+SELECT p.id AS project_id,
+       p.name,
+       pg.area_hectares,
+       ST_Intersects(ST_Boundary(pg.geometry::geometry), pt.geom) AS on_boundary
+FROM project_geometries pg
+JOIN projects p ON p.id = pg.project_id
+CROSS JOIN pt
+WHERE ST_Intersects(pg.geometry::geometry, pt.geom)
+  AND p.deleted_at IS NULL%s
+ORDER BY pg.area_hectares ASC, p.id
+`, filter)
 }
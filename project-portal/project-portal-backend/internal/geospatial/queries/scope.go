@@ -0,0 +1,24 @@
+package queries
+
+import "fmt"
+
+// ProjectScopeFilter limits a query joining projects p to the projects of one
+// organization or owner. column is "org_id" or "owner_id", as returned by
+// project.Restricts; the predicate takes the ID.
+func ProjectScopeFilter(column string) string {
+	return "\n  AND p." + column + " = ?"
+}
+
+// ProjectInScopeSQL reports whether a live project exists, taking its id.
+// filter is appended to the WHERE clause and is empty or a
+// ProjectScopeFilter predicate.
+func ProjectInScopeSQL(filter string) string {
+	return fmt.Sprintf(`
+SELECT EXISTS (
+  SELECT 1
+  FROM projects p
+  WHERE p.id = ?
+    AND p.deleted_at IS NULL%s
+)
+`, filter)
+}
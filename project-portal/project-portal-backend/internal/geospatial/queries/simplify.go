@@ -0,0 +1,27 @@
+package queries
+
+// SimplifyPreviewSQL simplifies a stored boundary the way an upload with a
+// simplification tolerance would, without writing it, and measures both.
+// Arguments: tolerance in degrees, project id.
+func SimplifyPreviewSQL() string {
+	return `
+WITH simplified AS (
+  SELECT version,
+         geometry::geometry AS geom,
+         ST_Multi(ST_SimplifyPreserveTopology(geometry::geometry, ?::double precision)) AS simplified
+  FROM project_geometries
+  WHERE project_id = ?
+)
+SELECT version,
+       ST_AsGeoJSON(simplified),
+       ST_NPoints(geom),
+       ST_NPoints(simplified),
+       ST_Area(geom::geography) * 0.0001,
+       ST_Area(simplified::geography) * 0.0001,
+       NOT ST_IsEmpty(simplified) AND ST_IsValid(simplified),
+       CASE WHEN ST_IsEmpty(simplified) THEN 'collapsed to an empty geometry'
+            WHEN ST_IsValid(simplified) THEN ''
+            ELSE ST_IsValidReason(simplified) END
+FROM simplified
+`
+}
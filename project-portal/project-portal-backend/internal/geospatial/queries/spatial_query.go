@@ -15,6 +15,7 @@ SELECT id,
        source_type,
        source_file,
        accuracy_score,
+       winding_corrected,
        version,
        previous_version_id,
        created_at,
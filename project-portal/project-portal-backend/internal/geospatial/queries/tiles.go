@@ -0,0 +1,41 @@
+package queries
+
+import "fmt"
+
+// ProjectTileSQL returns one Mapbox Vector Tile holding the live project
+// boundaries that touch tile z/x/y, as a single bytea column. Boundaries are
+// projected to Web Mercator, simplified to the given tolerance in metres and
+// clipped to the tile plus a buffer, so polygons do not show seams at tile
+// edges. The layer is named "projects" and carries id, name and type. filter
+// is appended to the WHERE clause and is empty or a ProjectScopeFilter
+// predicate.
+//
+// Arguments: z, x, y, tolerance, extent, buffer, then filter's.
+func ProjectTileSQL(filter string) string {
+	return fmt.Sprintf(`
+WITH bounds AS (
+    SELECT ST_TileEnvelope(?, ?, ?) AS env
+),
+params AS (
+    SELECT ?::float8 AS tolerance, ?::int AS extent, ?::int AS buffer
+),
+features AS (
+    SELECT p.id::text AS id,
+           p.name,
+           p.type,
+           ST_AsMVTGeom(
+               ST_SimplifyPreserveTopology(ST_Transform(pg.geometry::geometry, 3857), params.tolerance),
+               bounds.env, params.extent, params.buffer, true
+           ) AS geom
+    FROM project_geometries pg
+    JOIN projects p ON p.id = pg.project_id
+    CROSS JOIN bounds
+    CROSS JOIN params
+    WHERE p.deleted_at IS NULL
+      AND pg.geometry::geometry && ST_Transform(bounds.env, 4326)%s
+)
+SELECT COALESCE(ST_AsMVT(features.*, 'projects', (SELECT extent FROM params), 'geom'), ''::bytea)
+FROM features
+WHERE geom IS NOT NULL
+`, filter)
+}
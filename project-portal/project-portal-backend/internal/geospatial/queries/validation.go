@@ -0,0 +1,44 @@
+package queries
+
+// GeometryCheckSQL inspects a GeoJSON geometry without storing it. Besides
+// ST_IsValid it returns where ST_IsValidDetail places the problem, the
+// vertex count, whether the rings need rewinding to RFC 7946 order, whether
+// a valid boundary touches itself (a hole meeting its shell, or parts
+// meeting at a point) and how many parts are slivers: parts whose
+// Polsby-Popper compactness, 4π·area/perimeter², is below the threshold.
+// The sliver location is a point on the thinnest one.
+//
+// Arguments: GeoJSON geometry, sliver compactness threshold.
+func GeometryCheckSQL() string {
+	return `
+WITH input AS (
+  SELECT ST_SetSRID(ST_GeomFromGeoJSON(?), 4326) AS g, ?::double precision AS sliver_threshold
+),
+checked AS (
+  SELECT g, sliver_threshold, ST_IsValidDetail(g) AS detail FROM input
+),
+parts AS (
+  SELECT d.geom,
+         4 * pi() * ST_Area(d.geom::geography) / NULLIF(ST_Perimeter(d.geom::geography) ^ 2, 0) AS compactness
+  FROM checked, ST_Dump(checked.g) AS d
+  WHERE (checked.detail).valid
+),
+slivers AS (
+  SELECT geom, compactness FROM parts, checked
+  WHERE parts.compactness < checked.sliver_threshold
+)
+SELECT
+  (detail).valid,
+  COALESCE((detail).reason, ''),
+  ST_X((detail).location),
+  ST_Y((detail).location),
+  ST_Area(g::geography) * 0.0001,
+  ST_NPoints(g),
+  NOT ST_IsPolygonCCW(g),
+  (detail).valid AND NOT ST_IsSimple(ST_Boundary(g)),
+  (SELECT COUNT(*) FROM slivers),
+  (SELECT ST_X(ST_PointOnSurface(geom)) FROM slivers ORDER BY compactness LIMIT 1),
+  (SELECT ST_Y(ST_PointOnSurface(geom)) FROM slivers ORDER BY compactness LIMIT 1)
+FROM checked
+`
+}
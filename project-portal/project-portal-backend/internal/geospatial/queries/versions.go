@@ -0,0 +1,41 @@
+package queries
+
+// GeometryVersionsSQL lists the stored boundary versions of a project.
+func GeometryVersionsSQL() string {
+	return `
+SELECT project_id, version, area_hectares, created_at
+FROM project_geometry_versions
+WHERE project_id = ?
+ORDER BY version
+`
+}
+
+// GeometryDiffSQL compares two boundary versions of a project. Added is what
+// the later version covers and the earlier did not; removed is the reverse.
+// Arguments: project id, from version, to version.
+func GeometryDiffSQL() string {
+	return `
+WITH pair AS (
+  SELECT a.geometry::geometry AS before_geom,
+         b.geometry::geometry AS after_geom,
+         a.area_hectares AS before_area,
+         b.area_hectares AS after_area
+  FROM project_geometry_versions a
+  JOIN project_geometry_versions b ON b.project_id = a.project_id
+  WHERE a.project_id = ? AND a.version = ? AND b.version = ?
+),
+diff AS (
+  SELECT ST_Difference(after_geom, before_geom) AS added,
+         ST_Difference(before_geom, after_geom) AS removed,
+         before_area,
+         after_area
+  FROM pair
+)
+SELECT ST_AsGeoJSON(added) AS added_geo_json,
+       ST_AsGeoJSON(removed) AS removed_geo_json,
+       ST_Area(added::geography) * 0.0001 AS added_hectares,
+       ST_Area(removed::geography) * 0.0001 AS removed_hectares,
+       after_area - before_area AS net_change_hectares
+FROM diff
+`
+}
@@ -0,0 +1,177 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/geometry"
+	pkggeojson "carbon-scribe/project-portal/project-portal-backend/pkg/geojson"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownReferenceLayer is returned for a layer name nothing was
+	// stored under.
+	ErrUnknownReferenceLayer = errors.New("unknown reference layer")
+	ErrInvalidLayerName      = errors.New("layer name must be 1-63 lowercase letters, digits, '-' or '_'")
+)
+
+// layerName is what a reference layer may be called, so names are safe in
+// URLs and stable across environments.
+var layerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ReferenceLayer is a named set of polygons projects are checked against,
+// such as protected areas or indigenous territories.
+type ReferenceLayer struct {
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	FeatureCount int       `json:"feature_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PutReferenceLayerRequest replaces a reference layer's polygons with the
+// Polygon and MultiPolygon features of a FeatureCollection. properties.name,
+// when present, names each feature.
+type PutReferenceLayerRequest struct {
+	Description string          `json:"description,omitempty" binding:"max=500"`
+	GeoJSON     json.RawMessage `json:"geojson" binding:"required"`
+}
+
+// ReferenceFeature is one polygon of a reference layer, as stored.
+type ReferenceFeature struct {
+	Name     string
+	Geometry json.RawMessage // MultiPolygon in the storage SRID
+}
+
+// ReferenceFeatureOverlap is how much of a project one reference feature
+// covers.
+type ReferenceFeatureOverlap struct {
+	ID                       uuid.UUID `json:"id"`
+	Name                     string    `json:"name"`
+	IntersectionAreaHectares float64   `json:"intersection_area_hectares"`
+}
+
+// ReferenceLayerIntersection is the part of a project's boundary covered by
+// a reference layer. Where the layer's features overlap each other the
+// shared area is counted once, so IntersectionAreaHectares can be less than
+// the sum over Features.
+type ReferenceLayerIntersection struct {
+	ProjectID                uuid.UUID                 `json:"project_id"`
+	Layer                    string                    `json:"layer"`
+	Intersects               bool                      `json:"intersects"`
+	ProjectAreaHectares      float64                   `json:"project_area_hectares"`
+	IntersectionAreaHectares float64                   `json:"intersection_area_hectares"`
+	IntersectionPercent      float64                   `json:"intersection_percent"`
+	Features                 []ReferenceFeatureOverlap `json:"features"`
+}
+
+// PutReferenceLayer creates reference layer name or replaces its polygons.
+func (s *service) PutReferenceLayer(ctx context.Context, name string, req PutReferenceLayerRequest) (*ReferenceLayer, error) {
+	if !layerName.MatchString(name) {
+		return nil, ErrInvalidLayerName
+	}
+	features, err := s.referenceFeatures(ctx, req.GeoJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	layer, err := dbCall(ctx, s, weightBulk, func() (*ReferenceLayer, error) {
+		return s.repo.ReplaceReferenceLayer(ctx, name, req.Description, features)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("reference layer stored", "layer", name, "features", layer.FeatureCount)
+	return layer, nil
+}
+
+// referenceFeatures decodes the polygons of a reference layer upload. Unlike
+// project boundaries, features are not checked for validity: published
+// reference data is taken as it is and repaired when stored.
+func (s *service) referenceFeatures(ctx context.Context, raw json.RawMessage) ([]ReferenceFeature, error) {
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Properties struct {
+				Name string `json:"name"`
+			} `json:"properties"`
+			Geometry json.RawMessage `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(raw, &collection); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("geojson must be a FeatureCollection")
+	}
+	if len(collection.Features) == 0 {
+		return nil, fmt.Errorf("a reference layer needs at least one feature")
+	}
+	srid, err := geometry.DetectSRID(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]ReferenceFeature, 0, len(collection.Features))
+	for i, f := range collection.Features {
+		if err := pkggeojson.ValidateRFC7946(f.Geometry); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+		var kind struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal(f.Geometry, &kind)
+		if kind.Type != "Polygon" && kind.Type != "MultiPolygon" {
+			return nil, fmt.Errorf("feature %d: expected a Polygon or MultiPolygon, got %q", i, kind.Type)
+		}
+		geom, err := s.toStorageSRID(ctx, f.Geometry, srid)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+		if geom, err = geometry.ToMultiPolygon(geom); err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+		name := f.Properties.Name
+		if name == "" {
+			name = fmt.Sprintf("feature %d", i+1)
+		}
+		features = append(features, ReferenceFeature{Name: name, Geometry: geom})
+	}
+	return features, nil
+}
+
+func (s *service) ListReferenceLayers(ctx context.Context) ([]ReferenceLayer, error) {
+	return dbCall(ctx, s, weightQuery, func() ([]ReferenceLayer, error) {
+		return s.repo.ListReferenceLayers(ctx)
+	})
+}
+
+// IntersectReferenceLayer measures how much of projectID's boundary lies in
+// reference layer name. An unknown layer yields ErrUnknownReferenceLayer and
+// a project without a boundary sql.ErrNoRows.
+func (s *service) IntersectReferenceLayer(ctx context.Context, projectID uuid.UUID, name string) (*ReferenceLayerIntersection, error) {
+	if !layerName.MatchString(name) {
+		return nil, ErrUnknownReferenceLayer
+	}
+	out, err := dbCall(ctx, s, weightQuery, func() (*ReferenceLayerIntersection, error) {
+		return s.repo.IntersectReferenceLayer(ctx, projectID, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Features == nil {
+		out.Features = []ReferenceFeatureOverlap{}
+	}
+	out.Intersects = len(out.Features) > 0
+	if out.ProjectAreaHectares > 0 {
+		out.IntersectionPercent = out.IntersectionAreaHectares / out.ProjectAreaHectares * 100
+	}
+	return out, nil
+}
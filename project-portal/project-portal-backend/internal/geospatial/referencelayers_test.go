@@ -0,0 +1,137 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// referenceRepo keeps reference layers in memory. Boundaries and features
+// are axis-aligned boxes, so intersections are boxes too.
+type referenceRepo struct {
+	Repository
+	boundaries map[uuid.UUID][4]float64
+	layers     map[string][]ReferenceFeature
+}
+
+// boxOf is the bounding box of a MultiPolygon's outer rings.
+func boxOf(raw json.RawMessage) [4]float64 {
+	var multi struct {
+		Coordinates [][][][2]float64 `json:"coordinates"`
+	}
+	json.Unmarshal(raw, &multi)
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, polygon := range multi.Coordinates {
+		for _, p := range polygon[0] {
+			box = [4]float64{math.Min(box[0], p[0]), math.Min(box[1], p[1]), math.Max(box[2], p[0]), math.Max(box[3], p[1])}
+		}
+	}
+	return box
+}
+
+func boxHectares(b [4]float64) float64 {
+	if b[2] <= b[0] || b[3] <= b[1] {
+		return 0
+	}
+	return (b[2] - b[0]) * (b[3] - b[1]) * 1e6
+}
+
+func (r *referenceRepo) ReplaceReferenceLayer(_ context.Context, name, description string, features []ReferenceFeature) (*ReferenceLayer, error) {
+	r.layers[name] = features
+	return &ReferenceLayer{Name: name, Description: description, FeatureCount: len(features)}, nil
+}
+
+func (r *referenceRepo) IntersectReferenceLayer(_ context.Context, projectID uuid.UUID, layer string) (*ReferenceLayerIntersection, error) {
+	features, ok := r.layers[layer]
+	if !ok {
+		return nil, ErrUnknownReferenceLayer
+	}
+	box, ok := r.boundaries[projectID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	out := &ReferenceLayerIntersection{ProjectID: projectID, Layer: layer, ProjectAreaHectares: boxHectares(box)}
+	for _, f := range features {
+		fb := boxOf(f.Geometry)
+		ha := boxHectares([4]float64{math.Max(box[0], fb[0]), math.Max(box[1], fb[1]), math.Min(box[2], fb[2]), math.Min(box[3], fb[3])})
+		if ha > 0 {
+			out.Features = append(out.Features, ReferenceFeatureOverlap{ID: uuid.New(), Name: f.Name, IntersectionAreaHectares: ha})
+			out.IntersectionAreaHectares += ha
+		}
+	}
+	return out, nil
+}
+
+func TestIntersectReferenceLayer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	partial, apart, bare := uuid.New(), uuid.New(), uuid.New()
+	repo := &referenceRepo{
+		boundaries: map[uuid.UUID][4]float64{partial: {36.0, -1.02, 36.02, -1.0}, apart: {37.0, -1.02, 37.02, -1.0}},
+		layers:     map[string][]ReferenceFeature{},
+	}
+	svc := NewService(repo)
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(svc).RegisterRoutes(router.Group("/api/v1"))
+
+	// A reserve covering the eastern half of the first project.
+	_, err := svc.PutReferenceLayer(context.Background(), "protected-areas", PutReferenceLayerRequest{
+		GeoJSON: json.RawMessage(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Reserve"},
+			"geometry":{"type":"Polygon","coordinates":[[[36.01,-1.03],[36.05,-1.03],[36.05,-0.99],[36.01,-0.99],[36.01,-1.03]]]}}]}`),
+	})
+	if err != nil {
+		t.Fatalf("PutReferenceLayer: %v", err)
+	}
+
+	get := func(projectID uuid.UUID, layer string) (*httptest.ResponseRecorder, ReferenceLayerIntersection) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+projectID.String()+"/reference-layers/"+layer+"/intersection", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var out ReferenceLayerIntersection
+		json.Unmarshal(w.Body.Bytes(), &out)
+		return w, out
+	}
+
+	w, got := get(partial, "protected-areas")
+	if w.Code != http.StatusOK {
+		t.Fatalf("partial overlap: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !got.Intersects || len(got.Features) != 1 || got.Features[0].Name != "Reserve" {
+		t.Errorf("expected the reserve to intersect, got %+v", got)
+	}
+	if math.Abs(got.IntersectionPercent-50) > 0.01 {
+		t.Errorf("expected half of the project inside the reserve, got %.3f%%", got.IntersectionPercent)
+	}
+
+	w, got = get(apart, "protected-areas")
+	if w.Code != http.StatusOK || got.Intersects || got.IntersectionAreaHectares != 0 || got.IntersectionPercent != 0 {
+		t.Errorf("no overlap: expected 200 with nothing covered, got %d %+v", w.Code, got)
+	}
+	if got.Features == nil {
+		t.Errorf("expected an empty features list, got %s", w.Body.String())
+	}
+
+	if w, _ := get(partial, "ramsar-sites"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown layer: expected 404, got %d", w.Code)
+	}
+	if w, _ := get(bare, "protected-areas"); w.Code != http.StatusNotFound {
+		t.Errorf("project without a boundary: expected 404, got %d", w.Code)
+	}
+
+	if _, err := svc.PutReferenceLayer(context.Background(), "Protected Areas", PutReferenceLayerRequest{GeoJSON: json.RawMessage(`{"type":"FeatureCollection","features":[]}`)}); err != ErrInvalidLayerName {
+		t.Errorf("expected ErrInvalidLayerName, got %v", err)
+	}
+	_, err = svc.PutReferenceLayer(context.Background(), "roads", PutReferenceLayerRequest{
+		GeoJSON: json.RawMessage(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[36.0,-1.0],[36.1,-1.1]]}}]}`),
+	})
+	if err == nil {
+		t.Error("expected a layer of lines to be refused")
+	}
+}
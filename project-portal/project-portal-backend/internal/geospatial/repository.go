@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/queries"
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/postgis"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -17,28 +19,96 @@ import (
 type Repository interface {
 	UpsertProjectGeometry(ctx context.Context, projectID uuid.UUID, req UploadGeometryRequest) (*ProjectGeometry, error)
 	GetProjectGeometry(ctx context.Context, projectID uuid.UUID) (*ProjectGeometry, error)
+	GetProjectType(ctx context.Context, projectID uuid.UUID) (string, error)
+	GetProjectOwner(ctx context.Context, projectID uuid.UUID) (*uuid.UUID, error)
+	ProjectInScope(ctx context.Context, projectID uuid.UUID) (bool, error)
+	MeasureAreaHectares(ctx context.Context, geometry json.RawMessage) (float64, error)
+	RecomputeAreas(ctx context.Context) (*AreaRecompute, error)
+	CountVertices(ctx context.Context, geometry json.RawMessage) (int, error)
+	TransformToStorageSRID(ctx context.Context, geometry json.RawMessage, srid int) (json.RawMessage, error)
+	ProjectPerimeter(ctx context.Context, projectID uuid.UUID, exteriorOnly bool) (float64, error)
+	BoundaryDistance(ctx context.Context, projectID uuid.UUID, lon, lat float64) (meters float64, inside bool, err error)
+	BoundingShapes(ctx context.Context, projectID uuid.UUID) (*BoundingShapes, error)
+	GeometryParts(ctx context.Context, projectID uuid.UUID) ([]GeometryPart, error)
+	CarbonInputs(ctx context.Context, projectID uuid.UUID) (*CarbonInputs, error)
+	ListGeometryVersions(ctx context.Context, projectID uuid.UUID) ([]GeometryVersion, error)
+	DiffGeometryVersions(ctx context.Context, projectID uuid.UUID, from, to int) (*GeometryDiff, error)
+	CheckGeometry(ctx context.Context, geometry json.RawMessage) (*GeometryCheck, error)
+	SnapToGrid(ctx context.Context, geometry json.RawMessage, size float64) (*SnapResult, error)
+	SimplifyPreview(ctx context.Context, projectID uuid.UUID, tolerance float64) (*SimplifyPreview, error)
 	GetProjectBoundary(ctx context.Context, projectID uuid.UUID, format string) (*BoundaryResponse, error)
 	FindNearby(ctx context.Context, q NearbyQuery) ([]NearbyProject, error)
 	FindWithin(ctx context.Context, q WithinQuery) ([]NearbyProject, error)
-	Intersect(ctx context.Context, geometry json.RawMessage) ([]IntersectResult, error)
+	ProjectsAtPoint(ctx context.Context, lon, lat float64) ([]ProjectAtPoint, error)
+	ProjectCentroids(ctx context.Context, minLon, minLat, maxLon, maxLat float64) ([]ProjectPoint, error)
+	ExportGeometries(ctx context.Context, q ExportQuery, fn func(ExportFeature) error) error
+	ProjectExtent(ctx context.Context, q ExtentQuery) (bounds [4]float64, count int, err error)
+	ProjectTile(ctx context.Context, t TileCoord, tolerance float64) ([]byte, error)
+	Intersect(ctx context.Context, geometry json.RawMessage, includeDeleted bool) ([]IntersectResult, error)
+	ClipToAOI(ctx context.Context, aoi json.RawMessage) ([]ClippedProject, error)
+	LockOverlapRegions(ctx context.Context, geometry json.RawMessage) error
+	OverlapCandidates(ctx context.Context, geometry json.RawMessage, tolerance float64) ([]IntersectResult, OverlapStats, error)
+	MeasureOverlaps(ctx context.Context, geometry json.RawMessage) ([]OverlapMeasure, error)
+	UnionProjectGeometries(ctx context.Context, projectIDs []uuid.UUID) (*ProjectUnion, error)
+	CreateMergedProject(ctx context.Context, projectID uuid.UUID, name string, sources []uuid.UUID, areaHectares float64) error
 
 	CreateGeofence(ctx context.Context, req CreateGeofenceRequest) (*Geofence, error)
 	CheckProjectGeofences(ctx context.Context, projectID uuid.UUID) ([]GeofenceCheckResult, error)
+	CreateSubArea(ctx context.Context, projectID uuid.UUID, req CreateSubAreaRequest) (*SubArea, error)
+	ListSubAreas(ctx context.Context, projectID uuid.UUID) ([]SubArea, float64, error)
+	ReplaceReferenceLayer(ctx context.Context, name, description string, features []ReferenceFeature) (*ReferenceLayer, error)
+	ListReferenceLayers(ctx context.Context) ([]ReferenceLayer, error)
+	IntersectReferenceLayer(ctx context.Context, projectID uuid.UUID, layer string) (*ReferenceLayerIntersection, error)
 	GetAdministrativeBoundaries(ctx context.Context, level int, countryCode string) ([]AdministrativeBoundary, error)
 
+	SpatialIndexExists(ctx context.Context, index string) (bool, error)
+	ExplainWithinBBox(ctx context.Context) (json.RawMessage, error)
+
 	GetCachedTile(ctx context.Context, tileKey string) ([]byte, string, bool, error)
 	PutCachedTile(ctx context.Context, tileKey string, data []byte, contentType, style string, z, x, y int, ttl time.Duration) error
+
+	InTransaction(ctx context.Context, fn func(tx Repository) error) error
 }
 
 type repository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	readDB *gorm.DB
 }
 
 func NewRepository(db *gorm.DB) Repository {
-	return &repository{db: db}
+	return &repository{db: db, readDB: db}
+}
+
+// NewReplicaAwareRepository sends pure spatial reads (nearby, within,
+// intersect, boundary lookups) to the client's read replica when one is
+// configured. Reads that must observe a just-committed write stay on the primary.
+func NewReplicaAwareRepository(client *postgis.Client) Repository {
+	return &repository{db: client.DB(), readDB: client.ReadDB()}
+}
+
+// InTransaction runs fn against a repository bound to a single transaction on
+// the primary. Any error returned by fn, or a panic, rolls back every write
+// made through tx.
+func (r *repository) InTransaction(ctx context.Context, fn func(tx Repository) error) error {
+	return postgis.WithTransaction(ctx, r.db, func(tx *gorm.DB) error {
+		return fn(&repository{db: tx, readDB: tx})
+	})
 }
 
+// UpsertProjectGeometry stores a new boundary version for projectID. A project
+// outside the project.Scope in ctx is refused with ErrProjectNotFound, so a
+// batch import cannot write to other callers' projects.
 func (r *repository) UpsertProjectGeometry(ctx context.Context, projectID uuid.UUID, req UploadGeometryRequest) (*ProjectGeometry, error) {
+	if _, _, restricted := project.Restricts(ctx); restricted {
+		visible, err := r.ProjectInScope(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, projectID)
+		}
+	}
+
 	sourceType := req.SourceType
 	if sourceType == "" {
 		sourceType = "manual"
@@ -53,20 +123,26 @@ func (r *repository) UpsertProjectGeometry(ctx context.Context, projectID uuid.U
 WITH input AS (
   SELECT ST_SetSRID(ST_GeomFromGeoJSON(?), 4326) AS raw_geom
 ),
-normalized AS (
+simplified AS (
   SELECT
-    CASE
+    ST_Multi(CASE
       WHEN ?::double precision IS NULL OR ?::double precision <= 0 THEN raw_geom
       ELSE ST_SimplifyPreserveTopology(raw_geom, ?::double precision)
-    END AS geom
+    END) AS geom
   FROM input
-)
+),
+-- RFC 7946 winding: exterior rings counter-clockwise, holes clockwise.
+normalized AS (
+  SELECT ST_ForcePolygonCCW(geom) AS geom, NOT ST_IsPolygonCCW(geom) AS winding_corrected
+  FROM simplified
+),
+-- area_hectares is filled in by the project_geometries_cache_area trigger.
+upserted AS (
 INSERT INTO project_geometries (
   project_id,
   geometry,
   centroid,
   bounding_box,
-  area_hectares,
   perimeter_meters,
   is_valid,
   validation_errors,
@@ -74,6 +150,7 @@ INSERT INTO project_geometries (
   source_type,
   source_file,
   accuracy_score,
+  winding_corrected,
   version,
   previous_version_id,
   created_at,
@@ -84,17 +161,14 @@ SELECT
   geom::geography,
   ST_Centroid(geom)::geography,
   ST_Envelope(geom)::geography,
-  ST_Area(geom::geography) * 0.0001,
-  CASE
-    WHEN GeometryType(geom) IN ('POLYGON', 'MULTIPOLYGON') THEN ST_Perimeter(geom::geography)
-    ELSE ST_Length(geom::geography)
-  END,
+  ST_Perimeter(geom::geography),
   ST_IsValid(geom),
   CASE WHEN ST_IsValid(geom) THEN ARRAY[]::text[] ELSE ARRAY[ST_IsValidReason(geom)] END,
   ?,
   ?,
   ?,
   ?,
+  winding_corrected,
   1,
   NULL,
   NOW(),
@@ -106,7 +180,6 @@ DO UPDATE SET
   geometry = EXCLUDED.geometry,
   centroid = EXCLUDED.centroid,
   bounding_box = EXCLUDED.bounding_box,
-  area_hectares = EXCLUDED.area_hectares,
   perimeter_meters = EXCLUDED.perimeter_meters,
   is_valid = EXCLUDED.is_valid,
   validation_errors = EXCLUDED.validation_errors,
@@ -114,25 +187,41 @@ DO UPDATE SET
   source_type = EXCLUDED.source_type,
   source_file = EXCLUDED.source_file,
   accuracy_score = EXCLUDED.accuracy_score,
+  winding_corrected = EXCLUDED.winding_corrected,
   version = project_geometries.version + 1,
   updated_at = NOW()
+RETURNING project_id, version, geometry, area_hectares, updated_at
+)
+INSERT INTO project_geometry_versions (project_id, version, geometry, area_hectares, created_at)
+SELECT project_id, version, geometry, area_hectares, updated_at FROM upserted
+ON CONFLICT (project_id, version) DO UPDATE SET
+  geometry = EXCLUDED.geometry,
+  area_hectares = EXCLUDED.area_hectares,
+  created_at = EXCLUDED.created_at
 `
-	if err := r.db.WithContext(ctx).Exec(
-		sqlStmt,
-		string(req.GeoJSON),
-		tolerance,
-		tolerance,
-		tolerance,
-		projectID,
-		req.SimplificationTolerance,
-		sourceType,
-		req.SourceFile,
-		req.AccuracyScore,
-	).Error; err != nil {
-		return nil, fmt.Errorf("upsert project geometry: %w", err)
-	}
-
-	return r.GetProjectGeometry(ctx, projectID)
+	// The write and the read-back share a transaction so the caller sees the
+	// row it just stored, not a concurrent upload's.
+	var stored *ProjectGeometry
+	err := postgis.WithTransaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			sqlStmt,
+			string(req.GeoJSON),
+			tolerance,
+			tolerance,
+			tolerance,
+			projectID,
+			req.SimplificationTolerance,
+			sourceType,
+			req.SourceFile,
+			req.AccuracyScore,
+		).Error; err != nil {
+			return fmt.Errorf("upsert project geometry: %w", err)
+		}
+		var err error
+		stored, err = (&repository{db: tx, readDB: tx}).GetProjectGeometry(ctx, projectID)
+		return err
+	})
+	return stored, err
 }
 
 func (r *repository) GetProjectGeometry(ctx context.Context, projectID uuid.UUID) (*ProjectGeometry, error) {
@@ -158,6 +247,7 @@ func (r *repository) GetProjectGeometry(ctx context.Context, projectID uuid.UUID
 		&out.SourceType,
 		&sourceFile,
 		&acc,
+		&out.WindingCorrected,
 		&out.Version,
 		&prevID,
 		&out.CreatedAt,
@@ -195,6 +285,335 @@ func (r *repository) GetProjectGeometry(ctx context.Context, projectID uuid.UUID
 	return &out, nil
 }
 
+func (r *repository) GetProjectType(ctx context.Context, projectID uuid.UUID) (string, error) {
+	var projectType string
+	row := r.readDB.WithContext(ctx).Raw(`SELECT type FROM projects WHERE id = ? AND deleted_at IS NULL`, projectID).Row()
+	if err := row.Scan(&projectType); err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("project %s not found", projectID)
+		}
+		return "", err
+	}
+	return projectType, nil
+}
+
+// GetProjectOwner returns the project's owner, or nil for projects created
+// before ownership was recorded. It reads the primary so it can run inside the
+// overlap transaction.
+func (r *repository) GetProjectOwner(ctx context.Context, projectID uuid.UUID) (*uuid.UUID, error) {
+	var owner *uuid.UUID
+	row := r.db.WithContext(ctx).Raw(`SELECT owner_id FROM projects WHERE id = ? AND deleted_at IS NULL`, projectID).Row()
+	if err := row.Scan(&owner); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("project %s not found", projectID)
+		}
+		return nil, err
+	}
+	return owner, nil
+}
+
+// ProjectInScope reports whether projectID is a live project the
+// project.Scope in ctx may see. It reads the primary so a project created a
+// moment ago is found.
+func (r *repository) ProjectInScope(ctx context.Context, projectID uuid.UUID) (bool, error) {
+	filter, args := scopeFilter(ctx)
+	var visible bool
+	err := r.db.WithContext(ctx).Raw(queries.ProjectInScopeSQL(filter), append([]interface{}{projectID}, args...)...).
+		Row().Scan(&visible)
+	return visible, err
+}
+
+// RecomputeAreas runs on the primary, where the corrections are written.
+func (r *repository) RecomputeAreas(ctx context.Context) (*AreaRecompute, error) {
+	out := &AreaRecompute{}
+	if err := r.db.WithContext(ctx).Raw(queries.RecomputeAreasSQL()).Row().Scan(&out.Checked, &out.Corrected); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MeasureAreaHectares computes the geodesic area of a GeoJSON geometry without
+// storing it.
+func (r *repository) MeasureAreaHectares(ctx context.Context, geometry json.RawMessage) (float64, error) {
+	var area float64
+	row := r.readDB.WithContext(ctx).Raw(`SELECT ST_Area(ST_SetSRID(ST_GeomFromGeoJSON(?), 4326)::geography) * 0.0001`, string(geometry)).Row()
+	if err := row.Scan(&area); err != nil {
+		return 0, err
+	}
+	return area, nil
+}
+
+// TransformToStorageSRID reprojects a GeoJSON geometry whose coordinates are
+// in srid to WGS 84 longitude/latitude.
+func (r *repository) TransformToStorageSRID(ctx context.Context, geometry json.RawMessage, srid int) (json.RawMessage, error) {
+	var out string
+	row := r.readDB.WithContext(ctx).Raw(`SELECT ST_AsGeoJSON(ST_Transform(ST_SetSRID(ST_GeomFromGeoJSON(?), ?), 4326))`, string(geometry), srid).Row()
+	if err := row.Scan(&out); err != nil {
+		return nil, fmt.Errorf("reproject geometry from EPSG:%d: %w", srid, err)
+	}
+	return json.RawMessage(out), nil
+}
+
+// SimplifyPreview yields sql.ErrNoRows for a project without a boundary.
+// It reads the primary so a confirmation sees the version it previewed.
+func (r *repository) SimplifyPreview(ctx context.Context, projectID uuid.UUID, tolerance float64) (*SimplifyPreview, error) {
+	var preview SimplifyPreview
+	var geojson string
+	err := r.db.WithContext(ctx).Raw(queries.SimplifyPreviewSQL(), tolerance, projectID).Row().Scan(
+		&preview.Version, &geojson,
+		&preview.VerticesBefore, &preview.VerticesAfter,
+		&preview.AreaBeforeHectares, &preview.AreaAfterHectares,
+		&preview.IsValid, &preview.Reason,
+	)
+	if err != nil {
+		return nil, err
+	}
+	preview.Geometry = json.RawMessage(geojson)
+	return &preview, nil
+}
+
+// SnapToGrid rounds every vertex to a grid of the given size, dropping the
+// consecutive duplicates that produces, and re-checks validity.
+func (r *repository) SnapToGrid(ctx context.Context, geometry json.RawMessage, size float64) (*SnapResult, error) {
+	var row struct {
+		GeoJSON        string
+		VerticesBefore int
+		VerticesAfter  int
+		IsValid        bool
+		Reason         string
+	}
+	err := r.db.WithContext(ctx).Raw(`
+WITH input AS (
+  SELECT ST_SetSRID(ST_GeomFromGeoJSON(?), 4326) AS geom
+),
+snapped AS (
+  SELECT geom, ST_Multi(ST_SnapToGrid(geom, ?::double precision)) AS snapped FROM input
+)
+SELECT ST_AsGeoJSON(snapped) AS geo_json,
+       ST_NPoints(geom) AS vertices_before,
+       ST_NPoints(snapped) AS vertices_after,
+       NOT ST_IsEmpty(snapped) AND ST_IsValid(snapped) AS is_valid,
+       CASE WHEN ST_IsEmpty(snapped) THEN 'collapsed to an empty geometry'
+            WHEN ST_IsValid(snapped) THEN ''
+            ELSE ST_IsValidReason(snapped) END AS reason
+FROM snapped`, string(geometry), size).Scan(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("snap geometry to grid: %w", err)
+	}
+	return &SnapResult{
+		GeoJSON:        json.RawMessage(row.GeoJSON),
+		VerticesBefore: row.VerticesBefore,
+		VerticesAfter:  row.VerticesAfter,
+		IsValid:        row.IsValid,
+		Reason:         row.Reason,
+	}, nil
+}
+
+// CountVertices returns ST_NPoints of a GeoJSON geometry.
+func (r *repository) CountVertices(ctx context.Context, geometry json.RawMessage) (int, error) {
+	var n int
+	row := r.readDB.WithContext(ctx).Raw(`SELECT ST_NPoints(ST_GeomFromGeoJSON(?))`, string(geometry)).Row()
+	if err := row.Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// CheckGeometry runs ST_IsValidDetail and the other checks of
+// queries.GeometryCheckSQL without writing anything.
+func (r *repository) CheckGeometry(ctx context.Context, geometry json.RawMessage) (*GeometryCheck, error) {
+	var out GeometryCheck
+	var x, y, sliverX, sliverY sql.NullFloat64
+	row := r.readDB.WithContext(ctx).Raw(queries.GeometryCheckSQL(), string(geometry), SliverCompactness).Row()
+	if err := row.Scan(&out.IsValid, &out.Reason, &x, &y, &out.AreaHectares, &out.Vertices,
+		&out.NeedsRewinding, &out.SelfTouching, &out.Slivers, &sliverX, &sliverY); err != nil {
+		return nil, err
+	}
+	out.Location = lonLat(x, y)
+	out.SliverLocation = lonLat(sliverX, sliverY)
+	return &out, nil
+}
+
+// lonLat pairs two nullable coordinates, or returns nil if either is NULL.
+func lonLat(x, y sql.NullFloat64) *[2]float64 {
+	if !x.Valid || !y.Valid {
+		return nil
+	}
+	return &[2]float64{x.Float64, y.Float64}
+}
+
+func (r *repository) ListGeometryVersions(ctx context.Context, projectID uuid.UUID) ([]GeometryVersion, error) {
+	rows, err := r.db.WithContext(ctx).Raw(queries.GeometryVersionsSQL(), projectID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]GeometryVersion, 0)
+	for rows.Next() {
+		var v GeometryVersion
+		var id uuid.UUID
+		if err := rows.Scan(&id, &v.Version, &v.AreaHectares, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// DiffGeometryVersions returns gorm.ErrRecordNotFound when either version is
+// missing.
+func (r *repository) DiffGeometryVersions(ctx context.Context, projectID uuid.UUID, from, to int) (*GeometryDiff, error) {
+	var row struct {
+		AddedGeoJSON      string
+		RemovedGeoJSON    string
+		AddedHectares     float64
+		RemovedHectares   float64
+		NetChangeHectares float64
+	}
+	res := r.db.WithContext(ctx).Raw(queries.GeometryDiffSQL(), projectID, from, to).Scan(&row)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &GeometryDiff{
+		ProjectID:         projectID,
+		FromVersion:       from,
+		ToVersion:         to,
+		Added:             json.RawMessage(row.AddedGeoJSON),
+		Removed:           json.RawMessage(row.RemovedGeoJSON),
+		AddedHectares:     row.AddedHectares,
+		RemovedHectares:   row.RemovedHectares,
+		NetChangeHectares: row.NetChangeHectares,
+	}, nil
+}
+
+// UnionProjectGeometries unions the boundaries of the live projects among
+// projectIDs. It yields sql.ErrNoRows when none of them has a boundary.
+func (r *repository) UnionProjectGeometries(ctx context.Context, projectIDs []uuid.UUID) (*ProjectUnion, error) {
+	var (
+		union            ProjectUnion
+		geojson          string
+		validationErrors pq.StringArray
+		found            pq.StringArray
+	)
+	err := r.readDB.WithContext(ctx).Raw(queries.ProjectUnionSQL(), uuidArray(projectIDs)).Row().Scan(
+		&geojson, &union.AreaHectares, &union.SourceAreaHectares, &union.Components,
+		&union.IsValid, &validationErrors, &found,
+	)
+	if err != nil {
+		return nil, err
+	}
+	union.Geometry = json.RawMessage(geojson)
+	union.ValidationErrors = validationErrors
+	for _, id := range found {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, err
+		}
+		union.Found = append(union.Found, parsed)
+	}
+	return &union, nil
+}
+
+// CreateMergedProject inserts the project a merge of sources is stored as.
+// It yields sql.ErrNoRows when the first source is not a live project.
+func (r *repository) CreateMergedProject(ctx context.Context, projectID uuid.UUID, name string, sources []uuid.UUID, areaHectares float64) error {
+	result := r.db.WithContext(ctx).Exec(queries.MergedProjectSQL(), projectID, name, areaHectares, uuidArray(sources), sources[0])
+	if result.Error != nil {
+		return fmt.Errorf("create merged project: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func uuidArray(ids []uuid.UUID) pq.StringArray {
+	out := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
+// BoundaryDistance yields sql.ErrNoRows for a project without a boundary.
+func (r *repository) BoundaryDistance(ctx context.Context, projectID uuid.UUID, lon, lat float64) (float64, bool, error) {
+	var meters float64
+	var inside bool
+	err := r.readDB.WithContext(ctx).Raw(queries.BoundaryDistanceSQL(), lon, lat, projectID).Row().Scan(&meters, &inside)
+	return meters, inside, err
+}
+
+// BoundingShapes yields sql.ErrNoRows for a project without a boundary.
+func (r *repository) BoundingShapes(ctx context.Context, projectID uuid.UUID) (*BoundingShapes, error) {
+	var out BoundingShapes
+	var circle, rectangle string
+	err := r.readDB.WithContext(ctx).Raw(queries.BoundingShapesSQL(), projectID).Row().Scan(
+		&out.Center[0], &out.Center[1], &out.RadiusMeters, &circle,
+		&rectangle, &out.RectangleHectares, &out.SideMeters[0], &out.SideMeters[1],
+		&out.BoundaryHectares,
+	)
+	if err != nil {
+		return nil, err
+	}
+	out.Circle, out.Rectangle = json.RawMessage(circle), json.RawMessage(rectangle)
+	return &out, nil
+}
+
+// GeometryParts yields sql.ErrNoRows for a project without a boundary.
+func (r *repository) GeometryParts(ctx context.Context, projectID uuid.UUID) ([]GeometryPart, error) {
+	rows, err := r.readDB.WithContext(ctx).Raw(queries.GeometryPartsSQL(), projectID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []GeometryPart
+	for rows.Next() {
+		var p GeometryPart
+		var geom string
+		if err := rows.Scan(&p.Part, &geom, &p.AreaHectares); err != nil {
+			return nil, err
+		}
+		p.Geometry = json.RawMessage(geom)
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return out, nil
+}
+
+// CarbonInputs yields sql.ErrNoRows for a project without a boundary.
+func (r *repository) CarbonInputs(ctx context.Context, projectID uuid.UUID) (*CarbonInputs, error) {
+	var out CarbonInputs
+	var tags pq.StringArray
+	err := r.readDB.WithContext(ctx).Raw(queries.CarbonInputsSQL(), projectID).Row().Scan(&out.AreaHectares, &out.ProjectType, &tags)
+	if err != nil {
+		return nil, err
+	}
+	out.Tags = tags
+	return &out, nil
+}
+
+func (r *repository) ProjectPerimeter(ctx context.Context, projectID uuid.UUID, exteriorOnly bool) (float64, error) {
+	var perimeter float64
+	row := r.readDB.WithContext(ctx).Raw(queries.ProjectPerimeterSQL(exteriorOnly), projectID).Row()
+	if err := row.Scan(&perimeter); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("geometry for project %s: %w", projectID, err)
+		}
+		return 0, err
+	}
+	return perimeter, nil
+}
+
 func (r *repository) GetProjectBoundary(ctx context.Context, projectID uuid.UUID, format string) (*BoundaryResponse, error) {
 	if format == "" {
 		format = BoundaryFormatGeoJSON
@@ -204,28 +623,36 @@ func (r *repository) GetProjectBoundary(ctx context.Context, projectID uuid.UUID
 	var row *sql.Row
 	switch format {
 	case BoundaryFormatWKT:
-		row = r.db.WithContext(ctx).Raw(`
-SELECT ST_AsText(geometry::geometry), area_hectares, perimeter_meters
+		row = r.readDB.WithContext(ctx).Raw(`
+SELECT ST_AsText(geometry::geometry), area_hectares, perimeter_meters, updated_at
+FROM project_geometries WHERE project_id = ?
+`, projectID).Row()
+		if err := row.Scan(&resp.WKT, &resp.AreaHectares, &resp.PerimeterMeters, &resp.UpdatedAt); err != nil {
+			return nil, err
+		}
+	case BoundaryFormatWKB:
+		row = r.readDB.WithContext(ctx).Raw(`
+SELECT ST_AsBinary(geometry::geometry), area_hectares, perimeter_meters, updated_at
 FROM project_geometries WHERE project_id = ?
 `, projectID).Row()
-		if err := row.Scan(&resp.WKT, &resp.AreaHectares, &resp.PerimeterMeters); err != nil {
+		if err := row.Scan(&resp.WKB, &resp.AreaHectares, &resp.PerimeterMeters, &resp.UpdatedAt); err != nil {
 			return nil, err
 		}
 	case BoundaryFormatKML:
-		row = r.db.WithContext(ctx).Raw(`
-SELECT ST_AsKML(geometry::geometry), area_hectares, perimeter_meters
+		row = r.readDB.WithContext(ctx).Raw(`
+SELECT ST_AsKML(geometry::geometry), area_hectares, perimeter_meters, updated_at
 FROM project_geometries WHERE project_id = ?
 `, projectID).Row()
-		if err := row.Scan(&resp.KML, &resp.AreaHectares, &resp.PerimeterMeters); err != nil {
+		if err := row.Scan(&resp.KML, &resp.AreaHectares, &resp.PerimeterMeters, &resp.UpdatedAt); err != nil {
 			return nil, err
 		}
 	default:
 		var g string
-		row = r.db.WithContext(ctx).Raw(`
-SELECT ST_AsGeoJSON(geometry::geometry), area_hectares, perimeter_meters
+		row = r.readDB.WithContext(ctx).Raw(`
+SELECT ST_AsGeoJSON(geometry::geometry), area_hectares, perimeter_meters, updated_at
 FROM project_geometries WHERE project_id = ?
 `, projectID).Row()
-		if err := row.Scan(&g, &resp.AreaHectares, &resp.PerimeterMeters); err != nil {
+		if err := row.Scan(&g, &resp.AreaHectares, &resp.PerimeterMeters, &resp.UpdatedAt); err != nil {
 			return nil, err
 		}
 		resp.Geometry = json.RawMessage(g)
@@ -241,8 +668,10 @@ func (r *repository) FindNearby(ctx context.Context, q NearbyQuery) ([]NearbyPro
 		q.Limit = 20
 	}
 
-	sqlStmt := queries.NearbyProjectsSQL(q.Limit)
-	rows, err := r.db.WithContext(ctx).Raw(sqlStmt, q.Lon, q.Lat, q.Lon, q.Lat, q.RadiusMeters).Rows()
+	filter, scopeArgs := scopeFilter(ctx)
+	sqlStmt := queries.NearbyProjectsSQL(q.Limit, filter)
+	args := append([]interface{}{q.Lon, q.Lat, q.Lon, q.Lat, q.RadiusMeters}, scopeArgs...)
+	rows, err := r.readDB.WithContext(ctx).Raw(sqlStmt, args...).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -268,14 +697,27 @@ func (r *repository) FindWithin(ctx context.Context, q WithinQuery) ([]NearbyPro
 		rows *sql.Rows
 		err  error
 	)
+	var args []interface{}
+	var query string
+	tags := project.ParseTags(q.Tags)
+	filter := ""
+	if len(tags) > 0 {
+		filter = queries.TagFilterSQL(q.TagMatch == project.TagMatchAll)
+	}
+	scope, scopeArgs := scopeFilter(ctx)
+	filter += scope
 	if q.GeoJSON != "" {
-		rows, err = r.db.WithContext(ctx).Raw(queries.WithinPolygonSQL(q.Limit), q.GeoJSON).Rows()
+		query = queries.WithinPolygonSQL(q.Limit, filter)
+		args = []interface{}{q.GeoJSON}
 	} else {
-		rows, err = r.db.WithContext(ctx).Raw(
-			queries.WithinBBoxSQL(q.Limit),
-			*q.MinLon, *q.MinLat, *q.MaxLon, *q.MaxLat,
-		).Rows()
+		query = queries.WithinBBoxSQL(q.Limit, filter)
+		args = []interface{}{*q.MinLon, *q.MinLat, *q.MaxLon, *q.MaxLat}
 	}
+	if len(tags) > 0 {
+		args = append(args, pq.StringArray(tags))
+	}
+	args = append(args, scopeArgs...)
+	rows, err = r.readDB.WithContext(ctx).Raw(query, args...).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -294,8 +736,136 @@ func (r *repository) FindWithin(ctx context.Context, q WithinQuery) ([]NearbyPro
 	return out, nil
 }
 
-func (r *repository) Intersect(ctx context.Context, geometry json.RawMessage) ([]IntersectResult, error) {
-	rows, err := r.db.WithContext(ctx).Raw(queries.IntersectionSQL, string(geometry), string(geometry), string(geometry)).Rows()
+func (r *repository) ProjectsAtPoint(ctx context.Context, lon, lat float64) ([]ProjectAtPoint, error) {
+	filter, scopeArgs := scopeFilter(ctx)
+	rows, err := r.readDB.WithContext(ctx).Raw(queries.ProjectsAtPointSQL(filter), append([]interface{}{lon, lat}, scopeArgs...)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]ProjectAtPoint, 0)
+	for rows.Next() {
+		var p ProjectAtPoint
+		if err := rows.Scan(&p.ProjectID, &p.Name, &p.AreaHectares, &p.OnBoundary); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *repository) ProjectCentroids(ctx context.Context, minLon, minLat, maxLon, maxLat float64) ([]ProjectPoint, error) {
+	filter, scopeArgs := scopeFilter(ctx)
+	args := append([]interface{}{minLon, minLat, maxLon, maxLat}, scopeArgs...)
+	rows, err := r.readDB.WithContext(ctx).Raw(queries.CentroidsInBBoxSQL(0, filter), args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]ProjectPoint, 0)
+	for rows.Next() {
+		var p ProjectPoint
+		if err := rows.Scan(&p.ProjectID, &p.Name, &p.Lat, &p.Lon); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// ExportGeometries calls fn for every project matching q, one row at a time,
+// so an export never holds the whole result set in memory. An error from fn
+// stops the scan and is returned.
+func (r *repository) ExportGeometries(ctx context.Context, q ExportQuery, fn func(ExportFeature) error) error {
+	var filter string
+	var args []interface{}
+	if q.MinLat != nil && q.MinLon != nil && q.MaxLat != nil && q.MaxLon != nil {
+		filter += queries.ExportBBoxFilter
+		args = append(args, *q.MinLon, *q.MinLat, *q.MaxLon, *q.MaxLat)
+	}
+	tagFilter, tagArgs := projectSetFilter(ctx, q.Tags, q.TagMatch, q.OwnerID)
+	filter += tagFilter
+	args = append(args, tagArgs...)
+
+	rows, err := r.readDB.WithContext(ctx).Raw(queries.ExportSQL(filter), args...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f ExportFeature
+		var tags pq.StringArray
+		var geometry string
+		if err := rows.Scan(&f.ProjectID, &f.Name, &f.Type, &tags, &f.AreaHectares, &geometry); err != nil {
+			return err
+		}
+		f.Tags = tags
+		f.Geometry = json.RawMessage(geometry)
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ProjectExtent returns the bounding box of the projects matching q and how
+// many there are. The box is all zero when count is 0.
+func (r *repository) ProjectExtent(ctx context.Context, q ExtentQuery) ([4]float64, int, error) {
+	filter, args := projectSetFilter(ctx, q.Tags, q.TagMatch, q.OwnerID)
+	var bounds [4]float64
+	var count int
+	err := r.readDB.WithContext(ctx).Raw(queries.ExtentSQL(filter), args...).Row().
+		Scan(&count, &bounds[0], &bounds[1], &bounds[2], &bounds[3])
+	return bounds, count, err
+}
+
+// ProjectTile encodes the boundaries in tile t of the projects in ctx's scope
+// as a vector tile, simplified to tolerance metres.
+func (r *repository) ProjectTile(ctx context.Context, t TileCoord, tolerance float64) ([]byte, error) {
+	filter, scopeArgs := scopeFilter(ctx)
+	args := append([]interface{}{t.Z, t.X, t.Y, tolerance, tileExtent, tileBuffer}, scopeArgs...)
+	var tile []byte
+	err := r.readDB.WithContext(ctx).
+		Raw(queries.ProjectTileSQL(filter), args...).
+		Row().Scan(&tile)
+	return tile, err
+}
+
+// projectSetFilter builds the tag, owner and scope predicates shared by
+// exports and extents, with their arguments.
+func projectSetFilter(ctx context.Context, tags, tagMatch, ownerID string) (string, []interface{}) {
+	var filter string
+	var args []interface{}
+	if parsed := project.ParseTags(tags); len(parsed) > 0 {
+		filter += queries.TagFilterSQL(tagMatch == project.TagMatchAll)
+		args = append(args, pq.StringArray(parsed))
+	}
+	if ownerID != "" {
+		filter += queries.ExportOwnerFilter
+		args = append(args, ownerID)
+	}
+	scope, scopeArgs := scopeFilter(ctx)
+	return filter + scope, append(args, scopeArgs...)
+}
+
+// scopeFilter returns the predicate limiting a query on projects p to those
+// the project.Scope in ctx may see, with its argument. Contexts without a
+// restricting scope get no filter.
+func scopeFilter(ctx context.Context) (string, []interface{}) {
+	column, id, ok := project.Restricts(ctx)
+	if !ok {
+		return "", nil
+	}
+	return queries.ProjectScopeFilter(column), []interface{}{id}
+}
+
+func (r *repository) Intersect(ctx context.Context, geometry json.RawMessage, includeDeleted bool) ([]IntersectResult, error) {
+	filter, scopeArgs := scopeFilter(ctx)
+	args := append([]interface{}{string(geometry), string(geometry), string(geometry)}, scopeArgs...)
+	rows, err := r.readDB.WithContext(ctx).Raw(queries.IntersectionSQL(includeDeleted, filter), args...).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -304,7 +874,7 @@ func (r *repository) Intersect(ctx context.Context, geometry json.RawMessage) ([
 	out := make([]IntersectResult, 0)
 	for rows.Next() {
 		var i IntersectResult
-		if err := rows.Scan(&i.ProjectID, &i.Intersects, &i.IntersectionArea); err != nil {
+		if err := rows.Scan(&i.ProjectID, &i.Intersects, &i.IntersectionArea, &i.OwnerID); err != nil {
 			return nil, err
 		}
 		out = append(out, i)
@@ -312,6 +882,135 @@ func (r *repository) Intersect(ctx context.Context, geometry json.RawMessage) ([
 	return out, nil
 }
 
+func (r *repository) ClipToAOI(ctx context.Context, aoi json.RawMessage) ([]ClippedProject, error) {
+	filter, scopeArgs := scopeFilter(ctx)
+	rows, err := r.readDB.WithContext(ctx).Raw(queries.ClipToAOISQL(filter), append([]interface{}{string(aoi)}, scopeArgs...)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]ClippedProject, 0)
+	for rows.Next() {
+		var c ClippedProject
+		var geom string
+		var inside bool
+		if err := rows.Scan(&c.ProjectID, &c.Name, &geom, &c.ClippedAreaHectares, &c.ProjectAreaHectares, &inside); err != nil {
+			return nil, err
+		}
+		c.Geometry = json.RawMessage(geom)
+		c.Coverage = CoveragePartial
+		if inside {
+			c.Coverage = CoverageInside
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// OverlapCandidates returns the live projects whose boundary may overlap
+// geometry, with exact intersection results for those not ruled out by the
+// bounding-box and simplified pre-checks. It runs on the primary so it sees
+// boundaries committed by concurrent writers, and ignores ctx's scope.
+func (r *repository) OverlapCandidates(ctx context.Context, geometry json.RawMessage, tolerance float64) ([]IntersectResult, OverlapStats, error) {
+	var stats OverlapStats
+	rows, err := r.db.WithContext(ctx).Raw(queries.OverlapCandidatesSQL,
+		tolerance, string(geometry), tolerance, tolerance, 2*tolerance).Rows()
+	if err != nil {
+		return nil, stats, err
+	}
+	defer rows.Close()
+
+	out := make([]IntersectResult, 0)
+	for rows.Next() {
+		var i IntersectResult
+		var survived bool
+		if err := rows.Scan(&i.ProjectID, &survived, &i.Intersects, &i.IntersectionArea, &i.OwnerID); err != nil {
+			return nil, stats, err
+		}
+		stats.BBoxCandidates++
+		if survived {
+			stats.ExactComparisons++
+		}
+		out = append(out, i)
+	}
+	return out, stats, rows.Err()
+}
+
+// MeasureOverlaps returns the areas and containment of every live project in
+// ctx's scope sharing area with geometry, largest overlap first. Boundaries
+// that only touch are left out.
+func (r *repository) MeasureOverlaps(ctx context.Context, geometry json.RawMessage) ([]OverlapMeasure, error) {
+	filter, scopeArgs := scopeFilter(ctx)
+	rows, err := r.readDB.WithContext(ctx).Raw(queries.OverlapMeasuresSQL(filter), append([]interface{}{string(geometry)}, scopeArgs...)...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]OverlapMeasure, 0)
+	for rows.Next() {
+		var m OverlapMeasure
+		if err := rows.Scan(&m.ProjectID, &m.NewAreaHectares, &m.ExistingAreaHectares, &m.OverlapAreaHectares,
+			&m.NewCoversExisting, &m.ExistingCoversNew); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *repository) SpatialIndexExists(ctx context.Context, index string) (bool, error) {
+	return postgis.IndexExists(r.db.WithContext(ctx), "project_geometries", index)
+}
+
+// ExplainWithinBBox plans the bounding-box search with sequential scans
+// disabled for the transaction. On a small table the planner prefers a seq scan
+// even when the index is fine; with enable_seqscan off it only picks one when
+// no usable index exists.
+func (r *repository) ExplainWithinBBox(ctx context.Context) (json.RawMessage, error) {
+	var plan json.RawMessage
+	err := postgis.WithTransaction(ctx, r.db, func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL enable_seqscan = off").Error; err != nil {
+			return err
+		}
+		var err error
+		plan, err = postgis.ExplainJSON(tx, queries.WithinBBoxSQL(100, ""), 36.0, -2.0, 37.0, -1.0)
+		return err
+	})
+	return plan, err
+}
+
+// LockOverlapRegions takes the transaction-scoped advisory locks guarding the
+// overlap check for geometry. Outside a transaction the locks are released as
+// soon as each statement finishes, so call it through InTransaction.
+func (r *repository) LockOverlapRegions(ctx context.Context, geometry json.RawMessage) error {
+	var env struct {
+		MinX, MinY, MaxX, MaxY float64
+	}
+	err := r.db.WithContext(ctx).Raw(`
+SELECT ST_XMin(e) AS min_x, ST_YMin(e) AS min_y, ST_XMax(e) AS max_x, ST_YMax(e) AS max_y
+FROM (SELECT ST_Envelope(ST_SetSRID(ST_GeomFromGeoJSON(?), 4326)) AS e) s`, string(geometry)).Scan(&env).Error
+	if err != nil {
+		return fmt.Errorf("overlap lock envelope: %w", err)
+	}
+
+	db := r.db.WithContext(ctx)
+	keys, exclusive := overlapLockKeys(env.MinX, env.MinY, env.MaxX, env.MaxY)
+	if exclusive {
+		return db.Exec("SELECT pg_advisory_xact_lock(?)", overlapGlobalLock).Error
+	}
+	if err := db.Exec("SELECT pg_advisory_xact_lock_shared(?)", overlapGlobalLock).Error; err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := db.Exec("SELECT pg_advisory_xact_lock(?)", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *repository) CreateGeofence(ctx context.Context, req CreateGeofenceRequest) (*Geofence, error) {
 	priority := req.Priority
 	if priority <= 0 {
@@ -376,8 +1075,205 @@ ORDER BY g.priority DESC, distance_meters ASC
 	return out, nil
 }
 
+// CreateSubArea stores req.GeoJSON as a sub-area of projectID if it lies
+// within the project's boundary. The boundary row is locked until the insert
+// commits, so it cannot change between the check and the write.
+func (r *repository) CreateSubArea(ctx context.Context, projectID uuid.UUID, req CreateSubAreaRequest) (*SubArea, error) {
+	var out SubArea
+	err := postgis.WithTransaction(ctx, r.db, func(tx *gorm.DB) error {
+		var check struct {
+			Within          bool
+			OutsideHectares float64
+		}
+		row := tx.Raw(`
+WITH child AS (SELECT ST_SetSRID(ST_GeomFromGeoJSON(?), 4326) AS geom)
+SELECT ST_Within(child.geom, pg.geometry::geometry),
+       COALESCE(ST_Area(ST_Difference(child.geom, pg.geometry::geometry)::geography), 0) * 0.0001
+FROM project_geometries pg, child
+WHERE pg.project_id = ?
+FOR SHARE OF pg
+`, string(req.GeoJSON), projectID).Row()
+		if err := row.Scan(&check.Within, &check.OutsideHectares); err != nil {
+			return err
+		}
+		if !check.Within {
+			return subAreaOutside(check.OutsideHectares)
+		}
+
+		var geom string
+		row = tx.Raw(`
+WITH child AS (SELECT ST_Multi(ST_ForcePolygonCCW(ST_SetSRID(ST_GeomFromGeoJSON(?), 4326))) AS geom)
+INSERT INTO project_sub_areas (project_id, name, kind, geometry, area_hectares, created_at)
+SELECT ?, ?, ?, geom::geography, ST_Area(geom::geography) * 0.0001, NOW()
+FROM child
+RETURNING id, project_id, name, kind, ST_AsGeoJSON(geometry::geometry), area_hectares, created_at
+`, string(req.GeoJSON), projectID, req.Name, req.Kind).Row()
+		if err := row.Scan(&out.ID, &out.ProjectID, &out.Name, &out.Kind, &geom, &out.AreaHectares, &out.CreatedAt); err != nil {
+			return err
+		}
+		out.Geometry = json.RawMessage(geom)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSubAreas returns the sub-areas within projectID's current boundary,
+// oldest first, and the boundary's area in hectares. A project without a
+// boundary yields sql.ErrNoRows.
+func (r *repository) ListSubAreas(ctx context.Context, projectID uuid.UUID) ([]SubArea, float64, error) {
+	db := r.readDB.WithContext(ctx)
+	var projectArea float64
+	if err := db.Raw("SELECT area_hectares FROM project_geometries WHERE project_id = ?", projectID).Row().Scan(&projectArea); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Raw(`
+SELECT sa.id, sa.project_id, sa.name, sa.kind, ST_AsGeoJSON(sa.geometry::geometry), sa.area_hectares, sa.created_at
+FROM project_sub_areas sa
+JOIN project_geometries pg ON pg.project_id = sa.project_id
+WHERE sa.project_id = ?
+  AND ST_Within(sa.geometry::geometry, pg.geometry::geometry)
+ORDER BY sa.created_at, sa.id
+`, projectID).Rows()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := make([]SubArea, 0)
+	for rows.Next() {
+		var sa SubArea
+		var geom string
+		if err := rows.Scan(&sa.ID, &sa.ProjectID, &sa.Name, &sa.Kind, &geom, &sa.AreaHectares, &sa.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		sa.Geometry = json.RawMessage(geom)
+		out = append(out, sa)
+	}
+	return out, projectArea, rows.Err()
+}
+
+// ReplaceReferenceLayer creates layer name, or updates its description, and
+// replaces its features in one transaction. Invalid polygons are repaired
+// with ST_MakeValid.
+func (r *repository) ReplaceReferenceLayer(ctx context.Context, name, description string, features []ReferenceFeature) (*ReferenceLayer, error) {
+	var out ReferenceLayer
+	err := postgis.WithTransaction(ctx, r.db, func(tx *gorm.DB) error {
+		row := tx.Raw(`
+INSERT INTO reference_layers (name, description, created_at, updated_at)
+VALUES (?, ?, NOW(), NOW())
+ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, updated_at = NOW()
+RETURNING name, description, created_at, updated_at
+`, name, description).Row()
+		if err := row.Scan(&out.Name, &out.Description, &out.CreatedAt, &out.UpdatedAt); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM reference_layer_features WHERE layer_name = ?", name).Error; err != nil {
+			return err
+		}
+		for _, f := range features {
+			err := tx.Exec(`
+INSERT INTO reference_layer_features (layer_name, name, geometry)
+SELECT ?, ?, ST_Multi(ST_CollectionExtract(ST_MakeValid(ST_SetSRID(ST_GeomFromGeoJSON(?), 4326)), 3))::geography
+`, name, f.Name, string(f.Geometry)).Error
+			if err != nil {
+				return err
+			}
+		}
+		out.FeatureCount = len(features)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *repository) ListReferenceLayers(ctx context.Context) ([]ReferenceLayer, error) {
+	rows, err := r.readDB.WithContext(ctx).Raw(`
+SELECT l.name, l.description, COUNT(f.id), l.created_at, l.updated_at
+FROM reference_layers l
+LEFT JOIN reference_layer_features f ON f.layer_name = l.name
+GROUP BY l.name
+ORDER BY l.name
+`).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]ReferenceLayer, 0)
+	for rows.Next() {
+		var l ReferenceLayer
+		if err := rows.Scan(&l.Name, &l.Description, &l.FeatureCount, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// IntersectReferenceLayer measures projectID's boundary against the features
+// of layer: each intersecting feature's share, and the area of the boundary
+// covered by their union. An unknown layer yields ErrUnknownReferenceLayer
+// and a project without a boundary sql.ErrNoRows.
+func (r *repository) IntersectReferenceLayer(ctx context.Context, projectID uuid.UUID, layer string) (*ReferenceLayerIntersection, error) {
+	db := r.readDB.WithContext(ctx)
+	var known bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM reference_layers WHERE name = ?)", layer).Row().Scan(&known); err != nil {
+		return nil, err
+	}
+	if !known {
+		return nil, ErrUnknownReferenceLayer
+	}
+	out := &ReferenceLayerIntersection{ProjectID: projectID, Layer: layer}
+	if err := db.Raw("SELECT area_hectares FROM project_geometries WHERE project_id = ?", projectID).Row().Scan(&out.ProjectAreaHectares); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Raw(`
+WITH p AS (SELECT geometry::geometry AS geom FROM project_geometries WHERE project_id = ?)
+SELECT f.id, f.name, ST_Area(ST_Intersection(f.geometry::geometry, p.geom)::geography) * 0.0001 AS hectares
+FROM reference_layer_features f, p
+WHERE f.layer_name = ? AND ST_Intersects(f.geometry::geometry, p.geom)
+ORDER BY hectares DESC, f.id
+`, projectID, layer).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o ReferenceFeatureOverlap
+		if err := rows.Scan(&o.ID, &o.Name, &o.IntersectionAreaHectares); err != nil {
+			return nil, err
+		}
+		out.Features = append(out.Features, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = db.Raw(`
+WITH p AS (SELECT geometry::geometry AS geom FROM project_geometries WHERE project_id = ?),
+hits AS (
+    SELECT ST_Union(f.geometry::geometry) AS geom
+    FROM reference_layer_features f, p
+    WHERE f.layer_name = ? AND ST_Intersects(f.geometry::geometry, p.geom)
+)
+SELECT COALESCE(ST_Area(ST_Intersection(p.geom, hits.geom)::geography), 0) * 0.0001
+FROM p, hits
+`, projectID, layer).Row().Scan(&out.IntersectionAreaHectares)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (r *repository) GetAdministrativeBoundaries(ctx context.Context, level int, countryCode string) ([]AdministrativeBoundary, error) {
-	db := r.db.WithContext(ctx)
+	db := r.readDB.WithContext(ctx)
 	sqlStmt := `
 SELECT id, name, admin_level, country_code, ST_AsGeoJSON(geometry::geometry)
 FROM administrative_boundaries
@@ -0,0 +1,63 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/postgis"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newDryRunDB returns a gorm handle that never touches a server and counts
+// the raw queries routed through it.
+func newDryRunDB(t *testing.T, hits *int) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=unused sslmode=disable"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("open dry-run db: %v", err)
+	}
+	count := func(*gorm.DB) { *hits++ }
+	if err := db.Callback().Row().Before("gorm:row").Register("test:count_row", count); err != nil {
+		t.Fatalf("register row callback: %v", err)
+	}
+	if err := db.Callback().Raw().Before("gorm:raw").Register("test:count_raw", count); err != nil {
+		t.Fatalf("register raw callback: %v", err)
+	}
+	return db
+}
+
+func TestReplicaAwareRepository_RoutesReadsToReplica(t *testing.T) {
+	var primaryHits, replicaHits int
+	client := postgis.NewClientWithReplica(newDryRunDB(t, &primaryHits), newDryRunDB(t, &replicaHits))
+	repo := NewReplicaAwareRepository(client)
+	ctx := context.Background()
+
+	_, _ = repo.FindNearby(ctx, NearbyQuery{Lat: 1, Lon: 1})
+	minV, maxV := 0.0, 1.0
+	_, _ = repo.FindWithin(ctx, WithinQuery{MinLat: &minV, MinLon: &minV, MaxLat: &maxV, MaxLon: &maxV})
+	_, _ = repo.Intersect(ctx, json.RawMessage(`{"type":"Point","coordinates":[0,0]}`), false)
+
+	if replicaHits != 3 {
+		t.Errorf("expected 3 reads on the replica, got %d", replicaHits)
+	}
+	if primaryHits != 0 {
+		t.Errorf("expected no reads on the primary, got %d", primaryHits)
+	}
+}
+
+func TestReplicaAwareRepository_FallsBackToPrimary(t *testing.T) {
+	var primaryHits int
+	repo := NewReplicaAwareRepository(postgis.NewClient(newDryRunDB(t, &primaryHits)))
+
+	_, _ = repo.FindNearby(context.Background(), NearbyQuery{Lat: 1, Lon: 1})
+
+	if primaryHits != 1 {
+		t.Errorf("expected the read to fall back to the primary, got %d hits", primaryHits)
+	}
+}
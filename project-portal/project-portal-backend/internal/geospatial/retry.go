@@ -0,0 +1,104 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy bounds how often the service retries a database call that failed
+// with a transient error. MaxAttempts counts the first try; 1 disables retries.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used by services built without an explicit policy, and
+// in place of the zero RetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+
+// backoff is the wait before retry n (1-based): BaseDelay doubled per retry,
+// capped at MaxDelay.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// retryableSQLStates are Postgres errors that leave nothing behind and succeed
+// when the transaction is simply run again.
+var retryableSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// isRetryable reports whether err is a transient database failure. Both lib/pq
+// and pgx errors expose SQLState; driver.ErrBadConn means the statement never
+// reached the server.
+func isRetryable(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		return retryableSQLStates[pgErr.SQLState()]
+	}
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// TransientError is returned once every attempt failed with a retryable error.
+// RetryAfter is a hint for the client.
+type TransientError struct {
+	Attempts   int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("database busy after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *TransientError) Unwrap() error { return e.Err }
+
+// withRetry runs fn until it succeeds, fails with a non-retryable error, the
+// policy's attempts run out or ctx is done. fn must be idempotent or run a
+// whole transaction, so that a retry starts from a clean slate.
+func withRetry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	if policy == (RetryPolicy{}) {
+		policy = DefaultRetryPolicy
+	}
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for n := 1; ; n++ {
+		if err = fn(); err == nil || !isRetryable(err) {
+			return err
+		}
+		if n == attempts {
+			break
+		}
+		timer := time.NewTimer(policy.backoff(n))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return &TransientError{Attempts: attempts, RetryAfter: policy.backoff(attempts), Err: err}
+}
+
+// retryValue is withRetry for calls that return a value.
+func retryValue[T any](ctx context.Context, policy RetryPolicy, fn func() (T, error)) (T, error) {
+	var out T
+	err := withRetry(ctx, policy, func() error {
+		var err error
+		out, err = fn()
+		return err
+	})
+	return out, err
+}
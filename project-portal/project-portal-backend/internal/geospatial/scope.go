@@ -0,0 +1,56 @@
+package geospatial
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ErrProjectNotFound is returned for a project that does not exist or lies
+// outside the caller's project.Scope.
+var ErrProjectNotFound = errors.New("project not found")
+
+// ProjectInScope reports whether projectID is a live project the
+// project.Scope in ctx may see.
+func (s *service) ProjectInScope(ctx context.Context, projectID uuid.UUID) (bool, error) {
+	return dbCall(ctx, s, weightQuery, func() (bool, error) {
+		return s.repo.ProjectInScope(ctx, projectID)
+	})
+}
+
+// requireProject answers /projects/:id requests for a project outside the
+// caller's project.Scope with 404, as the project routes do. Callers whose
+// scope covers every project skip the lookup; a malformed id is left for the
+// handler to report.
+func (h *Handler) requireProject(c *gin.Context) {
+	if _, _, restricted := project.Restricts(c.Request.Context()); !restricted {
+		c.Next()
+		return
+	}
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Next()
+		return
+	}
+	visible, err := h.service.ProjectInScope(c.Request.Context(), projectID)
+	if respondTransient(c, err) {
+		c.Abort()
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("project scope check failed", "project_id", projectID, "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to look up project"})
+		return
+	}
+	if !visible {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": ErrProjectNotFound.Error()})
+		return
+	}
+	c.Next()
+}
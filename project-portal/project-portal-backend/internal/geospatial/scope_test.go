@@ -0,0 +1,78 @@
+package geospatial
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// scopeRepo sees only the projects in visible, and serves a boundary for any.
+type scopeRepo struct {
+	*fakeRepo
+	visible map[uuid.UUID]bool
+	scopes  []project.Scope
+}
+
+func (r *scopeRepo) ProjectInScope(ctx context.Context, projectID uuid.UUID) (bool, error) {
+	scope, _ := project.ScopeFromContext(ctx)
+	r.scopes = append(r.scopes, scope)
+	return r.visible[projectID], nil
+}
+
+func (r *scopeRepo) GetProjectGeometry(_ context.Context, projectID uuid.UUID) (*ProjectGeometry, error) {
+	return &ProjectGeometry{ID: uuid.New(), ProjectID: projectID, GeometryGeoJSON: []byte(`{"type":"MultiPolygon","coordinates":[]}`)}, nil
+}
+
+func TestProjectRoutes_RequireSignInAndScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mine, theirs := uuid.New(), uuid.New()
+	repo := &scopeRepo{fakeRepo: newFakeRepo(), visible: map[uuid.UUID]bool{mine: true}}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	owner := uuid.New()
+	token, err := auth.GenerateJWT(&auth.User{ID: owner.String(), Email: "owner@example.com", Role: "user"})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/geospatial"+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/projects/" + mine.String() + "/geometry", "/projects/nearby?lat=0&lon=0", "/projects/export"} {
+		if w := get(path, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous %s: expected 401, got %d", path, w.Code)
+		}
+	}
+	if w := get("/projects/"+theirs.String()+"/geometry", token); w.Code != http.StatusNotFound {
+		t.Errorf("another owner's project: expected 404, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("/projects/"+mine.String()+"/geometry", token); w.Code != http.StatusOK {
+		t.Errorf("own project: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(repo.scopes) == 0 || repo.scopes[0].OwnerID != owner || repo.scopes[0].All {
+		t.Errorf("expected the lookups to carry the caller's owner scope, got %+v", repo.scopes)
+	}
+
+	admin, _ := auth.GenerateJWT(&auth.User{ID: uuid.NewString(), Email: "admin@example.com", Role: "admin"})
+	repo.scopes = nil
+	if w := get("/projects/"+theirs.String()+"/geometry", admin); w.Code != http.StatusOK {
+		t.Errorf("admin: expected 200, got %d", w.Code)
+	}
+	if len(repo.scopes) != 0 {
+		t.Error("expected a caller seeing every project to skip the scope lookup")
+	}
+}
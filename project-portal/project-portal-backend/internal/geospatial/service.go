@@ -1,16 +1,24 @@
 package geospatial
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/geometry"
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/queries"
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
 	pkggeojson "carbon-scribe/project-portal/project-portal-backend/pkg/geojson"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/postgis"
 
 	"github.com/google/uuid"
 )
@@ -19,24 +27,107 @@ type Service interface {
 	UploadProjectGeometry(ctx context.Context, projectID uuid.UUID, req UploadGeometryRequest) (*ProjectGeometry, error)
 	GetProjectGeometry(ctx context.Context, projectID uuid.UUID) (*ProjectGeometry, error)
 	GetProjectBoundary(ctx context.Context, projectID uuid.UUID, format string) (*BoundaryResponse, error)
+	GetProjectPerimeter(ctx context.Context, projectID uuid.UUID, exteriorOnly bool) (*PerimeterResponse, error)
+	DistanceToBoundary(ctx context.Context, projectID uuid.UUID, q PointQuery) (*BoundaryDistance, error)
+	BoundingCircle(ctx context.Context, projectID uuid.UUID) (*BoundingCircle, error)
+	BoundingRectangle(ctx context.Context, projectID uuid.UUID) (*BoundingRectangle, error)
+	EstimateCarbon(ctx context.Context, projectID uuid.UUID, q CarbonEstimateQuery) (*CarbonEstimate, error)
+	ListGeometryVersions(ctx context.Context, projectID uuid.UUID) ([]GeometryVersion, error)
+	DiffGeometryVersions(ctx context.Context, projectID uuid.UUID, from, to int) (*GeometryDiff, error)
+	PreviewSimplification(ctx context.Context, projectID uuid.UUID, tolerance float64) (*SimplifyPreview, error)
+	ApplySimplification(ctx context.Context, projectID uuid.UUID, req SimplifyRequest) (*ProjectGeometry, error)
 	FindNearby(ctx context.Context, q NearbyQuery) ([]NearbyProject, error)
 	FindWithin(ctx context.Context, q WithinQuery) ([]NearbyProject, error)
+	ProjectsAtPoint(ctx context.Context, q PointQuery) ([]ProjectAtPoint, error)
+	ExportProjects(ctx context.Context, q ExportQuery, w io.Writer) error
+	ProjectExtent(ctx context.Context, q ExtentQuery) (*MapExtent, error)
+	ClusterProjects(ctx context.Context, q ClusterQuery) (*ClusterResponse, error)
+	ProjectTile(ctx context.Context, t TileCoord) ([]byte, error)
+	ExplodeGeometry(ctx context.Context, projectID uuid.UUID) (*GeometryParts, error)
 	Intersect(ctx context.Context, req IntersectRequest) ([]IntersectResult, error)
+	ClipToAOI(ctx context.Context, req ClipRequest) ([]ClippedProject, error)
+	PreviewOverlaps(ctx context.Context, req OverlapPreviewRequest) ([]ProjectOverlap, error)
+	MergeProjects(ctx context.Context, req MergeProjectsRequest) (*MergeResult, error)
 	BuildStaticMapURL(ctx context.Context, req StaticMapRequest) (string, error)
 	GetTile(ctx context.Context, z, x, y int, style string) ([]byte, string, bool, error)
 	CreateGeofence(ctx context.Context, req CreateGeofenceRequest) (*Geofence, error)
 	CheckProjectGeofences(ctx context.Context, projectID uuid.UUID) ([]GeofenceCheckResult, error)
+	CreateSubArea(ctx context.Context, projectID uuid.UUID, req CreateSubAreaRequest) (*SubArea, error)
+	ListSubAreas(ctx context.Context, projectID uuid.UUID) (*SubAreaList, error)
+	PutReferenceLayer(ctx context.Context, name string, req PutReferenceLayerRequest) (*ReferenceLayer, error)
+	ListReferenceLayers(ctx context.Context) ([]ReferenceLayer, error)
+	IntersectReferenceLayer(ctx context.Context, projectID uuid.UUID, name string) (*ReferenceLayerIntersection, error)
 	GetAdministrativeBoundaries(ctx context.Context, level int, countryCode string) ([]AdministrativeBoundary, error)
+	ImportFeatureCollection(ctx context.Context, req BatchImportRequest, mode string) (*BatchImportResult, error)
+	ImportFeatureStream(ctx context.Context, r io.Reader, req BatchImportRequest, mode string) (*BatchImportResult, error)
+	ImportKML(ctx context.Context, r io.Reader, req BatchImportRequest, mode string) (*BatchImportResult, error)
+	ValidateGeometries(ctx context.Context, req ValidateGeometryRequest) (*ValidationReport, error)
+	CheckSpatialIndex(ctx context.Context) (*IndexCheckReport, error)
+	RecomputeAreas(ctx context.Context) (*AreaRecompute, error)
+	ProjectInScope(ctx context.Context, projectID uuid.UUID) (bool, error)
 }
 
+// ErrBatchRejected is returned alongside a populated result when a strict
+// batch import wrote nothing because at least one feature failed.
+var ErrBatchRejected = errors.New("batch import rejected")
+
 type service struct {
-	repo Repository
+	repo           Repository
+	areaPolicy     AreaPolicy
+	rejectOverlaps bool
+	overlapScope   OverlapScope
+	// overlapTolerance is the ST_Simplify tolerance, in degrees, of the
+	// overlap pre-check; 0 compares every bbox candidate exactly.
+	overlapTolerance float64
+	// maxVertices caps ST_NPoints of an uploaded geometry; 0 is unlimited.
+	maxVertices int
+	retry       RetryPolicy
+	limiter     *dbLimiter
+	carbonRates CarbonRates
 }
 
 func NewService(repo Repository) Service {
 	return &service{repo: repo}
 }
 
+// ServiceOptions collects every policy of the geospatial service.
+// AreaPolicy rejects or flags polygon uploads whose area is implausible for
+// the project's type. RejectOverlaps refuses boundaries overlapping another
+// live project in OverlapScope; the check and the write share a transaction,
+// so two concurrent overlapping uploads cannot both succeed.
+// MaxConcurrentDB caps in-flight database operations (bulk imports and exports
+// count for several); callers that cannot get a slot within DBAcquireTimeout
+// fail with ErrDBBusy. Zero MaxConcurrentDB means no cap.
+// OverlapSimplifyTolerance (degrees) lets the overlap check rule out
+// candidates on simplified boundaries first; zero disables that stage.
+// CarbonRates are the per land-cover rates of carbon estimates.
+type ServiceOptions struct {
+	AreaPolicy               AreaPolicy
+	RejectOverlaps           bool
+	OverlapScope             OverlapScope
+	OverlapSimplifyTolerance float64
+	MaxVertices              int
+	Retry                    RetryPolicy
+	MaxConcurrentDB          int64
+	DBAcquireTimeout         time.Duration
+	CarbonRates              CarbonRates
+}
+
+// NewServiceWithOptions builds a service from opts.
+func NewServiceWithOptions(repo Repository, opts ServiceOptions) Service {
+	return &service{
+		repo:             repo,
+		areaPolicy:       opts.AreaPolicy,
+		rejectOverlaps:   opts.RejectOverlaps,
+		overlapScope:     opts.OverlapScope,
+		overlapTolerance: opts.OverlapSimplifyTolerance,
+		maxVertices:      opts.MaxVertices,
+		retry:            opts.Retry,
+		limiter:          newDBLimiter(opts.MaxConcurrentDB, opts.DBAcquireTimeout),
+		carbonRates:      opts.CarbonRates,
+	}
+}
+
 func (s *service) UploadProjectGeometry(ctx context.Context, projectID uuid.UUID, req UploadGeometryRequest) (*ProjectGeometry, error) {
 	if len(req.GeoJSON) == 0 {
 		return nil, fmt.Errorf("geojson is required")
@@ -44,27 +135,148 @@ func (s *service) UploadProjectGeometry(ctx context.Context, projectID uuid.UUID
 	if err := pkggeojson.ValidateRFC7946(req.GeoJSON); err != nil {
 		return nil, err
 	}
-	geometryRaw := geometry.ExtractGeometry(req.GeoJSON)
-	if err := geometry.ValidateGeoJSON(geometryRaw); err != nil {
+	srid, err := geometry.DetectSRID(req.GeoJSON)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := s.toStorageSRID(ctx, req.GeoJSON, srid)
+	if err != nil {
+		return nil, err
+	}
+	if err := geometry.ValidateBoundary(raw); err != nil {
+		return nil, err
+	}
+	geometryRaw, err := geometry.ToMultiPolygon(geometry.ExtractGeometry(raw))
+	if err != nil {
 		return nil, err
 	}
 	req.GeoJSON = geometryRaw
-	return s.repo.UpsertProjectGeometry(ctx, projectID, req)
+	if err := s.checkVertices(ctx, geometryRaw); err != nil {
+		return nil, err
+	}
+	warning, err := s.checkArea(ctx, projectID, geometryRaw)
+	if err != nil {
+		return nil, err
+	}
+	logger := logging.FromContext(ctx).With("project_id", projectID)
+	stored, err := s.writeGeometry(ctx, projectID, req)
+	if errors.Is(err, ErrGeometryOverlap) {
+		logger.Warn("project geometry rejected for overlap", "error", err)
+		return nil, err
+	}
+	if err != nil {
+		logger.Error("storing project geometry failed", "error", err)
+		return nil, err
+	}
+	if warning != "" {
+		logger.Warn("project geometry area is suspicious", "area_hectares", stored.AreaHectares)
+		stored.Warnings = append(stored.Warnings, warning)
+	}
+	logger.Info("project geometry stored", "version", stored.Version, "area_hectares", stored.AreaHectares)
+	return stored, nil
+}
+
+// toStorageSRID reprojects raw from srid to WGS 84. Geometries already in the
+// storage SRID are returned unchanged; others come back as a bare geometry.
+func (s *service) toStorageSRID(ctx context.Context, raw json.RawMessage, srid int) (json.RawMessage, error) {
+	if srid == geometry.StorageSRID {
+		return raw, nil
+	}
+	return dbCall(ctx, s, weightQuery, func() (json.RawMessage, error) {
+		return s.repo.TransformToStorageSRID(ctx, geometry.ExtractGeometry(raw), srid)
+	})
+}
+
+// checkArea applies the area policy to a boundary. It returns a warning for
+// suspicious sizes and an *AreaOutOfRangeError for rejected ones.
+func (s *service) checkArea(ctx context.Context, projectID uuid.UUID, geom json.RawMessage) (string, error) {
+	if !s.areaPolicy.enabled() {
+		return "", nil
+	}
+	projectType, err := s.repo.GetProjectType(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+	area, err := s.repo.MeasureAreaHectares(ctx, geom)
+	if err != nil {
+		return "", err
+	}
+	return s.areaPolicy.For(projectType).Check(area)
 }
 
 func (s *service) GetProjectGeometry(ctx context.Context, projectID uuid.UUID) (*ProjectGeometry, error) {
-	return s.repo.GetProjectGeometry(ctx, projectID)
+	return dbCall(ctx, s, weightQuery, func() (*ProjectGeometry, error) {
+		return s.repo.GetProjectGeometry(ctx, projectID)
+	})
 }
 
+// ErrUnsupportedBoundaryFormat is returned for a boundary format other than
+// the BoundaryFormat constants.
+var ErrUnsupportedBoundaryFormat = errors.New("unsupported format")
+
 func (s *service) GetProjectBoundary(ctx context.Context, projectID uuid.UUID, format string) (*BoundaryResponse, error) {
 	format = strings.ToLower(strings.TrimSpace(format))
 	if format == "" {
 		format = BoundaryFormatGeoJSON
 	}
-	if format != BoundaryFormatGeoJSON && format != BoundaryFormatWKT && format != BoundaryFormatKML {
-		return nil, fmt.Errorf("unsupported format: %s", format)
+	switch format {
+	case BoundaryFormatGeoJSON, BoundaryFormatWKT, BoundaryFormatKML, BoundaryFormatWKB:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedBoundaryFormat, format)
+	}
+	return dbCall(ctx, s, weightQuery, func() (*BoundaryResponse, error) {
+		return s.repo.GetProjectBoundary(ctx, projectID, format)
+	})
+}
+
+func (s *service) GetProjectPerimeter(ctx context.Context, projectID uuid.UUID, exteriorOnly bool) (*PerimeterResponse, error) {
+	perimeter, err := dbCall(ctx, s, weightQuery, func() (float64, error) {
+		return s.repo.ProjectPerimeter(ctx, projectID, exteriorOnly)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.GetProjectBoundary(ctx, projectID, format)
+	return &PerimeterResponse{ProjectID: projectID, PerimeterMeters: perimeter, ExteriorOnly: exteriorOnly}, nil
+}
+
+// DistanceToBoundary measures how far the point q is from the nearest edge of
+// projectID's boundary and whether it lies inside. A project without a
+// boundary yields sql.ErrNoRows.
+func (s *service) DistanceToBoundary(ctx context.Context, projectID uuid.UUID, q PointQuery) (*BoundaryDistance, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	type measured struct {
+		meters float64
+		inside bool
+	}
+	got, err := dbCall(ctx, s, weightQuery, func() (measured, error) {
+		meters, inside, err := s.repo.BoundaryDistance(ctx, projectID, *q.Lon, *q.Lat)
+		return measured{meters, inside}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BoundaryDistance{ProjectID: projectID, Lon: *q.Lon, Lat: *q.Lat, DistanceMeters: got.meters, Inside: got.inside}, nil
+}
+
+func (s *service) ListGeometryVersions(ctx context.Context, projectID uuid.UUID) ([]GeometryVersion, error) {
+	return dbCall(ctx, s, weightQuery, func() ([]GeometryVersion, error) {
+		return s.repo.ListGeometryVersions(ctx, projectID)
+	})
+}
+
+// DiffGeometryVersions compares boundary version from with version to.
+func (s *service) DiffGeometryVersions(ctx context.Context, projectID uuid.UUID, from, to int) (*GeometryDiff, error) {
+	if from < 1 || to < 1 {
+		return nil, fmt.Errorf("versions start at 1")
+	}
+	if from == to {
+		return nil, fmt.Errorf("from and to must be different versions")
+	}
+	return dbCall(ctx, s, weightQuery, func() (*GeometryDiff, error) {
+		return s.repo.DiffGeometryVersions(ctx, projectID, from, to)
+	})
 }
 
 func (s *service) FindNearby(ctx context.Context, q NearbyQuery) ([]NearbyProject, error) {
@@ -74,7 +286,9 @@ func (s *service) FindNearby(ctx context.Context, q NearbyQuery) ([]NearbyProjec
 	if q.Limit <= 0 {
 		q.Limit = 20
 	}
-	return s.repo.FindNearby(ctx, q)
+	return dbCall(ctx, s, weightQuery, func() ([]NearbyProject, error) {
+		return s.repo.FindNearby(ctx, q)
+	})
 }
 
 func (s *service) FindWithin(ctx context.Context, q WithinQuery) ([]NearbyProject, error) {
@@ -89,14 +303,101 @@ func (s *service) FindWithin(ctx context.Context, q WithinQuery) ([]NearbyProjec
 		}
 		q.GeoJSON = string(geometry.ExtractGeometry(json.RawMessage(q.GeoJSON)))
 	}
-	return s.repo.FindWithin(ctx, q)
+	match, err := project.ParseTagMatch(q.TagMatch)
+	if err != nil {
+		return nil, err
+	}
+	q.TagMatch = match
+	return dbCall(ctx, s, weightQuery, func() ([]NearbyProject, error) {
+		return s.repo.FindWithin(ctx, q)
+	})
+}
+
+// ProjectsAtPoint returns every project whose boundary contains the point,
+// smallest first so the most specific project leads when boundaries nest.
+func (s *service) ProjectsAtPoint(ctx context.Context, q PointQuery) ([]ProjectAtPoint, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	return dbCall(ctx, s, weightQuery, func() ([]ProjectAtPoint, error) {
+		return s.repo.ProjectsAtPoint(ctx, *q.Lon, *q.Lat)
+	})
+}
+
+func (q PointQuery) validate() error {
+	if q.Lon == nil || q.Lat == nil {
+		return fmt.Errorf("lon and lat are required")
+	}
+	if *q.Lon < -180 || *q.Lon > 180 || *q.Lat < -90 || *q.Lat > 90 {
+		return fmt.Errorf("lon must be within [-180,180] and lat within [-90,90]")
+	}
+	return nil
+}
+
+// ClusterProjects snaps project centroids in the bbox to a zoom-dependent grid.
+// Cells holding fewer than MinClusterSize projects are returned as individual
+// projects so the map can show real markers once the clusters are small.
+func (s *service) ClusterProjects(ctx context.Context, q ClusterQuery) (*ClusterResponse, error) {
+	if q.MinLat == nil || q.MinLon == nil || q.MaxLat == nil || q.MaxLon == nil {
+		return nil, fmt.Errorf("min/max lat/lon bbox is required")
+	}
+	if q.Zoom < 0 || q.Zoom > 22 {
+		return nil, fmt.Errorf("zoom must be between 0 and 22")
+	}
+	if q.MinClusterSize <= 0 {
+		q.MinClusterSize = 3
+	}
+
+	points, err := dbCall(ctx, s, weightQuery, func() ([]ProjectPoint, error) {
+		return s.repo.ProjectCentroids(ctx, *q.MinLon, *q.MinLat, *q.MaxLon, *q.MaxLat)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type cellKey struct{ lat, lon float64 }
+	precision := queries.ClusterPrecision(q.Zoom)
+	cells := make(map[cellKey][]ProjectPoint)
+	order := make([]cellKey, 0)
+	for _, p := range points {
+		lat, lon := queries.ClusterKey(p.Lat, p.Lon, precision)
+		key := cellKey{lat, lon}
+		if _, ok := cells[key]; !ok {
+			order = append(order, key)
+		}
+		cells[key] = append(cells[key], p)
+	}
+
+	resp := &ClusterResponse{Zoom: q.Zoom, Clusters: make([]MapCluster, 0), Projects: make([]ProjectPoint, 0)}
+	for _, key := range order {
+		members := cells[key]
+		if len(members) < q.MinClusterSize {
+			resp.Projects = append(resp.Projects, members...)
+			continue
+		}
+		cluster := MapCluster{Count: len(members), Bounds: [4]float64{members[0].Lon, members[0].Lat, members[0].Lon, members[0].Lat}}
+		for _, m := range members {
+			cluster.Lat += m.Lat
+			cluster.Lon += m.Lon
+			cluster.Bounds[0] = math.Min(cluster.Bounds[0], m.Lon)
+			cluster.Bounds[1] = math.Min(cluster.Bounds[1], m.Lat)
+			cluster.Bounds[2] = math.Max(cluster.Bounds[2], m.Lon)
+			cluster.Bounds[3] = math.Max(cluster.Bounds[3], m.Lat)
+		}
+		cluster.Lat /= float64(len(members))
+		cluster.Lon /= float64(len(members))
+		resp.Clusters = append(resp.Clusters, cluster)
+	}
+	return resp, nil
 }
 
 func (s *service) Intersect(ctx context.Context, req IntersectRequest) ([]IntersectResult, error) {
 	if err := geometry.ValidateGeoJSON(geometry.ExtractGeometry(req.GeoJSON)); err != nil {
 		return nil, err
 	}
-	return s.repo.Intersect(ctx, geometry.ExtractGeometry(req.GeoJSON))
+	return dbCall(ctx, s, weightQuery, func() ([]IntersectResult, error) {
+		return s.repo.Intersect(ctx, geometry.ExtractGeometry(req.GeoJSON), req.IncludeDeleted)
+	})
 }
 
 func (s *service) BuildStaticMapURL(ctx context.Context, req StaticMapRequest) (string, error) {
@@ -180,7 +481,9 @@ func (s *service) CreateGeofence(ctx context.Context, req CreateGeofenceRequest)
 }
 
 func (s *service) CheckProjectGeofences(ctx context.Context, projectID uuid.UUID) ([]GeofenceCheckResult, error) {
-	return s.repo.CheckProjectGeofences(ctx, projectID)
+	return dbCall(ctx, s, weightQuery, func() ([]GeofenceCheckResult, error) {
+		return s.repo.CheckProjectGeofences(ctx, projectID)
+	})
 }
 
 func (s *service) GetAdministrativeBoundaries(ctx context.Context, level int, countryCode string) ([]AdministrativeBoundary, error) {
@@ -188,7 +491,519 @@ func (s *service) GetAdministrativeBoundaries(ctx context.Context, level int, co
 		return nil, fmt.Errorf("boundary level must be >= 0")
 	}
 	countryCode = strings.ToUpper(strings.TrimSpace(countryCode))
-	return s.repo.GetAdministrativeBoundaries(ctx, level, countryCode)
+	return dbCall(ctx, s, weightQuery, func() ([]AdministrativeBoundary, error) {
+		return s.repo.GetAdministrativeBoundaries(ctx, level, countryCode)
+	})
+}
+
+// ImportFeatureCollection validates and stores every feature of a
+// FeatureCollection, one feature at a time. In strict mode all geometries are
+// written in one transaction and a single failure rolls back the whole batch;
+// in best-effort mode valid features are stored independently and failures
+// are only reported.
+func (s *service) ImportFeatureCollection(ctx context.Context, req BatchImportRequest, mode string) (*BatchImportResult, error) {
+	mode, err := batchImportMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	var collection struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(req.GeoJSON, &collection); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	// A crs member on the collection applies to every feature without one.
+	collectionSRID, err := geometry.DetectSRID(req.GeoJSON)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("geojson must be a FeatureCollection")
+	}
+	features, err := openFeatureStream(bytes.NewReader(req.GeoJSON), collectionSRID, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.importFeatures(ctx, features, req, mode)
+}
+
+// ImportFeatureStream is ImportFeatureCollection for a bare FeatureCollection
+// read from r, decoded and stored one feature at a time so memory use does not
+// grow with the size of the upload. The collection's type and crs must come
+// before its features. req carries the options; its GeoJSON is ignored.
+func (s *service) ImportFeatureStream(ctx context.Context, r io.Reader, req BatchImportRequest, mode string) (*BatchImportResult, error) {
+	mode, err := batchImportMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	features, err := readFeatureStream(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.importFeatures(ctx, features, req, mode)
+}
+
+func batchImportMode(mode string) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		mode = BatchImportModeStrict
+	}
+	if mode != BatchImportModeStrict && mode != BatchImportModeBestEffort {
+		return "", fmt.Errorf("unsupported import mode: %s", mode)
+	}
+	return mode, nil
+}
+
+func (s *service) importFeatures(ctx context.Context, features *featureStream, req BatchImportRequest, mode string) (*BatchImportResult, error) {
+	if req.SnapTolerance != nil && *req.SnapTolerance <= 0 {
+		return nil, fmt.Errorf("snapTolerance must be greater than 0")
+	}
+	if mode == BatchImportModeBestEffort {
+		return s.importBestEffort(ctx, features, req)
+	}
+	return s.importStrict(ctx, features, req)
+}
+
+// importBestEffort stores each valid feature as soon as it is read. A stream
+// that breaks off part way fails the request, but the features before the
+// break stay stored.
+func (s *service) importBestEffort(ctx context.Context, features *featureStream, req BatchImportRequest) (*BatchImportResult, error) {
+	result := &BatchImportResult{Mode: BatchImportModeBestEffort, Results: []BatchFeatureResult{}}
+	seen := map[uuid.UUID]int{}
+	for i := 0; ; i++ {
+		raw, err := features.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.tally()
+			logging.FromContext(ctx).Warn("feature collection import interrupted", "mode", result.Mode, "imported", result.Imported, "error", err)
+			return nil, err
+		}
+		res, upload := s.prepareFeature(ctx, i, raw, features.srid, req, seen)
+		if upload != nil {
+			stored, err := s.writeGeometry(ctx, *res.ProjectID, *upload)
+			res.record(stored, err)
+		}
+		result.Results = append(result.Results, res)
+	}
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("feature collection is empty")
+	}
+	result.Total = len(result.Results)
+	result.tally()
+	logging.FromContext(ctx).Info("feature collection imported", "mode", result.Mode, "imported", result.Imported, "failed", result.Failed)
+	return result, nil
+}
+
+// importStrict stores valid features in one transaction as they are read.
+// Once a feature is invalid nothing more is written, the rest are still
+// validated for the report, and the transaction is rolled back at the end.
+func (s *service) importStrict(ctx context.Context, features *featureStream, req BatchImportRequest) (*BatchImportResult, error) {
+	release, err := s.limiter.acquire(ctx, weightBulk)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	// The bulk slot covers the validation queries made along the way.
+	ctx = withLimiterSlot(ctx)
+
+	result := &BatchImportResult{Mode: BatchImportModeStrict, Results: []BatchFeatureResult{}}
+	seen := map[uuid.UUID]int{}
+	stored := map[int]*ProjectGeometry{}
+	failed, invalid := -1, 0
+	err = s.repo.InTransaction(ctx, func(tx Repository) error {
+		var storeErr error
+		for i := 0; ; i++ {
+			raw, err := features.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if storeErr != nil {
+				// The batch is lost; read the rest only to report it.
+				projectID, _ := optionalProjectID(raw)
+				res := BatchFeatureResult{Index: i, Status: BatchFeatureRolledBack}
+				if projectID != uuid.Nil {
+					res.ProjectID = &projectID
+				}
+				result.Results = append(result.Results, res)
+				continue
+			}
+			res, upload := s.prepareFeature(ctx, i, raw, features.srid, req, seen)
+			result.Results = append(result.Results, res)
+			if upload == nil {
+				invalid++
+			}
+			if invalid > 0 {
+				continue
+			}
+			g, err := s.storeGeometry(ctx, tx, *res.ProjectID, *upload)
+			if err != nil {
+				failed, storeErr = i, err
+				continue
+			}
+			stored[i] = g
+		}
+		if len(result.Results) == 0 {
+			return fmt.Errorf("feature collection is empty")
+		}
+		if storeErr != nil {
+			return storeErr
+		}
+		if invalid > 0 {
+			return ErrBatchRejected
+		}
+		return nil
+	})
+	result.Total = len(result.Results)
+
+	switch {
+	case errors.Is(err, ErrBatchRejected):
+		for i := range result.Results {
+			if result.Results[i].Status != BatchFeatureInvalid {
+				result.Results[i].Status = BatchFeatureSkipped
+			}
+		}
+		result.tally()
+		logging.FromContext(ctx).Warn("feature collection rejected", "mode", result.Mode, "invalid", invalid)
+		return result, ErrBatchRejected
+	case err != nil && failed >= 0:
+		logging.FromContext(ctx).Error("feature collection import rolled back", "failed_index", failed, "error", err)
+		for i := range result.Results {
+			if i == failed {
+				result.Results[i].record(nil, err)
+			} else {
+				result.Results[i].Status = BatchFeatureRolledBack
+			}
+		}
+		result.tally()
+		return result, ErrBatchRejected
+	case err != nil:
+		return nil, err
+	}
+	for i, g := range stored {
+		result.Results[i].record(g, nil)
+	}
+	result.tally()
+	logging.FromContext(ctx).Info("feature collection imported", "mode", result.Mode, "imported", result.Imported)
+	return result, nil
+}
+
+// prepareFeature validates the feature at index and returns its result and,
+// when it is valid, the upload to store. seen maps project ids to the index
+// of the feature that first used them.
+func (s *service) prepareFeature(ctx context.Context, index int, raw json.RawMessage, collectionSRID int, req BatchImportRequest, seen map[uuid.UUID]int) (BatchFeatureResult, *UploadGeometryRequest) {
+	res := BatchFeatureResult{Index: index}
+	projectID, geom, err := s.parseBatchFeature(ctx, raw, collectionSRID)
+	if projectID != uuid.Nil {
+		id := projectID
+		res.ProjectID = &id
+	}
+	if err == nil {
+		if first, dup := seen[projectID]; dup {
+			err = fmt.Errorf("duplicate project_id, already used by feature %d", first)
+		} else {
+			seen[projectID] = index
+		}
+	}
+	if err == nil && req.SnapTolerance != nil {
+		geom, err = s.snapFeature(ctx, geom, *req.SnapTolerance, &res)
+	}
+	if err == nil {
+		err = s.checkVertices(ctx, geom)
+	}
+	if err == nil {
+		var warning string
+		if warning, err = s.checkArea(ctx, projectID, geom); warning != "" {
+			res.Warnings = append(res.Warnings, warning)
+		}
+		var areaErr *AreaOutOfRangeError
+		if errors.As(err, &areaErr) {
+			res.AreaHectares = areaErr.AreaHectares
+		}
+	}
+	if err != nil {
+		res.Status = BatchFeatureInvalid
+		res.Error = err.Error()
+		return res, nil
+	}
+	return res, &UploadGeometryRequest{
+		GeoJSON:                 geom,
+		SimplificationTolerance: req.SimplificationTolerance,
+		SourceType:              req.SourceType,
+		SourceFile:              req.SourceFile,
+	}
+}
+
+// snapFeature snaps geom to the import grid and records the vertex counts on
+// res. A snap that collapses or invalidates the shape fails the feature rather
+// than storing something worse than the input.
+func (s *service) snapFeature(ctx context.Context, geom json.RawMessage, size float64, res *BatchFeatureResult) (json.RawMessage, error) {
+	snapped, err := s.repo.SnapToGrid(ctx, geom, size)
+	if err != nil {
+		return nil, err
+	}
+	res.VerticesBefore = &snapped.VerticesBefore
+	res.VerticesAfter = &snapped.VerticesAfter
+	if !snapped.IsValid {
+		return nil, fmt.Errorf("snapping to a %g grid made the geometry invalid: %s", size, snapped.Reason)
+	}
+	if err := geometry.ValidateBoundary(snapped.GeoJSON); err != nil {
+		return nil, fmt.Errorf("snapped geometry: %w", err)
+	}
+	return snapped.GeoJSON, nil
+}
+
+// ValidateGeometries is a dry run of an upload or import: each feature is
+// checked for structure, PostGIS validity, area bounds and overlap with
+// existing projects, and nothing is persisted. Findings are graded by
+// severity; see FeatureValidation.checkIssues.
+func (s *service) ValidateGeometries(ctx context.Context, req ValidateGeometryRequest) (*ValidationReport, error) {
+	features, err := splitFeatures(req.GeoJSON)
+	if err != nil {
+		return nil, err
+	}
+	collectionSRID, err := geometry.DetectSRID(req.GeoJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{Total: len(features), Severities: map[Severity]int{}, Features: make([]FeatureValidation, len(features))}
+	for i, raw := range features {
+		res := &report.Features[i]
+		res.Index = i
+		res.Issues = []ValidationIssue{}
+		if err := s.validateFeature(ctx, raw, collectionSRID, res); err != nil {
+			return nil, err
+		}
+		for _, issue := range res.Issues {
+			report.Severities[issue.Severity]++
+		}
+		if res.Valid {
+			report.Valid++
+		} else {
+			report.Invalid++
+		}
+	}
+	logging.FromContext(ctx).Info("geometry dry-run validated", "total", report.Total, "invalid", report.Invalid)
+	return report, nil
+}
+
+// validateFeature fills res for one feature. Problems with the feature are
+// recorded on res; only database failures are returned.
+func (s *service) validateFeature(ctx context.Context, raw json.RawMessage, collectionSRID int, res *FeatureValidation) error {
+	projectID, err := optionalProjectID(raw)
+	if projectID != uuid.Nil {
+		id := projectID
+		res.ProjectID = &id
+	}
+	if err == nil {
+		err = pkggeojson.ValidateRFC7946(raw)
+	}
+	srid := geometry.StorageSRID
+	if err == nil {
+		srid, err = geometry.DetectSRIDWithDefault(raw, collectionSRID)
+	}
+	if err == nil && srid != geometry.StorageSRID {
+		if raw, err = s.toStorageSRID(ctx, raw, srid); err != nil {
+			return err
+		}
+	}
+	if err == nil {
+		err = geometry.ValidateBoundary(raw)
+	}
+	var geom json.RawMessage
+	if err == nil {
+		geom, err = geometry.ToMultiPolygon(geometry.ExtractGeometry(raw))
+	}
+	if err != nil {
+		res.fail(IssueMalformed, err.Error(), nil)
+		return nil
+	}
+
+	check, err := s.repo.CheckGeometry(ctx, geom)
+	if err != nil {
+		return err
+	}
+	res.AreaHectares = check.AreaHectares
+	res.Valid = true
+	res.checkIssues(check)
+	if !res.Valid {
+		return nil
+	}
+
+	if projectID != uuid.Nil {
+		warning, err := s.checkArea(ctx, projectID, geom)
+		var areaErr *AreaOutOfRangeError
+		switch {
+		case errors.As(err, &areaErr):
+			res.fail(IssueAreaOutOfRange, err.Error(), nil)
+			return nil
+		case err != nil:
+			res.warn(IssueArea, err.Error(), nil)
+		case warning != "":
+			res.warn(IssueArea, warning, nil)
+		}
+	}
+
+	overlaps, err := s.repo.Intersect(ctx, geom, false)
+	if err != nil {
+		return err
+	}
+	for _, o := range overlaps {
+		if !o.Intersects || o.ProjectID == projectID {
+			continue
+		}
+		res.Overlaps = append(res.Overlaps, o)
+		res.warn(IssueOverlap, fmt.Sprintf("overlaps project %s by %.4f ha", o.ProjectID, o.IntersectionArea), nil)
+	}
+	return nil
+}
+
+// Spatial indexes on project_geometries. The expression index serves the
+// geometry::geometry predicates used by the bbox, polygon and overlap queries.
+const (
+	geographyIndex = "idx_project_geometries_geometry"
+	geometryIndex  = "idx_project_geometries_geometry_geom"
+)
+
+// CheckSpatialIndex confirms the spatial indexes exist and that the planner
+// uses one for a representative bounding-box search.
+func (s *service) CheckSpatialIndex(ctx context.Context) (*IndexCheckReport, error) {
+	report := &IndexCheckReport{Indexes: map[string]bool{}, Warnings: []string{}}
+	for _, name := range []string{geographyIndex, geometryIndex} {
+		exists, err := s.repo.SpatialIndexExists(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		report.Indexes[name] = exists
+		if !exists {
+			report.Warnings = append(report.Warnings, "index "+name+" is missing; run the geospatial migrations")
+		}
+	}
+
+	plan, err := s.repo.ExplainWithinBBox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := postgis.ParsePlan(plan)
+	if err != nil {
+		return nil, err
+	}
+	report.Plan = plan
+	report.PlanNodes = summary.NodeTypes
+	report.IndexesUsed = summary.Indexes
+	report.SequentialScan = summary.SeqScanOn("project_geometries")
+	if report.SequentialScan {
+		report.Warnings = append(report.Warnings, "spatial query plans a sequential scan on project_geometries; the spatial index is missing or unusable")
+	}
+	return report, nil
+}
+
+// splitFeatures returns the members of a FeatureCollection, or the input
+// itself when it is a single Feature or geometry.
+func splitFeatures(raw json.RawMessage) ([]json.RawMessage, error) {
+	var collection struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(raw, &collection); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return []json.RawMessage{raw}, nil
+	}
+	if len(collection.Features) == 0 {
+		return nil, fmt.Errorf("feature collection is empty")
+	}
+	return collection.Features, nil
+}
+
+// optionalProjectID reads properties.project_id from a Feature if present.
+func optionalProjectID(raw json.RawMessage) (uuid.UUID, error) {
+	var feature struct {
+		Properties struct {
+			ProjectID string `json:"project_id"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &feature); err != nil {
+		return uuid.Nil, fmt.Errorf("invalid feature: %w", err)
+	}
+	if feature.Properties.ProjectID == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.Parse(feature.Properties.ProjectID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("properties.project_id must be a valid uuid")
+	}
+	return id, nil
+}
+
+// parseBatchFeature extracts the target project and validated geometry from a
+// single FeatureCollection member.
+func (s *service) parseBatchFeature(ctx context.Context, raw json.RawMessage, collectionSRID int) (uuid.UUID, json.RawMessage, error) {
+	if err := pkggeojson.ValidateRFC7946(raw); err != nil {
+		return uuid.Nil, nil, err
+	}
+	var feature struct {
+		Type       string `json:"type"`
+		Properties struct {
+			ProjectID string `json:"project_id"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &feature); err != nil {
+		return uuid.Nil, nil, fmt.Errorf("invalid feature: %w", err)
+	}
+	if feature.Type != "Feature" {
+		return uuid.Nil, nil, fmt.Errorf("expected Feature, got %s", feature.Type)
+	}
+	projectID, err := uuid.Parse(feature.Properties.ProjectID)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("properties.project_id must be a valid uuid")
+	}
+	srid, err := geometry.DetectSRIDWithDefault(raw, collectionSRID)
+	if err != nil {
+		return projectID, nil, err
+	}
+	if raw, err = s.toStorageSRID(ctx, raw, srid); err != nil {
+		return projectID, nil, err
+	}
+	if err := geometry.ValidateBoundary(raw); err != nil {
+		return projectID, nil, err
+	}
+	geom, err := geometry.ToMultiPolygon(geometry.ExtractGeometry(raw))
+	if err != nil {
+		return projectID, nil, err
+	}
+	return projectID, geom, nil
+}
+
+func (r *BatchFeatureResult) record(g *ProjectGeometry, err error) {
+	if err != nil {
+		r.Status = BatchFeatureFailed
+		r.Error = err.Error()
+		return
+	}
+	r.Status = BatchFeatureImported
+	id := g.ID
+	r.GeometryID = &id
+	r.AreaHectares = g.AreaHectares
+	r.Version = g.Version
+	r.WindingCorrected = g.WindingCorrected
+}
+
+func (r *BatchImportResult) tally() {
+	r.Imported, r.Failed = 0, 0
+	for _, res := range r.Results {
+		if res.Status == BatchFeatureImported {
+			r.Imported++
+		} else {
+			r.Failed++
+		}
+	}
 }
 
 func getEnvOrDefault(key, fallback string) string {
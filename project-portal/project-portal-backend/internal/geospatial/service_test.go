@@ -0,0 +1,802 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// fakeRepo keeps geometries in memory. InTransaction stages writes on a copy
+// and only publishes them when fn succeeds.
+type fakeRepo struct {
+	Repository
+	stored  map[uuid.UUID]*ProjectGeometry
+	failFor map[uuid.UUID]bool
+	points  []ProjectPoint
+	last    UploadGeometryRequest
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{stored: map[uuid.UUID]*ProjectGeometry{}, failFor: map[uuid.UUID]bool{}}
+}
+
+func (f *fakeRepo) UpsertProjectGeometry(_ context.Context, projectID uuid.UUID, req UploadGeometryRequest) (*ProjectGeometry, error) {
+	f.last = req
+	if f.failFor[projectID] {
+		return nil, fmt.Errorf("insert failed for %s", projectID)
+	}
+	g := &ProjectGeometry{ID: uuid.New(), ProjectID: projectID, AreaHectares: 1.5, Version: 1}
+	f.stored[projectID] = g
+	return g, nil
+}
+
+func (f *fakeRepo) InTransaction(_ context.Context, fn func(tx Repository) error) error {
+	staged := &fakeRepo{stored: map[uuid.UUID]*ProjectGeometry{}, failFor: f.failFor}
+	if err := fn(staged); err != nil {
+		return err
+	}
+	for k, v := range staged.stored {
+		f.stored[k] = v
+	}
+	return nil
+}
+
+func (f *fakeRepo) ProjectCentroids(_ context.Context, _, _, _, _ float64) ([]ProjectPoint, error) {
+	return f.points, nil
+}
+
+func featureCollection(features ...string) BatchImportRequest {
+	raw := `{"type":"FeatureCollection","features":[`
+	for i, f := range features {
+		if i > 0 {
+			raw += ","
+		}
+		raw += f
+	}
+	return BatchImportRequest{GeoJSON: json.RawMessage(raw + "]}")}
+}
+
+func polygonFeature(projectID uuid.UUID) string {
+	return fmt.Sprintf(`{"type":"Feature","properties":{"project_id":%q},"geometry":{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}}`, projectID)
+}
+
+func TestImportFeatureCollection_StrictRejectsInvalidBeforeWriting(t *testing.T) {
+	repo := newFakeRepo()
+	svc := NewService(repo)
+	req := featureCollection(
+		polygonFeature(uuid.New()),
+		`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}`,
+	)
+
+	result, err := svc.ImportFeatureCollection(context.Background(), req, "")
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("expected ErrBatchRejected, got %v", err)
+	}
+	if len(repo.stored) != 0 {
+		t.Errorf("expected nothing stored, got %d", len(repo.stored))
+	}
+	if result.Results[0].Status != BatchFeatureSkipped || result.Results[1].Status != BatchFeatureInvalid {
+		t.Errorf("unexpected statuses: %+v", result.Results)
+	}
+}
+
+func TestImportFeatureCollection_StrictRollsBackOnWriteFailure(t *testing.T) {
+	repo := newFakeRepo()
+	bad := uuid.New()
+	repo.failFor[bad] = true
+	svc := NewService(repo)
+
+	result, err := svc.ImportFeatureCollection(context.Background(), featureCollection(polygonFeature(uuid.New()), polygonFeature(bad)), BatchImportModeStrict)
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("expected ErrBatchRejected, got %v", err)
+	}
+	if len(repo.stored) != 0 {
+		t.Errorf("expected rollback, got %d stored", len(repo.stored))
+	}
+	if result.Results[0].Status != BatchFeatureRolledBack || result.Results[1].Status != BatchFeatureFailed {
+		t.Errorf("unexpected statuses: %+v", result.Results)
+	}
+}
+
+func TestImportFeatureCollection_BestEffortKeepsValidFeatures(t *testing.T) {
+	repo := newFakeRepo()
+	bad := uuid.New()
+	repo.failFor[bad] = true
+	svc := NewService(repo)
+	dup := uuid.New()
+	req := featureCollection(polygonFeature(dup), polygonFeature(bad), polygonFeature(dup))
+
+	result, err := svc.ImportFeatureCollection(context.Background(), req, BatchImportModeBestEffort)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Imported != 1 || result.Failed != 2 {
+		t.Errorf("expected 1 imported and 2 failed, got %d/%d", result.Imported, result.Failed)
+	}
+	if result.Results[2].Status != BatchFeatureInvalid {
+		t.Errorf("expected duplicate project_id to be invalid, got %s", result.Results[2].Status)
+	}
+	if result.Results[0].GeometryID == nil {
+		t.Error("expected geometry id on imported feature")
+	}
+}
+
+func TestImportFeatureCollection_RejectsUnknownMode(t *testing.T) {
+	svc := NewService(newFakeRepo())
+	if _, err := svc.ImportFeatureCollection(context.Background(), featureCollection(polygonFeature(uuid.New())), "partial"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+// snapRepo pretends grid snapping removes two vertices, or collapses the
+// shape when the tolerance is coarser than 0.01 degrees.
+type snapRepo struct {
+	*fakeRepo
+}
+
+func (r *snapRepo) SnapToGrid(_ context.Context, geom json.RawMessage, size float64) (*SnapResult, error) {
+	if size > 0.01 {
+		return &SnapResult{GeoJSON: json.RawMessage(`{"type":"MultiPolygon","coordinates":[]}`), VerticesBefore: 7, VerticesAfter: 0, Reason: "collapsed to an empty geometry"}, nil
+	}
+	return &SnapResult{GeoJSON: geom, VerticesBefore: 7, VerticesAfter: 5, IsValid: true}, nil
+}
+
+func TestImportFeatureCollection_SnapReportsVertexCounts(t *testing.T) {
+	repo := &snapRepo{fakeRepo: newFakeRepo()}
+	svc := NewService(repo)
+	req := featureCollection(polygonFeature(uuid.New()))
+	tolerance := 0.0001
+	req.SnapTolerance = &tolerance
+
+	result, err := svc.ImportFeatureCollection(context.Background(), req, BatchImportModeStrict)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := result.Results[0]
+	if got.VerticesBefore == nil || got.VerticesAfter == nil || *got.VerticesBefore != 7 || *got.VerticesAfter != 5 {
+		t.Fatalf("expected vertex counts 7 -> 5, got %+v", got)
+	}
+
+	coarse := 0.5
+	req.SnapTolerance = &coarse
+	result, err = svc.ImportFeatureCollection(context.Background(), req, BatchImportModeStrict)
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("expected the collapsed feature to reject the batch, got %v", err)
+	}
+	if !strings.Contains(result.Results[0].Error, "invalid") {
+		t.Errorf("expected an invalid-after-snap error, got %q", result.Results[0].Error)
+	}
+
+	zero := 0.0
+	req.SnapTolerance = &zero
+	if _, err := svc.ImportFeatureCollection(context.Background(), req, BatchImportModeStrict); err == nil {
+		t.Error("expected a non-positive tolerance to be rejected")
+	}
+}
+
+// serializationRepo fails the first failures transactions with err, the way
+// Postgres aborts a serializable transaction at commit.
+type serializationRepo struct {
+	*fakeRepo
+	failures int
+	err      error
+	calls    int
+}
+
+func (r *serializationRepo) InTransaction(_ context.Context, fn func(tx Repository) error) error {
+	r.calls++
+	staged := &serializationRepo{fakeRepo: &fakeRepo{stored: map[uuid.UUID]*ProjectGeometry{}, failFor: r.failFor}}
+	if err := fn(staged); err != nil {
+		return err
+	}
+	if r.calls <= r.failures {
+		return r.err
+	}
+	for k, v := range staged.stored {
+		r.stored[k] = v
+	}
+	return nil
+}
+
+func (r *serializationRepo) LockOverlapRegions(context.Context, json.RawMessage) error { return nil }
+
+func (r *serializationRepo) OverlapCandidates(context.Context, json.RawMessage, float64) ([]IntersectResult, OverlapStats, error) {
+	return nil, OverlapStats{}, nil
+}
+
+func TestUploadProjectGeometry_RetriesSerializationFailure(t *testing.T) {
+	projectID := uuid.New()
+	upload := UploadGeometryRequest{GeoJSON: json.RawMessage(`{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}`)}
+	fast := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	repo := &serializationRepo{fakeRepo: newFakeRepo(), failures: 1, err: &pq.Error{Code: "40001", Message: "could not serialize access"}}
+	svc := NewServiceWithOptions(repo, ServiceOptions{RejectOverlaps: true, OverlapScope: OverlapAllProjects, Retry: fast})
+	if _, err := svc.UploadProjectGeometry(context.Background(), projectID, upload); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if repo.calls != 2 || repo.stored[projectID] == nil {
+		t.Fatalf("expected one retry that stored the geometry, got %d calls", repo.calls)
+	}
+
+	repo = &serializationRepo{fakeRepo: newFakeRepo(), failures: 10, err: &pq.Error{Code: "40P01", Message: "deadlock detected"}}
+	svc = NewServiceWithOptions(repo, ServiceOptions{RejectOverlaps: true, OverlapScope: OverlapAllProjects, Retry: fast})
+	_, err := svc.UploadProjectGeometry(context.Background(), projectID, upload)
+	var transient *TransientError
+	if !errors.As(err, &transient) || transient.Attempts != 3 || repo.calls != 3 {
+		t.Fatalf("expected a TransientError after 3 attempts, got %v after %d calls", err, repo.calls)
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "40P01" {
+		t.Errorf("expected the Postgres error to stay reachable, got %v", err)
+	}
+
+	repo = &serializationRepo{fakeRepo: newFakeRepo(), failures: 10, err: &pq.Error{Code: "23505", Message: "duplicate key"}}
+	svc = NewServiceWithOptions(repo, ServiceOptions{RejectOverlaps: true, OverlapScope: OverlapAllProjects, Retry: fast})
+	if _, err := svc.UploadProjectGeometry(context.Background(), projectID, upload); err == nil || errors.As(err, &transient) {
+		t.Fatalf("expected a non-retryable error to surface unchanged, got %v", err)
+	}
+	if repo.calls != 1 {
+		t.Errorf("non-retryable errors must not be retried, got %d calls", repo.calls)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 300 * time.Millisecond, 4: 300 * time.Millisecond} {
+		if got := p.backoff(n); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+// mercatorRepo reprojects EPSG:3857 geometries the way ST_Transform does.
+type mercatorRepo struct {
+	*fakeRepo
+	srids []int
+}
+
+const earthRadius = 6378137.0
+
+func (r *mercatorRepo) TransformToStorageSRID(_ context.Context, geom json.RawMessage, srid int) (json.RawMessage, error) {
+	r.srids = append(r.srids, srid)
+	if srid != 3857 {
+		return nil, fmt.Errorf("unexpected srid %d", srid)
+	}
+	var g struct {
+		Type        string         `json:"type"`
+		Coordinates [][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(geom, &g); err != nil {
+		return nil, err
+	}
+	for _, ring := range g.Coordinates {
+		for i, p := range ring {
+			ring[i] = [2]float64{
+				p[0] / earthRadius * 180 / math.Pi,
+				(2*math.Atan(math.Exp(p[1]/earthRadius)) - math.Pi/2) * 180 / math.Pi,
+			}
+		}
+	}
+	return json.Marshal(g)
+}
+
+// mercatorPolygon is a lon/lat ring projected to EPSG:3857 metres.
+func mercatorPolygon(ring [][2]float64) string {
+	out := make([][2]float64, len(ring))
+	for i, p := range ring {
+		out[i] = [2]float64{
+			p[0] * math.Pi / 180 * earthRadius,
+			math.Log(math.Tan(math.Pi/4+p[1]*math.Pi/360)) * earthRadius,
+		}
+	}
+	coords, _ := json.Marshal([][][2]float64{out})
+	return `{"type":"Polygon","coordinates":` + string(coords) + `}`
+}
+
+func TestUploadProjectGeometry_ReprojectsDeclaredCRS(t *testing.T) {
+	ring := [][2]float64{{36.8, -1.3}, {36.81, -1.3}, {36.81, -1.31}, {36.8, -1.31}, {36.8, -1.3}}
+	polygon := mercatorPolygon(ring)
+	feature := `{"type":"Feature","crs":{"type":"name","properties":{"name":"urn:ogc:def:crs:EPSG::3857"}},"properties":{},"geometry":` + polygon + `}`
+
+	repo := &mercatorRepo{fakeRepo: newFakeRepo()}
+	svc := NewService(repo)
+	if _, err := svc.UploadProjectGeometry(context.Background(), uuid.New(), UploadGeometryRequest{GeoJSON: json.RawMessage(feature)}); err != nil {
+		t.Fatalf("UploadProjectGeometry: %v", err)
+	}
+	var stored struct {
+		Type        string           `json:"type"`
+		Coordinates [][][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(repo.last.GeoJSON, &stored); err != nil {
+		t.Fatalf("decode stored geometry: %v", err)
+	}
+	if stored.Type != "MultiPolygon" || len(stored.Coordinates) != 1 {
+		t.Fatalf("unexpected stored geometry %s", repo.last.GeoJSON)
+	}
+	for i, p := range stored.Coordinates[0][0] {
+		if math.Abs(p[0]-ring[i][0]) > 1e-9 || math.Abs(p[1]-ring[i][1]) > 1e-9 {
+			t.Errorf("vertex %d stored as %v, want %v", i, p, ring[i])
+		}
+	}
+
+	// Without a crs member the coordinates are already WGS 84.
+	repo.srids = nil
+	plain := `{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}`
+	if _, err := svc.UploadProjectGeometry(context.Background(), uuid.New(), UploadGeometryRequest{GeoJSON: json.RawMessage(plain)}); err != nil {
+		t.Fatalf("UploadProjectGeometry: %v", err)
+	}
+	if len(repo.srids) != 0 {
+		t.Errorf("expected no reprojection without a crs member, got %v", repo.srids)
+	}
+
+	unknown := strings.Replace(feature, "urn:ogc:def:crs:EPSG::3857", "EPSG:27700", 1)
+	_, err := svc.UploadProjectGeometry(context.Background(), uuid.New(), UploadGeometryRequest{GeoJSON: json.RawMessage(unknown)})
+	if err == nil || !strings.Contains(err.Error(), "unsupported crs") {
+		t.Fatalf("expected an unsupported crs error, got %v", err)
+	}
+}
+
+func TestImportFeatureCollection_CollectionCRSAppliesToFeatures(t *testing.T) {
+	ring := [][2]float64{{36.8, -1.3}, {36.81, -1.3}, {36.81, -1.31}, {36.8, -1.31}, {36.8, -1.3}}
+	id := uuid.New()
+	collection := `{"type":"FeatureCollection","crs":{"type":"name","properties":{"name":"EPSG:3857"}},"features":[` +
+		`{"type":"Feature","properties":{"project_id":"` + id.String() + `"},"geometry":` + mercatorPolygon(ring) + `}]}`
+
+	repo := &mercatorRepo{fakeRepo: newFakeRepo()}
+	result, err := NewService(repo).ImportFeatureCollection(context.Background(), BatchImportRequest{GeoJSON: json.RawMessage(collection)}, BatchImportModeStrict)
+	if err != nil {
+		t.Fatalf("ImportFeatureCollection: %v (%+v)", err, result)
+	}
+	if result.Imported != 1 || len(repo.srids) != 1 || repo.srids[0] != 3857 {
+		t.Fatalf("expected one feature reprojected from EPSG:3857, got %+v and %v", result, repo.srids)
+	}
+}
+
+// overlapRepo reports a fixed set of intersections and records whether the
+// overlap check ran inside a transaction holding the region locks.
+type overlapRepo struct {
+	*fakeRepo
+	existing  []IntersectResult
+	inTx      bool
+	locked    bool
+	tolerance float64
+}
+
+func (r *overlapRepo) InTransaction(_ context.Context, fn func(tx Repository) error) error {
+	r.inTx = true
+	defer func() { r.inTx, r.locked = false, false }()
+	return fn(r)
+}
+
+func (r *overlapRepo) LockOverlapRegions(context.Context, json.RawMessage) error {
+	r.locked = r.inTx
+	return nil
+}
+
+func (r *overlapRepo) OverlapCandidates(_ context.Context, _ json.RawMessage, tolerance float64) ([]IntersectResult, OverlapStats, error) {
+	if !r.locked {
+		return nil, OverlapStats{}, errors.New("overlap check ran without the region locks")
+	}
+	r.tolerance = tolerance
+	return r.existing, OverlapStats{BBoxCandidates: len(r.existing), ExactComparisons: len(r.existing)}, nil
+}
+
+func TestUploadProjectGeometry_RejectsOverlapInsideTransaction(t *testing.T) {
+	self, neighbour, touching := uuid.New(), uuid.New(), uuid.New()
+	repo := &overlapRepo{fakeRepo: newFakeRepo(), existing: []IntersectResult{
+		{ProjectID: self, Intersects: true, IntersectionArea: 9},
+		{ProjectID: touching, Intersects: true},
+	}}
+	svc := NewServiceWithOptions(repo, ServiceOptions{RejectOverlaps: true})
+	upload := UploadGeometryRequest{GeoJSON: json.RawMessage(`{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}`)}
+
+	if _, err := svc.UploadProjectGeometry(context.Background(), self, upload); err != nil {
+		t.Fatalf("re-uploading over its own boundary or touching a neighbour should succeed: %v", err)
+	}
+
+	repo.existing = append(repo.existing, IntersectResult{ProjectID: neighbour, Intersects: true, IntersectionArea: 0.4})
+	_, err := svc.UploadProjectGeometry(context.Background(), self, upload)
+	var overlapErr *OverlapError
+	if !errors.As(err, &overlapErr) || !errors.Is(err, ErrGeometryOverlap) {
+		t.Fatalf("expected an OverlapError, got %v", err)
+	}
+	if len(overlapErr.Overlaps) != 1 || overlapErr.Overlaps[0].ProjectID != neighbour {
+		t.Errorf("expected only the neighbour to be reported, got %+v", overlapErr.Overlaps)
+	}
+}
+
+func TestUploadProjectGeometry_PassesOverlapSimplifyTolerance(t *testing.T) {
+	repo := &overlapRepo{fakeRepo: newFakeRepo()}
+	svc := NewServiceWithOptions(repo, ServiceOptions{RejectOverlaps: true, OverlapSimplifyTolerance: 0.0005})
+	upload := UploadGeometryRequest{GeoJSON: json.RawMessage(`{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}`)}
+	if _, err := svc.UploadProjectGeometry(context.Background(), uuid.New(), upload); err != nil {
+		t.Fatalf("UploadProjectGeometry: %v", err)
+	}
+	if repo.tolerance != 0.0005 {
+		t.Errorf("expected the configured tolerance to reach the overlap query, got %v", repo.tolerance)
+	}
+}
+
+// ownedOverlapRepo is overlapRepo with project owners.
+type ownedOverlapRepo struct {
+	*overlapRepo
+	owners map[uuid.UUID]*uuid.UUID
+}
+
+func (r *ownedOverlapRepo) InTransaction(_ context.Context, fn func(tx Repository) error) error {
+	r.inTx = true
+	defer func() { r.inTx, r.locked = false, false }()
+	return fn(r)
+}
+
+func (r *ownedOverlapRepo) GetProjectOwner(_ context.Context, projectID uuid.UUID) (*uuid.UUID, error) {
+	return r.owners[projectID], nil
+}
+
+func TestUploadProjectGeometry_SameOwnerOverlapScope(t *testing.T) {
+	self, sibling, foreign := uuid.New(), uuid.New(), uuid.New()
+	orgA, orgB := uuid.New(), uuid.New()
+	repo := &ownedOverlapRepo{
+		overlapRepo: &overlapRepo{fakeRepo: newFakeRepo(), existing: []IntersectResult{
+			{ProjectID: foreign, Intersects: true, IntersectionArea: 2, OwnerID: &orgB},
+		}},
+		owners: map[uuid.UUID]*uuid.UUID{self: &orgA},
+	}
+	upload := UploadGeometryRequest{GeoJSON: json.RawMessage(`{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}`)}
+
+	if _, err := NewServiceWithOptions(repo, ServiceOptions{RejectOverlaps: true}).UploadProjectGeometry(context.Background(), self, upload); !errors.Is(err, ErrGeometryOverlap) {
+		t.Fatalf("all-projects scope: expected ErrGeometryOverlap, got %v", err)
+	}
+	svc := NewServiceWithOptions(repo, ServiceOptions{RejectOverlaps: true, OverlapScope: OverlapSameOwner})
+	if _, err := svc.UploadProjectGeometry(context.Background(), self, upload); err != nil {
+		t.Fatalf("owner scope: another owner's project must not conflict: %v", err)
+	}
+
+	repo.existing = append(repo.existing, IntersectResult{ProjectID: sibling, Intersects: true, IntersectionArea: 1, OwnerID: &orgA})
+	var overlapErr *OverlapError
+	if _, err := svc.UploadProjectGeometry(context.Background(), self, upload); !errors.As(err, &overlapErr) {
+		t.Fatalf("owner scope: expected an OverlapError for the same owner's project, got %v", err)
+	}
+	if len(overlapErr.Overlaps) != 1 || overlapErr.Overlaps[0].ProjectID != sibling {
+		t.Errorf("expected only the sibling project to be reported, got %+v", overlapErr.Overlaps)
+	}
+}
+
+func TestParseOverlapScope(t *testing.T) {
+	for raw, want := range map[string]OverlapScope{"": OverlapAllProjects, "all": OverlapAllProjects, "owner": OverlapSameOwner} {
+		if got, err := ParseOverlapScope(raw); err != nil || got != want {
+			t.Errorf("ParseOverlapScope(%q) = %q, %v", raw, got, err)
+		}
+	}
+	if _, err := ParseOverlapScope("org"); err == nil {
+		t.Error("expected an error for an unknown scope")
+	}
+}
+
+func TestOverlapLockKeys(t *testing.T) {
+	keys, exclusive := overlapLockKeys(36.2, -1.8, 36.4, -1.2)
+	if exclusive || len(keys) != 1 {
+		t.Fatalf("expected one cell for a small box, got %v (exclusive=%v)", keys, exclusive)
+	}
+
+	straddling, _ := overlapLockKeys(35.9, -1.1, 36.1, -0.9)
+	if len(straddling) != 4 {
+		t.Fatalf("expected four cells for a box across two grid lines, got %v", straddling)
+	}
+	for i := 1; i < len(straddling); i++ {
+		if straddling[i-1] >= straddling[i] {
+			t.Fatalf("keys must be strictly ascending to avoid deadlocks: %v", straddling)
+		}
+	}
+	if !containsKey(straddling, keys[0]) {
+		t.Error("overlapping boxes must share a lock key")
+	}
+
+	if _, exclusive := overlapLockKeys(-20, -30, 40, 30); !exclusive {
+		t.Error("expected a continent-sized box to take the global lock")
+	}
+}
+
+// measureRepo returns fixed overlap measurements.
+type measureRepo struct {
+	*fakeRepo
+	measures []OverlapMeasure
+}
+
+func (r *measureRepo) MeasureOverlaps(context.Context, json.RawMessage) ([]OverlapMeasure, error) {
+	return r.measures, nil
+}
+
+func TestPreviewOverlaps_PartialAndContained(t *testing.T) {
+	half, container, self := uuid.New(), uuid.New(), uuid.New()
+	repo := &measureRepo{fakeRepo: newFakeRepo(), measures: []OverlapMeasure{
+		// The new boundary lies inside a project four times its size; the
+		// intersection area is a hair off the new boundary's own area.
+		{ProjectID: container, NewAreaHectares: 100, ExistingAreaHectares: 400, OverlapAreaHectares: 99.9999999, ExistingCoversNew: true},
+		// Equal-sized boundaries sharing half their area.
+		{ProjectID: half, NewAreaHectares: 100, ExistingAreaHectares: 100, OverlapAreaHectares: 50},
+		{ProjectID: self, NewAreaHectares: 100, ExistingAreaHectares: 100, OverlapAreaHectares: 100, NewCoversExisting: true, ExistingCoversNew: true},
+	}}
+	svc := NewService(repo)
+	req := OverlapPreviewRequest{
+		GeoJSON:   json.RawMessage(`{"type":"Polygon","coordinates":[[[36.8,-1.3],[36.81,-1.3],[36.81,-1.31],[36.8,-1.31],[36.8,-1.3]]]}`),
+		ProjectID: &self,
+	}
+
+	overlaps, err := svc.PreviewOverlaps(context.Background(), req)
+	if err != nil {
+		t.Fatalf("PreviewOverlaps: %v", err)
+	}
+	if len(overlaps) != 2 {
+		t.Fatalf("expected the project's own boundary to be left out, got %+v", overlaps)
+	}
+	within := overlaps[0]
+	if within.ProjectID != container || within.Relation != OverlapWithin || within.PercentOfNew != 100 || within.PercentOfExisting != 25 {
+		t.Errorf("containment: got %+v", within)
+	}
+	partial := overlaps[1]
+	if partial.ProjectID != half || partial.Relation != OverlapPartial || partial.PercentOfNew != 50 || partial.PercentOfExisting != 50 {
+		t.Errorf("50%% overlap: got %+v", partial)
+	}
+
+	req.ProjectID = nil
+	overlaps, _ = svc.PreviewOverlaps(context.Background(), req)
+	if len(overlaps) != 3 || overlaps[2].Relation != OverlapEqual {
+		t.Errorf("expected an identical boundary to be reported as equal, got %+v", overlaps)
+	}
+
+	repo.measures = []OverlapMeasure{{ProjectID: half, NewAreaHectares: 400, ExistingAreaHectares: 100, OverlapAreaHectares: 100, NewCoversExisting: true}}
+	overlaps, _ = svc.PreviewOverlaps(context.Background(), req)
+	if overlaps[0].Relation != OverlapContains || overlaps[0].PercentOfNew != 25 || overlaps[0].PercentOfExisting != 100 {
+		t.Errorf("new boundary covering an existing one: got %+v", overlaps[0])
+	}
+
+	if _, err := svc.PreviewOverlaps(context.Background(), OverlapPreviewRequest{GeoJSON: json.RawMessage(`{"type":"Point"}`)}); err == nil {
+		t.Error("expected invalid GeoJSON to be rejected")
+	}
+}
+
+func containsKey(keys []int64, key int64) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClusterProjects_CollapsesAtLowZoomAndSeparatesAtHighZoom(t *testing.T) {
+	repo := newFakeRepo()
+	// Two dense groups of ten projects roughly 55km apart in central Kenya.
+	for i := 0; i < 10; i++ {
+		offset := float64(i) * 0.001
+		repo.points = append(repo.points,
+			ProjectPoint{ProjectID: uuid.New(), Lat: -1.25 - offset, Lon: 36.75 + offset},
+			ProjectPoint{ProjectID: uuid.New(), Lat: -1.25 - offset, Lon: 37.25 + offset},
+		)
+	}
+	svc := NewService(repo)
+	minLat, minLon, maxLat, maxLon := -2.0, 36.0, 0.0, 38.0
+	query := ClusterQuery{MinLat: &minLat, MinLon: &minLon, MaxLat: &maxLat, MaxLon: &maxLon}
+
+	query.Zoom = 4
+	low, err := svc.ClusterProjects(context.Background(), query)
+	if err != nil {
+		t.Fatalf("ClusterProjects: %v", err)
+	}
+	if len(low.Clusters) != 1 || low.Clusters[0].Count != 20 || len(low.Projects) != 0 {
+		t.Fatalf("expected a single cluster of 20 at low zoom, got %+v", low)
+	}
+	b := low.Clusters[0].Bounds
+	if b[0] > 36.75 || b[2] < 37.259 || b[1] > -1.259 || b[3] < -1.25 {
+		t.Errorf("cluster bounds do not cover all members: %v", b)
+	}
+
+	query.Zoom = 8
+	mid, err := svc.ClusterProjects(context.Background(), query)
+	if err != nil {
+		t.Fatalf("ClusterProjects: %v", err)
+	}
+	if len(mid.Clusters) != 2 {
+		t.Fatalf("expected the two groups to separate, got %d clusters", len(mid.Clusters))
+	}
+
+	query.Zoom = 18
+	high, err := svc.ClusterProjects(context.Background(), query)
+	if err != nil {
+		t.Fatalf("ClusterProjects: %v", err)
+	}
+	if len(high.Clusters) != 0 || len(high.Projects) != 20 {
+		t.Errorf("expected individual projects at high zoom, got %d clusters and %d projects", len(high.Clusters), len(high.Projects))
+	}
+}
+
+func TestClusterProjects_RequiresBBox(t *testing.T) {
+	svc := NewService(newFakeRepo())
+	if _, err := svc.ClusterProjects(context.Background(), ClusterQuery{Zoom: 3}); err == nil {
+		t.Fatal("expected an error without a bbox")
+	}
+}
+
+// areaRepo answers area checks with a fixed area.
+type areaRepo struct {
+	*fakeRepo
+	area float64
+}
+
+func (a *areaRepo) GetProjectType(_ context.Context, _ uuid.UUID) (string, error) {
+	return "Reforestation", nil
+}
+
+func (a *areaRepo) MeasureAreaHectares(_ context.Context, _ json.RawMessage) (float64, error) {
+	return a.area, nil
+}
+
+func uploadWithArea(t *testing.T, area float64) (*ProjectGeometry, error) {
+	t.Helper()
+	repo := &areaRepo{fakeRepo: newFakeRepo(), area: area}
+	policy, err := ParseAreaPolicy("reforestation=0.5:5:200000:1000000")
+	if err != nil {
+		t.Fatalf("ParseAreaPolicy: %v", err)
+	}
+	svc := NewServiceWithOptions(repo, ServiceOptions{AreaPolicy: policy})
+	square := json.RawMessage(`{"type":"Polygon","coordinates":[[[36.0,-1.0],[36.01,-1.0],[36.01,-1.01],[36.0,-1.01],[36.0,-1.0]]]}`)
+	return svc.UploadProjectGeometry(context.Background(), uuid.New(), UploadGeometryRequest{GeoJSON: square})
+}
+
+func TestUploadProjectGeometry_RejectsTooSmallArea(t *testing.T) {
+	_, err := uploadWithArea(t, 0.0001)
+	var areaErr *AreaOutOfRangeError
+	if !errors.As(err, &areaErr) {
+		t.Fatalf("expected AreaOutOfRangeError, got %v", err)
+	}
+	if areaErr.AreaHectares != 0.0001 || areaErr.MinHectares != 0.5 {
+		t.Errorf("unexpected error details: %+v", areaErr)
+	}
+}
+
+func TestUploadProjectGeometry_RejectsTooLargeArea(t *testing.T) {
+	_, err := uploadWithArea(t, 10000000)
+	var areaErr *AreaOutOfRangeError
+	if !errors.As(err, &areaErr) || areaErr.MaxHectares != 1000000 {
+		t.Fatalf("expected AreaOutOfRangeError with the reforestation max, got %v", err)
+	}
+}
+
+func TestUploadProjectGeometry_WarnsInSuspiciousBand(t *testing.T) {
+	stored, err := uploadWithArea(t, 2)
+	if err != nil {
+		t.Fatalf("expected suspicious area to be accepted, got %v", err)
+	}
+	if len(stored.Warnings) != 1 {
+		t.Fatalf("expected one warning, got %v", stored.Warnings)
+	}
+}
+
+func TestUploadProjectGeometry_PlausibleAreaHasNoWarning(t *testing.T) {
+	stored, err := uploadWithArea(t, 120)
+	if err != nil || len(stored.Warnings) != 0 {
+		t.Fatalf("expected a clean upload, got %v / %v", err, stored)
+	}
+}
+
+func TestParseAreaPolicy(t *testing.T) {
+	policy, err := ParseAreaPolicy("default=1:2:3:4; Agroforestry=0.2:1:50:100")
+	if err != nil {
+		t.Fatalf("ParseAreaPolicy: %v", err)
+	}
+	if policy.For("agroforestry").Max != 100 || policy.For("unknown").Max != 4 {
+		t.Errorf("unexpected policy: %+v", policy)
+	}
+	if _, err := ParseAreaPolicy("reforestation=1:2:3"); err == nil {
+		t.Error("expected an error for an incomplete entry")
+	}
+}
+
+func TestUploadProjectGeometry_NormalizesPolygonWithHoleToMultiPolygon(t *testing.T) {
+	repo := newFakeRepo()
+	svc := NewService(repo)
+	// A 0.02° square with a 0.01° lake cut out of the middle.
+	withHole := json.RawMessage(`{"type":"Polygon","coordinates":[
+		[[36.0,-1.0],[36.02,-1.0],[36.02,-1.02],[36.0,-1.02],[36.0,-1.0]],
+		[[36.005,-1.005],[36.005,-1.015],[36.015,-1.015],[36.015,-1.005],[36.005,-1.005]]
+	]}`)
+
+	if _, err := svc.UploadProjectGeometry(context.Background(), uuid.New(), UploadGeometryRequest{GeoJSON: withHole}); err != nil {
+		t.Fatalf("UploadProjectGeometry: %v", err)
+	}
+	var stored struct {
+		Type        string          `json:"type"`
+		Coordinates [][][][]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(repo.last.GeoJSON, &stored); err != nil {
+		t.Fatalf("decode stored geometry: %v", err)
+	}
+	if stored.Type != "MultiPolygon" || len(stored.Coordinates) != 1 || len(stored.Coordinates[0]) != 2 {
+		t.Errorf("expected a single-part MultiPolygon keeping its hole, got %s", repo.last.GeoJSON)
+	}
+}
+
+func TestUploadProjectGeometry_RejectsNonPolygonalBoundary(t *testing.T) {
+	svc := NewService(newFakeRepo())
+	point := json.RawMessage(`{"type":"Point","coordinates":[36.8,-1.3]}`)
+	if _, err := svc.UploadProjectGeometry(context.Background(), uuid.New(), UploadGeometryRequest{GeoJSON: point}); err == nil {
+		t.Fatal("expected a Point boundary to be rejected")
+	}
+}
+
+// validityRepo reports bow-tie rings as self-intersecting and every valid
+// geometry as overlapping one existing project.
+type validityRepo struct {
+	*fakeRepo
+	neighbour uuid.UUID
+}
+
+func (v *validityRepo) CheckGeometry(_ context.Context, geom json.RawMessage) (*GeometryCheck, error) {
+	if strings.Contains(string(geom), "[36.01,-1.01],[36.01,-1]") {
+		return &GeometryCheck{IsValid: false, Reason: "Self-intersection[36.005 -1.005]", AreaHectares: 0}, nil
+	}
+	return &GeometryCheck{IsValid: true, AreaHectares: 123.4}, nil
+}
+
+func (v *validityRepo) Intersect(_ context.Context, _ json.RawMessage, _ bool) ([]IntersectResult, error) {
+	return []IntersectResult{{ProjectID: v.neighbour, IntersectionArea: 2.5, Intersects: true}}, nil
+}
+
+func TestValidateGeometries_ReportsPerFeatureReasons(t *testing.T) {
+	repo := &validityRepo{fakeRepo: newFakeRepo(), neighbour: uuid.New()}
+	svc := NewService(repo)
+
+	bowtie := `{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[36,-1],[36.01,-1.01],[36.01,-1],[36,-1.01],[36,-1]]]}}`
+	point := `{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[36,-1]}}`
+	badID := `{"type":"Feature","properties":{"project_id":"nope"},"geometry":{"type":"Polygon","coordinates":[[[36,-1],[36.01,-1],[36.01,-1.01],[36,-1.01],[36,-1]]]}}`
+	req := ValidateGeometryRequest{GeoJSON: featureCollection(polygonFeature(uuid.New()), bowtie, point, badID).GeoJSON}
+
+	report, err := svc.ValidateGeometries(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ValidateGeometries: %v", err)
+	}
+	if report.Total != 4 || report.Valid != 1 || report.Invalid != 3 {
+		t.Fatalf("expected 1 valid and 3 invalid of 4, got %+v", report)
+	}
+
+	good := report.Features[0]
+	if !good.Valid || good.AreaHectares != 123.4 {
+		t.Errorf("feature 0: expected valid with area, got %+v", good)
+	}
+	if len(good.Overlaps) != 1 || good.Overlaps[0].ProjectID != repo.neighbour || len(good.Warnings) != 1 {
+		t.Errorf("feature 0: expected an overlap warning, got %+v", good)
+	}
+
+	reasons := []string{"Self-intersection", "Point", "project_id"}
+	for i, want := range reasons {
+		f := report.Features[i+1]
+		if f.Valid || !strings.Contains(f.Reason, want) {
+			t.Errorf("feature %d: expected invalid with reason containing %q, got %+v", i+1, want, f)
+		}
+	}
+	if len(repo.stored) != 0 {
+		t.Fatalf("dry run must not store anything, stored %d", len(repo.stored))
+	}
+}
+
+func TestValidateGeometries_AcceptsSingleGeometry(t *testing.T) {
+	svc := NewService(&validityRepo{fakeRepo: newFakeRepo(), neighbour: uuid.New()})
+	square := json.RawMessage(`{"type":"Polygon","coordinates":[[[36.0,-1.0],[36.01,-1.0],[36.01,-1.01],[36.0,-1.01],[36.0,-1.0]]]}`)
+
+	report, err := svc.ValidateGeometries(context.Background(), ValidateGeometryRequest{GeoJSON: square})
+	if err != nil {
+		t.Fatalf("ValidateGeometries: %v", err)
+	}
+	if report.Total != 1 || !report.Features[0].Valid {
+		t.Fatalf("expected one valid geometry, got %+v", report)
+	}
+}
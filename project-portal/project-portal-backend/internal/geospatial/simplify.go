@@ -0,0 +1,95 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MaxSimplifyTolerance caps a simplification tolerance, in degrees (about
+// 11 km at the equator); anything coarser erases a project boundary.
+const MaxSimplifyTolerance = 0.1
+
+// ErrGeometryChanged refuses to store a simplification previewed against a
+// boundary version that has since been replaced.
+var ErrGeometryChanged = errors.New("project boundary changed since the preview")
+
+// SimplifyPreview is a stored boundary as ST_SimplifyPreserveTopology at
+// Tolerance would leave it. Version is the stored version it was made from;
+// AreaDeltaHectares is the simplified area less the stored one.
+type SimplifyPreview struct {
+	ProjectID          uuid.UUID       `json:"project_id"`
+	Version            int             `json:"version"`
+	Tolerance          float64         `json:"tolerance"`
+	Geometry           json.RawMessage `json:"geometry"`
+	VerticesBefore     int             `json:"vertices_before"`
+	VerticesAfter      int             `json:"vertices_after"`
+	AreaBeforeHectares float64         `json:"area_before_hectares"`
+	AreaAfterHectares  float64         `json:"area_after_hectares"`
+	AreaDeltaHectares  float64         `json:"area_delta_hectares"`
+	AreaDeltaPercent   float64         `json:"area_delta_percent"`
+	IsValid            bool            `json:"is_valid"`
+	Reason             string          `json:"reason,omitempty"`
+}
+
+// SimplifyRequest stores a previewed simplification. Version, when set, is
+// the version the preview was made from; the request fails with
+// ErrGeometryChanged if the stored boundary is newer.
+type SimplifyRequest struct {
+	Tolerance float64 `json:"tolerance" binding:"required,gt=0"`
+	Version   int     `json:"version" binding:"omitempty,min=1"`
+}
+
+// PreviewSimplification simplifies projectID's boundary at tolerance degrees
+// and measures the result. Nothing is stored.
+func (s *service) PreviewSimplification(ctx context.Context, projectID uuid.UUID, tolerance float64) (*SimplifyPreview, error) {
+	if tolerance <= 0 || tolerance > MaxSimplifyTolerance {
+		return nil, fmt.Errorf("tolerance must be greater than 0 and at most %g degrees", MaxSimplifyTolerance)
+	}
+	preview, err := dbCall(ctx, s, weightQuery, func() (*SimplifyPreview, error) {
+		return s.repo.SimplifyPreview(ctx, projectID, tolerance)
+	})
+	if err != nil {
+		return nil, err
+	}
+	preview.ProjectID, preview.Tolerance = projectID, tolerance
+	preview.AreaDeltaHectares = preview.AreaAfterHectares - preview.AreaBeforeHectares
+	if preview.AreaBeforeHectares > 0 {
+		preview.AreaDeltaPercent = preview.AreaDeltaHectares / preview.AreaBeforeHectares * 100
+	}
+	return preview, nil
+}
+
+// ApplySimplification stores projectID's boundary simplified at
+// req.Tolerance as a new version, subject to the same checks as an upload.
+// A simplification that would leave an invalid boundary is refused.
+func (s *service) ApplySimplification(ctx context.Context, projectID uuid.UUID, req SimplifyRequest) (*ProjectGeometry, error) {
+	preview, err := s.PreviewSimplification(ctx, projectID, req.Tolerance)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version != 0 && req.Version != preview.Version {
+		return nil, fmt.Errorf("%w: previewed version %d, stored version %d", ErrGeometryChanged, req.Version, preview.Version)
+	}
+	if !preview.IsValid {
+		return nil, fmt.Errorf("simplified boundary is invalid: %s", preview.Reason)
+	}
+	stored, err := s.GetProjectGeometry(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if stored.Version != preview.Version {
+		return nil, fmt.Errorf("%w: previewed version %d, stored version %d", ErrGeometryChanged, preview.Version, stored.Version)
+	}
+	tolerance := req.Tolerance
+	return s.UploadProjectGeometry(ctx, projectID, UploadGeometryRequest{
+		GeoJSON:                 stored.GeometryGeoJSON,
+		SimplificationTolerance: &tolerance,
+		SourceType:              stored.SourceType,
+		SourceFile:              stored.SourceFile,
+		AccuracyScore:           stored.AccuracyScore,
+	})
+}
@@ -0,0 +1,78 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// simplifyRepo previews a simplification of the one boundary it holds: 40
+// vertices and 12 ha down to 5 vertices and 11.5 ha.
+type simplifyRepo struct {
+	*fakeRepo
+	projectID uuid.UUID
+	version   int
+}
+
+func (r *simplifyRepo) SimplifyPreview(_ context.Context, projectID uuid.UUID, _ float64) (*SimplifyPreview, error) {
+	if projectID != r.projectID {
+		return nil, sql.ErrNoRows
+	}
+	return &SimplifyPreview{
+		Version:            r.version,
+		Geometry:           json.RawMessage(`{"type":"MultiPolygon","coordinates":[]}`),
+		VerticesBefore:     40,
+		VerticesAfter:      5,
+		AreaBeforeHectares: 12,
+		AreaAfterHectares:  11.5,
+		IsValid:            true,
+	}, nil
+}
+
+func TestSimplification_PreviewAndStaleConfirm(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &simplifyRepo{fakeRepo: newFakeRepo(), projectID: uuid.New(), version: 3}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	send := func(method, projectID, query, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/v1/geospatial/projects/"+projectID+"/geometry/simplify"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	id := repo.projectID.String()
+
+	w := send(http.MethodGet, id, "?tolerance=0.0001", "")
+	var preview SimplifyPreview
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if preview.AreaDeltaHectares != -0.5 || math.Abs(preview.AreaDeltaPercent+0.5/12*100) > 1e-9 || preview.Version != 3 || preview.Tolerance != 0.0001 {
+		t.Errorf("unexpected preview %+v", preview)
+	}
+	if len(repo.stored) != 0 {
+		t.Error("a preview must not store anything")
+	}
+
+	for _, q := range []string{"", "?tolerance=abc", "?tolerance=0", "?tolerance=-1", "?tolerance=5"} {
+		if w := send(http.MethodGet, id, q, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", q, w.Code)
+		}
+	}
+	if w := send(http.MethodGet, uuid.NewString(), "?tolerance=0.0001", ""); w.Code != http.StatusNotFound {
+		t.Errorf("project without a boundary: expected 404, got %d", w.Code)
+	}
+	if w := send(http.MethodPost, id, "", `{"tolerance":0.0001,"version":2}`); w.Code != http.StatusConflict || len(repo.stored) != 0 {
+		t.Errorf("stale version: expected 409 with nothing stored, got %d: %s", w.Code, w.Body.String())
+	}
+}
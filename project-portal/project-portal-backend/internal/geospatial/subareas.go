@@ -0,0 +1,122 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial/geometry"
+	pkggeojson "carbon-scribe/project-portal/project-portal-backend/pkg/geojson"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/google/uuid"
+)
+
+// ErrSubAreaOutsideParent is returned when a sub-area is not within its
+// project's boundary.
+var ErrSubAreaOutsideParent = errors.New("sub-area is not within the project boundary")
+
+// SubArea is a delineated part of a project, such as a stand or parcel. It
+// always lies within the project's boundary.
+type SubArea struct {
+	ID           uuid.UUID       `json:"id"`
+	ProjectID    uuid.UUID       `json:"project_id"`
+	Name         string          `json:"name"`
+	Kind         string          `json:"kind,omitempty"`
+	Geometry     json.RawMessage `json:"geometry"`
+	AreaHectares float64         `json:"area_hectares"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// CreateSubAreaRequest adds a sub-area to a project. Kind is free text such
+// as "stand" or "parcel".
+type CreateSubAreaRequest struct {
+	Name    string          `json:"name" binding:"required,max=255"`
+	Kind    string          `json:"kind,omitempty" binding:"max=50"`
+	GeoJSON json.RawMessage `json:"geojson" binding:"required"`
+}
+
+// SubAreaList is a project's sub-areas within its current boundary.
+// CoveredFraction is the sum of their areas over the project's; sub-areas
+// are not required to be disjoint, so it can exceed 1.
+type SubAreaList struct {
+	ProjectID           uuid.UUID `json:"project_id"`
+	SubAreas            []SubArea `json:"sub_areas"`
+	Count               int       `json:"count"`
+	TotalAreaHectares   float64   `json:"total_area_hectares"`
+	ProjectAreaHectares float64   `json:"project_area_hectares"`
+	CoveredFraction     float64   `json:"covered_fraction"`
+}
+
+// CreateSubArea validates req's polygon like a project boundary and stores it
+// under projectID, provided it lies within the project's boundary. A project
+// without a boundary yields sql.ErrNoRows.
+func (s *service) CreateSubArea(ctx context.Context, projectID uuid.UUID, req CreateSubAreaRequest) (*SubArea, error) {
+	if err := pkggeojson.ValidateRFC7946(req.GeoJSON); err != nil {
+		return nil, err
+	}
+	srid, err := geometry.DetectSRID(req.GeoJSON)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := s.toStorageSRID(ctx, req.GeoJSON, srid)
+	if err != nil {
+		return nil, err
+	}
+	if err := geometry.ValidateBoundary(raw); err != nil {
+		return nil, err
+	}
+	req.GeoJSON, err = geometry.ToMultiPolygon(geometry.ExtractGeometry(raw))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVertices(ctx, req.GeoJSON); err != nil {
+		return nil, err
+	}
+
+	created, err := dbCall(ctx, s, weightQuery, func() (*SubArea, error) {
+		return s.repo.CreateSubArea(ctx, projectID, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("project sub-area stored", "project_id", projectID, "sub_area_id", created.ID, "area_hectares", created.AreaHectares)
+	return created, nil
+}
+
+// ListSubAreas returns the sub-areas of projectID that lie within its current
+// boundary, with their total area. Sub-areas left outside by a later change
+// of the boundary are not listed.
+func (s *service) ListSubAreas(ctx context.Context, projectID uuid.UUID) (*SubAreaList, error) {
+	type listed struct {
+		subAreas    []SubArea
+		projectArea float64
+	}
+	got, err := dbCall(ctx, s, weightQuery, func() (listed, error) {
+		subAreas, projectArea, err := s.repo.ListSubAreas(ctx, projectID)
+		return listed{subAreas, projectArea}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SubAreaList{ProjectID: projectID, SubAreas: got.subAreas, Count: len(got.subAreas), ProjectAreaHectares: got.projectArea}
+	if out.SubAreas == nil {
+		out.SubAreas = []SubArea{}
+	}
+	for _, sa := range out.SubAreas {
+		out.TotalAreaHectares += sa.AreaHectares
+	}
+	if out.ProjectAreaHectares > 0 {
+		out.CoveredFraction = out.TotalAreaHectares / out.ProjectAreaHectares
+	}
+	return out, nil
+}
+
+// subAreaOutside wraps ErrSubAreaOutsideParent with the part of the sub-area
+// that falls outside, in hectares.
+func subAreaOutside(outsideHectares float64) error {
+	return fmt.Errorf("%w: %.4f ha lies outside", ErrSubAreaOutsideParent, outsideHectares)
+}
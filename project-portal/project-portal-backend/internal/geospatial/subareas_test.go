@@ -0,0 +1,113 @@
+package geospatial
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// subAreaRepo keeps sub-areas in memory. A project's boundary is an
+// axis-aligned box and a sub-area is within it when all its vertices are.
+type subAreaRepo struct {
+	Repository
+	boundaries map[uuid.UUID][4]float64
+	subAreas   []SubArea
+}
+
+func (r *subAreaRepo) CreateSubArea(_ context.Context, projectID uuid.UUID, req CreateSubAreaRequest) (*SubArea, error) {
+	box, ok := r.boundaries[projectID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	var multi struct {
+		Coordinates [][][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(req.GeoJSON, &multi); err != nil {
+		return nil, err
+	}
+	var points [][2]float64
+	for _, polygon := range multi.Coordinates {
+		for _, ring := range polygon {
+			points = append(points, ring...)
+		}
+	}
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		if p[0] < box[0] || p[1] < box[1] || p[0] > box[2] || p[1] > box[3] {
+			return nil, subAreaOutside(0)
+		}
+		minX, minY, maxX, maxY = math.Min(minX, p[0]), math.Min(minY, p[1]), math.Max(maxX, p[0]), math.Max(maxY, p[1])
+	}
+	sa := SubArea{ID: uuid.New(), ProjectID: projectID, Name: req.Name, Kind: req.Kind, Geometry: req.GeoJSON, AreaHectares: (maxX - minX) * (maxY - minY) * 1e6}
+	r.subAreas = append(r.subAreas, sa)
+	return &sa, nil
+}
+
+func (r *subAreaRepo) ListSubAreas(_ context.Context, projectID uuid.UUID) ([]SubArea, float64, error) {
+	box, ok := r.boundaries[projectID]
+	if !ok {
+		return nil, 0, sql.ErrNoRows
+	}
+	var out []SubArea
+	for _, sa := range r.subAreas {
+		if sa.ProjectID == projectID {
+			out = append(out, sa)
+		}
+	}
+	return out, (box[2] - box[0]) * (box[3] - box[1]) * 1e6, nil
+}
+
+func TestProjectSubAreas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	id := uuid.New()
+	repo := &subAreaRepo{boundaries: map[uuid.UUID][4]float64{id: {36.0, -1.02, 36.02, -1.0}}}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	create := func(projectID uuid.UUID, ring string) *httptest.ResponseRecorder {
+		body := `{"name":"Stand","kind":"stand","geojson":{"type":"Polygon","coordinates":[` + ring + `]}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/projects/"+projectID.String()+"/sub-areas", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, ring := range []string{
+		`[[36.0,-1.0],[36.0,-1.01],[36.01,-1.01],[36.01,-1.0],[36.0,-1.0]]`,
+		`[[36.01,-1.01],[36.01,-1.02],[36.02,-1.02],[36.02,-1.01],[36.01,-1.01]]`,
+	} {
+		if w := create(id, ring); w.Code != http.StatusCreated {
+			t.Fatalf("contained stand: expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	poking := `[[36.015,-1.0],[36.015,-1.01],[36.03,-1.01],[36.03,-1.0],[36.015,-1.0]]`
+	if w := create(id, poking); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("stand crossing the boundary: expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := create(uuid.New(), poking); w.Code != http.StatusNotFound {
+		t.Errorf("project without a boundary: expected 404, got %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/projects/"+id.String()+"/sub-areas", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var list SubAreaList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if list.Count != 2 || math.Abs(list.TotalAreaHectares-200) > 1e-6 || math.Abs(list.CoveredFraction-0.5) > 1e-9 {
+		t.Errorf("expected 2 stands totalling 200 ha covering half the project, got %d, %.4f ha, %.3f", list.Count, list.TotalAreaHectares, list.CoveredFraction)
+	}
+}
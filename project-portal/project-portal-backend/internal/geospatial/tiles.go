@@ -0,0 +1,78 @@
+package geospatial
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MVTContentType is the media type of a Mapbox Vector Tile.
+const MVTContentType = "application/vnd.mapbox-vector-tile"
+
+// MaxTileZoom is the deepest zoom served as vector tiles; beyond it a tile is
+// a few metres across and the boundaries no longer change.
+const MaxTileZoom = 22
+
+const (
+	// tileExtent is the integer grid of a tile, the MVT default.
+	tileExtent = 4096
+	// tileBuffer is how far, in grid units, geometry reaches past the tile
+	// edge so polygon outlines join up across neighbouring tiles.
+	tileBuffer = 64
+	// webMercatorCircumference is the width of the Web Mercator plane in
+	// metres.
+	webMercatorCircumference = 40075016.68557849
+)
+
+// tileCacheControl lets the browser reuse a tile for a few minutes; a
+// boundary edit shows up on the map once it expires, or at once for clients
+// revalidating with the ETag. Tiles hold only the caller's projects, so
+// shared caches must not keep them.
+const tileCacheControl = "private, max-age=300"
+
+// TileCoord addresses one XYZ tile.
+type TileCoord struct {
+	Z, X, Y int
+}
+
+// ParseTileCoord reads the z, x and y path segments of a tile request. y
+// carries the ".mvt" extension. z must be 0 to MaxTileZoom and x and y 0 to
+// 2^z-1.
+func ParseTileCoord(z, x, y string) (TileCoord, error) {
+	if !strings.HasSuffix(y, ".mvt") {
+		return TileCoord{}, fmt.Errorf("tiles are served as .mvt")
+	}
+	y = strings.TrimSuffix(y, ".mvt")
+
+	var t TileCoord
+	var err error
+	if t.Z, err = strconv.Atoi(z); err != nil || t.Z < 0 || t.Z > MaxTileZoom {
+		return TileCoord{}, fmt.Errorf("z must be an integer from 0 to %d", MaxTileZoom)
+	}
+	limit := 1<<uint(t.Z) - 1
+	if t.X, err = strconv.Atoi(x); err != nil || t.X < 0 || t.X > limit {
+		return TileCoord{}, fmt.Errorf("x must be an integer from 0 to %d at zoom %d", limit, t.Z)
+	}
+	if t.Y, err = strconv.Atoi(y); err != nil || t.Y < 0 || t.Y > limit {
+		return TileCoord{}, fmt.Errorf("y must be an integer from 0 to %d at zoom %d", limit, t.Z)
+	}
+	return t, nil
+}
+
+// tileTolerance is the simplification tolerance for zoom z in Web Mercator
+// metres: one cell of the tile grid, finer detail than that being lost when
+// the geometry is snapped to the grid anyway.
+func tileTolerance(z int) float64 {
+	return webMercatorCircumference / math.Exp2(float64(z)) / tileExtent
+}
+
+// ProjectTile renders the boundaries in tile t of the projects the caller may
+// see as a Mapbox Vector Tile with a single "projects" layer. A tile with no projects is empty,
+// which is itself a valid tile.
+func (s *service) ProjectTile(ctx context.Context, t TileCoord) ([]byte, error) {
+	return dbCall(ctx, s, weightQuery, func() ([]byte, error) {
+		return s.repo.ProjectTile(ctx, t, tileTolerance(t.Z))
+	})
+}
@@ -0,0 +1,133 @@
+package geospatial
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tileRepo returns canned tile bytes and records the tile and scope asked for.
+type tileRepo struct {
+	*fakeRepo
+	tile      []byte
+	last      TileCoord
+	tolerance float64
+	scope     project.Scope
+}
+
+func (r *tileRepo) ProjectTile(ctx context.Context, t TileCoord, tolerance float64) ([]byte, error) {
+	r.last, r.tolerance = t, tolerance
+	r.scope, _ = project.ScopeFromContext(ctx)
+	return r.tile, nil
+}
+
+func TestParseTileCoord(t *testing.T) {
+	got, err := ParseTileCoord("3", "7", "5.mvt")
+	if err != nil || got != (TileCoord{Z: 3, X: 7, Y: 5}) {
+		t.Fatalf("expected 3/7/5, got %+v (%v)", got, err)
+	}
+	if _, err := ParseTileCoord("22", "0", "4194303.mvt"); err != nil {
+		t.Errorf("deepest zoom: %v", err)
+	}
+	for _, c := range [][3]string{
+		{"3", "7", "5"},         // no extension
+		{"3", "7", "5.png"},     // wrong extension
+		{"-1", "0", "0.mvt"},    // negative zoom
+		{"23", "0", "0.mvt"},    // beyond MaxTileZoom
+		{"3", "8", "0.mvt"},     // x past the edge
+		{"3", "0", "8.mvt"},     // y past the edge
+		{"3", "-1", "0.mvt"},    // negative x
+		{"z", "0", "0.mvt"},     // not a number
+		{"0", "0", "0.5.mvt"},   // fractional y
+		{"1", "0x1", "0.mvt"},   // hex x
+		{"2", "1", ".mvt"},      // missing y
+		{"0", "1", "0.mvt"},     // zoom 0 has one tile
+		{"30", "0", "0.mvt"},    // far beyond MaxTileZoom
+		{"3", "7", "5.mvt.mvt"}, // doubled extension
+	} {
+		if _, err := ParseTileCoord(c[0], c[1], c[2]); err == nil {
+			t.Errorf("%v: expected an error", c)
+		}
+	}
+}
+
+func TestTileTolerance_HalvesPerZoom(t *testing.T) {
+	// One grid cell of the zoom 0 tile is about 9.8 km.
+	if got := tileTolerance(0); math.Abs(got-9784) > 1 {
+		t.Errorf("zoom 0: expected about 9784 m, got %.1f", got)
+	}
+	if got := tileTolerance(10) * 2; math.Abs(got-tileTolerance(9)) > 1e-9 {
+		t.Errorf("expected the tolerance to halve from zoom 9 to 10")
+	}
+}
+
+func TestGetProjectTile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &tileRepo{fakeRepo: newFakeRepo(), tile: []byte{0x1a, 0x02, 0x78, 0x02}}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+	anonymous := httptest.NewRecorder()
+	router.ServeHTTP(anonymous, httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/tiles/projects/0/0/0.mvt", nil))
+	if anonymous.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", anonymous.Code)
+	}
+
+	token, err := auth.GenerateJWT(&auth.User{ID: uuid.NewString(), Email: "owner@example.com", Role: "user"})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/geospatial/tiles/projects/"+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("12/2412/2039.mvt", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != MVTContentType {
+		t.Errorf("expected %s, got %q", MVTContentType, ct)
+	}
+	if !bytes.Equal(w.Body.Bytes(), repo.tile) {
+		t.Errorf("expected the repository's tile bytes, got %x", w.Body.Bytes())
+	}
+	if w.Header().Get("Cache-Control") != tileCacheControl || w.Header().Get("ETag") == "" {
+		t.Errorf("expected caching headers, got %v", w.Header())
+	}
+	if repo.scope.All || repo.scope.OwnerID == uuid.Nil {
+		t.Errorf("expected the tile query to carry the caller's owner scope, got %+v", repo.scope)
+	}
+	if repo.last != (TileCoord{Z: 12, X: 2412, Y: 2039}) || repo.tolerance != tileTolerance(12) {
+		t.Errorf("expected tile 12/2412/2039 at its zoom's tolerance, got %+v %.3f", repo.last, repo.tolerance)
+	}
+
+	if w := get("12/2412/2039.mvt", w.Header().Get("ETag")); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("revalidation: expected an empty 304, got %d", w.Code)
+	}
+
+	repo.tile = []byte{}
+	if w := get("0/0/0.mvt", ""); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("empty tile: expected an empty 200, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+
+	for _, path := range []string{"12/2412/2039", "12/4096/0.mvt", "23/0/0.mvt"} {
+		if w := get(path, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultUploadTTL is how long an unfinished resumable upload is kept.
+	DefaultUploadTTL = 24 * time.Hour
+	// DefaultMaxUploadsPerOwner is how many unfinished uploads one user may
+	// hold at once.
+	DefaultMaxUploadsPerOwner = 5
+)
+
+var (
+	ErrUploadNotFound = errors.New("upload not found or expired")
+	// ErrTooManyUploads is returned by Create when the owner already holds
+	// the maximum number of unfinished uploads.
+	ErrTooManyUploads = errors.New("too many unfinished uploads; finalize or delete one first")
+	// ErrUploadBusy is returned when a chunk arrives for an upload that is
+	// still receiving another one.
+	ErrUploadBusy = errors.New("upload is receiving another chunk")
+)
+
+// UploadOffsetError is returned when a chunk does not start where the upload
+// currently ends. The client resumes from Offset.
+type UploadOffsetError struct {
+	Offset int64
+}
+
+func (e *UploadOffsetError) Error() string {
+	return fmt.Sprintf("chunk must start at offset %d", e.Offset)
+}
+
+// Upload is a geometry import received in chunks. Offset is how many bytes
+// have arrived; Size, when the client declared it, is how many are expected.
+type Upload struct {
+	ID          string    `json:"id"`
+	ContentType string    `json:"content_type"`
+	Filename    string    `json:"filename,omitempty"`
+	Size        int64     `json:"size,omitempty"`
+	Offset      int64     `json:"offset"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Complete reports whether every declared byte has arrived. Uploads without a
+// declared size are complete whenever the client finalizes them.
+func (u *Upload) Complete() bool {
+	return u.Size == 0 || u.Offset == u.Size
+}
+
+// CreateUploadRequest starts a resumable upload.
+type CreateUploadRequest struct {
+	ContentType string `json:"content_type" binding:"required"`
+	Filename    string `json:"filename,omitempty" binding:"max=255"`
+	Size        int64  `json:"size,omitempty" binding:"gte=0"`
+}
+
+// UploadStore keeps partial uploads as files below Dir, one directory per
+// owner: the bytes received so far in <owner>/<id>.part and the upload's
+// description in <owner>/<id>.json. An upload is only found under the user
+// who started it. Bytes that reach the store are kept even when the request
+// carrying them breaks off, so the client can resume from the reported
+// offset. Uploads are removed once finalized, or by Sweep after TTL.
+type UploadStore struct {
+	dir         string
+	ttl         time.Duration
+	maxPerOwner int
+	now         func() time.Time
+
+	mu     sync.Mutex
+	active map[string]bool // uploads receiving a chunk
+
+	createMu sync.Mutex // serializes the per-owner count in Create
+}
+
+// NewUploadStore creates dir if needed. ttl <= 0 uses DefaultUploadTTL and
+// maxPerOwner <= 0 uses DefaultMaxUploadsPerOwner.
+func NewUploadStore(dir string, ttl time.Duration, maxPerOwner int) (*UploadStore, error) {
+	if ttl <= 0 {
+		ttl = DefaultUploadTTL
+	}
+	if maxPerOwner <= 0 {
+		maxPerOwner = DefaultMaxUploadsPerOwner
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &UploadStore{dir: dir, ttl: ttl, maxPerOwner: maxPerOwner, now: time.Now, active: map[string]bool{}}, nil
+}
+
+// Create starts an empty upload for owner, refusing with ErrTooManyUploads
+// when owner already holds the maximum number of unfinished ones.
+func (s *UploadStore) Create(owner, contentType, filename string, size int64) (*Upload, error) {
+	if _, err := uuid.Parse(owner); err != nil {
+		return nil, fmt.Errorf("upload owner must be a user ID, got %q", owner)
+	}
+	s.createMu.Lock()
+	defer s.createMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Join(s.dir, owner), 0o750); err != nil {
+		return nil, err
+	}
+	open, err := s.list(owner)
+	if err != nil {
+		return nil, err
+	}
+	if len(open) >= s.maxPerOwner {
+		return nil, ErrTooManyUploads
+	}
+
+	now := s.now()
+	u := &Upload{
+		ID:          uuid.NewString(),
+		ContentType: contentType,
+		Filename:    filename,
+		Size:        size,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	if err := os.WriteFile(s.partPath(owner, u.ID), nil, 0o640); err != nil {
+		return nil, err
+	}
+	if err := s.writeMeta(owner, u); err != nil {
+		os.Remove(s.partPath(owner, u.ID))
+		return nil, err
+	}
+	return u, nil
+}
+
+// Get returns owner's upload id with its current offset. Uploads started by
+// someone else are ErrUploadNotFound.
+func (s *UploadStore) Get(owner, id string) (*Upload, error) {
+	if !validUploadPath(owner, id) {
+		return nil, ErrUploadNotFound
+	}
+	raw, err := os.ReadFile(s.metaPath(owner, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var u Upload
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return nil, err
+	}
+	if !s.now().Before(u.ExpiresAt) {
+		s.Remove(owner, id)
+		return nil, ErrUploadNotFound
+	}
+	info, err := os.Stat(s.partPath(owner, id))
+	if err != nil {
+		s.Remove(owner, id)
+		return nil, ErrUploadNotFound
+	}
+	u.Offset = info.Size()
+	return &u, nil
+}
+
+// Append writes r to owner's upload id, which must currently end at offset. At most
+// limit bytes, or the declared size if smaller, may be stored in total; a
+// chunk going past that fails with *UploadTooLargeError after storing up to
+// it. Whatever part of r arrived is kept when reading it fails, and the
+// returned upload reports the new offset either way.
+func (s *UploadStore) Append(owner, id string, offset int64, r io.Reader, limit int64) (*Upload, error) {
+	if !s.claim(id) {
+		return nil, ErrUploadBusy
+	}
+	defer s.release(id)
+
+	u, err := s.Get(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != u.Offset {
+		return u, &UploadOffsetError{Offset: u.Offset}
+	}
+	max := limit
+	if u.Size > 0 && u.Size < max {
+		max = u.Size
+	}
+
+	f, err := os.OpenFile(s.partPath(owner, id), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, err
+	}
+	n, copyErr := io.Copy(f, io.LimitReader(r, max-u.Offset))
+	closeErr := f.Close()
+	u.Offset += n
+	if copyErr != nil {
+		return u, copyErr
+	}
+	if closeErr != nil {
+		return u, closeErr
+	}
+	// Anything left in r is past the limit.
+	var probe [1]byte
+	if m, _ := r.Read(probe[:]); m > 0 {
+		return u, &UploadTooLargeError{Limit: max}
+	}
+	return u, nil
+}
+
+// UploadTooLargeError is returned when a chunk would take an upload past its
+// declared size or the import limit.
+type UploadTooLargeError struct {
+	Limit int64
+}
+
+func (e *UploadTooLargeError) Error() string {
+	return fmt.Sprintf("upload exceeds its %d byte limit", e.Limit)
+}
+
+// Open returns the received bytes of owner's upload id for reading. No chunk
+// can be appended until the reader is closed.
+func (s *UploadStore) Open(owner, id string) (io.ReadCloser, *Upload, error) {
+	if !s.claim(id) {
+		return nil, nil, ErrUploadBusy
+	}
+	u, err := s.Get(owner, id)
+	if err != nil {
+		s.release(id)
+		return nil, nil, err
+	}
+	f, err := os.Open(s.partPath(owner, id))
+	if err != nil {
+		s.release(id)
+		return nil, nil, err
+	}
+	return &claimedFile{File: f, release: func() { s.release(id) }}, u, nil
+}
+
+// claimedFile releases its upload's claim when closed.
+type claimedFile struct {
+	*os.File
+	release func()
+}
+
+func (f *claimedFile) Close() error {
+	defer f.release()
+	return f.File.Close()
+}
+
+// Remove deletes owner's upload id. Removing an unknown upload, or one
+// started by someone else, is not an error and leaves it in place.
+func (s *UploadStore) Remove(owner, id string) error {
+	if !validUploadPath(owner, id) {
+		return nil
+	}
+	err := errors.Join(os.Remove(s.metaPath(owner, id)), os.Remove(s.partPath(owner, id)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Sweep removes expired and orphaned uploads and returns how many it removed.
+func (s *UploadStore) Sweep() int {
+	owners, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, owner := range owners {
+		if !owner.IsDir() {
+			continue
+		}
+		ids, err := s.list(owner.Name())
+		if err != nil {
+			continue
+		}
+		for _, id := range ids {
+			if _, err := s.Get(owner.Name(), id); errors.Is(err, ErrUploadNotFound) {
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// RunUploadSweep sweeps store every interval until ctx is cancelled.
+func RunUploadSweep(ctx context.Context, store *UploadStore, interval time.Duration) {
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if removed := store.Sweep(); removed > 0 {
+			logger.Info("geometry upload sweep", "uploads_removed", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// list returns the IDs of owner's uploads, expired ones included.
+func (s *UploadStore) list(owner string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, owner))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *UploadStore) claim(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active[id] {
+		return false
+	}
+	s.active[id] = true
+	return true
+}
+
+func (s *UploadStore) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, id)
+}
+
+func (s *UploadStore) writeMeta(owner string, u *Upload) error {
+	raw, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.metaPath(owner, u.ID), raw, 0o640)
+}
+
+// validUploadPath reports whether owner and id are both UUIDs, and so safe
+// to join into a path below the store.
+func validUploadPath(owner, id string) bool {
+	_, ownerErr := uuid.Parse(owner)
+	_, idErr := uuid.Parse(id)
+	return ownerErr == nil && idErr == nil
+}
+
+func (s *UploadStore) partPath(owner, id string) string {
+	return filepath.Join(s.dir, owner, id+".part")
+}
+
+func (s *UploadStore) metaPath(owner, id string) string {
+	return filepath.Join(s.dir, owner, id+".json")
+}
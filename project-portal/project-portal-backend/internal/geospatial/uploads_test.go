@@ -0,0 +1,188 @@
+package geospatial
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// brokenReader yields data and then fails, like a connection dropped midway
+// through a chunk.
+type brokenReader struct {
+	data string
+}
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("connection reset by peer")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestResumableUpload_ResumesAfterInterruptedChunk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := newFakeRepo()
+	uploads, err := NewUploadStore(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatalf("NewUploadStore: %v", err)
+	}
+	router := gin.New()
+	NewHandlerWithUploads(NewService(repo), 1<<20, uploads).RegisterRoutes(router.Group("/api/v1"))
+
+	id := uuid.New()
+	body := `{"type":"FeatureCollection","features":[` + polygonFeature(id) + `]}`
+	first, rest := body[:len(body)/2], body[len(body)/2:]
+
+	token := func(user *auth.User) string {
+		token, err := auth.GenerateJWT(user)
+		if err != nil {
+			t.Fatalf("GenerateJWT: %v", err)
+		}
+		return "Bearer " + token
+	}
+	owner := token(&auth.User{ID: uuid.NewString(), Email: "owner@example.com", Role: "user"})
+	other := token(&auth.User{ID: uuid.NewString(), Email: "other@example.com", Role: "user"})
+	do := func(method, path string, r io.Reader, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/geospatial/uploads"+path, r)
+		req.Header.Set("Authorization", owner)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "", strings.NewReader(`{"content_type":"application/geo+json","filename":"plots.geojson","size":`+strconv.Itoa(len(body))+`}`), map[string]string{"Content-Type": "application/json"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var upload Upload
+	json.Unmarshal(w.Body.Bytes(), &upload)
+	path := "/" + upload.ID
+	if w.Header().Get("Location") != "/api/v1/geospatial/uploads"+path {
+		t.Errorf("unexpected Location %q", w.Header().Get("Location"))
+	}
+	if w := do(http.MethodGet, path, nil, map[string]string{"Authorization": ""}); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", w.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPatch} {
+		if w := do(method, path, strings.NewReader(first), map[string]string{"Authorization": other, "Upload-Offset": "0"}); w.Code != http.StatusNotFound {
+			t.Errorf("%s by another user: expected 404, got %d", method, w.Code)
+		}
+	}
+	do(http.MethodDelete, path, nil, map[string]string{"Authorization": other})
+
+	// The first chunk breaks off after 10 bytes.
+	w = do(http.MethodPatch, path, &brokenReader{data: first[:10]}, map[string]string{"Upload-Offset": "0"})
+	if w.Code != http.StatusBadRequest || w.Header().Get("Upload-Offset") != "10" {
+		t.Fatalf("interrupted chunk: expected 400 at offset 10, got %d at %q: %s", w.Code, w.Header().Get("Upload-Offset"), w.Body.String())
+	}
+	if w := do(http.MethodPost, path+"/finalize", nil, nil); w.Code != http.StatusConflict {
+		t.Errorf("finalizing an incomplete upload: expected 409, got %d", w.Code)
+	}
+
+	w = do(http.MethodGet, path, nil, nil)
+	offset := w.Header().Get("Upload-Offset")
+	if w.Code != http.StatusOK || offset != "10" {
+		t.Fatalf("expected the upload to report offset 10, got %d %q", w.Code, offset)
+	}
+	if w := do(http.MethodPatch, path, strings.NewReader(first), map[string]string{"Upload-Offset": "0"}); w.Code != http.StatusConflict || w.Header().Get("Upload-Offset") != "10" {
+		t.Errorf("stale offset: expected 409 with offset 10, got %d %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+
+	for _, chunk := range []string{first[10:], rest} {
+		w := do(http.MethodPatch, path, strings.NewReader(chunk), map[string]string{"Upload-Offset": offset})
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("resume at %s: expected 204, got %d: %s", offset, w.Code, w.Body.String())
+		}
+		offset = w.Header().Get("Upload-Offset")
+	}
+	if offset != strconv.Itoa(len(body)) {
+		t.Fatalf("expected the whole file at offset %d, got %s", len(body), offset)
+	}
+
+	w = do(http.MethodPost, path+"/finalize?mode=best_effort", nil, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("finalize: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := repo.stored[id]; !ok || repo.last.SourceFile != "plots.geojson" {
+		t.Errorf("expected the uploaded feature to be imported from plots.geojson, got %+v", repo.last)
+	}
+	if w := do(http.MethodGet, path, nil, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected a finalized upload to be removed, got %d", w.Code)
+	}
+}
+
+func TestUploadStore_ExpiresUnfinishedUploads(t *testing.T) {
+	store, err := NewUploadStore(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatalf("NewUploadStore: %v", err)
+	}
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	owner := uuid.NewString()
+	upload, err := store.Create(owner, mediaTypeGeoJSON, "", 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.Append(owner, upload.ID, 0, strings.NewReader(`{"type":`), 1024); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if _, err := store.Append(owner, upload.ID, 8, strings.NewReader(strings.Repeat("x", 2048)), 1024); !errors.As(err, new(*UploadTooLargeError)) {
+		t.Errorf("expected a chunk past the limit to fail with UploadTooLargeError, got %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if n := store.Sweep(); n != 1 {
+		t.Errorf("expected Sweep to remove 1 expired upload, removed %d", n)
+	}
+	if _, err := store.Get(owner, upload.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("expected the expired upload to be gone, got %v", err)
+	}
+}
+
+func TestUploadStore_CapsUnfinishedUploadsPerOwner(t *testing.T) {
+	store, err := NewUploadStore(t.TempDir(), time.Hour, 2)
+	if err != nil {
+		t.Fatalf("NewUploadStore: %v", err)
+	}
+	owner := uuid.NewString()
+	var first *Upload
+	for i := 0; i < 2; i++ {
+		u, err := store.Create(owner, mediaTypeGeoJSON, "", 0)
+		if err != nil {
+			t.Fatalf("Create %d: %v", i, err)
+		}
+		if first == nil {
+			first = u
+		}
+	}
+	if _, err := store.Create(owner, mediaTypeGeoJSON, "", 0); !errors.Is(err, ErrTooManyUploads) {
+		t.Fatalf("third upload: expected ErrTooManyUploads, got %v", err)
+	}
+	if _, err := store.Create(uuid.NewString(), mediaTypeGeoJSON, "", 0); err != nil {
+		t.Errorf("another owner's upload: %v", err)
+	}
+
+	if err := store.Remove(owner, first.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := store.Create(owner, mediaTypeGeoJSON, "", 0); err != nil {
+		t.Errorf("after removing one: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package geospatial
+
+import "fmt"
+
+// Severity grades a validation issue. Errors make a feature invalid;
+// warnings and infos are for the data steward to review.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Issue codes reported by the validate endpoint.
+const (
+	IssueMalformed       = "malformed"
+	IssueInvalidGeometry = "invalid_geometry"
+	IssueAreaOutOfRange  = "area_out_of_range"
+	IssueArea            = "area"
+	IssueOverlap         = "overlap"
+	IssueSliver          = "sliver"
+	IssueSelfTouch       = "self_touch"
+	IssueManyVertices    = "many_vertices"
+	IssueWindingFixed    = "winding_fixed"
+)
+
+const (
+	// SliverCompactness is the Polsby-Popper score below which a polygon
+	// part counts as a sliver; a 1:60 rectangle scores about 0.05.
+	SliverCompactness = 0.05
+	// ManyVertices is the vertex count above which a boundary is flagged as
+	// needlessly detailed.
+	ManyVertices = 10000
+)
+
+// ValidationIssue is one finding about a feature. Location is [lon, lat]
+// where PostGIS can place it.
+type ValidationIssue struct {
+	Severity Severity    `json:"severity"`
+	Code     string      `json:"code"`
+	Message  string      `json:"message"`
+	Location *[2]float64 `json:"location,omitempty"`
+}
+
+// fail marks the feature invalid for reason and records it as an error.
+func (f *FeatureValidation) fail(code, reason string, location *[2]float64) {
+	f.Valid = false
+	f.Reason = reason
+	f.Issues = append(f.Issues, ValidationIssue{Severity: SeverityError, Code: code, Message: reason, Location: location})
+}
+
+// warn records a warning, also listing it in Warnings.
+func (f *FeatureValidation) warn(code, message string, location *[2]float64) {
+	f.Warnings = append(f.Warnings, message)
+	f.Issues = append(f.Issues, ValidationIssue{Severity: SeverityWarning, Code: code, Message: message, Location: location})
+}
+
+// checkIssues turns what PostGIS found into the feature's issues: an
+// ST_IsValid failure is an error, slivers, self-touching boundaries and
+// excessive vertices are warnings, and ring order that an upload would fix
+// is an info.
+func (f *FeatureValidation) checkIssues(check *GeometryCheck) {
+	if !check.IsValid {
+		f.fail(IssueInvalidGeometry, check.Reason, check.Location)
+	}
+	if check.Slivers > 0 {
+		f.warn(IssueSliver, fmt.Sprintf("%d polygon part(s) are slivers, thinner than a compactness of %.2f", check.Slivers, SliverCompactness), check.SliverLocation)
+	}
+	if check.SelfTouching {
+		f.warn(IssueSelfTouch, "the boundary touches itself at a point", nil)
+	}
+	if check.Vertices > ManyVertices {
+		f.warn(IssueManyVertices, fmt.Sprintf("geometry has %d vertices, more than %d; consider simplifying it", check.Vertices, ManyVertices), nil)
+	}
+	if check.NeedsRewinding {
+		f.Issues = append(f.Issues, ValidationIssue{Severity: SeverityInfo, Code: IssueWindingFixed, Message: "ring orientation will be corrected to RFC 7946 order on upload"})
+	}
+}
@@ -0,0 +1,100 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkRepo reports a scripted GeometryCheck for each geometry, keyed by the
+// longitude of its first vertex.
+type checkRepo struct {
+	*fakeRepo
+	checks map[string]GeometryCheck
+}
+
+func (r *checkRepo) CheckGeometry(_ context.Context, geom json.RawMessage) (*GeometryCheck, error) {
+	for lon, check := range r.checks {
+		if strings.Contains(string(geom), "[["+lon+",") {
+			check := check
+			return &check, nil
+		}
+	}
+	return &GeometryCheck{IsValid: true, AreaHectares: 10}, nil
+}
+
+func (r *checkRepo) Intersect(context.Context, json.RawMessage, bool) ([]IntersectResult, error) {
+	return nil, nil
+}
+
+func squareAt(lon int) string {
+	return fmt.Sprintf(`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[%d,-1],[%d.01,-1],[%d.01,-1.01],[%d,-1.01],[%d,-1]]]}}`, lon, lon, lon, lon, lon)
+}
+
+func TestValidateGeometries_ClassifiesIssuesBySeverity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &checkRepo{fakeRepo: newFakeRepo(), checks: map[string]GeometryCheck{
+		"30": {IsValid: false, Reason: "Self-intersection", Location: &[2]float64{30.005, -1.005}},
+		"31": {IsValid: true, Slivers: 1, SliverLocation: &[2]float64{31.001, -1.0}, SelfTouching: true, Vertices: ManyVertices + 1},
+		"32": {IsValid: true, NeedsRewinding: true},
+	}}
+	router := gin.New()
+	signIn(t, router, "admin")
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	body, _ := json.Marshal(ValidateGeometryRequest{GeoJSON: featureCollection(squareAt(30), squareAt(31), squareAt(32), squareAt(33), `{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[36,-1]}}`).GeoJSON})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/geospatial/geometry/validate", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var report ValidationReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	codes := func(f FeatureValidation) map[string]ValidationIssue {
+		out := map[string]ValidationIssue{}
+		for _, issue := range f.Issues {
+			out[issue.Code] = issue
+		}
+		return out
+	}
+
+	invalid := codes(report.Features[0])
+	if issue := invalid[IssueInvalidGeometry]; issue.Severity != SeverityError || issue.Location == nil || issue.Location[0] != 30.005 || report.Features[0].Valid {
+		t.Errorf("feature 0: expected an invalid_geometry error with a location, got %+v", report.Features[0])
+	}
+
+	warned := codes(report.Features[1])
+	for _, code := range []string{IssueSliver, IssueSelfTouch, IssueManyVertices} {
+		if warned[code].Severity != SeverityWarning {
+			t.Errorf("feature 1: expected a %s warning, got %+v", code, report.Features[1].Issues)
+		}
+	}
+	if warned[IssueSliver].Location == nil || !report.Features[1].Valid || len(report.Features[1].Warnings) != 3 {
+		t.Errorf("feature 1: expected a valid feature with three located-where-possible warnings, got %+v", report.Features[1])
+	}
+
+	if issue := codes(report.Features[2])[IssueWindingFixed]; issue.Severity != SeverityInfo || !report.Features[2].Valid {
+		t.Errorf("feature 2: expected a winding_fixed info, got %+v", report.Features[2])
+	}
+	if !report.Features[3].Valid || report.Features[3].Issues == nil || len(report.Features[3].Issues) != 0 {
+		t.Errorf("feature 3: expected a clean feature with an empty issue list, got %+v", report.Features[3])
+	}
+	if issue := codes(report.Features[4])[IssueMalformed]; issue.Severity != SeverityError {
+		t.Errorf("feature 4: expected a malformed error, got %+v", report.Features[4])
+	}
+
+	want := map[Severity]int{SeverityError: 2, SeverityWarning: 3, SeverityInfo: 1}
+	for severity, n := range want {
+		if report.Severities[severity] != n {
+			t.Errorf("severities: expected %d %s, got %v", n, severity, report.Severities)
+		}
+	}
+}
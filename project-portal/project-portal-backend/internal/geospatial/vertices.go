@@ -0,0 +1,37 @@
+package geospatial
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// VertexLimitError reports a geometry with more vertices than the configured
+// maximum. Such uploads are refused before they reach storage.
+type VertexLimitError struct {
+	Vertices    int
+	MaxVertices int
+}
+
+func (e *VertexLimitError) Error() string {
+	return fmt.Sprintf("geometry has %d vertices, more than the limit of %d; simplify it before uploading", e.Vertices, e.MaxVertices)
+}
+
+// checkVertices counts geom's vertices with ST_NPoints and returns a
+// *VertexLimitError when there are more than s.maxVertices. A limit of 0
+// disables the check.
+func (s *service) checkVertices(ctx context.Context, geom json.RawMessage) error {
+	if s.maxVertices <= 0 {
+		return nil
+	}
+	n, err := dbCall(ctx, s, weightQuery, func() (int, error) {
+		return s.repo.CountVertices(ctx, geom)
+	})
+	if err != nil {
+		return err
+	}
+	if n > s.maxVertices {
+		return &VertexLimitError{Vertices: n, MaxVertices: s.maxVertices}
+	}
+	return nil
+}
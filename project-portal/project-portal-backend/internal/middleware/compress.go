@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompressConfig controls gzip response compression. Responses smaller than
+// MinSize bytes are sent as they are, since compressing them costs more than
+// it saves. Requests whose path starts with one of Exempt are never
+// compressed.
+type CompressConfig struct {
+	MinSize int
+	Level   int // gzip level; 0 means gzip.DefaultCompression
+	Exempt  []string
+}
+
+// alreadyCompressed lists content types that gain nothing from gzip.
+var alreadyCompressed = []string{
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-zip-compressed",
+	"application/pdf",
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"video/",
+	"audio/",
+}
+
+// Compress gzips responses for clients that accept it. The body is held back
+// until MinSize bytes have been written; only then, and only when the handler
+// did not set a Content-Encoding or an already compressed Content-Type such as
+// application/zip, is it compressed. WebSocket upgrades and HEAD requests pass
+// through untouched.
+func Compress(cfg CompressConfig) gin.HandlerFunc {
+	if cfg.Level == 0 {
+		cfg.Level = gzip.DefaultCompression
+	}
+	return func(c *gin.Context) {
+		if !acceptsGzip(c.Request) || c.Request.Method == http.MethodHead ||
+			c.GetHeader("Upgrade") != "" || hasPrefix(c.Request.URL.Path, cfg.Exempt) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &gzipWriter{ResponseWriter: original, cfg: cfg}
+		c.Writer = w
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		c.Next()
+		w.finish()
+		c.Writer = original
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter buffers the start of a response until it knows whether to
+// compress it: pending holds the bytes written so far and decided is set once
+// the choice is made, after which gz is nil for a response sent as is.
+type gzipWriter struct {
+	gin.ResponseWriter
+	cfg CompressConfig
+
+	pending []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.pending = append(w.pending, b...)
+		if len(w.pending) < w.cfg.MinSize && w.compressible() {
+			return len(b), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports a buffered body as written, so handlers and middleware that
+// check it do not write a second response.
+func (w *gzipWriter) Written() bool {
+	return len(w.pending) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *gzipWriter) Size() int {
+	if !w.decided {
+		return len(w.pending)
+	}
+	return w.ResponseWriter.Size()
+}
+
+// Flush sends what has been written so far; a streamed response that has not
+// reached MinSize by its first flush is sent uncompressed.
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.send(false)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// compressible reports whether the response, as the handler has set it up so
+// far, may be gzipped.
+func (w *gzipWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range alreadyCompressed {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	status := w.Status()
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+func (w *gzipWriter) decide() error {
+	return w.send(len(w.pending) >= w.cfg.MinSize && w.compressible())
+}
+
+// send makes the choice and writes out the pending bytes.
+func (w *gzipWriter) send(compress bool) error {
+	w.decided = true
+	pending := w.pending
+	w.pending = nil
+	if compress {
+		header := w.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.cfg.Level)
+		if err != nil {
+			return err
+		}
+		w.gz = gz
+		_, err = gz.Write(pending)
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(pending)
+	return err
+}
+
+// finish sends a response that never reached MinSize as it is and closes the
+// gzip stream of one that did.
+func (w *gzipWriter) finish() {
+	if !w.decided {
+		w.send(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCompress_GzipsLargeJSONOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(CompressConfig{MinSize: 1024}))
+
+	features := make([]gin.H, 200)
+	for i := range features {
+		features[i] = gin.H{"type": "Feature", "geometry": gin.H{"type": "Point", "coordinates": []float64{36.8, -1.3}}}
+	}
+	router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"type": "FeatureCollection", "features": features})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	archive := strings.Repeat("PK\x03\x04", 1024)
+	router.GET("/export.zip", func(c *gin.Context) {
+		c.Header("Content-Disposition", `attachment; filename="export.zip"`)
+		c.Data(http.StatusOK, "application/zip", []byte(archive))
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/large", "br, gzip;q=0.8")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected the large response to be gzipped, got headers %v", w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	var body struct {
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.NewDecoder(gz).Decode(&body); err != nil || len(body.Features) != len(features) {
+		t.Fatalf("expected %d features after decompressing, got %d (err %v)", len(features), len(body.Features), err)
+	}
+
+	if w := get("/large", ""); w.Header().Get("Content-Encoding") != "" || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("expected plain JSON without Accept-Encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if w := get("/large", "gzip;q=0"); w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected gzip;q=0 to be honoured, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	w = get("/small", "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{"status":"ok"}` {
+		t.Errorf("expected the small response as is, got %q %q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+
+	w = get("/export.zip", "gzip")
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != archive {
+		t.Errorf("expected the zip download not to be compressed again, got %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompress_SkipsWebSocketUpgrades(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(CompressConfig{MinSize: 1}))
+	router.GET("/ws", func(c *gin.Context) {
+		if _, ok := c.Writer.(*gzipWriter); ok {
+			t.Error("an upgrade request must keep the original writer")
+		}
+		io.WriteString(c.Writer, strings.Repeat("x", 64))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected no Content-Encoding on an upgrade, got %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompress_KeepsEarlierVary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Header("Vary", "Origin") })
+	router.Use(Compress(CompressConfig{MinSize: 1}))
+	router.GET("/data", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/data", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	vary := strings.Join(w.Header().Values("Vary"), ",")
+	if !strings.Contains(vary, "Origin") || !strings.Contains(vary, "Accept-Encoding") {
+		t.Errorf("expected Vary to list Origin and Accept-Encoding, got %q", vary)
+	}
+}
+
+func TestCompress_TimeoutResponseIsPlain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RouteTimeouts(20*time.Millisecond, nil))
+	router.Use(Compress(CompressConfig{MinSize: 1}))
+	router.GET("/slow", func(c *gin.Context) {
+		err := slowQuery(c.Request.Context(), 200*time.Millisecond)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprint(err), "padding": strings.Repeat("x", 2048)})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	if enc := w.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("expected the timeout response without Content-Encoding, got %q", enc)
+	}
+	if !strings.Contains(w.Body.String(), `"TIMEOUT"`) {
+		t.Errorf("expected the TIMEOUT body as plain JSON, got %q", w.Body.String())
+	}
+}
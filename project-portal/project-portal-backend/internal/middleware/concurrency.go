@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimitConfig caps the requests the whole process handles at once.
+// A request arriving when MaxInFlight are running waits up to QueueTimeout
+// for a slot. Requests whose path starts with one of Exempt take no slot. A
+// MaxInFlight below 1 disables the limit.
+type ConcurrencyLimitConfig struct {
+	MaxInFlight  int
+	QueueTimeout time.Duration
+	Exempt       []string
+}
+
+// ConcurrencyLimit bounds in-flight requests with a buffered channel used as
+// a semaphore. A request that gets no slot before cfg.QueueTimeout, or before
+// its client goes away, is answered 503 with Retry-After.
+func ConcurrencyLimit(cfg ConcurrencyLimitConfig) gin.HandlerFunc {
+	if cfg.MaxInFlight < 1 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	slots := make(chan struct{}, cfg.MaxInFlight)
+	retryAfter := strconv.Itoa(max(ceilSeconds(cfg.QueueTimeout), 1))
+	return func(c *gin.Context) {
+		for _, prefix := range cfg.Exempt {
+			if prefix != "" && strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			timer := time.NewTimer(cfg.QueueTimeout)
+			defer timer.Stop()
+			select {
+			case slots <- struct{}{}:
+			case <-timer.C:
+				c.Header("Retry-After", retryAfter)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+					"error": "server is busy, try again shortly",
+					"code":  "OVERLOADED",
+				})
+				return
+			case <-c.Request.Context().Done():
+				c.AbortWithStatus(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		defer func() { <-slots }()
+		c.Next()
+	}
+}
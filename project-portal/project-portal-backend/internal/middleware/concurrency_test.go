@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newConcurrencyRouter serves /work, which blocks until release is closed,
+// and /health. started receives once per request that reaches the handler.
+func newConcurrencyRouter(cfg ConcurrencyLimitConfig, release <-chan struct{}, started chan<- struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ConcurrencyLimit(cfg))
+	router.GET("/work", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func serve(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestConcurrencyLimit_QueuedRequestProceedsWhenASlotFrees(t *testing.T) {
+	release, started := make(chan struct{}), make(chan struct{}, 3)
+	router := newConcurrencyRouter(ConcurrencyLimitConfig{MaxInFlight: 2, QueueTimeout: 5 * time.Second}, release, started)
+
+	codes := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func() { codes <- serve(router, "/work").Code }()
+	}
+	<-started
+	<-started
+	select {
+	case <-started:
+		t.Fatal("a third request ran while both slots were taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the queued request never got a slot")
+	}
+	for i := 0; i < 3; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("expected every request to finish with 200, got %d", code)
+		}
+	}
+}
+
+func TestConcurrencyLimit_QueueTimeoutGets503(t *testing.T) {
+	release, started := make(chan struct{}), make(chan struct{}, 2)
+	router := newConcurrencyRouter(ConcurrencyLimitConfig{MaxInFlight: 1, QueueTimeout: 30 * time.Millisecond, Exempt: []string{"/health"}}, release, started)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serve(router, "/work")
+	}()
+	<-started
+
+	start := time.Now()
+	w := serve(router, "/work")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected 503 with Retry-After 1 while saturated, got %d (%q)", w.Code, w.Header().Get("Retry-After"))
+	}
+	if waited := time.Since(start); waited < 30*time.Millisecond {
+		t.Errorf("expected the request to wait for the queue timeout, gave up after %s", waited)
+	}
+	if w := serve(router, "/health"); w.Code != http.StatusOK {
+		t.Errorf("expected /health to be exempt while saturated, got %d", w.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	if w := serve(router, "/work"); w.Code != http.StatusOK {
+		t.Errorf("expected the freed slot to be reusable, got %d", w.Code)
+	}
+}
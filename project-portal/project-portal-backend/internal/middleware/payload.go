@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"io"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PayloadMetrics are the request and response body size histograms, in
+// bytes, labelled by method and route.
+type PayloadMetrics struct {
+	Request  *metrics.HistogramVec
+	Response *metrics.HistogramVec
+}
+
+// NewPayloadMetrics returns histograms with buckets from 256 B to 64 MiB.
+func NewPayloadMetrics() *PayloadMetrics {
+	buckets := metrics.ExponentialBuckets(256, 4, 10)
+	return &PayloadMetrics{
+		Request:  metrics.NewHistogramVec("http_request_size_bytes", "Size of HTTP request bodies.", buckets, "method", "route"),
+		Response: metrics.NewHistogramVec("http_response_size_bytes", "Size of HTTP response bodies as sent.", buckets, "method", "route"),
+	}
+}
+
+// PayloadSizeConfig configures PayloadSizes. Bodies larger than WarnBytes
+// are logged at Warn; 0 disables the warning.
+type PayloadSizeConfig struct {
+	Metrics   *PayloadMetrics
+	WarnBytes int64
+}
+
+// PayloadSizes records the size of every request and response body. The
+// request body is counted as the handler reads it and the response as it is
+// written, so streamed bodies are never buffered. A request body the handler
+// did not read in full counts at its Content-Length. Responses are measured
+// as sent, after any compression by later middleware.
+func PayloadSizes(cfg PayloadSizeConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body := &countingBody{ReadCloser: c.Request.Body}
+		if c.Request.Body != nil {
+			c.Request.Body = body
+		}
+		w := c.Writer
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requestBytes := body.n
+		if c.Request.ContentLength > requestBytes {
+			requestBytes = c.Request.ContentLength
+		}
+		responseBytes := int64(w.Size())
+		if responseBytes < 0 {
+			responseBytes = 0
+		}
+		cfg.Metrics.Request.Observe(float64(requestBytes), c.Request.Method, route)
+		cfg.Metrics.Response.Observe(float64(responseBytes), c.Request.Method, route)
+
+		if cfg.WarnBytes > 0 && (requestBytes > cfg.WarnBytes || responseBytes > cfg.WarnBytes) {
+			logging.FromContext(c.Request.Context()).Warn("large payload",
+				"route", route, "request_bytes", requestBytes, "response_bytes", responseBytes, "warn_bytes", cfg.WarnBytes)
+		}
+	}
+}
+
+// countingBody counts the bytes read through it.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
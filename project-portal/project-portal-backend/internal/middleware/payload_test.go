@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newPayloadRouter echoes the length of /upload bodies and streams three
+// 1000-byte chunks from /export. Warnings are written to logs.
+func newPayloadRouter(cfg PayloadSizeConfig, logs *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		logger := slog.New(slog.NewTextHandler(logs, nil))
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+	})
+	router.Use(PayloadSizes(cfg))
+	router.POST("/projects/:id/upload", func(c *gin.Context) {
+		n, _ := io.Copy(io.Discard, c.Request.Body)
+		c.JSON(http.StatusOK, gin.H{"read": n})
+	})
+	router.GET("/export", func(c *gin.Context) {
+		chunk := strings.Repeat("x", 1000)
+		for i := 0; i < 3; i++ {
+			_, _ = io.WriteString(c.Writer, chunk)
+			c.Writer.Flush()
+		}
+	})
+	return router
+}
+
+func TestPayloadSizes_RecordsRequestAndStreamedResponseSizes(t *testing.T) {
+	var logs bytes.Buffer
+	m := NewPayloadMetrics()
+	router := newPayloadRouter(PayloadSizeConfig{Metrics: m, WarnBytes: 4096}, &logs)
+
+	// Chunked, so the size is only known by counting what the handler reads.
+	req := httptest.NewRequest(http.MethodPost, "/projects/1/upload", io.MultiReader(strings.NewReader(strings.Repeat("a", 5000))))
+	req.ContentLength = -1
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if n := m.Request.Count("POST", "/projects/:id/upload"); n != 1 {
+		t.Fatalf("expected one request observation for the route pattern, got %d", n)
+	}
+	if sum := m.Request.Sum("POST", "/projects/:id/upload"); sum != 5000 {
+		t.Errorf("expected a 5000-byte request body, got %v", sum)
+	}
+	if !strings.Contains(logs.String(), "large payload") || !strings.Contains(logs.String(), "request_bytes=5000") {
+		t.Errorf("expected a warning for the 5000-byte body, got %q", logs.String())
+	}
+
+	logs.Reset()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export", nil))
+	if w.Body.Len() != 3000 {
+		t.Fatalf("expected the streamed body to reach the client, got %d bytes", w.Body.Len())
+	}
+	if sum := m.Response.Sum("GET", "/export"); sum != 3000 {
+		t.Errorf("expected a 3000-byte streamed response, got %v", sum)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no warning under the threshold, got %q", logs.String())
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if n := m.Response.Count("GET", "unmatched"); n != 1 {
+		t.Errorf("expected unknown paths under one label, got %d", n)
+	}
+}
+
+func TestPayloadSizes_UnreadBodyCountsAtContentLength(t *testing.T) {
+	m := NewPayloadMetrics()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(PayloadSizes(PayloadSizeConfig{Metrics: m}))
+	router.POST("/reject", func(c *gin.Context) { c.Status(http.StatusRequestEntityTooLarge) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/reject", strings.NewReader(strings.Repeat("b", 2048))))
+	if sum := m.Request.Sum("POST", "/reject"); sum != 2048 {
+		t.Errorf("expected the declared 2048 bytes, got %v", sum)
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitConfig sets a token bucket per client: Burst requests at once,
+// refilled at Rate requests per second. Requests whose path starts with one of
+// Exempt are never limited. A Rate or Burst below 1 disables limiting.
+// OnLimited, when set, is called for every request refused with 429.
+type RateLimitConfig struct {
+	Rate      float64
+	Burst     int
+	Exempt    []string
+	OnLimited func(c *gin.Context)
+}
+
+// rateLimiter holds one bucket per client key.
+type rateLimiter struct {
+	cfg RateLimitConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// RateLimit limits each client to cfg's token bucket. Clients are keyed by
+// gin's ClientIP, or by their API key when auth.ResolveAPIKey has
+// authenticated it, so mount the limiter after that. Every limited response
+// carries X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// (seconds until the bucket is full again); a rejected request gets 429 with
+// Retry-After.
+func RateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	return newRateLimiter(cfg, time.Now).handle
+}
+
+func newRateLimiter(cfg RateLimitConfig, now func() time.Time) *rateLimiter {
+	return &rateLimiter{cfg: cfg, now: now, buckets: map[string]*bucket{}, lastSweep: now()}
+}
+
+func (l *rateLimiter) handle(c *gin.Context) {
+	if l.cfg.Rate <= 0 || l.cfg.Burst < 1 || l.exempt(c.Request.URL.Path) {
+		c.Next()
+		return
+	}
+
+	allowed, remaining, retryAfter, reset := l.take(rateLimitKey(c))
+	c.Header("X-RateLimit-Limit", strconv.Itoa(l.cfg.Burst))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+	if !allowed {
+		if l.cfg.OnLimited != nil {
+			l.cfg.OnLimited(c)
+		}
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error": "rate limit exceeded",
+			"code":  "RATE_LIMITED",
+		})
+		return
+	}
+	c.Next()
+}
+
+func (l *rateLimiter) exempt(path string) bool {
+	for _, prefix := range l.cfg.Exempt {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// take spends a token from key's bucket. It reports whether the request may
+// proceed, the whole tokens left, how long until the next token and how long
+// until the bucket is full.
+func (l *rateLimiter) take(key string) (allowed bool, remaining int, retryAfter, reset time.Duration) {
+	now := l.now()
+	burst := float64(l.cfg.Burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, seen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.seen).Seconds()*l.cfg.Rate)
+	b.seen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		allowed = true
+	} else {
+		retryAfter = l.refill(1 - b.tokens)
+	}
+	return allowed, int(b.tokens), retryAfter, l.refill(burst - b.tokens)
+}
+
+// refill is how long the bucket takes to gain tokens.
+func (l *rateLimiter) refill(tokens float64) time.Duration {
+	return time.Duration(tokens / l.cfg.Rate * float64(time.Second))
+}
+
+// sweep drops buckets that have been full for a while, at most once per
+// refill period, so one-off clients do not accumulate. l.mu must be held.
+func (l *rateLimiter) sweep(now time.Time) {
+	full := l.refill(float64(l.cfg.Burst))
+	if now.Sub(l.lastSweep) < full {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitKey identifies the client. An X-API-Key header alone does not
+// count: unverified keys are free to vary, so keying on them would let a
+// client dodge its bucket and grow the limiter without bound.
+func rateLimitKey(c *gin.Context) string {
+	if id := c.GetString("api_key_id"); id != "" {
+		return "key:" + id
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
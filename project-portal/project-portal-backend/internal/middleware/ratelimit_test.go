@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitedRouter(cfg RateLimitConfig, now func() time.Time) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(newRateLimiter(cfg, now).handle)
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) }
+	router.GET("/api/v1/projects", ok)
+	router.GET("/health", ok)
+	return router
+}
+
+func rateLimitedGet(router *gin.Engine, path, ip, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":40000"
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_ExceedingBurstGets429(t *testing.T) {
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	router := newRateLimitedRouter(RateLimitConfig{Rate: 0.5, Burst: 3, Exempt: []string{"/health"}}, func() time.Time { return clock })
+
+	for i, wantRemaining := range []string{"2", "1", "0"} {
+		w := rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: expected X-RateLimit-Remaining %s, got %q", i+1, wantRemaining, got)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("request %d: expected X-RateLimit-Limit 3, got %q", i+1, got)
+		}
+	}
+
+	w := rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the burst is spent, got %d", w.Code)
+	}
+	// One token every 2s; the bucket is empty, so it is full again in 6s.
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Reset"); got != "6" {
+		t.Errorf("expected X-RateLimit-Reset 6, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("expected X-RateLimit-Remaining 0, got %q", got)
+	}
+
+	// Other clients and exempt paths are unaffected.
+	if w := rateLimitedGet(router, "/api/v1/projects", "198.51.100.1", ""); w.Code != http.StatusOK {
+		t.Errorf("expected another IP to have its own bucket, got %d", w.Code)
+	}
+	if w := rateLimitedGet(router, "/health", "203.0.113.7", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("expected /health to be exempt, got %d with limit %q", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	clock = clock.Add(2 * time.Second)
+	if w := rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", ""); w.Code != http.StatusOK {
+		t.Errorf("expected a request to pass after Retry-After, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_OnLimitedSeesRefusedRequestsOnly(t *testing.T) {
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	var limited []string
+	router := newRateLimitedRouter(RateLimitConfig{Rate: 0.5, Burst: 1, OnLimited: func(c *gin.Context) {
+		limited = append(limited, c.Request.URL.Path)
+	}}, func() time.Time { return clock })
+
+	rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", "")
+	if len(limited) != 0 {
+		t.Fatalf("expected an allowed request not to be reported, got %v", limited)
+	}
+	rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", "")
+	if len(limited) != 1 || limited[0] != "/api/v1/projects" {
+		t.Errorf("expected the refused request to be reported once, got %v", limited)
+	}
+}
+
+func TestRateLimit_OnlyAuthenticatedAPIKeysGetTheirOwnBucket(t *testing.T) {
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Stands in for auth.ResolveAPIKey, which sets api_key_id for a valid key;
+	// the auth package tests the two together.
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("X-API-Key") == "cs_machine" {
+			c.Set("api_key_id", "key-1")
+		}
+	}, newRateLimiter(RateLimitConfig{Rate: 1, Burst: 1}, func() time.Time { return clock }).handle)
+	router.GET("/api/v1/projects", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if w := rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the first anonymous request to pass, got %d", w.Code)
+	}
+	if w := rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", "cs_random"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected an unverified API key to share the IP's bucket, got %d", w.Code)
+	}
+	// Same IP, but keyed by the authenticated API key.
+	if w := rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", "cs_machine"); w.Code != http.StatusOK {
+		t.Fatalf("expected the authenticated API key to have its own bucket, got %d", w.Code)
+	}
+	if w := rateLimitedGet(router, "/api/v1/projects", "192.0.2.50", "cs_machine"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the API key's bucket to follow it across IPs, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_DisabledPassesEverything(t *testing.T) {
+	router := newRateLimitedRouter(RateLimitConfig{}, time.Now)
+	for i := 0; i < 5; i++ {
+		if w := rateLimitedGet(router, "/api/v1/projects", "203.0.113.7", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("expected no limiting when disabled, got %d", w.Code)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"log/slog"
+	"strings"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the correlation id on every response.
+const RequestIDHeader = "X-Request-ID"
+
+// TraceparentHeader is the W3C Trace Context header. As a request id source
+// only its trace-id is used.
+const TraceparentHeader = "traceparent"
+
+// maxRequestIDLength bounds an incoming id so a caller cannot bloat every log
+// line of the request.
+const maxRequestIDLength = 128
+
+// RequestIDConfig lists the incoming headers a correlation id is taken from,
+// in order; the first one holding a usable id wins. Empty Headers means just
+// X-Request-ID.
+type RequestIDConfig struct {
+	Headers []string
+}
+
+// RequestLogger assigns every request a correlation id, reusing one from the
+// configured headers before generating a new one, echoes it as X-Request-ID
+// and stores a child of base tagged with it in the request context, so
+// services can log through logging.FromContext.
+func RequestLogger(base *slog.Logger, cfg RequestIDConfig) gin.HandlerFunc {
+	headers := cfg.Headers
+	if len(headers) == 0 {
+		headers = []string{RequestIDHeader}
+	}
+	return func(c *gin.Context) {
+		requestID := incomingRequestID(c, headers)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		logger := base.With("request_id", requestID, "method", c.Request.Method, "path", c.FullPath())
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+	}
+}
+
+// incomingRequestID returns the first usable id among headers, or "". Values
+// that are too long or not printable ASCII are skipped, as is a malformed
+// traceparent.
+func incomingRequestID(c *gin.Context, headers []string) string {
+	for _, name := range headers {
+		value := strings.TrimSpace(c.GetHeader(name))
+		if strings.EqualFold(name, TraceparentHeader) {
+			value = traceID(value)
+		}
+		if validRequestID(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+// traceID extracts the trace-id from a W3C traceparent value
+// (version-traceid-parentid-flags), or returns "" when it is malformed or the
+// trace-id is all zeros.
+func traceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !isLowerHex(parts[0]) {
+		return ""
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return ""
+	}
+	id, parent, flags := parts[1], parts[2], parts[3]
+	if len(id) != 32 || !isLowerHex(id) || strings.Trim(id, "0") == "" {
+		return ""
+	}
+	if len(parent) != 16 || !isLowerHex(parent) || len(flags) != 2 || !isLowerHex(flags) {
+		return ""
+	}
+	return id
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
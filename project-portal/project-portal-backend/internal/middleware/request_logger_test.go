@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newRequestIDRouter(logs *bytes.Buffer, cfg RequestIDConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogger(slog.New(slog.NewJSONHandler(logs, nil)), cfg))
+	router.GET("/ping", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("handled")
+		c.String(http.StatusOK, c.GetString("request_id"))
+	})
+	return router
+}
+
+func TestRequestLogger_PropagatesEachConfiguredHeader(t *testing.T) {
+	cfg := RequestIDConfig{Headers: []string{"X-Request-ID", "X-Correlation-ID", "traceparent"}}
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"request id", map[string]string{"X-Request-ID": "req-1"}, "req-1"},
+		{"correlation id", map[string]string{"X-Correlation-ID": "corr-2"}, "corr-2"},
+		{"traceparent", map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"first listed wins", map[string]string{"X-Correlation-ID": "corr-2", "X-Request-ID": "req-1"}, "req-1"},
+		{"unusable value falls through", map[string]string{"X-Request-ID": "has space", "X-Correlation-ID": "corr-2"}, "corr-2"},
+		{"malformed traceparent falls through", map[string]string{"X-Correlation-ID": "corr-2", "traceparent": "00-abc-01"}, "corr-2"},
+	}
+	for _, tc := range cases {
+		var logs bytes.Buffer
+		router := newRequestIDRouter(&logs, cfg)
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if got := w.Header().Get(RequestIDHeader); got != tc.want {
+			t.Errorf("%s: expected response header %q, got %q", tc.name, tc.want, got)
+		}
+		if got := w.Body.String(); got != tc.want {
+			t.Errorf("%s: expected the context to hold %q, got %q", tc.name, tc.want, got)
+		}
+		if !strings.Contains(logs.String(), `"request_id":"`+tc.want+`"`) {
+			t.Errorf("%s: expected the log line to carry %q, got %s", tc.name, tc.want, logs.String())
+		}
+	}
+}
+
+func TestRequestLogger_GeneratesWhenAbsent(t *testing.T) {
+	var logs bytes.Buffer
+	// Only X-Request-ID is read by default, so a correlation id is ignored.
+	router := newRequestIDRouter(&logs, RequestIDConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("X-Correlation-ID", "corr-2")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	id := w.Header().Get(RequestIDHeader)
+	if _, err := uuid.Parse(id); err != nil {
+		t.Fatalf("expected a generated uuid, got %q", id)
+	}
+	if w.Body.String() != id || !strings.Contains(logs.String(), `"request_id":"`+id+`"`) {
+		t.Errorf("expected the generated id in the context and logs, got %q and %s", w.Body.String(), logs.String())
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	cases := map[string]string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        "4bf92f3577b34da6a3ce929d0e0e4736",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":        "",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":        "",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        "",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra":  "",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":           "",
+		"": "",
+	}
+	for in, want := range cases {
+		if got := traceID(in); got != want {
+			t.Errorf("traceID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
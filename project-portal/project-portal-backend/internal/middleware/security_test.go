@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SecurityHeaders(SecurityHeadersConfig{
+		HSTSMaxAge:            180 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		NoSniff:               true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+	}))
+	router.GET("/things", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	router.GET("/broken", func(c *gin.Context) { c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"}) })
+	get := func(path string, prepare func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if prepare != nil {
+			prepare(req)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/things", "/broken"} {
+		w := get(path, nil)
+		for header, want := range map[string]string{
+			"X-Content-Type-Options": "nosniff",
+			"X-Frame-Options":        "DENY",
+			"Referrer-Policy":        "strict-origin-when-cross-origin",
+		} {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("%s: %s = %q, want %q", path, header, got, want)
+			}
+		}
+		if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+			t.Errorf("%s: expected no HSTS over plain HTTP, got %q", path, got)
+		}
+	}
+
+	const want = "max-age=15552000; includeSubDomains"
+	if got := get("/things", func(r *http.Request) { r.TLS = &tls.ConnectionState{} }).Header().Get("Strict-Transport-Security"); got != want {
+		t.Errorf("TLS: Strict-Transport-Security = %q, want %q", got, want)
+	}
+	if got := get("/things", func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") }).Header().Get("Strict-Transport-Security"); got != want {
+		t.Errorf("behind a TLS proxy: Strict-Transport-Security = %q, want %q", got, want)
+	}
+}
+
+func TestSecurityHeaders_EmptyValuesAreLeftOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SecurityHeaders(SecurityHeadersConfig{ReferrerPolicy: "no-referrer"}))
+	router.GET("/things", func(c *gin.Context) { c.Status(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, "/things", nil)
+	req.TLS = &tls.ConnectionState{}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	for _, header := range []string{"Strict-Transport-Security", "X-Content-Type-Options", "X-Frame-Options"} {
+		if got := w.Header().Get(header); got != "" {
+			t.Errorf("expected no %s, got %q", header, got)
+		}
+	}
+	if got := w.Header().Get("Referrer-Policy"); got != "no-referrer" {
+		t.Errorf("Referrer-Policy = %q, want no-referrer", got)
+	}
+}
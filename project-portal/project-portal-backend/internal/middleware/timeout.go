@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout gives every request a context deadline of d; see RouteTimeouts.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return RouteTimeouts(d, nil)
+}
+
+// RouteTimeouts sets a context deadline on each request: routes[c.FullPath()]
+// when present, otherwise def. A zero or negative duration leaves the route
+// unbounded.
+//
+// Handlers run on the request goroutine, so the deadline only takes effect
+// through the context: a GORM query started with WithContext is cancelled by
+// the driver and returns early. Whatever the handler then tries to write is
+// discarded and the client gets 503 with code TIMEOUT instead.
+func RouteTimeouts(def time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, ok := routes[c.FullPath()]
+		if !ok {
+			d = def
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		c.Writer = &timeoutWriter{ResponseWriter: original, ctx: ctx}
+		c.Next()
+		c.Writer = original
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !original.Written() {
+			// The dropped body may have been compressed on its way out; the
+			// 503 is sent as it is.
+			header := original.Header()
+			header.Del("Content-Encoding")
+			header.Del("Content-Length")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "request timed out",
+				"code":  "TIMEOUT",
+			})
+		}
+	}
+}
+
+// timeoutWriter drops the response once the deadline has passed, so a handler
+// reporting the cancelled query does not race the middleware's 503. Output
+// written before the deadline goes through untouched.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+func (w *timeoutWriter) timedOut() bool {
+	return !w.ResponseWriter.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded)
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if !w.timedOut() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if !w.timedOut() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if w.timedOut() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.timedOut() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// ParseRouteTimeouts reads "route=duration" pairs separated by ";", where
+// route is a gin route pattern such as /api/v1/geospatial/projects/:id/geometry.
+func ParseRouteTimeouts(raw string) (map[string]time.Duration, error) {
+	routes := map[string]time.Duration{}
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(entry, "=")
+		route = strings.TrimSpace(route)
+		if !ok || !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("route timeout %q: want /route=duration", entry)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("route timeout %q: %w", entry, err)
+		}
+		routes[route] = d
+	}
+	return routes, nil
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slowQuery stands in for a database call: it blocks until ctx is done, like a
+// driver waiting on a cancelled statement, and reports ctx's error.
+func slowQuery(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRouteTimeouts_SlowQueryGets503(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var queryErr error
+	router := gin.New()
+	router.Use(RouteTimeouts(20*time.Millisecond, map[string]time.Duration{"/generous": time.Second}))
+	slow := func(c *gin.Context) {
+		queryErr = slowQuery(c.Request.Context(), 200*time.Millisecond)
+		if queryErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": queryErr.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+	router.GET("/slow", slow)
+	router.GET("/generous", slow)
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Fatalf("expected the query to be cancelled at the deadline, took %v", elapsed)
+	}
+	if !errors.Is(queryErr, context.DeadlineExceeded) {
+		t.Fatalf("expected the query to see DeadlineExceeded, got %v", queryErr)
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v (%s)", err, w.Body.String())
+	}
+	if body.Code != "TIMEOUT" {
+		t.Fatalf("expected code TIMEOUT, got %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/generous", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the per-route override to allow the query, got %d", w.Code)
+	}
+}
+
+func TestParseRouteTimeouts(t *testing.T) {
+	routes, err := ParseRouteTimeouts(" /api/v1/geospatial/projects/:id/geometry=2m; /api/v1/projects/stats=5s ;")
+	if err != nil {
+		t.Fatalf("ParseRouteTimeouts: %v", err)
+	}
+	if routes["/api/v1/geospatial/projects/:id/geometry"] != 2*time.Minute || routes["/api/v1/projects/stats"] != 5*time.Second {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+	for _, raw := range []string{"/x", "x=1s", "/x=soon"} {
+		if _, err := ParseRouteTimeouts(raw); err == nil {
+			t.Errorf("ParseRouteTimeouts(%q): expected an error", raw)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ChangeChannel is the Postgres NOTIFY channel that the triggers from
+// migration 021 publish project and boundary writes on.
+const ChangeChannel = "project_changes"
+
+// Kinds of ChangeEvent.
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// changeBuffer is how many events a subscriber may fall behind by before it is
+// dropped.
+const changeBuffer = 16
+
+// ChangeEvent is one write to a project or its boundary. BBox is the
+// boundary's envelope, nil while the project has none.
+type ChangeEvent struct {
+	Type      string     `json:"type"`
+	ProjectID uuid.UUID  `json:"project_id"`
+	OwnerID   *uuid.UUID `json:"owner_id,omitempty"`
+	OrgID     *uuid.UUID `json:"org_id,omitempty"`
+	BBox      *BBox      `json:"bbox,omitempty"`
+}
+
+// ParseChangeNotification decodes the JSON payload of a ChangeChannel
+// notification.
+func ParseChangeNotification(payload string) (ChangeEvent, error) {
+	var ev ChangeEvent
+	if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+		return ev, fmt.Errorf("invalid project change notification: %w", err)
+	}
+	switch ev.Type {
+	case ChangeCreated, ChangeUpdated, ChangeDeleted:
+	default:
+		return ev, fmt.Errorf("invalid project change notification: unknown type %q", ev.Type)
+	}
+	if ev.ProjectID == uuid.Nil {
+		return ev, fmt.Errorf("invalid project change notification: missing project_id")
+	}
+	return ev, nil
+}
+
+// sees reports whether a caller with this scope may see the project ev is
+// about, matching the repository's organization and owner filter.
+func (s Scope) sees(ev ChangeEvent) bool {
+	switch {
+	case s.All:
+		return true
+	case s.OrgID != uuid.Nil:
+		return ev.OrgID != nil && *ev.OrgID == s.OrgID
+	default:
+		return ev.OwnerID != nil && *ev.OwnerID == s.OwnerID
+	}
+}
+
+// ChangeHub fans ChangeEvents out to subscribers. Publish never blocks: a
+// subscriber whose buffer is full is dropped and its channel closed, so one
+// slow client cannot hold up the others or the database listener.
+type ChangeHub struct {
+	mu   sync.Mutex
+	subs map[*changeSubscriber]struct{}
+}
+
+type changeSubscriber struct {
+	scope  Scope
+	events chan ChangeEvent
+}
+
+func NewChangeHub() *ChangeHub {
+	return &ChangeHub{subs: map[*changeSubscriber]struct{}{}}
+}
+
+// Subscribe returns a channel of the events visible to scope and a function
+// that ends the subscription. The channel is closed when the subscription ends
+// or the subscriber falls too far behind.
+func (h *ChangeHub) Subscribe(scope Scope) (<-chan ChangeEvent, func()) {
+	sub := &changeSubscriber{scope: scope, events: make(chan ChangeEvent, changeBuffer)}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub.events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(sub)
+	}
+}
+
+// Publish delivers ev to every subscriber whose scope covers its project.
+func (h *ChangeHub) Publish(ev ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if !sub.scope.sees(ev) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription, which disconnects the WebSocket clients
+// streaming them. Used at shutdown.
+func (h *ChangeHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// remove ends sub's subscription; h.mu must be held.
+func (h *ChangeHub) remove(sub *changeSubscriber) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+}
+
+// ListenChanges relays ChangeChannel notifications from the database at dsn
+// to hub until ctx is done. The listener reconnects on its own; notifications
+// sent while it is disconnected are lost.
+func ListenChanges(ctx context.Context, dsn string, hub *ChangeHub) error {
+	logger := logging.FromContext(ctx)
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn("project changes: listener connection", "error", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(ChangeChannel); err != nil {
+		return fmt.Errorf("listen %s: %w", ChangeChannel, err)
+	}
+
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// nil follows a reconnect.
+			if n == nil {
+				continue
+			}
+			ev, err := ParseChangeNotification(n.Extra)
+			if err != nil {
+				logger.Warn("project changes: dropping notification", "error", err)
+				continue
+			}
+			hub.Publish(ev)
+		case <-ping.C:
+			// Surfaces a dead connection so the listener reconnects.
+			go listener.Ping()
+		}
+	}
+}
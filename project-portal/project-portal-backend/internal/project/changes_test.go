@@ -0,0 +1,221 @@
+package project
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+func (h *ChangeHub) subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// waitForSubscribers polls until hub has n subscribers.
+func waitForSubscribers(t *testing.T, hub *ChangeHub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.subscribers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers, have %d", n, hub.subscribers())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestParseChangeNotification(t *testing.T) {
+	id, owner := uuid.New(), uuid.New()
+	ev, err := ParseChangeNotification(`{"type":"updated","project_id":"` + id.String() + `","owner_id":"` + owner.String() +
+		`","bbox":{"min_lon":36.1,"min_lat":-1.2,"max_lon":36.3,"max_lat":-1.1}}`)
+	if err != nil {
+		t.Fatalf("ParseChangeNotification: %v", err)
+	}
+	if ev.Type != ChangeUpdated || ev.ProjectID != id || ev.OwnerID == nil || *ev.OwnerID != owner {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev.BBox == nil || ev.BBox.MinLon != 36.1 || ev.BBox.MaxLat != -1.1 {
+		t.Errorf("unexpected bbox %+v", ev.BBox)
+	}
+
+	for _, payload := range []string{
+		`not json`,
+		`{"type":"renamed","project_id":"` + id.String() + `"}`,
+		`{"type":"created"}`,
+	} {
+		if _, err := ParseChangeNotification(payload); err == nil {
+			t.Errorf("%s: expected an error", payload)
+		}
+	}
+}
+
+func TestChangeHub_ScopesAndDropsSlowSubscribers(t *testing.T) {
+	hub := NewChangeHub()
+	alice, bob := uuid.New(), uuid.New()
+	aliceEvents, _ := hub.Subscribe(Scope{OwnerID: alice})
+	adminEvents, stopAdmin := hub.Subscribe(Scope{All: true})
+
+	hub.Publish(ChangeEvent{Type: ChangeCreated, ProjectID: uuid.New(), OwnerID: &bob})
+	if len(aliceEvents) != 0 {
+		t.Errorf("another owner's project must not reach alice")
+	}
+	if len(adminEvents) != 1 {
+		t.Errorf("expected the admin to see every project, got %d events", len(adminEvents))
+	}
+	stopAdmin()
+	<-adminEvents
+	if _, open := <-adminEvents; open {
+		t.Errorf("expected the channel to close when the subscription ends")
+	}
+
+	for i := 0; i <= changeBuffer; i++ {
+		hub.Publish(ChangeEvent{Type: ChangeUpdated, ProjectID: uuid.New(), OwnerID: &alice})
+	}
+	received := 0
+	for range aliceEvents {
+		received++
+	}
+	if received != changeBuffer {
+		t.Errorf("expected the %d buffered events before the drop, got %d", changeBuffer, received)
+	}
+	if n := hub.subscribers(); n != 0 {
+		t.Errorf("expected the slow subscriber to be removed, %d remain", n)
+	}
+}
+
+func TestChangeHub_ScopesByOrganization(t *testing.T) {
+	hub := NewChangeHub()
+	orgA, orgB, alice := uuid.New(), uuid.New(), uuid.New()
+	memberEvents, _ := hub.Subscribe(Scope{OwnerID: uuid.New(), OrgID: orgA})
+	outsiderEvents, _ := hub.Subscribe(Scope{OwnerID: alice, OrgID: orgB})
+
+	hub.Publish(ChangeEvent{Type: ChangeCreated, ProjectID: uuid.New(), OwnerID: &alice, OrgID: &orgA})
+	if len(memberEvents) != 1 {
+		t.Errorf("expected another member's project to reach the organization, got %d events", len(memberEvents))
+	}
+	if len(outsiderEvents) != 0 {
+		t.Errorf("a project in another organization must not be seen, even by its creator")
+	}
+}
+
+func TestChangeHub_CloseEndsEverySubscription(t *testing.T) {
+	hub := NewChangeHub()
+	events, stop := hub.Subscribe(Scope{All: true})
+	other, _ := hub.Subscribe(Scope{OwnerID: uuid.New()})
+
+	hub.Close()
+	if _, ok := <-events; ok {
+		t.Error("expected the subscription to be closed")
+	}
+	if _, ok := <-other; ok {
+		t.Error("expected every subscription to be closed")
+	}
+	if n := hub.subscribers(); n != 0 {
+		t.Errorf("expected no subscribers after Close, %d remain", n)
+	}
+	stop() // unsubscribing after Close must not panic
+}
+
+func TestChangeStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := NewChangeHub()
+	router := gin.New()
+	NewChangeStream(hub).RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/projects"
+
+	alice, bob := uuid.New(), uuid.New()
+
+	resp, err := http.Get(server.URL + "/ws/projects")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", resp.StatusCode)
+	}
+
+	conn, err := websocket.Dial(wsURL+"?access_token="+token(t, alice, "user"), "", server.URL)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	waitForSubscribers(t, hub, 1)
+
+	bbox := &BBox{MinLon: 36.1, MinLat: -1.2, MaxLon: 36.3, MaxLat: -1.1}
+	hub.Publish(ChangeEvent{Type: ChangeCreated, ProjectID: uuid.New(), OwnerID: &bob})
+	want := ChangeEvent{Type: ChangeUpdated, ProjectID: uuid.New(), OwnerID: &alice, BBox: bbox}
+	hub.Publish(want)
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var got ChangeEvent
+	if err := websocket.JSON.Receive(conn, &got); err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if got.Type != want.Type || got.ProjectID != want.ProjectID || got.BBox == nil || *got.BBox != *bbox {
+		t.Errorf("expected alice's own event %+v, got %+v", want, got)
+	}
+
+	conn.Close()
+	waitForSubscribers(t, hub, 0)
+
+	config, err := websocket.NewConfig(wsURL, server.URL)
+	if err != nil {
+		t.Fatalf("NewConfig: %v", err)
+	}
+	config.Header.Set("Authorization", "Bearer "+token(t, alice, "user"))
+	conn, err = websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("DialConfig with an Authorization header: %v", err)
+	}
+	conn.Close()
+}
+
+func TestChangeStream_CookieHandshakeChecksOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stream := NewChangeStream(NewChangeHub())
+	stream.SetAllowedOrigins([]string{"*", "https://portal.example.com"})
+	router := gin.New()
+	stream.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/projects"
+	access := token(t, uuid.New(), "user")
+
+	for origin, allowed := range map[string]bool{
+		server.URL:                   true,
+		"https://portal.example.com": true,
+		"https://evil.example.com":   false,
+	} {
+		config, err := websocket.NewConfig(wsURL, origin)
+		if err != nil {
+			t.Fatalf("NewConfig: %v", err)
+		}
+		config.Header.Set("Cookie", auth.AccessTokenCookie+"="+access)
+		conn, err := websocket.DialConfig(config)
+		if (err == nil) != allowed {
+			t.Errorf("cookie from %s: dial error %v, want allowed=%v", origin, err, allowed)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+
+	config, err := websocket.NewConfig(wsURL+"?access_token="+access, "https://evil.example.com")
+	if err != nil {
+		t.Fatalf("NewConfig: %v", err)
+	}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("a bearer token should connect from any origin: %v", err)
+	}
+	conn.Close()
+}
@@ -1,11 +1,16 @@
 package project
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/apierror"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type Handler struct {
@@ -42,7 +47,7 @@ func (h *Handler) GetProject(c *gin.Context) {
 
 	project, err := h.service.GetProject(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		apierror.Respond(c, err, "project")
 		return
 	}
 
@@ -53,7 +58,25 @@ func (h *Handler) ListProjects(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	projects, err := h.service.ListProjects(c.Request.Context(), limit, offset)
+	withDeleted, ok := includeDeleted(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "include_deleted requires the " + auth.PermProjectsViewDeleted + " permission"})
+		return
+	}
+
+	tagMatch, err := ParseTagMatch(c.Query("tag_match"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projects, err := h.service.ListProjects(c.Request.Context(), ListFilter{
+		Limit:          limit,
+		Offset:         offset,
+		IncludeDeleted: withDeleted,
+		Tags:           ParseTags(c.Query("tags")),
+		TagMatch:       tagMatch,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -62,6 +85,24 @@ func (h *Handler) ListProjects(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"projects": projects})
 }
 
+// ProjectStats returns aggregate counts and areas, optionally limited to
+// projects whose boundary intersects ?bbox=minLon,minLat,maxLon,maxLat.
+func (h *Handler) ProjectStats(c *gin.Context) {
+	bbox, err := ParseBBox(c.Query("bbox"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.service.Stats(c.Request.Context(), bbox)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 func (h *Handler) UpdateProject(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
@@ -77,6 +118,10 @@ func (h *Handler) UpdateProject(c *gin.Context) {
 	}
 
 	project, err := h.service.UpdateProject(c.Request.Context(), id, &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -94,6 +139,10 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 	}
 
 	err = h.service.DeleteProject(c.Request.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -102,14 +151,208 @@ func (h *Handler) DeleteProject(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "project deleted"})
 }
 
-// RegisterRoutes registers all project routes with the Gin router
+func (h *Handler) RestoreProject(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	project, err := h.service.RestoreProject(c.Request.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deleted project not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *Handler) AddProjectTags(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req TagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.service.AddTags(c.Request.Context(), id, req.Tags)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *Handler) RemoveProjectTag(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	project, err := h.service.RemoveTag(c.Request.Context(), id, c.Param("tag"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// TransitionVerification moves a project through its verification lifecycle.
+// Moves the lifecycle does not allow get 409; reviewing without
+// projects:verify gets 403.
+func (h *Handler) TransitionVerification(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req StatusTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.service.TransitionVerification(c.Request.Context(), id, req.Status)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+	case errors.Is(err, ErrTransitionForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, project)
+	}
+}
+
+// TransferOwnership hands a project to another user and their organization.
+// Only the owner or a caller holding projects:manage_all may transfer (403);
+// an unknown or inactive target gets 404, and an owner handing the project
+// outside its organization 422.
+func (h *Handler) TransferOwnership(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := uuid.Parse(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	project, err := h.service.TransferOwnership(c.Request.Context(), id, to)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+	case errors.Is(err, ErrTransferTargetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTransferForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTransferOutsideOrg):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrOwnerChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, project)
+	}
+}
+
+// UploadThumbnail accepts a multipart form with the image in the "image" field.
+func (h *Handler) UploadThumbnail(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+		return
+	}
+
+	fh, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"image\" is required"})
+		return
+	}
+	file, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read uploaded image"})
+		return
+	}
+	defer file.Close()
+
+	project, err := h.service.SetThumbnail(c.Request.Context(), id, file)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+	case errors.Is(err, ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUnsupportedImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrThumbnailsDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, project)
+	}
+}
+
+// includeDeleted reads the include_deleted query flag. The second return value
+// is false when a caller without projects:view_deleted asks for soft-deleted rows.
+func includeDeleted(c *gin.Context) (bool, bool) {
+	if c.Query("include_deleted") != "true" {
+		return false, true
+	}
+	return true, auth.HasPermission(c.GetString("role"), auth.PermProjectsViewDeleted)
+}
+
+// RegisterRoutes registers all project routes with the Gin router. Every route
+// needs a token or an API key with a projects scope; callers only reach their
+// own projects unless they hold projects:manage_all.
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
-	projects := router.Group("/projects")
+	projects := router.Group("/projects", auth.AuthMiddlewareOrAPIKey(), auth.RequireScope(auth.ScopeProjectsRead, auth.ScopeProjectsWrite), OwnerScope())
 	{
 		projects.POST("", h.CreateProject)
 		projects.GET("", h.ListProjects)
+		projects.GET("/stats", h.ProjectStats)
 		projects.GET("/:id", h.GetProject)
 		projects.PUT("/:id", h.UpdateProject)
 		projects.DELETE("/:id", h.DeleteProject)
+		projects.POST("/:id/restore", h.RestoreProject)
+		projects.POST("/:id/tags", h.AddProjectTags)
+		projects.DELETE("/:id/tags/:tag", h.RemoveProjectTag)
+		projects.POST("/:id/thumbnail", h.UploadThumbnail)
+		projects.POST("/:id/status", h.TransitionVerification)
+		projects.POST("/:id/transfer", h.TransferOwnership)
 	}
 }
\ No newline at end of file
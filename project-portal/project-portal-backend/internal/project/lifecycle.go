@@ -0,0 +1,89 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Verification states a project moves through on its way to issuing credits.
+// A new project is a draft; its owner submits it, a verifier takes it under
+// review and then verifies or rejects it. A rejected project goes back to
+// draft to be reworked and submitted again.
+const (
+	VerificationDraft       = "draft"
+	VerificationSubmitted   = "submitted"
+	VerificationUnderReview = "under_review"
+	VerificationVerified    = "verified"
+	VerificationRejected    = "rejected"
+)
+
+// verificationTransitions lists the states each state may move to. Verified
+// is final.
+var verificationTransitions = map[string][]string{
+	VerificationDraft:       {VerificationSubmitted},
+	VerificationSubmitted:   {VerificationDraft, VerificationUnderReview},
+	VerificationUnderReview: {VerificationVerified, VerificationRejected},
+	VerificationRejected:    {VerificationDraft},
+}
+
+// reviewStates are entered only by callers holding projects:verify.
+var reviewStates = map[string]bool{
+	VerificationUnderReview: true,
+	VerificationVerified:    true,
+	VerificationRejected:    true,
+}
+
+var (
+	// ErrInvalidTransition is returned for a move the lifecycle does not allow
+	// from the project's current state, such as draft to verified.
+	ErrInvalidTransition = errors.New("invalid verification status transition")
+	// ErrTransitionForbidden is returned when a caller without projects:verify
+	// tries to review a project.
+	ErrTransitionForbidden = errors.New("reviewing a project requires the projects:verify permission")
+)
+
+// CanTransition reports whether the lifecycle allows moving from one
+// verification state to another.
+func CanTransition(from, to string) bool {
+	for _, next := range verificationTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusTransitionRequest moves a project to another verification state.
+type StatusTransitionRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// TransitionVerification moves project id to the verification state to.
+// Entering a review state needs ctx's scope to allow verifying; contexts
+// without a scope are trusted. The change is applied only if the project is
+// still in the state it was read in, so two concurrent moves cannot both win.
+func (s *service) TransitionVerification(ctx context.Context, id uuid.UUID, to string) (*Project, error) {
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	from := project.VerificationStatus
+	if from == "" {
+		from = VerificationDraft
+	}
+	if !CanTransition(from, to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	if scope, ok := ScopeFromContext(ctx); ok && reviewStates[to] && !scope.Verify {
+		return nil, ErrTransitionForbidden
+	}
+
+	if err := s.repo.TransitionVerification(ctx, id, from, to, time.Now()); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
@@ -0,0 +1,104 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (r *memoryRepo) TransitionVerification(ctx context.Context, id uuid.UUID, from, to string, _ time.Time) error {
+	p, ok := r.projects[id]
+	if !ok || !visible(ctx, p) {
+		return gorm.ErrRecordNotFound
+	}
+	if p.VerificationStatus != from {
+		return fmt.Errorf("%w: the project is no longer %s", ErrInvalidTransition, from)
+	}
+	p.VerificationStatus = to
+	return nil
+}
+
+func TestCanTransition(t *testing.T) {
+	for _, tc := range []struct {
+		from, to string
+		want     bool
+	}{
+		{VerificationDraft, VerificationSubmitted, true},
+		{VerificationSubmitted, VerificationUnderReview, true},
+		{VerificationUnderReview, VerificationVerified, true},
+		{VerificationUnderReview, VerificationRejected, true},
+		{VerificationRejected, VerificationDraft, true},
+		{VerificationDraft, VerificationVerified, false},
+		{VerificationSubmitted, VerificationVerified, false},
+		{VerificationVerified, VerificationDraft, false},
+		{VerificationDraft, "archived", false},
+	} {
+		if got := CanTransition(tc.from, tc.to); got != tc.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func TestTransitionVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &memoryRepo{projects: map[uuid.UUID]*Project{}}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	org, owner, verifier := uuid.New(), uuid.New(), uuid.New()
+	do := func(method, path, body string, user uuid.UUID, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/projects"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+orgToken(t, user, org, role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "", `{"name":"Mangroves","type":"Blue carbon","location":"Kenya","area":40}`, owner, "user")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created Project
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.VerificationStatus != VerificationDraft {
+		t.Fatalf("expected a new project to be a draft, got %q", created.VerificationStatus)
+	}
+	path := "/" + created.ID.String() + "/status"
+	move := func(to string, user uuid.UUID, role string) *httptest.ResponseRecorder {
+		return do(http.MethodPost, path, `{"status":"`+to+`"}`, user, role)
+	}
+
+	if w := move(VerificationVerified, verifier, "verifier"); w.Code != http.StatusConflict {
+		t.Errorf("draft to verified: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := move(VerificationSubmitted, owner, "user"); w.Code != http.StatusOK {
+		t.Fatalf("submit: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := move(VerificationUnderReview, owner, "user"); w.Code != http.StatusForbidden {
+		t.Errorf("owner starting review: expected 403, got %d", w.Code)
+	}
+	for _, to := range []string{VerificationUnderReview, VerificationVerified} {
+		if w := move(to, verifier, "verifier"); w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", to, w.Code, w.Body.String())
+		}
+	}
+	if got := repo.projects[created.ID].VerificationStatus; got != VerificationVerified {
+		t.Errorf("expected the project to be verified, got %q", got)
+	}
+	if w := move(VerificationDraft, owner, "user"); w.Code != http.StatusConflict {
+		t.Errorf("verified is final: expected 409, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/"+uuid.NewString()+"/status", `{"status":"submitted"}`, owner, "user"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown project: expected 404, got %d", w.Code)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -20,8 +21,18 @@ type Project struct {
 	Progress       int       `json:"progress"` // percentage
 	Icon           string    `json:"icon"`
 	Status         string    `json:"status" gorm:"default:'pending'"` // active, pending, completed
+	// VerificationStatus is the review lifecycle; see CanTransition.
+	VerificationStatus string `json:"verification_status" gorm:"not null;default:'draft';index"`
+	Tags           pq.StringArray `json:"tags" gorm:"type:text[];not null;default:'{}';index:idx_projects_tags,type:gin"`
+	ThumbnailURL   string    `json:"thumbnail_url,omitempty"`
+	OwnerID        *uuid.UUID `json:"owner_id,omitempty" gorm:"type:uuid;index"`
+	OrgID          *uuid.UUID `json:"org_id,omitempty" gorm:"type:uuid;index"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// PerimeterMeters is read from the stored boundary on detail responses.
+	PerimeterMeters *float64 `json:"perimeter_meters,omitempty" gorm:"-"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID.
@@ -44,6 +55,7 @@ type ProjectCreateRequest struct {
 	Progress      int     `json:"progress" binding:"min=0,max=100"`
 	Icon          string  `json:"icon"`
 	Status        string  `json:"status"`
+	Tags          []string `json:"tags,omitempty"`
 }
 
 // ProjectUpdateRequest represents the request to update a project
@@ -58,4 +70,43 @@ type ProjectUpdateRequest struct {
 	Progress      *int     `json:"progress,omitempty"`
 	Icon          *string  `json:"icon,omitempty"`
 	Status        *string  `json:"status,omitempty"`
-}
\ No newline at end of file
+}
+// Tag match modes for list filters: TagMatchAny returns projects carrying at
+// least one of the tags, TagMatchAll only those carrying every tag.
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// ListFilter narrows a project listing.
+type ListFilter struct {
+	Limit          int
+	Offset         int
+	IncludeDeleted bool
+	Tags           []string
+	TagMatch       string // TagMatchAny (default) or TagMatchAll
+}
+
+// TagsRequest adds tags to a project.
+type TagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+// BBox is a lon/lat envelope in EPSG:4326.
+type BBox struct {
+	MinLon float64 `json:"min_lon"`
+	MinLat float64 `json:"min_lat"`
+	MaxLon float64 `json:"max_lon"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+// ProjectStats summarises active projects for dashboards. Areas are in
+// hectares; ByTag counts a project once under each of its tags.
+type ProjectStats struct {
+	ProjectCount        int64            `json:"project_count"`
+	TotalAreaHectares   float64          `json:"total_area_hectares"`
+	AverageAreaHectares float64          `json:"average_area_hectares"`
+	ByType              map[string]int64 `json:"by_type"`
+	ByTag               map[string]int64 `json:"by_tag"`
+	BBox                *BBox            `json:"bbox,omitempty"`
+}
@@ -2,17 +2,27 @@ package project
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
 type Repository interface {
 	Create(ctx context.Context, project *Project) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Project, error)
-	List(ctx context.Context, limit, offset int) ([]Project, error)
+	List(ctx context.Context, filter ListFilter) ([]Project, error)
 	Update(ctx context.Context, project *Project) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) error
+	GetPerimeterMeters(ctx context.Context, id uuid.UUID) (*float64, error)
+	Stats(ctx context.Context, bbox *BBox) (*ProjectStats, error)
+	TransitionVerification(ctx context.Context, id uuid.UUID, from, to string, at time.Time) error
+	UserOrg(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error)
+	TransferOwnership(ctx context.Context, id uuid.UUID, from *uuid.UUID, to uuid.UUID, org *uuid.UUID, at time.Time) error
 }
 
 type repository struct {
@@ -29,16 +39,28 @@ func (r *repository) Create(ctx context.Context, project *Project) error {
 
 func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*Project, error) {
 	var project Project
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&project).Error
+	err := scoped(ctx, r.db.WithContext(ctx)).Where("id = ?", id).First(&project).Error
 	if err != nil {
 		return nil, err
 	}
 	return &project, nil
 }
 
-func (r *repository) List(ctx context.Context, limit, offset int) ([]Project, error) {
+func (r *repository) List(ctx context.Context, filter ListFilter) ([]Project, error) {
 	var projects []Project
-	err := r.db.WithContext(ctx).Limit(limit).Offset(offset).Find(&projects).Error
+	query := scoped(ctx, r.db.WithContext(ctx))
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if len(filter.Tags) > 0 {
+		// Both operators are served by the GIN index on tags.
+		if filter.TagMatch == TagMatchAll {
+			query = query.Where("tags @> ?", pq.StringArray(filter.Tags))
+		} else {
+			query = query.Where("tags && ?", pq.StringArray(filter.Tags))
+		}
+	}
+	err := query.Limit(filter.Limit).Offset(filter.Offset).Find(&projects).Error
 	return projects, err
 }
 
@@ -46,6 +68,129 @@ func (r *repository) Update(ctx context.Context, project *Project) error {
 	return r.db.WithContext(ctx).Save(project).Error
 }
 
+// Delete soft-deletes the project; the row is kept for audit history.
 func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&Project{}, "id = ?", id).Error
-}
\ No newline at end of file
+	result := scoped(ctx, r.db.WithContext(ctx)).Delete(&Project{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore clears deleted_at on a soft-deleted project.
+func (r *repository) Restore(ctx context.Context, id uuid.UUID) error {
+	result := scoped(ctx, r.db.WithContext(ctx)).Unscoped().Model(&Project{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+// TransitionVerification sets the verification status of project id to to,
+// provided it is still from. A project that has moved on meanwhile is reported
+// as ErrInvalidTransition.
+func (r *repository) TransitionVerification(ctx context.Context, id uuid.UUID, from, to string, at time.Time) error {
+	result := scoped(ctx, r.db.WithContext(ctx)).Model(&Project{}).
+		Where("id = ? AND verification_status = ?", id, from).
+		Updates(map[string]interface{}{"verification_status": to, "updated_at": at})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: the project is no longer %s", ErrInvalidTransition, from)
+	}
+	return nil
+}
+
+// UserOrg returns the organization of an active user, nil when they have
+// none, and gorm.ErrRecordNotFound when there is no such active user.
+func (r *repository) UserOrg(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	var users []struct{ OrgID *uuid.UUID }
+	err := r.db.WithContext(ctx).
+		Raw("SELECT org_id FROM users WHERE id = ? AND is_active", userID).
+		Scan(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return users[0].OrgID, nil
+}
+
+// TransferOwnership sets the owner and organization of project id, provided
+// it is still owned by from. A project that changed hands meanwhile is
+// reported as ErrOwnerChanged.
+func (r *repository) TransferOwnership(ctx context.Context, id uuid.UUID, from *uuid.UUID, to uuid.UUID, org *uuid.UUID, at time.Time) error {
+	result := scoped(ctx, r.db.WithContext(ctx)).Model(&Project{}).
+		Where("id = ? AND owner_id IS NOT DISTINCT FROM ?", id, from).
+		Updates(map[string]interface{}{"owner_id": to, "org_id": org, "updated_at": at})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOwnerChanged
+	}
+	return nil
+}
+
+// GetPerimeterMeters returns the stored boundary perimeter, or nil when the
+// project has no geometry yet.
+func (r *repository) GetPerimeterMeters(ctx context.Context, id uuid.UUID) (*float64, error) {
+	var perimeters []float64
+	err := r.db.WithContext(ctx).
+		Raw("SELECT perimeter_meters FROM project_geometries WHERE project_id = ? AND perimeter_meters IS NOT NULL", id).
+		Scan(&perimeters).Error
+	if err != nil || len(perimeters) == 0 {
+		return nil, err
+	}
+	return &perimeters[0], nil
+}
+
+// Stats aggregates the active projects in ctx's scope, optionally only those
+// whose boundary intersects bbox.
+func (r *repository) Stats(ctx context.Context, bbox *BBox) (*ProjectStats, error) {
+	var row struct {
+		ProjectCount        int64
+		TotalAreaHectares   float64
+		AverageAreaHectares float64
+		ByType              string
+		ByTag               string
+	}
+	var filter string
+	var args []interface{}
+	if column, id, ok := Restricts(ctx); ok {
+		filter += scopeStatsFilter(column)
+		args = append(args, id)
+	}
+	if bbox != nil {
+		filter += bboxStatsFilter
+		args = append(args, bbox.MinLon, bbox.MinLat, bbox.MaxLon, bbox.MaxLat)
+	}
+	if err := r.db.WithContext(ctx).Raw(statsSQL(filter), args...).Scan(&row).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &ProjectStats{
+		ProjectCount:        row.ProjectCount,
+		TotalAreaHectares:   row.TotalAreaHectares,
+		AverageAreaHectares: row.AverageAreaHectares,
+		ByType:              map[string]int64{},
+		ByTag:               map[string]int64{},
+		BBox:                bbox,
+	}
+	if err := json.Unmarshal([]byte(row.ByType), &stats.ByType); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(row.ByTag), &stats.ByTag); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -1,14 +1,25 @@
 package project
 
-import "github.com/gin-gonic/gin"
+import (
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
 
 func RegisterRoutes(r *gin.Engine, handler *Handler) {
-	projectGroup := r.Group("/api/v1/projects")
+	projectGroup := r.Group("/api/v1/projects", auth.AuthMiddlewareOrAPIKey(), auth.RequireScope(auth.ScopeProjectsRead, auth.ScopeProjectsWrite), OwnerScope())
 	{
 		projectGroup.POST("", handler.CreateProject)
 		projectGroup.GET("", handler.ListProjects)
+		projectGroup.GET("/stats", handler.ProjectStats)
 		projectGroup.GET("/:id", handler.GetProject)
 		projectGroup.PUT("/:id", handler.UpdateProject)
 		projectGroup.DELETE("/:id", handler.DeleteProject)
+		projectGroup.POST("/:id/restore", handler.RestoreProject)
+		projectGroup.POST("/:id/tags", handler.AddProjectTags)
+		projectGroup.DELETE("/:id/tags/:tag", handler.RemoveProjectTag)
+		projectGroup.POST("/:id/thumbnail", handler.UploadThumbnail)
+		projectGroup.POST("/:id/status", handler.TransitionVerification)
+		projectGroup.POST("/:id/transfer", handler.TransferOwnership)
 	}
 }
@@ -0,0 +1,89 @@
+package project
+
+import (
+	"context"
+	"net/http"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Scope is the set of projects a caller may see. OwnerID is the caller and
+// owns the projects it creates. Reads and writes are restricted to the
+// projects of the caller's organization, OrgID, or to the owner's own projects
+// when the caller has no organization, unless All is set. Verify allows
+// moving projects into the review states of their verification lifecycle.
+type Scope struct {
+	OwnerID uuid.UUID
+	OrgID   uuid.UUID
+	All     bool
+	Verify  bool
+}
+
+type scopeKey struct{}
+
+// WithScope returns ctx carrying scope. The repository applies it to every
+// lookup; contexts without a scope, such as background jobs, are unrestricted.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the scope stored by WithScope.
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// Restricts reports whether ctx limits queries to a single organization or
+// owner, and the projects column and value to filter on.
+func Restricts(ctx context.Context) (string, uuid.UUID, bool) {
+	scope, ok := ScopeFromContext(ctx)
+	if !ok || scope.All {
+		return "", uuid.Nil, false
+	}
+	if scope.OrgID != uuid.Nil {
+		return "org_id", scope.OrgID, true
+	}
+	return "owner_id", scope.OwnerID, true
+}
+
+// scoped adds the organization or owner filter for ctx's scope to a projects
+// query.
+func scoped(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if column, id, ok := Restricts(ctx); ok {
+		return db.Where(column+" = ?", id)
+	}
+	return db
+}
+
+// OwnerScope derives the request's Scope from the authenticated user and
+// organization set by auth.AuthMiddleware. Callers holding
+// projects:manage_all see every project, across organizations. Other
+// packages serving project data, such as geospatial, use it too so their
+// queries see the same projects.
+func OwnerScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		scope := Scope{
+			All:    auth.HasPermission(role, auth.PermProjectsManageAll),
+			Verify: auth.HasPermission(role, auth.PermProjectsVerify),
+		}
+		owner, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil && !scope.All {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token does not identify a user"})
+			return
+		}
+		scope.OwnerID = owner
+		if raw := c.GetString("org_id"); raw != "" {
+			if scope.OrgID, err = uuid.Parse(raw); err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token carries an invalid org_id"})
+				return
+			}
+		}
+		c.Request = c.Request.WithContext(WithScope(c.Request.Context(), scope))
+		c.Next()
+	}
+}
@@ -0,0 +1,243 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func token(t *testing.T, userID uuid.UUID, role string) string {
+	t.Helper()
+	signed, err := auth.GenerateJWT(&auth.User{ID: userID.String(), Email: role + "@example.com", Role: role})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	return signed
+}
+
+// orgToken signs an access token for a member of org.
+func orgToken(t *testing.T, userID, org uuid.UUID, role string) string {
+	t.Helper()
+	orgID := org.String()
+	signed, err := auth.GenerateJWT(&auth.User{ID: userID.String(), Email: role + "@example.com", Role: role, OrgID: &orgID})
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	return signed
+}
+
+// visible applies ctx's scope the way the repository does.
+func visible(ctx context.Context, p *Project) bool {
+	column, id, ok := Restricts(ctx)
+	if !ok {
+		return true
+	}
+	if column == "org_id" {
+		return p.OrgID != nil && *p.OrgID == id
+	}
+	return p.OwnerID != nil && *p.OwnerID == id
+}
+
+func (r *memoryRepo) Create(_ context.Context, p *Project) error {
+	p.ID = uuid.New()
+	r.projects[p.ID] = p
+	return nil
+}
+
+func (r *memoryRepo) List(ctx context.Context, _ ListFilter) ([]Project, error) {
+	var out []Project
+	for _, p := range r.projects {
+		if visible(ctx, p) {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	p, ok := r.projects[id]
+	if !ok || !visible(ctx, p) {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.projects, id)
+	return nil
+}
+
+func (r *memoryRepo) GetPerimeterMeters(context.Context, uuid.UUID) (*float64, error) {
+	return nil, nil
+}
+
+func TestProjectOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &memoryRepo{projects: map[uuid.UUID]*Project{}}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	alice, bob, admin := uuid.New(), uuid.New(), uuid.New()
+	do := func(method, path, body string, user uuid.UUID, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/projects"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token(t, user, role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "", `{"name":"Alice's forest","type":"Reforestation","location":"Kenya","area":12}`, alice, "user")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created Project
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.OwnerID == nil || *created.OwnerID != alice {
+		t.Fatalf("expected the creator to own the project, got %v", created.OwnerID)
+	}
+	path := "/" + created.ID.String()
+
+	if w := do(http.MethodGet, path, "", alice, "user"); w.Code != http.StatusOK {
+		t.Errorf("owner get: expected 200, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, path, "", bob, "user"); w.Code != http.StatusNotFound {
+		t.Errorf("other owner get: expected 404, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, path, `{"name":"taken"}`, bob, "user"); w.Code != http.StatusNotFound {
+		t.Errorf("other owner update: expected 404, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, path, "", bob, "user"); w.Code != http.StatusNotFound {
+		t.Errorf("other owner delete: expected 404, got %d", w.Code)
+	}
+	if repo.projects[created.ID].Name != "Alice's forest" {
+		t.Errorf("another owner's update must not apply")
+	}
+
+	var listed struct {
+		Projects []Project `json:"projects"`
+	}
+	json.Unmarshal(do(http.MethodGet, "", "", bob, "user").Body.Bytes(), &listed)
+	if len(listed.Projects) != 0 {
+		t.Errorf("other owner list: expected no projects, got %d", len(listed.Projects))
+	}
+
+	if w := do(http.MethodGet, path, "", admin, "admin"); w.Code != http.StatusOK {
+		t.Errorf("admin get: expected 200, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, path, `{"name":"Renamed by admin"}`, admin, "admin"); w.Code != http.StatusOK {
+		t.Errorf("admin update: expected 200, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, path, "", admin, "admin"); w.Code != http.StatusOK {
+		t.Errorf("admin delete: expected 200, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/projects", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous list: expected 401, got %d", w.Code)
+	}
+}
+
+func TestProjectOrganizationScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &memoryRepo{projects: map[uuid.UUID]*Project{}}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	orgA, orgB := uuid.New(), uuid.New()
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	do := func(method, path, body, bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/projects"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "", `{"name":"Co-op forest","type":"Reforestation","location":"Kenya","area":12}`, orgToken(t, alice, orgA, "user"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created Project
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.OrgID == nil || *created.OrgID != orgA {
+		t.Fatalf("expected the project to belong to the creator's organization, got %v", created.OrgID)
+	}
+	path := "/" + created.ID.String()
+
+	if w := do(http.MethodGet, path, "", orgToken(t, bob, orgA, "user")); w.Code != http.StatusOK {
+		t.Errorf("same-organization get: expected 200, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, path, "", orgToken(t, carol, orgB, "user")); w.Code != http.StatusNotFound {
+		t.Errorf("other-organization get: expected 404, got %d", w.Code)
+	}
+	// The creator's own id does not reach across organizations: the org claim
+	// decides.
+	if w := do(http.MethodGet, path, "", orgToken(t, alice, orgB, "user")); w.Code != http.StatusNotFound {
+		t.Errorf("creator under another organization: expected 404, got %d", w.Code)
+	}
+	var listed struct {
+		Projects []Project `json:"projects"`
+	}
+	json.Unmarshal(do(http.MethodGet, "", "", orgToken(t, carol, orgB, "user")).Body.Bytes(), &listed)
+	if len(listed.Projects) != 0 {
+		t.Errorf("other-organization list: expected no projects, got %d", len(listed.Projects))
+	}
+	if w := do(http.MethodGet, path, "", orgToken(t, uuid.New(), orgB, "admin")); w.Code != http.StatusOK {
+		t.Errorf("admin of another organization get: expected 200, got %d", w.Code)
+	}
+
+	if w := do(http.MethodGet, path, "", token(t, carol, "user")); w.Code != http.StatusNotFound {
+		t.Errorf("token without an organization: expected 404, got %d", w.Code)
+	}
+	bad := "not-a-uuid"
+	malformed, _ := auth.GenerateJWT(&auth.User{ID: carol.String(), Role: "user", OrgID: &bad})
+	if w := do(http.MethodGet, path, "", malformed); w.Code != http.StatusUnauthorized {
+		t.Errorf("malformed org_id: expected 401, got %d", w.Code)
+	}
+}
+
+// brokenRepo fails every lookup the way an unreachable database would.
+type brokenRepo struct{ memoryRepo }
+
+func (brokenRepo) GetByID(context.Context, uuid.UUID) (*Project, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestGetProject_NotFoundIsNot500(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	get := func(repo Repository, id uuid.UUID) *httptest.ResponseRecorder {
+		router := gin.New()
+		NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/projects/"+id.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+token(t, uuid.New(), "admin"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(&memoryRepo{projects: map[uuid.UUID]*Project{}}, uuid.New())
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusNotFound || body.Error != "project not found" {
+		t.Errorf("unknown project: expected 404 project not found, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get(&brokenRepo{}, uuid.New()); w.Code != http.StatusInternalServerError {
+		t.Errorf("failed lookup: expected 500, got %d: %s", w.Code, w.Body.String())
+	}
+}
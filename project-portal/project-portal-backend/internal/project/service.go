@@ -3,6 +3,7 @@ package project
 import (
 	"context"
 	"errors"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,17 +12,26 @@ import (
 type Service interface {
 	CreateProject(ctx context.Context, req *ProjectCreateRequest) (*Project, error)
 	GetProject(ctx context.Context, id uuid.UUID) (*Project, error)
-	ListProjects(ctx context.Context, limit, offset int) ([]Project, error)
+	ListProjects(ctx context.Context, filter ListFilter) ([]Project, error)
 	UpdateProject(ctx context.Context, id uuid.UUID, req *ProjectUpdateRequest) (*Project, error)
 	DeleteProject(ctx context.Context, id uuid.UUID) error
+	RestoreProject(ctx context.Context, id uuid.UUID) (*Project, error)
+	AddTags(ctx context.Context, id uuid.UUID, tags []string) (*Project, error)
+	RemoveTag(ctx context.Context, id uuid.UUID, tag string) (*Project, error)
+	SetThumbnail(ctx context.Context, id uuid.UUID, r io.Reader) (*Project, error)
+	Stats(ctx context.Context, bbox *BBox) (*ProjectStats, error)
+	TransitionVerification(ctx context.Context, id uuid.UUID, to string) (*Project, error)
+	TransferOwnership(ctx context.Context, id, to uuid.UUID) (*Project, error)
 }
 
 type service struct {
-	repo Repository
+	repo          Repository
+	images        ImageStore
+	maxImageBytes int64
 }
 
 func NewService(repo Repository) Service {
-	return &service{repo: repo}
+	return &service{repo: repo, maxImageBytes: DefaultThumbnailMaxBytes}
 }
 
 func (s *service) CreateProject(ctx context.Context, req *ProjectCreateRequest) (*Project, error) {
@@ -42,6 +52,22 @@ func (s *service) CreateProject(ctx context.Context, req *ProjectCreateRequest)
 	if req.Status == "" {
 		project.Status = "pending"
 	}
+	project.VerificationStatus = VerificationDraft
+	if scope, ok := ScopeFromContext(ctx); ok {
+		if scope.OwnerID != uuid.Nil {
+			owner := scope.OwnerID
+			project.OwnerID = &owner
+		}
+		if scope.OrgID != uuid.Nil {
+			org := scope.OrgID
+			project.OrgID = &org
+		}
+	}
+
+	project.Tags = NormalizeTags(req.Tags)
+	if err := validateTags(project.Tags); err != nil {
+		return nil, err
+	}
 
 	if req.StartDate != "" {
 		startDate, err := time.Parse("2006-01-02", req.StartDate)
@@ -60,17 +86,30 @@ func (s *service) CreateProject(ctx context.Context, req *ProjectCreateRequest)
 }
 
 func (s *service) GetProject(ctx context.Context, id uuid.UUID) (*Project, error) {
-	return s.repo.GetByID(ctx, id)
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	project.PerimeterMeters, err = s.repo.GetPerimeterMeters(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
 }
 
-func (s *service) ListProjects(ctx context.Context, limit, offset int) ([]Project, error) {
-	if limit <= 0 {
-		limit = 10
+func (s *service) ListProjects(ctx context.Context, filter ListFilter) ([]Project, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 10
 	}
-	if limit > 100 {
-		limit = 100
+	if filter.Limit > 100 {
+		filter.Limit = 100
 	}
-	return s.repo.List(ctx, limit, offset)
+	filter.Tags = NormalizeTags(filter.Tags)
+	return s.repo.List(ctx, filter)
+}
+
+func (s *service) Stats(ctx context.Context, bbox *BBox) (*ProjectStats, error) {
+	return s.repo.Stats(ctx, bbox)
 }
 
 func (s *service) UpdateProject(ctx context.Context, id uuid.UUID, req *ProjectUpdateRequest) (*Project, error) {
@@ -126,4 +165,44 @@ func (s *service) UpdateProject(ctx context.Context, id uuid.UUID, req *ProjectU
 
 func (s *service) DeleteProject(ctx context.Context, id uuid.UUID) error {
 	return s.repo.Delete(ctx, id)
-}
\ No newline at end of file
+}
+
+func (s *service) RestoreProject(ctx context.Context, id uuid.UUID) (*Project, error) {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *service) AddTags(ctx context.Context, id uuid.UUID, tags []string) (*Project, error) {
+	add := NormalizeTags(tags)
+	if len(add) == 0 {
+		return nil, errors.New("at least one non-empty tag is required")
+	}
+	if err := validateTags(add); err != nil {
+		return nil, err
+	}
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	project.Tags = mergeTags(project.Tags, add)
+	project.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (s *service) RemoveTag(ctx context.Context, id uuid.UUID, tag string) (*Project, error) {
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	project.Tags = removeTag(project.Tags, tag)
+	project.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
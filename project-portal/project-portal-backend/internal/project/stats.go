@@ -0,0 +1,71 @@
+package project
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// statsSQL aggregates every figure of ProjectStats in one round trip. Mapped
+// projects contribute the cached area of their stored boundary; projects
+// without one fall back to their declared area. filter is appended to the
+// WHERE clause and is built from ownerStatsFilter and bboxStatsFilter.
+func statsSQL(filter string) string {
+	return fmt.Sprintf(`
+WITH scoped AS (
+  SELECT p.type,
+         p.tags,
+         COALESCE(pg.area_hectares, p.area) AS area_hectares
+  FROM projects p
+  LEFT JOIN project_geometries pg ON pg.project_id = p.id
+  WHERE p.deleted_at IS NULL%s
+)
+SELECT
+  (SELECT COUNT(*) FROM scoped) AS project_count,
+  (SELECT COALESCE(SUM(area_hectares), 0) FROM scoped) AS total_area_hectares,
+  (SELECT COALESCE(AVG(area_hectares), 0) FROM scoped) AS average_area_hectares,
+  (SELECT COALESCE(json_object_agg(type, n), '{}')
+     FROM (SELECT type, COUNT(*) AS n FROM scoped GROUP BY type) t) AS by_type,
+  (SELECT COALESCE(json_object_agg(tag, n), '{}')
+     FROM (SELECT tag, COUNT(*) AS n FROM scoped, unnest(tags) AS tag GROUP BY tag) t) AS by_tag
+`, filter)
+}
+
+// scopeStatsFilter limits statsSQL to the projects whose column, org_id or
+// owner_id, matches.
+func scopeStatsFilter(column string) string {
+	return "\n    AND p." + column + " = ?"
+}
+
+// bboxStatsFilter limits statsSQL to boundaries intersecting an envelope. It
+// takes min lon, min lat, max lon and max lat.
+const bboxStatsFilter = `
+    AND ST_Intersects(pg.geometry::geometry, ST_MakeEnvelope(?, ?, ?, ?, 4326))`
+
+// ParseBBox reads a "minLon,minLat,maxLon,maxLat" query value, the GeoJSON
+// bbox order. An empty value means no filter.
+func ParseBBox(raw string) (*BBox, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox must be minLon,minLat,maxLon,maxLat")
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bbox must be minLon,minLat,maxLon,maxLat")
+		}
+		v[i] = f
+	}
+	box := &BBox{MinLon: v[0], MinLat: v[1], MaxLon: v[2], MaxLat: v[3]}
+	if box.MinLon < -180 || box.MaxLon > 180 || box.MinLat < -90 || box.MaxLat > 90 {
+		return nil, fmt.Errorf("bbox is outside lon [-180,180] / lat [-90,90]")
+	}
+	if box.MinLon >= box.MaxLon || box.MinLat >= box.MaxLat {
+		return nil, fmt.Errorf("bbox minimums must be below its maximums")
+	}
+	return box, nil
+}
@@ -0,0 +1,82 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestParseBBox(t *testing.T) {
+	box, err := ParseBBox("36.5, -1.5,37,-1")
+	if err != nil {
+		t.Fatalf("ParseBBox: %v", err)
+	}
+	if *box != (BBox{MinLon: 36.5, MinLat: -1.5, MaxLon: 37, MaxLat: -1}) {
+		t.Fatalf("unexpected bbox %+v", *box)
+	}
+	if box, err := ParseBBox(""); box != nil || err != nil {
+		t.Fatalf("expected no filter for an empty value, got %+v, %v", box, err)
+	}
+	for _, raw := range []string{"1,2,3", "a,b,c,d", "37,-1.5,36.5,-1", "-181,0,0,1", "0,0,1,91"} {
+		if _, err := ParseBBox(raw); err == nil {
+			t.Errorf("ParseBBox(%q): expected an error", raw)
+		}
+	}
+}
+
+// statsRepo records the bbox it was asked to aggregate over.
+type statsRepo struct {
+	Repository
+	got *BBox
+}
+
+func (r *statsRepo) Stats(_ context.Context, bbox *BBox) (*ProjectStats, error) {
+	r.got = bbox
+	return &ProjectStats{
+		ProjectCount:        2,
+		TotalAreaHectares:   30,
+		AverageAreaHectares: 15,
+		ByType:              map[string]int64{"Reforestation": 2},
+		ByTag:               map[string]int64{"kenya": 2},
+		BBox:                bbox,
+	}, nil
+}
+
+func TestProjectStatsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &statsRepo{}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token(t, uuid.New(), "user"))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := get("/api/v1/projects/stats?bbox=36,-2,37,-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if repo.got == nil || repo.got.MinLon != 36 || repo.got.MaxLat != -1 {
+		t.Fatalf("expected the bbox to reach the repository, got %+v", repo.got)
+	}
+	var stats ProjectStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if stats.ProjectCount != 2 || stats.TotalAreaHectares != 30 || stats.ByTag["kenya"] != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	w = get("/api/v1/projects/stats?bbox=37,-2,36,-1")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an inverted bbox, got %d", w.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package project
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// streamWriteTimeout bounds each message write, so a client that stops
+// reading is disconnected instead of pinning its goroutine.
+const streamWriteTimeout = 10 * time.Second
+
+// ChangeStream serves a ChangeHub over WebSocket.
+type ChangeStream struct {
+	hub *ChangeHub
+	// origins may open the socket with the access token cookie; see
+	// SetAllowedOrigins.
+	origins []string
+}
+
+func NewChangeStream(hub *ChangeHub) *ChangeStream {
+	return &ChangeStream{hub: hub}
+}
+
+// SetAllowedOrigins lists the browser origins, besides the API's own, that
+// may open the socket signed in with the access token cookie. "*" matches
+// none of them, as a credentialed CORS request would not either.
+func (s *ChangeStream) SetAllowedOrigins(origins []string) {
+	s.origins = origins
+}
+
+var errForeignOrigin = errors.New("origin may not use the access token cookie")
+
+// Serve upgrades the request to a WebSocket and sends every ChangeEvent in the
+// caller's Scope as a JSON text message. Messages from the client are read and
+// discarded; that is how a disconnect is noticed.
+func (s *ChangeStream) Serve(c *gin.Context) {
+	scope, _ := ScopeFromContext(c.Request.Context())
+	server := websocket.Server{
+		// WebSocket upgrades bypass CORS, so a handshake signed in by the
+		// cookie alone must come from an allowed origin; otherwise any page
+		// the browser visits could read the caller's feed. Bearer tokens are
+		// never sent implicitly and need no check.
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			if r.Header.Get("Authorization") == "" && !s.originAllowed(r) {
+				return errForeignOrigin
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) { s.stream(conn, scope) },
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// originAllowed reports whether the request's Origin is the API's own host or
+// one of s.origins. Requests without an Origin come from outside a browser.
+func (s *ChangeStream) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+	for _, allowed := range s.origins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ChangeStream) stream(conn *websocket.Conn, scope Scope) {
+	defer conn.Close()
+	events, unsubscribe := s.hub.Subscribe(scope)
+	defer unsubscribe()
+
+	// The server's read and write timeouts still apply to the hijacked
+	// connection; a subscription is open-ended.
+	_ = conn.SetDeadline(time.Time{})
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		var discard []byte
+		for {
+			if err := websocket.Message.Receive(conn, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				// Dropped by the hub for falling behind.
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := websocket.JSON.Send(conn, ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// tokenFromQuery lets browser WebSocket clients, which cannot set headers,
+// pass their access token as ?access_token= (RFC 6750 section 2.3). An
+// Authorization header takes precedence.
+func tokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
+// RegisterRoutes mounts the feed at /ws/projects.
+func (s *ChangeStream) RegisterRoutes(router gin.IRoutes) {
+	router.GET("/ws/projects", tokenFromQuery(), auth.AuthMiddleware(), OwnerScope(), s.Serve)
+}
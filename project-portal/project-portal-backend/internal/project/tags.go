@@ -0,0 +1,72 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const maxTagLength = 64
+
+// NormalizeTags lowercases and trims tags, drops empties and duplicates and
+// returns them sorted so stored arrays compare cleanly.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ParseTags splits a comma-separated query value such as "reforestation,kenya".
+func ParseTags(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	return NormalizeTags(strings.Split(raw, ","))
+}
+
+// ParseTagMatch validates the tag_match query value.
+func ParseTagMatch(raw string) (string, error) {
+	switch strings.ToLower(raw) {
+	case "", TagMatchAny:
+		return TagMatchAny, nil
+	case TagMatchAll:
+		return TagMatchAll, nil
+	}
+	return "", fmt.Errorf("tag_match must be %q or %q", TagMatchAny, TagMatchAll)
+}
+
+func validateTags(tags []string) error {
+	for _, t := range tags {
+		if len(t) > maxTagLength {
+			return fmt.Errorf("tag %q exceeds %d characters", t, maxTagLength)
+		}
+	}
+	return nil
+}
+
+func mergeTags(existing, add []string) []string {
+	return NormalizeTags(append(append([]string{}, existing...), add...))
+}
+
+func removeTag(existing []string, tag string) []string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	out := make([]string, 0, len(existing))
+	for _, t := range existing {
+		if t != tag {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := NormalizeTags([]string{" Soil-Carbon", "kenya", "", "KENYA", "agroforestry"})
+	want := []string{"agroforestry", "kenya", "soil-carbon"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NormalizeTags = %v, want %v", got, want)
+	}
+}
+
+func TestParseTagMatch(t *testing.T) {
+	for raw, want := range map[string]string{"": TagMatchAny, "any": TagMatchAny, "ALL": TagMatchAll} {
+		got, err := ParseTagMatch(raw)
+		if err != nil || got != want {
+			t.Errorf("ParseTagMatch(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ParseTagMatch("some"); err == nil {
+		t.Error("expected an error for an unknown match mode")
+	}
+}
@@ -0,0 +1,132 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// DefaultThumbnailMaxBytes caps thumbnail uploads when no limit is configured.
+const DefaultThumbnailMaxBytes = 5 * 1024 * 1024
+
+var (
+	ErrUnsupportedImage   = errors.New("thumbnail must be a JPEG, PNG, GIF or WebP image")
+	ErrImageTooLarge      = errors.New("thumbnail exceeds the maximum allowed size")
+	ErrThumbnailsDisabled = errors.New("thumbnail storage is not configured")
+)
+
+// thumbnailExtensions maps the sniffed content types we accept to the file
+// extension used for the stored object.
+var thumbnailExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageStore saves an image under key and returns the URL clients load it from.
+type ImageStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+}
+
+// LocalImageStore writes images below Dir and serves them from BaseURL, which
+// the router maps onto Dir.
+type LocalImageStore struct {
+	Dir     string
+	BaseURL string
+}
+
+func (s *LocalImageStore) Put(_ context.Context, key string, r io.Reader, _ string) (string, error) {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(s.BaseURL, "/") + "/" + key, nil
+}
+
+// S3ImageStore uploads to an S3-compatible bucket. With BaseURL set (a CDN or
+// public bucket endpoint) the URL is BaseURL/key; otherwise the upload location
+// reported by S3 is returned.
+type S3ImageStore struct {
+	Client  *storage.S3Client
+	BaseURL string
+}
+
+func (s *S3ImageStore) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	result, err := s.Client.Upload(ctx, key, r, contentType)
+	if err != nil {
+		return "", err
+	}
+	if s.BaseURL != "" {
+		return strings.TrimRight(s.BaseURL, "/") + "/" + key, nil
+	}
+	return result.Location, nil
+}
+
+// NewServiceWithThumbnails returns a service that stores thumbnails in images.
+// maxBytes <= 0 uses DefaultThumbnailMaxBytes.
+func NewServiceWithThumbnails(repo Repository, images ImageStore, maxBytes int64) Service {
+	if maxBytes <= 0 {
+		maxBytes = DefaultThumbnailMaxBytes
+	}
+	return &service{repo: repo, images: images, maxImageBytes: maxBytes}
+}
+
+// SetThumbnail stores an uploaded image and records its URL on the project.
+// The content type is sniffed from the bytes; the client's header is ignored.
+func (s *service) SetThumbnail(ctx context.Context, id uuid.UUID, r io.Reader) (*Project, error) {
+	if s.images == nil {
+		return nil, ErrThumbnailsDisabled
+	}
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxImageBytes {
+		return nil, fmt.Errorf("%w of %d bytes", ErrImageTooLarge, s.maxImageBytes)
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := thumbnailExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w (got %s)", ErrUnsupportedImage, contentType)
+	}
+
+	key := fmt.Sprintf("projects/%s/thumbnail/%s%s", id, time.Now().UTC().Format("20060102T150405"), ext)
+	url, err := s.images.Put(ctx, key, bytes.NewReader(data), contentType)
+	if err != nil {
+		return nil, err
+	}
+	project.ThumbnailURL = url
+	project.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
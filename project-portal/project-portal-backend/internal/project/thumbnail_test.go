@@ -0,0 +1,104 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryRepo keeps projects in a map for handler tests.
+type memoryRepo struct {
+	Repository
+	projects map[uuid.UUID]*Project
+}
+
+func (r *memoryRepo) GetByID(ctx context.Context, id uuid.UUID) (*Project, error) {
+	p, ok := r.projects[id]
+	if !ok || !visible(ctx, p) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func (r *memoryRepo) Update(_ context.Context, p *Project) error {
+	r.projects[p.ID] = p
+	return nil
+}
+
+func uploadThumbnail(t *testing.T, router *gin.Engine, id uuid.UUID, filename string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("image", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/projects/"+id.String()+"/thumbnail", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token(t, uuid.New(), "admin"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadThumbnail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	id := uuid.New()
+	repo := &memoryRepo{projects: map[uuid.UUID]*Project{id: {ID: id, Name: "Mau Forest"}}}
+	store := &LocalImageStore{Dir: dir, BaseURL: "/media/thumbnails"}
+	router := gin.New()
+	NewHandler(NewServiceWithThumbnails(repo, store, 1024)).RegisterRoutes(router.Group("/api/v1"))
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	w := uploadThumbnail(t, router, id, "map.png", img.Bytes())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var got Project
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !strings.HasPrefix(got.ThumbnailURL, "/media/thumbnails/projects/"+id.String()+"/thumbnail/") || !strings.HasSuffix(got.ThumbnailURL, ".png") {
+		t.Fatalf("unexpected thumbnail url %q", got.ThumbnailURL)
+	}
+	stored, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(got.ThumbnailURL, "/media/thumbnails/")))
+	if err != nil || !bytes.Equal(stored, img.Bytes()) {
+		t.Fatalf("stored file does not match the upload: %v", err)
+	}
+
+	// The client's filename and part header do not matter; the bytes do.
+	if w := uploadThumbnail(t, router, id, "photo.png", []byte("%PDF-1.7 not an image")); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("non-image: expected 415, got %d", w.Code)
+	}
+	large := append(append([]byte{}, img.Bytes()...), make([]byte, 1024)...)
+	if w := uploadThumbnail(t, router, id, "big.png", large); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized: expected 413, got %d", w.Code)
+	}
+	if w := uploadThumbnail(t, router, uuid.New(), "map.png", img.Bytes()); w.Code != http.StatusNotFound {
+		t.Errorf("unknown project: expected 404, got %d", w.Code)
+	}
+	if repo.projects[id].ThumbnailURL != got.ThumbnailURL {
+		t.Errorf("rejected uploads must not replace the stored thumbnail")
+	}
+}
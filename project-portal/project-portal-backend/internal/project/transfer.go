@@ -0,0 +1,119 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/compliance"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTransferForbidden is returned when a caller who neither owns the
+	// project nor holds projects:manage_all tries to transfer it.
+	ErrTransferForbidden = errors.New("only the project owner or an administrator can transfer the project")
+	// ErrTransferTargetNotFound is returned when the new owner is not an
+	// active user.
+	ErrTransferTargetNotFound = errors.New("target user not found")
+	// ErrTransferOutsideOrg is returned when an owner tries to hand the
+	// project to a user outside its organization.
+	ErrTransferOutsideOrg = errors.New("target user is not in the project's organization")
+	// ErrOwnerChanged is returned when the project changed hands while the
+	// transfer was being checked.
+	ErrOwnerChanged = errors.New("project owner changed during the transfer")
+)
+
+// TransferRequest hands a project to another user.
+type TransferRequest struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+}
+
+// AuditLog records audit events; *compliance.Service is one.
+type AuditLog interface {
+	LogAuditEvent(ctx context.Context, entry compliance.AuditEntry) error
+}
+
+var auditLog AuditLog
+
+// RecordAuditTo makes ownership transfers write to log. Without it they are
+// only logged.
+func RecordAuditTo(log AuditLog) {
+	auditLog = log
+}
+
+// TransferOwnership hands project id to the active user to and
+// moves the project into to's organization. Within ctx's scope only the
+// owner may transfer, and only to a member of the project's organization;
+// callers seeing every project may transfer to anyone. Contexts without a
+// scope are trusted.
+func (s *service) TransferOwnership(ctx context.Context, id, to uuid.UUID) (*Project, error) {
+	project, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	scope, scoped := ScopeFromContext(ctx)
+	if scoped && !scope.All && (project.OwnerID == nil || *project.OwnerID != scope.OwnerID) {
+		return nil, ErrTransferForbidden
+	}
+
+	org, err := s.repo.UserOrg(ctx, to)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTransferTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if scoped && !scope.All && !sameID(org, project.OrgID) {
+		return nil, ErrTransferOutsideOrg
+	}
+
+	from, fromOrg, at := project.OwnerID, project.OrgID, time.Now()
+	if err := s.repo.TransferOwnership(ctx, id, from, to, org, at); err != nil {
+		return nil, err
+	}
+	project.OwnerID, project.OrgID, project.UpdatedAt = &to, org, at
+
+	logging.FromContext(ctx).Info("project ownership transferred", "project_id", id, "from", from, "to", to)
+	recordTransfer(ctx, scope.OwnerID, project, from, fromOrg)
+	return project, nil
+}
+
+// recordTransfer writes the transfer to the audit log. The transfer already
+// happened, so a failure is logged rather than returned.
+func recordTransfer(ctx context.Context, actor uuid.UUID, project *Project, from, fromOrg *uuid.UUID) {
+	if auditLog == nil {
+		return
+	}
+	entry := compliance.AuditEntry{
+		EventType:        "project_ownership",
+		EventAction:      "transfer",
+		ActorType:        compliance.ActorTypeUser,
+		TargetType:       "project",
+		TargetID:         project.ID.String(),
+		TargetOwnerID:    project.OwnerID.String(),
+		SensitivityLevel: compliance.SensitivitySensitive,
+		ServiceName:      "project",
+		Endpoint:         "/api/v1/projects/:id/transfer",
+		OldValues:        map[string]any{"owner_id": from, "org_id": fromOrg},
+		NewValues:        map[string]any{"owner_id": project.OwnerID, "org_id": project.OrgID},
+	}
+	if actor != uuid.Nil {
+		entry.ActorID = actor.String()
+	} else {
+		entry.ActorType = compliance.ActorTypeSystem
+	}
+	if err := auditLog.LogAuditEvent(ctx, entry); err != nil {
+		logging.FromContext(ctx).Error("recording project transfer in the audit log failed", "project_id", project.ID, "error", err)
+	}
+}
+
+func sameID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
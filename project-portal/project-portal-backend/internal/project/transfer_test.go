@@ -0,0 +1,134 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/compliance"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// transferRepo is a memoryRepo that also knows each active user's
+// organization.
+type transferRepo struct {
+	*memoryRepo
+	users map[uuid.UUID]*uuid.UUID
+}
+
+func (r *transferRepo) UserOrg(_ context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	org, ok := r.users[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return org, nil
+}
+
+func (r *transferRepo) TransferOwnership(ctx context.Context, id uuid.UUID, from *uuid.UUID, to uuid.UUID, org *uuid.UUID, _ time.Time) error {
+	p, ok := r.projects[id]
+	if !ok || !visible(ctx, p) {
+		return gorm.ErrRecordNotFound
+	}
+	if !sameID(p.OwnerID, from) {
+		return ErrOwnerChanged
+	}
+	p.OwnerID, p.OrgID = &to, org
+	return nil
+}
+
+type recordedAudit struct {
+	entries []compliance.AuditEntry
+}
+
+func (a *recordedAudit) LogAuditEvent(_ context.Context, entry compliance.AuditEntry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func TestTransferOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &recordedAudit{}
+	RecordAuditTo(audit)
+	t.Cleanup(func() { RecordAuditTo(nil) })
+
+	orgA, orgB := uuid.New(), uuid.New()
+	owner, colleague, member, outsider, admin := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	repo := &transferRepo{
+		memoryRepo: &memoryRepo{projects: map[uuid.UUID]*Project{}},
+		users:      map[uuid.UUID]*uuid.UUID{owner: &orgA, colleague: &orgA, member: &orgA, outsider: &orgB},
+	}
+	router := gin.New()
+	NewHandler(NewService(repo)).RegisterRoutes(router.Group("/api/v1"))
+
+	do := func(method, path, body string, user, org uuid.UUID, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/projects"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+orgToken(t, user, org, role))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "", `{"name":"Riverbank","type":"Reforestation","location":"Ghana","area":30}`, owner, orgA, "user")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created Project
+	json.Unmarshal(w.Body.Bytes(), &created)
+	path := "/" + created.ID.String() + "/transfer"
+	transfer := func(to, user, org uuid.UUID, role string) *httptest.ResponseRecorder {
+		return do(http.MethodPost, path, `{"user_id":"`+to.String()+`"}`, user, org, role)
+	}
+
+	if w := transfer(member, member, orgA, "user"); w.Code != http.StatusForbidden {
+		t.Errorf("non-owner member: expected 403, got %d", w.Code)
+	}
+	if w := transfer(uuid.New(), owner, orgA, "user"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown target: expected 404, got %d", w.Code)
+	}
+	if w := transfer(outsider, owner, orgA, "user"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("target in another organization: expected 422, got %d", w.Code)
+	}
+	if len(audit.entries) != 0 || *repo.projects[created.ID].OwnerID != owner {
+		t.Fatalf("rejected transfers must change nothing")
+	}
+
+	w = transfer(colleague, owner, orgA, "user")
+	if w.Code != http.StatusOK {
+		t.Fatalf("owner transfer: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var moved Project
+	json.Unmarshal(w.Body.Bytes(), &moved)
+	if moved.OwnerID == nil || *moved.OwnerID != colleague || moved.OrgID == nil || *moved.OrgID != orgA {
+		t.Errorf("expected the colleague to own the project in orgA, got owner %v org %v", moved.OwnerID, moved.OrgID)
+	}
+	if w := transfer(owner, owner, orgA, "user"); w.Code != http.StatusForbidden {
+		t.Errorf("former owner: expected 403, got %d", w.Code)
+	}
+
+	if w := transfer(outsider, admin, uuid.New(), "admin"); w.Code != http.StatusOK {
+		t.Fatalf("admin transfer across organizations: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if p := repo.projects[created.ID]; *p.OwnerID != outsider || *p.OrgID != orgB {
+		t.Errorf("expected the project to follow its new owner into orgB, got owner %v org %v", p.OwnerID, p.OrgID)
+	}
+
+	if len(audit.entries) != 2 {
+		t.Fatalf("expected both transfers in the audit log, got %d entries", len(audit.entries))
+	}
+	first := audit.entries[0]
+	if first.EventAction != "transfer" || first.TargetID != created.ID.String() || first.ActorID != owner.String() ||
+		first.TargetOwnerID != colleague.String() || first.OldValues["owner_id"].(*uuid.UUID).String() != owner.String() {
+		t.Errorf("unexpected audit entry %+v", first)
+	}
+	if audit.entries[1].ActorID != admin.String() {
+		t.Errorf("expected the admin recorded as the actor, got %q", audit.entries[1].ActorID)
+	}
+}
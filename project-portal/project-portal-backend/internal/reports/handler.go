@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"time"
 
+	"carbon-scribe/project-portal/project-portal-backend/pkg/apierror"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -183,7 +185,7 @@ func (h *Handler) GetReport(c *gin.Context) {
 	userID := getUserID(c)
 	report, err := h.service.GetReport(c.Request.Context(), userID, reportID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierror.Respond(c, err, "report")
 		return
 	}
 
@@ -430,7 +432,7 @@ func (h *Handler) GetExecution(c *gin.Context) {
 
 	execution, err := h.service.GetExecution(c.Request.Context(), executionID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierror.Respond(c, err, "execution")
 		return
 	}
 
@@ -734,7 +736,7 @@ func (h *Handler) GetSchedule(c *gin.Context) {
 
 	schedule, err := h.service.GetSchedule(c.Request.Context(), scheduleID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		apierror.Respond(c, err, "schedule")
 		return
 	}
 
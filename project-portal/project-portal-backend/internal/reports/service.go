@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"carbon-scribe/project-portal/project-portal-backend/pkg/apierror"
+
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 )
@@ -128,9 +130,9 @@ func (s *service) GetReport(ctx context.Context, userID uuid.UUID, reportID uuid
 		return nil, fmt.Errorf("report not found: %w", err)
 	}
 
-	// Check access permission
+	// Check access permission; reports the caller cannot see do not exist to them
 	if !s.canAccessReport(report, userID) {
-		return nil, fmt.Errorf("access denied to report")
+		return nil, fmt.Errorf("access denied to report: %w", apierror.ErrNotFound)
 	}
 
 	return report, nil
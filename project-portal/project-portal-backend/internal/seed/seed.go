@@ -0,0 +1,190 @@
+// Package seed fills a development database with a few users of known
+// credentials and sample project boundaries. Running it again only adds what
+// is missing.
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/internal/auth"
+	"carbon-scribe/project-portal/project-portal-backend/internal/geospatial"
+	"carbon-scribe/project-portal/project-portal-backend/internal/project"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/postgis"
+	"carbon-scribe/project-portal/project-portal-backend/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Password is the password of every seeded user.
+const Password = "carbon-scribe-dev"
+
+// ErrProduction is returned when seeding is attempted in production mode.
+var ErrProduction = errors.New("refusing to seed a production database (SERVER_MODE=production)")
+
+// Account is a seeded user; every one signs in with Password.
+type Account struct {
+	Email, Username, FullName, Role string
+}
+
+// Users are the seeded accounts, one per role.
+var Users = []Account{
+	{"admin@carbonscribe.dev", "admin", "Ada Admin", "admin"},
+	{"verifier@carbonscribe.dev", "verifier", "Vera Verifier", "verifier"},
+	{"partner@carbonscribe.dev", "partner", "Pat Partner", "partner"},
+	{"user@carbonscribe.dev", "user", "Uma User", "user"},
+}
+
+// SampleProject is a seeded project with a square boundary.
+type SampleProject struct {
+	ID                   uuid.UUID
+	Name, Type, Location string
+	Tags                 []string
+	MinLon, MinLat, Size float64
+}
+
+// Projects are the seeded projects. Their fixed ids keep reruns idempotent;
+// each gets a square boundary Size degrees across, owned by the partner.
+var Projects = []SampleProject{
+	{uuid.MustParse("5eed0000-0000-4000-8000-000000000001"), "Mau Forest Restoration", "Reforestation", "Kenya", []string{"forest", "africa"}, 35.80, -0.60, 0.05},
+	{uuid.MustParse("5eed0000-0000-4000-8000-000000000002"), "Tambopata Agroforestry", "Agroforestry", "Peru", []string{"agroforestry", "amazon"}, -69.40, -12.90, 0.04},
+	{uuid.MustParse("5eed0000-0000-4000-8000-000000000003"), "Sumatra Mangroves", "Blue Carbon", "Indonesia", []string{"mangrove", "coastal"}, 103.80, 1.00, 0.03},
+}
+
+// Result counts the rows a run inserted.
+type Result struct {
+	UsersCreated      int `json:"users_created"`
+	ProjectsCreated   int `json:"projects_created"`
+	GeometriesCreated int `json:"geometries_created"`
+}
+
+// Run seeds db in one transaction unless mode is production.
+func Run(ctx context.Context, db *gorm.DB, mode string) (*Result, error) {
+	if strings.EqualFold(strings.TrimSpace(mode), "production") {
+		return nil, ErrProduction
+	}
+
+	result := &Result{}
+	err := postgis.WithTransaction(ctx, db, func(tx *gorm.DB) error {
+		var ownerID *uuid.UUID
+		for _, u := range Users {
+			created, id, err := ensureUser(tx, u)
+			if err != nil {
+				return fmt.Errorf("seed user %s: %w", u.Email, err)
+			}
+			if created {
+				result.UsersCreated++
+			}
+			if u.Role == "partner" {
+				if parsed, err := uuid.Parse(id); err == nil {
+					ownerID = &parsed
+				}
+			}
+		}
+
+		geo := geospatial.NewRepository(tx)
+		for _, p := range Projects {
+			created, err := ensureProject(tx, p, ownerID)
+			if err != nil {
+				return fmt.Errorf("seed project %s: %w", p.Name, err)
+			}
+			if created {
+				result.ProjectsCreated++
+			}
+
+			var exists bool
+			if err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM project_geometries WHERE project_id = ?)`, p.ID).Scan(&exists).Error; err != nil {
+				return fmt.Errorf("seed geometry %s: %w", p.Name, err)
+			}
+			if exists {
+				continue
+			}
+			if _, err := geo.UpsertProjectGeometry(ctx, p.ID, geospatial.UploadGeometryRequest{GeoJSON: p.boundary(), SourceType: "seed"}); err != nil {
+				return fmt.Errorf("seed geometry %s: %w", p.Name, err)
+			}
+			result.GeometriesCreated++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("development data seeded",
+		"users_created", result.UsersCreated, "projects_created", result.ProjectsCreated, "geometries_created", result.GeometriesCreated)
+	return result, nil
+}
+
+func ensureUser(tx *gorm.DB, u Account) (bool, string, error) {
+	var existing auth.User
+	err := tx.Where("email = ?", u.Email).First(&existing).Error
+	if err == nil {
+		return false, existing.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, "", err
+	}
+
+	hash, err := utils.HashPassword(Password)
+	if err != nil {
+		return false, "", err
+	}
+	username := u.Username
+	user := &auth.User{
+		Email:         u.Email,
+		Username:      &username,
+		PasswordHash:  hash,
+		FullName:      u.FullName,
+		Role:          u.Role,
+		EmailVerified: true,
+		IsActive:      true,
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return false, "", err
+	}
+	return true, user.ID, nil
+}
+
+func ensureProject(tx *gorm.DB, p SampleProject, ownerID *uuid.UUID) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&project.Project{}).Where("id = ?", p.ID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	record := &project.Project{
+		ID:        p.ID,
+		Name:      p.Name,
+		Type:      p.Type,
+		Location:  p.Location,
+		Area:      p.areaHectares(),
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Status:    "active",
+		Tags:      p.Tags,
+		OwnerID:   ownerID,
+	}
+	return true, tx.Create(record).Error
+}
+
+func (p SampleProject) boundary() json.RawMessage {
+	minLon, minLat, maxLon, maxLat := p.MinLon, p.MinLat, p.MinLon+p.Size, p.MinLat+p.Size
+	raw, _ := json.Marshal(map[string]interface{}{
+		"type":        "Polygon",
+		"coordinates": [][][2]float64{{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}},
+	})
+	return raw
+}
+
+// areaHectares roughly sizes the square for the project record; the stored
+// boundary carries the exact PostGIS area.
+func (p SampleProject) areaHectares() float64 {
+	const metersPerDegree = 111_320.0
+	side := p.Size * metersPerDegree
+	return side * side / 10_000
+}
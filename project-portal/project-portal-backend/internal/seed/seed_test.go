@@ -0,0 +1,16 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRun_RefusesProduction(t *testing.T) {
+	for _, mode := range []string{"production", " Production "} {
+		// No database is needed: the guard runs before any query.
+		if _, err := Run(context.Background(), nil, mode); !errors.Is(err, ErrProduction) {
+			t.Errorf("mode %q: expected ErrProduction, got %v", mode, err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package apierror translates service errors into the API's error envelope,
+// {"error": "..."}, so a lookup that finds nothing is a 404 in every handler
+// and only real failures are 500s.
+package apierror
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound marks a lookup that found nothing outside the database, for
+// services that do not return a gorm or database/sql error.
+var ErrNotFound = errors.New("not found")
+
+// IsNotFound reports whether err, or any error it wraps, means the
+// requested record does not exist: gorm.ErrRecordNotFound from First,
+// sql.ErrNoRows from a raw row scan, or ErrNotFound.
+func IsNotFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrNotFound)
+}
+
+// Respond writes err for a request about a single resource, e.g. "project":
+// 404 "project not found" when it does not exist, otherwise a 500 whose
+// cause is logged rather than sent to the client.
+func Respond(c *gin.Context, err error, resource string) {
+	if IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+		return
+	}
+	logging.FromContext(c.Request.Context()).Error("loading "+resource+" failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load " + resource})
+}
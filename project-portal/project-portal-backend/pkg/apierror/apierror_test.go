@@ -0,0 +1,42 @@
+package apierror
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestRespond_NotFoundIs404AndOtherErrors500(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tc := range []struct {
+		err     error
+		code    int
+		message string
+	}{
+		{gorm.ErrRecordNotFound, http.StatusNotFound, "project not found"},
+		{fmt.Errorf("document not found: %w", gorm.ErrRecordNotFound), http.StatusNotFound, "project not found"},
+		{sql.ErrNoRows, http.StatusNotFound, "project not found"},
+		{ErrNotFound, http.StatusNotFound, "project not found"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "failed to load project"},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		Respond(c, tc.err, "project")
+
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%v: decode: %v", tc.err, err)
+		}
+		if w.Code != tc.code || body["error"] != tc.message {
+			t.Errorf("%v: got %d %q, want %d %q", tc.err, w.Code, body["error"], tc.code, tc.message)
+		}
+	}
+}
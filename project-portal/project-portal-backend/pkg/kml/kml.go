@@ -0,0 +1,244 @@
+// Package kml reads polygon Placemarks from KML documents and writes project
+// boundaries back out as KML, for Google Earth and similar tools. KML
+// coordinates are always WGS 84 longitude/latitude.
+package kml
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the registered media type of KML documents.
+const ContentType = "application/vnd.google-earth.kml+xml"
+
+// Placemark is a KML Placemark read by Decode. Geometry is its polygons as a
+// GeoJSON Polygon or MultiPolygon, or nil when the Placemark has none. Data
+// holds its ExtendedData values, both Data and SchemaData/SimpleData.
+type Placemark struct {
+	Name     string
+	Data     map[string]string
+	Geometry json.RawMessage
+}
+
+type placemark struct {
+	Name         string `xml:"name"`
+	ExtendedData struct {
+		Data []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value"`
+		} `xml:"Data"`
+		SchemaData []struct {
+			SimpleData []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"SimpleData"`
+		} `xml:"SchemaData"`
+	} `xml:"ExtendedData"`
+	Polygon       *polygon       `xml:"Polygon"`
+	MultiGeometry *multiGeometry `xml:"MultiGeometry"`
+}
+
+type polygon struct {
+	Outer string   `xml:"outerBoundaryIs>LinearRing>coordinates"`
+	Inner []string `xml:"innerBoundaryIs>LinearRing>coordinates"`
+}
+
+type multiGeometry struct {
+	Polygons []polygon       `xml:"Polygon"`
+	Nested   []multiGeometry `xml:"MultiGeometry"`
+}
+
+// Decode reads every Placemark of a KML document, however deeply it is nested
+// in Documents and Folders. Altitudes are dropped.
+func Decode(r io.Reader) ([]Placemark, error) {
+	dec := xml.NewDecoder(r)
+	var out []Placemark
+	sawRoot := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid kml: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !sawRoot {
+			if start.Name.Local != "kml" {
+				return nil, fmt.Errorf("invalid kml: root element is <%s>, want <kml>", start.Name.Local)
+			}
+			sawRoot = true
+			continue
+		}
+		if start.Name.Local != "Placemark" {
+			continue
+		}
+		var pm placemark
+		if err := dec.DecodeElement(&pm, &start); err != nil {
+			return nil, fmt.Errorf("invalid kml placemark %d: %w", len(out)+1, err)
+		}
+		p, err := pm.convert()
+		if err != nil {
+			return nil, fmt.Errorf("placemark %d: %w", len(out)+1, err)
+		}
+		out = append(out, p)
+	}
+	if !sawRoot {
+		return nil, errors.New("invalid kml: document is empty")
+	}
+	return out, nil
+}
+
+func (pm placemark) convert() (Placemark, error) {
+	p := Placemark{Name: strings.TrimSpace(pm.Name), Data: map[string]string{}}
+	for _, d := range pm.ExtendedData.Data {
+		p.Data[d.Name] = strings.TrimSpace(d.Value)
+	}
+	for _, sd := range pm.ExtendedData.SchemaData {
+		for _, d := range sd.SimpleData {
+			p.Data[d.Name] = strings.TrimSpace(d.Value)
+		}
+	}
+
+	var polygons []polygon
+	if pm.Polygon != nil {
+		polygons = append(polygons, *pm.Polygon)
+	}
+	if pm.MultiGeometry != nil {
+		polygons = append(polygons, pm.MultiGeometry.polygons()...)
+	}
+	if len(polygons) == 0 {
+		return p, nil
+	}
+
+	coords := make([][][][2]float64, 0, len(polygons))
+	for _, poly := range polygons {
+		rings, err := poly.rings()
+		if err != nil {
+			return p, err
+		}
+		coords = append(coords, rings)
+	}
+	var geometry interface{}
+	if len(coords) == 1 {
+		geometry = map[string]interface{}{"type": "Polygon", "coordinates": coords[0]}
+	} else {
+		geometry = map[string]interface{}{"type": "MultiPolygon", "coordinates": coords}
+	}
+	raw, err := json.Marshal(geometry)
+	if err != nil {
+		return p, err
+	}
+	p.Geometry = raw
+	return p, nil
+}
+
+func (m multiGeometry) polygons() []polygon {
+	out := append([]polygon(nil), m.Polygons...)
+	for _, nested := range m.Nested {
+		out = append(out, nested.polygons()...)
+	}
+	return out
+}
+
+func (p polygon) rings() ([][][2]float64, error) {
+	outer, err := parseRing(p.Outer)
+	if err != nil {
+		return nil, fmt.Errorf("outer boundary: %w", err)
+	}
+	rings := [][][2]float64{outer}
+	for i, raw := range p.Inner {
+		inner, err := parseRing(raw)
+		if err != nil {
+			return nil, fmt.Errorf("inner boundary %d: %w", i+1, err)
+		}
+		rings = append(rings, inner)
+	}
+	return rings, nil
+}
+
+// parseRing reads a KML coordinates string of lon,lat[,alt] tuples separated
+// by whitespace. The altitude is dropped and an unclosed ring is closed.
+func parseRing(raw string) ([][2]float64, error) {
+	tuples := strings.Fields(raw)
+	if len(tuples) == 0 {
+		return nil, errors.New("linear ring has no coordinates")
+	}
+	ring := make([][2]float64, 0, len(tuples)+1)
+	for _, tuple := range tuples {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("coordinate %q: want lon,lat[,alt]", tuple)
+		}
+		lon, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("coordinate %q: %w", tuple, err)
+		}
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("coordinate %q: %w", tuple, err)
+		}
+		ring = append(ring, [2]float64{lon, lat})
+	}
+	if ring[0] != ring[len(ring)-1] {
+		ring = append(ring, ring[0])
+	}
+	return ring, nil
+}
+
+// Feature is a Placemark to Encode. Geometry is a KML geometry element such
+// as the output of PostGIS ST_AsKML, written as is.
+type Feature struct {
+	Name     string
+	Data     map[string]string
+	Geometry string
+}
+
+// Encode writes features as a KML 2.2 document.
+func Encode(w io.Writer, features []Feature) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2"><Document>`)
+	for _, f := range features {
+		b.WriteString("<Placemark>")
+		if f.Name != "" {
+			b.WriteString("<name>")
+			escape(&b, f.Name)
+			b.WriteString("</name>")
+		}
+		if len(f.Data) > 0 {
+			keys := make([]string, 0, len(f.Data))
+			for k := range f.Data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			b.WriteString("<ExtendedData>")
+			for _, k := range keys {
+				b.WriteString(`<Data name="`)
+				escape(&b, k)
+				b.WriteString(`"><value>`)
+				escape(&b, f.Data[k])
+				b.WriteString("</value></Data>")
+			}
+			b.WriteString("</ExtendedData>")
+		}
+		b.WriteString(f.Geometry)
+		b.WriteString("</Placemark>")
+	}
+	b.WriteString("</Document></kml>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escape(b *strings.Builder, s string) {
+	_ = xml.EscapeText(b, []byte(s))
+}
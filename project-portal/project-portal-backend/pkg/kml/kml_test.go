@@ -0,0 +1,121 @@
+package kml
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const googleEarthDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+  <Folder>
+    <name>Field survey</name>
+    <Placemark>
+      <name>North block</name>
+      <ExtendedData><Data name="project_id"><value>6f1c2a7e-0000-4000-8000-000000000001</value></Data></ExtendedData>
+      <Polygon>
+        <altitudeMode>clampToGround</altitudeMode>
+        <outerBoundaryIs><LinearRing><coordinates>
+          36.80,-1.30,1650 36.81,-1.30,1652 36.81,-1.31,1649 36.80,-1.31,1651 36.80,-1.30,1650
+        </coordinates></LinearRing></outerBoundaryIs>
+        <innerBoundaryIs><LinearRing><coordinates>36.803,-1.303 36.806,-1.303 36.806,-1.306</coordinates></LinearRing></innerBoundaryIs>
+      </Polygon>
+    </Placemark>
+    <Placemark>
+      <name>Islands</name>
+      <ExtendedData><SchemaData schemaUrl="#s"><SimpleData name="project_id">6f1c2a7e-0000-4000-8000-000000000002</SimpleData></SchemaData></ExtendedData>
+      <MultiGeometry>
+        <Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 1,0 1,1 0,0</coordinates></LinearRing></outerBoundaryIs></Polygon>
+        <MultiGeometry>
+          <Polygon><outerBoundaryIs><LinearRing><coordinates>2,2 3,2 3,3 2,2</coordinates></LinearRing></outerBoundaryIs></Polygon>
+        </MultiGeometry>
+      </MultiGeometry>
+    </Placemark>
+    <Placemark><name>Camp</name><Point><coordinates>36.8,-1.3,0</coordinates></Point></Placemark>
+  </Folder>
+</Document>
+</kml>`
+
+func TestDecode(t *testing.T) {
+	placemarks, err := Decode(strings.NewReader(googleEarthDoc))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(placemarks) != 3 {
+		t.Fatalf("expected 3 placemarks, got %d", len(placemarks))
+	}
+
+	north := placemarks[0]
+	if north.Name != "North block" || north.Data["project_id"] != "6f1c2a7e-0000-4000-8000-000000000001" {
+		t.Errorf("unexpected name or data: %+v", north)
+	}
+	var poly struct {
+		Type        string         `json:"type"`
+		Coordinates [][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(north.Geometry, &poly); err != nil {
+		t.Fatalf("decode polygon: %v", err)
+	}
+	if poly.Type != "Polygon" || len(poly.Coordinates) != 2 {
+		t.Fatalf("expected a polygon with one hole, got %s", north.Geometry)
+	}
+	if poly.Coordinates[0][1] != [2]float64{36.81, -1.30} {
+		t.Errorf("expected altitude to be dropped, got %v", poly.Coordinates[0][1])
+	}
+	if hole := poly.Coordinates[1]; len(hole) != 4 || hole[0] != hole[3] {
+		t.Errorf("expected the unclosed hole to be closed, got %v", hole)
+	}
+
+	islands := placemarks[1]
+	if islands.Data["project_id"] != "6f1c2a7e-0000-4000-8000-000000000002" {
+		t.Errorf("expected SimpleData to be read, got %v", islands.Data)
+	}
+	var multi struct {
+		Type        string           `json:"type"`
+		Coordinates [][][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(islands.Geometry, &multi); err != nil || multi.Type != "MultiPolygon" || len(multi.Coordinates) != 2 {
+		t.Errorf("expected a two-part MultiPolygon, got %s (%v)", islands.Geometry, err)
+	}
+
+	if placemarks[2].Geometry != nil {
+		t.Errorf("expected a point placemark to have no polygon geometry, got %s", placemarks[2].Geometry)
+	}
+}
+
+func TestDecode_Rejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"not kml":       `<gpx><trk/></gpx>`,
+		"empty":         ``,
+		"bad tuple":     `<kml><Placemark><Polygon><outerBoundaryIs><LinearRing><coordinates>1;2 3;4</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark></kml>`,
+		"no coordinate": `<kml><Placemark><Polygon><outerBoundaryIs><LinearRing><coordinates> </coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark></kml>`,
+	} {
+		if _, err := Decode(strings.NewReader(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, []Feature{{
+		Name:     "Mangroves & co",
+		Data:     map[string]string{"project_id": "abc", "area_hectares": "12.5"},
+		Geometry: `<Polygon><outerBoundaryIs><LinearRing><coordinates>0,0 1,0 1,1 0,0</coordinates></LinearRing></outerBoundaryIs></Polygon>`,
+	}})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	placemarks, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(placemarks) != 1 || placemarks[0].Name != "Mangroves & co" || placemarks[0].Data["area_hectares"] != "12.5" {
+		t.Fatalf("unexpected round trip: %+v", placemarks)
+	}
+	if string(placemarks[0].Geometry) != `{"coordinates":[[[0,0],[1,0],[1,1],[0,0]]],"type":"Polygon"}` {
+		t.Errorf("unexpected geometry %s", placemarks[0].Geometry)
+	}
+}
@@ -0,0 +1,80 @@
+// Package lifecycle coordinates the background workers of a process, so they
+// start with it and stop together when it shuts down.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+)
+
+// Group runs named workers on a shared context. Shutdown cancels that context
+// and waits for every worker to return.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]int
+}
+
+// NewGroup returns a Group whose workers run until Shutdown or until parent
+// is done.
+func NewGroup(parent context.Context) *Group {
+	ctx, cancel := context.WithCancel(parent)
+	return &Group{ctx: ctx, cancel: cancel, running: map[string]int{}}
+}
+
+// Go starts worker in its own goroutine. worker must return once ctx is
+// done; its logger is tagged with name.
+func (g *Group) Go(name string, worker func(ctx context.Context)) {
+	g.mu.Lock()
+	g.running[name]++
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			g.mu.Lock()
+			if g.running[name]--; g.running[name] == 0 {
+				delete(g.running, name)
+			}
+			g.mu.Unlock()
+		}()
+		worker(logging.With(g.ctx, "worker", name))
+	}()
+}
+
+// Shutdown cancels the workers' context and waits for them to return. If ctx
+// ends first, it returns an error naming the workers still running.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.cancel()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers still running: %s: %w", strings.Join(g.stragglers(), ", "), ctx.Err())
+	}
+}
+
+func (g *Group) stragglers() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	names := make([]string, 0, len(g.running))
+	for name := range g.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
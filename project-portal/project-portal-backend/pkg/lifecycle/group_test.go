@@ -0,0 +1,50 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_ShutdownCancelsEveryWorker(t *testing.T) {
+	group := NewGroup(context.Background())
+	var stopped atomic.Int32
+	started := make(chan struct{}, 3)
+	for _, name := range []string{"token-cleanup", "change-feed", "change-stream"} {
+		group.Go(name, func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+			stopped.Add(1)
+		})
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := group.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := stopped.Load(); got != 3 {
+		t.Errorf("expected all 3 workers to observe cancellation, %d did", got)
+	}
+}
+
+func TestGroup_ShutdownTimeoutNamesStragglers(t *testing.T) {
+	group := NewGroup(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	group.Go("prompt", func(ctx context.Context) { <-ctx.Done() })
+	group.Go("stuck", func(ctx context.Context) { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := group.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stuck") || strings.Contains(err.Error(), "prompt") {
+		t.Errorf("expected a deadline error naming only the stuck worker, got %v", err)
+	}
+}
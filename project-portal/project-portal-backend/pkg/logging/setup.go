@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options selects the handler built by New.
+type Options struct {
+	Format string // "json" or "console" (text key=value); empty means console
+	Level  string // debug, info, warn or error; empty means info
+	Output string // "stdout", "stderr" or a file path; empty means stderr
+}
+
+// New builds a logger from opts. The returned closer releases the output
+// file, if one was opened, and is a no-op otherwise.
+func New(opts Options) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		out    io.Writer
+		closer io.Closer = nopCloser{}
+	)
+	switch strings.ToLower(strings.TrimSpace(opts.Output)) {
+	case "", "stderr":
+		out = os.Stderr
+	case "stdout":
+		out = os.Stdout
+	default:
+		f, err := os.OpenFile(opts.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log output %q: %w", opts.Output, err)
+		}
+		out, closer = f, f
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", "console", "text":
+		return slog.New(slog.NewTextHandler(out, handlerOpts)), closer, nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(out, handlerOpts)), closer, nil
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("unknown log format %q (want json or console)", opts.Format)
+	}
+}
+
+// ParseLevel maps a level name to a slog level. An empty name is info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", name)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_Levels(t *testing.T) {
+	cases := []struct {
+		opts    Options
+		enabled slog.Level
+		below   slog.Level
+	}{
+		{Options{}, slog.LevelInfo, slog.LevelDebug},
+		{Options{Format: "json", Level: "info", Output: "stdout"}, slog.LevelInfo, slog.LevelDebug},
+		{Options{Format: "console", Level: "debug"}, slog.LevelDebug, slog.LevelDebug - 4},
+		{Options{Format: "json", Level: "WARN", Output: "stderr"}, slog.LevelWarn, slog.LevelInfo},
+		{Options{Level: "error"}, slog.LevelError, slog.LevelWarn},
+	}
+	for _, tc := range cases {
+		logger, closer, err := New(tc.opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", tc.opts, err)
+		}
+		closer.Close()
+		ctx := context.Background()
+		if !logger.Enabled(ctx, tc.enabled) || logger.Enabled(ctx, tc.below) {
+			t.Errorf("%+v: expected level %v to be the threshold", tc.opts, tc.enabled)
+		}
+	}
+}
+
+func TestNew_JSONToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.log")
+	logger, closer, err := New(Options{Format: "json", Level: "info", Output: path})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	logger.Debug("dropped")
+	logger.Info("kept", "k", "v")
+	closer.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one line at info, got %q", data)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil || entry["msg"] != "kept" || entry["k"] != "v" {
+		t.Errorf("expected a JSON record, got %q (%v)", lines[0], err)
+	}
+}
+
+func TestNew_RejectsUnknownSettings(t *testing.T) {
+	for _, opts := range []Options{{Format: "xml"}, {Level: "verbose"}} {
+		if _, _, err := New(opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
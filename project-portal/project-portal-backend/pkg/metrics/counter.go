@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CounterVec is a monotonically increasing count partitioned by label values,
+// e.g. one series per outcome.
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	values []string
+	value  float64
+}
+
+// NewCounterVec returns a counter. Every increment passes one value per label
+// name.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{name: name, help: help, labels: labels, series: map[string]*counterSeries{}}
+}
+
+// Inc adds 1 to the series of labelValues.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series of labelValues.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", c.name, len(c.labels), len(labelValues)))
+	}
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: %s cannot decrease", c.name))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{values: append([]string(nil), labelValues...)}
+		c.series[key] = s
+	}
+	s.value += v
+}
+
+// Value reports the count in the series of labelValues.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// WriteText writes every series in the text exposition format, ordered by
+// label values.
+func (c *CounterVec) WriteText(w io.Writer) error {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.series))
+	for k := range c.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	snapshot := make([]counterSeries, len(keys))
+	for i, k := range keys {
+		snapshot[i] = *c.series[k]
+	}
+	c.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, s := range snapshot {
+		if len(c.labels) == 0 {
+			fmt.Fprintf(&b, "%s %s\n", c.name, formatFloat(s.value))
+			continue
+		}
+		fmt.Fprintf(&b, "%s{%s} %s\n", c.name, labelPairs(c.labels, s.values, ""), formatFloat(s.value))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVec_WritesOneSeriesPerLabelSet(t *testing.T) {
+	c := NewCounterVec("logins_total", "Login attempts.", "outcome")
+	c.Inc("success")
+	c.Inc("failure")
+	c.Add(2, "failure")
+
+	reg := NewRegistry()
+	reg.Register(c, NewHistogramVec("empty_bytes", "Nothing yet.", []float64{1}, "route"))
+	w := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	want := strings.Join([]string{
+		"# HELP logins_total Login attempts.",
+		"# TYPE logins_total counter",
+		`logins_total{outcome="failure"} 3`,
+		`logins_total{outcome="success"} 1`,
+		"# HELP empty_bytes Nothing yet.",
+		"# TYPE empty_bytes histogram",
+	}, "\n") + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+	if c.Value("failure") != 3 || c.Value("locked") != 0 {
+		t.Errorf("unexpected values %v, %v", c.Value("failure"), c.Value("locked"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a negative increment to panic")
+		}
+	}()
+	c.Add(-1, "success")
+}
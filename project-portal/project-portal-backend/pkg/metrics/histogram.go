@@ -0,0 +1,194 @@
+// Package metrics keeps histograms and counters in memory and writes them in
+// the Prometheus text exposition format, so /metrics can be scraped without a
+// client library. The service exports a handful of labelled counters and
+// histograms and nothing else; client_golang would bring its protobuf,
+// process collector and registry dependencies into the module for that, while
+// the text format is stable and covered by this package's tests.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Prometheus text exposition format served by Handler.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// ExponentialBuckets returns count upper bounds, the first start and each
+// next one factor times the previous.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// HistogramVec is a histogram partitioned by label values, e.g. one series
+// per route.
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	counts []uint64 // per bucket, not cumulative; the last is +Inf
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec returns a histogram with the given upper bounds, which
+// must be increasing. Every observation passes one value per label name.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, series: map[string]*series{}}
+}
+
+// Observe records v in the series of labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", h.name, len(h.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets)+1)}
+		h.series[key] = s
+	}
+	s.counts[i]++
+	s.count++
+	s.sum += v
+}
+
+// Count reports the number of observations in the series of labelValues.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// Sum reports the total of the observations in the series of labelValues.
+func (h *HistogramVec) Sum(labelValues ...string) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.sum
+	}
+	return 0
+}
+
+// WriteText writes every series in the text exposition format, ordered by
+// label values.
+func (h *HistogramVec) WriteText(w io.Writer) error {
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	snapshot := make([]series, len(keys))
+	for i, k := range keys {
+		s := h.series[k]
+		snapshot[i] = series{values: s.values, counts: append([]uint64(nil), s.counts...), count: s.count, sum: s.sum}
+	}
+	h.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, s := range snapshot {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(&b, "%s_bucket{%s} %d\n", h.name, h.labelPairs(s.values, formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket{%s} %d\n", h.name, h.labelPairs(s.values, "+Inf"), s.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", h.name, h.labelPairs(s.values, ""), formatFloat(s.sum))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", h.name, h.labelPairs(s.values, ""), s.count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelPairs renders the series labels, with le when it is not empty.
+func (h *HistogramVec) labelPairs(values []string, le string) string {
+	return labelPairs(h.labels, values, le)
+}
+
+func labelPairs(names, values []string, le string) string {
+	pairs := make([]string, 0, len(values)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Collector is a metric family that can write itself in the text exposition
+// format; HistogramVec and CounterVec are collectors.
+type Collector interface {
+	WriteText(w io.Writer) error
+}
+
+// Registry is the set of metrics served on /metrics.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds collectors to the registry.
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Handler serves every registered collector.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		r.mu.Lock()
+		collectors := append([]Collector(nil), r.collectors...)
+		r.mu.Unlock()
+
+		w.Header().Set("Content-Type", ContentType)
+		for _, c := range collectors {
+			if err := c.WriteText(w); err != nil {
+				return
+			}
+		}
+	})
+}
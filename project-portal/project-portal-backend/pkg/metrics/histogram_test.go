@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHistogramVec_WritesCumulativeBuckets(t *testing.T) {
+	h := NewHistogramVec("payload_bytes", "Payload sizes.", []float64{100, 1000}, "route")
+	h.Observe(100, "/a") // le is inclusive
+	h.Observe(500, "/a")
+	h.Observe(5000, "/a")
+	h.Observe(1, `/b"`)
+
+	reg := NewRegistry()
+	reg.Register(h)
+	w := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	want := strings.Join([]string{
+		"# HELP payload_bytes Payload sizes.",
+		"# TYPE payload_bytes histogram",
+		`payload_bytes_bucket{route="/a",le="100"} 1`,
+		`payload_bytes_bucket{route="/a",le="1000"} 2`,
+		`payload_bytes_bucket{route="/a",le="+Inf"} 3`,
+		`payload_bytes_sum{route="/a"} 5600`,
+		`payload_bytes_count{route="/a"} 3`,
+		`payload_bytes_bucket{route="/b\"",le="100"} 1`,
+		`payload_bytes_bucket{route="/b\"",le="1000"} 1`,
+		`payload_bytes_bucket{route="/b\"",le="+Inf"} 1`,
+		`payload_bytes_sum{route="/b\""} 1`,
+		`payload_bytes_count{route="/b\""} 1`,
+	}, "\n") + "\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+	if h.Count("/a") != 3 || h.Sum("/a") != 5600 || h.Count("/c") != 0 {
+		t.Errorf("unexpected count %d or sum %v", h.Count("/a"), h.Sum("/a"))
+	}
+}
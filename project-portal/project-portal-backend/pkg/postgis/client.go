@@ -1,15 +1,115 @@
 package postgis
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Config holds connection pool and logging settings for the PostGIS database.
+type Config struct {
+	DSN                string
+	ReplicaDSN         string // optional read-only replica
+	MaxOpenConns       int
+	MaxIdleConns       int
+	ConnMaxLifetime    time.Duration
+	ConnMaxIdleTime    time.Duration
+	SlowQueryThreshold time.Duration
+	Debug              bool
+	CreatePostGIS      bool // try CREATE EXTENSION postgis when it is missing
+
+	// StatementTimeout makes Postgres cancel any statement running longer,
+	// whatever the caller's context says; 0 leaves statements unbounded.
+	StatementTimeout time.Duration
+}
 
 type Client struct {
-	db *gorm.DB
+	db      *gorm.DB
+	replica *gorm.DB
 }
 
 func NewClient(db *gorm.DB) *Client {
 	return &Client{db: db}
 }
 
+// NewClientWithReplica returns a client that routes ReadDB to replica.
+func NewClientWithReplica(db, replica *gorm.DB) *Client {
+	return &Client{db: db, replica: replica}
+}
+
+// Open connects to Postgres, applies the pool settings and verifies the
+// connection with a ping. It fails fast with ErrPostGISMissing when the
+// postgis extension is absent and cannot be created. When ReplicaDSN is set a
+// second pool is opened against the replica with the same settings.
+func Open(cfg Config) (*Client, error) {
+	level := logger.Silent
+	if cfg.Debug {
+		level = logger.Info
+	}
+	writer := log.New(os.Stdout, "\r\n", log.LstdFlags)
+	base := logger.New(writer, logger.Config{LogLevel: level, IgnoreRecordNotFoundError: true})
+	gormConfig := &gorm.Config{
+		Logger: NewSlowQueryLogger(base, writer, cfg.SlowQueryThreshold),
+	}
+
+	db, err := openPool(cfg.DSN, cfg, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err := EnsurePostGIS(db, cfg.CreatePostGIS); err != nil {
+		return nil, err
+	}
+	if cfg.ReplicaDSN == "" {
+		return NewClient(db), nil
+	}
+
+	replica, err := openPool(cfg.ReplicaDSN, cfg, gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("replica: %w", err)
+	}
+	return NewClientWithReplica(db, replica), nil
+}
+
+func openPool(dsn string, cfg Config, gormConfig *gorm.Config) (*gorm.DB, error) {
+	dsn, err := withStatementTimeout(dsn, cfg.StatementTimeout)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying DB: %w", err)
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("database ping failed: %w", err)
+	}
+	return db, nil
+}
+
 func (c *Client) DB() *gorm.DB {
 	return c.db
 }
+
+// ReadDB returns the replica handle for read-only queries, falling back to
+// the primary when no replica is configured.
+func (c *Client) ReadDB() *gorm.DB {
+	if c.replica != nil {
+		return c.replica
+	}
+	return c.db
+}
@@ -0,0 +1,56 @@
+package postgis
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrPostGISMissing is returned at startup when the postgis extension is not
+// installed and could not (or may not) be created.
+var ErrPostGISMissing = errors.New("postgis extension is not installed in the database")
+
+// extensionStore is the part of the database EnsurePostGIS talks to.
+type extensionStore interface {
+	ExtensionInstalled(name string) (bool, error)
+	CreateExtension(name string) error
+}
+
+type gormExtensions struct {
+	db *gorm.DB
+}
+
+func (g gormExtensions) ExtensionInstalled(name string) (bool, error) {
+	var installed bool
+	err := g.db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = ?)", name).Row().Scan(&installed)
+	return installed, err
+}
+
+func (g gormExtensions) CreateExtension(name string) error {
+	return g.db.Exec("CREATE EXTENSION IF NOT EXISTS " + name).Error
+}
+
+// EnsurePostGIS verifies the postgis extension is installed. With create set
+// it tries CREATE EXTENSION first, which needs superuser or the CREATE
+// privilege on the database.
+func EnsurePostGIS(db *gorm.DB, create bool) error {
+	return ensureExtension(gormExtensions{db: db}, "postgis", create)
+}
+
+func ensureExtension(store extensionStore, name string, create bool) error {
+	installed, err := store.ExtensionInstalled(name)
+	if err != nil {
+		return fmt.Errorf("checking for the %s extension: %w", name, err)
+	}
+	if installed {
+		return nil
+	}
+	if !create {
+		return fmt.Errorf("%w; run CREATE EXTENSION %s as a privileged user or set DATABASE_CREATE_POSTGIS=true", ErrPostGISMissing, name)
+	}
+	if err := store.CreateExtension(name); err != nil {
+		return fmt.Errorf("%w and creating it failed (needs superuser or CREATE on the database): %v", ErrPostGISMissing, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package postgis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeExtensions struct {
+	installed map[string]bool
+	createErr error
+	created   []string
+}
+
+func (f *fakeExtensions) ExtensionInstalled(name string) (bool, error) {
+	return f.installed[name], nil
+}
+
+func (f *fakeExtensions) CreateExtension(name string) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	f.created = append(f.created, name)
+	f.installed[name] = true
+	return nil
+}
+
+func TestEnsureExtension_MissingFailsFastWithClearError(t *testing.T) {
+	store := &fakeExtensions{installed: map[string]bool{}}
+
+	err := ensureExtension(store, "postgis", false)
+	if !errors.Is(err, ErrPostGISMissing) {
+		t.Fatalf("expected ErrPostGISMissing, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "CREATE EXTENSION postgis") {
+		t.Errorf("expected the error to say how to fix it, got %q", err)
+	}
+	if len(store.created) != 0 {
+		t.Error("must not create the extension when creation is disabled")
+	}
+}
+
+func TestEnsureExtension_CreatesWhenAllowed(t *testing.T) {
+	store := &fakeExtensions{installed: map[string]bool{}}
+	if err := ensureExtension(store, "postgis", true); err != nil {
+		t.Fatalf("ensureExtension: %v", err)
+	}
+	if len(store.created) != 1 {
+		t.Fatalf("expected the extension to be created once, got %v", store.created)
+	}
+}
+
+func TestEnsureExtension_CreateWithoutPrivilege(t *testing.T) {
+	store := &fakeExtensions{installed: map[string]bool{}, createErr: errors.New("permission denied to create extension \"postgis\"")}
+
+	err := ensureExtension(store, "postgis", true)
+	if !errors.Is(err, ErrPostGISMissing) || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected ErrPostGISMissing wrapping the privilege error, got %v", err)
+	}
+}
+
+func TestEnsureExtension_AlreadyInstalled(t *testing.T) {
+	store := &fakeExtensions{installed: map[string]bool{"postgis": true}}
+	if err := ensureExtension(store, "postgis", false); err != nil {
+		t.Fatalf("expected no error when installed, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package postgis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Health pings the primary and, when configured, the read replica.
+func (c *Client) Health(ctx context.Context) error {
+	if err := ping(ctx, c.db); err != nil {
+		return err
+	}
+	if c.replica != nil {
+		if err := ping(ctx, c.replica); err != nil {
+			return fmt.Errorf("replica: %w", err)
+		}
+	}
+	return nil
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// HealthCache reuses the result of a health check for TTL, so frequent probes
+// cost at most one database round trip per TTL. Concurrent callers arriving
+// while a check runs wait for it instead of starting their own.
+type HealthCache struct {
+	check func(context.Context) error
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	err       error
+	checkedAt time.Time
+	inflight  chan struct{}
+}
+
+// NewHealthCache caches check for ttl; a ttl of 0 checks on every call.
+func NewHealthCache(check func(context.Context) error, ttl time.Duration) *HealthCache {
+	return &HealthCache{check: check, ttl: ttl, now: time.Now}
+}
+
+// Check returns the last result if it is younger than the TTL and runs the
+// check otherwise, along with when that result was taken.
+func (h *HealthCache) Check(ctx context.Context) (time.Time, error) {
+	h.mu.Lock()
+	if !h.checkedAt.IsZero() && h.now().Sub(h.checkedAt) < h.ttl {
+		defer h.mu.Unlock()
+		return h.checkedAt, h.err
+	}
+	if wait := h.inflight; wait != nil {
+		h.mu.Unlock()
+		select {
+		case <-wait:
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			return h.checkedAt, h.err
+		case <-ctx.Done():
+			return h.now(), ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	h.inflight = done
+	h.mu.Unlock()
+
+	err := h.check(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.err, h.checkedAt, h.inflight = err, h.now(), nil
+	close(done)
+	return h.checkedAt, h.err
+}
@@ -0,0 +1,66 @@
+package postgis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCache_ReusesResultWithinTTL(t *testing.T) {
+	var pings atomic.Int32
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewHealthCache(func(context.Context) error { pings.Add(1); return nil }, 2*time.Second)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		if _, err := cache.Check(context.Background()); err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+	}
+	if got := pings.Load(); got != 1 {
+		t.Fatalf("expected one ping within the TTL, got %d", got)
+	}
+
+	now = now.Add(2 * time.Second)
+	_, _ = cache.Check(context.Background())
+	if got := pings.Load(); got != 2 {
+		t.Errorf("expected a fresh ping once the TTL passed, got %d pings", got)
+	}
+}
+
+func TestHealthCache_ConcurrentChecksShareOnePing(t *testing.T) {
+	var pings atomic.Int32
+	release := make(chan struct{})
+	cache := NewHealthCache(func(context.Context) error {
+		pings.Add(1)
+		<-release
+		return errors.New("connection refused")
+	}, time.Minute)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Check(context.Background())
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	if got := pings.Load(); got != 1 {
+		t.Errorf("expected concurrent checks to share one ping, got %d", got)
+	}
+	for err := range errs {
+		if err == nil || err.Error() != "connection refused" {
+			t.Errorf("expected every caller to see the outage, got %v", err)
+		}
+	}
+}
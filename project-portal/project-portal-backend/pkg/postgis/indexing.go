@@ -1,7 +1,94 @@
 package postgis
 
-import "gorm.io/gorm"
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+)
 
 func EnsureIndex(db *gorm.DB, sql string) error {
 	return db.Exec(sql).Error
 }
+
+// IndexExists reports whether an index named index is defined on table.
+func IndexExists(db *gorm.DB, table, index string) (bool, error) {
+	var exists bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = ? AND indexname = ?)", table, index).Row().Scan(&exists)
+	return exists, err
+}
+
+// ExplainJSON returns the planner output of EXPLAIN (FORMAT JSON) for query.
+// The query is planned, not executed.
+func ExplainJSON(db *gorm.DB, query string, args ...interface{}) (json.RawMessage, error) {
+	var plan string
+	if err := db.Raw("EXPLAIN (FORMAT JSON) "+query, args...).Row().Scan(&plan); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(plan), nil
+}
+
+// PlanSummary is the part of a query plan the index diagnostics look at.
+type PlanSummary struct {
+	NodeTypes []string `json:"node_types"`
+	// SeqScans lists the relations read by a sequential scan.
+	SeqScans []string `json:"seq_scans"`
+	// Indexes lists the indexes any node reads.
+	Indexes []string `json:"indexes"`
+}
+
+// SeqScanOn reports whether relation is read by a sequential scan.
+func (p *PlanSummary) SeqScanOn(relation string) bool {
+	for _, r := range p.SeqScans {
+		if r == relation {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesIndex reports whether the plan reads index.
+func (p *PlanSummary) UsesIndex(index string) bool {
+	for _, i := range p.Indexes {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	IndexName    string     `json:"Index Name"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// ParsePlan walks the node tree of an EXPLAIN (FORMAT JSON) result.
+func ParsePlan(raw []byte) (*PlanSummary, error) {
+	var doc []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("decoding query plan: %w", err)
+	}
+	if len(doc) == 0 {
+		return nil, fmt.Errorf("query plan is empty")
+	}
+	summary := &PlanSummary{NodeTypes: []string{}, SeqScans: []string{}, Indexes: []string{}}
+	var walk func(n planNode)
+	walk = func(n planNode) {
+		summary.NodeTypes = append(summary.NodeTypes, n.NodeType)
+		if n.NodeType == "Seq Scan" && n.RelationName != "" {
+			summary.SeqScans = append(summary.SeqScans, n.RelationName)
+		}
+		if n.IndexName != "" {
+			summary.Indexes = append(summary.Indexes, n.IndexName)
+		}
+		for _, child := range n.Plans {
+			walk(child)
+		}
+	}
+	walk(doc[0].Plan)
+	return summary, nil
+}
@@ -0,0 +1,49 @@
+package postgis
+
+import "testing"
+
+const bitmapPlan = `[{"Plan":{"Node Type":"Limit","Plans":[{"Node Type":"Nested Loop","Plans":[
+  {"Node Type":"Bitmap Heap Scan","Relation Name":"project_geometries","Plans":[
+    {"Node Type":"Bitmap Index Scan","Index Name":"idx_project_geometries_geometry_geom"}]},
+  {"Node Type":"Index Scan","Relation Name":"projects","Index Name":"projects_pkey"}]}]}}]`
+
+const seqScanPlan = `[{"Plan":{"Node Type":"Limit","Plans":[{"Node Type":"Hash Join","Plans":[
+  {"Node Type":"Seq Scan","Relation Name":"project_geometries"},
+  {"Node Type":"Hash","Plans":[{"Node Type":"Seq Scan","Relation Name":"projects"}]}]}]}}]`
+
+func TestParsePlan_IndexScan(t *testing.T) {
+	p, err := ParsePlan([]byte(bitmapPlan))
+	if err != nil {
+		t.Fatalf("ParsePlan: %v", err)
+	}
+	if p.SeqScanOn("project_geometries") {
+		t.Error("did not expect a sequential scan on project_geometries")
+	}
+	if !p.UsesIndex("idx_project_geometries_geometry_geom") {
+		t.Errorf("expected the spatial index to be used, got %v", p.Indexes)
+	}
+	if len(p.NodeTypes) != 5 {
+		t.Errorf("expected 5 plan nodes, got %v", p.NodeTypes)
+	}
+}
+
+func TestParsePlan_SeqScan(t *testing.T) {
+	p, err := ParsePlan([]byte(seqScanPlan))
+	if err != nil {
+		t.Fatalf("ParsePlan: %v", err)
+	}
+	if !p.SeqScanOn("project_geometries") || !p.SeqScanOn("projects") {
+		t.Errorf("expected sequential scans on both tables, got %v", p.SeqScans)
+	}
+	if len(p.Indexes) != 0 {
+		t.Errorf("expected no indexes, got %v", p.Indexes)
+	}
+}
+
+func TestParsePlan_RejectsGarbage(t *testing.T) {
+	for _, raw := range []string{`not json`, `[]`} {
+		if _, err := ParsePlan([]byte(raw)); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
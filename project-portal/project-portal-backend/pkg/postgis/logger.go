@@ -0,0 +1,45 @@
+package postgis
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+var sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// SanitizeSQL masks quoted literals so slow-query logs never carry user
+// supplied values such as emails or raw GeoJSON payloads.
+func SanitizeSQL(sql string) string {
+	return sqlStringLiteral.ReplaceAllString(sql, "'?'")
+}
+
+// SlowQueryLogger wraps a GORM logger and reports any statement that runs
+// longer than Threshold at warn level, regardless of the wrapped log level.
+type SlowQueryLogger struct {
+	logger.Interface
+	Threshold time.Duration
+	writer    logger.Writer
+}
+
+// NewSlowQueryLogger decorates base with slow-query reporting to w.
+// A zero threshold disables slow-query reporting.
+func NewSlowQueryLogger(base logger.Interface, w logger.Writer, threshold time.Duration) *SlowQueryLogger {
+	return &SlowQueryLogger{Interface: base, Threshold: threshold, writer: w}
+}
+
+func (l *SlowQueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &SlowQueryLogger{Interface: l.Interface.LogMode(level), Threshold: l.Threshold, writer: l.writer}
+}
+
+func (l *SlowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Threshold > 0 {
+		if elapsed := time.Since(begin); elapsed >= l.Threshold {
+			sql, rows := fc()
+			l.writer.Printf("[warn] slow query duration=%s threshold=%s rows=%d sql=%s", elapsed, l.Threshold, rows, SanitizeSQL(sql))
+		}
+	}
+	l.Interface.Trace(ctx, begin, fc, err)
+}
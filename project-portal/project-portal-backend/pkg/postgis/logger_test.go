@@ -0,0 +1,70 @@
+package postgis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+type bufferWriter struct {
+	lines []string
+}
+
+func (w *bufferWriter) Printf(format string, args ...interface{}) {
+	w.lines = append(w.lines, fmt.Sprintf(format, args...))
+}
+
+func TestSlowQueryLogger_LogsQueriesOverThreshold(t *testing.T) {
+	w := &bufferWriter{}
+	l := NewSlowQueryLogger(logger.Discard, w, 100*time.Millisecond)
+
+	fc := func() (string, int64) {
+		return "SELECT pg_sleep(0.2) WHERE email = 'someone@example.com'", 1
+	}
+	l.Trace(context.Background(), time.Now().Add(-200*time.Millisecond), fc, nil)
+
+	if len(w.lines) != 1 {
+		t.Fatalf("expected one slow query log line, got %d", len(w.lines))
+	}
+	line := w.lines[0]
+	if !strings.Contains(line, "[warn] slow query") || !strings.Contains(line, "pg_sleep") {
+		t.Errorf("unexpected log line: %s", line)
+	}
+	if strings.Contains(line, "someone@example.com") {
+		t.Errorf("slow query log leaked a literal value: %s", line)
+	}
+}
+
+func TestSlowQueryLogger_IgnoresFastQueries(t *testing.T) {
+	w := &bufferWriter{}
+	l := NewSlowQueryLogger(logger.Discard, w, time.Second)
+
+	l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+
+	if len(w.lines) != 0 {
+		t.Errorf("expected no log lines, got %v", w.lines)
+	}
+}
+
+func TestSlowQueryLogger_LogModeKeepsThreshold(t *testing.T) {
+	w := &bufferWriter{}
+	l := NewSlowQueryLogger(logger.Discard, w, 50*time.Millisecond).LogMode(logger.Silent)
+
+	l.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) { return "SELECT 1", 0 }, nil)
+
+	if len(w.lines) != 1 {
+		t.Errorf("expected slow query to be logged after LogMode, got %d lines", len(w.lines))
+	}
+}
+
+func TestSanitizeSQL(t *testing.T) {
+	got := SanitizeSQL(`SELECT * FROM users WHERE email = 'a''b@x.io' AND id = 4`)
+	want := `SELECT * FROM users WHERE email = '?' AND id = 4`
+	if got != want {
+		t.Errorf("SanitizeSQL() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package postgis
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"carbon-scribe/project-portal/project-portal-backend/pkg/logging"
+)
+
+// Singleton keeps a background job running on at most one replica. Each
+// replica calls Run; the one that takes the job's Postgres advisory lock runs
+// the job, and the others retry every RetryInterval so one of them takes over
+// when the holder shuts down or loses its connection.
+//
+// The lock is session-level, so it is held on a connection taken out of the
+// pool for as long as the job runs.
+type Singleton struct {
+	db   *sql.DB
+	name string
+	key  int64
+
+	// RetryInterval is how often a replica without the lock tries again, and
+	// how often the holder checks that its connection is still alive.
+	RetryInterval time.Duration
+}
+
+// Singleton returns a Singleton for the job called name on the primary.
+func (c *Client) Singleton(name string) (*Singleton, error) {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return NewSingleton(sqlDB, name), nil
+}
+
+// NewSingleton returns a Singleton for the job called name. Replicas must use
+// the same name for the same job; the lock key is derived from it.
+func NewSingleton(db *sql.DB, name string) *Singleton {
+	h := fnv.New64a()
+	h.Write([]byte("singleton:" + name))
+	return &Singleton{db: db, name: name, key: int64(h.Sum64()), RetryInterval: 30 * time.Second}
+}
+
+// Lease is a held job lock.
+type Lease struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// TryAcquire takes the job's lock without waiting. It returns a nil Lease
+// when another session holds it.
+func (s *Singleton) TryAcquire(ctx context.Context) (*Lease, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("singleton %s: %w", s.name, err)
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", s.key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("singleton %s: %w", s.name, err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, nil
+	}
+	return &Lease{conn: conn, key: s.key}, nil
+}
+
+// Alive reports an error once the lease's connection, and with it the lock,
+// is gone.
+func (l *Lease) Alive(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+// Release unlocks and returns the connection to the pool.
+func (l *Lease) Release(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	if closeErr := l.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Run blocks until ctx is done, running job whenever this replica holds the
+// lock. job gets a context that is cancelled on shutdown or when the lock is
+// lost, and must return promptly once it is; the lock is released after job
+// returns.
+func (s *Singleton) Run(ctx context.Context, job func(ctx context.Context)) {
+	logger := logging.FromContext(ctx).With("job", s.name)
+	for {
+		lease, err := s.TryAcquire(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logger.Warn("singleton: acquiring lock failed", "error", err)
+		case lease != nil:
+			logger.Info("singleton: lock acquired, running job")
+			s.hold(ctx, lease, job)
+			// Release on a fresh context: ctx may be the cancelled shutdown one.
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := lease.Release(releaseCtx); err != nil {
+				logger.Warn("singleton: releasing lock failed", "error", err)
+			}
+			cancel()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.RetryInterval):
+		}
+	}
+}
+
+// hold runs job until it returns, ctx is done or the lease's connection dies.
+func (s *Singleton) hold(ctx context.Context, lease *Lease, job func(ctx context.Context)) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job(jobCtx)
+	}()
+
+	check := time.NewTicker(s.RetryInterval)
+	defer check.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-check.C:
+			if err := lease.Alive(jobCtx); err != nil && jobCtx.Err() == nil {
+				logging.FromContext(ctx).Warn("singleton: lock connection lost, stopping job", "job", s.name, "error", err)
+				cancel()
+				<-done
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package postgis
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lockServer emulates Postgres session-level advisory locks: a lock belongs
+// to the connection that took it and is dropped when that connection closes.
+type lockServer struct {
+	mu      sync.Mutex
+	holders map[int64]*lockConn
+}
+
+func (s *lockServer) Open(string) (driver.Conn, error) {
+	return &lockConn{server: s}, nil
+}
+
+type lockConn struct{ server *lockServer }
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *lockConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *lockConn) Close() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	for key, holder := range c.server.holders {
+		if holder == c {
+			delete(c.server.holders, key)
+		}
+	}
+	return nil
+}
+
+func (c *lockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "pg_try_advisory_lock") {
+		return nil, errors.New("unexpected query: " + query)
+	}
+	key := args[0].Value.(int64)
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	holder, held := c.server.holders[key]
+	if !held {
+		c.server.holders[key] = c
+	}
+	return &boolRows{value: !held || holder == c}, nil
+}
+
+func (c *lockConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.Contains(query, "pg_advisory_unlock") {
+		return nil, errors.New("unexpected statement: " + query)
+	}
+	key := args[0].Value.(int64)
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.server.holders[key] == c {
+		delete(c.server.holders, key)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type boolRows struct {
+	value bool
+	done  bool
+}
+
+func (r *boolRows) Columns() []string { return []string{"locked"} }
+func (r *boolRows) Close() error      { return nil }
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+var registerLockDriver sync.Once
+
+// openLockDB returns two pools on one lockServer, standing in for two
+// replicas sharing a database.
+func openLockDB(t *testing.T) (*sql.DB, *sql.DB) {
+	t.Helper()
+	registerLockDriver.Do(func() {
+		sql.Register("advisorylock", &lockServer{holders: map[int64]*lockConn{}})
+	})
+	a, err := sql.Open("advisorylock", "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	b, _ := sql.Open("advisorylock", "")
+	t.Cleanup(func() { a.Close(); b.Close() })
+	return a, b
+}
+
+func TestSingleton_OnlyOneInstanceAcquires(t *testing.T) {
+	dbA, dbB := openLockDB(t)
+	ctx := context.Background()
+	first, second := NewSingleton(dbA, "contended"), NewSingleton(dbB, "contended")
+
+	lease, err := first.TryAcquire(ctx)
+	if err != nil || lease == nil {
+		t.Fatalf("expected the first instance to acquire, got lease=%v err=%v", lease, err)
+	}
+	if other, err := second.TryAcquire(ctx); err != nil || other != nil {
+		t.Fatalf("expected the second instance to be refused, got lease=%v err=%v", other, err)
+	}
+	if other, err := NewSingleton(dbB, "another-job").TryAcquire(ctx); err != nil || other == nil {
+		t.Fatalf("a different job must not contend, got lease=%v err=%v", other, err)
+	} else {
+		other.Release(ctx)
+	}
+
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	taken, err := second.TryAcquire(ctx)
+	if err != nil || taken == nil {
+		t.Fatalf("expected the second instance to acquire after release, got lease=%v err=%v", taken, err)
+	}
+	taken.Release(ctx)
+}
+
+func TestSingleton_RunStartsJobOnOneInstance(t *testing.T) {
+	dbA, dbB := openLockDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var running, started atomic.Int32
+	job := func(ctx context.Context) {
+		started.Add(1)
+		running.Add(1)
+		defer running.Add(-1)
+		<-ctx.Done()
+	}
+	var wg sync.WaitGroup
+	for _, db := range []*sql.DB{dbA, dbB} {
+		s := NewSingleton(db, "run")
+		s.RetryInterval = 10 * time.Millisecond
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Run(ctx, job)
+		}()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for started.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Give the other instance several retries to (wrongly) start as well.
+	time.Sleep(100 * time.Millisecond)
+	if n := started.Load(); n != 1 {
+		t.Errorf("expected exactly one instance to run the job, %d did", n)
+	}
+
+	cancel()
+	wg.Wait()
+	if n := running.Load(); n != 0 {
+		t.Errorf("expected the job to stop on shutdown, %d still running", n)
+	}
+	lease, err := NewSingleton(dbA, "run").TryAcquire(context.Background())
+	if err != nil || lease == nil {
+		t.Fatalf("expected the lock to be released on shutdown, got lease=%v err=%v", lease, err)
+	}
+	lease.Release(context.Background())
+}
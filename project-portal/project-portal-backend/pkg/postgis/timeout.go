@@ -0,0 +1,58 @@
+package postgis
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sqlStateQueryCanceled is what Postgres reports for a statement cancelled by
+// statement_timeout (or by a cancel request).
+const sqlStateQueryCanceled = "57014"
+
+// IsStatementTimeout reports whether err is Postgres cancelling a statement,
+// as it does once statement_timeout runs out.
+func IsStatementTimeout(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == sqlStateQueryCanceled
+}
+
+// withStatementTimeout adds statement_timeout to dsn as a run-time parameter,
+// which the driver sends when each connection starts, so every session in
+// the pool has it. Both URL and keyword/value DSNs are accepted. A timeout
+// of 0 or less leaves dsn as it is.
+func withStatementTimeout(dsn string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return dsn, nil
+	}
+	ms := strconv.FormatInt(max(timeout.Milliseconds(), 1), 10)
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid database url: %w", err)
+		}
+		q := u.Query()
+		q.Set("statement_timeout", ms)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return strings.TrimSpace(dsn + " statement_timeout=" + ms), nil
+}
+
+// WithoutStatementTimeout runs fn on a single connection with
+// statement_timeout lifted, for work such as migrations that may run longer
+// than queries are allowed to. The connection's timeout is restored after.
+func WithoutStatementTimeout(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SET statement_timeout = 0").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("RESET statement_timeout")
+		return fn(conn)
+	})
+}
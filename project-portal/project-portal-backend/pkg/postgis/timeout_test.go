@@ -0,0 +1,41 @@
+package postgis
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWithStatementTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		dsn     string
+		timeout time.Duration
+		want    string
+	}{
+		{"postgres://u:p@db:5432/app?sslmode=disable", 0, "postgres://u:p@db:5432/app?sslmode=disable"},
+		{"postgres://u:p@db:5432/app?sslmode=disable", 30 * time.Second, "postgres://u:p@db:5432/app?sslmode=disable&statement_timeout=30000"},
+		{"postgresql://db/app?statement_timeout=1", 1500 * time.Millisecond, "postgresql://db/app?statement_timeout=1500"},
+		{"host=db user=u dbname=app", 2 * time.Minute, "host=db user=u dbname=app statement_timeout=120000"},
+		{"host=db", time.Microsecond, "host=db statement_timeout=1"},
+	} {
+		got, err := withStatementTimeout(tc.dsn, tc.timeout)
+		if err != nil || got != tc.want {
+			t.Errorf("withStatementTimeout(%q, %s) = %q, %v; want %q", tc.dsn, tc.timeout, got, err, tc.want)
+		}
+	}
+}
+
+// sqlStateError stands in for a driver error carrying a Postgres SQLSTATE.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "pq: " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestIsStatementTimeout(t *testing.T) {
+	if !IsStatementTimeout(fmt.Errorf("query: %w", sqlStateError("57014"))) {
+		t.Error("expected a wrapped query_canceled error to count")
+	}
+	if IsStatementTimeout(sqlStateError("40P01")) || IsStatementTimeout(fmt.Errorf("plain")) {
+		t.Error("expected other errors not to count")
+	}
+}
@@ -0,0 +1,47 @@
+package postgis
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// WithTransaction runs fn in a transaction on the primary. See the package
+// function of the same name.
+func (c *Client) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return WithTransaction(ctx, c.db, fn)
+}
+
+// WithTransaction begins a transaction on db, runs fn and commits when fn
+// returns nil. An error from fn rolls back and is returned as is; a panic in
+// fn rolls back and is re-raised. Called on a db that is already a
+// transaction, fn runs in a savepoint instead.
+func WithTransaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) (err error) {
+	db = db.WithContext(ctx)
+	if _, nested := db.Statement.ConnPool.(gorm.TxCommitter); nested {
+		return db.Transaction(fn)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return fmt.Errorf("begin transaction: %w", tx.Error)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback().Error; rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package postgis
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// fakePool is a connection pool that only records transaction boundaries.
+type fakePool struct {
+	begun, committed, rolledBack int
+}
+
+func (p *fakePool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (p *fakePool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (p *fakePool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+func (p *fakePool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *fakePool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	p.begun++
+	return &fakeTx{fakePool: p}, nil
+}
+
+type fakeTx struct{ *fakePool }
+
+func (t *fakeTx) Commit() error   { t.committed++; return nil }
+func (t *fakeTx) Rollback() error { t.rolledBack++; return nil }
+
+func newFakeClient(t *testing.T) (*Client, *fakePool) {
+	t.Helper()
+	pool := &fakePool{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	return NewClient(db), pool
+}
+
+func TestWithTransaction_CommitsOnSuccess(t *testing.T) {
+	client, pool := newFakeClient(t)
+
+	ran := false
+	err := client.WithTransaction(context.Background(), func(tx *gorm.DB) error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("expected fn to run without error, got ran=%v err=%v", ran, err)
+	}
+	if pool.begun != 1 || pool.committed != 1 || pool.rolledBack != 0 {
+		t.Errorf("expected begin+commit, got %+v", *pool)
+	}
+}
+
+func TestWithTransaction_RollsBackOnError(t *testing.T) {
+	client, pool := newFakeClient(t)
+	failure := errors.New("area computation failed")
+
+	err := client.WithTransaction(context.Background(), func(tx *gorm.DB) error { return failure })
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected fn's error back, got %v", err)
+	}
+	if pool.committed != 0 || pool.rolledBack != 1 {
+		t.Errorf("expected a rollback and no commit, got %+v", *pool)
+	}
+}
+
+func TestWithTransaction_RollsBackAndRepanics(t *testing.T) {
+	client, pool := newFakeClient(t)
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Fatalf("expected the panic to propagate, got %v", p)
+		}
+		if pool.committed != 0 || pool.rolledBack != 1 {
+			t.Errorf("expected a rollback and no commit, got %+v", *pool)
+		}
+	}()
+	_ = client.WithTransaction(context.Background(), func(tx *gorm.DB) error { panic("boom") })
+	t.Fatal("unreachable: WithTransaction must re-panic")
+}
@@ -0,0 +1,286 @@
+// Package shapefile writes ESRI Shapefiles of polygons with a dBASE attribute
+// table, enough to hand project boundaries to desktop GIS tools.
+package shapefile
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	fileCode      = 9994
+	version       = 1000
+	shapePolygon  = 5
+	headerBytes   = 100
+	dbfFieldBytes = 32
+)
+
+// WGS84 is the .prj content for EPSG:4326 coordinates.
+const WGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+// Point is an x/y (lon/lat) pair.
+type Point [2]float64
+
+// Ring is a closed sequence of points; Polygon is an exterior ring followed by
+// its holes, as in GeoJSON.
+type (
+	Ring    []Point
+	Polygon []Ring
+)
+
+// Field describes a dBASE column. Type is 'C' (text) or 'N' (numeric); Name is
+// at most 10 bytes.
+type Field struct {
+	Name     string
+	Type     byte
+	Length   int
+	Decimals int
+}
+
+// Writer spools .shp, .shx and .dbf content to temporary files, because every
+// header carries totals that are only known once the last record is written.
+// Memory use does not grow with the number of records.
+type Writer struct {
+	fields []Field
+
+	shp, shx, dbf *os.File
+	shpBuf        *bufio.Writer
+	shxBuf        *bufio.Writer
+	dbfBuf        *bufio.Writer
+
+	count   int
+	shpSize int64 // bytes, including the header
+	bbox    [4]float64
+}
+
+// NewWriter creates the spool files in dir (os.TempDir when empty).
+func NewWriter(dir string, fields []Field) (*Writer, error) {
+	for _, f := range fields {
+		if len(f.Name) == 0 || len(f.Name) > 10 || (f.Type != 'C' && f.Type != 'N') || f.Length < 1 || f.Length > 254 {
+			return nil, fmt.Errorf("shapefile: invalid field %+v", f)
+		}
+	}
+	w := &Writer{fields: fields, shpSize: headerBytes, bbox: [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}}
+	for _, f := range []**os.File{&w.shp, &w.shx, &w.dbf} {
+		file, err := os.CreateTemp(dir, "export-*.part")
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		*f = file
+	}
+	// Headers are written last; reserve their space now.
+	w.shpBuf = bufio.NewWriter(w.shp)
+	w.shxBuf = bufio.NewWriter(w.shx)
+	w.dbfBuf = bufio.NewWriter(w.dbf)
+	w.shpBuf.Write(make([]byte, headerBytes))
+	w.shxBuf.Write(make([]byte, headerBytes))
+	w.dbfBuf.Write(make([]byte, w.dbfHeaderSize()))
+	return w, nil
+}
+
+// WritePolygons appends one record made of polygons with the given attribute
+// values, one per field. Rings are re-oriented as the format requires:
+// exterior rings clockwise, holes counter-clockwise.
+func (w *Writer) WritePolygons(polygons []Polygon, values []string) error {
+	if len(values) != len(w.fields) {
+		return fmt.Errorf("shapefile: got %d values for %d fields", len(values), len(w.fields))
+	}
+
+	var parts []int32
+	var points []Point
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, polygon := range polygons {
+		for i, ring := range polygon {
+			if len(ring) < 4 {
+				return fmt.Errorf("shapefile: ring with %d points", len(ring))
+			}
+			parts = append(parts, int32(len(points)))
+			if clockwise(ring) != (i == 0) {
+				ring = reversed(ring)
+			}
+			for _, p := range ring {
+				box[0], box[1] = math.Min(box[0], p[0]), math.Min(box[1], p[1])
+				box[2], box[3] = math.Max(box[2], p[0]), math.Max(box[3], p[1])
+			}
+			points = append(points, ring...)
+		}
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("shapefile: record has no rings")
+	}
+
+	contentBytes := 4 + 32 + 4 + 4 + 4*len(parts) + 16*len(points)
+	w.count++
+
+	// .shx: offset and content length of the record, both in 16-bit words.
+	binary.Write(w.shxBuf, binary.BigEndian, [2]int32{int32(w.shpSize / 2), int32(contentBytes / 2)})
+
+	binary.Write(w.shpBuf, binary.BigEndian, [2]int32{int32(w.count), int32(contentBytes / 2)})
+	binary.Write(w.shpBuf, binary.LittleEndian, int32(shapePolygon))
+	binary.Write(w.shpBuf, binary.LittleEndian, box)
+	binary.Write(w.shpBuf, binary.LittleEndian, [2]int32{int32(len(parts)), int32(len(points))})
+	binary.Write(w.shpBuf, binary.LittleEndian, parts)
+	if err := binary.Write(w.shpBuf, binary.LittleEndian, points); err != nil {
+		return err
+	}
+	w.shpSize += int64(8 + contentBytes)
+
+	w.bbox[0], w.bbox[1] = math.Min(w.bbox[0], box[0]), math.Min(w.bbox[1], box[1])
+	w.bbox[2], w.bbox[3] = math.Max(w.bbox[2], box[2]), math.Max(w.bbox[3], box[3])
+
+	w.dbfBuf.WriteByte(' ')
+	for i, f := range w.fields {
+		w.dbfBuf.WriteString(formatValue(f, values[i]))
+	}
+	return nil
+}
+
+// WriteZip finishes the headers and adds base.shp, .shx, .dbf, .prj and .cpg
+// to zw.
+func (w *Writer) WriteZip(zw *zip.Writer, base string) error {
+	if err := w.finish(); err != nil {
+		return err
+	}
+	for _, entry := range []struct {
+		ext  string
+		file *os.File
+	}{{".shp", w.shp}, {".shx", w.shx}, {".dbf", w.dbf}} {
+		if _, err := entry.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		out, err := zw.Create(base + entry.ext)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, entry.file); err != nil {
+			return err
+		}
+	}
+	for _, entry := range [][2]string{{".prj", WGS84}, {".cpg", "UTF-8"}} {
+		out, err := zw.Create(base + entry[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(out, entry[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close removes the spool files.
+func (w *Writer) Close() error {
+	for _, f := range []*os.File{w.shp, w.shx, w.dbf} {
+		if f != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+	return nil
+}
+
+func (w *Writer) finish() error {
+	w.dbfBuf.WriteByte(0x1A)
+	for _, b := range []*bufio.Writer{w.shpBuf, w.shxBuf, w.dbfBuf} {
+		if err := b.Flush(); err != nil {
+			return err
+		}
+	}
+	bbox := w.bbox
+	if w.count == 0 {
+		bbox = [4]float64{}
+	}
+	if _, err := w.shp.WriteAt(mainHeader(w.shpSize, bbox), 0); err != nil {
+		return err
+	}
+	if _, err := w.shx.WriteAt(mainHeader(headerBytes+int64(8*w.count), bbox), 0); err != nil {
+		return err
+	}
+	_, err := w.dbf.WriteAt(w.dbfHeader(time.Now()), 0)
+	return err
+}
+
+// mainHeader is the 100-byte header shared by .shp and .shx.
+func mainHeader(size int64, bbox [4]float64) []byte {
+	h := make([]byte, headerBytes)
+	binary.BigEndian.PutUint32(h[0:], fileCode)
+	binary.BigEndian.PutUint32(h[24:], uint32(size/2))
+	binary.LittleEndian.PutUint32(h[28:], version)
+	binary.LittleEndian.PutUint32(h[32:], shapePolygon)
+	for i, v := range bbox {
+		binary.LittleEndian.PutUint64(h[36+8*i:], math.Float64bits(v))
+	}
+	return h
+}
+
+func (w *Writer) dbfHeaderSize() int {
+	return dbfFieldBytes + dbfFieldBytes*len(w.fields) + 1
+}
+
+func (w *Writer) dbfHeader(now time.Time) []byte {
+	recordSize := 1
+	for _, f := range w.fields {
+		recordSize += f.Length
+	}
+	h := make([]byte, w.dbfHeaderSize())
+	h[0] = 0x03
+	h[1], h[2], h[3] = byte(now.Year()-1900), byte(now.Month()), byte(now.Day())
+	binary.LittleEndian.PutUint32(h[4:], uint32(w.count))
+	binary.LittleEndian.PutUint16(h[8:], uint16(len(h)))
+	binary.LittleEndian.PutUint16(h[10:], uint16(recordSize))
+	for i, f := range w.fields {
+		d := h[dbfFieldBytes*(i+1):]
+		copy(d[:11], f.Name)
+		d[11] = f.Type
+		d[16] = byte(f.Length)
+		d[17] = byte(f.Decimals)
+	}
+	h[len(h)-1] = 0x0D
+	return h
+}
+
+// formatValue pads text to the field width, cutting on a rune boundary, and
+// right-aligns numbers.
+func formatValue(f Field, v string) string {
+	if f.Type == 'N' {
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			v = strconv.FormatFloat(n, 'f', f.Decimals, 64)
+		}
+		if len(v) > f.Length {
+			v = strings.Repeat("*", f.Length)
+		}
+		return strings.Repeat(" ", f.Length-len(v)) + v
+	}
+	for len(v) > f.Length {
+		_, size := utf8.DecodeLastRuneInString(v)
+		v = v[:len(v)-size]
+	}
+	return v + strings.Repeat(" ", f.Length-len(v))
+}
+
+// clockwise reports whether ring winds clockwise (negative shoelace area).
+func clockwise(ring Ring) bool {
+	var sum float64
+	for i := 0; i < len(ring)-1; i++ {
+		sum += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return sum < 0
+}
+
+func reversed(ring Ring) Ring {
+	out := make(Ring, len(ring))
+	for i, p := range ring {
+		out[len(ring)-1-i] = p
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// readZip returns the archive's entries by name.
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	return files
+}
+
+type record struct {
+	parts  []int32
+	points []Point
+}
+
+// readShp parses the polygon records of a .shp file, checking the header length.
+func readShp(t *testing.T, data []byte) []record {
+	t.Helper()
+	if code := binary.BigEndian.Uint32(data[0:]); code != fileCode {
+		t.Fatalf("file code = %d", code)
+	}
+	if words := binary.BigEndian.Uint32(data[24:]); int(words)*2 != len(data) {
+		t.Fatalf("header length %d bytes, file is %d", words*2, len(data))
+	}
+	var out []record
+	for off := headerBytes; off < len(data); {
+		length := int(binary.BigEndian.Uint32(data[off+4:])) * 2
+		c := data[off+8 : off+8+length]
+		if binary.LittleEndian.Uint32(c) != shapePolygon {
+			t.Fatalf("record at %d is not a polygon", off)
+		}
+		numParts := int(binary.LittleEndian.Uint32(c[36:]))
+		numPoints := int(binary.LittleEndian.Uint32(c[40:]))
+		var r record
+		for i := 0; i < numParts; i++ {
+			r.parts = append(r.parts, int32(binary.LittleEndian.Uint32(c[44+4*i:])))
+		}
+		pts := c[44+4*numParts:]
+		for i := 0; i < numPoints; i++ {
+			r.points = append(r.points, Point{
+				math.Float64frombits(binary.LittleEndian.Uint64(pts[16*i:])),
+				math.Float64frombits(binary.LittleEndian.Uint64(pts[16*i+8:])),
+			})
+		}
+		out = append(out, r)
+		off += 8 + length
+	}
+	return out
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	fields := []Field{{Name: "name", Type: 'C', Length: 8}, {Name: "area_ha", Type: 'N', Length: 12, Decimals: 2}}
+	w, err := NewWriter(t.TempDir(), fields)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	defer w.Close()
+
+	// Exterior counter-clockwise and hole clockwise, as GeoJSON writes them.
+	outer := Ring{{0, 0}, {4, 0}, {4, 4}, {0, 4}, {0, 0}}
+	hole := Ring{{1, 1}, {1, 2}, {2, 2}, {2, 1}, {1, 1}}
+	if err := w.WritePolygons([]Polygon{{outer, hole}}, []string{"Mau Forest Reserve", "1234.5"}); err != nil {
+		t.Fatalf("WritePolygons: %v", err)
+	}
+	second := Ring{{10, 10}, {11, 10}, {11, 11}, {10, 10}}
+	if err := w.WritePolygons([]Polygon{{second}}, []string{"Käpylä", "7"}); err != nil {
+		t.Fatalf("WritePolygons: %v", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := w.WriteZip(zw, "projects"); err != nil {
+		t.Fatalf("WriteZip: %v", err)
+	}
+	zw.Close()
+	files := readZip(t, buf.Bytes())
+	for _, name := range []string{"projects.shp", "projects.shx", "projects.dbf", "projects.prj", "projects.cpg"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("archive is missing %s", name)
+		}
+	}
+
+	records := readShp(t, files["projects.shp"])
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	first := records[0]
+	if len(first.parts) != 2 || first.parts[1] != 5 || len(first.points) != 10 {
+		t.Fatalf("unexpected first record %+v", first)
+	}
+	if !clockwise(Ring(first.points[:5])) || clockwise(Ring(first.points[5:])) {
+		t.Error("expected a clockwise exterior and a counter-clockwise hole")
+	}
+	bbox := files["projects.shp"][36:68]
+	if math.Float64frombits(binary.LittleEndian.Uint64(bbox[16:])) != 11 {
+		t.Error("file bounding box should cover every record")
+	}
+
+	shx := files["projects.shx"]
+	if len(shx) != headerBytes+16 {
+		t.Fatalf(".shx is %d bytes, want %d", len(shx), headerBytes+16)
+	}
+	if off := binary.BigEndian.Uint32(shx[headerBytes:]); off*2 != headerBytes {
+		t.Errorf("first record offset = %d bytes", off*2)
+	}
+
+	dbf := files["projects.dbf"]
+	if n := binary.LittleEndian.Uint32(dbf[4:]); n != 2 {
+		t.Fatalf("dbf record count = %d", n)
+	}
+	headerLen := int(binary.LittleEndian.Uint16(dbf[8:]))
+	recordLen := int(binary.LittleEndian.Uint16(dbf[10:]))
+	if recordLen != 1+8+12 {
+		t.Fatalf("dbf record length = %d", recordLen)
+	}
+	row := func(i int) string { return string(dbf[headerLen+i*recordLen : headerLen+(i+1)*recordLen]) }
+	if got := row(0); got != " Mau Fore     1234.50" {
+		t.Errorf("row 0 = %q", got)
+	}
+	if got := strings.TrimSpace(row(1)[1:9]); got != "Käpylä" {
+		t.Errorf("row 1 name = %q", got)
+	}
+	if dbf[len(dbf)-1] != 0x1A {
+		t.Error("dbf must end with the EOF marker")
+	}
+}
+
+func TestNewWriter_RejectsBadFields(t *testing.T) {
+	if _, err := NewWriter(t.TempDir(), []Field{{Name: "much_too_long", Type: 'C', Length: 5}}); err == nil {
+		t.Error("expected an error for an 11-byte field name")
+	}
+	if _, err := NewWriter(t.TempDir(), []Field{{Name: "x", Type: 'D', Length: 8}}); err == nil {
+		t.Error("expected an error for an unsupported field type")
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptHasher struct{}
+
+func (bcryptHasher) Hash(password []byte) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+	return string(hashed), err
+}
+
+func (bcryptHasher) Compare(hashed string, password []byte) error {
+	return bcrypt.CompareHashAndPassword([]byte(hashed), password)
+}
+
+func (bcryptHasher) Recognizes(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$")
+}
+
+// Argon2idHasher hashes with Argon2id and encodes in the PHC string format,
+// $argon2id$v=19$m=<KiB>,t=<passes>,p=<lanes>$<salt>$<key>. Verification
+// reads the parameters from the hash, so they can be raised without breaking
+// existing hashes.
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2id follows the RFC 9106 second recommended option, 64 MiB and
+// three passes.
+var DefaultArgon2id = Argon2idHasher{Time: 3, Memory: 64 * 1024, Threads: 4, SaltLen: 16, KeyLen: 32}
+
+const argon2idPrefix = "$argon2id$"
+
+var errPasswordMismatch = errors.New("password does not match hash")
+
+func (a Argon2idHasher) Hash(password []byte) (string, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, a.Memory, a.Time, a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (Argon2idHasher) Compare(hashed string, password []byte) error {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		return ErrUnknownPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return fmt.Errorf("invalid argon2 parameters %q: %w", parts[3], err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return fmt.Errorf("invalid argon2 key")
+	}
+	got := argon2.IDKey(password, salt, time, memory, threads, uint32(len(want)))
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
+func (Argon2idHasher) Recognizes(hashed string) bool {
+	return strings.HasPrefix(hashed, argon2idPrefix)
+}
@@ -1,12 +1,118 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pepperedPrefix marks hashes whose input was HMAC'd with the pepper, so both
+// kinds can be verified while existing accounts are migrated.
+const pepperedPrefix = "pepper$"
+
+// ErrPepperUnavailable is returned when a peppered hash is checked without the
+// pepper secret configured.
+var ErrPepperUnavailable = errors.New("password hash is peppered but no pepper is configured")
+
+// ErrUnknownPasswordHash is returned for a stored hash no configured
+// algorithm recognises.
+var ErrUnknownPasswordHash = errors.New("password hash format not recognised")
+
+var pepper struct {
+	secret  []byte
+	enabled bool
+}
+
+// ConfigurePepper sets the server-held pepper. With enabled set, new hashes
+// are peppered; the secret is used to verify peppered hashes either way, so
+// it can be disabled for new hashes without locking anyone out.
+func ConfigurePepper(secret []byte, enabled bool) {
+	pepper.secret = secret
+	pepper.enabled = enabled && len(secret) > 0
+}
+
+// Password hashing algorithms selectable for new hashes.
+const (
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmArgon2id = "argon2id"
+)
+
+// PasswordHasher is one password hashing algorithm. Its encoded hashes carry
+// a recognisable prefix, which is how CheckPassword picks the algorithm.
+type PasswordHasher interface {
+	Hash(password []byte) (string, error)
+	Compare(hashed string, password []byte) error
+	// Recognizes reports whether hashed was produced by this algorithm.
+	Recognizes(hashed string) bool
+}
+
+var hashers = map[string]PasswordHasher{
+	HashAlgorithmBcrypt:   bcryptHasher{},
+	HashAlgorithmArgon2id: DefaultArgon2id,
+}
+
+// currentHasher hashes new passwords; every algorithm in hashers still
+// verifies.
+var currentHasher = hashers[HashAlgorithmBcrypt]
+
+// ConfigurePasswordHashing selects the algorithm for new hashes. Existing
+// hashes of the other algorithm keep verifying and are upgraded on login.
+func ConfigurePasswordHashing(algorithm string) error {
+	h, ok := hashers[strings.ToLower(strings.TrimSpace(algorithm))]
+	if !ok {
+		return fmt.Errorf("unsupported password hash algorithm %q (want %s or %s)", algorithm, HashAlgorithmBcrypt, HashAlgorithmArgon2id)
+	}
+	currentHasher = h
+	return nil
+}
 
 func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashed), err
+	if pepper.enabled {
+		hashed, err := currentHasher.Hash(applyPepper(password))
+		return pepperedPrefix + hashed, err
+	}
+	return currentHasher.Hash([]byte(password))
 }
 
 func CheckPassword(password, hashed string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	input := []byte(password)
+	if rest, ok := strings.CutPrefix(hashed, pepperedPrefix); ok {
+		if len(pepper.secret) == 0 {
+			return ErrPepperUnavailable
+		}
+		hashed, input = rest, applyPepper(password)
+	}
+	h := hasherFor(hashed)
+	if h == nil {
+		return ErrUnknownPasswordHash
+	}
+	return h.Compare(hashed, input)
+}
+
+// NeedsRehash reports whether hashed was made by an algorithm other than the
+// configured one. Call it after a successful CheckPassword, while the
+// plaintext is at hand, and store a fresh HashPassword.
+func NeedsRehash(hashed string) bool {
+	hashed = strings.TrimPrefix(hashed, pepperedPrefix)
+	return !currentHasher.Recognizes(hashed)
+}
+
+func hasherFor(hashed string) PasswordHasher {
+	for _, h := range hashers {
+		if h.Recognizes(hashed) {
+			return h
+		}
+	}
+	return nil
+}
+
+// applyPepper returns hex(HMAC-SHA256(pepper, password)). The 64-byte hex
+// digest stays under bcrypt's 72-byte input limit.
+func applyPepper(password string) []byte {
+	mac := hmac.New(sha256.New, pepper.secret)
+	mac.Write([]byte(password))
+	return []byte(hex.EncodeToString(mac.Sum(nil)))
 }
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func withPepper(t *testing.T, secret string, enabled bool) {
+	t.Helper()
+	prevSecret, prevEnabled := pepper.secret, pepper.enabled
+	ConfigurePepper([]byte(secret), enabled)
+	t.Cleanup(func() { pepper.secret, pepper.enabled = prevSecret, prevEnabled })
+}
+
+func TestPepperedHashVerifies(t *testing.T) {
+	withPepper(t, "server-side-pepper", true)
+
+	hashed, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !strings.HasPrefix(hashed, pepperedPrefix) {
+		t.Fatalf("expected a peppered hash, got %q", hashed)
+	}
+	if err := CheckPassword("correct horse battery", hashed); err != nil {
+		t.Errorf("expected the password to verify: %v", err)
+	}
+	if err := CheckPassword("wrong", hashed); err == nil {
+		t.Error("expected a wrong password to fail")
+	}
+
+	ConfigurePepper([]byte("rotated-pepper"), true)
+	if err := CheckPassword("correct horse battery", hashed); err == nil {
+		t.Error("expected verification to fail under a different pepper")
+	}
+}
+
+func TestLegacyHashesVerifyWithPepperOnOrOff(t *testing.T) {
+	withPepper(t, "", false)
+	legacy, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	ConfigurePepper([]byte("server-side-pepper"), true)
+	if err := CheckPassword("correct horse battery", legacy); err != nil {
+		t.Errorf("legacy hash should verify with the pepper enabled: %v", err)
+	}
+	peppered, _ := HashPassword("correct horse battery")
+
+	// Disabling the pepper for new hashes keeps both kinds working.
+	ConfigurePepper([]byte("server-side-pepper"), false)
+	if err := CheckPassword("correct horse battery", legacy); err != nil {
+		t.Errorf("legacy hash should verify with the pepper disabled: %v", err)
+	}
+	if err := CheckPassword("correct horse battery", peppered); err != nil {
+		t.Errorf("peppered hash should verify while the secret is still configured: %v", err)
+	}
+
+	ConfigurePepper(nil, false)
+	if err := CheckPassword("correct horse battery", peppered); !errors.Is(err, ErrPepperUnavailable) {
+		t.Errorf("expected ErrPepperUnavailable without a secret, got %v", err)
+	}
+}
+
+func withHashAlgorithm(t *testing.T, algorithm string) {
+	t.Helper()
+	prev := currentHasher
+	if err := ConfigurePasswordHashing(algorithm); err != nil {
+		t.Fatalf("ConfigurePasswordHashing: %v", err)
+	}
+	t.Cleanup(func() { currentHasher = prev })
+}
+
+func TestHashesOfBothAlgorithmsVerify(t *testing.T) {
+	withPepper(t, "", false)
+
+	withHashAlgorithm(t, HashAlgorithmBcrypt)
+	bcrypted, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashPassword(bcrypt): %v", err)
+	}
+	withHashAlgorithm(t, HashAlgorithmArgon2id)
+	argoned, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("HashPassword(argon2id): %v", err)
+	}
+	if !strings.HasPrefix(argoned, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Fatalf("expected a PHC-encoded argon2id hash, got %q", argoned)
+	}
+
+	for _, algorithm := range []string{HashAlgorithmBcrypt, HashAlgorithmArgon2id} {
+		withHashAlgorithm(t, algorithm)
+		for name, hashed := range map[string]string{"bcrypt": bcrypted, "argon2id": argoned} {
+			if err := CheckPassword("correct horse battery", hashed); err != nil {
+				t.Errorf("%s hash should verify while %s is configured: %v", name, algorithm, err)
+			}
+			if err := CheckPassword("wrong", hashed); err == nil {
+				t.Errorf("%s hash accepted a wrong password", name)
+			}
+		}
+	}
+
+	if err := CheckPassword("correct horse battery", "plaintext"); !errors.Is(err, ErrUnknownPasswordHash) {
+		t.Errorf("expected ErrUnknownPasswordHash, got %v", err)
+	}
+	if err := ConfigurePasswordHashing("md5"); err == nil {
+		t.Error("expected an unknown algorithm to be rejected")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	withPepper(t, "server-side-pepper", true)
+	withHashAlgorithm(t, HashAlgorithmBcrypt)
+	old, _ := HashPassword("correct horse battery")
+	if NeedsRehash(old) {
+		t.Error("a hash of the configured algorithm must not need a rehash")
+	}
+
+	withHashAlgorithm(t, HashAlgorithmArgon2id)
+	if !NeedsRehash(old) {
+		t.Error("expected a bcrypt hash to need a rehash once argon2id is configured")
+	}
+	upgraded, _ := HashPassword("correct horse battery")
+	if !strings.HasPrefix(upgraded, pepperedPrefix+"$argon2id$") || NeedsRehash(upgraded) {
+		t.Errorf("expected a peppered argon2id hash, got %q", upgraded)
+	}
+	if err := CheckPassword("correct horse battery", upgraded); err != nil {
+		t.Errorf("peppered argon2id hash should verify: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package validation turns request binding failures into field-level error
+// responses of the form {"error": "...", "errors": [{"field", "message"}]}.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one invalid field using its JSON name.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report fields by their json names rather than Go struct field names.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+		// trimmed_email is email for fields the service trims before use,
+		// so surrounding whitespace alone does not fail a request.
+		_ = v.RegisterValidation("trimmed_email", func(fl validator.FieldLevel) bool {
+			return v.Var(strings.TrimSpace(fl.Field().String()), "email") == nil
+		})
+	}
+}
+
+// SetStrictDecoding makes JSON request binding refuse fields the target
+// struct does not declare, so a misspelt field is reported instead of
+// silently ignored. It applies process-wide, to BindJSON and to every
+// c.ShouldBindJSON, and is off by default.
+func SetStrictDecoding(strict bool) {
+	binding.EnableDecoderDisallowUnknownFields = strict
+}
+
+// BindJSON binds the request body into obj. On failure it writes a 400 with
+// field-level errors and returns false.
+func BindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "errors": Errors(err)})
+		return false
+	}
+	return true
+}
+
+// Errors converts a binding error into field errors. Decode errors that are
+// not tied to a field are reported against "body".
+func Errors(err error) []FieldError {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		out := make([]FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			out = append(out, FieldError{Field: fieldPath(fe), Message: message(fe)})
+		}
+		return out
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{Field: typeErr.Field, Message: "must be of type " + jsonType(typeErr.Type)}}
+	}
+	if name, ok := unknownField(err); ok {
+		return []FieldError{{Field: name, Message: "is not a known field"}}
+	}
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return []FieldError{{Field: "body", Message: "malformed JSON"}}
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Field: "body", Message: "request body is required"}}
+	}
+	return []FieldError{{Field: "body", Message: err.Error()}}
+}
+
+// unknownField extracts the field name from the error encoding/json returns
+// for an undeclared field under DisallowUnknownFields; it has no error type.
+func unknownField(err error) (string, bool) {
+	quoted, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	name, uerr := strconv.Unquote(quoted)
+	return name, uerr == nil
+}
+
+// fieldPath drops the struct name from the namespace, so nested fields read
+// as "bounds.min_lat" instead of "Request.bounds.min_lat".
+func fieldPath(fe validator.FieldError) string {
+	if _, rest, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return rest
+	}
+	return fe.Field()
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email", "trimmed_email":
+		return "must be a valid email address"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte":
+		return bound(fe, "at least")
+	case "max", "lte":
+		return bound(fe, "at most")
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "url":
+		return "must be a valid URL"
+	}
+	return fmt.Sprintf("failed the %q check", fe.Tag())
+}
+
+// bound phrases a min/max parameter for the kind of field it applies to.
+func bound(fe validator.FieldError, limit string) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("must be %s %s characters long", limit, fe.Param())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("must contain %s %s items", limit, fe.Param())
+	}
+	return fmt.Sprintf("must be %s %s", limit, fe.Param())
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return t.String()
+}
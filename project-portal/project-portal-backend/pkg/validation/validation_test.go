@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sampleRequest struct {
+	Email string   `json:"email" binding:"required,email"`
+	Name  string   `json:"name" binding:"required,max=5"`
+	Tags  []string `json:"tags" binding:"min=1"`
+	Count int      `json:"count" binding:"gte=0"`
+}
+
+func bind(t *testing.T, body string) (int, map[string]string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		var req sampleRequest
+		if BindJSON(c, &req) {
+			c.Status(http.StatusNoContent)
+		}
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	var resp struct {
+		Errors []FieldError `json:"errors"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	byField := map[string]string{}
+	for _, fe := range resp.Errors {
+		byField[fe.Field] = fe.Message
+	}
+	return w.Code, byField
+}
+
+func TestBindJSON_ReportsEveryInvalidField(t *testing.T) {
+	code, errs := bind(t, `{"email":"not-an-email","name":"far too long","tags":[],"count":-1}`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", code)
+	}
+	want := map[string]string{
+		"email": "must be a valid email address",
+		"name":  "must be at most 5 characters long",
+		"tags":  "must contain at least 1 items",
+		"count": "must be at least 0",
+	}
+	for field, msg := range want {
+		if errs[field] != msg {
+			t.Errorf("%s: got %q, want %q", field, errs[field], msg)
+		}
+	}
+}
+
+func TestBindJSON_RequiredFields(t *testing.T) {
+	_, errs := bind(t, `{"tags":["a"]}`)
+	if errs["email"] != "is required" || errs["name"] != "is required" {
+		t.Fatalf("expected email and name to be required, got %v", errs)
+	}
+}
+
+func TestBindJSON_DecodeErrors(t *testing.T) {
+	if _, errs := bind(t, `{"email":`); errs["body"] == "" {
+		t.Errorf("expected a body error for malformed JSON, got %v", errs)
+	}
+	if _, errs := bind(t, `{"email":"a@b.co","name":"x","tags":["a"],"count":"three"}`); errs["count"] != "must be of type number" {
+		t.Errorf("expected a type error on count, got %v", errs)
+	}
+}
+
+func TestBindJSON_ValidBody(t *testing.T) {
+	if code, _ := bind(t, `{"email":"a@b.co","name":"x","tags":["a"],"count":2}`); code != http.StatusNoContent {
+		t.Fatalf("expected 204 for a valid body, got %d", code)
+	}
+}
+
+func TestBindJSON_TrimmedEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" binding:"required,trimmed_email"`
+		}
+		if BindJSON(c, &req) {
+			c.Status(http.StatusNoContent)
+		}
+	})
+	for body, want := range map[string]int{
+		`{"email":"  User@Example.com "}`: http.StatusNoContent,
+		`{"email":"not an email"}`:        http.StatusBadRequest,
+		`{"email":"   "}`:                 http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", body, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestBindJSON_UnknownFields(t *testing.T) {
+	body := `{"email":"a@b.co","name":"x","tags":["a"],"count":2,"colour":"red"}`
+	if code, _ := bind(t, body); code != http.StatusNoContent {
+		t.Fatalf("lenient: expected an extra field to be ignored, got %d", code)
+	}
+
+	SetStrictDecoding(true)
+	t.Cleanup(func() { SetStrictDecoding(false) })
+	code, errs := bind(t, body)
+	if code != http.StatusBadRequest || errs["colour"] != "is not a known field" {
+		t.Fatalf("strict: expected 400 naming colour, got %d %v", code, errs)
+	}
+	if code, _ := bind(t, `{"email":"a@b.co","name":"x","tags":["a"],"count":2}`); code != http.StatusNoContent {
+		t.Errorf("strict: expected a body without extra fields to bind, got %d", code)
+	}
+}
@@ -0,0 +1,25 @@
+// Package version holds build metadata injected at link time:
+//
+//	go build -ldflags "-X carbon-scribe/project-portal/project-portal-backend/pkg/version.Version=1.4.0 \
+//	  -X carbon-scribe/project-portal/project-portal-backend/pkg/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X carbon-scribe/project-portal/project-portal-backend/pkg/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+// Set with -ldflags -X; see the package comment and the Makefile.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info is the build metadata as returned by GET /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// Get returns the metadata of the running binary.
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, BuildDate: BuildDate}
+}